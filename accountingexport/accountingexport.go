@@ -0,0 +1,237 @@
+package accountingexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+const (
+	DocumentTypeInvoice    = "invoice"
+	DocumentTypeCreditNote = "credit_note"
+)
+
+// Mapping translates billing concepts into the codes used by the finance
+// ledger. It is read from the accounting_export section of config.json.
+type Mapping struct {
+	DebtorsNominalCode string            `json:"debtors_nominal_code"` // sales ledger control account, debited with invoice totals
+	VATNominalCode     string            `json:"vat_nominal_code"`     // VAT liability account, credited with VAT
+	DefaultNominalCode string            `json:"default_nominal_code"` // sales account for plans missing from PlanNominalCodes
+	PlanNominalCodes   map[string]string `json:"plan_nominal_codes"`   // plan name -> sales account
+	VATTaxCodes        map[string]string `json:"vat_tax_codes"`        // VAT code (eg Standard) -> ledger tax code (eg T1)
+	CustomerRefs       map[string]string `json:"customer_refs"`        // billing account id or org guid -> customer reference
+}
+
+func LoadMapping(filename string) (Mapping, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return Mapping{}, err
+	}
+	var cfg struct {
+		AccountingExport Mapping `json:"accounting_export"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return Mapping{}, err
+	}
+	return cfg.AccountingExport, nil
+}
+
+// Document is a sales invoice or credit note ready to be written by a Format.
+// Amounts are in hundredths of CurrencyCode, eg pence for GBP, and Type says
+// which way they go. Lines can be negative, eg credits for costs shared with
+// other spaces or allowances.
+type Document struct {
+	Type         string
+	Reference    string
+	Date         time.Time
+	CustomerRef  string
+	CustomerName string
+	CurrencyCode string
+	Lines        []Line
+}
+
+type Line struct {
+	Description string
+	NominalCode string
+	TaxCode     string
+	Net         int64
+	Tax         int64
+}
+
+func (d Document) Net() int64 {
+	var total int64
+	for _, line := range d.Lines {
+		total += line.Net
+	}
+	return total
+}
+
+func (d Document) Tax() int64 {
+	var total int64
+	for _, line := range d.Lines {
+		total += line.Tax
+	}
+	return total
+}
+
+type Exporter struct {
+	Store   eventio.StatementReader
+	Mapping Mapping
+}
+
+func New(store eventio.StatementReader, mapping Mapping) *Exporter {
+	return &Exporter{
+		Store:   store,
+		Mapping: mapping,
+	}
+}
+
+// Documents returns an invoice for every statement issued for the month and
+// a credit note for every one of those statements that has been credited.
+// Statements with nothing to pay are left out.
+func (e *Exporter) Documents(month string) ([]Document, error) {
+	return e.DocumentsContext(context.Background(), month)
+}
+
+// DocumentsContext is Documents stopping early if ctx is done
+func (e *Exporter) DocumentsContext(ctx context.Context, month string) ([]Document, error) {
+	filter, err := eventio.ParseStatementMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := e.Store.GetStatementsContext(ctx, eventio.StatementFilter{
+		RangeStart: filter.RangeStart,
+		RangeStop:  filter.RangeStop,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unmapped := map[string]bool{}
+	documents := []Document{}
+	for _, statement := range statements {
+		if statement.PeriodStart != filter.RangeStart {
+			continue
+		}
+		missing := map[string]bool{}
+		invoice, err := e.invoice(statement, missing)
+		if err != nil {
+			return nil, err
+		}
+		if invoice.Net() == 0 && invoice.Tax() == 0 {
+			continue
+		}
+		for k := range missing {
+			unmapped[k] = true
+		}
+		documents = append(documents, invoice)
+		if statement.CreditNote != nil {
+			creditNote := invoice
+			creditNote.Type = DocumentTypeCreditNote
+			creditNote.Reference = fmt.Sprintf("CN%d", statement.CreditNote.CreditNoteNumber)
+			creditNote.Date, err = parseDate(statement.CreditNote.IssuedAt)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, creditNote)
+		}
+	}
+	if len(unmapped) > 0 {
+		missing := []string{}
+		for k := range unmapped {
+			missing = append(missing, k)
+		}
+		sort.Strings(missing)
+		return nil, fmt.Errorf("accounting export mapping is incomplete: %s", strings.Join(missing, ", "))
+	}
+	sort.SliceStable(documents, func(i, j int) bool {
+		return documents[i].Date.Before(documents[j].Date)
+	})
+	return documents, nil
+}
+
+func (e *Exporter) invoice(statement eventio.Statement, unmapped map[string]bool) (Document, error) {
+	date, err := parseDate(statement.IssuedAt)
+	if err != nil {
+		return Document{}, err
+	}
+	customerRef, ok := e.Mapping.CustomerRefs[statement.AccountID]
+	if !ok {
+		unmapped[fmt.Sprintf("no customer reference for account %s (%s)", statement.AccountID, statement.AccountName)] = true
+	}
+	doc := Document{
+		Type:         DocumentTypeInvoice,
+		Reference:    fmt.Sprintf("%d", statement.StatementNumber),
+		Date:         date,
+		CustomerRef:  customerRef,
+		CustomerName: statement.AccountName,
+		CurrencyCode: statement.CurrencyCode,
+	}
+
+	period, err := time.Parse("2006-01-02", statement.PeriodStart)
+	if err != nil {
+		return Document{}, err
+	}
+	for _, vat := range statement.VAT {
+		taxCode, ok := e.Mapping.VATTaxCodes[vat.VatCode]
+		if !ok {
+			unmapped[fmt.Sprintf("no tax code for VAT code %s", vat.VatCode)] = true
+		}
+		lines := []Line{}
+		for _, item := range statement.LineItems {
+			if item.VatCode != vat.VatCode || item.VatRate != vat.VatRate {
+				continue
+			}
+			nominalCode, ok := e.Mapping.PlanNominalCodes[item.PlanName]
+			if !ok {
+				nominalCode = e.Mapping.DefaultNominalCode
+			}
+			if nominalCode == "" {
+				unmapped[fmt.Sprintf("no nominal code for plan %s", item.PlanName)] = true
+			}
+			net, err := ParsePence(item.ExVAT)
+			if err != nil {
+				return Document{}, err
+			}
+			lines = append(lines, Line{
+				Description: fmt.Sprintf("%s %s %s", item.ResourceType, item.PlanName, period.Format("Jan 2006")),
+				NominalCode: nominalCode,
+				TaxCode:     taxCode,
+				Net:         net,
+			})
+		}
+		vatTotal, err := ParsePence(vat.VAT)
+		if err != nil {
+			return Document{}, err
+		}
+		if err := allocateTax(lines, vat.VatRate, vatTotal); err != nil {
+			return Document{}, err
+		}
+		doc.Lines = append(doc.Lines, lines...)
+	}
+	return doc, nil
+}
+
+// Export writes the documents for the month in the given format
+func (e *Exporter) Export(w io.Writer, month string, format Format) error {
+	documents, err := e.Documents(month)
+	if err != nil {
+		return err
+	}
+	return format.Write(w, e.Mapping, documents)
+}
+
+func parseDate(timestamp string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
@@ -0,0 +1,13 @@
+package accountingexport_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestAccountingExport(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AccountingExport")
+}
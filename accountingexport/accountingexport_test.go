@@ -0,0 +1,183 @@
+package accountingexport_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	. "github.com/alphagov/paas-billing/accountingexport"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exporter", func() {
+	var (
+		fakeStore *eventiofakes.FakeEventStore
+		mapping   Mapping
+		exporter  *Exporter
+		statement eventio.Statement
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		mapping = Mapping{
+			DebtorsNominalCode: "1100",
+			VATNominalCode:     "2200",
+			DefaultNominalCode: "4000",
+			PlanNominalCodes: map[string]string{
+				"postgres small": "4010",
+			},
+			VATTaxCodes: map[string]string{
+				"Standard": "T1",
+			},
+			CustomerRefs: map[string]string{
+				"account-1": "DEPT01",
+			},
+		}
+		exporter = New(fakeStore, mapping)
+		statement = eventio.Statement{
+			StatementNumber: 42,
+			AccountID:       "account-1",
+			AccountName:     "Department of Examples",
+			CurrencyCode:    "GBP",
+			PeriodStart:     "2001-01-01",
+			PeriodStop:      "2001-02-01",
+			IssuedAt:        "2001-02-06T10:11:12.345+00:00",
+			LineItems: []eventio.StatementLineItem{
+				{ResourceType: "app", PlanName: "app", VatCode: "Standard", VatRate: "0.2", ExVAT: "0.03"},
+				{ResourceType: "postgres", PlanName: "postgres small", VatCode: "Standard", VatRate: "0.2", ExVAT: "0.03"},
+			},
+			VAT: []eventio.StatementVAT{
+				{VatCode: "Standard", VatRate: "0.2", ExVAT: "0.06", VAT: "0.01"},
+			},
+			Subtotal: "0.06",
+			VATTotal: "0.01",
+			Total:    "0.07",
+		}
+		fakeStore.GetStatementsContextReturns([]eventio.Statement{statement}, nil)
+	})
+
+	It("should turn statements for the month into invoices", func() {
+		documents, err := exporter.Documents("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, filter := fakeStore.GetStatementsContextArgsForCall(0)
+		Expect(filter).To(Equal(eventio.StatementFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		}))
+		Expect(documents).To(HaveLen(1))
+		Expect(documents[0].Type).To(Equal(DocumentTypeInvoice))
+		Expect(documents[0].Reference).To(Equal("42"))
+		Expect(documents[0].Date.Format("2006-01-02")).To(Equal("2001-02-06"))
+		Expect(documents[0].CustomerRef).To(Equal("DEPT01"))
+		Expect(documents[0].CurrencyCode).To(Equal("GBP"))
+		Expect(documents[0].Lines).To(Equal([]Line{
+			{Description: "app app Jan 2001", NominalCode: "4000", TaxCode: "T1", Net: 3, Tax: 0},
+			{Description: "postgres postgres small Jan 2001", NominalCode: "4010", TaxCode: "T1", Net: 3, Tax: 1},
+		}))
+		Expect(documents[0].Tax()).To(Equal(int64(1)), "line VAT must add up to the statement VAT")
+	})
+
+	It("should add a credit note for credited statements", func() {
+		statement.CreditNote = &eventio.CreditNote{
+			CreditNoteNumber: 3,
+			IssuedAt:         "2001-03-01T00:00:00+00:00",
+		}
+		fakeStore.GetStatementsContextReturns([]eventio.Statement{statement}, nil)
+
+		documents, err := exporter.Documents("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(documents).To(HaveLen(2))
+		Expect(documents[1].Type).To(Equal(DocumentTypeCreditNote))
+		Expect(documents[1].Reference).To(Equal("CN3"))
+		Expect(documents[1].Date.Format("2006-01-02")).To(Equal("2001-03-01"))
+		Expect(documents[1].Lines).To(Equal(documents[0].Lines))
+	})
+
+	It("should keep the currency of statements that are not in GBP", func() {
+		statement.CurrencyCode = "USD"
+		statement.CreditNote = &eventio.CreditNote{
+			CreditNoteNumber: 3,
+			IssuedAt:         "2001-03-01T00:00:00+00:00",
+		}
+		fakeStore.GetStatementsContextReturns([]eventio.Statement{statement}, nil)
+
+		documents, err := exporter.Documents("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(documents).To(HaveLen(2))
+		Expect(documents[0].CurrencyCode).To(Equal("USD"))
+		Expect(documents[1].CurrencyCode).To(Equal("USD"))
+
+		var buf bytes.Buffer
+		format, err := GetFormat("sage50")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exporter.Export(&buf, "2001-01", format)).To(MatchError(ContainSubstring("is in USD")))
+	})
+
+	It("should leave out statements with nothing to pay", func() {
+		statement.LineItems = []eventio.StatementLineItem{
+			{ResourceType: "app", PlanName: "free", VatCode: "Standard", VatRate: "0.2", ExVAT: "0.00"},
+		}
+		statement.VAT = []eventio.StatementVAT{
+			{VatCode: "Standard", VatRate: "0.2", ExVAT: "0.00", VAT: "0.00"},
+		}
+		statement.AccountID = "unmapped-account"
+		fakeStore.GetStatementsContextReturns([]eventio.Statement{statement}, nil)
+
+		documents, err := exporter.Documents("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(documents).To(BeEmpty())
+	})
+
+	It("should list everything missing from the mapping", func() {
+		exporter.Mapping.DefaultNominalCode = ""
+		exporter.Mapping.VATTaxCodes = map[string]string{}
+		exporter.Mapping.CustomerRefs = map[string]string{}
+
+		_, err := exporter.Documents("2001-01")
+		Expect(err).To(MatchError(
+			"accounting export mapping is incomplete: " +
+				"no customer reference for account account-1 (Department of Examples), " +
+				"no nominal code for plan app, " +
+				"no tax code for VAT code Standard",
+		))
+	})
+
+	It("should reject an invalid month", func() {
+		_, err := exporter.Documents("2001-01-01")
+		Expect(err).To(MatchError(ContainSubstring("YYYY-MM")))
+		Expect(fakeStore.GetStatementsContextCallCount()).To(Equal(0))
+	})
+
+	It("should write the documents in the requested format", func() {
+		var buf bytes.Buffer
+		format, err := GetFormat("sage50")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(exporter.Export(&buf, "2001-01", format)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring("SI,DEPT01,4010,,06/02/2001,42,postgres postgres small Jan 2001,0.03,T1,0.01\r\n"))
+	})
+
+	It("should load the mapping from the accounting_export section of the config file", func() {
+		dir := GinkgoT().TempDir()
+		filename := filepath.Join(dir, "config.json")
+		Expect(os.WriteFile(filename, []byte(`{
+			"pricing_plans": [],
+			"accounting_export": {
+				"debtors_nominal_code": "1100",
+				"vat_tax_codes": {"Standard": "T1"},
+				"customer_refs": {"account-1": "DEPT01"}
+			}
+		}`), 0644)).To(Succeed())
+
+		loaded, err := LoadMapping(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded.DebtorsNominalCode).To(Equal("1100"))
+		Expect(loaded.VATTaxCodes).To(Equal(map[string]string{"Standard": "T1"}))
+		Expect(loaded.CustomerRefs).To(Equal(map[string]string{"account-1": "DEPT01"}))
+	})
+})
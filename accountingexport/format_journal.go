@@ -0,0 +1,73 @@
+package accountingexport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// JournalFormat is a generic double-entry journal as CSV. Each invoice
+// debits the debtors account with the gross amount and credits the sales
+// accounts with the net amounts and the VAT account with the VAT. Credit
+// notes are the reverse. Negative amounts are written as positive amounts on
+// the opposite side. The debits and credits of each document balance, and
+// are in the currency of the document.
+type JournalFormat struct{}
+
+func (JournalFormat) Name() string          { return "journal" }
+func (JournalFormat) ContentType() string   { return "text/csv; charset=UTF-8" }
+func (JournalFormat) FileExtension() string { return "csv" }
+
+func (JournalFormat) Write(w io.Writer, mapping Mapping, documents []Document) error {
+	if mapping.DebtorsNominalCode == "" || mapping.VATNominalCode == "" {
+		return fmt.Errorf("the journal format requires debtors_nominal_code and vat_nominal_code to be mapped")
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"date", "reference", "type", "nominal_code", "customer_ref", "description", "tax_code", "debit", "credit", "currency",
+	}); err != nil {
+		return err
+	}
+	for _, doc := range documents {
+		// invoices are a debit on the debtors account, credit notes a credit.
+		// Negative amounts, such as credits for shared costs, go on the
+		// other side as ledgers don't accept negative debits or credits.
+		entry := func(nominalCode string, taxCode string, description string, debtorsSide bool, amount int64) error {
+			if amount < 0 {
+				debtorsSide = !debtorsSide
+				amount = -amount
+			}
+			debit, credit := "", FormatPence(amount)
+			if debtorsSide == (doc.Type == DocumentTypeInvoice) {
+				debit, credit = credit, debit
+			}
+			return cw.Write([]string{
+				doc.Date.Format("2006-01-02"), doc.Reference, doc.Type, nominalCode,
+				doc.CustomerRef, description, taxCode, debit, credit, doc.CurrencyCode,
+			})
+		}
+		if err := entry(mapping.DebtorsNominalCode, "", doc.CustomerName, true, doc.Net()+doc.Tax()); err != nil {
+			return err
+		}
+		taxByCode := map[string]int64{}
+		for _, line := range doc.Lines {
+			if err := entry(line.NominalCode, line.TaxCode, line.Description, false, line.Net); err != nil {
+				return err
+			}
+			taxByCode[line.TaxCode] += line.Tax
+		}
+		taxCodes := []string{}
+		for taxCode := range taxByCode {
+			taxCodes = append(taxCodes, taxCode)
+		}
+		sort.Strings(taxCodes)
+		for _, taxCode := range taxCodes {
+			if err := entry(mapping.VATNominalCode, taxCode, "VAT", false, taxByCode[taxCode]); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
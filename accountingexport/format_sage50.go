@@ -0,0 +1,71 @@
+package accountingexport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// Sage50Format is the Sage 50 Accounts audit trail transaction import
+// layout. Each invoice line becomes a sales invoice (SI) or sales credit
+// (SC) transaction posted to the customer's account. Sage rejects negative
+// amounts, so negative lines, such as credits for shared costs, are posted
+// as the opposite transaction type. The layout has no currency, so amounts
+// are posted in the base currency of the ledger, which must be GBP, and
+// documents in other currencies cannot be written.
+type Sage50Format struct{}
+
+// sage50CurrencyCode is the currency of the amounts in Sage 50 imports
+const sage50CurrencyCode = "GBP"
+
+var sage50ReverseTypes = map[string]string{
+	"SI": "SC",
+	"SC": "SI",
+}
+
+func (Sage50Format) Name() string          { return "sage50" }
+func (Sage50Format) ContentType() string   { return "text/csv; charset=UTF-8" }
+func (Sage50Format) FileExtension() string { return "csv" }
+
+func (Sage50Format) Write(w io.Writer, mapping Mapping, documents []Document) error {
+	cw := csv.NewWriter(w)
+	cw.UseCRLF = true
+	if err := cw.Write([]string{
+		"Type", "Account Reference", "Nominal A/C Ref", "Department Code", "Date",
+		"Reference", "Details", "Net Amount", "Tax Code", "Tax Amount",
+	}); err != nil {
+		return err
+	}
+	for _, doc := range documents {
+		if doc.CurrencyCode != sage50CurrencyCode {
+			return fmt.Errorf("the sage50 format can only write %s documents - %s %s is in %s", sage50CurrencyCode, doc.Type, doc.Reference, doc.CurrencyCode)
+		}
+		transactionType := "SI"
+		if doc.Type == DocumentTypeCreditNote {
+			transactionType = "SC"
+		}
+		for _, line := range doc.Lines {
+			lineType, net, tax := transactionType, line.Net, line.Tax
+			if net < 0 || (net == 0 && tax < 0) {
+				lineType = sage50ReverseTypes[transactionType]
+				net, tax = -net, -tax
+			}
+			if err := cw.Write([]string{
+				lineType,
+				doc.CustomerRef,
+				line.NominalCode,
+				"",
+				doc.Date.Format("02/01/2006"),
+				doc.Reference,
+				line.Description,
+				FormatPence(net),
+				line.TaxCode,
+				FormatPence(tax),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,96 @@
+package accountingexport
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// Format writes documents as a file that can be imported into a ledger
+type Format interface {
+	// Name identifies the format on the command line and in the API
+	Name() string
+	// ContentType is the MIME type of the written file
+	ContentType() string
+	// FileExtension is appended to the suggested file name, eg csv
+	FileExtension() string
+	// Write writes the documents to w
+	Write(w io.Writer, mapping Mapping, documents []Document) error
+}
+
+var formats = map[string]Format{}
+
+// Register makes a format available by name. It panics if a format with the
+// same name has already been registered.
+func Register(format Format) {
+	if _, exists := formats[format.Name()]; exists {
+		panic(fmt.Sprintf("accounting export format %s registered twice", format.Name()))
+	}
+	formats[format.Name()] = format
+}
+
+func init() {
+	Register(JournalFormat{})
+	Register(Sage50Format{})
+}
+
+// GetFormat looks up a registered format by name
+func GetFormat(name string) (Format, error) {
+	format, ok := formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown accounting export format '%s' - must be one of %v", name, FormatNames())
+	}
+	return format, nil
+}
+
+// FormatNames lists the registered formats in alphabetical order
+func FormatNames() []string {
+	names := []string{}
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePence parses a decimal amount of pounds such as "12.34" into pence.
+// It fails rather than round if the amount has fractions of a penny.
+func ParsePence(amount eventio.Money) (int64, error) {
+	return amount.Pence()
+}
+
+// FormatPence formats pence as a decimal amount of pounds, eg 1234 as 12.34
+func FormatPence(pence int64) string {
+	return string(eventio.MoneyFromPence(pence))
+}
+
+// allocateTax spreads the VAT charged for a VAT code across the lines it
+// was charged on. Each line gets its own rounded share and any difference
+// from rounding is put on the largest line so the lines add up to total.
+func allocateTax(lines []Line, rate string, total int64) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	var allocated int64
+	largest := 0
+	for i := range lines {
+		tax, err := eventio.MoneyFromPence(lines[i].Net).MulRate(rate)
+		if err != nil {
+			return err
+		}
+		if tax, err = tax.Round(2); err != nil {
+			return err
+		}
+		if lines[i].Tax, err = tax.Pence(); err != nil {
+			return err
+		}
+		allocated += lines[i].Tax
+		if lines[i].Net > lines[largest].Net {
+			largest = i
+		}
+	}
+	lines[largest].Tax += total - allocated
+	return nil
+}
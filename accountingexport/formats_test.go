@@ -0,0 +1,158 @@
+package accountingexport_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/alphagov/paas-billing/accountingexport"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Formats", func() {
+	var (
+		mapping   Mapping
+		documents []Document
+	)
+
+	BeforeEach(func() {
+		mapping = Mapping{
+			DebtorsNominalCode: "1100",
+			VATNominalCode:     "2200",
+		}
+		invoice := Document{
+			Type:         DocumentTypeInvoice,
+			Reference:    "42",
+			Date:         time.Date(2001, 2, 6, 0, 0, 0, 0, time.UTC),
+			CustomerRef:  "DEPT01",
+			CustomerName: "Department of Examples, Ltd",
+			CurrencyCode: "GBP",
+			Lines: []Line{
+				{Description: "app app Jan 2001", NominalCode: "4000", TaxCode: "T1", Net: 1000, Tax: 200},
+				{Description: "postgres small Jan 2001", NominalCode: "4010", TaxCode: "T1", Net: 550, Tax: 110},
+			},
+		}
+		creditNote := invoice
+		creditNote.Type = DocumentTypeCreditNote
+		creditNote.Reference = "CN3"
+		documents = []Document{invoice, creditNote}
+	})
+
+	It("should list the registered formats", func() {
+		Expect(FormatNames()).To(Equal([]string{"journal", "sage50"}))
+
+		_, err := GetFormat("quickbooks")
+		Expect(err).To(MatchError("unknown accounting export format 'quickbooks' - must be one of [journal sage50]"))
+	})
+
+	It("should not allow a format to be registered twice", func() {
+		Expect(func() { Register(JournalFormat{}) }).To(Panic())
+	})
+
+	Describe("journal", func() {
+		It("should write balanced double entries", func() {
+			var buf bytes.Buffer
+			Expect(JournalFormat{}.Write(&buf, mapping, documents)).To(Succeed())
+
+			Expect(buf.String()).To(Equal(
+				"date,reference,type,nominal_code,customer_ref,description,tax_code,debit,credit,currency\n" +
+					"2001-02-06,42,invoice,1100,DEPT01,\"Department of Examples, Ltd\",,18.60,,GBP\n" +
+					"2001-02-06,42,invoice,4000,DEPT01,app app Jan 2001,T1,,10.00,GBP\n" +
+					"2001-02-06,42,invoice,4010,DEPT01,postgres small Jan 2001,T1,,5.50,GBP\n" +
+					"2001-02-06,42,invoice,2200,DEPT01,VAT,T1,,3.10,GBP\n" +
+					"2001-02-06,CN3,credit_note,1100,DEPT01,\"Department of Examples, Ltd\",,,18.60,GBP\n" +
+					"2001-02-06,CN3,credit_note,4000,DEPT01,app app Jan 2001,T1,10.00,,GBP\n" +
+					"2001-02-06,CN3,credit_note,4010,DEPT01,postgres small Jan 2001,T1,5.50,,GBP\n" +
+					"2001-02-06,CN3,credit_note,2200,DEPT01,VAT,T1,3.10,,GBP\n",
+			))
+		})
+
+		It("should write negative amounts on the opposite side", func() {
+			var buf bytes.Buffer
+			documents[0].Lines = append(documents[0].Lines, Line{
+				Description: "shared postgres small Jan 2001", NominalCode: "4010", TaxCode: "T1", Net: -1550, Tax: -310,
+			})
+			Expect(JournalFormat{}.Write(&buf, mapping, documents[:1])).To(Succeed())
+
+			Expect(buf.String()).To(Equal(
+				"date,reference,type,nominal_code,customer_ref,description,tax_code,debit,credit,currency\n" +
+					"2001-02-06,42,invoice,1100,DEPT01,\"Department of Examples, Ltd\",,0.00,,GBP\n" +
+					"2001-02-06,42,invoice,4000,DEPT01,app app Jan 2001,T1,,10.00,GBP\n" +
+					"2001-02-06,42,invoice,4010,DEPT01,postgres small Jan 2001,T1,,5.50,GBP\n" +
+					"2001-02-06,42,invoice,4010,DEPT01,shared postgres small Jan 2001,T1,15.50,,GBP\n" +
+					"2001-02-06,42,invoice,2200,DEPT01,VAT,T1,,0.00,GBP\n",
+			))
+		})
+
+		It("should write the currency of each document", func() {
+			var buf bytes.Buffer
+			documents[0].CurrencyCode = "USD"
+			Expect(JournalFormat{}.Write(&buf, mapping, documents[:1])).To(Succeed())
+
+			Expect(buf.String()).To(Equal(
+				"date,reference,type,nominal_code,customer_ref,description,tax_code,debit,credit,currency\n" +
+					"2001-02-06,42,invoice,1100,DEPT01,\"Department of Examples, Ltd\",,18.60,,USD\n" +
+					"2001-02-06,42,invoice,4000,DEPT01,app app Jan 2001,T1,,10.00,USD\n" +
+					"2001-02-06,42,invoice,4010,DEPT01,postgres small Jan 2001,T1,,5.50,USD\n" +
+					"2001-02-06,42,invoice,2200,DEPT01,VAT,T1,,3.10,USD\n",
+			))
+		})
+
+		It("should require the control accounts to be mapped", func() {
+			var buf bytes.Buffer
+			mapping.VATNominalCode = ""
+			Expect(JournalFormat{}.Write(&buf, mapping, documents)).To(MatchError(ContainSubstring("vat_nominal_code")))
+		})
+	})
+
+	Describe("sage50", func() {
+		It("should write audit trail transactions", func() {
+			var buf bytes.Buffer
+			Expect(Sage50Format{}.Write(&buf, mapping, documents)).To(Succeed())
+
+			Expect(buf.String()).To(Equal(
+				"Type,Account Reference,Nominal A/C Ref,Department Code,Date,Reference,Details,Net Amount,Tax Code,Tax Amount\r\n" +
+					"SI,DEPT01,4000,,06/02/2001,42,app app Jan 2001,10.00,T1,2.00\r\n" +
+					"SI,DEPT01,4010,,06/02/2001,42,postgres small Jan 2001,5.50,T1,1.10\r\n" +
+					"SC,DEPT01,4000,,06/02/2001,CN3,app app Jan 2001,10.00,T1,2.00\r\n" +
+					"SC,DEPT01,4010,,06/02/2001,CN3,postgres small Jan 2001,5.50,T1,1.10\r\n",
+			))
+		})
+
+		It("should post negative lines as the opposite transaction type", func() {
+			var buf bytes.Buffer
+			documents[0].Lines[1].Net, documents[0].Lines[1].Tax = -550, -110
+			documents[1].Lines[1].Net, documents[1].Lines[1].Tax = -550, -110
+			Expect(Sage50Format{}.Write(&buf, mapping, documents)).To(Succeed())
+
+			Expect(buf.String()).To(Equal(
+				"Type,Account Reference,Nominal A/C Ref,Department Code,Date,Reference,Details,Net Amount,Tax Code,Tax Amount\r\n" +
+					"SI,DEPT01,4000,,06/02/2001,42,app app Jan 2001,10.00,T1,2.00\r\n" +
+					"SC,DEPT01,4010,,06/02/2001,42,postgres small Jan 2001,5.50,T1,1.10\r\n" +
+					"SC,DEPT01,4000,,06/02/2001,CN3,app app Jan 2001,10.00,T1,2.00\r\n" +
+					"SI,DEPT01,4010,,06/02/2001,CN3,postgres small Jan 2001,5.50,T1,1.10\r\n",
+			))
+		})
+
+		It("should refuse to write documents that are not in GBP", func() {
+			var buf bytes.Buffer
+			documents[1].CurrencyCode = "USD"
+			Expect(Sage50Format{}.Write(&buf, mapping, documents)).To(MatchError(
+				"the sage50 format can only write GBP documents - credit_note CN3 is in USD",
+			))
+		})
+	})
+
+	Describe("money", func() {
+		It("should parse and format pence exactly", func() {
+			pence, err := ParsePence("12.30")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pence).To(Equal(int64(1230)))
+			Expect(FormatPence(pence)).To(Equal("12.30"))
+			Expect(FormatPence(-5)).To(Equal("-0.05"))
+
+			_, err = ParsePence("0.001")
+			Expect(err).To(MatchError(ContainSubstring("not a whole number of pence")))
+		})
+	})
+})
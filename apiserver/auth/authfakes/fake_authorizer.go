@@ -20,6 +20,18 @@ type FakeAuthorizer struct {
 		result1 bool
 		result2 error
 	}
+	HasAnyBillingAccessStub        func() (bool, error)
+	hasAnyBillingAccessMutex       sync.RWMutex
+	hasAnyBillingAccessArgsForCall []struct {
+	}
+	hasAnyBillingAccessReturns struct {
+		result1 bool
+		result2 error
+	}
+	hasAnyBillingAccessReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	HasBillingAccessStub        func([]string) (bool, error)
 	hasBillingAccessMutex       sync.RWMutex
 	hasBillingAccessArgsForCall []struct {
@@ -93,6 +105,62 @@ func (fake *FakeAuthorizer) AdminReturnsOnCall(i int, result1 bool, result2 erro
 	}{result1, result2}
 }
 
+func (fake *FakeAuthorizer) HasAnyBillingAccess() (bool, error) {
+	fake.hasAnyBillingAccessMutex.Lock()
+	ret, specificReturn := fake.hasAnyBillingAccessReturnsOnCall[len(fake.hasAnyBillingAccessArgsForCall)]
+	fake.hasAnyBillingAccessArgsForCall = append(fake.hasAnyBillingAccessArgsForCall, struct {
+	}{})
+	stub := fake.HasAnyBillingAccessStub
+	fakeReturns := fake.hasAnyBillingAccessReturns
+	fake.recordInvocation("HasAnyBillingAccess", []interface{}{})
+	fake.hasAnyBillingAccessMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeAuthorizer) HasAnyBillingAccessCallCount() int {
+	fake.hasAnyBillingAccessMutex.RLock()
+	defer fake.hasAnyBillingAccessMutex.RUnlock()
+	return len(fake.hasAnyBillingAccessArgsForCall)
+}
+
+func (fake *FakeAuthorizer) HasAnyBillingAccessCalls(stub func() (bool, error)) {
+	fake.hasAnyBillingAccessMutex.Lock()
+	defer fake.hasAnyBillingAccessMutex.Unlock()
+	fake.HasAnyBillingAccessStub = stub
+}
+
+func (fake *FakeAuthorizer) HasAnyBillingAccessReturns(result1 bool, result2 error) {
+	fake.hasAnyBillingAccessMutex.Lock()
+	defer fake.hasAnyBillingAccessMutex.Unlock()
+	fake.HasAnyBillingAccessStub = nil
+	fake.hasAnyBillingAccessReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeAuthorizer) HasAnyBillingAccessReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.hasAnyBillingAccessMutex.Lock()
+	defer fake.hasAnyBillingAccessMutex.Unlock()
+	fake.HasAnyBillingAccessStub = nil
+	if fake.hasAnyBillingAccessReturnsOnCall == nil {
+		fake.hasAnyBillingAccessReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.hasAnyBillingAccessReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeAuthorizer) HasBillingAccess(arg1 []string) (bool, error) {
 	var arg1Copy []string
 	if arg1 != nil {
@@ -167,6 +235,8 @@ func (fake *FakeAuthorizer) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.adminMutex.RLock()
 	defer fake.adminMutex.RUnlock()
+	fake.hasAnyBillingAccessMutex.RLock()
+	defer fake.hasAnyBillingAccessMutex.RUnlock()
 	fake.hasBillingAccessMutex.RLock()
 	defer fake.hasBillingAccessMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
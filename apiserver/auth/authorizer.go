@@ -4,4 +4,5 @@ package auth
 type Authorizer interface {
 	Admin() (bool, error)
 	HasBillingAccess([]string) (bool, error)
+	HasAnyBillingAccess() (bool, error)
 }
@@ -22,6 +22,10 @@ func (sa *SimpleAuthorizer) HasBillingAccess(orgs []string) (bool, error) {
 	return true, nil
 }
 
+func (sa *SimpleAuthorizer) HasAnyBillingAccess() (bool, error) {
+	return len(sa.authorizedOrgGUIDs) > 0, nil
+}
+
 func (sa *SimpleAuthorizer) Admin() (bool, error) {
 	return sa.admin, nil
 }
@@ -115,6 +115,31 @@ func (a *ClientAuthorizer) HasBillingAccess(requestedOrgs []string) (bool, error
 	return true, nil
 }
 
+// HasAnyBillingAccess checks whether the user is a billing manager or org
+// manager of at least one organisation
+func (a *ClientAuthorizer) HasAnyBillingAccess() (bool, error) {
+	err := a.composeClaims()
+	if err != nil {
+		return false, err
+	}
+	cf, err := a.client()
+	if err != nil {
+		return false, err
+	}
+	billingManagerOrganisations, err := cf.ListUserBillingManagedOrgs(a.claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	if len(billingManagerOrganisations) > 0 {
+		return true, nil
+	}
+	managerOrganisations, err := cf.ListUserManagedOrgs(a.claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	return len(managerOrganisations) > 0, nil
+}
+
 func (a *ClientAuthorizer) Admin() (bool, error) {
 	if ok, err := a.hasScope("cloud_controller.admin_read_only"); ok {
 		return true, nil
@@ -7,6 +7,7 @@ import (
 	prom_client "github.com/prometheus/client_golang/prometheus"
 
 	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/accountingexport"
 	"github.com/alphagov/paas-billing/instancediscoverer"
 	"github.com/alphagov/paas-billing/metricsproxy"
 	"github.com/labstack/echo-contrib/prometheus"
@@ -28,9 +29,19 @@ type Config struct {
 	Logger lager.Logger
 	// EnablePanic will cause the server to crash on panic if set to true
 	EnablePanic bool
+	// AccountingExport maps plans, VAT codes and accounts to ledger codes
+	AccountingExport accountingexport.Mapping
+	// ConsolidatedMonthCacheSize is the number of consolidated months of
+	// billable events to keep in memory. Zero disables the cache.
+	ConsolidatedMonthCacheSize int
+	// QueryTimeouts overrides DefaultQueryTimeouts for the given routes
+	QueryTimeouts map[string]time.Duration
+	// MeteringBrokers are the brokers that can post metering requests
+	MeteringBrokers map[string]MeteringBroker
 }
 
-// CacheHeaders sets the cache headers to prevent caching
+// CacheHeaders sets the cache headers to prevent caching. Handlers that
+// serve immutable consolidated data may replace them.
 func CacheHeaders(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		c.Response().Header().Set("Cache-Control", "no-store")
@@ -72,14 +83,39 @@ func NewBaseServer(cfg Config) *echo.Echo {
 func New(cfg Config) *echo.Echo {
 
 	e := NewBaseServer(cfg)
+	e.Use(QueryTimeouts(cfg.QueryTimeouts))
 
 	e.GET("/vat_rates", VATRatesHandler(cfg.Store))
 	e.GET("/currency_rates", CurrencyRatesHandler(cfg.Store))
 	e.GET("/pricing_plans", PricingPlansHandler(cfg.Store))
 	e.GET("/forecast_events", ForecastEventsHandler(cfg.Store))
 	e.GET("/usage_events", UsageEventsHandler(cfg.Store, cfg.Authenticator))
-	e.GET("/billable_events", BillableEventsHandler(cfg.Store, cfg.Store, cfg.Authenticator))
+	e.GET("/billable_events", BillableEventsHandler(cfg.Store, cfg.Store, cfg.Authenticator, NewConsolidatedMonthCache(cfg.ConsolidatedMonthCacheSize)))
 	e.GET("/totals", TotalCostHandler(cfg.Store))
+	e.GET("/cost_timeseries", CostTimeSeriesHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/footprint", FootprintHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/footprint_plans", FootprintPlansHandler(cfg.Store))
+	e.GET("/allowances", AllowancesHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/statements", StatementsHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/statements", GenerateStatementsHandler(cfg.Store, cfg.Store, cfg.Authenticator))
+	e.GET("/statements/:statement_number", StatementHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/statements/:statement_number/credit_notes", CreditNoteHandler(cfg.Store, cfg.Store, cfg.Authenticator))
+	e.GET("/data_quality", DataQualityHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/unpriced_plans", UnpricedPlansHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/exemptions", ExemptionsHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/exemptions", AddExemptionHandler(cfg.Store, cfg.Authenticator))
+	e.DELETE("/exemptions/:exemption_guid", RemoveExemptionHandler(cfg.Store, cfg.Authenticator))
+	e.POST("/metering", MeteringHandler(cfg.Store, cfg.MeteringBrokers))
+	e.GET("/resources/:resource_guid/history", ResourceHistoryHandler(cfg.Store, cfg.Authenticator))
+	e.GET("/accounting_export", AccountingExportHandler(accountingexport.New(cfg.Store, cfg.AccountingExport), cfg.Store, cfg.Authenticator))
+
+	grafana := e.Group("/grafana")
+	grafana.GET("", EventStoreStatusHandler(cfg.Store))
+	grafana.GET("/", EventStoreStatusHandler(cfg.Store))
+	grafana.POST("/search", GrafanaSearchHandler(cfg.Authenticator))
+	grafana.POST("/query", GrafanaQueryHandler(cfg.Store, cfg.Authenticator))
+	grafana.POST("/annotations", GrafanaAnnotationsHandler(cfg.Store, cfg.Authenticator))
+	grafana.POST("/tag-keys", GrafanaTagKeysHandler(cfg.Authenticator))
 
 	return e
 }
@@ -0,0 +1,57 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alphagov/paas-billing/accountingexport"
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultAccountingExportFormat = "journal"
+
+// AccountingExportHandler renders the statements for a consolidated month as
+// a file that can be imported into the finance ledger
+func AccountingExportHandler(exporter *accountingexport.Exporter, consolidated eventio.ConsolidatedBillableEventReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := authorize(c, uaa, []string{}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		month := c.QueryParam("month")
+		filter, err := eventio.ParseStatementMonth(month)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		formatName := c.QueryParam("format")
+		if formatName == "" {
+			formatName = defaultAccountingExportFormat
+		}
+		format, err := accountingexport.GetFormat(formatName)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		isConsolidated, err := consolidated.IsRangeConsolidatedContext(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		if !isConsolidated {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s has not been consolidated yet", month))
+		}
+		// build the documents before sending any headers so mapping errors
+		// can be reported properly
+		documents, err := exporter.DocumentsContext(c.Request().Context(), month)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, err)
+		}
+		c.Response().Header().Set(echo.HeaderContentType, format.ContentType())
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(
+			`attachment; filename="paas-billing-%s-%s.%s"`, month, format.Name(), format.FileExtension(),
+		))
+		c.Response().WriteHeader(http.StatusOK)
+		return format.Write(c.Response(), exporter.Mapping, documents)
+	}
+}
@@ -0,0 +1,133 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+
+	"github.com/alphagov/paas-billing/accountingexport"
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AccountingExportHandler", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+	)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+			AccountingExport: accountingexport.Mapping{
+				DebtorsNominalCode: "1100",
+				VATNominalCode:     "2200",
+				DefaultNominalCode: "4000",
+				VATTaxCodes:        map[string]string{"Standard": "T1"},
+				CustomerRefs:       map[string]string{"account-1": "DEPT01"},
+			},
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeStore.IsRangeConsolidatedContextReturns(true, nil)
+		fakeStore.GetStatementsContextReturns([]eventio.Statement{{
+			StatementNumber: 42,
+			AccountID:       "account-1",
+			AccountName:     "Department of Examples",
+			CurrencyCode:    "GBP",
+			PeriodStart:     "2001-01-01",
+			IssuedAt:        "2001-02-06T00:00:00+00:00",
+			LineItems: []eventio.StatementLineItem{
+				{ResourceType: "app", PlanName: "app", VatCode: "Standard", VatRate: "0.2", ExVAT: "10.00"},
+			},
+			VAT: []eventio.StatementVAT{
+				{VatCode: "Standard", VatRate: "0.2", ExVAT: "10.00", VAT: "2.00"},
+			},
+		}}, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should only be available to admins", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := get("/accounting_export?month=2001-01")
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetStatementsContextCallCount()).To(Equal(0))
+	})
+
+	It("should default to the journal format", func() {
+		res := get("/accounting_export?month=2001-01")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("text/csv; charset=UTF-8"))
+		Expect(res.Header().Get("Content-Disposition")).To(Equal(`attachment; filename="paas-billing-2001-01-journal.csv"`))
+		Expect(res.Body.String()).To(Equal(
+			"date,reference,type,nominal_code,customer_ref,description,tax_code,debit,credit,currency\n" +
+				"2001-02-06,42,invoice,1100,DEPT01,Department of Examples,,12.00,,GBP\n" +
+				"2001-02-06,42,invoice,4000,DEPT01,app app Jan 2001,T1,,10.00,GBP\n" +
+				"2001-02-06,42,invoice,2200,DEPT01,VAT,T1,,2.00,GBP\n",
+		))
+	})
+
+	It("should reject an unknown format", func() {
+		res := get("/accounting_export?month=2001-01&format=abacus")
+
+		Expect(res.Code).To(Equal(400))
+	})
+
+	It("should refuse to export a month that is not consolidated", func() {
+		fakeStore.IsRangeConsolidatedContextReturns(false, nil)
+
+		res := get("/accounting_export?month=2001-01")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{"error": "2001-01 has not been consolidated yet"}`))
+	})
+
+	It("should report gaps in the mapping", func() {
+		cfg.AccountingExport.CustomerRefs = nil
+
+		res := get("/accounting_export?month=2001-01&format=sage50")
+
+		Expect(res.Code).To(Equal(422))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "accounting export mapping is incomplete: no customer reference for account account-1 (Department of Examples)"
+		}`))
+	})
+})
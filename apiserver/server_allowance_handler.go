@@ -0,0 +1,35 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+// AllowancesHandler lists how much of each allowance the requested orgs used
+// and have left in each calendar month starting in a range
+func AllowancesHandler(store eventio.AllowanceReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		filter := eventio.EventFilter{
+			RangeStart: c.QueryParam("range_start"),
+			RangeStop:  c.QueryParam("range_stop"),
+			OrgGUIDs:   requestedOrgs,
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		usage, err := store.GetAllowanceUsageContext(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, usage)
+	}
+}
@@ -0,0 +1,160 @@
+package apiserver_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AllowancesHandler", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID1          = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should return error if no token in request", func() {
+		fakeAuthenticator.NewAuthorizerReturns(nil, nil)
+		req := httptest.NewRequest(echo.GET, "/allowances?org_guid="+orgGUID1, nil)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "no access_token in request"
+		}`))
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetAllowanceUsageContextCallCount()).To(Equal(0))
+	})
+
+	It("should require billing access to the requested orgs", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+		req := httptest.NewRequest(echo.GET, "/allowances?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-02-01", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetAllowanceUsageContextCallCount()).To(Equal(0))
+	})
+
+	It("should reject an invalid range", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		req := httptest.NewRequest(echo.GET, "/allowances?org_guid="+orgGUID1+"&range_start=January", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetAllowanceUsageContextCallCount()).To(Equal(0))
+	})
+
+	It("should return the allowance usage of the requested orgs when manager", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetAllowanceUsageContextReturns([]eventio.AllowanceUsage{
+			{
+				OrgGUID:   orgGUID1,
+				Name:      "free compute",
+				Unit:      "GB-hours",
+				Month:     "2001-01-01T00:00:00+00:00",
+				Amount:    "100",
+				Used:      "40",
+				Remaining: "60",
+			},
+		}, nil)
+
+		req := httptest.NewRequest(echo.GET, "/allowances?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-02-01", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID1}))
+		Expect(fakeStore.GetAllowanceUsageContextCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetAllowanceUsageContextArgsForCall(0)
+		Expect(filter.RangeStart).To(Equal("2001-01-01"))
+		Expect(filter.RangeStop).To(Equal("2001-02-01"))
+		Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1}))
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+		Expect(res.Body).To(MatchJSON(`[{
+			"org_guid": "` + orgGUID1 + `",
+			"name": "free compute",
+			"unit": "GB-hours",
+			"month": "2001-01-01T00:00:00+00:00",
+			"amount": "100",
+			"used": "40",
+			"remaining": "60"
+		}]`))
+	})
+
+	It("should return error if GetAllowanceUsage returns error", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeStore.GetAllowanceUsageContextReturns(nil, errors.New("query-error"))
+
+		req := httptest.NewRequest(echo.GET, "/allowances?range_start=2001-01-01&range_stop=2001-02-01", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "internal server error"
+		}`))
+		Expect(res.Code).To(Equal(500))
+	})
+
+})
@@ -3,8 +3,10 @@ package apiserver
 import (
 	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
 	"github.com/labstack/echo/v4"
 )
 
@@ -40,3 +42,64 @@ func authorize(c echo.Context, uaa auth.Authenticator, orgs []string) (bool, err
 	}
 	return false, errors.New("you need to be billing_manager or an administrator to retrieve the billing data")
 }
+
+// authorizeAnyOrg is authorize for data shared by several orgs, such as the
+// statement of a billing account, which billing managers of any one of the
+// orgs can see
+func authorizeAnyOrg(c echo.Context, uaa auth.Authenticator, orgs []string) (bool, error) {
+	if len(orgs) == 0 {
+		return authorize(c, uaa, orgs)
+	}
+	var err error
+	for _, org := range orgs {
+		var ok bool
+		if ok, err = authorize(c, uaa, []string{org}); ok {
+			return true, nil
+		}
+	}
+	return false, err
+}
+
+// authorizeAnyBillingAccess is authorize for data that is not specific to an
+// org, such as the names of the Grafana targets, which any billing manager or
+// org manager can see
+func authorizeAnyBillingAccess(c echo.Context, uaa auth.Authenticator) (bool, error) {
+	token, err := auth.GetTokenFromRequest(c)
+	if err != nil {
+		return false, err
+	}
+	authorizer, err := uaa.NewAuthorizer(token)
+	if err != nil {
+		return false, err
+	}
+
+	isAdmin, err := authorizer.Admin()
+	if err != nil {
+		return false, fmt.Errorf("invalid credentials: %s", err)
+	}
+	if isAdmin {
+		return true, nil
+	}
+
+	hasBillingAccess, err := authorizer.HasAnyBillingAccess()
+	if err != nil {
+		return false, fmt.Errorf("invalid credentials: %s", err)
+	}
+	if hasBillingAccess {
+		return true, nil
+	}
+	return false, errors.New("you need to be billing_manager or an administrator to retrieve the billing data")
+}
+
+// authorizeAggregate only lets admins turn off aggregation, as individual
+// events of aggregated resource types are not part of the billing data
+// shown to billing managers
+func authorizeAggregate(c echo.Context, uaa auth.Authenticator, filter eventio.EventFilter) error {
+	if filter.Aggregate != eventio.AggregateNone {
+		return nil
+	}
+	if ok, err := authorize(c, uaa, []string{}); err != nil || !ok {
+		return echo.NewHTTPError(http.StatusForbidden, "only administrators can request aggregate=none")
+	}
+	return nil
+}
@@ -2,31 +2,18 @@ package apiserver
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"net/http"
-	"strconv"
-	"time"
 
 	"github.com/alphagov/paas-billing/apiserver/auth"
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/labstack/echo/v4"
 )
 
-func BillableEventsHandler(store eventio.BillableEventReader, consolidatedStore eventio.ConsolidatedBillableEventReader, uaa auth.Authenticator) echo.HandlerFunc {
+// BillableEventsHandler streams the billable events for a range. Responses
+// made up entirely of consolidated months get an ETag and may be cached by
+// the client; each month's events are also kept in cache, if it is not nil.
+func BillableEventsHandler(store eventio.BillableEventReader, consolidatedStore eventio.ConsolidatedBillableEventReader, uaa auth.Authenticator, cache *ConsolidatedMonthCache) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		sentOKHeader := false
-		sendOKHeader := func() error {
-			c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
-			c.Response().WriteHeader(http.StatusOK)
-			if _, err := c.Response().Write([]byte("[\n")); err != nil {
-				return err
-			}
-			c.Response().Flush()
-			sentOKHeader = true
-			return nil
-		}
-
 		requestedOrgs := c.Request().URL.Query()["org_guid"]
 		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
 			return echo.NewHTTPError(http.StatusUnauthorized, err)
@@ -34,16 +21,25 @@ func BillableEventsHandler(store eventio.BillableEventReader, consolidatedStore
 			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 		}
 		// parse params
+		format, err := negotiateFormat(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
 		filter := eventio.EventFilter{
-			RangeStart: c.QueryParam("range_start"),
-			RangeStop:  c.QueryParam("range_stop"),
-			OrgGUIDs:   requestedOrgs,
+			RangeStart:    c.QueryParam("range_start"),
+			RangeStop:     c.QueryParam("range_stop"),
+			OrgGUIDs:      requestedOrgs,
+			Aggregate:     c.QueryParam("aggregate"),
+			LabelSelector: c.QueryParam("label_selector"),
 		}
 		if err := filter.Validate(); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
+		if err := authorizeAggregate(c, uaa, filter); err != nil {
+			return err
+		}
 
-		storeCtx, cancel := context.WithCancel(context.Background())
+		storeCtx, cancel := context.WithCancel(c.Request().Context())
 		defer cancel()
 
 		months, err := filter.SplitByMonth()
@@ -51,143 +47,71 @@ func BillableEventsHandler(store eventio.BillableEventReader, consolidatedStore
 			return err
 		}
 
-		delim := ""
-		for _, monthFilter := range months {
-			err := func() error { // so we can use defer in-loop
-				isConsolidated, err := consolidatedStore.IsRangeConsolidated(monthFilter)
+		records, err := consolidationRecordsFor(storeCtx, consolidatedStore, filter, months)
+		if err != nil {
+			return err
+		}
+		if records != nil {
+			if done, err := notModified(c, consolidatedETag(billableEventTable.name, records, filter, format)); done {
+				return err
+			}
+		}
+
+		enc := newEventEncoder(c, format, billableEventTable)
+		// months are fetched concurrently but written in order
+		fetch := func(ctx context.Context, i int, monthFilter eventio.EventFilter, emit func(eventJSON []byte) error) error {
+			var rows eventio.BillableEventRows
+			var cacheKey string
+			var recorded [][]byte // non-nil when the month should be added to the cache
+
+			if records != nil {
+				cacheKey = consolidatedMonthCacheKey(monthFilter, records[i])
+				if events, ok := cache.get(cacheKey); ok {
+					rows = &cachedBillableEventRows{events: events}
+				}
+			}
+			if rows == nil {
+				isConsolidated, err := consolidatedStore.IsRangeConsolidatedContext(ctx, monthFilter)
 				if err != nil {
 					return err
 				}
-				var rows eventio.BillableEventRows
-
 				if isConsolidated {
-					rows, err = consolidatedStore.GetConsolidatedBillableEventRows(storeCtx, monthFilter)
+					rows, err = consolidatedStore.GetConsolidatedBillableEventRows(ctx, monthFilter)
+					if records != nil && cache != nil {
+						recorded = [][]byte{}
+					}
 				} else {
-					rows, err = store.GetBillableEventRows(storeCtx, monthFilter)
+					rows, err = store.GetBillableEventRows(ctx, monthFilter)
 				}
 				if err != nil {
 					return err
 				}
-				defer rows.Close()
-
-				// Assume rows is a slice of event data
-				taskEvents := make(map[string]*eventio.BillableEvent)
-
-				next := rows.Next()
-				for next {
-					b, err := rows.EventJSON()
-
-					// Check if the resource type is "task"
-					row, err := rows.Event()
-
-					if row != nil && row.ResourceType == "task" {
-						// Set the key as a combination of Org GUID and Space GUID
-						key := fmt.Sprintf("%s-%s", row.OrgGUID, row.SpaceGUID)
-
-						// Convert the price values to float
-						priceInc, _ := strconv.ParseFloat(row.Price.IncVAT, 64)
-						priceEx, _ := strconv.ParseFloat(row.Price.ExVAT, 64)
-
-						event, exists := taskEvents[key]
-						if !exists {
-							const layout = "2006-01-02"
-
-							rangeStart, _ := time.Parse(layout, c.QueryParam("range_start"))
-							rangeStop, _ := time.Parse(layout, c.QueryParam("range_stop"))
-
-							event = &eventio.BillableEvent{
-								EventGUID:           row.EventGUID,
-								EventStart:          rangeStart.Format("2006-01-02T00:00:00+00:00"),
-								EventStop:           rangeStop.Format("2006-01-02T00:00:00+00:00"),
-								ResourceGUID:        row.ResourceGUID,
-								ResourceName:        "Total Task Events",
-								ResourceType:        "task",
-								OrgGUID:             row.OrgGUID,
-								OrgName:             row.OrgName,
-								SpaceGUID:           row.SpaceGUID,
-								SpaceName:           row.SpaceName,
-								PlanGUID:            row.PlanGUID,
-								PlanName:            row.PlanName,
-								QuotaDefinitionGUID: row.QuotaDefinitionGUID,
-								Price: eventio.Price{
-									Details: []eventio.PriceComponent{{
-										Name:         "All tasks aggregated",
-										PlanName:     "tasks",
-										Start:        rangeStart.Format("2006-01-02T00:00:00+00:00"),
-										Stop:         rangeStop.Format("2006-01-02T00:00:00+00:00"),
-										CurrencyCode: "USD",
-										VatRate:      "0.2",
-									}},
-									FloatIncVAT: priceInc,
-									FloatExVAT:  priceEx,
-								},
-							}
-							taskEvents[key] = event
-						} else {
-							// Add this priceInc to event.Price.IncVAT
-							event.Price.FloatIncVAT = event.Price.FloatIncVAT + priceInc
-							event.Price.FloatExVAT = event.Price.FloatExVAT + priceEx
-						}
-
-						// Skip the event as we will group them all into one event at the end
-						next = rows.Next()
-						continue
-					}
+			}
+			defer rows.Close()
 
-					if err != nil {
-						return err
-					}
-					if !sentOKHeader {
-						// this is sent as late as possible because any errors encountered after
-						// this won't be communicated to the client correctly
-						if err := sendOKHeader(); err != nil {
-							return err
-						}
-					}
-					if _, err := c.Response().Write([]byte(delim)); err != nil {
-						return err
-					}
-					if _, err := c.Response().Write(b); err != nil {
-						return err
-					}
-					delim = ",\n"
-					next = rows.Next()
-					c.Response().Flush()
+			for rows.Next() {
+				b, err := rows.EventJSON()
+				if err != nil {
+					return err
 				}
-				// loop over each task event and send it
-				for _, event := range taskEvents {
-					event.Price.IncVAT = fmt.Sprintf("%.16f", event.Price.FloatIncVAT)
-					event.Price.ExVAT = fmt.Sprintf("%.16f", event.Price.FloatExVAT)
-					event.Price.Details[0].IncVAT = fmt.Sprintf("%.16f", event.Price.FloatIncVAT)
-					event.Price.Details[0].ExVAT = fmt.Sprintf("%.16f", event.Price.FloatExVAT)
-					b, err := json.Marshal(event)
-					if err != nil {
-						return err
-					}
-					// send the delimiter
-					if _, err := c.Response().Write([]byte(",\n")); err != nil {
-						return err
-					}
-					if _, err := c.Response().Write(b); err != nil {
-						return err
-					}
-					c.Response().Flush()
+				if recorded != nil {
+					recorded = append(recorded, b)
+				}
+				if err := emit(b); err != nil {
+					return err
 				}
-
-				return nil
-			}()
-			if err != nil {
-				return err
 			}
-		}
-		if !sentOKHeader {
-			if err := sendOKHeader(); err != nil {
+			if err := rows.Err(); err != nil {
 				return err
 			}
+			if recorded != nil {
+				cache.add(cacheKey, recorded)
+			}
+			return nil
 		}
-		if _, err := c.Response().Write([]byte("\n]\n")); err != nil {
+		if err := streamMonths(storeCtx, months, maxConcurrentMonthFetches, fetch, enc.Encode); err != nil {
 			return err
 		}
-		return nil
+		return enc.End()
 	}
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
 	"github.com/alphagov/paas-billing/eventio/eventiofakes"
@@ -31,7 +34,6 @@ var _ = Describe("BillableEventsHandler", func() {
 		fakeStore         *eventiofakes.FakeEventStore
 		token             = "ACCESS_GRANTED_TOKEN"
 		orgGUID1          = "f5f32499-db32-4ab7-a314-20cbe3e49080"
-		orgGUID2          = "3f8c7d62-7077-4f42-8d7d-93dce9be2e95"
 	)
 
 	BeforeEach(func(specContext SpecContext) {
@@ -260,10 +262,10 @@ var _ = Describe("BillableEventsHandler", func() {
 		fakeRows := &eventiofakes.FakeBillableEventRows{}
 		fakeRows.CloseReturns(nil)
 		fakeRows.NextReturns(false)
-		fakeStore.IsRangeConsolidatedReturnsOnCall(0, false, nil)
-		fakeStore.IsRangeConsolidatedReturnsOnCall(1, true, nil)
-		fakeStore.IsRangeConsolidatedReturnsOnCall(2, true, nil)
-		fakeStore.IsRangeConsolidatedReturnsOnCall(3, false, nil)
+		// months are fetched concurrently so answer by month, not call order
+		fakeStore.IsRangeConsolidatedContextStub = func(_ context.Context, filter eventio.EventFilter) (bool, error) {
+			return filter.RangeStart == "2001-02-01" || filter.RangeStart == "2001-03-01", nil
+		}
 		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
 		fakeStore.GetConsolidatedBillableEventRowsReturns(fakeRows, nil)
 
@@ -288,18 +290,14 @@ var _ = Describe("BillableEventsHandler", func() {
 		_, filter2 := fakeStore.GetConsolidatedBillableEventRowsArgsForCall(0)
 		_, filter3 := fakeStore.GetConsolidatedBillableEventRowsArgsForCall(1)
 		_, filter4 := fakeStore.GetBillableEventRowsArgsForCall(1)
-		receivedFilters := []eventio.EventFilter{
-			filter1,
-			filter2,
-			filter3,
-			filter4,
-		}
-		Expect(receivedFilters).To(Equal([]eventio.EventFilter{
-			{RangeStart: "2001-01-15", RangeStop: "2001-02-01", OrgGUIDs: []string{orgGUID1}},
-			{RangeStart: "2001-02-01", RangeStop: "2001-03-01", OrgGUIDs: []string{orgGUID1}},
-			{RangeStart: "2001-03-01", RangeStop: "2001-04-01", OrgGUIDs: []string{orgGUID1}},
-			{RangeStart: "2001-04-01", RangeStop: "2001-04-15", OrgGUIDs: []string{orgGUID1}},
-		}))
+		Expect([]eventio.EventFilter{filter1, filter4}).To(ConsistOf(
+			eventio.EventFilter{RangeStart: "2001-01-15", RangeStop: "2001-02-01", OrgGUIDs: []string{orgGUID1}},
+			eventio.EventFilter{RangeStart: "2001-04-01", RangeStop: "2001-04-15", OrgGUIDs: []string{orgGUID1}},
+		))
+		Expect([]eventio.EventFilter{filter2, filter3}).To(ConsistOf(
+			eventio.EventFilter{RangeStart: "2001-02-01", RangeStop: "2001-03-01", OrgGUIDs: []string{orgGUID1}},
+			eventio.EventFilter{RangeStart: "2001-03-01", RangeStop: "2001-04-01", OrgGUIDs: []string{orgGUID1}},
+		))
 
 		Expect(res.Code).To(Equal(200))
 		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
@@ -338,7 +336,7 @@ var _ = Describe("BillableEventsHandler", func() {
 		}`
 		fakeRows.EventJSONReturnsOnCall(0, []byte(event1JSON), nil)
 		fakeRows.EventJSONReturnsOnCall(1, []byte(event2JSON), nil)
-		fakeStore.IsRangeConsolidatedReturns(true, nil)
+		fakeStore.IsRangeConsolidatedContextReturns(true, nil)
 		fakeStore.GetConsolidatedBillableEventRowsReturns(fakeRows, nil)
 
 		u := url.URL{}
@@ -402,56 +400,20 @@ var _ = Describe("BillableEventsHandler", func() {
 		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
 	})
 
-	It("should aggregate task events if there are any", func() {
+	It("should stream task events as the store returns them", func() {
 		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
 		fakeAuthorizer.AdminReturns(true, nil)
-		fakeAuthorizer.HasBillingAccessReturns(false, nil)
 		fakeRows := &eventiofakes.FakeBillableEventRows{}
-		fakeRows.CloseReturns(nil)
 		fakeRows.NextReturnsOnCall(0, true)
 		fakeRows.NextReturnsOnCall(1, true)
 		fakeRows.NextReturnsOnCall(2, false)
-		fakePrice := eventio.Price{
-			IncVAT:  "10.0000000000000000",
-			ExVAT:   "8.0000000000000000",
-			Details: []eventio.PriceComponent{},
-		}
-		fakeAppEvent := &eventio.BillableEvent{
-			OrgGUID:      orgGUID1,
-			OrgName:      "org-1",
-			ResourceType: "app",
-			ResourceName: "app-1",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price:        fakePrice,
-		}
-		fakeTaskEvent := &eventio.BillableEvent{
-			OrgGUID:      orgGUID1,
-			OrgName:      "org-1",
-			ResourceType: "task",
-			ResourceName: "task-1",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price:        fakePrice,
-		}
-		fakeRows.EventReturnsOnCall(0, fakeAppEvent, nil)
-		fakeRows.EventReturnsOnCall(1, fakeTaskEvent, nil)
-		fakeRows.EventReturnsOnCall(2, fakeTaskEvent, nil)
-		eventAppJSON, _ := json.MarshalIndent(fakeAppEvent, "", "  ")
-		eventTaskJSON, _ := json.MarshalIndent(fakeTaskEvent, "", "  ")
-		fakeRows.EventJSONReturnsOnCall(0, []byte(eventAppJSON), nil)
-		fakeRows.EventJSONReturnsOnCall(1, []byte(eventTaskJSON), nil)
-		fakeRows.EventJSONReturnsOnCall(2, []byte(eventTaskJSON), nil)
+		appEventJSON := `{"event_guid": "app-event-guid", "resource_type": "app"}`
+		taskEventJSON := `{"event_guid": "task-event-guid", "resource_type": "task", "resource_name": "Total Task Events"}`
+		fakeRows.EventJSONReturnsOnCall(0, []byte(appEventJSON), nil)
+		fakeRows.EventJSONReturnsOnCall(1, []byte(taskEventJSON), nil)
 		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
 
-		u := url.URL{}
-		u.Path = "/billable_events"
-		q := u.Query()
-		q.Set("org_guid", orgGUID1)
-		q.Set("range_start", "2001-01-01")
-		q.Set("range_stop", "2001-01-02")
-		u.RawQuery = q.Encode()
-		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req := httptest.NewRequest(echo.GET, "/billable_events?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02", nil)
 		req.Header.Set("Authorization", "bearer "+token)
 		res := httptest.NewRecorder()
 
@@ -461,146 +423,40 @@ var _ = Describe("BillableEventsHandler", func() {
 
 		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(1))
 		_, filter := fakeStore.GetBillableEventRowsArgsForCall(0)
-		Expect(filter.RangeStart).To(Equal("2001-01-01"))
-		Expect(filter.RangeStop).To(Equal("2001-01-02"))
-		Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1}))
+		Expect(filter.Aggregate).To(Equal(eventio.AggregateDefault))
 
-		Expect(fakeRows.NextCallCount()).To(Equal(3))
-		Expect(fakeRows.EventJSONCallCount()).To(Equal(2))
-		Expect(fakeRows.CloseCallCount()).To(Equal(1))
+		Expect(fakeRows.EventCallCount()).To(Equal(0))
+		Expect(res.Body).To(MatchJSON("[" + appEventJSON + "," + taskEventJSON + "]"))
+		Expect(res.Code).To(Equal(200))
+	})
 
-		aggregateTaskEvent := &eventio.BillableEvent{
-			OrgGUID:      orgGUID1,
-			OrgName:      "org-1",
-			EventStart:   "2001-01-01T00:00:00+00:00",
-			EventStop:    "2001-01-02T00:00:00+00:00",
-			ResourceName: "Total Task Events",
-			ResourceType: "task",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price: eventio.Price{
-				IncVAT: "10.0000000000000000",
-				ExVAT:  "8.0000000000000000",
-				Details: []eventio.PriceComponent{
-					{
-						Name:         "All tasks aggregated",
-						Start:        "2001-01-01T00:00:00+00:00",
-						Stop:         "2001-01-02T00:00:00+00:00",
-						IncVAT:       "10.0000000000000000",
-						ExVAT:        "8.0000000000000000",
-						CurrencyCode: "USD",
-						VatRate:      "0.2",
-						PlanName:     "tasks",
-					},
-				},
-			},
-		}
-		events := []*eventio.BillableEvent{
-			fakeAppEvent,
-			aggregateTaskEvent,
-		}
+	It("should let admins turn off aggregation", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeStore.GetBillableEventRowsReturns(&eventiofakes.FakeBillableEventRows{}, nil)
 
-		aggregateJSON, _ := json.MarshalIndent(events, "", "  ")
+		req := httptest.NewRequest(echo.GET, "/billable_events?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02&aggregate=none", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
 
-		Expect(res.Body).To(MatchJSON(string(aggregateJSON)))
 		Expect(res.Code).To(Equal(200))
-		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetBillableEventRowsArgsForCall(0)
+		Expect(filter.Aggregate).To(Equal(eventio.AggregateNone))
 	})
 
-	It("should aggregate task events per org", func() {
+	It("should not let billing managers turn off aggregation", func() {
 		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
-		fakeAuthorizer.AdminReturns(true, nil)
-		fakeAuthorizer.HasBillingAccessReturns(false, nil)
-		fakeRows := &eventiofakes.FakeBillableEventRows{}
-		fakeRows.CloseReturns(nil)
-		fakeRows.NextReturnsOnCall(0, true)
-		fakeRows.NextReturnsOnCall(1, true)
-		fakeRows.NextReturnsOnCall(2, true)
-		fakeRows.NextReturnsOnCall(3, true)
-		fakeRows.NextReturnsOnCall(4, true)
-		fakeRows.NextReturnsOnCall(5, false)
-		// org1: 1 app, 1 task
-		// org2: 1 app, 2 tasks
-		fakePriceOrg1 := eventio.Price{
-			IncVAT:  "10.0000000000000000",
-			ExVAT:   "8.0000000000000000",
-			Details: []eventio.PriceComponent{},
-		}
-		fakePriceOrg2 := eventio.Price{
-			IncVAT:  "5.00",
-			ExVAT:   "4.00",
-			Details: []eventio.PriceComponent{},
-		}
-		fakeAppEventOrg1 := &eventio.BillableEvent{
-			OrgGUID:      orgGUID1,
-			OrgName:      "org-1",
-			ResourceType: "app",
-			ResourceName: "app-1",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price:        fakePriceOrg1,
-		}
-		fakeTaskEvent1Org1 := &eventio.BillableEvent{
-			OrgGUID:      orgGUID1,
-			OrgName:      "org-1",
-			ResourceType: "task",
-			ResourceName: "task-1",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price:        fakePriceOrg1,
-		}
-		fakeAppEventOrg2 := &eventio.BillableEvent{
-			OrgGUID:      orgGUID2,
-			OrgName:      "org-2",
-			ResourceType: "app",
-			ResourceName: "app-1",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price:        fakePriceOrg2,
-		}
-		fakeTaskEvent1Org2 := &eventio.BillableEvent{
-			OrgGUID:      orgGUID2,
-			OrgName:      "org-2",
-			ResourceType: "task",
-			ResourceName: "task-1",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price:        fakePriceOrg2,
-		}
-		fakeTaskEvent2Org2 := &eventio.BillableEvent{
-			OrgGUID:      orgGUID2,
-			OrgName:      "org-2",
-			ResourceType: "task",
-			ResourceName: "task-2",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price:        fakePriceOrg2,
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessStub = func(orgs []string) (bool, error) {
+			return len(orgs) > 0, nil
 		}
-		fakeRows.EventReturnsOnCall(0, fakeAppEventOrg1, nil)
-		fakeRows.EventReturnsOnCall(1, fakeTaskEvent1Org1, nil)
-		fakeRows.EventReturnsOnCall(2, fakeAppEventOrg2, nil)
-		fakeRows.EventReturnsOnCall(3, fakeTaskEvent1Org2, nil)
-		fakeRows.EventReturnsOnCall(4, fakeTaskEvent2Org2, nil)
-		eventAppOrg1JSON, _ := json.MarshalIndent(fakeAppEventOrg1, "", "  ")
-		eventTask1Org1JSON, _ := json.MarshalIndent(fakeTaskEvent1Org1, "", "  ")
-		eventAppOrg2JSON, _ := json.MarshalIndent(fakeAppEventOrg2, "", "  ")
-		eventTask1Org2JSON, _ := json.MarshalIndent(fakeTaskEvent1Org2, "", "  ")
-		eventTask2Org2JSON, _ := json.MarshalIndent(fakeTaskEvent1Org2, "", "  ")
-		fakeRows.EventJSONReturnsOnCall(0, []byte(eventAppOrg1JSON), nil)
-		fakeRows.EventJSONReturnsOnCall(1, []byte(eventTask1Org1JSON), nil)
-		fakeRows.EventJSONReturnsOnCall(2, []byte(eventAppOrg2JSON), nil)
-		fakeRows.EventJSONReturnsOnCall(3, []byte(eventTask1Org2JSON), nil)
-		fakeRows.EventJSONReturnsOnCall(4, []byte(eventTask2Org2JSON), nil)
-		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
 
-		u := url.URL{}
-		u.Path = "/billable_events"
-		q := u.Query()
-		q.Set("org_guid", orgGUID1+","+orgGUID2)
-		q.Set("range_start", "2001-01-01")
-		q.Set("range_stop", "2001-01-02")
-		u.RawQuery = q.Encode()
-		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req := httptest.NewRequest(echo.GET, "/billable_events?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02&aggregate=none", nil)
 		req.Header.Set("Authorization", "bearer "+token)
 		res := httptest.NewRecorder()
 
@@ -608,82 +464,288 @@ var _ = Describe("BillableEventsHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(1))
-		_, filter := fakeStore.GetBillableEventRowsArgsForCall(0)
-		Expect(filter.RangeStart).To(Equal("2001-01-01"))
-		Expect(filter.RangeStop).To(Equal("2001-01-02"))
-		Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1 + "," + orgGUID2}))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "only administrators can request aggregate=none"
+		}`))
+		Expect(res.Code).To(Equal(403))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(0))
+	})
 
-		Expect(fakeRows.NextCallCount()).To(Equal(6))
-		Expect(fakeRows.EventJSONCallCount()).To(Equal(5))
-		Expect(fakeRows.CloseCallCount()).To(Equal(1))
+	It("should reject unknown aggregations", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
 
-		// org1: 1 app, 1 task
-		// org2: 1 app, 2 tasks
-		taskEventsOrg1 := &eventio.BillableEvent{
-			OrgGUID:      orgGUID1,
-			OrgName:      "org-1",
-			EventStart:   "2001-01-01T00:00:00+00:00",
-			EventStop:    "2001-01-02T00:00:00+00:00",
-			ResourceName: "Total Task Events",
-			ResourceType: "task",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price: eventio.Price{
-				IncVAT: "10.0000000000000000",
-				ExVAT:  "8.0000000000000000",
-				Details: []eventio.PriceComponent{
-					{
-						Name:         "All tasks aggregated",
-						Start:        "2001-01-01T00:00:00+00:00",
-						Stop:         "2001-01-02T00:00:00+00:00",
-						IncVAT:       "10.0000000000000000",
-						ExVAT:        "8.0000000000000000",
-						CurrencyCode: "USD",
-						VatRate:      "0.2",
-						PlanName:     "tasks",
-					},
-				},
-			},
-		}
-		taskEventsOrg2 := &eventio.BillableEvent{
-			OrgGUID:      orgGUID2,
-			OrgName:      "org-2",
-			EventStart:   "2001-01-01T00:00:00+00:00",
-			EventStop:    "2001-01-02T00:00:00+00:00",
-			ResourceName: "Total Task Events",
-			ResourceType: "task",
-			SpaceGUID:    "space-guid",
-			SpaceName:    "all",
-			Price: eventio.Price{
-				IncVAT: "10.0000000000000000",
-				ExVAT:  "8.0000000000000000",
-				Details: []eventio.PriceComponent{
-					{
-						Name:         "All tasks aggregated",
-						Start:        "2001-01-01T00:00:00+00:00",
-						Stop:         "2001-01-02T00:00:00+00:00",
-						IncVAT:       "10.0000000000000000",
-						ExVAT:        "8.0000000000000000",
-						CurrencyCode: "USD",
-						VatRate:      "0.2",
-						PlanName:     "tasks",
-					},
-				},
-			},
+		req := httptest.NewRequest(echo.GET, "/billable_events?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02&aggregate=daily", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(0))
+	})
+
+	Describe("caching consolidated months", func() {
+		var (
+			consolidatedAt time.Time
+			eventJSON      string
+		)
+
+		newRequest := func(rangeStop string) *http.Request {
+			u := url.URL{}
+			u.Path = "/billable_events"
+			q := u.Query()
+			q.Set("org_guid", orgGUID1)
+			q.Set("range_start", "2001-01-01")
+			q.Set("range_stop", rangeStop)
+			u.RawQuery = q.Encode()
+			req := httptest.NewRequest(echo.GET, u.String(), nil)
+			req.Header.Set("Authorization", "bearer "+token)
+			return req
 		}
-		events := []*eventio.BillableEvent{
-			fakeAppEventOrg1,
-			fakeAppEventOrg2,
-			taskEventsOrg1,
-			taskEventsOrg2,
+
+		BeforeEach(func() {
+			fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+			fakeAuthorizer.HasBillingAccessReturns(true, nil)
+			consolidatedAt = time.Date(2001, 2, 5, 1, 2, 3, 0, time.UTC)
+			eventJSON = `{"event_guid": "consolidated-guid", "resource_type": "app"}`
+			fakeStore.IsRangeConsolidatedContextReturns(true, nil)
+			fakeStore.GetConsolidationHistoryContextStub = func(_ context.Context, filter eventio.EventFilter) ([]eventio.ConsolidationRecord, error) {
+				return []eventio.ConsolidationRecord{
+					{RangeStart: "2001-01-01", RangeStop: "2001-02-01", CreatedAt: consolidatedAt},
+				}, nil
+			}
+			fakeStore.GetConsolidatedBillableEventRowsStub = func(ctx context.Context, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+				fakeRows := &eventiofakes.FakeBillableEventRows{}
+				fakeRows.NextReturnsOnCall(0, true)
+				fakeRows.EventJSONReturns([]byte(eventJSON), nil)
+				fakeRows.EventReturns(&eventio.BillableEvent{ResourceType: "app"}, nil)
+				return fakeRows, nil
+			}
+			fakeStore.GetBillableEventRowsReturns(&eventiofakes.FakeBillableEventRows{}, nil)
+		})
+
+		It("should allow clients to cache wholly consolidated ranges", func() {
+			res := httptest.NewRecorder()
+			e := New(cfg)
+			e.ServeHTTP(res, newRequest("2001-02-01"))
+			defer e.Shutdown(ctx)
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Body).To(MatchJSON("[" + eventJSON + "]"))
+			Expect(res.Header().Get("ETag")).To(MatchRegexp(`^"[0-9a-f]{64}"$`))
+			Expect(res.Header().Get("Cache-Control")).To(Equal("private, max-age=86400"))
+			Expect(res.Header().Get("Vary")).To(Equal("Accept, Authorization"))
+			Expect(res.Header().Get("Pragma")).To(BeEmpty())
+			Expect(res.Header().Get("Expires")).To(BeEmpty())
+		})
+
+		It("should change the ETag when the month is consolidated again", func() {
+			e := New(cfg)
+			defer e.Shutdown(ctx)
+
+			res1 := httptest.NewRecorder()
+			e.ServeHTTP(res1, newRequest("2001-02-01"))
+			consolidatedAt = consolidatedAt.Add(time.Hour)
+			res2 := httptest.NewRecorder()
+			e.ServeHTTP(res2, newRequest("2001-02-01"))
+
+			Expect(res1.Header().Get("ETag")).ToNot(BeEmpty())
+			Expect(res2.Header().Get("ETag")).ToNot(Equal(res1.Header().Get("ETag")))
+		})
+
+		It("should respond 304 Not Modified without querying events when the ETag matches", func() {
+			e := New(cfg)
+			defer e.Shutdown(ctx)
+
+			res := httptest.NewRecorder()
+			e.ServeHTTP(res, newRequest("2001-02-01"))
+			etag := res.Header().Get("ETag")
+
+			req := newRequest("2001-02-01")
+			req.Header.Set("If-None-Match", `"other", W/`+etag)
+			res = httptest.NewRecorder()
+			e.ServeHTTP(res, req)
+
+			Expect(res.Code).To(Equal(304))
+			Expect(res.Body.Len()).To(Equal(0))
+			Expect(res.Header().Get("ETag")).To(Equal(etag))
+			Expect(fakeStore.GetConsolidatedBillableEventRowsCallCount()).To(Equal(1))
+		})
+
+		It("should still require authorization before responding 304", func() {
+			fakeAuthorizer.HasBillingAccessReturns(false, nil)
+			req := newRequest("2001-02-01")
+			req.Header.Set("If-None-Match", "*")
+			res := httptest.NewRecorder()
+			e := New(cfg)
+			e.ServeHTTP(res, req)
+			defer e.Shutdown(ctx)
+
+			Expect(res.Code).To(Equal(401))
+			Expect(fakeStore.GetConsolidationHistoryContextCallCount()).To(Equal(0))
+		})
+
+		It("should not allow caching of ranges that include live data", func() {
+			req := newRequest("2001-02-15")
+			req.Header.Set("If-None-Match", "*")
+			res := httptest.NewRecorder()
+			e := New(cfg)
+			e.ServeHTTP(res, req)
+			defer e.Shutdown(ctx)
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Header().Get("ETag")).To(BeEmpty())
+			Expect(res.Header().Get("Cache-Control")).To(Equal("no-store"))
+			Expect(res.Header().Get("Pragma")).To(Equal("no-cache"))
+		})
+
+		It("should serve consolidated months from memory when the cache is enabled", func() {
+			cfg.ConsolidatedMonthCacheSize = 1
+			e := New(cfg)
+			defer e.Shutdown(ctx)
+
+			res1 := httptest.NewRecorder()
+			e.ServeHTTP(res1, newRequest("2001-02-01"))
+			res2 := httptest.NewRecorder()
+			e.ServeHTTP(res2, newRequest("2001-02-01"))
+
+			Expect(res2.Code).To(Equal(200))
+			Expect(res2.Body.String()).To(Equal(res1.Body.String()))
+			Expect(fakeStore.GetConsolidatedBillableEventRowsCallCount()).To(Equal(1))
+
+			consolidatedAt = consolidatedAt.Add(time.Hour)
+			res3 := httptest.NewRecorder()
+			e.ServeHTTP(res3, newRequest("2001-02-01"))
+			Expect(fakeStore.GetConsolidatedBillableEventRowsCallCount()).To(Equal(2), "a new consolidation must not be served from the cache")
+		})
+
+		It("should not cache months when the cache is disabled", func() {
+			e := New(cfg)
+			defer e.Shutdown(ctx)
+
+			e.ServeHTTP(httptest.NewRecorder(), newRequest("2001-02-01"))
+			e.ServeHTTP(httptest.NewRecorder(), newRequest("2001-02-01"))
+
+			Expect(fakeStore.GetConsolidatedBillableEventRowsCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("fetching months concurrently", func() {
+		rowsOf := func(eventJSON ...string) *eventiofakes.FakeBillableEventRows {
+			fakeRows := &eventiofakes.FakeBillableEventRows{}
+			for i, ev := range eventJSON {
+				fakeRows.NextReturnsOnCall(i, true)
+				fakeRows.EventJSONReturnsOnCall(i, []byte(ev), nil)
+				fakeRows.EventReturnsOnCall(i, &eventio.BillableEvent{ResourceType: "app"}, nil)
+			}
+			return fakeRows
 		}
 
-		var actualEvents []*eventio.BillableEvent
-		json.Unmarshal(res.Body.Bytes(), &actualEvents)
+		newRequest := func(rangeStop string) *http.Request {
+			u := url.URL{}
+			u.Path = "/billable_events"
+			q := u.Query()
+			q.Set("org_guid", orgGUID1)
+			q.Set("range_start", "2001-01-01")
+			q.Set("range_stop", rangeStop)
+			u.RawQuery = q.Encode()
+			req := httptest.NewRequest(echo.GET, u.String(), nil)
+			req.Header.Set("Authorization", "bearer "+token)
+			return req
+		}
 
-		Expect(actualEvents).To(ConsistOf(events))
-		Expect(res.Code).To(Equal(200))
-		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+		BeforeEach(func() {
+			fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+			fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		})
+
+		It("should write the months in order however long each takes", func() {
+			fakeStore.GetBillableEventRowsStub = func(ctx context.Context, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+				switch filter.RangeStart {
+				case "2001-01-01":
+					time.Sleep(30 * time.Millisecond)
+					return rowsOf(`{"event_guid": "jan-1"}`, `{"event_guid": "jan-2"}`), nil
+				case "2001-02-01":
+					time.Sleep(10 * time.Millisecond)
+					return rowsOf(`{"event_guid": "feb-1"}`), nil
+				default:
+					return rowsOf(`{"event_guid": "mar-1"}`), nil
+				}
+			}
+
+			res := httptest.NewRecorder()
+			e := New(cfg)
+			e.ServeHTTP(res, newRequest("2001-04-01"))
+			defer e.Shutdown(ctx)
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Body).To(MatchJSON(`[
+				{"event_guid": "jan-1"},
+				{"event_guid": "jan-2"},
+				{"event_guid": "feb-1"},
+				{"event_guid": "mar-1"}
+			]`))
+		})
+
+		It("should fetch several months at once but no more than the limit", func() {
+			var mu sync.Mutex
+			running, maxRunning := 0, 0
+			fakeStore.GetBillableEventRowsStub = func(ctx context.Context, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+				mu.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mu.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				mu.Lock()
+				running--
+				mu.Unlock()
+				return rowsOf(), nil
+			}
+
+			res := httptest.NewRecorder()
+			e := New(cfg)
+			e.ServeHTTP(res, newRequest("2002-01-01"))
+			defer e.Shutdown(ctx)
+
+			Expect(res.Code).To(Equal(200))
+			Expect(fakeStore.GetBillableEventRowsCallCount()).To(Equal(12))
+			Expect(maxRunning).To(BeNumerically(">", 1))
+			Expect(maxRunning).To(BeNumerically("<=", 4))
+		})
+
+		It("should fail the request and cancel the other months when one month fails", func() {
+			var mu sync.Mutex
+			cancelled := 0
+			fakeStore.GetBillableEventRowsStub = func(ctx context.Context, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+				if filter.RangeStart == "2001-02-01" {
+					return nil, errors.New("query-error")
+				}
+				select {
+				case <-ctx.Done():
+					mu.Lock()
+					cancelled++
+					mu.Unlock()
+					return nil, ctx.Err()
+				case <-time.After(5 * time.Second):
+					return rowsOf(), nil
+				}
+			}
+
+			res := httptest.NewRecorder()
+			e := New(cfg)
+			e.ServeHTTP(res, newRequest("2001-04-01"))
+			defer e.Shutdown(ctx)
+
+			Expect(res.Code).To(Equal(500))
+			Expect(res.Body).To(MatchJSON(`{"error": "internal server error"}`))
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(cancelled).To(Equal(2), "january and march should have been cancelled")
+		})
 	})
 })
@@ -0,0 +1,213 @@
+package apiserver
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+// consolidatedETagVersion is mixed into every ETag. Bump it when a change
+// to the code alters the response body for consolidated events so that
+// clients holding the old body fetch the new one.
+const consolidatedETagVersion = "4"
+
+// consolidatedMaxAge is how long clients may reuse a consolidated response
+// before revalidating it with If-None-Match
+const consolidatedMaxAge = 24 * time.Hour
+
+// consolidationRecordsFor returns the consolidation record for each of the
+// months, or nil if any of them is not a whole consolidated month. Only
+// responses made entirely of consolidated months can be cached.
+func consolidationRecordsFor(ctx context.Context, store eventio.ConsolidatedBillableEventReader, filter eventio.EventFilter, months []eventio.EventFilter) ([]eventio.ConsolidationRecord, error) {
+	history, err := store.GetConsolidationHistoryContext(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]eventio.ConsolidationRecord, 0, len(months))
+	for _, month := range months {
+		found := false
+		for _, record := range history {
+			if record.RangeStart == month.RangeStart && record.RangeStop == month.RangeStop {
+				records = append(records, record)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil
+		}
+	}
+	return records, nil
+}
+
+// consolidatedETag derives a strong ETag from everything that determines
+// the response body: the consolidation records, the request filter and
+// the format
+func consolidatedETag(resource string, records []eventio.ConsolidationRecord, filter eventio.EventFilter, format string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", consolidatedETagVersion, resource, format)
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s\n", filter.RangeStart, filter.RangeStop, strings.Join(sortedOrgGUIDs(filter.OrgGUIDs), ","), filter.Aggregate, filter.LabelSelector)
+	for _, record := range records {
+		fmt.Fprintf(h, "%s\n%s\n%s\n", record.RangeStart, record.RangeStop, record.CreatedAt.UTC().Format(time.RFC3339Nano))
+	}
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+func sortedOrgGUIDs(orgGUIDs []string) []string {
+	sorted := append([]string{}, orgGUIDs...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// setCacheableHeaders replaces the no-store headers set by CacheHeaders.
+// Responses are private because they depend on who is asking.
+func setCacheableHeaders(c echo.Context, etag string) {
+	h := c.Response().Header()
+	h.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(consolidatedMaxAge.Seconds())))
+	h.Set("ETag", etag)
+	h.Set("Vary", "Accept, Authorization")
+	h.Del("Pragma")
+	h.Del("Expires")
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag,
+// using the weak comparison RFC 7232 requires for If-None-Match
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the cache headers and reports whether the client already
+// has the response, in which case the caller should return without a body
+func notModified(c echo.Context, etag string) (bool, error) {
+	setCacheableHeaders(c, etag)
+	if ifNoneMatch := c.Request().Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		return true, c.NoContent(http.StatusNotModified)
+	}
+	return false, nil
+}
+
+// ConsolidatedMonthCache keeps the serialised events of recently requested
+// consolidated months in memory. Consolidated months never change, so
+// entries only leave the cache to make room for newer ones. A nil cache is
+// valid and caches nothing.
+type ConsolidatedMonthCache struct {
+	mu      sync.Mutex
+	size    int
+	entries *list.List
+	index   map[string]*list.Element
+}
+
+type consolidatedMonthCacheEntry struct {
+	key    string
+	events [][]byte
+}
+
+// NewConsolidatedMonthCache creates a cache holding up to size months. It
+// returns nil if size is zero or less, which disables caching.
+func NewConsolidatedMonthCache(size int) *ConsolidatedMonthCache {
+	if size <= 0 {
+		return nil
+	}
+	return &ConsolidatedMonthCache{
+		size:    size,
+		entries: list.New(),
+		index:   map[string]*list.Element{},
+	}
+}
+
+func consolidatedMonthCacheKey(month eventio.EventFilter, record eventio.ConsolidationRecord) string {
+	return strings.Join([]string{
+		month.RangeStart,
+		month.RangeStop,
+		strings.Join(sortedOrgGUIDs(month.OrgGUIDs), ","),
+		month.Aggregate,
+		month.LabelSelector,
+		record.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "|")
+}
+
+func (m *ConsolidatedMonthCache) get(key string) ([][]byte, bool) {
+	if m == nil {
+		return nil, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.index[key]
+	if !ok {
+		return nil, false
+	}
+	m.entries.MoveToFront(el)
+	return el.Value.(*consolidatedMonthCacheEntry).events, true
+}
+
+func (m *ConsolidatedMonthCache) add(key string, events [][]byte) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.index[key]; ok {
+		m.entries.MoveToFront(el)
+		el.Value.(*consolidatedMonthCacheEntry).events = events
+		return
+	}
+	m.index[key] = m.entries.PushFront(&consolidatedMonthCacheEntry{key: key, events: events})
+	for m.entries.Len() > m.size {
+		oldest := m.entries.Back()
+		m.entries.Remove(oldest)
+		delete(m.index, oldest.Value.(*consolidatedMonthCacheEntry).key)
+	}
+}
+
+// cachedBillableEventRows replays the events of a cached month
+type cachedBillableEventRows struct {
+	events [][]byte
+	pos    int
+}
+
+var _ eventio.BillableEventRows = &cachedBillableEventRows{}
+
+func (r *cachedBillableEventRows) Next() bool {
+	if r.pos >= len(r.events) {
+		return false
+	}
+	r.pos++
+	return true
+}
+
+func (r *cachedBillableEventRows) Close() error {
+	return nil
+}
+
+func (r *cachedBillableEventRows) Err() error {
+	return nil
+}
+
+func (r *cachedBillableEventRows) EventJSON() ([]byte, error) {
+	return r.events[r.pos-1], nil
+}
+
+func (r *cachedBillableEventRows) Event() (*eventio.BillableEvent, error) {
+	var event eventio.BillableEvent
+	if err := json.Unmarshal(r.events[r.pos-1], &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,74 @@
+package apiserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+func CostTimeSeriesHandler(store eventio.CostTimeSeriesReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		// parse params
+		format, err := negotiateFormat(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		step := c.QueryParam("step")
+		if step == "" {
+			step = "day"
+		}
+		filter := eventio.CostTimeSeriesFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart:    c.QueryParam("range_start"),
+				RangeStop:     c.QueryParam("range_stop"),
+				OrgGUIDs:      requestedOrgs,
+				LabelSelector: c.QueryParam("label_selector"),
+			},
+			Step:    step,
+			GroupBy: c.QueryParam("group_by"),
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		storeCtx, cancel := context.WithCancel(c.Request().Context())
+		defer cancel()
+
+		// query the store
+		rows, err := store.GetCostTimeSeriesRows(storeCtx, filter)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// stream response to client
+		enc := newEventEncoder(c, format, costTimeSeriesTable)
+		for rows.Next() {
+			point, err := rows.Point()
+			if err != nil {
+				return err
+			}
+			b, err := json.Marshal(point)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(b); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return enc.End()
+	}
+}
@@ -0,0 +1,192 @@
+package apiserver_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CostTimeSeriesHandler", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		fakeRows          *eventiofakes.FakeCostTimeSeriesRows
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID1          = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+	)
+
+	newRequest := func(params map[string]string) *httptest.ResponseRecorder {
+		u := url.URL{}
+		u.Path = "/cost_timeseries"
+		q := u.Query()
+		q.Set("org_guid", orgGUID1)
+		q.Set("range_start", "2001-01-01")
+		q.Set("range_stop", "2001-01-03")
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+
+		fakeRows = &eventiofakes.FakeCostTimeSeriesRows{}
+		fakeRows.NextReturnsOnCall(0, true)
+		fakeRows.NextReturnsOnCall(1, true)
+		fakeRows.NextReturnsOnCall(2, false)
+		fakeRows.PointReturnsOnCall(0, &eventio.CostTimeSeriesPoint{
+			PeriodStart:   "2001-01-01",
+			Group:         orgGUID1,
+			GroupName:     "org-1",
+			ExVAT:         "1.5",
+			IncVAT:        "1.8",
+			InstanceHours: "24",
+		}, nil)
+		fakeRows.PointReturnsOnCall(1, &eventio.CostTimeSeriesPoint{
+			PeriodStart:   "2001-01-02",
+			Group:         orgGUID1,
+			GroupName:     "org-1",
+			ExVAT:         "0",
+			IncVAT:        "0",
+			InstanceHours: "0",
+		}, nil)
+		fakeStore.GetCostTimeSeriesRowsReturns(fakeRows, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should require billing access to the requested orgs", func() {
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := newRequest(nil)
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "you need to be billing_manager or an administrator to retrieve the billing data"
+		}`))
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(0))
+	})
+
+	It("should reject an unknown step", func() {
+		res := newRequest(map[string]string{"step": "fortnight"})
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(0))
+	})
+
+	It("should reject an unknown group_by", func() {
+		res := newRequest(map[string]string{"group_by": "colour"})
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(0))
+	})
+
+	It("should default to a daily series and stream it as JSON", func() {
+		res := newRequest(nil)
+
+		Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetCostTimeSeriesRowsArgsForCall(0)
+		Expect(filter.RangeStart).To(Equal("2001-01-01"))
+		Expect(filter.RangeStop).To(Equal("2001-01-03"))
+		Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1}))
+		Expect(filter.Step).To(Equal("day"))
+		Expect(filter.GroupBy).To(Equal(""))
+		Expect(fakeRows.CloseCallCount()).To(Equal(1))
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+		Expect(res.Body).To(MatchJSON(`[
+			{"period_start": "2001-01-01", "group": "` + orgGUID1 + `", "group_name": "org-1", "ex_vat": "1.5", "inc_vat": "1.8", "instance_hours": "24"},
+			{"period_start": "2001-01-02", "group": "` + orgGUID1 + `", "group_name": "org-1", "ex_vat": "0", "inc_vat": "0", "instance_hours": "0"}
+		]`))
+	})
+
+	It("should pass the step and group_by to the store", func() {
+		res := newRequest(map[string]string{"step": "month", "group_by": "space"})
+
+		Expect(res.Code).To(Equal(200))
+		_, filter := fakeStore.GetCostTimeSeriesRowsArgsForCall(0)
+		Expect(filter.Step).To(Equal("month"))
+		Expect(filter.GroupBy).To(Equal("space"))
+	})
+
+	It("should pass a label selector and group_by=label:<key> to the store", func() {
+		res := newRequest(map[string]string{"label_selector": "team=billing", "group_by": "label:cost-centre"})
+
+		Expect(res.Code).To(Equal(200))
+		_, filter := fakeStore.GetCostTimeSeriesRowsArgsForCall(0)
+		Expect(filter.LabelSelector).To(Equal("team=billing"))
+		Expect(filter.GroupBy).To(Equal("label:cost-centre"))
+	})
+
+	It("should reject an invalid label selector", func() {
+		res := newRequest(map[string]string{"label_selector": "team=not valid"})
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(0))
+	})
+
+	It("should stream CSV when format=csv", func() {
+		res := newRequest(map[string]string{"format": "csv"})
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("text/csv; charset=UTF-8"))
+		Expect(res.Body.String()).To(Equal(
+			"period_start,group,group_name,ex_vat,inc_vat,instance_hours\n" +
+				"2001-01-01," + orgGUID1 + ",org-1,1.5,1.8,24\n" +
+				"2001-01-02," + orgGUID1 + ",org-1,0,0,0\n",
+		))
+	})
+
+	It("should return error if GetCostTimeSeriesRows returns error", func() {
+		fakeStore.GetCostTimeSeriesRowsReturns(nil, errors.New("query-error"))
+
+		res := newRequest(nil)
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "internal server error"
+		}`))
+		Expect(res.Code).To(Equal(500))
+	})
+})
@@ -9,7 +9,7 @@ import (
 
 func TotalCostHandler(store eventio.TotalCostReader) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		costTotals, err := store.GetTotalCost()
+		costTotals, err := store.GetTotalCostContext(c.Request().Context())
 		if err != nil {
 			return err
 		}
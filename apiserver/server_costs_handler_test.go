@@ -46,18 +46,18 @@ var _ = Describe("TotalCostHandler", func() {
 	})
 
 	It("should return the total cost by plan_guids as json", func() {
-		fakeStore.GetTotalCostReturns([]eventio.TotalCost{
+		fakeStore.GetTotalCostContextReturns([]eventio.TotalCost{
 			{
 				PlanGUID: "b1341aba-63f9-4747-9abd-d48313483044",
-				Cost:     45.23,
+				Cost:     "45.23",
 			},
 			{
 				PlanGUID: "f1019263-081c-4776-bd9e-b056e4a32e31",
-				Cost:     543,
+				Cost:     "543",
 			},
 			{
 				PlanGUID: "f19ac069-ed93-47c9-98ff-c23945b56cb9",
-				Cost:     6.23,
+				Cost:     "6.23",
 			},
 		}, nil)
 
@@ -70,7 +70,7 @@ var _ = Describe("TotalCostHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetTotalCostCallCount()).To(Equal(1))
+		Expect(fakeStore.GetTotalCostContextCallCount()).To(Equal(1))
 
 		Expect(res.Body).To(MatchJSON(`[
             {
@@ -13,7 +13,7 @@ func CurrencyRatesHandler(store eventio.CurrencyRateReader) echo.HandlerFunc {
 			RangeStart: c.QueryParam("range_start"),
 			RangeStop:  c.QueryParam("range_stop"),
 		}
-		currencyRates, err := store.GetCurrencyRates(filter)
+		currencyRates, err := store.GetCurrencyRatesContext(c.Request().Context(), filter)
 		if err != nil {
 			return err
 		}
@@ -47,7 +47,7 @@ var _ = Describe("CurrencyRatesHandler", func() {
 	})
 
 	It("should request the rates from the store and return json", func() {
-		fakeStore.GetCurrencyRatesReturns([]eventio.CurrencyRate{
+		fakeStore.GetCurrencyRatesContextReturns([]eventio.CurrencyRate{
 			{
 				Code:      "GBP",
 				ValidFrom: "2001-01-01",
@@ -76,9 +76,9 @@ var _ = Describe("CurrencyRatesHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetCurrencyRatesCallCount()).To(Equal(1))
+		Expect(fakeStore.GetCurrencyRatesContextCallCount()).To(Equal(1))
 
-		filter := fakeStore.GetCurrencyRatesArgsForCall(0)
+		_, filter := fakeStore.GetCurrencyRatesContextArgsForCall(0)
 		Expect(filter.RangeStart).To(Equal(rangeStart))
 		Expect(filter.RangeStop).To(Equal(rangeStop))
 
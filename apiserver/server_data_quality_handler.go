@@ -0,0 +1,34 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+// DataQualityHandler lists the usage events that were dropped, mis-paired or
+// priced with a generated plan when events were last refreshed
+func DataQualityHandler(store eventio.DataQualityReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		filter := eventio.DataQualityFilter{
+			OrgGUIDs: requestedOrgs,
+			Category: c.QueryParam("category"),
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		issues, err := store.GetDataQualityIssuesContext(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, issues)
+	}
+}
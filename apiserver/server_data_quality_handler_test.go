@@ -0,0 +1,108 @@
+package apiserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DataQualityHandler", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+	)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should list the issues for the requested orgs", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		issues := []eventio.DataQualityIssue{{
+			Category:     eventio.DataQualityStoppedWithoutStart,
+			EventGUID:    "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+			ObservedAt:   "2001-01-01T00:00:00+00:00",
+			OrgGUID:      orgGUID,
+			ResourceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+			ResourceType: "app",
+			Detail:       "STOPPED",
+		}}
+		fakeStore.GetDataQualityIssuesContextReturns(issues, nil)
+
+		res := request("/data_quality?org_guid=" + orgGUID + "&category=stopped_without_start")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		_, filter := fakeStore.GetDataQualityIssuesContextArgsForCall(0)
+		Expect(filter).To(Equal(eventio.DataQualityFilter{
+			OrgGUIDs: []string{orgGUID},
+			Category: eventio.DataQualityStoppedWithoutStart,
+		}))
+		var body []eventio.DataQualityIssue
+		Expect(json.Unmarshal(res.Body.Bytes(), &body)).To(Succeed())
+		Expect(body).To(Equal(issues))
+	})
+
+	It("should refuse someone without billing access to the requested orgs", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := request("/data_quality?org_guid=" + orgGUID)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetDataQualityIssuesContextCallCount()).To(Equal(0))
+	})
+
+	It("should reject an unknown category", func() {
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		res := request("/data_quality?category=typos")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetDataQualityIssuesContextCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,626 @@
+package apiserver
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+	formatXLSX   = "xlsx"
+
+	mimeApplicationNDJSON = "application/x-ndjson"
+	mimeTextCSV           = "text/csv"
+	mimeApplicationXLSX   = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+type formatMIMEType struct {
+	format   string
+	mimeType string
+}
+
+// formatMIMETypes lists the supported formats in order of preference when
+// matching an Accept header
+var formatMIMETypes = []formatMIMEType{
+	{formatJSON, echo.MIMEApplicationJSON},
+	{formatNDJSON, mimeApplicationNDJSON},
+	{formatNDJSON, "application/ndjson"},
+	{formatCSV, mimeTextCSV},
+	{formatXLSX, mimeApplicationXLSX},
+}
+
+// negotiateFormat picks the response format from the format= query param,
+// falling back to the Accept header and then to JSON
+func negotiateFormat(c echo.Context) (string, error) {
+	return negotiateFormatFrom(c, formatMIMETypes, "json, ndjson, csv or xlsx")
+}
+
+// negotiateFormatFrom is negotiateFormat for endpoints that support a
+// different set of formats. The first entry is the default.
+func negotiateFormatFrom(c echo.Context, supported []formatMIMEType, description string) (string, error) {
+	if format := c.QueryParam("format"); format != "" {
+		for _, f := range supported {
+			if f.format == format {
+				return format, nil
+			}
+		}
+		return "", fmt.Errorf("format must be one of %s - got %s", description, format)
+	}
+	for _, accepted := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mimeType := strings.TrimSpace(strings.Split(accepted, ";")[0])
+		for _, f := range supported {
+			if f.mimeType == mimeType {
+				return f.format, nil
+			}
+		}
+	}
+	return supported[0].format, nil
+}
+
+// eventEncoder streams events to the client in a negotiated format. The
+// response status and headers are sent as late as possible (on the first
+// Encode or on End) because any errors encountered after this won't be
+// communicated to the client correctly.
+type eventEncoder interface {
+	// Encode writes a single event given its JSON representation
+	Encode(eventJSON []byte) error
+	// End writes any trailer and flushes the response. You must call this.
+	End() error
+}
+
+type eventColumn struct {
+	name    string
+	numeric bool
+}
+
+// eventTable describes how to flatten an event into spreadsheet rows
+type eventTable struct {
+	name    string
+	columns []eventColumn
+	rows    func(eventJSON []byte) ([][]string, error)
+}
+
+func (t eventTable) header() []string {
+	header := make([]string, len(t.columns))
+	for i, col := range t.columns {
+		header[i] = col.name
+	}
+	return header
+}
+
+var billableEventTable = eventTable{
+	name: "billable_events",
+	columns: []eventColumn{
+		{name: "event_guid"},
+		{name: "event_start"},
+		{name: "event_stop"},
+		{name: "resource_guid"},
+		{name: "resource_name"},
+		{name: "resource_type"},
+		{name: "org_guid"},
+		{name: "org_name"},
+		{name: "space_guid"},
+		{name: "space_name"},
+		{name: "plan_guid"},
+		{name: "plan_name"},
+		{name: "quota_definition_guid"},
+		{name: "number_of_nodes", numeric: true},
+		{name: "memory_in_mb", numeric: true},
+		{name: "storage_in_mb", numeric: true},
+		{name: "price_inc_vat", numeric: true},
+		{name: "price_ex_vat", numeric: true},
+		{name: "component_name"},
+		{name: "component_plan_name"},
+		{name: "component_start"},
+		{name: "component_stop"},
+		{name: "component_vat_rate", numeric: true},
+		{name: "component_vat_code"},
+		{name: "component_currency_code"},
+		{name: "component_inc_vat", numeric: true},
+		{name: "component_ex_vat", numeric: true},
+	},
+	// one row per price component, repeating the event details on each
+	rows: func(eventJSON []byte) ([][]string, error) {
+		var ev eventio.BillableEvent
+		if err := json.Unmarshal(eventJSON, &ev); err != nil {
+			return nil, err
+		}
+		event := []string{
+			ev.EventGUID,
+			ev.EventStart,
+			ev.EventStop,
+			ev.ResourceGUID,
+			ev.ResourceName,
+			ev.ResourceType,
+			ev.OrgGUID,
+			ev.OrgName,
+			ev.SpaceGUID,
+			ev.SpaceName,
+			ev.PlanGUID,
+			ev.PlanName,
+			ev.QuotaDefinitionGUID,
+			strconv.FormatInt(ev.NumberOfNodes, 10),
+			strconv.FormatInt(ev.MemoryInMB, 10),
+			strconv.FormatInt(ev.StorageInMB, 10),
+			string(ev.Price.IncVAT),
+			string(ev.Price.ExVAT),
+		}
+		if len(ev.Price.Details) == 0 {
+			return [][]string{append(event, make([]string, 9)...)}, nil
+		}
+		rows := [][]string{}
+		for _, pc := range ev.Price.Details {
+			row := append(append([]string{}, event...),
+				pc.Name,
+				pc.PlanName,
+				pc.Start,
+				pc.Stop,
+				pc.VatRate,
+				pc.VatCode,
+				pc.CurrencyCode,
+				string(pc.IncVAT),
+				string(pc.ExVAT),
+			)
+			rows = append(rows, row)
+		}
+		return rows, nil
+	},
+}
+
+var usageEventTable = eventTable{
+	name: "usage_events",
+	columns: []eventColumn{
+		{name: "event_guid"},
+		{name: "event_start"},
+		{name: "event_stop"},
+		{name: "resource_guid"},
+		{name: "resource_name"},
+		{name: "resource_type"},
+		{name: "org_guid"},
+		{name: "org_name"},
+		{name: "space_guid"},
+		{name: "space_name"},
+		{name: "plan_guid"},
+		{name: "plan_name"},
+		{name: "service_guid"},
+		{name: "service_name"},
+		{name: "number_of_nodes", numeric: true},
+		{name: "memory_in_mb", numeric: true},
+		{name: "storage_in_mb", numeric: true},
+	},
+	rows: func(eventJSON []byte) ([][]string, error) {
+		var ev eventio.UsageEvent
+		if err := json.Unmarshal(eventJSON, &ev); err != nil {
+			return nil, err
+		}
+		return [][]string{{
+			ev.EventGUID,
+			ev.EventStart,
+			ev.EventStop,
+			ev.ResourceGUID,
+			ev.ResourceName,
+			ev.ResourceType,
+			ev.OrgGUID,
+			ev.OrgName,
+			ev.SpaceGUID,
+			ev.SpaceName,
+			ev.PlanGUID,
+			ev.PlanName,
+			ev.ServiceGUID,
+			ev.ServiceName,
+			strconv.FormatInt(ev.NumberOfNodes, 10),
+			strconv.FormatInt(ev.MemoryInMB, 10),
+			strconv.FormatInt(ev.StorageInMB, 10),
+		}}, nil
+	},
+}
+
+var footprintEventTable = eventTable{
+	name: "footprint",
+	columns: []eventColumn{
+		{name: "event_guid"},
+		{name: "event_start"},
+		{name: "event_stop"},
+		{name: "resource_guid"},
+		{name: "resource_name"},
+		{name: "resource_type"},
+		{name: "org_guid"},
+		{name: "org_name"},
+		{name: "space_guid"},
+		{name: "space_name"},
+		{name: "plan_guid"},
+		{name: "energy_kwh", numeric: true},
+		{name: "carbon_gco2e", numeric: true},
+		{name: "component_plan_name"},
+		{name: "component_start"},
+		{name: "component_stop"},
+		{name: "component_grid_intensity", numeric: true},
+		{name: "component_energy_kwh", numeric: true},
+		{name: "component_carbon_gco2e", numeric: true},
+	},
+	// one row per period, repeating the event details on each
+	rows: func(eventJSON []byte) ([][]string, error) {
+		var ev eventio.FootprintEvent
+		if err := json.Unmarshal(eventJSON, &ev); err != nil {
+			return nil, err
+		}
+		event := []string{
+			ev.EventGUID,
+			ev.EventStart,
+			ev.EventStop,
+			ev.ResourceGUID,
+			ev.ResourceName,
+			ev.ResourceType,
+			ev.OrgGUID,
+			ev.OrgName,
+			ev.SpaceGUID,
+			ev.SpaceName,
+			ev.PlanGUID,
+			ev.EnergyKWh,
+			ev.CarbonGCO2e,
+		}
+		if len(ev.Details) == 0 {
+			return [][]string{append(event, make([]string, 6)...)}, nil
+		}
+		rows := [][]string{}
+		for _, fc := range ev.Details {
+			row := append(append([]string{}, event...),
+				fc.PlanName,
+				fc.Start,
+				fc.Stop,
+				fc.GridIntensity,
+				fc.EnergyKWh,
+				fc.CarbonGCO2e,
+			)
+			rows = append(rows, row)
+		}
+		return rows, nil
+	},
+}
+
+var costTimeSeriesTable = eventTable{
+	name: "cost_timeseries",
+	columns: []eventColumn{
+		{name: "period_start"},
+		{name: "group"},
+		{name: "group_name"},
+		{name: "ex_vat", numeric: true},
+		{name: "inc_vat", numeric: true},
+		{name: "instance_hours", numeric: true},
+	},
+	rows: func(eventJSON []byte) ([][]string, error) {
+		var point eventio.CostTimeSeriesPoint
+		if err := json.Unmarshal(eventJSON, &point); err != nil {
+			return nil, err
+		}
+		return [][]string{{
+			point.PeriodStart,
+			point.Group,
+			point.GroupName,
+			string(point.ExVAT),
+			string(point.IncVAT),
+			point.InstanceHours,
+		}}, nil
+	},
+}
+
+// newEventEncoder returns an eventEncoder writing to the response of c in
+// the given format. table describes how events are flattened for the
+// spreadsheet formats.
+func newEventEncoder(c echo.Context, format string, table eventTable) eventEncoder {
+	base := baseEncoder{c: c, table: table}
+	switch format {
+	case formatNDJSON:
+		return &ndjsonEncoder{base}
+	case formatCSV:
+		return &csvEncoder{baseEncoder: base}
+	case formatXLSX:
+		return &xlsxEncoder{baseEncoder: base}
+	default:
+		return &jsonEncoder{baseEncoder: base}
+	}
+}
+
+type baseEncoder struct {
+	c       echo.Context
+	table   eventTable
+	started bool
+}
+
+func (e *baseEncoder) start(contentType string, filename string) {
+	e.started = true
+	e.c.Response().Header().Set(echo.HeaderContentType, contentType)
+	if filename != "" {
+		e.c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	}
+	e.c.Response().WriteHeader(http.StatusOK)
+}
+
+type jsonEncoder struct {
+	baseEncoder
+	delim string
+}
+
+func (e *jsonEncoder) begin() error {
+	if e.started {
+		return nil
+	}
+	e.start(echo.MIMEApplicationJSONCharsetUTF8, "")
+	if _, err := e.c.Response().Write([]byte("[\n")); err != nil {
+		return err
+	}
+	e.c.Response().Flush()
+	return nil
+}
+
+func (e *jsonEncoder) Encode(eventJSON []byte) error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	if _, err := e.c.Response().Write([]byte(e.delim)); err != nil {
+		return err
+	}
+	if _, err := e.c.Response().Write(eventJSON); err != nil {
+		return err
+	}
+	e.delim = ",\n"
+	e.c.Response().Flush()
+	return nil
+}
+
+func (e *jsonEncoder) End() error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	if _, err := e.c.Response().Write([]byte("\n]\n")); err != nil {
+		return err
+	}
+	e.c.Response().Flush()
+	return nil
+}
+
+type ndjsonEncoder struct {
+	baseEncoder
+}
+
+func (e *ndjsonEncoder) begin() {
+	if !e.started {
+		e.start(mimeApplicationNDJSON, "")
+	}
+}
+
+func (e *ndjsonEncoder) Encode(eventJSON []byte) error {
+	e.begin()
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, eventJSON); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	if _, err := e.c.Response().Write(buf.Bytes()); err != nil {
+		return err
+	}
+	e.c.Response().Flush()
+	return nil
+}
+
+func (e *ndjsonEncoder) End() error {
+	e.begin()
+	e.c.Response().Flush()
+	return nil
+}
+
+type csvEncoder struct {
+	baseEncoder
+	w *csv.Writer
+}
+
+func (e *csvEncoder) begin() error {
+	if e.started {
+		return nil
+	}
+	e.start(mimeTextCSV+"; charset=UTF-8", e.table.name+".csv")
+	e.w = csv.NewWriter(e.c.Response())
+	return e.w.Write(e.table.header())
+}
+
+func (e *csvEncoder) Encode(eventJSON []byte) error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	rows, err := e.table.rows(eventJSON)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := e.w.Write(csvEscapeRow(e.table.columns, row)); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	e.c.Response().Flush()
+	return nil
+}
+
+func (e *csvEncoder) End() error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		return err
+	}
+	e.c.Response().Flush()
+	return nil
+}
+
+// csvFormulaPrefixes are the leading characters that make spreadsheet
+// applications evaluate a CSV cell as a formula
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvEscapeRow prefixes text cells that would otherwise be evaluated as
+// formulas with a quote so that names chosen by tenants, like a space called
+// "=HYPERLINK(...)", are shown as text. Numbers in numeric columns, such as
+// negative credits, are left alone.
+func csvEscapeRow(columns []eventColumn, values []string) []string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = value
+		if value == "" || !strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+			continue
+		}
+		if _, err := strconv.ParseFloat(value, 64); err == nil && i < len(columns) && columns[i].numeric {
+			continue
+		}
+		escaped[i] = "'" + value
+	}
+	return escaped
+}
+
+// xlsxEncoder streams a minimal single-sheet workbook. The zip entries are
+// written sequentially so the worksheet can be written row by row without
+// buffering the whole sheet. Cells use inline strings so no shared strings
+// table needs to be built up front, and so text is never evaluated as a
+// formula.
+type xlsxEncoder struct {
+	baseEncoder
+	zw     *zip.Writer
+	sheet  io.Writer
+	rowNum int
+}
+
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func (e *xlsxEncoder) begin() error {
+	if e.started {
+		return nil
+	}
+	e.start(mimeApplicationXLSX, e.table.name+".xlsx")
+	e.zw = zip.NewWriter(e.c.Response())
+	for _, part := range xlsxStaticParts {
+		w, err := e.zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+	w, err := e.zw.Create("xl/workbook.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `%s<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`+
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`, xml.Header, xmlEscape(e.table.name)); err != nil {
+		return err
+	}
+	e.sheet, err = e.zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return err
+	}
+	header := make([]eventColumn, len(e.table.columns))
+	for i, col := range e.table.columns {
+		header[i] = eventColumn{name: col.name}
+	}
+	return e.writeRow(header, e.table.header())
+}
+
+func (e *xlsxEncoder) writeRow(columns []eventColumn, values []string) error {
+	e.rowNum++
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<row r="%d">`, e.rowNum)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		ref := xlsxColumnName(i) + strconv.Itoa(e.rowNum)
+		if _, err := strconv.ParseFloat(value, 64); err == nil && i < len(columns) && columns[i].numeric {
+			fmt.Fprintf(&buf, `<c r="%s"><v>%s</v></c>`, ref, value)
+		} else {
+			fmt.Fprintf(&buf, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xmlEscape(value))
+		}
+	}
+	buf.WriteString(`</row>`)
+	_, err := e.sheet.Write(buf.Bytes())
+	return err
+}
+
+func (e *xlsxEncoder) Encode(eventJSON []byte) error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	rows, err := e.table.rows(eventJSON)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := e.writeRow(e.table.columns, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *xlsxEncoder) End() error {
+	if err := e.begin(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(e.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := e.zw.Close(); err != nil {
+		return err
+	}
+	e.c.Response().Flush()
+	return nil
+}
+
+// xlsxColumnName converts a zero based column index to a spreadsheet column
+// name, eg 0 => A, 26 => AA
+func xlsxColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
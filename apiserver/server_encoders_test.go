@@ -0,0 +1,237 @@
+package apiserver_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Response formats", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID1          = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		billableEvent     = eventio.BillableEvent{
+			EventGUID:     "aa30fa3c-725d-4272-9052-c7186d4968a6",
+			EventStart:    "2001-01-01T00:00:00+00:00",
+			EventStop:     "2001-01-01T01:00:00+00:00",
+			ResourceName:  "APP1",
+			ResourceType:  "app",
+			OrgGUID:       orgGUID1,
+			OrgName:       "org, with a comma",
+			NumberOfNodes: 1,
+			MemoryInMB:    1024,
+			Price: eventio.Price{
+				IncVAT: "0.024",
+				ExVAT:  "0.02",
+				Details: []eventio.PriceComponent{
+					{Name: "compute", PlanName: "PLAN1", VatRate: "0.2", VatCode: "Standard", CurrencyCode: "GBP", IncVAT: "0.012", ExVAT: "0.01"},
+					{Name: "platform", PlanName: "PLAN1", VatRate: "0.2", VatCode: "Standard", CurrencyCode: "GBP", IncVAT: "0.012", ExVAT: "0.01"},
+				},
+			},
+		}
+	)
+
+	request := func(path string, params map[string]string, accept string) *httptest.ResponseRecorder {
+		u := url.URL{}
+		u.Path = path
+		q := u.Query()
+		q.Set("org_guid", orgGUID1)
+		q.Set("range_start", "2001-01-01")
+		q.Set("range_stop", "2001-01-02")
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		u.RawQuery = q.Encode()
+		req := httptest.NewRequest(echo.GET, u.String(), nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		if accept != "" {
+			req.Header.Set(echo.HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		eventJSON, err := json.MarshalIndent(billableEvent, "", "  ")
+		Expect(err).ToNot(HaveOccurred())
+		fakeRows := &eventiofakes.FakeBillableEventRows{}
+		fakeRows.NextReturnsOnCall(0, true)
+		fakeRows.NextReturnsOnCall(1, false)
+		fakeRows.EventJSONReturns(eventJSON, nil)
+		fakeRows.EventReturns(&billableEvent, nil)
+		fakeStore.GetBillableEventRowsReturns(fakeRows, nil)
+
+		fakeUsageRows := &eventiofakes.FakeUsageEventRows{}
+		fakeUsageRows.NextReturnsOnCall(0, true)
+		fakeUsageRows.NextReturnsOnCall(1, true)
+		fakeUsageRows.NextReturnsOnCall(2, false)
+		fakeUsageRows.EventJSONReturnsOnCall(0, []byte(`{
+			"event_guid": "raw-json-guid-1",
+			"memory_in_mb": 64
+		}`), nil)
+		fakeUsageRows.EventJSONReturnsOnCall(1, []byte(`{"event_guid": "raw-json-guid-2"}`), nil)
+		fakeStore.GetUsageEventRowsContextReturns(fakeUsageRows, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should reject an unknown format", func() {
+		res := request("/usage_events", map[string]string{"format": "pdf"}, "")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "format must be one of json, ndjson, csv or xlsx - got pdf"
+		}`))
+	})
+
+	It("should default to JSON for unknown Accept headers", func() {
+		res := request("/usage_events", nil, "text/html")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+		Expect(res.Body).To(MatchJSON(`[
+			{"event_guid": "raw-json-guid-1", "memory_in_mb": 64},
+			{"event_guid": "raw-json-guid-2"}
+		]`))
+	})
+
+	It("should return an empty JSON array when there are no events", func() {
+		fakeStore.GetUsageEventRowsContextReturns(&eventiofakes.FakeUsageEventRows{}, nil)
+
+		res := request("/usage_events", nil, "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[]`))
+	})
+
+	It("should stream one compact event per line for NDJSON", func() {
+		res := request("/usage_events", nil, "application/x-ndjson")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/x-ndjson"))
+		Expect(res.Body.String()).To(Equal(
+			`{"event_guid":"raw-json-guid-1","memory_in_mb":64}` + "\n" +
+				`{"event_guid":"raw-json-guid-2"}` + "\n",
+		))
+	})
+
+	It("should write usage events as CSV with a header row", func() {
+		res := request("/usage_events", map[string]string{"format": "csv"}, "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("text/csv; charset=UTF-8"))
+		Expect(res.Header().Get("Content-Disposition")).To(Equal(`attachment; filename="usage_events.csv"`))
+		Expect(res.Body.String()).To(Equal(
+			"event_guid,event_start,event_stop,resource_guid,resource_name,resource_type,org_guid,org_name,space_guid,space_name,plan_guid,plan_name,service_guid,service_name,number_of_nodes,memory_in_mb,storage_in_mb\n" +
+				"raw-json-guid-1,,,,,,,,,,,,,,0,64,0\n" +
+				"raw-json-guid-2,,,,,,,,,,,,,,0,0,0\n",
+		))
+	})
+
+	It("should flatten billable event price components into CSV rows", func() {
+		res := request("/billable_events", nil, "text/csv")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("text/csv; charset=UTF-8"))
+		Expect(res.Body.String()).To(Equal(
+			"event_guid,event_start,event_stop,resource_guid,resource_name,resource_type,org_guid,org_name,space_guid,space_name,plan_guid,plan_name,quota_definition_guid,number_of_nodes,memory_in_mb,storage_in_mb,price_inc_vat,price_ex_vat,component_name,component_plan_name,component_start,component_stop,component_vat_rate,component_vat_code,component_currency_code,component_inc_vat,component_ex_vat\n" +
+				"aa30fa3c-725d-4272-9052-c7186d4968a6,2001-01-01T00:00:00+00:00,2001-01-01T01:00:00+00:00,,APP1,app," + orgGUID1 + ",\"org, with a comma\",,,,,,1,1024,0,0.024,0.02,compute,PLAN1,,,0.2,Standard,GBP,0.012,0.01\n" +
+				"aa30fa3c-725d-4272-9052-c7186d4968a6,2001-01-01T00:00:00+00:00,2001-01-01T01:00:00+00:00,,APP1,app," + orgGUID1 + ",\"org, with a comma\",,,,,,1,1024,0,0.024,0.02,platform,PLAN1,,,0.2,Standard,GBP,0.012,0.01\n",
+		))
+	})
+
+	It("should stop names that look like formulas being evaluated in CSV", func() {
+		fakeUsageRows := &eventiofakes.FakeUsageEventRows{}
+		fakeUsageRows.NextReturnsOnCall(0, true)
+		fakeUsageRows.NextReturnsOnCall(1, false)
+		fakeUsageRows.EventJSONReturns([]byte(`{
+			"event_guid": "raw-json-guid-1",
+			"resource_name": "=HYPERLINK(\"http://example.com\")",
+			"org_name": "+org",
+			"space_name": "-space",
+			"plan_name": "@plan",
+			"number_of_nodes": -1
+		}`), nil)
+		fakeStore.GetUsageEventRowsContextReturns(fakeUsageRows, nil)
+
+		res := request("/usage_events", map[string]string{"format": "csv"}, "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body.String()).To(HaveSuffix(
+			"raw-json-guid-1,,,,\"'=HYPERLINK(\"\"http://example.com\"\")\",,,'+org,,'-space,,'@plan,,,-1,0,0\n",
+		))
+	})
+
+	It("should write billable events as an XLSX workbook", func() {
+		res := request("/billable_events", map[string]string{"format": "xlsx"}, "")
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"))
+		Expect(res.Header().Get("Content-Disposition")).To(Equal(`attachment; filename="billable_events.xlsx"`))
+
+		body := res.Body.Bytes()
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		Expect(err).ToNot(HaveOccurred())
+		parts := map[string]string{}
+		for _, f := range zr.File {
+			r, err := f.Open()
+			Expect(err).ToNot(HaveOccurred())
+			b, err := io.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			parts[f.Name] = string(b)
+		}
+		Expect(parts).To(HaveKey("[Content_Types].xml"))
+		Expect(parts).To(HaveKey("_rels/.rels"))
+		Expect(parts).To(HaveKey("xl/_rels/workbook.xml.rels"))
+		Expect(parts["xl/workbook.xml"]).To(ContainSubstring(`<sheet name="billable_events" sheetId="1" r:id="rId1"/>`))
+
+		sheet := parts["xl/worksheets/sheet1.xml"]
+		Expect(sheet).To(ContainSubstring(`<row r="1"><c r="A1" t="inlineStr"><is><t>event_guid</t></is></c>`))
+		Expect(sheet).To(ContainSubstring(`<c r="H2" t="inlineStr"><is><t>org, with a comma</t></is></c>`))
+		Expect(sheet).To(ContainSubstring(`<c r="O2"><v>1024</v></c>`))
+		Expect(sheet).To(ContainSubstring(`<c r="S3" t="inlineStr"><is><t>platform</t></is></c>`))
+		Expect(sheet).To(ContainSubstring(`<c r="AA3"><v>0.01</v></c>`))
+		Expect(sheet).To(HaveSuffix(`</sheetData></worksheet>`))
+	})
+})
@@ -0,0 +1,91 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+type exemptionRequest struct {
+	OrgGUID   string `json:"org_guid" form:"org_guid"`
+	SpaceGUID string `json:"space_guid" form:"space_guid"`
+	ValidFrom string `json:"valid_from" form:"valid_from"`
+	ValidTo   string `json:"valid_to" form:"valid_to"`
+	Reason    string `json:"reason" form:"reason"`
+}
+
+// ExemptionsHandler lists the orgs and spaces whose usage is priced at £0.
+// Only admins can see it, as it covers every org.
+func ExemptionsHandler(store eventio.ExemptionReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := authorize(c, uaa, []string{}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		exemptions, err := store.GetExemptionsContext(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, exemptions)
+	}
+}
+
+// AddExemptionHandler exempts an org, or a space within it, from being
+// charged for. It applies from the next refresh.
+func AddExemptionHandler(store eventio.ExemptionWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := authorize(c, uaa, []string{}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		var req exemptionRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		exemption := eventio.Exemption{
+			OrgGUID:   req.OrgGUID,
+			SpaceGUID: req.SpaceGUID,
+			ValidFrom: req.ValidFrom,
+			ValidTo:   req.ValidTo,
+			Reason:    req.Reason,
+		}
+		if err := exemption.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		added, err := store.AddExemption(exemption)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, added)
+	}
+}
+
+// RemoveExemptionHandler removes an exemption added with
+// AddExemptionHandler. Exemptions from the config can only be removed from
+// the config.
+func RemoveExemptionHandler(store eventio.ExemptionWriter, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := authorize(c, uaa, []string{}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		guid := c.Param("exemption_guid")
+		found, err := store.RemoveExemption(guid)
+		if err == eventio.ErrExemptionInConfig {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("exemption %s is set in the config and can only be removed there", guid))
+		}
+		if err != nil {
+			return err
+		}
+		if !found {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("exemption %s not found", guid))
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,177 @@
+package apiserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exemptions", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		exemption         eventio.Exemption
+	)
+
+	request := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "bearer "+token)
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		exemption = eventio.Exemption{
+			GUID:         "0f0e2a7c-51bb-4d39-a1c5-0c3f9e4e4bde",
+			OrgGUID:      "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			SpaceGUID:    "276f4886-ac40-492d-a8cd-b2646637ba76",
+			ValidFrom:    "2018-01-01T00:00:00+00:00",
+			Reason:       "smoke tests",
+			Source:       eventio.ExemptionSourceAPI,
+			CreatedAt:    "2018-01-02T10:00:00+00:00",
+			ExemptEvents: 2,
+			ExemptHours:  "3.5",
+		}
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	Describe("GET /exemptions", func() {
+		It("should list the exemptions to admins", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+			fakeStore.GetExemptionsContextReturns([]eventio.Exemption{exemption}, nil)
+
+			res := request(echo.GET, "/exemptions", "")
+
+			Expect(res.Code).To(Equal(200))
+			var body []eventio.Exemption
+			Expect(json.Unmarshal(res.Body.Bytes(), &body)).To(Succeed())
+			Expect(body).To(Equal([]eventio.Exemption{exemption}))
+		})
+
+		It("should not list the exemptions to billing managers", func() {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+			res := request(echo.GET, "/exemptions", "")
+
+			Expect(res.Code).To(Equal(401))
+			Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(BeEmpty())
+			Expect(fakeStore.GetExemptionsContextCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("POST /exemptions", func() {
+		BeforeEach(func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+		})
+
+		It("should require a reason", func() {
+			res := request(echo.POST, "/exemptions", `{"org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "valid_from": "2018-01-01"}`)
+
+			Expect(res.Code).To(Equal(400))
+			Expect(res.Body.String()).To(ContainSubstring("a reason is required"))
+			Expect(fakeStore.AddExemptionCallCount()).To(Equal(0))
+		})
+
+		It("should add the exemption", func() {
+			fakeStore.AddExemptionReturns(&exemption, nil)
+
+			res := request(echo.POST, "/exemptions", `{
+				"org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				"space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76",
+				"valid_from": "2018-01-01",
+				"reason": "smoke tests"
+			}`)
+
+			Expect(res.Code).To(Equal(201))
+			Expect(fakeStore.AddExemptionArgsForCall(0)).To(Equal(eventio.Exemption{
+				OrgGUID:   "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				SpaceGUID: "276f4886-ac40-492d-a8cd-b2646637ba76",
+				ValidFrom: "2018-01-01",
+				Reason:    "smoke tests",
+			}))
+			Expect(res.Body.String()).To(ContainSubstring(`"guid":"0f0e2a7c-51bb-4d39-a1c5-0c3f9e4e4bde"`))
+		})
+
+		It("should not let billing managers add exemptions", func() {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+			res := request(echo.POST, "/exemptions", `{"org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "valid_from": "2018-01-01", "reason": "mine"}`)
+
+			Expect(res.Code).To(Equal(401))
+			Expect(fakeStore.AddExemptionCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("DELETE /exemptions/:exemption_guid", func() {
+		BeforeEach(func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+		})
+
+		It("should remove the exemption", func() {
+			fakeStore.RemoveExemptionReturns(true, nil)
+
+			res := request(echo.DELETE, "/exemptions/0f0e2a7c-51bb-4d39-a1c5-0c3f9e4e4bde", "")
+
+			Expect(res.Code).To(Equal(204))
+			Expect(fakeStore.RemoveExemptionArgsForCall(0)).To(Equal("0f0e2a7c-51bb-4d39-a1c5-0c3f9e4e4bde"))
+		})
+
+		It("should return 404 for an unknown exemption", func() {
+			fakeStore.RemoveExemptionReturns(false, nil)
+
+			res := request(echo.DELETE, "/exemptions/0f0e2a7c-51bb-4d39-a1c5-0c3f9e4e4bde", "")
+
+			Expect(res.Code).To(Equal(404))
+		})
+
+		It("should not remove exemptions set in the config", func() {
+			fakeStore.RemoveExemptionReturns(true, eventio.ErrExemptionInConfig)
+
+			res := request(echo.DELETE, "/exemptions/0f0e2a7c-51bb-4d39-a1c5-0c3f9e4e4bde", "")
+
+			Expect(res.Code).To(Equal(409))
+			Expect(res.Body.String()).To(ContainSubstring("can only be removed there"))
+		})
+	})
+})
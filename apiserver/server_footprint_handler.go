@@ -0,0 +1,76 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+// FootprintHandler streams the estimated energy use and carbon emissions of
+// the billable events in a range that have a footprint plan
+func FootprintHandler(store eventio.FootprintReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		// parse params
+		format, err := negotiateFormat(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		filter := eventio.EventFilter{
+			RangeStart:    c.QueryParam("range_start"),
+			RangeStop:     c.QueryParam("range_stop"),
+			OrgGUIDs:      requestedOrgs,
+			LabelSelector: c.QueryParam("label_selector"),
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		// query the store
+		rows, err := store.GetFootprintEventRows(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		// stream response to client
+		enc := newEventEncoder(c, format, footprintEventTable)
+		for rows.Next() {
+			b, err := rows.EventJSON()
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(b); err != nil {
+				return err
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return enc.End()
+	}
+}
+
+// FootprintPlansHandler lists the footprint plans valid during a range
+func FootprintPlansHandler(store eventio.FootprintReader) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		filter := eventio.TimeRangeFilter{
+			RangeStart: c.QueryParam("range_start"),
+			RangeStop:  c.QueryParam("range_stop"),
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		plans, err := store.GetFootprintPlans(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
+		return c.JSON(http.StatusOK, plans)
+	}
+}
@@ -0,0 +1,239 @@
+package apiserver_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FootprintHandler", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID1          = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should return error if no token in request", func() {
+		fakeAuthenticator.NewAuthorizerReturns(nil, nil)
+		req := httptest.NewRequest(echo.GET, "/footprint?org_guid="+orgGUID1, nil)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "no access_token in request"
+		}`))
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetFootprintEventRowsCallCount()).To(Equal(0))
+	})
+
+	It("should require billing access to the requested orgs", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+		req := httptest.NewRequest(echo.GET, "/footprint?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.GetFootprintEventRowsCallCount()).To(Equal(0))
+	})
+
+	It("should fetch footprint events from the store when manager", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeRows := &eventiofakes.FakeFootprintEventRows{}
+		fakeRows.NextReturnsOnCall(0, true)
+		fakeRows.NextReturnsOnCall(1, false)
+		eventJSON := `{
+			"event_guid": "raw-json-guid-1",
+			"energy_kwh": "1.5",
+			"carbon_gco2e": "300"
+		}`
+		fakeRows.EventJSONReturnsOnCall(0, []byte(eventJSON), nil)
+		fakeStore.GetFootprintEventRowsReturns(fakeRows, nil)
+
+		req := httptest.NewRequest(echo.GET, "/footprint?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02&label_selector=team%3Dbilling", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(fakeStore.GetFootprintEventRowsCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetFootprintEventRowsArgsForCall(0)
+		Expect(filter.RangeStart).To(Equal("2001-01-01"))
+		Expect(filter.RangeStop).To(Equal("2001-01-02"))
+		Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1}))
+		Expect(filter.LabelSelector).To(Equal("team=billing"))
+		Expect(fakeRows.CloseCallCount()).To(Equal(1))
+
+		Expect(res.Body).To(MatchJSON("[" + eventJSON + "]"))
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+	})
+
+	It("should write one CSV row per footprint period", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeRows := &eventiofakes.FakeFootprintEventRows{}
+		fakeRows.NextReturnsOnCall(0, true)
+		fakeRows.NextReturnsOnCall(1, false)
+		fakeRows.EventJSONReturnsOnCall(0, []byte(`{
+			"event_guid": "event-1",
+			"plan_guid": "plan-1",
+			"energy_kwh": "3",
+			"carbon_gco2e": "500",
+			"details": [
+				{"start": "2001-01-01T00:00:00+00:00", "stop": "2001-01-01T12:00:00+00:00", "plan_name": "app", "grid_intensity": "200", "energy_kwh": "1.5", "carbon_gco2e": "300"},
+				{"start": "2001-01-01T12:00:00+00:00", "stop": "2001-01-02T00:00:00+00:00", "plan_name": "app", "grid_intensity": "", "energy_kwh": "1.5", "carbon_gco2e": "200"}
+			]
+		}`), nil)
+		fakeStore.GetFootprintEventRowsReturns(fakeRows, nil)
+
+		req := httptest.NewRequest(echo.GET, "/footprint?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02&format=csv", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body.String()).To(Equal("" +
+			"event_guid,event_start,event_stop,resource_guid,resource_name,resource_type,org_guid,org_name,space_guid,space_name,plan_guid,energy_kwh,carbon_gco2e,component_plan_name,component_start,component_stop,component_grid_intensity,component_energy_kwh,component_carbon_gco2e\n" +
+			"event-1,,,,,,,,,,plan-1,3,500,app,2001-01-01T00:00:00+00:00,2001-01-01T12:00:00+00:00,200,1.5,300\n" +
+			"event-1,,,,,,,,,,plan-1,3,500,app,2001-01-01T12:00:00+00:00,2001-01-02T00:00:00+00:00,,1.5,200\n",
+		))
+	})
+
+	It("should return error if GetFootprintEventRows returns error", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeStore.GetFootprintEventRowsReturns(nil, errors.New("query-error"))
+
+		req := httptest.NewRequest(echo.GET, "/footprint?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Body).To(MatchJSON(`{
+			"error": "internal server error"
+		}`))
+		Expect(res.Code).To(Equal(500))
+	})
+
+})
+
+var _ = Describe("FootprintPlansHandler", func() {
+
+	var (
+		ctx       context.Context
+		cancel    context.CancelFunc
+		cfg       Config
+		fakeStore *eventiofakes.FakeEventStore
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		cfg = Config{
+			Authenticator: &authfakes.FakeAuthenticator{},
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should return the footprint plans from the store", func() {
+		fakeStore.GetFootprintPlansReturns([]eventio.FootprintPlan{
+			{
+				Name:          "app",
+				PlanGUID:      "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				ValidFrom:     "2001-01-01",
+				EnergyFormula: "($memory_in_mb/1024.0) * ($time_in_seconds/3600) * 0.0004",
+			},
+		}, nil)
+
+		req := httptest.NewRequest(echo.GET, "/footprint_plans?range_start=2001-01-01&range_stop=2001-02-01", nil)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(fakeStore.GetFootprintPlansCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetFootprintPlansArgsForCall(0)
+		Expect(filter.RangeStart).To(Equal("2001-01-01"))
+		Expect(filter.RangeStop).To(Equal("2001-02-01"))
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{
+			"name": "app",
+			"plan_guid": "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+			"valid_from": "2001-01-01",
+			"energy_formula": "($memory_in_mb/1024.0) * ($time_in_seconds/3600) * 0.0004"
+		}]`))
+	})
+
+	It("should reject an invalid range", func() {
+		req := httptest.NewRequest(echo.GET, "/footprint_plans?range_start=2001-02-01&range_stop=not-a-date", nil)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetFootprintPlansCallCount()).To(Equal(0))
+	})
+
+})
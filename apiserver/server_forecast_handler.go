@@ -21,6 +21,10 @@ func ForecastEventsHandler(store eventio.BillableEventForecaster) echo.HandlerFu
 			}
 		}
 		// parse params
+		format, err := negotiateFormat(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
 		filter := eventio.EventFilter{
 			RangeStart: c.QueryParam("range_start"),
 			RangeStop:  c.QueryParam("range_stop"),
@@ -38,7 +42,7 @@ func ForecastEventsHandler(store eventio.BillableEventForecaster) echo.HandlerFu
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
 
-		storeCtx, cancel := context.WithCancel(context.Background())
+		storeCtx, cancel := context.WithCancel(c.Request().Context())
 		defer cancel()
 
 		// query the store
@@ -48,38 +52,19 @@ func ForecastEventsHandler(store eventio.BillableEventForecaster) echo.HandlerFu
 		}
 		defer rows.Close()
 		// stream response to client
-		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
-		c.Response().WriteHeader(http.StatusOK)
-		if _, err := c.Response().Write([]byte("[\n")); err != nil {
-			return err
-		}
-		c.Response().Flush()
-		next := rows.Next()
-		delim := "\n"
-		for next {
+		enc := newEventEncoder(c, format, billableEventTable)
+		for rows.Next() {
 			b, err := rows.EventJSON()
 			if err != nil {
 				return err
 			}
-			if _, err := c.Response().Write(b); err != nil {
+			if err := enc.Encode(b); err != nil {
 				return err
 			}
-			next = rows.Next()
-			if next {
-				delim = ",\n"
-			} else {
-				delim = "\n"
-			}
-			if _, err := c.Response().Write([]byte(delim)); err != nil {
-				return err
-			}
-			c.Response().Flush()
 		}
-		if _, err := c.Response().Write([]byte("]\n")); err != nil {
+		if err := rows.Err(); err != nil {
 			return err
 		}
-		c.Response().Flush()
-		return rows.Err()
-
+		return enc.End()
 	}
 }
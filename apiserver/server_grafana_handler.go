@@ -0,0 +1,310 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+// The handlers in this file implement the Grafana JSON (SimpleJSON)
+// datasource protocol so billing data can be charted directly in Grafana.
+// See https://grafana.com/grafana/plugins/grafana-simple-json-datasource/
+
+const grafanaOrgGUIDTag = "org_guid"
+
+// grafanaMetrics are the values that can be charted for each grouping
+var grafanaMetrics = []string{"cost", "usage"}
+
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type grafanaTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"`
+}
+
+type grafanaAdhocFilter struct {
+	Key      string `json:"key"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+type grafanaQueryRequest struct {
+	Range        grafanaRange         `json:"range"`
+	IntervalMs   int64                `json:"intervalMs"`
+	Targets      []grafanaTarget      `json:"targets"`
+	AdhocFilters []grafanaAdhocFilter `json:"adhocFilters"`
+}
+
+type grafanaTimeSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaAnnotationRequest struct {
+	Range      grafanaRange           `json:"range"`
+	Annotation map[string]interface{} `json:"annotation"`
+}
+
+type grafanaAnnotation struct {
+	Annotation map[string]interface{} `json:"annotation"`
+	Time       int64                  `json:"time"`
+	Title      string                 `json:"title"`
+	Text       string                 `json:"text"`
+	Tags       []string               `json:"tags"`
+}
+
+type grafanaTagKey struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// grafanaTargets returns every target name that can be queried, eg cost,
+// cost_by_org, usage_by_resource_type
+func grafanaTargets() []string {
+	targets := []string{}
+	for _, metric := range grafanaMetrics {
+		for _, groupBy := range eventio.CostTimeSeriesGroupBys {
+			if groupBy == "" {
+				targets = append(targets, metric)
+			} else {
+				targets = append(targets, metric+"_by_"+groupBy)
+			}
+		}
+	}
+	return targets
+}
+
+func parseGrafanaTarget(target string) (metric string, groupBy string, err error) {
+	for _, m := range grafanaMetrics {
+		if target == m {
+			return m, "", nil
+		}
+		if strings.HasPrefix(target, m+"_by_") {
+			groupBy = strings.TrimPrefix(target, m+"_by_")
+			for _, g := range eventio.CostTimeSeriesGroupBys {
+				if g != "" && g == groupBy {
+					return m, groupBy, nil
+				}
+			}
+		}
+	}
+	return "", "", fmt.Errorf("unknown target '%s'", target)
+}
+
+// grafanaStep picks the coarsest step that still gives at least one point per
+// requested interval
+func grafanaStep(interval time.Duration) string {
+	switch {
+	case interval >= 28*24*time.Hour:
+		return "month"
+	case interval >= 7*24*time.Hour:
+		return "week"
+	default:
+		return "day"
+	}
+}
+
+func grafanaOrgGUIDs(filters []grafanaAdhocFilter) []string {
+	orgGUIDs := []string{}
+	for _, f := range filters {
+		if f.Key == grafanaOrgGUIDTag && f.Operator == "=" && f.Value != "" {
+			orgGUIDs = append(orgGUIDs, f.Value)
+		}
+	}
+	return orgGUIDs
+}
+
+// authorizeGrafana lets admins, and billing managers of every org in the
+// adhoc filters, query the datasource. Queries without org filters need an
+// admin.
+func authorizeGrafana(c echo.Context, uaa auth.Authenticator, orgs []string) error {
+	if ok, err := authorize(c, uaa, orgs); err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err)
+	} else if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	return nil
+}
+
+// authorizeGrafanaMetadata lets anyone with billing access to some org use
+// the endpoints that only describe the datasource, such as /search, so that
+// billing managers can fill in the Grafana query editor
+func authorizeGrafanaMetadata(c echo.Context, uaa auth.Authenticator) error {
+	if ok, err := authorizeAnyBillingAccess(c, uaa); err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err)
+	} else if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+	}
+	return nil
+}
+
+func GrafanaSearchHandler(uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := authorizeGrafanaMetadata(c, uaa); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, grafanaTargets())
+	}
+}
+
+func GrafanaTagKeysHandler(uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if err := authorizeGrafanaMetadata(c, uaa); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, []grafanaTagKey{
+			{Type: "string", Text: grafanaOrgGUIDTag},
+		})
+	}
+}
+
+func GrafanaQueryHandler(store eventio.CostTimeSeriesReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req grafanaQueryRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		requestedOrgs := grafanaOrgGUIDs(req.AdhocFilters)
+		if err := authorizeGrafana(c, uaa, requestedOrgs); err != nil {
+			return err
+		}
+
+		storeCtx, cancel := context.WithCancel(c.Request().Context())
+		defer cancel()
+
+		series := []grafanaTimeSeries{}
+		for _, target := range req.Targets {
+			if target.Target == "" {
+				continue
+			}
+			metric, groupBy, err := parseGrafanaTarget(target.Target)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			filter := eventio.CostTimeSeriesFilter{
+				EventFilter: eventio.EventFilter{
+					RangeStart: req.Range.From.UTC().Format("2006-01-02"),
+					RangeStop:  req.Range.To.UTC().AddDate(0, 0, 1).Format("2006-01-02"),
+					OrgGUIDs:   requestedOrgs,
+				},
+				Step:    grafanaStep(time.Duration(req.IntervalMs) * time.Millisecond),
+				GroupBy: groupBy,
+			}
+			if err := filter.Validate(); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err)
+			}
+			targetSeries, err := queryGrafanaTimeSeries(storeCtx, store, filter, metric, target.Target)
+			if err != nil {
+				return err
+			}
+			series = append(series, targetSeries...)
+		}
+		return c.JSON(http.StatusOK, series)
+	}
+}
+
+func queryGrafanaTimeSeries(ctx context.Context, store eventio.CostTimeSeriesReader, filter eventio.CostTimeSeriesFilter, metric string, name string) ([]grafanaTimeSeries, error) {
+	rows, err := store.GetCostTimeSeriesRows(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []grafanaTimeSeries{}
+	seriesByGroup := map[string]int{}
+	for rows.Next() {
+		point, err := rows.Point()
+		if err != nil {
+			return nil, err
+		}
+		periodStart, err := time.Parse("2006-01-02", point.PeriodStart)
+		if err != nil {
+			return nil, err
+		}
+		var value float64
+		if metric == "usage" {
+			value, err = strconv.ParseFloat(point.InstanceHours, 64)
+		} else {
+			value, err = point.ExVAT.Float64()
+		}
+		if err != nil {
+			return nil, err
+		}
+		i, ok := seriesByGroup[point.Group]
+		if !ok {
+			target := name
+			if filter.GroupBy != "" {
+				target = point.GroupName
+			}
+			series = append(series, grafanaTimeSeries{Target: target, Datapoints: [][2]float64{}})
+			i = len(series) - 1
+			seriesByGroup[point.Group] = i
+		}
+		series[i].Datapoints = append(series[i].Datapoints, [2]float64{
+			value,
+			float64(periodStart.UnixNano() / int64(time.Millisecond)),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// GrafanaAnnotationsHandler marks the points in time where pricing plans
+// changed, which usually explains a step change in cost
+func GrafanaAnnotationsHandler(store eventio.PricingPlanReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var req grafanaAnnotationRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if err := authorizeGrafanaMetadata(c, uaa); err != nil {
+			return err
+		}
+		filter := eventio.TimeRangeFilter{
+			RangeStart: req.Range.From.UTC().Format("2006-01-02"),
+			RangeStop:  req.Range.To.UTC().AddDate(0, 0, 1).Format("2006-01-02"),
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		plans, err := store.GetPricingPlansContext(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		annotations := []grafanaAnnotation{}
+		for _, plan := range plans {
+			validFrom, err := time.Parse(time.RFC3339, plan.ValidFrom)
+			if err != nil {
+				return err
+			}
+			if validFrom.Before(req.Range.From) || validFrom.After(req.Range.To) {
+				continue
+			}
+			annotations = append(annotations, grafanaAnnotation{
+				Annotation: req.Annotation,
+				Time:       validFrom.UnixNano() / int64(time.Millisecond),
+				Title:      "Pricing plan change",
+				Text:       fmt.Sprintf("%s (%s) pricing valid from %s", plan.Name, plan.PlanGUID, plan.ValidFrom),
+				Tags:       []string{"pricing_plan"},
+			})
+		}
+		sort.Slice(annotations, func(i, j int) bool {
+			return annotations[i].Time < annotations[j].Time
+		})
+		return c.JSON(http.StatusOK, annotations)
+	}
+}
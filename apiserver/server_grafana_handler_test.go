@@ -0,0 +1,276 @@
+package apiserver_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Grafana datasource handlers", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID1          = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+	)
+
+	post := func(path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.POST, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "bearer "+token)
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should respond to the connection test", func() {
+		req := httptest.NewRequest(echo.GET, "/grafana/", nil)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+	})
+
+	It("should list the available targets on /search", func() {
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		res := post("/grafana/search", `{"target": ""}`)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[
+			"cost", "cost_by_org", "cost_by_space", "cost_by_plan", "cost_by_resource_type",
+			"usage", "usage_by_org", "usage_by_space", "usage_by_plan", "usage_by_resource_type"
+		]`))
+	})
+
+	It("should list org_guid as a tag key", func() {
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		res := post("/grafana/tag-keys", `{}`)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(res.Body).To(MatchJSON(`[{"type": "string", "text": "org_guid"}]`))
+	})
+
+	DescribeTable("should let billing managers of any org use the metadata endpoints",
+		func(path string, body string) {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasAnyBillingAccessReturns(true, nil)
+
+			res := post(path, body)
+
+			Expect(res.Code).To(Equal(200))
+			Expect(fakeAuthorizer.HasAnyBillingAccessCallCount()).To(Equal(1))
+			Expect(fakeAuthorizer.HasBillingAccessCallCount()).To(Equal(0))
+		},
+		Entry("search", "/grafana/search", `{"target": ""}`),
+		Entry("tag-keys", "/grafana/tag-keys", `{}`),
+		Entry("annotations", "/grafana/annotations", `{
+			"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-02-01T00:00:00.000Z"},
+			"annotation": {"name": "plans", "enable": true}
+		}`),
+	)
+
+	DescribeTable("should reject users without billing access from the metadata endpoints",
+		func(path string, body string) {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasAnyBillingAccessReturns(false, nil)
+
+			res := post(path, body)
+
+			Expect(res.Code).To(Equal(401))
+			Expect(fakeStore.GetPricingPlansContextCallCount()).To(Equal(0))
+		},
+		Entry("search", "/grafana/search", `{"target": ""}`),
+		Entry("tag-keys", "/grafana/tag-keys", `{}`),
+		Entry("annotations", "/grafana/annotations", `{
+			"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-02-01T00:00:00.000Z"},
+			"annotation": {"name": "plans", "enable": true}
+		}`),
+	)
+
+	It("should reject requests without a token", func() {
+		token = ""
+		defer func() { token = "ACCESS_GRANTED_TOKEN" }()
+
+		res := post("/grafana/search", `{"target": ""}`)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeAuthenticator.NewAuthorizerCallCount()).To(Equal(0))
+	})
+
+	Describe("/query", func() {
+		var fakeRows *eventiofakes.FakeCostTimeSeriesRows
+
+		BeforeEach(func() {
+			fakeRows = &eventiofakes.FakeCostTimeSeriesRows{}
+			fakeRows.NextReturnsOnCall(0, true)
+			fakeRows.NextReturnsOnCall(1, true)
+			fakeRows.NextReturnsOnCall(2, true)
+			fakeRows.NextReturnsOnCall(3, false)
+			fakeRows.PointReturnsOnCall(0, &eventio.CostTimeSeriesPoint{
+				PeriodStart: "2001-01-01", Group: "a", GroupName: "space-a", ExVAT: "1.5", InstanceHours: "24",
+			}, nil)
+			fakeRows.PointReturnsOnCall(1, &eventio.CostTimeSeriesPoint{
+				PeriodStart: "2001-01-02", Group: "a", GroupName: "space-a", ExVAT: "0", InstanceHours: "0",
+			}, nil)
+			fakeRows.PointReturnsOnCall(2, &eventio.CostTimeSeriesPoint{
+				PeriodStart: "2001-01-01", Group: "b", GroupName: "space-b", ExVAT: "2", InstanceHours: "12",
+			}, nil)
+			fakeStore.GetCostTimeSeriesRowsReturns(fakeRows, nil)
+		})
+
+		It("should require admin when no org_guid filter is given", func() {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+			res := post("/grafana/query", `{
+				"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-01-02T23:59:59.000Z"},
+				"intervalMs": 86400000,
+				"targets": [{"target": "cost_by_space", "refId": "A", "type": "timeserie"}]
+			}`)
+
+			Expect(res.Code).To(Equal(401))
+			Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(BeEmpty())
+			Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(0))
+		})
+
+		It("should allow billing managers to query the orgs in the adhoc filters", func() {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasBillingAccessReturns(true, nil)
+
+			res := post("/grafana/query", `{
+				"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-01-02T23:59:59.000Z"},
+				"intervalMs": 86400000,
+				"targets": [{"target": "cost_by_space", "refId": "A", "type": "timeserie"}],
+				"adhocFilters": [{"key": "org_guid", "operator": "=", "value": "`+orgGUID1+`"}]
+			}`)
+
+			Expect(res.Code).To(Equal(200))
+			Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID1}))
+
+			Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(1))
+			_, filter := fakeStore.GetCostTimeSeriesRowsArgsForCall(0)
+			Expect(filter.RangeStart).To(Equal("2001-01-01"))
+			Expect(filter.RangeStop).To(Equal("2001-01-03"))
+			Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1}))
+			Expect(filter.Step).To(Equal("day"))
+			Expect(filter.GroupBy).To(Equal("space"))
+			Expect(fakeRows.CloseCallCount()).To(Equal(1))
+
+			Expect(res.Body).To(MatchJSON(`[
+				{"target": "space-a", "datapoints": [[1.5, 978307200000], [0, 978393600000]]},
+				{"target": "space-b", "datapoints": [[2, 978307200000]]}
+			]`))
+		})
+
+		It("should pick a monthly step for long intervals", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+
+			res := post("/grafana/query", `{
+				"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-03-01T00:00:00.000Z"},
+				"intervalMs": 2592000000,
+				"targets": [{"target": "cost_by_space", "refId": "A", "type": "timeserie"}]
+			}`)
+
+			Expect(res.Code).To(Equal(200))
+			_, filter := fakeStore.GetCostTimeSeriesRowsArgsForCall(0)
+			Expect(filter.Step).To(Equal("month"))
+		})
+
+		It("should chart instance hours for the usage targets", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+
+			res := post("/grafana/query", `{
+				"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-01-02T23:59:59.000Z"},
+				"intervalMs": 86400000,
+				"targets": [{"target": "usage_by_space", "refId": "A", "type": "timeserie"}]
+			}`)
+
+			Expect(res.Code).To(Equal(200))
+			_, filter := fakeStore.GetCostTimeSeriesRowsArgsForCall(0)
+			Expect(filter.GroupBy).To(Equal("space"))
+			Expect(res.Body).To(MatchJSON(`[
+				{"target": "space-a", "datapoints": [[24, 978307200000], [0, 978393600000]]},
+				{"target": "space-b", "datapoints": [[12, 978307200000]]}
+			]`))
+		})
+
+		It("should reject unknown targets", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+
+			res := post("/grafana/query", `{
+				"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-01-02T00:00:00.000Z"},
+				"targets": [{"target": "cost_by_colour", "refId": "A", "type": "timeserie"}]
+			}`)
+
+			Expect(res.Code).To(Equal(400))
+			Expect(fakeStore.GetCostTimeSeriesRowsCallCount()).To(Equal(0))
+		})
+	})
+
+	It("should annotate pricing plan changes within the range", func() {
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeStore.GetPricingPlansContextReturns([]eventio.PricingPlan{
+			{Name: "old-plan", PlanGUID: "p1", ValidFrom: "2000-01-01T00:00:00+00:00"},
+			{Name: "new-plan", PlanGUID: "p1", ValidFrom: "2001-01-15T00:00:00+00:00"},
+		}, nil)
+
+		res := post("/grafana/annotations", `{
+			"range": {"from": "2001-01-01T00:00:00.000Z", "to": "2001-02-01T00:00:00.000Z"},
+			"annotation": {"name": "plans", "enable": true}
+		}`)
+
+		Expect(res.Code).To(Equal(200))
+		_, filter := fakeStore.GetPricingPlansContextArgsForCall(0)
+		Expect(filter.RangeStart).To(Equal("2001-01-01"))
+		Expect(filter.RangeStop).To(Equal("2001-02-02"))
+		Expect(res.Body).To(MatchJSON(`[{
+			"annotation": {"name": "plans", "enable": true},
+			"time": 979516800000,
+			"title": "Pricing plan change",
+			"text": "new-plan (p1) pricing valid from 2001-01-15T00:00:00+00:00",
+			"tags": ["pricing_plan"]
+		}]`))
+	})
+})
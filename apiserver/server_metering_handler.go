@@ -0,0 +1,178 @@
+package apiserver
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// MeteringBrokerHeader names the broker posting metering records
+	MeteringBrokerHeader = "X-Broker-Name"
+	// MeteringTimestampHeader holds the unix time in seconds the request
+	// was signed at
+	MeteringTimestampHeader = "X-Metering-Timestamp"
+	// MeteringSignatureHeader holds the hex HMAC-SHA256 of the timestamp, a
+	// full stop and the request body, keyed with the broker's metering
+	// key, as "sha256=<hex>"
+	MeteringSignatureHeader = "X-Metering-Signature"
+	// MeteringMaxClockSkew is how far the timestamp of a request can be
+	// from the time it is received, which limits how long a captured
+	// request can be replayed for
+	MeteringMaxClockSkew = 5 * time.Minute
+	// maxMeteringBodySize limits the size of a batch of metering records
+	maxMeteringBodySize = 1024 * 1024
+)
+
+// MeteringBroker is a service broker that can post metering records. Key
+// is the key it signs requests with, and its records must be for instances
+// of the services in ServiceGUIDs.
+type MeteringBroker struct {
+	Key          string
+	ServiceGUIDs []string
+}
+
+type meteringRequest struct {
+	Records []eventio.MeteringRecord `json:"records"`
+}
+
+// MeteringHandler stores the metering records that service brokers post
+// for their service instances. Brokers are not CF users, so rather than a
+// token each request is signed with the key configured for the broker, and
+// brokers can only meter instances of their own services. Records are
+// priced from the next refresh.
+func MeteringHandler(store eventio.RawEventWriter, brokers map[string]MeteringBroker) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		broker := c.Request().Header.Get(MeteringBrokerHeader)
+		cfg, ok := brokers[broker]
+		if broker == "" || !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unknown broker")
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxMeteringBodySize+1))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if len(body) > maxMeteringBodySize {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("metering requests must be at most %d bytes", maxMeteringBodySize))
+		}
+		timestamp := c.Request().Header.Get(MeteringTimestampHeader)
+		signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid timestamp")
+		}
+		if !validMeteringSignature(cfg.Key, timestamp, body, c.Request().Header.Get(MeteringSignatureHeader)) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid signature")
+		}
+		if skew := time.Since(time.Unix(signedAt, 0)); skew > MeteringMaxClockSkew || skew < -MeteringMaxClockSkew {
+			return echo.NewHTTPError(http.StatusUnauthorized, "expired timestamp")
+		}
+
+		var req meteringRequest
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if len(req.Records) == 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "no metering records")
+		}
+		events := make([]eventio.RawEvent, 0, len(req.Records))
+		for i, record := range req.Records {
+			record.Broker = broker
+			record.ServiceGUIDs = cfg.ServiceGUIDs
+			event, err := record.RawEvent()
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("record %d: %s", i, err))
+			}
+			events = append(events, event)
+		}
+		if err := store.StoreEvents(events); err != nil {
+			if errors.Is(err, eventio.ErrUnknownServiceInstance) {
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+			}
+			return err
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"records": len(events)})
+	}
+}
+
+func validMeteringSignature(key string, timestamp string, body []byte, signature string) bool {
+	if !strings.HasPrefix(signature, "sha256=") {
+		return false
+	}
+	sum, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hmac.Equal(sum, mac.Sum(nil))
+}
+
+// ParseMeteringBrokers parses the keys brokers sign metering requests with,
+// in the form "rds-broker=key1,s3-broker=key2", and the guids of the
+// services each broker offers, in the form
+// "rds-broker=guid1;guid2,s3-broker=guid3". Every broker must have both.
+func ParseMeteringBrokers(keys string, services string) (map[string]MeteringBroker, error) {
+	brokerKeys, err := parseBrokerList(keys)
+	if err != nil {
+		return nil, fmt.Errorf("metering keys must be in the form broker=key,broker=key")
+	}
+	brokerServices, err := parseBrokerList(services)
+	if err != nil {
+		return nil, fmt.Errorf("metering services must be in the form broker=guid;guid,broker=guid")
+	}
+	brokers := map[string]MeteringBroker{}
+	for broker, key := range brokerKeys {
+		if _, ok := brokerServices[broker]; !ok {
+			return nil, fmt.Errorf("metering broker %s has a key but no services", broker)
+		}
+		brokers[broker] = MeteringBroker{Key: key}
+	}
+	for broker, guids := range brokerServices {
+		b, ok := brokers[broker]
+		if !ok {
+			return nil, fmt.Errorf("metering broker %s has services but no key", broker)
+		}
+		for _, guid := range strings.Split(guids, ";") {
+			guid = strings.ToLower(strings.TrimSpace(guid))
+			if _, err := uuid.FromString(guid); err != nil || len(guid) != 36 {
+				return nil, fmt.Errorf("metering broker %s service guids must be guids - got %s", broker, guid)
+			}
+			b.ServiceGUIDs = append(b.ServiceGUIDs, guid)
+		}
+		brokers[broker] = b
+	}
+	return brokers, nil
+}
+
+// parseBrokerList parses entries in the form "broker=value,broker=value"
+func parseBrokerList(s string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.New("invalid entry")
+		}
+		values[parts[0]] = parts[1]
+	}
+	return values, nil
+}
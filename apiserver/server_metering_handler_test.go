@@ -0,0 +1,247 @@
+package apiserver_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("POST /metering", func() {
+
+	const (
+		brokerKey           = "s3-broker-key"
+		serviceGUID         = "efadb775-58c4-4e17-8087-6d0f4febc489"
+		serviceInstanceGUID = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+	)
+
+	var (
+		ctx       context.Context
+		cancel    context.CancelFunc
+		cfg       Config
+		fakeStore *eventiofakes.FakeEventStore
+		body      string
+		timestamp string
+	)
+
+	sign := func(key string, body string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(timestamp + "." + body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	request := func(broker string, signature string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.POST, "/metering", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(MeteringBrokerHeader, broker)
+		req.Header.Set(MeteringTimestampHeader, timestamp)
+		req.Header.Set(MeteringSignatureHeader, signature)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		cfg = Config{
+			Logger:      lager.NewLogger("test"),
+			Store:       fakeStore,
+			EnablePanic: true,
+			MeteringBrokers: map[string]MeteringBroker{
+				"s3-broker": {Key: brokerKey, ServiceGUIDs: []string{serviceGUID}},
+			},
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		timestamp = strconv.FormatInt(time.Now().Unix(), 10)
+		body = `{"records": [{
+			"idempotency_key": "bucket-1/2001-01-01T00",
+			"service_instance_guid": "` + serviceInstanceGUID + `",
+			"metric": "storage_gb_hours",
+			"quantity": 12.5,
+			"start": "2001-01-01T00:00:00Z",
+			"stop": "2001-01-01T01:00:00Z"
+		}]}`
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should store signed records as metering events of the broker and its services", func() {
+		res := request("s3-broker", sign(brokerKey, body), body)
+
+		Expect(res.Code).To(Equal(201))
+		Expect(res.Body.String()).To(MatchJSON(`{"records": 1}`))
+		Expect(fakeStore.StoreEventsCallCount()).To(Equal(1))
+		events := fakeStore.StoreEventsArgsForCall(0)
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Kind).To(Equal(eventio.MeteringKind))
+
+		var record eventio.MeteringRecord
+		Expect(json.Unmarshal(events[0].RawMessage, &record)).To(Succeed())
+		Expect(record).To(Equal(eventio.MeteringRecord{
+			Broker:              "s3-broker",
+			ServiceGUIDs:        []string{serviceGUID},
+			IdempotencyKey:      "bucket-1/2001-01-01T00",
+			ServiceInstanceGUID: serviceInstanceGUID,
+			Metric:              "storage_gb_hours",
+			Quantity:            "12.5",
+			Start:               "2001-01-01T00:00:00Z",
+			Stop:                "2001-01-01T01:00:00Z",
+		}))
+	})
+
+	It("should record the broker that signed the request, not the one in the body", func() {
+		body = strings.Replace(body, `"metric"`, `"broker": "rds-broker", "metric"`, 1)
+		res := request("s3-broker", sign(brokerKey, body), body)
+
+		Expect(res.Code).To(Equal(201))
+		Expect(string(fakeStore.StoreEventsArgsForCall(0)[0].RawMessage)).To(ContainSubstring(`"broker":"s3-broker"`))
+	})
+
+	It("should record the services of the broker that signed the request, not those in the body", func() {
+		body = strings.Replace(body, `"metric"`, `"service_guids": ["aaaaaaaa-0000-0000-0000-000000000001"], "metric"`, 1)
+		res := request("s3-broker", sign(brokerKey, body), body)
+
+		Expect(res.Code).To(Equal(201))
+		Expect(string(fakeStore.StoreEventsArgsForCall(0)[0].RawMessage)).To(ContainSubstring(`"service_guids":["` + serviceGUID + `"]`))
+	})
+
+	DescribeTable("should reject requests signed too long ago or in the future",
+		func(offset time.Duration) {
+			timestamp = strconv.FormatInt(time.Now().Add(offset).Unix(), 10)
+			res := request("s3-broker", sign(brokerKey, body), body)
+
+			Expect(res.Code).To(Equal(401))
+			Expect(res.Body.String()).To(ContainSubstring("expired timestamp"))
+			Expect(fakeStore.StoreEventsCallCount()).To(Equal(0))
+		},
+		Entry("replayed", -MeteringMaxClockSkew-time.Minute),
+		Entry("from the future", MeteringMaxClockSkew+time.Minute),
+	)
+
+	DescribeTable("should reject unauthenticated requests",
+		func(broker string, signature func() string) {
+			res := request(broker, signature(), body)
+
+			Expect(res.Code).To(Equal(401))
+			Expect(fakeStore.StoreEventsCallCount()).To(Equal(0))
+		},
+		Entry("unknown broker", "rds-broker", func() string { return sign(brokerKey, body) }),
+		Entry("no broker", "", func() string { return sign(brokerKey, body) }),
+		Entry("wrong key", "s3-broker", func() string { return sign("guess", body) }),
+		Entry("signature of another body", "s3-broker", func() string { return sign(brokerKey, body+" ") }),
+		Entry("no signature", "s3-broker", func() string { return "" }),
+		Entry("signature without its algorithm", "s3-broker", func() string { return strings.TrimPrefix(sign(brokerKey, body), "sha256=") }),
+		Entry("signature of the body without the timestamp", "s3-broker", func() string {
+			mac := hmac.New(sha256.New, []byte(brokerKey))
+			mac.Write([]byte(body))
+			return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}),
+	)
+
+	It("should reject requests without a timestamp", func() {
+		signature := sign(brokerKey, body)
+		timestamp = ""
+		res := request("s3-broker", signature, body)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeStore.StoreEventsCallCount()).To(Equal(0))
+	})
+
+	DescribeTable("should reject invalid records",
+		func(body string, expectedErr string) {
+			res := request("s3-broker", sign(brokerKey, body), body)
+
+			Expect(res.Code).To(Equal(400))
+			Expect(res.Body.String()).To(ContainSubstring(expectedErr))
+			Expect(fakeStore.StoreEventsCallCount()).To(Equal(0))
+		},
+		Entry("not json", `records`, "invalid character"),
+		Entry("no records", `{"records": []}`, "no metering records"),
+		Entry("invalid record", `{"records": [{"idempotency_key": "k", "service_instance_guid": "bucket"}]}`, "record 0: metering record service_instance_guid must be a guid"),
+	)
+
+	It("should reject records for unknown service instances", func() {
+		fakeStore.StoreEventsReturns(fmt.Errorf("%w: %s", eventio.ErrUnknownServiceInstance, serviceInstanceGUID))
+
+		res := request("s3-broker", sign(brokerKey, body), body)
+
+		Expect(res.Code).To(Equal(422))
+		Expect(res.Body.String()).To(ContainSubstring("unknown service instance: " + serviceInstanceGUID))
+	})
+
+	It("should fail if the records cannot be stored", func() {
+		fakeStore.StoreEventsReturns(errors.New("no database"))
+
+		res := request("s3-broker", sign(brokerKey, body), body)
+
+		Expect(res.Code).To(Equal(500))
+	})
+})
+
+var _ = Describe("ParseMeteringBrokers", func() {
+	const (
+		rdsGUID      = "efadb775-58c4-4e17-8087-6d0f4febc489"
+		rdsOtherGUID = "aaaaaaaa-0000-0000-0000-000000000001"
+		s3GUID       = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+	)
+
+	It("should parse broker keys and services", func() {
+		brokers, err := ParseMeteringBrokers(
+			" rds-broker=key1, s3-broker=key=2 ,",
+			"rds-broker="+rdsGUID+";"+strings.ToUpper(rdsOtherGUID)+",s3-broker="+s3GUID,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokers).To(Equal(map[string]MeteringBroker{
+			"rds-broker": {Key: "key1", ServiceGUIDs: []string{rdsGUID, rdsOtherGUID}},
+			"s3-broker":  {Key: "key=2", ServiceGUIDs: []string{s3GUID}},
+		}))
+	})
+
+	It("should return no brokers for empty strings", func() {
+		brokers, err := ParseMeteringBrokers("", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokers).To(BeEmpty())
+	})
+
+	It("should reject entries without a key without revealing them", func() {
+		_, err := ParseMeteringBrokers("secret-key", "")
+		Expect(err).To(MatchError("metering keys must be in the form broker=key,broker=key"))
+	})
+
+	It("should reject brokers with a key but no services", func() {
+		_, err := ParseMeteringBrokers("rds-broker=key1", "")
+		Expect(err).To(MatchError("metering broker rds-broker has a key but no services"))
+	})
+
+	It("should reject brokers with services but no key", func() {
+		_, err := ParseMeteringBrokers("", "rds-broker="+rdsGUID)
+		Expect(err).To(MatchError("metering broker rds-broker has services but no key"))
+	})
+
+	It("should reject services that are not guids", func() {
+		_, err := ParseMeteringBrokers("rds-broker=key1", "rds-broker=postgres")
+		Expect(err).To(MatchError(ContainSubstring("service guids must be guids")))
+	})
+})
@@ -0,0 +1,101 @@
+package apiserver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// maxConcurrentMonthFetches caps how many months a single request may query
+// at once. Each running fetch holds its own database connection.
+const maxConcurrentMonthFetches = 4
+
+// monthStreamBufferSize is how many events a month may fetch ahead of the
+// month being written to the client before its fetch is paused
+const monthStreamBufferSize = 1000
+
+// monthFetchFunc fetches the events for months[i], passing each one to emit
+// in the order they should be written
+type monthFetchFunc func(ctx context.Context, i int, month eventio.EventFilter, emit func(eventJSON []byte) error) error
+
+// streamMonths fetches up to concurrency months at a time and passes their
+// events to write in month order. Months are started in order, so the month
+// being written always has a running fetch and later months wait for buffer
+// space. The first error from a fetch or from write cancels every other
+// fetch and is returned once they have all stopped.
+func streamMonths(ctx context.Context, months []eventio.EventFilter, concurrency int, fetch monthFetchFunc, write func(eventJSON []byte) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	// errs[i] is written before streams[i] is closed, so it is safe to
+	// read once the stream has been drained
+	streams := make([]chan []byte, len(months))
+	errs := make([]error, len(months))
+	for i := range months {
+		streams[i] = make(chan []byte, monthStreamBufferSize)
+	}
+
+	// the first fetch to fail stops the rest straight away rather than
+	// waiting for the writer to reach it
+	var firstErr error
+	var failOnce sync.Once
+	fail := func(err error) {
+		failOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		slots := make(chan struct{}, concurrency)
+		for i, month := range months {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				// months that never started still need to report why
+				for j := i; j < len(months); j++ {
+					errs[j] = ctx.Err()
+					close(streams[j])
+				}
+				return
+			}
+			wg.Add(1)
+			go func(i int, month eventio.EventFilter) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				defer close(streams[i])
+				errs[i] = fetch(ctx, i, month, func(eventJSON []byte) error {
+					select {
+					case streams[i] <- eventJSON:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				})
+				if errs[i] != nil {
+					fail(errs[i])
+				}
+			}(i, month)
+		}
+	}()
+
+	for i := range months {
+		for eventJSON := range streams[i] {
+			if err := write(eventJSON); err != nil {
+				return err
+			}
+		}
+		if errs[i] != nil {
+			failOnce.Do(func() { firstErr = errs[i] })
+			return firstErr
+		}
+	}
+	return nil
+}
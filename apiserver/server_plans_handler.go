@@ -13,7 +13,7 @@ func PricingPlansHandler(store eventio.PricingPlanReader) echo.HandlerFunc {
 			RangeStart: c.QueryParam("range_start"),
 			RangeStop:  c.QueryParam("range_stop"),
 		}
-		plans, err := store.GetPricingPlans(filter)
+		plans, err := store.GetPricingPlansContext(c.Request().Context(), filter)
 		if err != nil {
 			return err
 		}
@@ -49,7 +49,7 @@ var _ = Describe("PricingPlansHandler", func() {
 	})
 
 	It("should request the plans from the store and return json", func() {
-		fakeStore.GetPricingPlansReturns([]eventio.PricingPlan{
+		fakeStore.GetPricingPlansContextReturns([]eventio.PricingPlan{
 			{
 				PlanGUID:      eventstore.ComputePlanGUID,
 				ValidFrom:     "2001-01-01",
@@ -106,9 +106,9 @@ var _ = Describe("PricingPlansHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetPricingPlansCallCount()).To(Equal(1))
+		Expect(fakeStore.GetPricingPlansContextCallCount()).To(Equal(1))
 
-		filter := fakeStore.GetPricingPlansArgsForCall(0)
+		_, filter := fakeStore.GetPricingPlansContextArgsForCall(0)
 		Expect(filter.RangeStart).To(Equal(rangeStart))
 		Expect(filter.RangeStop).To(Equal(rangeStop))
 
@@ -0,0 +1,45 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+// ResourceHistoryHandler explains the charges for a single resource with
+// the usage events, intervals and priced components behind them. Access
+// is authorized through the orgs the resource belongs to.
+func ResourceHistoryHandler(store eventio.ResourceHistoryReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		filter := eventio.ResourceHistoryFilter{
+			ResourceGUID: c.Param("resource_guid"),
+			RangeStart:   c.QueryParam("range_start"),
+			RangeStop:    c.QueryParam("range_stop"),
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		history, err := store.GetResourceHistoryContext(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		// always authorize, even when nothing is known about the resource,
+		// so that resource guids can't be probed without credentials
+		orgs := []string{}
+		if history != nil {
+			orgs = history.OrgGUIDs
+		}
+		if ok, err := authorize(c, uaa, orgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if history == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("no history for resource %s", filter.ResourceGUID))
+		}
+		return c.JSON(http.StatusOK, history)
+	}
+}
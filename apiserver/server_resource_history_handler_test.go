@@ -0,0 +1,160 @@
+package apiserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"time"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResourceHistoryHandler", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID           = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		resourceGUID      = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+		historyURL        = "/resources/" + resourceGUID + "/history?range_start=2001-01-01&range_stop=2001-02-01"
+		history           eventio.ResourceHistory
+	)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+
+		history = eventio.ResourceHistory{
+			ResourceGUID: resourceGUID,
+			OrgGUIDs:     []string{orgGUID},
+			UsageEvents: []eventio.RawEvent{{
+				GUID:       "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				Kind:       "app",
+				RawMessage: json.RawMessage(`{"state":"STARTED"}`),
+				CreatedAt:  time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+			}},
+			Events: []eventio.ResourceHistoryEvent{{
+				UsageEvent: eventio.UsageEvent{
+					EventGUID:    "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+					ResourceGUID: resourceGUID,
+					OrgGUID:      orgGUID,
+				},
+				Change: eventio.ResourceChangeStart,
+			}},
+			Components: []eventio.ResourceHistoryComponent{{
+				EventGUID:     "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				Name:          "compute",
+				Formula:       "$number_of_nodes * $time_in_seconds * ($memory_in_mb/1024.0) * 0.01",
+				MemoryInMB:    "1024",
+				NumberOfNodes: 2,
+				TimeInSeconds: "3600",
+				CurrencyRate:  "1",
+				VATRate:       "0.2",
+				ExVAT:         "72",
+				IncVAT:        "86.4",
+			}},
+		}
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should return the history to someone with billing access to the resource's org", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(true, nil)
+		fakeStore.GetResourceHistoryContextReturns(&history, nil)
+
+		res := request(historyURL)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID}))
+		_, filter := fakeStore.GetResourceHistoryContextArgsForCall(0)
+		Expect(filter).To(Equal(eventio.ResourceHistoryFilter{
+			ResourceGUID: resourceGUID,
+			RangeStart:   "2001-01-01",
+			RangeStop:    "2001-02-01",
+		}))
+		var body eventio.ResourceHistory
+		Expect(json.Unmarshal(res.Body.Bytes(), &body)).To(Succeed())
+		Expect(body).To(Equal(history))
+		Expect(res.Body.String()).To(ContainSubstring(`"change":"start"`))
+		Expect(res.Body.String()).To(ContainSubstring(`"time_in_seconds":"3600"`))
+	})
+
+	It("should refuse someone without billing access to the resource's org", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+		fakeStore.GetResourceHistoryContextReturns(&history, nil)
+
+		res := request(historyURL)
+
+		Expect(res.Code).To(Equal(401))
+		Expect(res.Body.String()).ToNot(ContainSubstring(resourceGUID))
+	})
+
+	It("should only tell administrators that a resource has no history", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+		fakeStore.GetResourceHistoryContextReturns(nil, nil)
+
+		res := request(historyURL)
+		Expect(res.Code).To(Equal(401))
+
+		fakeAuthorizer.AdminReturns(true, nil)
+		res = request(historyURL)
+		Expect(res.Code).To(Equal(404))
+	})
+
+	It("should reject a resource guid that is not a guid", func() {
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		res := request("/resources/not-a-guid/history?range_start=2001-01-01&range_stop=2001-02-01")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetResourceHistoryContextCallCount()).To(Equal(0))
+	})
+
+	It("should reject an invalid range", func() {
+		fakeAuthorizer.AdminReturns(true, nil)
+
+		res := request("/resources/" + resourceGUID + "/history?range_start=2001-01-01")
+
+		Expect(res.Code).To(Equal(400))
+		Expect(fakeStore.GetResourceHistoryContextCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,240 @@
+package apiserver
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	formatHTML = "html"
+	formatPDF  = "pdf"
+
+	mimeApplicationPDF = "application/pdf"
+)
+
+// statementMIMETypes lists the formats a single statement can be downloaded in
+var statementMIMETypes = []formatMIMEType{
+	{formatJSON, echo.MIMEApplicationJSON},
+	{formatHTML, echo.MIMETextHTML},
+	{formatPDF, mimeApplicationPDF},
+}
+
+func negotiateStatementFormat(c echo.Context) (string, error) {
+	return negotiateFormatFrom(c, statementMIMETypes, "json, html or pdf")
+}
+
+func renderStatement(c echo.Context, format string, statement *eventio.Statement) error {
+	switch format {
+	case formatHTML:
+		var buf bytes.Buffer
+		if err := statementHTMLTemplate.Execute(&buf, statement); err != nil {
+			return err
+		}
+		return c.HTMLBlob(http.StatusOK, buf.Bytes())
+	case formatPDF:
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(
+			`attachment; filename="statement-%d.pdf"`, statement.StatementNumber,
+		))
+		return c.Blob(http.StatusOK, mimeApplicationPDF, renderStatementPDF(statement))
+	default:
+		return c.JSON(http.StatusOK, statement)
+	}
+}
+
+// statementPeriodName describes the month a statement covers, eg January 2001
+func statementPeriodName(periodStart string) string {
+	t, err := time.Parse("2006-01-02", periodStart)
+	if err != nil {
+		return periodStart
+	}
+	return t.Format("January 2006")
+}
+
+// statementVATPercent formats a VAT rate such as 0.2 as 20%
+func statementVATPercent(rate string) string {
+	percent, err := eventio.Money(rate).MulRate("100")
+	if err != nil {
+		return rate
+	}
+	return string(percent) + "%"
+}
+
+var statementHTMLTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"period":  statementPeriodName,
+	"percent": statementVATPercent,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Statement {{.StatementNumber}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.25em 1em; text-align: left; }
+td.amount, th.amount { text-align: right; }
+tfoot td { font-weight: bold; }
+</style>
+</head>
+<body>
+<h1>Statement {{.StatementNumber}}</h1>
+<dl>
+<dt>Account</dt><dd>{{.AccountName}} ({{.AccountID}})</dd>
+<dt>Organisations</dt><dd>{{range $i, $org := .OrgGUIDs}}{{if $i}}, {{end}}{{$org}}{{end}}</dd>
+<dt>Period</dt><dd>{{period .PeriodStart}}</dd>
+<dt>Issued</dt><dd>{{.IssuedAt}}</dd>
+{{- if .Replaces}}
+<dt>Replaces</dt><dd>Statement {{.Replaces}}</dd>
+{{- end}}
+</dl>
+{{- with .CreditNote}}
+<p><strong>Cancelled by credit note {{.CreditNoteNumber}} issued {{.IssuedAt}}: {{.Reason}}</strong></p>
+{{- end}}
+<table>
+<thead>
+<tr><th>Service</th><th>Plan</th><th>VAT code</th><th class="amount">Amount ({{.CurrencyCode}})</th></tr>
+</thead>
+<tbody>
+{{- range .LineItems}}
+<tr><td>{{.ResourceType}}</td><td>{{.PlanName}}</td><td>{{.VatCode}}</td><td class="amount">{{.ExVAT}}</td></tr>
+{{- end}}
+</tbody>
+<tfoot>
+<tr><td colspan="3">Subtotal</td><td class="amount">{{.Subtotal}}</td></tr>
+{{- range .VAT}}
+<tr><td colspan="3">VAT {{.VatCode}} at {{percent .VatRate}} on {{.ExVAT}}</td><td class="amount">{{.VAT}}</td></tr>
+{{- end}}
+<tr><td colspan="3">Total</td><td class="amount">{{.Total}}</td></tr>
+</tfoot>
+</table>
+</body>
+</html>
+`))
+
+// statementTextLines lays out a statement as fixed width lines of text for
+// rendering in a monospaced font
+func statementTextLines(statement *eventio.Statement) []string {
+	row := func(service, plan, vatCode, amount string) string {
+		return fmt.Sprintf("%-16.16s %-40.40s %-12.12s %14s", service, plan, vatCode, amount)
+	}
+	lines := []string{
+		fmt.Sprintf("STATEMENT %d", statement.StatementNumber),
+		"",
+		fmt.Sprintf("Account:       %s (%s)", statement.AccountName, statement.AccountID),
+		fmt.Sprintf("Organisations: %s", strings.Join(statement.OrgGUIDs, ", ")),
+		fmt.Sprintf("Period:        %s", statementPeriodName(statement.PeriodStart)),
+		fmt.Sprintf("Issued:        %s", statement.IssuedAt),
+	}
+	if statement.Replaces != nil {
+		lines = append(lines, fmt.Sprintf("Replaces:      Statement %d", *statement.Replaces))
+	}
+	if cn := statement.CreditNote; cn != nil {
+		lines = append(lines, "", fmt.Sprintf(
+			"CANCELLED by credit note %d issued %s: %s",
+			cn.CreditNoteNumber, cn.IssuedAt, cn.Reason,
+		))
+	}
+	lines = append(lines,
+		"",
+		row("Service", "Plan", "VAT code", "Amount ("+statement.CurrencyCode+")"),
+		strings.Repeat("-", 85),
+	)
+	for _, item := range statement.LineItems {
+		lines = append(lines, row(item.ResourceType, item.PlanName, item.VatCode, string(item.ExVAT)))
+	}
+	lines = append(lines,
+		strings.Repeat("-", 85),
+		row("Subtotal", "", "", string(statement.Subtotal)),
+	)
+	for _, vat := range statement.VAT {
+		lines = append(lines, row("VAT", fmt.Sprintf("%s at %s on %s", vat.VatCode, statementVATPercent(vat.VatRate), vat.ExVAT), "", string(vat.VAT)))
+	}
+	lines = append(lines, row("Total", "", "", string(statement.Total)))
+	return lines
+}
+
+// renderStatementPDF writes the statement as a minimal PDF document using
+// the standard Courier font, so no fonts need to be embedded
+func renderStatementPDF(statement *eventio.Statement) []byte {
+	const (
+		pageWidth    = 595 // A4 in points
+		pageHeight   = 842
+		margin       = 40
+		fontSize     = 9
+		lineHeight   = 12
+		linesPerPage = (pageHeight - 2*margin) / lineHeight
+	)
+
+	lines := statementTextLines(statement)
+	pages := [][]string{}
+	for len(lines) > linesPerPage {
+		pages = append(pages, lines[:linesPerPage])
+		lines = lines[linesPerPage:]
+	}
+	pages = append(pages, lines)
+
+	// objects 1 and 2 are the catalog and page tree, 3 is the font, then
+	// each page is followed by its content stream
+	objects := []string{"", "", "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>"}
+	kids := []string{}
+	for _, page := range pages {
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, lineHeight, margin, pageHeight-margin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) '\n", pdfEscape(line))
+		}
+		content.WriteString("ET")
+		pageObj := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObj))
+		objects = append(objects,
+			fmt.Sprintf(
+				"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, pageObj+1,
+			),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R /Lang (en-GB) >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfEscape makes s safe to use in a PDF string literal. Characters outside
+// Latin-1 cannot be represented in WinAnsiEncoding and are replaced with ?
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
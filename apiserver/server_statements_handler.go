@@ -0,0 +1,150 @@
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+type creditNoteRequest struct {
+	Reason string `json:"reason" form:"reason"`
+}
+
+type creditNoteResponse struct {
+	CreditNote *eventio.CreditNote `json:"credit_note"`
+	Statement  *eventio.Statement  `json:"statement"`
+}
+
+func StatementsHandler(store eventio.StatementReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestedOrgs := c.Request().URL.Query()["org_guid"]
+		if ok, err := authorize(c, uaa, requestedOrgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		filter := eventio.StatementFilter{
+			RangeStart: c.QueryParam("range_start"),
+			RangeStop:  c.QueryParam("range_stop"),
+			OrgGUIDs:   requestedOrgs,
+		}
+		if err := filter.Validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		statements, err := store.GetStatementsContext(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, statements)
+	}
+}
+
+func StatementHandler(store eventio.StatementReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		statementNumber, err := strconv.ParseInt(c.Param("statement_number"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "statement_number must be an integer")
+		}
+		format, err := negotiateStatementFormat(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		statement, err := store.GetStatementContext(c.Request().Context(), statementNumber)
+		if err != nil {
+			return err
+		}
+		// always authorize, even when the statement does not exist, so that
+		// statement numbers can't be enumerated without credentials
+		orgs := []string{}
+		if statement != nil {
+			orgs = statement.OrgGUIDs
+		}
+		if ok, err := authorizeAnyOrg(c, uaa, orgs); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		if statement == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("statement %d not found", statementNumber))
+		}
+		return renderStatement(c, format, statement)
+	}
+}
+
+// GenerateStatementsHandler issues statements for a consolidated month. It is
+// safe to call more than once; orgs that already have a statement for the
+// month are skipped.
+func GenerateStatementsHandler(generator eventio.StatementGenerator, consolidated eventio.ConsolidatedBillableEventReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := authorize(c, uaa, []string{}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		month := c.QueryParam("month")
+		filter, err := eventio.ParseStatementMonth(month)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		isConsolidated, err := consolidated.IsRangeConsolidatedContext(c.Request().Context(), filter)
+		if err != nil {
+			return err
+		}
+		if !isConsolidated {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s has not been consolidated yet", month))
+		}
+		statements, err := generator.GenerateStatements(month)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, statements)
+	}
+}
+
+// CreditNoteHandler cancels a statement with a credit note and issues a
+// replacement. This is the only way to regenerate a statement.
+func CreditNoteHandler(store eventio.StatementReader, generator eventio.StatementGenerator, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := authorize(c, uaa, []string{}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		statementNumber, err := strconv.ParseInt(c.Param("statement_number"), 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "statement_number must be an integer")
+		}
+		var req creditNoteRequest
+		if err := c.Bind(&req); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+		if req.Reason == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "a reason is required to credit a statement")
+		}
+		statement, err := store.GetStatementContext(c.Request().Context(), statementNumber)
+		if err != nil {
+			return err
+		}
+		if statement == nil {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("statement %d not found", statementNumber))
+		}
+		if statement.CreditNote != nil {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf(
+				"statement %d has already been credited by credit note %d",
+				statementNumber, statement.CreditNote.CreditNoteNumber,
+			))
+		}
+		creditNote, replacement, err := generator.CreditStatement(statementNumber, req.Reason)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusCreated, creditNoteResponse{
+			CreditNote: creditNote,
+			Statement:  replacement,
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package apiserver_test
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Statements", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+		orgGUID1          = "f5f32499-db32-4ab7-a314-20cbe3e49080"
+		statement         eventio.Statement
+	)
+
+	request := func(method string, path string, body string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "bearer "+token)
+		if body != "" {
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		}
+		if accept != "" {
+			req.Header.Set(echo.HeaderAccept, accept)
+		}
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+
+		statement = eventio.Statement{
+			StatementNumber: 7,
+			AccountID:       orgGUID1,
+			AccountName:     "org <one>",
+			OrgGUIDs:        []string{orgGUID1},
+			PeriodStart:     "2001-01-01",
+			PeriodStop:      "2001-02-01",
+			IssuedAt:        "2001-02-06T00:00:00+00:00",
+			CurrencyCode:    "GBP",
+			LineItems: []eventio.StatementLineItem{
+				{ResourceType: "app", PlanName: "app", VatCode: "Standard", VatRate: "0.2", ExVAT: "10.00"},
+				{ResourceType: "postgres", PlanName: "small (ha)", VatCode: "Standard", VatRate: "0.2", ExVAT: "5.50"},
+			},
+			VAT: []eventio.StatementVAT{
+				{VatCode: "Standard", VatRate: "0.2", ExVAT: "15.50", VAT: "3.10"},
+			},
+			Subtotal: "15.50",
+			VATTotal: "3.10",
+			Total:    "18.60",
+		}
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	Describe("GET /statements", func() {
+		It("should list the statements for the requested orgs", func() {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasBillingAccessReturns(true, nil)
+			fakeStore.GetStatementsContextReturns([]eventio.Statement{statement}, nil)
+
+			res := request(echo.GET, "/statements?range_start=2001-01-01&range_stop=2001-02-01&org_guid="+orgGUID1, "", "")
+
+			Expect(res.Code).To(Equal(200))
+			Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID1}))
+			_, filter := fakeStore.GetStatementsContextArgsForCall(0)
+			Expect(filter).To(Equal(eventio.StatementFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-02-01",
+				OrgGUIDs:   []string{orgGUID1},
+			}))
+			Expect(res.Body.String()).To(ContainSubstring(`"statement_number":7`))
+		})
+
+		It("should reject an invalid range", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+
+			res := request(echo.GET, "/statements?range_start=bad&range_stop=2001-02-01", "", "")
+
+			Expect(res.Code).To(Equal(400))
+			Expect(fakeStore.GetStatementsContextCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("GET /statements/:statement_number", func() {
+		BeforeEach(func() {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasBillingAccessReturns(true, nil)
+			fakeStore.GetStatementContextReturns(&statement, nil)
+		})
+
+		It("should authorize against the orgs on the statement", func() {
+			fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+			res := request(echo.GET, "/statements/7", "", "")
+
+			Expect(res.Code).To(Equal(401))
+			_, statementNumber := fakeStore.GetStatementContextArgsForCall(0)
+			Expect(statementNumber).To(Equal(int64(7)))
+			Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID1}))
+		})
+
+		It("should let billing managers of any one org on a billing account's statement see it", func() {
+			orgGUID2 := "4a7b0f3d-7f5c-4a3e-9b64-3d1c2b8e5f10"
+			statement.OrgGUIDs = []string{orgGUID1, orgGUID2}
+			fakeAuthorizer.HasBillingAccessStub = func(orgs []string) (bool, error) {
+				return len(orgs) == 1 && orgs[0] == orgGUID2, nil
+			}
+
+			res := request(echo.GET, "/statements/7", "", "")
+
+			Expect(res.Code).To(Equal(200))
+			Expect(fakeAuthorizer.HasBillingAccessCallCount()).To(Equal(2))
+			Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(Equal([]string{orgGUID1}))
+			Expect(fakeAuthorizer.HasBillingAccessArgsForCall(1)).To(Equal([]string{orgGUID2}))
+		})
+
+		It("should return 404 for an unknown statement", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+			fakeStore.GetStatementContextReturns(nil, nil)
+
+			res := request(echo.GET, "/statements/8", "", "")
+
+			Expect(res.Code).To(Equal(404))
+		})
+
+		It("should default to JSON", func() {
+			res := request(echo.GET, "/statements/7", "", "")
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
+			Expect(res.Body.String()).To(ContainSubstring(`"total":"18.60"`))
+		})
+
+		It("should render HTML", func() {
+			res := request(echo.GET, "/statements/7", "", "text/html")
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Header().Get("Content-Type")).To(Equal("text/html; charset=UTF-8"))
+			body := res.Body.String()
+			Expect(body).To(ContainSubstring(`<h1>Statement 7</h1>`))
+			Expect(body).To(ContainSubstring(`org &lt;one&gt;`))
+			Expect(body).To(ContainSubstring(`<dd>January 2001</dd>`))
+			Expect(body).To(ContainSubstring(`<tr><td>postgres</td><td>small (ha)</td><td>Standard</td><td class="amount">5.50</td></tr>`))
+			Expect(body).To(ContainSubstring(`VAT Standard at 20% on 15.50</td><td class="amount">3.10</td>`))
+			Expect(body).ToNot(ContainSubstring(`Cancelled`))
+		})
+
+		It("should show when a statement has been credited", func() {
+			replaces := int64(3)
+			statement.Replaces = &replaces
+			statement.CreditNote = &eventio.CreditNote{CreditNoteNumber: 2, Reason: "wrong plan"}
+
+			res := request(echo.GET, "/statements/7?format=html", "", "")
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Body.String()).To(ContainSubstring(`<dd>Statement 3</dd>`))
+			Expect(res.Body.String()).To(ContainSubstring(`Cancelled by credit note 2`))
+		})
+
+		It("should render a PDF", func() {
+			res := request(echo.GET, "/statements/7?format=pdf", "", "")
+
+			Expect(res.Code).To(Equal(200))
+			Expect(res.Header().Get("Content-Type")).To(Equal("application/pdf"))
+			Expect(res.Header().Get("Content-Disposition")).To(Equal(`attachment; filename="statement-7.pdf"`))
+			body := res.Body.String()
+			Expect(body).To(HavePrefix("%PDF-1.4\n"))
+			Expect(body).To(HaveSuffix("%%EOF\n"))
+			Expect(body).To(ContainSubstring(`(STATEMENT 7) '`))
+			Expect(body).To(ContainSubstring(`small \(ha\)`))
+			Expect(body).To(ContainSubstring("/Count 1"))
+
+			xref := strings.Index(body, "xref\n")
+			Expect(body).To(ContainSubstring(fmt.Sprintf("startxref\n%d\n", xref)))
+			Expect(body[strings.Index(body, "1 0 obj"):]).To(HavePrefix("1 0 obj\n<< /Type /Catalog"))
+		})
+
+		It("should reject an unknown format", func() {
+			res := request(echo.GET, "/statements/7?format=xlsx", "", "")
+
+			Expect(res.Code).To(Equal(400))
+			Expect(res.Body).To(MatchJSON(`{"error": "format must be one of json, html or pdf - got xlsx"}`))
+		})
+	})
+
+	Describe("POST /statements", func() {
+		It("should only allow admins to generate statements", func() {
+			fakeAuthorizer.AdminReturns(false, nil)
+			fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+			res := request(echo.POST, "/statements?month=2001-01", "", "")
+
+			Expect(res.Code).To(Equal(401))
+			Expect(fakeStore.GenerateStatementsCallCount()).To(Equal(0))
+		})
+
+		It("should refuse to generate statements for a month that is not consolidated", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+			fakeStore.IsRangeConsolidatedContextReturns(false, nil)
+
+			res := request(echo.POST, "/statements?month=2001-01", "", "")
+
+			Expect(res.Code).To(Equal(400))
+			_, filter := fakeStore.IsRangeConsolidatedContextArgsForCall(0)
+			Expect(filter).To(Equal(eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-02-01",
+			}))
+			Expect(fakeStore.GenerateStatementsCallCount()).To(Equal(0))
+		})
+
+		It("should generate statements for a consolidated month", func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+			fakeStore.IsRangeConsolidatedContextReturns(true, nil)
+			fakeStore.GenerateStatementsReturns([]eventio.Statement{statement}, nil)
+
+			res := request(echo.POST, "/statements?month=2001-01", "", "")
+
+			Expect(res.Code).To(Equal(201))
+			Expect(fakeStore.GenerateStatementsArgsForCall(0)).To(Equal("2001-01"))
+			Expect(res.Body.String()).To(ContainSubstring(`"statement_number":7`))
+		})
+	})
+
+	Describe("POST /statements/:statement_number/credit_notes", func() {
+		BeforeEach(func() {
+			fakeAuthorizer.AdminReturns(true, nil)
+			fakeStore.GetStatementContextReturns(&statement, nil)
+		})
+
+		It("should require a reason", func() {
+			res := request(echo.POST, "/statements/7/credit_notes", `{}`, "")
+
+			Expect(res.Code).To(Equal(400))
+			Expect(fakeStore.CreditStatementCallCount()).To(Equal(0))
+		})
+
+		It("should not credit a statement twice", func() {
+			statement.CreditNote = &eventio.CreditNote{CreditNoteNumber: 2}
+
+			res := request(echo.POST, "/statements/7/credit_notes", `{"reason": "wrong plan"}`, "")
+
+			Expect(res.Code).To(Equal(409))
+			Expect(fakeStore.CreditStatementCallCount()).To(Equal(0))
+		})
+
+		It("should issue a credit note and return the replacement statement", func() {
+			replaces := int64(7)
+			replacement := statement
+			replacement.StatementNumber = 8
+			replacement.Replaces = &replaces
+			fakeStore.CreditStatementReturns(&eventio.CreditNote{
+				CreditNoteNumber: 1,
+				StatementNumber:  7,
+				Reason:           "wrong plan",
+			}, &replacement, nil)
+
+			res := request(echo.POST, "/statements/7/credit_notes", `{"reason": "wrong plan"}`, "")
+
+			Expect(res.Code).To(Equal(201))
+			number, reason := fakeStore.CreditStatementArgsForCall(0)
+			Expect(number).To(Equal(int64(7)))
+			Expect(reason).To(Equal("wrong plan"))
+			Expect(res.Body.String()).To(ContainSubstring(`"credit_note_number":1`))
+			Expect(res.Body.String()).To(ContainSubstring(`"replaces":7`))
+		})
+	})
+})
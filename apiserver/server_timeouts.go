@@ -0,0 +1,73 @@
+package apiserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultQueryTimeout bounds the store queries made by any route that is
+// not listed in DefaultQueryTimeouts
+const DefaultQueryTimeout = 30 * time.Second
+
+// DefaultQueryTimeouts bounds the store queries made for each route. The
+// event endpoints stream large results so get longer.
+var DefaultQueryTimeouts = map[string]time.Duration{
+	"/usage_events":      5 * time.Minute,
+	"/billable_events":   5 * time.Minute,
+	"/forecast_events":   time.Minute,
+	"/cost_timeseries":   2 * time.Minute,
+	"/totals":            time.Minute,
+	"/accounting_export": 2 * time.Minute,
+	"/grafana/query":     2 * time.Minute,
+}
+
+// QueryTimeouts bounds the request context by the timeout for the matched
+// route, falling back to DefaultQueryTimeouts and then DefaultQueryTimeout.
+// Handlers pass the request context to the store, so queries stop when the
+// timeout expires or the client goes away.
+func QueryTimeouts(timeouts map[string]time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			timeout, ok := timeouts[c.Path()]
+			if !ok {
+				timeout, ok = DefaultQueryTimeouts[c.Path()]
+			}
+			if !ok {
+				timeout = DefaultQueryTimeout
+			}
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// ParseQueryTimeouts parses route timeouts in the form
+// "/billable_events=10m,/usage_events=90s"
+func ParseQueryTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "/") {
+			return nil, fmt.Errorf("query timeout must be in the form /path=duration - got %s", entry)
+		}
+		timeout, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("query timeout for %s: %s", parts[0], err)
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("query timeout for %s must be positive - got %s", parts[0], parts[1])
+		}
+		timeouts[parts[0]] = timeout
+	}
+	return timeouts, nil
+}
@@ -0,0 +1,143 @@
+package apiserver_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QueryTimeouts", func() {
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		fakeRows          *eventiofakes.FakeUsageEventRows
+		token             = "ACCESS_GRANTED_TOKEN"
+		usageEventsURL    = "/usage_events?org_guid=f5f32499-db32-4ab7-a314-20cbe3e49080&range_start=2001-01-01&range_stop=2001-01-02"
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeRows = &eventiofakes.FakeUsageEventRows{}
+		fakeStore.GetUsageEventRowsContextReturns(fakeRows, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	serve := func(res *httptest.ResponseRecorder, path string, reqCtx context.Context) {
+		r := httptest.NewRequest(echo.GET, path, nil).WithContext(reqCtx)
+		r.Header.Set("Authorization", "bearer "+token)
+		e := New(cfg)
+		e.ServeHTTP(res, r)
+		defer e.Shutdown(ctx)
+	}
+
+	It("should bound store queries by the default timeout for the route", func() {
+		res := httptest.NewRecorder()
+		serve(res, usageEventsURL, ctx)
+		Expect(res.Code).To(Equal(200))
+
+		Expect(fakeStore.GetUsageEventRowsContextCallCount()).To(Equal(1))
+		storeCtx, _ := fakeStore.GetUsageEventRowsContextArgsForCall(0)
+		deadline, ok := storeCtx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(time.Until(deadline)).To(BeNumerically("~", DefaultQueryTimeouts["/usage_events"], time.Minute))
+	})
+
+	It("should fall back to DefaultQueryTimeout for routes without their own default", func() {
+		res := httptest.NewRecorder()
+		serve(res, "/vat_rates", ctx)
+		Expect(res.Code).To(Equal(200))
+
+		Expect(fakeStore.GetVATRatesContextCallCount()).To(Equal(1))
+		storeCtx, _ := fakeStore.GetVATRatesContextArgsForCall(0)
+		deadline, ok := storeCtx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(time.Until(deadline)).To(BeNumerically("~", DefaultQueryTimeout, 5*time.Second))
+	})
+
+	It("should prefer timeouts from the config", func() {
+		cfg.QueryTimeouts = map[string]time.Duration{
+			"/usage_events": 90 * time.Second,
+		}
+		res := httptest.NewRecorder()
+		serve(res, usageEventsURL, ctx)
+		Expect(res.Code).To(Equal(200))
+
+		storeCtx, _ := fakeStore.GetUsageEventRowsContextArgsForCall(0)
+		deadline, ok := storeCtx.Deadline()
+		Expect(ok).To(BeTrue())
+		Expect(time.Until(deadline)).To(BeNumerically("~", 90*time.Second, 5*time.Second))
+	})
+
+	It("should stop store queries when the client goes away", func() {
+		fakeStore.GetUsageEventRowsContextStub = func(storeCtx context.Context, filter eventio.EventFilter) (eventio.UsageEventRows, error) {
+			cancel()
+			<-storeCtx.Done()
+			return nil, storeCtx.Err()
+		}
+		res := httptest.NewRecorder()
+		serve(res, usageEventsURL, ctx)
+
+		Expect(fakeStore.GetUsageEventRowsContextCallCount()).To(Equal(1))
+		storeCtx, _ := fakeStore.GetUsageEventRowsContextArgsForCall(0)
+		Expect(errors.Is(storeCtx.Err(), context.Canceled)).To(BeTrue())
+		Expect(res.Code).To(Equal(500))
+	})
+})
+
+var _ = Describe("ParseQueryTimeouts", func() {
+	It("should parse route timeouts", func() {
+		timeouts, err := ParseQueryTimeouts(" /billable_events=10m, /usage_events=90s ,")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timeouts).To(Equal(map[string]time.Duration{
+			"/billable_events": 10 * time.Minute,
+			"/usage_events":    90 * time.Second,
+		}))
+	})
+
+	It("should return no timeouts for an empty string", func() {
+		timeouts, err := ParseQueryTimeouts("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(timeouts).To(BeEmpty())
+	})
+
+	DescribeTable("should reject invalid timeouts",
+		func(s string, expectedErr string) {
+			_, err := ParseQueryTimeouts(s)
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("missing duration", "/usage_events", "must be in the form /path=duration"),
+		Entry("missing leading slash", "usage_events=1m", "must be in the form /path=duration"),
+		Entry("bad duration", "/usage_events=soon", "query timeout for /usage_events"),
+		Entry("zero duration", "/usage_events=0s", "must be positive"),
+	)
+})
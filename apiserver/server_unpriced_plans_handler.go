@@ -0,0 +1,27 @@
+package apiserver
+
+import (
+	"net/http"
+
+	"github.com/alphagov/paas-billing/apiserver/auth"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/labstack/echo/v4"
+)
+
+// UnpricedPlansHandler lists the plans seen in usage events with usage that
+// no configured pricing plan covers. Only admins can see it, as it covers
+// every org.
+func UnpricedPlansHandler(store eventio.UnpricedPlanReader, uaa auth.Authenticator) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if ok, err := authorize(c, uaa, []string{}); err != nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		} else if !ok {
+			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
+		}
+		plans, err := store.GetUnpricedPlansContext(c.Request().Context())
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, plans)
+	}
+}
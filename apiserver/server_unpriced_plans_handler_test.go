@@ -0,0 +1,100 @@
+package apiserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+
+	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/labstack/echo/v4"
+
+	. "github.com/alphagov/paas-billing/apiserver"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UnpricedPlansHandler", func() {
+
+	var (
+		ctx               context.Context
+		cancel            context.CancelFunc
+		cfg               Config
+		fakeAuthenticator *authfakes.FakeAuthenticator
+		fakeAuthorizer    *authfakes.FakeAuthorizer
+		fakeStore         *eventiofakes.FakeEventStore
+		token             = "ACCESS_GRANTED_TOKEN"
+	)
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(echo.GET, path, nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+		return res
+	}
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeAuthenticator = &authfakes.FakeAuthenticator{}
+		fakeAuthorizer = &authfakes.FakeAuthorizer{}
+		cfg = Config{
+			Authenticator: fakeAuthenticator,
+			Logger:        lager.NewLogger("test"),
+			Store:         fakeStore,
+			EnablePanic:   true,
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+	})
+
+	AfterEach(func() {
+		defer cancel()
+	})
+
+	It("should list the unpriced plans to admins", func() {
+		fakeAuthorizer.AdminReturns(true, nil)
+		unbilledFrom := "2001-01-01T00:00:00+00:00"
+		plans := []eventio.UnpricedPlan{{
+			PlanGUID:          "c6221308-b7bb-46d2-9d79-a357f5a3837b",
+			PlanName:          "new-plan",
+			ResourceType:      "service",
+			ServicePlanGUID:   "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5",
+			ServicePlanName:   "new-plan",
+			ServiceLabel:      "postgres",
+			FirstSeenAt:       "2001-01-02T00:00:00+00:00",
+			LastSeenAt:        "2001-01-03T00:00:00+00:00",
+			OrgGUIDs:          []string{"51ba75ef-edc0-47ad-a633-a8f6e8770944"},
+			UnbilledEvents:    1,
+			UnbilledFrom:      &unbilledFrom,
+			UnbilledHours:     "1",
+			UnbilledNodeHours: "1",
+		}}
+		fakeStore.GetUnpricedPlansContextReturns(plans, nil)
+
+		res := request("/unpriced_plans")
+
+		Expect(res.Code).To(Equal(200))
+		var body []eventio.UnpricedPlan
+		Expect(json.Unmarshal(res.Body.Bytes(), &body)).To(Succeed())
+		Expect(body).To(Equal(plans))
+		Expect(res.Body.String()).To(ContainSubstring(`"priced_at":null`))
+	})
+
+	It("should not list the unpriced plans to billing managers", func() {
+		fakeAuthorizer.AdminReturns(false, nil)
+		fakeAuthorizer.HasBillingAccessReturns(false, nil)
+
+		res := request("/unpriced_plans")
+
+		Expect(res.Code).To(Equal(401))
+		Expect(fakeAuthorizer.HasBillingAccessArgsForCall(0)).To(BeEmpty())
+		Expect(fakeStore.GetUnpricedPlansContextCallCount()).To(Equal(0))
+	})
+})
@@ -17,52 +17,43 @@ func UsageEventsHandler(store eventio.UsageEventReader, uaa auth.Authenticator)
 			return echo.NewHTTPError(http.StatusUnauthorized, "unauthorized")
 		}
 		// parse params
+		format, err := negotiateFormat(c)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
 		filter := eventio.EventFilter{
-			RangeStart: c.QueryParam("range_start"),
-			RangeStop:  c.QueryParam("range_stop"),
-			OrgGUIDs:   requestedOrgs,
+			RangeStart:    c.QueryParam("range_start"),
+			RangeStop:     c.QueryParam("range_stop"),
+			OrgGUIDs:      requestedOrgs,
+			Aggregate:     c.QueryParam("aggregate"),
+			LabelSelector: c.QueryParam("label_selector"),
 		}
 		if err := filter.Validate(); err != nil {
 			return echo.NewHTTPError(http.StatusBadRequest, err)
 		}
+		if err := authorizeAggregate(c, uaa, filter); err != nil {
+			return err
+		}
 		// query the store
-		rows, err := store.GetUsageEventRows(filter)
+		rows, err := store.GetUsageEventRowsContext(c.Request().Context(), filter)
 		if err != nil {
 			return err
 		}
 		defer rows.Close()
 		// stream response to client
-		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEApplicationJSONCharsetUTF8)
-		c.Response().WriteHeader(http.StatusOK)
-		if _, err := c.Response().Write([]byte("[\n")); err != nil {
-			return err
-		}
-		c.Response().Flush()
-		next := rows.Next()
-		delim := "\n"
-		for next {
+		enc := newEventEncoder(c, format, usageEventTable)
+		for rows.Next() {
 			b, err := rows.EventJSON()
 			if err != nil {
 				return err
 			}
-			if _, err := c.Response().Write(b); err != nil {
-				return err
-			}
-			next = rows.Next()
-			if next {
-				delim = ",\n"
-			} else {
-				delim = "\n"
-			}
-			if _, err := c.Response().Write([]byte(delim)); err != nil {
+			if err := enc.Encode(b); err != nil {
 				return err
 			}
-			c.Response().Flush()
 		}
-		if _, err := c.Response().Write([]byte("]\n")); err != nil {
+		if err := rows.Err(); err != nil {
 			return err
 		}
-		c.Response().Flush()
-		return rows.Err()
+		return enc.End()
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"net/url"
 
 	"github.com/alphagov/paas-billing/apiserver/auth/authfakes"
+	"github.com/alphagov/paas-billing/eventio"
 	"github.com/alphagov/paas-billing/eventio/eventiofakes"
 
 	"code.cloudfoundry.org/lager"
@@ -140,7 +141,7 @@ var _ = Describe("UsageEventsHandler", func() {
 		}`
 		fakeRows.EventJSONReturnsOnCall(0, []byte(event1JSON), nil)
 		fakeRows.EventJSONReturnsOnCall(1, []byte(event2JSON), nil)
-		fakeStore.GetUsageEventRowsReturns(fakeRows, nil)
+		fakeStore.GetUsageEventRowsContextReturns(fakeRows, nil)
 
 		u := url.URL{}
 		u.Path = "/usage_events"
@@ -157,8 +158,8 @@ var _ = Describe("UsageEventsHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetUsageEventRowsCallCount()).To(Equal(1))
-		filter := fakeStore.GetUsageEventRowsArgsForCall(0)
+		Expect(fakeStore.GetUsageEventRowsContextCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetUsageEventRowsContextArgsForCall(0)
 		Expect(filter.RangeStart).To(Equal("2001-01-01"))
 		Expect(filter.RangeStop).To(Equal("2001-01-02"))
 		Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1}))
@@ -193,7 +194,7 @@ var _ = Describe("UsageEventsHandler", func() {
 		}`
 		fakeRows.EventJSONReturnsOnCall(0, []byte(event1JSON), nil)
 		fakeRows.EventJSONReturnsOnCall(1, []byte(event2JSON), nil)
-		fakeStore.GetUsageEventRowsReturns(fakeRows, nil)
+		fakeStore.GetUsageEventRowsContextReturns(fakeRows, nil)
 
 		u := url.URL{}
 		u.Path = "/usage_events"
@@ -210,8 +211,8 @@ var _ = Describe("UsageEventsHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetUsageEventRowsCallCount()).To(Equal(1))
-		filter := fakeStore.GetUsageEventRowsArgsForCall(0)
+		Expect(fakeStore.GetUsageEventRowsContextCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetUsageEventRowsContextArgsForCall(0)
 		Expect(filter.RangeStart).To(Equal("2001-01-01"))
 		Expect(filter.RangeStop).To(Equal("2001-01-02"))
 		Expect(filter.OrgGUIDs).To(Equal([]string{orgGUID1}))
@@ -233,7 +234,7 @@ var _ = Describe("UsageEventsHandler", func() {
 		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
 		fakeAuthorizer.AdminReturns(true, nil)
 		queryErr := errors.New("query-error")
-		fakeStore.GetUsageEventRowsReturns(nil, queryErr)
+		fakeStore.GetUsageEventRowsContextReturns(nil, queryErr)
 
 		u := url.URL{}
 		u.Path = "/usage_events"
@@ -250,7 +251,7 @@ var _ = Describe("UsageEventsHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetUsageEventRowsCallCount()).To(Equal(1))
+		Expect(fakeStore.GetUsageEventRowsContextCallCount()).To(Equal(1))
 
 		Expect(res.Body).To(MatchJSON(`{
 			"error": "internal server error"
@@ -259,4 +260,41 @@ var _ = Describe("UsageEventsHandler", func() {
 		Expect(res.Header().Get("Content-Type")).To(Equal("application/json; charset=UTF-8"))
 	})
 
+	It("should pass aggregate=none to the store for admins", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeStore.GetUsageEventRowsContextReturns(&eventiofakes.FakeUsageEventRows{}, nil)
+
+		req := httptest.NewRequest(echo.GET, "/usage_events?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02&aggregate=none", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+		Expect(fakeStore.GetUsageEventRowsContextCallCount()).To(Equal(1))
+		_, filter := fakeStore.GetUsageEventRowsContextArgsForCall(0)
+		Expect(filter.Aggregate).To(Equal(eventio.AggregateNone))
+	})
+
+	It("should pass the label selector to the store", func() {
+		fakeAuthenticator.NewAuthorizerReturns(fakeAuthorizer, nil)
+		fakeAuthorizer.AdminReturns(true, nil)
+		fakeStore.GetUsageEventRowsContextReturns(&eventiofakes.FakeUsageEventRows{}, nil)
+
+		req := httptest.NewRequest(echo.GET, "/usage_events?org_guid="+orgGUID1+"&range_start=2001-01-01&range_stop=2001-01-02&label_selector=team%3Dbilling,!temporary", nil)
+		req.Header.Set("Authorization", "bearer "+token)
+		res := httptest.NewRecorder()
+
+		e := New(cfg)
+		e.ServeHTTP(res, req)
+		defer e.Shutdown(ctx)
+
+		Expect(res.Code).To(Equal(200))
+		_, filter := fakeStore.GetUsageEventRowsContextArgsForCall(0)
+		Expect(filter.LabelSelector).To(Equal("team=billing,!temporary"))
+	})
+
 })
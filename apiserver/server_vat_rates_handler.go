@@ -13,7 +13,7 @@ func VATRatesHandler(store eventio.VATRateReader) echo.HandlerFunc {
 			RangeStart: c.QueryParam("range_start"),
 			RangeStop:  c.QueryParam("range_stop"),
 		}
-		vatRates, err := store.GetVATRates(filter)
+		vatRates, err := store.GetVATRatesContext(c.Request().Context(), filter)
 		if err != nil {
 			return err
 		}
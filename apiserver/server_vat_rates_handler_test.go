@@ -48,7 +48,7 @@ var _ = Describe("VATRatesHandler", func() {
 	})
 
 	It("should request the rates from the store and return json", func() {
-		fakeStore.GetVATRatesReturns([]eventio.VATRate{
+		fakeStore.GetVATRatesContextReturns([]eventio.VATRate{
 			{
 				Code:      "Standard",
 				ValidFrom: "2001-01-01",
@@ -82,9 +82,9 @@ var _ = Describe("VATRatesHandler", func() {
 		e.ServeHTTP(res, req)
 		defer e.Shutdown(ctx)
 
-		Expect(fakeStore.GetVATRatesCallCount()).To(Equal(1))
+		Expect(fakeStore.GetVATRatesContextCallCount()).To(Equal(1))
 
-		filter := fakeStore.GetVATRatesArgsForCall(0)
+		_, filter := fakeStore.GetVATRatesContextArgsForCall(0)
 		Expect(filter.RangeStart).To(Equal(rangeStart))
 		Expect(filter.RangeStop).To(Equal(rangeStop))
 
@@ -5,11 +5,13 @@ package cfstore
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"code.cloudfoundry.org/lager"
 	cfclient "github.com/cloudfoundry-community/go-cfclient"
+	"github.com/lib/pq"
 )
 
 const (
@@ -60,6 +62,22 @@ func (s *Store) Init() error {
 		s.logger.Error("collectSpaces-failed", err)
 		return err
 	}
+	if err := s.collectProcesses(tx); err != nil {
+		s.logger.Error("collectProcesses-failed", err)
+		return err
+	}
+	if err := s.collectTasks(tx); err != nil {
+		s.logger.Error("collectTasks-failed", err)
+		return err
+	}
+	if err := s.collectLabels(tx); err != nil {
+		s.logger.Error("collectLabels-failed", err)
+		return err
+	}
+	if err := s.collectServiceInstanceShares(tx); err != nil {
+		s.logger.Error("collectServiceInstanceShares-failed", err)
+		return err
+	}
 	s.logger.Info("initialized")
 	return tx.Commit()
 }
@@ -229,6 +247,7 @@ func (s *Store) collectOrgs(tx *sql.Tx) error {
 	if err != nil {
 		return err
 	}
+	orgGUIDs := []string{}
 	for _, org := range orgs {
 		validFrom := org.UpdatedAt
 		var recordCount int
@@ -269,8 +288,24 @@ func (s *Store) collectOrgs(tx *sql.Tx) error {
 		if err != nil {
 			return err
 		}
+		orgGUIDs = append(orgGUIDs, org.Guid)
 	}
-	return nil
+	// Cloud Foundry does not list deleted orgs, so record when an org we
+	// know about is first missing
+	_, err = tx.Exec(`
+		insert into org_deletions (
+			guid, deleted_at
+		) (
+			select distinct
+				guid, now()
+			from
+				orgs
+			where
+				guid::text <> all($1::text[])
+		) on conflict (guid) do nothing`,
+		pq.Array(orgGUIDs),
+	)
+	return err
 }
 
 func (s *Store) CollectSpaces() error {
@@ -312,21 +347,335 @@ func (s *Store) collectSpaces(tx *sql.Tx) error {
 				valid_from,
 				name,
 				created_at,
-				updated_at
+				updated_at,
+				isolation_segment_guid
 			) values (
 				$1,
 				$2,
 				$3,
 				$4,
-				$5
+				$5,
+				nullif($6, '')::uuid
 			) on conflict (guid, valid_from) do nothing`,
 			space.Guid,
 			validFrom,
 			space.Name,
 			space.CreatedAt,
 			space.UpdatedAt,
+			space.IsolationSegmentGuid,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) CollectProcesses() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultInitTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.collectProcesses(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// collectProcesses records the disk quota and log rate limit of the
+// processes of every app, with a row each time a process is updated
+func (s *Store) collectProcesses(tx *sql.Tx) error {
+	processes, err := s.client.ListV3Processes()
+	if err != nil {
+		return err
+	}
+	for _, process := range processes {
+		validFrom := process.UpdatedAt
+		var recordCount int
+		err := tx.QueryRow(
+			`select count(*) from app_processes where guid = $1`,
+			process.Guid,
+		).Scan(&recordCount)
+		if err != nil {
+			return err
+		}
+		if recordCount == 0 {
+			validFrom = process.CreatedAt
+		}
+
+		_, err = tx.Exec(`
+			insert into app_processes (
+				guid,
+				valid_from,
+				app_guid,
+				process_type,
+				disk_in_mb,
+				log_rate_limit,
+				created_at,
+				updated_at
+			) values (
+				$1,
+				$2,
+				$3,
+				$4,
+				$5,
+				$6,
+				$7,
+				$8
+			) on conflict (guid, valid_from) do nothing`,
+			process.Guid,
+			validFrom,
+			process.Relationships.App.Data.Guid,
+			process.Type,
+			process.DiskInMB,
+			process.LogRateLimitInBytesPerSecond,
+			process.CreatedAt,
+			process.UpdatedAt,
 		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) CollectTasks() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultInitTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.collectTasks(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
+// collectTasks records the disk quota and log rate limit of the tasks
+// created since the last task recorded. Tasks cannot be changed, so each is
+// only requested once.
+func (s *Store) collectTasks(tx *sql.Tx) error {
+	var lastCreatedAt *time.Time
+	if err := tx.QueryRow(`select max(created_at) from app_tasks`).Scan(&lastCreatedAt); err != nil {
+		return err
+	}
+	createdAfter := ""
+	if lastCreatedAt != nil {
+		createdAfter = lastCreatedAt.UTC().Format(time.RFC3339)
+	}
+	tasks, err := s.client.ListV3TasksCreatedAfter(createdAfter)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		_, err = tx.Exec(`
+			insert into app_tasks (
+				guid,
+				app_guid,
+				disk_in_mb,
+				log_rate_limit,
+				created_at
+			) values (
+				$1,
+				$2,
+				$3,
+				$4,
+				$5
+			) on conflict (guid) do nothing`,
+			task.Guid,
+			task.Relationships.App.Data.Guid,
+			task.DiskInMB,
+			task.LogRateLimitInBytesPerSecond,
+			task.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelledResources are the v3 api resources whose labels are collected,
+// keyed by the resource_type stored with them
+var labelledResources = []struct {
+	resourceType string
+	path         string
+}{
+	{"org", "/v3/organizations"},
+	{"space", "/v3/spaces"},
+	{"app", "/v3/apps"},
+	{"service_instance", "/v3/service_instances"},
+}
+
+func (s *Store) CollectLabels() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultInitTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.collectLabels(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// collectLabels records the metadata labels of orgs, spaces, apps and
+// service instances whenever they differ from the last labels recorded
+func (s *Store) collectLabels(tx *sql.Tx) error {
+	for _, r := range labelledResources {
+		resources, err := s.client.ListV3Resources(r.path)
+		if err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			validFrom := resource.UpdatedAt
+			var recordCount int
+			err := tx.QueryRow(
+				`select count(*) from resource_labels where guid = $1`,
+				resource.Guid,
+			).Scan(&recordCount)
+			if err != nil {
+				return err
+			}
+			if recordCount == 0 {
+				validFrom = resource.CreatedAt
+			}
+			labels := resource.Metadata.Labels
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labelsJSON, err := json.Marshal(labels)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(`
+				insert into resource_labels (
+					guid,
+					valid_from,
+					resource_type,
+					labels
+				) (
+					select
+						$1::uuid,
+						$2::timestamptz,
+						$3::text,
+						$4::jsonb
+					where
+						$4::jsonb <> coalesce((
+							select labels
+							from resource_labels
+							where guid = $1::uuid
+							order by valid_from desc
+							limit 1
+						), '{}'::jsonb)
+				) on conflict (guid, valid_from) do nothing`,
+				resource.Guid,
+				validFrom,
+				r.resourceType,
+				string(labelsJSON),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Store) CollectServiceInstanceShares() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultInitTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.collectServiceInstanceShares(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// collectServiceInstanceShares records the spaces that service instances
+// with a cost sharing rule are shared into, and their bindings, whenever
+// they differ from the last recorded. Other service instances are skipped
+// to avoid requesting every service instance on each collection.
+func (s *Store) collectServiceInstanceShares(tx *sql.Tx) error {
+	rows, err := tx.Query(`select distinct service_instance_guid from cost_sharing_rules`)
+	if err != nil {
+		return err
+	}
+	guids := []string{}
+	for rows.Next() {
+		var guid string
+		if err := rows.Scan(&guid); err != nil {
+			rows.Close()
+			return err
+		}
+		guids = append(guids, guid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, guid := range guids {
+		shares, err := s.client.GetServiceInstanceShares(guid)
+		if err != nil {
+			return err
+		}
+		if shares == nil {
+			s.logger.Info("service-instance-not-found", lager.Data{"service_instance_guid": guid})
+			continue
+		}
+		sharedSpacesJSON, err := json.Marshal(shares.SharedSpaces)
+		if err != nil {
+			return err
+		}
+		bindingsJSON, err := json.Marshal(shares.Bindings)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			insert into service_instance_shares (
+				service_instance_guid,
+				valid_from,
+				shared_spaces,
+				bindings
+			) (
+				select
+					$1::uuid,
+					now(),
+					$2::jsonb,
+					$3::jsonb
+				where
+					not exists (
+						select 1
+						from (
+							select shared_spaces, bindings
+							from service_instance_shares
+							where service_instance_guid = $1::uuid
+							order by valid_from desc
+							limit 1
+						) latest
+						where
+							latest.shared_spaces = $2::jsonb
+							and latest.bindings = $3::jsonb
+					)
+			)`,
+			guid,
+			string(sharedSpacesJSON),
+			string(bindingsJSON),
+		)
 		if err != nil {
 			return err
 		}
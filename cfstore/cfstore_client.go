@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/url"
+	"sort"
+	"strings"
 
 	"github.com/cloudfoundry-community/go-cfclient"
 )
@@ -15,6 +17,10 @@ type CFDataClient interface {
 	ListServices() ([]cfclient.Service, error)
 	ListOrgs() ([]V3Org, error)
 	ListSpaces() ([]cfclient.Space, error)
+	ListV3Resources(path string) ([]V3Resource, error)
+	GetServiceInstanceShares(serviceInstanceGUID string) (*ServiceInstanceShares, error)
+	ListV3Processes() ([]V3Process, error)
+	ListV3TasksCreatedAfter(createdAt string) ([]V3Task, error)
 }
 
 var _ CFDataClient = &Client{}
@@ -27,6 +33,18 @@ type V3OrgMetadata struct {
 	Annotations V3OrgMetadataAnnotations `json:"annotations"`
 }
 
+type V3Metadata struct {
+	Labels map[string]string `json:"labels"`
+}
+
+// V3Resource has the fields common to every resource in the v3 api
+type V3Resource struct {
+	Guid      string     `json:"guid"`
+	CreatedAt string     `json:"created_at"`
+	UpdatedAt string     `json:"updated_at"`
+	Metadata  V3Metadata `json:"metadata"`
+}
+
 type V3OrgRelationshipsQuotaData struct {
 	Guid string `json:"guid"`
 }
@@ -70,6 +88,105 @@ type V3OrgsResponse struct {
 	Resources  []V3Org    `json:"resources"`
 }
 
+type V3ResourcesResponse struct {
+	Pagination Pagination   `json:"pagination"`
+	Resources  []V3Resource `json:"resources"`
+}
+
+// SharedSpace is a space that a service instance is shared into
+type SharedSpace struct {
+	SpaceGUID string `json:"space_guid"`
+	SpaceName string `json:"space_name"`
+	OrgGUID   string `json:"org_guid"`
+	OrgName   string `json:"org_name"`
+}
+
+// ServiceInstanceShares are the spaces a service instance is shared into,
+// ordered by guid, and the number of apps bound to it in each space,
+// including the space that owns it
+type ServiceInstanceShares struct {
+	SharedSpaces []SharedSpace  `json:"shared_spaces"`
+	Bindings     map[string]int `json:"bindings"`
+}
+
+type V3RelationshipData struct {
+	Guid string `json:"guid"`
+}
+
+type V3ToOneRelationship struct {
+	Data V3RelationshipData `json:"data"`
+}
+
+type V3AppRelationships struct {
+	App V3ToOneRelationship `json:"app"`
+}
+
+// V3Process is the part of a v3 process that is priced. A
+// LogRateLimitInBytesPerSecond of -1 is unlimited.
+type V3Process struct {
+	Guid                         string             `json:"guid"`
+	Type                         string             `json:"type"`
+	CreatedAt                    string             `json:"created_at"`
+	UpdatedAt                    string             `json:"updated_at"`
+	DiskInMB                     *int64             `json:"disk_in_mb"`
+	LogRateLimitInBytesPerSecond *int64             `json:"log_rate_limit_in_bytes_per_second"`
+	Relationships                V3AppRelationships `json:"relationships"`
+}
+
+type V3ProcessesResponse struct {
+	Pagination Pagination  `json:"pagination"`
+	Resources  []V3Process `json:"resources"`
+}
+
+// V3Task is the part of a v3 task that is priced. A task's limits cannot
+// change once it is created.
+type V3Task struct {
+	Guid                         string             `json:"guid"`
+	CreatedAt                    string             `json:"created_at"`
+	DiskInMB                     *int64             `json:"disk_in_mb"`
+	LogRateLimitInBytesPerSecond *int64             `json:"log_rate_limit_in_bytes_per_second"`
+	Relationships                V3AppRelationships `json:"relationships"`
+}
+
+type V3TasksResponse struct {
+	Pagination Pagination `json:"pagination"`
+	Resources  []V3Task   `json:"resources"`
+}
+
+type v3SharedSpacesResponse struct {
+	Data     []V3RelationshipData `json:"data"`
+	Included struct {
+		Spaces []struct {
+			Guid          string `json:"guid"`
+			Name          string `json:"name"`
+			Relationships struct {
+				Organization V3ToOneRelationship `json:"organization"`
+			} `json:"relationships"`
+		} `json:"spaces"`
+		Organizations []struct {
+			Guid string `json:"guid"`
+			Name string `json:"name"`
+		} `json:"organizations"`
+	} `json:"included"`
+}
+
+type v3AppBindingsResponse struct {
+	Pagination Pagination `json:"pagination"`
+	Resources  []struct {
+		Relationships struct {
+			App V3ToOneRelationship `json:"app"`
+		} `json:"relationships"`
+	} `json:"resources"`
+	Included struct {
+		Apps []struct {
+			Guid          string `json:"guid"`
+			Relationships struct {
+				Space V3ToOneRelationship `json:"space"`
+			} `json:"relationships"`
+		} `json:"apps"`
+	} `json:"included"`
+}
+
 type Client struct {
 	Client *cfclient.Client
 }
@@ -84,43 +201,177 @@ func (c *Client) ListServices() ([]cfclient.Service, error) {
 
 func (c *Client) ListOrgs() ([]V3Org, error) {
 	var orgs []V3Org
-	requestQuery := "/v3/organizations?"
+	err := c.listV3Pages("/v3/organizations", "orgs", func(body []byte) (Pagination, error) {
+		var orgsRes V3OrgsResponse
+		if err := json.Unmarshal(body, &orgsRes); err != nil {
+			return Pagination{}, err
+		}
+		orgs = append(orgs, orgsRes.Resources...)
+		return orgsRes.Pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// ListV3Resources lists every resource at a v3 api path such as /v3/apps
+func (c *Client) ListV3Resources(path string) ([]V3Resource, error) {
+	var resources []V3Resource
+	err := c.listV3Pages(path, path, func(body []byte) (Pagination, error) {
+		var res V3ResourcesResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			return Pagination{}, err
+		}
+		resources = append(resources, res.Resources...)
+		return res.Pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// listV3Pages requests every page of a v3 api list, passing the body of
+// each page to fn, which returns the pagination of the page
+func (c *Client) listV3Pages(path string, name string, fn func(body []byte) (Pagination, error)) error {
+	requestQuery := path
+	if !strings.Contains(path, "?") {
+		requestQuery += "?"
+	}
 	for {
 		req := c.Client.NewRequest("GET", requestQuery)
 		res, err := c.Client.DoRequest(req)
 		if err != nil {
-			return nil, fmt.Errorf("unable to interact with v3 api gathering orgs: %v", err)
+			return fmt.Errorf("unable to interact with v3 api gathering %s: %v", name, err)
 		}
 		defer res.Body.Close()
 
 		body, err := ioutil.ReadAll(res.Body)
 		if err != nil {
-			return nil, fmt.Errorf("unable to read out body into bytes array: %v", err)
+			return fmt.Errorf("unable to read out body into bytes array: %v", err)
 		}
 
-		var orgsRes V3OrgsResponse
-		if err = json.Unmarshal(body, &orgsRes); err != nil {
-			return nil, fmt.Errorf("unable to unmarshal json response into the struct: %v", err)
-		}
-
-		for _, org := range orgsRes.Resources {
-			orgs = append(orgs, org)
+		pagination, err := fn(body)
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal json response into the struct: %v", err)
 		}
 
-		requestUrl := orgsRes.Pagination.Next.Href
+		requestUrl := pagination.Next.Href
 		if requestUrl == "" {
 			break
 		}
 
 		urlParsed, err := url.Parse(requestUrl)
 		if err != nil {
-			return nil, fmt.Errorf("unable to parse pagination URL: %v", err)
+			return fmt.Errorf("unable to parse pagination URL: %v", err)
 		}
 
 		requestQuery = urlParsed.Path + "?" + urlParsed.RawQuery
 	}
+	return nil
+}
 
-	return orgs, nil
+// GetServiceInstanceShares returns the spaces a service instance is shared
+// into and its app bindings, or nil if the service instance does not exist
+func (c *Client) GetServiceInstanceShares(serviceInstanceGUID string) (*ServiceInstanceShares, error) {
+	req := c.Client.NewRequest("GET", "/v3/service_instances/"+url.PathEscape(serviceInstanceGUID)+"/relationships/shared_spaces?fields[space]=name,relationships.organization&fields[space.organization]=guid,name")
+	res, err := c.Client.DoRequest(req)
+	if cfclient.IsResourceNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to interact with v3 api gathering shared spaces: %v", err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read out body into bytes array: %v", err)
+	}
+	var sharedRes v3SharedSpacesResponse
+	if err := json.Unmarshal(body, &sharedRes); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal json response into the struct: %v", err)
+	}
+	orgNames := map[string]string{}
+	for _, org := range sharedRes.Included.Organizations {
+		orgNames[org.Guid] = org.Name
+	}
+	shares := &ServiceInstanceShares{
+		SharedSpaces: []SharedSpace{},
+		Bindings:     map[string]int{},
+	}
+	for _, space := range sharedRes.Included.Spaces {
+		orgGUID := space.Relationships.Organization.Data.Guid
+		shares.SharedSpaces = append(shares.SharedSpaces, SharedSpace{
+			SpaceGUID: space.Guid,
+			SpaceName: space.Name,
+			OrgGUID:   orgGUID,
+			OrgName:   orgNames[orgGUID],
+		})
+	}
+	sort.Slice(shares.SharedSpaces, func(i, j int) bool {
+		return shares.SharedSpaces[i].SpaceGUID < shares.SharedSpaces[j].SpaceGUID
+	})
+
+	path := "/v3/service_credential_bindings?type=app&include=app&service_instance_guids=" + url.QueryEscape(serviceInstanceGUID)
+	err = c.listV3Pages(path, "service credential bindings", func(body []byte) (Pagination, error) {
+		var bindingsRes v3AppBindingsResponse
+		if err := json.Unmarshal(body, &bindingsRes); err != nil {
+			return Pagination{}, err
+		}
+		appSpaces := map[string]string{}
+		for _, app := range bindingsRes.Included.Apps {
+			appSpaces[app.Guid] = app.Relationships.Space.Data.Guid
+		}
+		for _, binding := range bindingsRes.Resources {
+			if spaceGUID, ok := appSpaces[binding.Relationships.App.Data.Guid]; ok {
+				shares.Bindings[spaceGUID]++
+			}
+		}
+		return bindingsRes.Pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+func (c *Client) ListV3Processes() ([]V3Process, error) {
+	var processes []V3Process
+	err := c.listV3Pages("/v3/processes", "processes", func(body []byte) (Pagination, error) {
+		var res V3ProcessesResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			return Pagination{}, err
+		}
+		processes = append(processes, res.Resources...)
+		return res.Pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return processes, nil
+}
+
+// ListV3TasksCreatedAfter lists the tasks created after a timestamp, or
+// every task if createdAt is empty
+func (c *Client) ListV3TasksCreatedAfter(createdAt string) ([]V3Task, error) {
+	path := "/v3/tasks?order_by=created_at"
+	if createdAt != "" {
+		path += "&created_ats[gt]=" + url.QueryEscape(createdAt)
+	}
+	var tasks []V3Task
+	err := c.listV3Pages(path, "tasks", func(body []byte) (Pagination, error) {
+		var res V3TasksResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			return Pagination{}, err
+		}
+		tasks = append(tasks, res.Resources...)
+		return res.Pagination, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
 }
 
 func (c *Client) ListSpaces() ([]cfclient.Space, error) {
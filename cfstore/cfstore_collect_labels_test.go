@@ -0,0 +1,87 @@
+package cfstore_test
+
+import (
+	"github.com/alphagov/paas-billing/cfstore"
+	"github.com/alphagov/paas-billing/cfstore/cfstorefakes"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+)
+
+var _ = Describe("Labels", func() {
+
+	var (
+		tempdb     *testenv.TempDB
+		fakeClient *cfstorefakes.FakeCFDataClient
+		store      *cfstore.Store
+		appGUID    string
+	)
+
+	BeforeEach(func(ctx SpecContext) {
+		var err error
+		tempdb, err = testenv.OpenWithContext(testenv.BasicConfig, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeClient = &cfstorefakes.FakeCFDataClient{}
+		store, err = cfstore.New(cfstore.Config{
+			Client: fakeClient,
+			DB:     tempdb.Conn,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.Init()).To(Succeed())
+
+		appGUID = uuid.NewV4().String()
+	})
+
+	AfterEach(func() {
+		tempdb.Close()
+	})
+
+	collectAppLabels := func(updatedAt string, labels map[string]string) {
+		fakeClient.ListV3ResourcesStub = func(path string) ([]cfstore.V3Resource, error) {
+			if path != "/v3/apps" {
+				return []cfstore.V3Resource{}, nil
+			}
+			return []cfstore.V3Resource{{
+				Guid:      appGUID,
+				CreatedAt: "2001-01-01T01:01:01+00:00",
+				UpdatedAt: updatedAt,
+				Metadata:  cfstore.V3Metadata{Labels: labels},
+			}}, nil
+		}
+		Expect(store.CollectLabels()).To(Succeed())
+	}
+
+	It("should record labels each time they change", func() {
+		By("using the created_at date for the first labels")
+		collectAppLabels("2002-02-02T02:02:02+00:00", map[string]string{"team": "billing"})
+		firstRow := testenv.Row{
+			"guid":          appGUID,
+			"valid_from":    "2001-01-01T01:01:01+00:00",
+			"resource_type": "app",
+			"labels":        map[string]string{"team": "billing"},
+		}
+		Expect(tempdb.Query(`select * from resource_labels`)).To(MatchJSON(testenv.Rows{firstRow}))
+
+		By("not recording unchanged labels")
+		collectAppLabels("2003-03-03T03:03:03+00:00", map[string]string{"team": "billing"})
+		Expect(tempdb.Query(`select * from resource_labels`)).To(MatchJSON(testenv.Rows{firstRow}))
+
+		By("using the updated_at date when the labels change")
+		collectAppLabels("2004-04-04T04:04:04+00:00", map[string]string{})
+		secondRow := testenv.Row{
+			"guid":          appGUID,
+			"valid_from":    "2004-04-04T04:04:04+00:00",
+			"resource_type": "app",
+			"labels":        map[string]string{},
+		}
+		Expect(tempdb.Query(`select * from resource_labels order by valid_from`)).To(MatchJSON(testenv.Rows{firstRow, secondRow}))
+	})
+
+	It("should not record a resource that has never had labels", func() {
+		collectAppLabels("2002-02-02T02:02:02+00:00", nil)
+		Expect(tempdb.Query(`select * from resource_labels`)).To(MatchJSON(testenv.Rows{}))
+	})
+})
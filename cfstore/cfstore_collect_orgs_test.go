@@ -140,4 +140,31 @@ var _ = Describe("Orgs", func() {
 		Expect(tempdb.Query(`select * from orgs`)).To(MatchJSON(expectedResult3))
 	})
 
+	It("should record when an org is no longer listed", func() {
+		org1 := cfstore.V3Org{
+			Guid:      uuid.NewV4().String(),
+			Name:      "my-org",
+			CreatedAt: "2001-01-01T01:01:01+00:00",
+			UpdatedAt: "2001-01-01T01:01:01+00:00",
+		}
+		org2 := cfstore.V3Org{
+			Guid:      uuid.NewV4().String(),
+			Name:      "my-other-org",
+			CreatedAt: "2001-01-01T01:01:01+00:00",
+			UpdatedAt: "2001-01-01T01:01:01+00:00",
+		}
+		fakeClient.ListOrgsReturnsOnCall(1, []cfstore.V3Org{org1, org2}, nil)
+		Expect(store.CollectOrgs()).To(Succeed())
+		Expect(tempdb.Get(`select count(*) from org_deletions`)).To(BeNumerically("==", 0))
+
+		fakeClient.ListOrgsReturnsOnCall(2, []cfstore.V3Org{org1}, nil)
+		Expect(store.CollectOrgs()).To(Succeed())
+		Expect(tempdb.Get(`select guid::text from org_deletions`)).To(Equal(org2.Guid))
+		deletedAt := tempdb.Get(`select deleted_at from org_deletions`)
+
+		By("keeping the time it was first missing")
+		fakeClient.ListOrgsReturnsOnCall(3, []cfstore.V3Org{org1}, nil)
+		Expect(store.CollectOrgs()).To(Succeed())
+		Expect(tempdb.Get(`select deleted_at from org_deletions`)).To(Equal(deletedAt))
+	})
 })
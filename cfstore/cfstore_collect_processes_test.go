@@ -0,0 +1,120 @@
+package cfstore_test
+
+import (
+	"github.com/alphagov/paas-billing/cfstore"
+	"github.com/alphagov/paas-billing/cfstore/cfstorefakes"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+)
+
+var _ = Describe("Processes and tasks", func() {
+
+	var (
+		tempdb     *testenv.TempDB
+		fakeClient *cfstorefakes.FakeCFDataClient
+		store      *cfstore.Store
+		appGUID    string
+	)
+
+	BeforeEach(func(ctx SpecContext) {
+		var err error
+		tempdb, err = testenv.OpenWithContext(testenv.BasicConfig, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeClient = &cfstorefakes.FakeCFDataClient{}
+		store, err = cfstore.New(cfstore.Config{
+			Client: fakeClient,
+			DB:     tempdb.Conn,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.Init()).To(Succeed())
+
+		appGUID = uuid.NewV4().String()
+	})
+
+	AfterEach(func() {
+		tempdb.Close()
+	})
+
+	int64Ptr := func(i int64) *int64 {
+		return &i
+	}
+
+	It("should record the disk quota and log rate limit each time a process is updated", func() {
+		process := cfstore.V3Process{
+			Guid:                         appGUID,
+			Type:                         "web",
+			CreatedAt:                    "2001-01-01T01:01:01+00:00",
+			UpdatedAt:                    "2002-02-02T02:02:02+00:00",
+			DiskInMB:                     int64Ptr(1024),
+			LogRateLimitInBytesPerSecond: int64Ptr(-1),
+		}
+		process.Relationships.App.Data.Guid = appGUID
+		fakeClient.ListV3ProcessesReturns([]cfstore.V3Process{process}, nil)
+
+		By("using the created_at date for the first record")
+		Expect(store.CollectProcesses()).To(Succeed())
+		firstRow := testenv.Row{
+			"guid":           appGUID,
+			"valid_from":     "2001-01-01T01:01:01+00:00",
+			"app_guid":       appGUID,
+			"process_type":   "web",
+			"disk_in_mb":     1024,
+			"log_rate_limit": -1,
+			"created_at":     "2001-01-01T01:01:01+00:00",
+			"updated_at":     "2002-02-02T02:02:02+00:00",
+		}
+		Expect(tempdb.Query(`select * from app_processes`)).To(MatchJSON(testenv.Rows{firstRow}))
+
+		By("not recording an unchanged process")
+		Expect(store.CollectProcesses()).To(Succeed())
+		Expect(tempdb.Query(`select * from app_processes`)).To(MatchJSON(testenv.Rows{firstRow}))
+
+		By("using the updated_at date when the process changes")
+		process.UpdatedAt = "2003-03-03T03:03:03+00:00"
+		process.DiskInMB = int64Ptr(2048)
+		process.LogRateLimitInBytesPerSecond = int64Ptr(4096)
+		fakeClient.ListV3ProcessesReturns([]cfstore.V3Process{process}, nil)
+		Expect(store.CollectProcesses()).To(Succeed())
+		secondRow := testenv.Row{
+			"guid":           appGUID,
+			"valid_from":     "2003-03-03T03:03:03+00:00",
+			"app_guid":       appGUID,
+			"process_type":   "web",
+			"disk_in_mb":     2048,
+			"log_rate_limit": 4096,
+			"created_at":     "2001-01-01T01:01:01+00:00",
+			"updated_at":     "2003-03-03T03:03:03+00:00",
+		}
+		Expect(tempdb.Query(`select * from app_processes order by valid_from`)).To(MatchJSON(testenv.Rows{firstRow, secondRow}))
+	})
+
+	It("should only request the tasks created since the last task recorded", func() {
+		taskGUID := uuid.NewV4().String()
+		task := cfstore.V3Task{
+			Guid:                         taskGUID,
+			CreatedAt:                    "2001-01-01T01:01:01Z",
+			DiskInMB:                     int64Ptr(512),
+			LogRateLimitInBytesPerSecond: int64Ptr(1024),
+		}
+		task.Relationships.App.Data.Guid = appGUID
+		fakeClient.ListV3TasksCreatedAfterReturns([]cfstore.V3Task{task}, nil)
+
+		Expect(store.CollectTasks()).To(Succeed())
+		Expect(tempdb.Query(`select * from app_tasks`)).To(MatchJSON(testenv.Rows{{
+			"guid":           taskGUID,
+			"app_guid":       appGUID,
+			"disk_in_mb":     512,
+			"log_rate_limit": 1024,
+			"created_at":     "2001-01-01T01:01:01+00:00",
+		}}))
+
+		Expect(store.CollectTasks()).To(Succeed())
+		callCount := fakeClient.ListV3TasksCreatedAfterCallCount()
+		Expect(fakeClient.ListV3TasksCreatedAfterArgsForCall(callCount - 1)).To(Equal("2001-01-01T01:01:01Z"))
+		Expect(tempdb.Query(`select count(*) from app_tasks`)).To(MatchJSON(testenv.Rows{{"count": 1}}))
+	})
+})
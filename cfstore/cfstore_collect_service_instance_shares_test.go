@@ -0,0 +1,112 @@
+package cfstore_test
+
+import (
+	"github.com/alphagov/paas-billing/cfstore"
+	"github.com/alphagov/paas-billing/cfstore/cfstorefakes"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+
+	. "github.com/onsi/gomega"
+	uuid "github.com/satori/go.uuid"
+)
+
+var _ = Describe("ServiceInstanceShares", func() {
+
+	var (
+		tempdb              *testenv.TempDB
+		fakeClient          *cfstorefakes.FakeCFDataClient
+		store               *cfstore.Store
+		serviceInstanceGUID string
+		shares              *cfstore.ServiceInstanceShares
+	)
+
+	BeforeEach(func(ctx SpecContext) {
+		var err error
+		tempdb, err = testenv.OpenWithContext(testenv.BasicConfig, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeClient = &cfstorefakes.FakeCFDataClient{}
+		store, err = cfstore.New(cfstore.Config{
+			Client: fakeClient,
+			DB:     tempdb.Conn,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.Init()).To(Succeed())
+
+		serviceInstanceGUID = uuid.NewV4().String()
+		shares = &cfstore.ServiceInstanceShares{
+			SharedSpaces: []cfstore.SharedSpace{{
+				SpaceGUID: uuid.NewV4().String(),
+				SpaceName: "product",
+				OrgGUID:   uuid.NewV4().String(),
+				OrgName:   "product-org",
+			}},
+			Bindings: map[string]int{},
+		}
+		fakeClient.GetServiceInstanceSharesReturns(shares, nil)
+	})
+
+	AfterEach(func() {
+		tempdb.Close()
+	})
+
+	It("should only request service instances with a cost sharing rule", func() {
+		Expect(store.CollectServiceInstanceShares()).To(Succeed())
+		Expect(fakeClient.GetServiceInstanceSharesCallCount()).To(Equal(0))
+
+		Expect(tempdb.Insert("cost_sharing_rules", testenv.Row{
+			"service_instance_guid": serviceInstanceGUID,
+			"valid_from":            "2001-01-01T00:00:00Z",
+			"method":                "equal",
+		})).To(Succeed())
+		Expect(store.CollectServiceInstanceShares()).To(Succeed())
+		Expect(fakeClient.GetServiceInstanceSharesCallCount()).To(Equal(1))
+		Expect(fakeClient.GetServiceInstanceSharesArgsForCall(0)).To(Equal(serviceInstanceGUID))
+	})
+
+	It("should record the sharing each time it changes", func() {
+		Expect(tempdb.Insert("cost_sharing_rules", testenv.Row{
+			"service_instance_guid": serviceInstanceGUID,
+			"valid_from":            "2001-01-01T00:00:00Z",
+			"method":                "bindings",
+		})).To(Succeed())
+
+		By("recording the first sharing seen")
+		Expect(store.CollectServiceInstanceShares()).To(Succeed())
+		Expect(tempdb.Get(`select count(*) from service_instance_shares`)).To(BeNumerically("==", 1))
+
+		By("not recording unchanged sharing")
+		Expect(store.CollectServiceInstanceShares()).To(Succeed())
+		Expect(tempdb.Get(`select count(*) from service_instance_shares`)).To(BeNumerically("==", 1))
+
+		By("recording changed bindings")
+		shares.Bindings[shares.SharedSpaces[0].SpaceGUID] = 2
+		Expect(store.CollectServiceInstanceShares()).To(Succeed())
+		Expect(tempdb.Query(`
+			select shared_spaces, bindings
+			from service_instance_shares
+			order by valid_from
+		`)).To(MatchJSON(testenv.Rows{
+			{
+				"shared_spaces": []cfstore.SharedSpace{shares.SharedSpaces[0]},
+				"bindings":      map[string]int{},
+			},
+			{
+				"shared_spaces": []cfstore.SharedSpace{shares.SharedSpaces[0]},
+				"bindings":      map[string]int{shares.SharedSpaces[0].SpaceGUID: 2},
+			},
+		}))
+	})
+
+	It("should skip service instances that no longer exist", func() {
+		Expect(tempdb.Insert("cost_sharing_rules", testenv.Row{
+			"service_instance_guid": serviceInstanceGUID,
+			"valid_from":            "2001-01-01T00:00:00Z",
+			"method":                "equal",
+		})).To(Succeed())
+		fakeClient.GetServiceInstanceSharesReturns(nil, nil)
+
+		Expect(store.CollectServiceInstanceShares()).To(Succeed())
+		Expect(tempdb.Get(`select count(*) from service_instance_shares`)).To(BeNumerically("==", 0))
+	})
+})
@@ -53,22 +53,24 @@ var _ = Describe("Spaces", func() {
 		}, nil)
 		Expect(store.CollectSpaces()).To(Succeed())
 		expectedFirstRow := testenv.Row{
-			"guid":       space1.Guid,
-			"name":       space1.Name,
-			"valid_from": space1.CreatedAt,
-			"updated_at": space1.UpdatedAt,
-			"created_at": space1.CreatedAt,
+			"guid":                   space1.Guid,
+			"name":                   space1.Name,
+			"valid_from":             space1.CreatedAt,
+			"updated_at":             space1.UpdatedAt,
+			"created_at":             space1.CreatedAt,
+			"isolation_segment_guid": nil,
 		}
 		expectedResult1 := testenv.Rows{expectedFirstRow}
 		Expect(tempdb.Query(`select * from spaces`)).To(MatchJSON(expectedResult1))
 
 		By("storing the data using the updated_at date for the valid_from field for all subsequent operations")
 		expectedSecondRow := testenv.Row{
-			"guid":       space1.Guid,
-			"name":       space1.Name,
-			"valid_from": space1.UpdatedAt, // THIS IS THE DIFFERENCE FROM 1stROW ^^
-			"updated_at": space1.UpdatedAt,
-			"created_at": space1.CreatedAt,
+			"guid":                   space1.Guid,
+			"name":                   space1.Name,
+			"valid_from":             space1.UpdatedAt, // THIS IS THE DIFFERENCE FROM 1stROW ^^
+			"updated_at":             space1.UpdatedAt,
+			"created_at":             space1.CreatedAt,
+			"isolation_segment_guid": nil,
 		}
 		expectedResult2 := testenv.Rows{expectedFirstRow, expectedSecondRow}
 
@@ -97,11 +99,12 @@ var _ = Describe("Spaces", func() {
 		}, nil)
 		Expect(store.CollectSpaces()).To(Succeed())
 		expectedThirdRow := testenv.Row{
-			"guid":       space2.Guid,
-			"name":       space2.Name,
-			"valid_from": space2.UpdatedAt,
-			"updated_at": space2.UpdatedAt,
-			"created_at": space2.CreatedAt,
+			"guid":                   space2.Guid,
+			"name":                   space2.Name,
+			"valid_from":             space2.UpdatedAt,
+			"updated_at":             space2.UpdatedAt,
+			"created_at":             space2.CreatedAt,
+			"isolation_segment_guid": nil,
 		}
 		expectedResult3 := testenv.Rows{
 			expectedFirstRow,
@@ -111,4 +114,30 @@ var _ = Describe("Spaces", func() {
 		Expect(tempdb.Query(`select * from spaces`)).To(MatchJSON(expectedResult3))
 	})
 
+	It("should record the isolation segment of a space as it changes", func() {
+		space := cfclient.Space{
+			Guid:      uuid.NewV4().String(),
+			Name:      "my-space",
+			CreatedAt: "2001-01-01T01:01:01+00:00",
+			UpdatedAt: "2001-01-01T01:01:01+00:00",
+		}
+		fakeClient.ListSpacesReturnsOnCall(1, []cfclient.Space{space}, nil)
+		Expect(store.CollectSpaces()).To(Succeed())
+
+		segmentGUID := uuid.NewV4().String()
+		space.IsolationSegmentGuid = segmentGUID
+		space.UpdatedAt = "2002-02-02T02:02:02+00:00"
+		fakeClient.ListSpacesReturnsOnCall(2, []cfclient.Space{space}, nil)
+		Expect(store.CollectSpaces()).To(Succeed())
+
+		Expect(tempdb.Query(`
+			select valid_from, isolation_segment_guid
+			from spaces
+			order by valid_from
+		`)).To(MatchJSON(testenv.Rows{
+			{"valid_from": "2001-01-01T01:01:01+00:00", "isolation_segment_guid": nil},
+			{"valid_from": "2002-02-02T02:02:02+00:00", "isolation_segment_guid": segmentGUID},
+		}))
+	})
+
 })
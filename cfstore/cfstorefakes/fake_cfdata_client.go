@@ -9,6 +9,19 @@ import (
 )
 
 type FakeCFDataClient struct {
+	GetServiceInstanceSharesStub        func(string) (*cfstore.ServiceInstanceShares, error)
+	getServiceInstanceSharesMutex       sync.RWMutex
+	getServiceInstanceSharesArgsForCall []struct {
+		arg1 string
+	}
+	getServiceInstanceSharesReturns struct {
+		result1 *cfstore.ServiceInstanceShares
+		result2 error
+	}
+	getServiceInstanceSharesReturnsOnCall map[int]struct {
+		result1 *cfstore.ServiceInstanceShares
+		result2 error
+	}
 	ListOrgsStub        func() ([]cfstore.V3Org, error)
 	listOrgsMutex       sync.RWMutex
 	listOrgsArgsForCall []struct {
@@ -57,10 +70,112 @@ type FakeCFDataClient struct {
 		result1 []cfclient.Space
 		result2 error
 	}
+	ListV3ProcessesStub        func() ([]cfstore.V3Process, error)
+	listV3ProcessesMutex       sync.RWMutex
+	listV3ProcessesArgsForCall []struct {
+	}
+	listV3ProcessesReturns struct {
+		result1 []cfstore.V3Process
+		result2 error
+	}
+	listV3ProcessesReturnsOnCall map[int]struct {
+		result1 []cfstore.V3Process
+		result2 error
+	}
+	ListV3ResourcesStub        func(string) ([]cfstore.V3Resource, error)
+	listV3ResourcesMutex       sync.RWMutex
+	listV3ResourcesArgsForCall []struct {
+		arg1 string
+	}
+	listV3ResourcesReturns struct {
+		result1 []cfstore.V3Resource
+		result2 error
+	}
+	listV3ResourcesReturnsOnCall map[int]struct {
+		result1 []cfstore.V3Resource
+		result2 error
+	}
+	ListV3TasksCreatedAfterStub        func(string) ([]cfstore.V3Task, error)
+	listV3TasksCreatedAfterMutex       sync.RWMutex
+	listV3TasksCreatedAfterArgsForCall []struct {
+		arg1 string
+	}
+	listV3TasksCreatedAfterReturns struct {
+		result1 []cfstore.V3Task
+		result2 error
+	}
+	listV3TasksCreatedAfterReturnsOnCall map[int]struct {
+		result1 []cfstore.V3Task
+		result2 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeCFDataClient) GetServiceInstanceShares(arg1 string) (*cfstore.ServiceInstanceShares, error) {
+	fake.getServiceInstanceSharesMutex.Lock()
+	ret, specificReturn := fake.getServiceInstanceSharesReturnsOnCall[len(fake.getServiceInstanceSharesArgsForCall)]
+	fake.getServiceInstanceSharesArgsForCall = append(fake.getServiceInstanceSharesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GetServiceInstanceSharesStub
+	fakeReturns := fake.getServiceInstanceSharesReturns
+	fake.recordInvocation("GetServiceInstanceShares", []interface{}{arg1})
+	fake.getServiceInstanceSharesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCFDataClient) GetServiceInstanceSharesCallCount() int {
+	fake.getServiceInstanceSharesMutex.RLock()
+	defer fake.getServiceInstanceSharesMutex.RUnlock()
+	return len(fake.getServiceInstanceSharesArgsForCall)
+}
+
+func (fake *FakeCFDataClient) GetServiceInstanceSharesCalls(stub func(string) (*cfstore.ServiceInstanceShares, error)) {
+	fake.getServiceInstanceSharesMutex.Lock()
+	defer fake.getServiceInstanceSharesMutex.Unlock()
+	fake.GetServiceInstanceSharesStub = stub
+}
+
+func (fake *FakeCFDataClient) GetServiceInstanceSharesArgsForCall(i int) string {
+	fake.getServiceInstanceSharesMutex.RLock()
+	defer fake.getServiceInstanceSharesMutex.RUnlock()
+	argsForCall := fake.getServiceInstanceSharesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCFDataClient) GetServiceInstanceSharesReturns(result1 *cfstore.ServiceInstanceShares, result2 error) {
+	fake.getServiceInstanceSharesMutex.Lock()
+	defer fake.getServiceInstanceSharesMutex.Unlock()
+	fake.GetServiceInstanceSharesStub = nil
+	fake.getServiceInstanceSharesReturns = struct {
+		result1 *cfstore.ServiceInstanceShares
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCFDataClient) GetServiceInstanceSharesReturnsOnCall(i int, result1 *cfstore.ServiceInstanceShares, result2 error) {
+	fake.getServiceInstanceSharesMutex.Lock()
+	defer fake.getServiceInstanceSharesMutex.Unlock()
+	fake.GetServiceInstanceSharesStub = nil
+	if fake.getServiceInstanceSharesReturnsOnCall == nil {
+		fake.getServiceInstanceSharesReturnsOnCall = make(map[int]struct {
+			result1 *cfstore.ServiceInstanceShares
+			result2 error
+		})
+	}
+	fake.getServiceInstanceSharesReturnsOnCall[i] = struct {
+		result1 *cfstore.ServiceInstanceShares
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeCFDataClient) ListOrgs() ([]cfstore.V3Org, error) {
 	fake.listOrgsMutex.Lock()
 	ret, specificReturn := fake.listOrgsReturnsOnCall[len(fake.listOrgsArgsForCall)]
@@ -285,9 +400,195 @@ func (fake *FakeCFDataClient) ListSpacesReturnsOnCall(i int, result1 []cfclient.
 	}{result1, result2}
 }
 
+func (fake *FakeCFDataClient) ListV3Processes() ([]cfstore.V3Process, error) {
+	fake.listV3ProcessesMutex.Lock()
+	ret, specificReturn := fake.listV3ProcessesReturnsOnCall[len(fake.listV3ProcessesArgsForCall)]
+	fake.listV3ProcessesArgsForCall = append(fake.listV3ProcessesArgsForCall, struct {
+	}{})
+	stub := fake.ListV3ProcessesStub
+	fakeReturns := fake.listV3ProcessesReturns
+	fake.recordInvocation("ListV3Processes", []interface{}{})
+	fake.listV3ProcessesMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCFDataClient) ListV3ProcessesCallCount() int {
+	fake.listV3ProcessesMutex.RLock()
+	defer fake.listV3ProcessesMutex.RUnlock()
+	return len(fake.listV3ProcessesArgsForCall)
+}
+
+func (fake *FakeCFDataClient) ListV3ProcessesCalls(stub func() ([]cfstore.V3Process, error)) {
+	fake.listV3ProcessesMutex.Lock()
+	defer fake.listV3ProcessesMutex.Unlock()
+	fake.ListV3ProcessesStub = stub
+}
+
+func (fake *FakeCFDataClient) ListV3ProcessesReturns(result1 []cfstore.V3Process, result2 error) {
+	fake.listV3ProcessesMutex.Lock()
+	defer fake.listV3ProcessesMutex.Unlock()
+	fake.ListV3ProcessesStub = nil
+	fake.listV3ProcessesReturns = struct {
+		result1 []cfstore.V3Process
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCFDataClient) ListV3ProcessesReturnsOnCall(i int, result1 []cfstore.V3Process, result2 error) {
+	fake.listV3ProcessesMutex.Lock()
+	defer fake.listV3ProcessesMutex.Unlock()
+	fake.ListV3ProcessesStub = nil
+	if fake.listV3ProcessesReturnsOnCall == nil {
+		fake.listV3ProcessesReturnsOnCall = make(map[int]struct {
+			result1 []cfstore.V3Process
+			result2 error
+		})
+	}
+	fake.listV3ProcessesReturnsOnCall[i] = struct {
+		result1 []cfstore.V3Process
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCFDataClient) ListV3Resources(arg1 string) ([]cfstore.V3Resource, error) {
+	fake.listV3ResourcesMutex.Lock()
+	ret, specificReturn := fake.listV3ResourcesReturnsOnCall[len(fake.listV3ResourcesArgsForCall)]
+	fake.listV3ResourcesArgsForCall = append(fake.listV3ResourcesArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ListV3ResourcesStub
+	fakeReturns := fake.listV3ResourcesReturns
+	fake.recordInvocation("ListV3Resources", []interface{}{arg1})
+	fake.listV3ResourcesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCFDataClient) ListV3ResourcesCallCount() int {
+	fake.listV3ResourcesMutex.RLock()
+	defer fake.listV3ResourcesMutex.RUnlock()
+	return len(fake.listV3ResourcesArgsForCall)
+}
+
+func (fake *FakeCFDataClient) ListV3ResourcesCalls(stub func(string) ([]cfstore.V3Resource, error)) {
+	fake.listV3ResourcesMutex.Lock()
+	defer fake.listV3ResourcesMutex.Unlock()
+	fake.ListV3ResourcesStub = stub
+}
+
+func (fake *FakeCFDataClient) ListV3ResourcesArgsForCall(i int) string {
+	fake.listV3ResourcesMutex.RLock()
+	defer fake.listV3ResourcesMutex.RUnlock()
+	argsForCall := fake.listV3ResourcesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCFDataClient) ListV3ResourcesReturns(result1 []cfstore.V3Resource, result2 error) {
+	fake.listV3ResourcesMutex.Lock()
+	defer fake.listV3ResourcesMutex.Unlock()
+	fake.ListV3ResourcesStub = nil
+	fake.listV3ResourcesReturns = struct {
+		result1 []cfstore.V3Resource
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCFDataClient) ListV3ResourcesReturnsOnCall(i int, result1 []cfstore.V3Resource, result2 error) {
+	fake.listV3ResourcesMutex.Lock()
+	defer fake.listV3ResourcesMutex.Unlock()
+	fake.ListV3ResourcesStub = nil
+	if fake.listV3ResourcesReturnsOnCall == nil {
+		fake.listV3ResourcesReturnsOnCall = make(map[int]struct {
+			result1 []cfstore.V3Resource
+			result2 error
+		})
+	}
+	fake.listV3ResourcesReturnsOnCall[i] = struct {
+		result1 []cfstore.V3Resource
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCFDataClient) ListV3TasksCreatedAfter(arg1 string) ([]cfstore.V3Task, error) {
+	fake.listV3TasksCreatedAfterMutex.Lock()
+	ret, specificReturn := fake.listV3TasksCreatedAfterReturnsOnCall[len(fake.listV3TasksCreatedAfterArgsForCall)]
+	fake.listV3TasksCreatedAfterArgsForCall = append(fake.listV3TasksCreatedAfterArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.ListV3TasksCreatedAfterStub
+	fakeReturns := fake.listV3TasksCreatedAfterReturns
+	fake.recordInvocation("ListV3TasksCreatedAfter", []interface{}{arg1})
+	fake.listV3TasksCreatedAfterMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCFDataClient) ListV3TasksCreatedAfterCallCount() int {
+	fake.listV3TasksCreatedAfterMutex.RLock()
+	defer fake.listV3TasksCreatedAfterMutex.RUnlock()
+	return len(fake.listV3TasksCreatedAfterArgsForCall)
+}
+
+func (fake *FakeCFDataClient) ListV3TasksCreatedAfterCalls(stub func(string) ([]cfstore.V3Task, error)) {
+	fake.listV3TasksCreatedAfterMutex.Lock()
+	defer fake.listV3TasksCreatedAfterMutex.Unlock()
+	fake.ListV3TasksCreatedAfterStub = stub
+}
+
+func (fake *FakeCFDataClient) ListV3TasksCreatedAfterArgsForCall(i int) string {
+	fake.listV3TasksCreatedAfterMutex.RLock()
+	defer fake.listV3TasksCreatedAfterMutex.RUnlock()
+	argsForCall := fake.listV3TasksCreatedAfterArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCFDataClient) ListV3TasksCreatedAfterReturns(result1 []cfstore.V3Task, result2 error) {
+	fake.listV3TasksCreatedAfterMutex.Lock()
+	defer fake.listV3TasksCreatedAfterMutex.Unlock()
+	fake.ListV3TasksCreatedAfterStub = nil
+	fake.listV3TasksCreatedAfterReturns = struct {
+		result1 []cfstore.V3Task
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCFDataClient) ListV3TasksCreatedAfterReturnsOnCall(i int, result1 []cfstore.V3Task, result2 error) {
+	fake.listV3TasksCreatedAfterMutex.Lock()
+	defer fake.listV3TasksCreatedAfterMutex.Unlock()
+	fake.ListV3TasksCreatedAfterStub = nil
+	if fake.listV3TasksCreatedAfterReturnsOnCall == nil {
+		fake.listV3TasksCreatedAfterReturnsOnCall = make(map[int]struct {
+			result1 []cfstore.V3Task
+			result2 error
+		})
+	}
+	fake.listV3TasksCreatedAfterReturnsOnCall[i] = struct {
+		result1 []cfstore.V3Task
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeCFDataClient) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.getServiceInstanceSharesMutex.RLock()
+	defer fake.getServiceInstanceSharesMutex.RUnlock()
 	fake.listOrgsMutex.RLock()
 	defer fake.listOrgsMutex.RUnlock()
 	fake.listServicePlansMutex.RLock()
@@ -296,6 +597,12 @@ func (fake *FakeCFDataClient) Invocations() map[string][][]interface{} {
 	defer fake.listServicesMutex.RUnlock()
 	fake.listSpacesMutex.RLock()
 	defer fake.listSpacesMutex.RUnlock()
+	fake.listV3ProcessesMutex.RLock()
+	defer fake.listV3ProcessesMutex.RUnlock()
+	fake.listV3ResourcesMutex.RLock()
+	defer fake.listV3ResourcesMutex.RUnlock()
+	fake.listV3TasksCreatedAfterMutex.RLock()
+	defer fake.listV3TasksCreatedAfterMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,83 @@
+package eventio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AllowanceReader interface {
+	GetAllowanceUsage(filter EventFilter) ([]AllowanceUsage, error)
+	GetAllowanceUsageContext(ctx context.Context, filter EventFilter) ([]AllowanceUsage, error)
+}
+
+// Allowance gives each org Amount of the usage measured in Unit of the
+// resources with any of PlanGUIDs free every calendar month from ValidFrom
+// until ValidTo. An empty ValidTo never ends. An allowance with a
+// QuotaDefinitionGUID only applies to orgs while they have that quota, and
+// takes the place of allowances for every org of the same plans and unit.
+// Usage is set against the allowance in the order it happened and the part
+// it covers is credited as a negative price component named after the
+// allowance.
+type Allowance struct {
+	Name                string   `json:"name"`
+	PlanGUIDs           []string `json:"plan_guids"`
+	Unit                string   `json:"unit"`
+	Amount              string   `json:"amount"`
+	QuotaDefinitionGUID string   `json:"quota_definition_guid,omitempty"`
+	ValidFrom           string   `json:"valid_from"`
+	ValidTo             string   `json:"valid_to,omitempty"`
+}
+
+func (a *Allowance) Validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("allowance must have a name")
+	}
+	if len(a.PlanGUIDs) == 0 {
+		return fmt.Errorf("allowance %s must have plan_guids", a.Name)
+	}
+	for _, planGUID := range a.PlanGUIDs {
+		if !guidPattern.MatchString(planGUID) {
+			return fmt.Errorf("allowance %s plan_guids must be guids - got %s", a.Name, planGUID)
+		}
+	}
+	if a.Unit == "" {
+		return fmt.Errorf("allowance %s must have a unit", a.Name)
+	}
+	if _, err := ParseMoney(a.Amount); err != nil {
+		return fmt.Errorf("allowance %s amount: %s", a.Name, err)
+	}
+	if strings.HasPrefix(a.Amount, "-") {
+		return fmt.Errorf("allowance %s amount must not be negative - got %s", a.Name, a.Amount)
+	}
+	if a.QuotaDefinitionGUID != "" && !guidPattern.MatchString(a.QuotaDefinitionGUID) {
+		return fmt.Errorf("allowance %s quota_definition_guid must be a guid if given - got %s", a.Name, a.QuotaDefinitionGUID)
+	}
+	validFrom, err := time.Parse("2006-01-02", a.ValidFrom)
+	if err != nil {
+		return fmt.Errorf("allowance %s valid_from must be a date - expected format 2006-01-02 - got %s", a.Name, a.ValidFrom)
+	}
+	if a.ValidTo != "" {
+		validTo, err := time.Parse("2006-01-02", a.ValidTo)
+		if err != nil {
+			return fmt.Errorf("allowance %s valid_to must be a date if given - expected format 2006-01-02 - got %s", a.Name, a.ValidTo)
+		}
+		if !validTo.After(validFrom) {
+			return fmt.Errorf("allowance %s valid_to must be after valid_from - got %s to %s", a.Name, a.ValidFrom, a.ValidTo)
+		}
+	}
+	return nil
+}
+
+// AllowanceUsage is how much of an allowance an org used in the calendar
+// month starting at Month, and how much it has left
+type AllowanceUsage struct {
+	OrgGUID   string `json:"org_guid"`
+	Name      string `json:"name"`
+	Unit      string `json:"unit"`
+	Month     string `json:"month"`
+	Amount    string `json:"amount"`
+	Used      string `json:"used"`
+	Remaining string `json:"remaining"`
+}
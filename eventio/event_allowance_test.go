@@ -0,0 +1,35 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Allowance", func() {
+	const (
+		planGUID  = "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5"
+		quotaGUID = "f9909cea-81fe-4934-ba17-2a10278d2646"
+	)
+
+	DescribeTable("Validate",
+		func(allowance Allowance, expectedErr string) {
+			if expectedErr == "" {
+				Expect(allowance.Validate()).To(Succeed())
+			} else {
+				Expect(allowance.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("every org", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "100", ValidFrom: "2001-01-01"}, ""),
+		Entry("a quota until a date", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "0.5", QuotaDefinitionGUID: quotaGUID, ValidFrom: "2001-01-01", ValidTo: "2002-01-01"}, ""),
+		Entry("missing name", Allowance{PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "100", ValidFrom: "2001-01-01"}, "must have a name"),
+		Entry("missing plans", Allowance{Name: "free compute", Unit: "GB-hours", Amount: "100", ValidFrom: "2001-01-01"}, "must have plan_guids"),
+		Entry("plan not a guid", Allowance{Name: "free compute", PlanGUIDs: []string{"app"}, Unit: "GB-hours", Amount: "100", ValidFrom: "2001-01-01"}, "plan_guids must be guids"),
+		Entry("missing unit", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Amount: "100", ValidFrom: "2001-01-01"}, "must have a unit"),
+		Entry("bad amount", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "lots", ValidFrom: "2001-01-01"}, "amount"),
+		Entry("negative amount", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "-1", ValidFrom: "2001-01-01"}, "must not be negative"),
+		Entry("quota not a guid", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "100", QuotaDefinitionGUID: "trial", ValidFrom: "2001-01-01"}, "quota_definition_guid must be a guid"),
+		Entry("missing valid_from", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "100"}, "valid_from must be a date"),
+		Entry("valid_to before valid_from", Allowance{Name: "free compute", PlanGUIDs: []string{planGUID}, Unit: "GB-hours", Amount: "100", ValidFrom: "2001-01-01", ValidTo: "2000-01-01"}, "valid_to must be after valid_from"),
+	)
+})
@@ -4,17 +4,31 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 type BillableEventReader interface {
 	GetBillableEventRows(ctx context.Context, filter EventFilter) (BillableEventRows, error)
 	GetBillableEvents(filter EventFilter) ([]BillableEvent, error)
+	GetBillableEventsContext(ctx context.Context, filter EventFilter) ([]BillableEvent, error)
 }
 
 type ConsolidatedBillableEventReader interface {
 	GetConsolidatedBillableEventRows(ctx context.Context, filter EventFilter) (BillableEventRows, error)
 	GetConsolidatedBillableEvents(filter EventFilter) ([]BillableEvent, error)
+	GetConsolidatedBillableEventsContext(ctx context.Context, filter EventFilter) ([]BillableEvent, error)
 	IsRangeConsolidated(filter EventFilter) (bool, error)
+	IsRangeConsolidatedContext(ctx context.Context, filter EventFilter) (bool, error)
+	GetConsolidationHistory(filter EventFilter) ([]ConsolidationRecord, error)
+	GetConsolidationHistoryContext(ctx context.Context, filter EventFilter) ([]ConsolidationRecord, error)
+}
+
+// ConsolidationRecord says when a month was consolidated. The consolidated
+// events for a month never change once this record exists.
+type ConsolidationRecord struct {
+	RangeStart string    `json:"range_start"`
+	RangeStop  string    `json:"range_stop"`
+	CreatedAt  time.Time `json:"created_at"`
 }
 
 type BillableEventConsolidator interface {
@@ -26,6 +40,7 @@ type BillableEventConsolidator interface {
 type BillableEventForecaster interface {
 	ForecastBillableEventRows(ctx context.Context, events []UsageEvent, filter EventFilter) (BillableEventRows, error)
 	ForecastBillableEvents(events []UsageEvent, filter EventFilter) ([]BillableEvent, error)
+	ForecastBillableEventsContext(ctx context.Context, events []UsageEvent, filter EventFilter) ([]BillableEvent, error)
 }
 
 //counterfeiter:generate . BillableEventRows
@@ -45,16 +60,18 @@ type PriceComponent struct {
 	VatRate      string `json:"vat_rate"`
 	VatCode      string `json:"vat_code"`
 	CurrencyCode string `json:"currency_code"`
-	IncVAT       string `json:"inc_vat"`
-	ExVAT        string `json:"ex_vat"`
+	IncVAT       Money  `json:"inc_vat"`
+	ExVAT        Money  `json:"ex_vat"`
+	// Unit and Quantity are the usage the component charges for, if its
+	// pricing plan component has a unit
+	Unit     string `json:"unit,omitempty"`
+	Quantity string `json:"quantity,omitempty"`
 }
 
 type Price struct {
-	IncVAT      string           `json:"inc_vat"`
-	ExVAT       string           `json:"ex_vat"`
-	FloatIncVAT float64          `json:"-"`
-	FloatExVAT  float64          `json:"-"`
-	Details     []PriceComponent `json:"details"`
+	IncVAT  Money            `json:"inc_vat"`
+	ExVAT   Money            `json:"ex_vat"`
+	Details []PriceComponent `json:"details"`
 }
 
 type BillableEvent struct {
@@ -74,7 +91,12 @@ type BillableEvent struct {
 	NumberOfNodes       int64  `json:"number_of_nodes"`
 	MemoryInMB          int64  `json:"memory_in_mb"`
 	StorageInMB         int64  `json:"storage_in_mb"`
+	DiskInMB            int64  `json:"disk_in_mb,omitempty"`
+	LogRateLimit        int64  `json:"log_rate_limit,omitempty"`
 	Price               Price  `json:"price"`
+	// Labels are the cf metadata labels of the resource, its space and its
+	// org during the event, with the resource's taking precedence
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 func (e *BillableEvent) Scan(src interface{}) error {
@@ -1,20 +1,188 @@
 package eventio
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PricingPlan prices the resources with PlanGUID. A plan with an
+// IsolationSegmentGUID replaces the app, task or staging plan PlanGUID for
+// spaces in that isolation segment.
 type PricingPlan struct {
-	Name          string                 `json:"name"`
-	PlanGUID      string                 `json:"plan_guid"`
-	ValidFrom     string                 `json:"valid_from"`
-	Components    []PricingPlanComponent `json:"components"`
-	MemoryInMB    uint                   `json:"memory_in_mb"`
-	StorageInMB   uint                   `json:"storage_in_mb"`
-	NumberOfNodes uint                   `json:"number_of_nodes"`
+	Name                 string                 `json:"name"`
+	PlanGUID             string                 `json:"plan_guid"`
+	ValidFrom            string                 `json:"valid_from"`
+	Components           []PricingPlanComponent `json:"components"`
+	MemoryInMB           uint                   `json:"memory_in_mb"`
+	StorageInMB          uint                   `json:"storage_in_mb"`
+	NumberOfNodes        uint                   `json:"number_of_nodes"`
+	IsolationSegmentGUID string                 `json:"isolation_segment_guid,omitempty"`
 }
 
+// PricingPlanComponent is one charge of a PricingPlan. A component with a
+// Unit also reports the usage it charges for, such as "GB-hours", as
+// QuantityFormula evaluated with the same variables as Formula.
 type PricingPlanComponent struct {
-	Name         string `json:"name"`
-	Formula      string `json:"formula"`
-	VATCode      string `json:"vat_code"`
-	CurrencyCode string `json:"currency_code"`
+	Name            string `json:"name"`
+	Formula         string `json:"formula"`
+	VATCode         string `json:"vat_code"`
+	CurrencyCode    string `json:"currency_code"`
+	Unit            string `json:"unit,omitempty"`
+	QuantityFormula string `json:"quantity_formula,omitempty"`
+}
+
+// QuotaPlan prices orgs by the quota definition assigned to them in Cloud
+// Foundry. MonthlyFee is charged for every calendar month, prorated by the
+// time the org has the quota. Multipliers scale the price of the org's
+// resources while it has the quota.
+type QuotaPlan struct {
+	Name                string            `json:"name"`
+	QuotaDefinitionGUID string            `json:"quota_definition_guid"`
+	ValidFrom           string            `json:"valid_from"`
+	MonthlyFee          Money             `json:"monthly_fee"`
+	CurrencyCode        string            `json:"currency_code"`
+	VATCode             string            `json:"vat_code"`
+	Multipliers         []QuotaMultiplier `json:"multipliers"`
+}
+
+// QuotaMultiplier multiplies the price of the pricing plan components
+// called ComponentName, or of every component if ComponentName is empty
+type QuotaMultiplier struct {
+	ComponentName string `json:"component_name"`
+	Multiplier    string `json:"multiplier"`
+}
+
+func (p *QuotaPlan) Validate() error {
+	if !guidPattern.MatchString(p.QuotaDefinitionGUID) {
+		return fmt.Errorf("quota plan quota_definition_guid must be a guid - got %s", p.QuotaDefinitionGUID)
+	}
+	if p.Name == "" {
+		return fmt.Errorf("quota plan %s must have a name", p.QuotaDefinitionGUID)
+	}
+	if _, err := ParseMoney(string(p.MonthlyFee)); err != nil {
+		return fmt.Errorf("quota plan %s monthly_fee: %s", p.QuotaDefinitionGUID, err)
+	}
+	for _, m := range p.Multipliers {
+		if _, err := ParseMoney(m.Multiplier); err != nil {
+			return fmt.Errorf("quota plan %s multiplier: %s", p.QuotaDefinitionGUID, err)
+		}
+	}
+	return nil
+}
+
+const (
+	// CostSharingEqual splits costs equally between the owning space and
+	// the spaces the service instance is shared into
+	CostSharingEqual = "equal"
+	// CostSharingBindings splits costs by the number of apps bound to the
+	// service instance in each space
+	CostSharingBindings = "bindings"
+	// CostSharingWeights splits costs by the weights given to each space
+	CostSharingWeights = "weights"
+)
+
+// CostSharingRule splits the cost of a service instance that is shared into
+// other spaces between the owning space and those spaces from ValidFrom
+// until ValidTo. Each consuming space is billed for its share in its own
+// org, and the owning space is credited the same amount. An empty ValidTo
+// never ends.
+type CostSharingRule struct {
+	ServiceInstanceGUID string              `json:"service_instance_guid"`
+	Method              string              `json:"method"`
+	Weights             []CostSharingWeight `json:"weights"` // only used by CostSharingWeights
+	ValidFrom           string              `json:"valid_from"`
+	ValidTo             string              `json:"valid_to"`
+}
+
+// CostSharingWeight is the relative share of the cost given to a space.
+// Spaces without a weight are not charged.
+type CostSharingWeight struct {
+	SpaceGUID string `json:"space_guid"`
+	Weight    string `json:"weight"`
+}
+
+func (r *CostSharingRule) Validate() error {
+	if !guidPattern.MatchString(r.ServiceInstanceGUID) {
+		return fmt.Errorf("cost sharing rule service_instance_guid must be a guid - got %s", r.ServiceInstanceGUID)
+	}
+	switch r.Method {
+	case CostSharingEqual, CostSharingBindings:
+		if len(r.Weights) > 0 {
+			return fmt.Errorf("cost sharing rule %s: weights can only be given with method %q", r.ServiceInstanceGUID, CostSharingWeights)
+		}
+	case CostSharingWeights:
+		if len(r.Weights) == 0 {
+			return fmt.Errorf("cost sharing rule %s: method %q needs weights", r.ServiceInstanceGUID, CostSharingWeights)
+		}
+		for _, w := range r.Weights {
+			if !guidPattern.MatchString(w.SpaceGUID) {
+				return fmt.Errorf("cost sharing rule %s weight space_guid must be a guid - got %s", r.ServiceInstanceGUID, w.SpaceGUID)
+			}
+			if _, err := ParseMoney(w.Weight); err != nil {
+				return fmt.Errorf("cost sharing rule %s weight: %s", r.ServiceInstanceGUID, err)
+			}
+			if strings.HasPrefix(w.Weight, "-") {
+				return fmt.Errorf("cost sharing rule %s weight must not be negative - got %s", r.ServiceInstanceGUID, w.Weight)
+			}
+		}
+	default:
+		return fmt.Errorf("cost sharing rule %s method must be one of %q - got %q", r.ServiceInstanceGUID,
+			[]string{CostSharingEqual, CostSharingBindings, CostSharingWeights}, r.Method)
+	}
+	validFrom, err := time.Parse("2006-01-02", r.ValidFrom)
+	if err != nil {
+		return fmt.Errorf("cost sharing rule %s valid_from must be a date - expected format 2006-01-02 - got %s", r.ServiceInstanceGUID, r.ValidFrom)
+	}
+	if r.ValidTo != "" {
+		validTo, err := time.Parse("2006-01-02", r.ValidTo)
+		if err != nil {
+			return fmt.Errorf("cost sharing rule %s valid_to must be a date if given - expected format 2006-01-02 - got %s", r.ServiceInstanceGUID, r.ValidTo)
+		}
+		if !validTo.After(validFrom) {
+			return fmt.Errorf("cost sharing rule %s valid_to must be after valid_from - got %s to %s", r.ServiceInstanceGUID, r.ValidFrom, r.ValidTo)
+		}
+	}
+	return nil
+}
+
+// ProviderCostRule bills the provider costs imported for the service
+// instances of PlanGUID as pass-through price components, from ValidFrom
+// until the plan's next rule. Markup is the fraction added to the cost, such
+// as "0.1" for 10%, and is 0 if empty. The currency is that of the costs.
+type ProviderCostRule struct {
+	PlanGUID  string `json:"plan_guid"`
+	ValidFrom string `json:"valid_from"`
+	Markup    string `json:"markup"`
+	VATCode   string `json:"vat_code"`
+}
+
+func (r *ProviderCostRule) Validate() error {
+	if !guidPattern.MatchString(r.PlanGUID) {
+		return fmt.Errorf("provider cost rule plan_guid must be a guid - got %s", r.PlanGUID)
+	}
+	if _, err := time.Parse("2006-01-02", r.ValidFrom); err != nil {
+		return fmt.Errorf("provider cost rule %s valid_from must be a date - expected format 2006-01-02 - got %s", r.PlanGUID, r.ValidFrom)
+	}
+	if r.Markup != "" {
+		if _, err := ParseMoney(r.Markup); err != nil {
+			return fmt.Errorf("provider cost rule %s markup: %s", r.PlanGUID, err)
+		}
+		if strings.HasPrefix(r.Markup, "-") {
+			return fmt.Errorf("provider cost rule %s markup must not be negative - got %s", r.PlanGUID, r.Markup)
+		}
+	}
+	if r.VATCode == "" {
+		return fmt.Errorf("provider cost rule %s must have a vat_code", r.PlanGUID)
+	}
+	return nil
+}
+
+// AggregationRule folds the billable and usage events of a resource type
+// into a single event per space and plan for each period queried
+type AggregationRule struct {
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"` // the resource name of the aggregated events
 }
 
 type VATRate struct {
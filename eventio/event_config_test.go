@@ -0,0 +1,75 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QuotaPlan", func() {
+	const quotaGUID = "f9909cea-81fe-4934-ba17-2a10278d2646"
+
+	DescribeTable("Validate",
+		func(plan QuotaPlan, expectedErr string) {
+			if expectedErr == "" {
+				Expect(plan.Validate()).To(Succeed())
+			} else {
+				Expect(plan.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("fee", QuotaPlan{Name: "small", QuotaDefinitionGUID: quotaGUID, MonthlyFee: "250"}, ""),
+		Entry("multipliers", QuotaPlan{Name: "large", QuotaDefinitionGUID: quotaGUID, MonthlyFee: "0", Multipliers: []QuotaMultiplier{{ComponentName: "compute", Multiplier: "0.8"}, {Multiplier: "0.9"}}}, ""),
+		Entry("quota not a guid", QuotaPlan{Name: "small", QuotaDefinitionGUID: "default", MonthlyFee: "250"}, "quota_definition_guid must be a guid"),
+		Entry("missing name", QuotaPlan{QuotaDefinitionGUID: quotaGUID, MonthlyFee: "250"}, "must have a name"),
+		Entry("bad fee", QuotaPlan{Name: "small", QuotaDefinitionGUID: quotaGUID, MonthlyFee: "£250"}, "monthly_fee"),
+		Entry("bad multiplier", QuotaPlan{Name: "small", QuotaDefinitionGUID: quotaGUID, MonthlyFee: "250", Multipliers: []QuotaMultiplier{{Multiplier: "double"}}}, "multiplier"),
+	)
+})
+
+var _ = Describe("CostSharingRule", func() {
+	const (
+		serviceInstanceGUID = "3ad1c9a9-4e70-4a8e-9b5c-5b1d1ad0f2e1"
+		spaceGUID           = "276f4886-ac40-492d-a8cd-b2646637ba76"
+	)
+
+	DescribeTable("Validate",
+		func(rule CostSharingRule, expectedErr string) {
+			if expectedErr == "" {
+				Expect(rule.Validate()).To(Succeed())
+			} else {
+				Expect(rule.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("equal", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingEqual, ValidFrom: "2001-01-01"}, ""),
+		Entry("bindings until a date", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingBindings, ValidFrom: "2001-01-01", ValidTo: "2002-01-01"}, ""),
+		Entry("weights", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingWeights, ValidFrom: "2001-01-01", Weights: []CostSharingWeight{{SpaceGUID: spaceGUID, Weight: "2.5"}}}, ""),
+		Entry("service instance not a guid", CostSharingRule{ServiceInstanceGUID: "db", Method: CostSharingEqual, ValidFrom: "2001-01-01"}, "service_instance_guid must be a guid"),
+		Entry("unknown method", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: "usage", ValidFrom: "2001-01-01"}, "method must be one of"),
+		Entry("weights without weights method", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingEqual, ValidFrom: "2001-01-01", Weights: []CostSharingWeight{{SpaceGUID: spaceGUID, Weight: "1"}}}, "weights can only be given"),
+		Entry("weights method without weights", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingWeights, ValidFrom: "2001-01-01"}, "needs weights"),
+		Entry("negative weight", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingWeights, ValidFrom: "2001-01-01", Weights: []CostSharingWeight{{SpaceGUID: spaceGUID, Weight: "-1"}}}, "must not be negative"),
+		Entry("missing valid_from", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingEqual}, "valid_from must be a date"),
+		Entry("valid_to before valid_from", CostSharingRule{ServiceInstanceGUID: serviceInstanceGUID, Method: CostSharingEqual, ValidFrom: "2001-01-01", ValidTo: "2001-01-01"}, "valid_to must be after valid_from"),
+	)
+})
+
+var _ = Describe("ProviderCostRule", func() {
+	const planGUID = "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5"
+
+	DescribeTable("Validate",
+		func(rule ProviderCostRule, expectedErr string) {
+			if expectedErr == "" {
+				Expect(rule.Validate()).To(Succeed())
+			} else {
+				Expect(rule.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("without markup", ProviderCostRule{PlanGUID: planGUID, ValidFrom: "2001-01-01", VATCode: "Standard"}, ""),
+		Entry("with markup", ProviderCostRule{PlanGUID: planGUID, ValidFrom: "2001-01-01", Markup: "0.1", VATCode: "Standard"}, ""),
+		Entry("plan not a guid", ProviderCostRule{PlanGUID: "postgres", ValidFrom: "2001-01-01", VATCode: "Standard"}, "plan_guid must be a guid"),
+		Entry("missing valid_from", ProviderCostRule{PlanGUID: planGUID, VATCode: "Standard"}, "valid_from must be a date"),
+		Entry("bad markup", ProviderCostRule{PlanGUID: planGUID, ValidFrom: "2001-01-01", Markup: "10%", VATCode: "Standard"}, "markup"),
+		Entry("negative markup", ProviderCostRule{PlanGUID: planGUID, ValidFrom: "2001-01-01", Markup: "-0.1", VATCode: "Standard"}, "must not be negative"),
+		Entry("missing vat_code", ProviderCostRule{PlanGUID: planGUID, ValidFrom: "2001-01-01"}, "must have a vat_code"),
+	)
+})
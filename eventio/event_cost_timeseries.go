@@ -0,0 +1,73 @@
+package eventio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var (
+	CostTimeSeriesSteps    = []string{"day", "week", "month"}
+	CostTimeSeriesGroupBys = []string{"", "org", "space", "plan", "resource_type"}
+)
+
+type CostTimeSeriesReader interface {
+	GetCostTimeSeriesRows(ctx context.Context, filter CostTimeSeriesFilter) (CostTimeSeriesRows, error)
+}
+
+// CostTimeSeriesFilter selects a dense series of costs for the given range
+// bucketed by Step (day, week or month) and split into one series per value
+// of GroupBy. An empty GroupBy returns a single series totalling everything.
+type CostTimeSeriesFilter struct {
+	EventFilter
+	Step    string
+	GroupBy string
+}
+
+func (filter *CostTimeSeriesFilter) Validate() error {
+	if err := filter.EventFilter.Validate(); err != nil {
+		return err
+	}
+	if !contains(CostTimeSeriesSteps, filter.Step) {
+		return fmt.Errorf("step must be one of %q - got %q", CostTimeSeriesSteps, filter.Step)
+	}
+	if strings.HasPrefix(filter.GroupBy, LabelGroupByPrefix) {
+		if err := ValidateLabelKey(strings.TrimPrefix(filter.GroupBy, LabelGroupByPrefix)); err != nil {
+			return fmt.Errorf("group_by: %s", err)
+		}
+	} else if !contains(CostTimeSeriesGroupBys, filter.GroupBy) {
+		return fmt.Errorf("group_by must be one of %q or %s<key> - got %q", CostTimeSeriesGroupBys[1:], LabelGroupByPrefix, filter.GroupBy)
+	}
+	return nil
+}
+
+//counterfeiter:generate . CostTimeSeriesRows
+type CostTimeSeriesRows interface {
+	Next() bool
+	Close() error
+	Err() error
+	Point() (*CostTimeSeriesPoint, error)
+}
+
+// CostTimeSeriesPoint is the cost and usage (in instance hours) of a single
+// group for a single step. Points are zero-filled so every group has a point
+// for every step in the range. Quantities totals the usage measured by
+// pricing components with a unit, by unit, and is empty if there was none.
+type CostTimeSeriesPoint struct {
+	PeriodStart   string            `json:"period_start"`
+	Group         string            `json:"group"`
+	GroupName     string            `json:"group_name"`
+	ExVAT         Money             `json:"ex_vat"`
+	IncVAT        Money             `json:"inc_vat"`
+	InstanceHours string            `json:"instance_hours"`
+	Quantities    map[string]string `json:"quantities,omitempty"`
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,27 @@
 package eventio
 
+import (
+	"context"
+	"encoding/json"
+)
+
 type TotalCostReader interface {
 	GetTotalCost() ([]TotalCost, error)
+	GetTotalCostContext(ctx context.Context) ([]TotalCost, error)
 }
 
 type TotalCost struct {
-	PlanGUID string  `json:"plan_guid"`
-	PlanName string  `json:"-"`
-	Kind     string  `json:"-"`
-	Cost     float32 `json:"cost"`
+	PlanGUID string `json:"plan_guid"`
+	PlanName string `json:"-"`
+	Kind     string `json:"-"`
+	Cost     Money  `json:"cost"`
+}
+
+// MarshalJSON writes Cost as a JSON number, as /totals always has
+func (t TotalCost) MarshalJSON() ([]byte, error) {
+	type totalCost TotalCost
+	return json.Marshal(struct {
+		totalCost
+		Cost json.Number `json:"cost"`
+	}{totalCost(t), json.Number(t.Cost)})
 }
@@ -0,0 +1,74 @@
+package eventio
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// DataQualityStoppedWithoutStart is an app or task that stopped without
+	// having started, so the time it ran for is not billed
+	DataQualityStoppedWithoutStart = "stopped_without_start"
+	// DataQualityDeletedWithoutCreate is a service instance that was deleted
+	// without having been created
+	DataQualityDeletedWithoutCreate = "deleted_without_create"
+	// DataQualityEmptyDuration is a start event followed by another event
+	// for the same resource at the same time, so it was dropped
+	DataQualityEmptyDuration = "empty_duration"
+	// DataQualityUnknownPlan is usage priced with a £0 plan generated because
+	// its pricing plan is not configured
+	DataQualityUnknownPlan = "unknown_plan"
+	// DataQualityUnmatchedProviderCost is an imported provider cost for a
+	// service instance that did not exist during its usage period, so it is
+	// not billed
+	DataQualityUnmatchedProviderCost = "unmatched_provider_cost"
+)
+
+// DataQualityCategories lists every category of data quality issue
+var DataQualityCategories = []string{
+	DataQualityStoppedWithoutStart,
+	DataQualityDeletedWithoutCreate,
+	DataQualityEmptyDuration,
+	DataQualityUnknownPlan,
+	DataQualityUnmatchedProviderCost,
+}
+
+type DataQualityReader interface {
+	GetDataQualityIssues(filter DataQualityFilter) ([]DataQualityIssue, error)
+	GetDataQualityIssuesContext(ctx context.Context, filter DataQualityFilter) ([]DataQualityIssue, error)
+}
+
+// DataQualityFilter selects the issues found in the given orgs, or in every
+// org if OrgGUIDs is empty, optionally of a single category
+type DataQualityFilter struct {
+	OrgGUIDs []string
+	Category string
+}
+
+func (filter *DataQualityFilter) Validate() error {
+	if filter.Category == "" {
+		return nil
+	}
+	for _, category := range DataQualityCategories {
+		if filter.Category == category {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown data quality category '%s'", filter.Category)
+}
+
+// DataQualityIssue is a usage event that was dropped, mis-paired or priced
+// at nothing when events were last refreshed. EventGUID is the raw usage
+// event, for unknown plans the billable event and for unmatched provider
+// costs the imported line item.
+type DataQualityIssue struct {
+	Category     string `json:"category"`
+	EventGUID    string `json:"event_guid"`
+	ObservedAt   string `json:"observed_at"`
+	OrgGUID      string `json:"org_guid"`
+	SpaceGUID    string `json:"space_guid"`
+	ResourceGUID string `json:"resource_guid"`
+	ResourceType string `json:"resource_type"`
+	PlanGUID     string `json:"plan_guid"`
+	Detail       string `json:"detail"`
+}
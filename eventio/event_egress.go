@@ -0,0 +1,74 @@
+package eventio
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var egressSourcePattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// EgressUsage is the bytes an app sent in responses to Requests requests in
+// the hour starting at Hour
+type EgressUsage struct {
+	AppGUID  string `json:"app_guid"`
+	Hour     string `json:"hour"`
+	Bytes    int64  `json:"bytes"`
+	Requests int64  `json:"requests"`
+}
+
+func (u *EgressUsage) Validate() error {
+	if !guidPattern.MatchString(u.AppGUID) {
+		return fmt.Errorf("egress usage app_guid must be a guid - got %s", u.AppGUID)
+	}
+	hour, err := time.Parse(time.RFC3339, u.Hour)
+	if err != nil {
+		return fmt.Errorf("egress usage hour must be an RFC3339 time - got %s", u.Hour)
+	}
+	if !hour.Equal(hour.Truncate(time.Hour)) {
+		return fmt.Errorf("egress usage hour must be the start of an hour - got %s", u.Hour)
+	}
+	if u.Bytes < 0 {
+		return fmt.Errorf("egress usage bytes must not be negative")
+	}
+	if u.Requests < 0 {
+		return fmt.Errorf("egress usage requests must not be negative")
+	}
+	return nil
+}
+
+// EgressBatch is the usage read from the lines of an access log between the
+// byte offsets From and To. Source identifies the log however it is named or
+// compressed, so a log that has been appended to or rotated is only read
+// from where it was last ingested.
+type EgressBatch struct {
+	Source string        `json:"source"`
+	From   int64         `json:"from"`
+	To     int64         `json:"to"`
+	Usage  []EgressUsage `json:"usage"`
+}
+
+func (b *EgressBatch) Validate() error {
+	if !egressSourcePattern.MatchString(b.Source) {
+		return fmt.Errorf("egress batch source must be 32 lowercase hex digits - got %s", b.Source)
+	}
+	if b.From < 0 || b.To < b.From {
+		return fmt.Errorf("egress batch must be from offset %d to a later offset - got %d", b.From, b.To)
+	}
+	for _, u := range b.Usage {
+		if err := u.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EgressUsageWriter stores the usage read from router access logs, one batch
+// of lines at a time. A batch is only stored if it starts where the last
+// batch of its log ended, so ingesting a log again, or logs that overlap, eg
+// a log that has since been appended to or rotated, does not count their
+// bytes twice.
+type EgressUsageWriter interface {
+	GetEgressOffset(source string) (int64, error)
+	StoreEgressBatch(batch EgressBatch) error
+}
@@ -0,0 +1,43 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EgressBatch", func() {
+	DescribeTable("Validate",
+		func(update func(*EgressBatch), expectedErr string) {
+			batch := EgressBatch{
+				Source: "0123456789abcdef0123456789abcdef",
+				From:   0,
+				To:     2048,
+				Usage: []EgressUsage{{
+					AppGUID:  "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+					Hour:     "2001-01-01T10:00:00Z",
+					Bytes:    1024,
+					Requests: 2,
+				}},
+			}
+			update(&batch)
+			if expectedErr == "" {
+				Expect(batch.Validate()).To(Succeed())
+			} else {
+				Expect(batch.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("valid", func(b *EgressBatch) {}, ""),
+		Entry("no usage", func(b *EgressBatch) { b.Usage = nil }, ""),
+		Entry("no bytes", func(b *EgressBatch) { b.Usage[0].Bytes = 0 }, ""),
+		Entry("missing source", func(b *EgressBatch) { b.Source = "" }, "source must be 32 lowercase hex digits"),
+		Entry("source not hex", func(b *EgressBatch) { b.Source = "0123456789ABCDEF0123456789ABCDEF" }, "source must be 32 lowercase hex digits"),
+		Entry("negative offset", func(b *EgressBatch) { b.From = -1 }, "must be from offset"),
+		Entry("ends before it starts", func(b *EgressBatch) { b.From = 4096 }, "must be from offset"),
+		Entry("app not a guid", func(b *EgressBatch) { b.Usage[0].AppGUID = "app" }, "app_guid must be a guid"),
+		Entry("bad hour", func(b *EgressBatch) { b.Usage[0].Hour = "2001-01-01" }, "hour must be an RFC3339 time"),
+		Entry("hour not on the hour", func(b *EgressBatch) { b.Usage[0].Hour = "2001-01-01T10:30:00Z" }, "must be the start of an hour"),
+		Entry("negative bytes", func(b *EgressBatch) { b.Usage[0].Bytes = -1 }, "bytes must not be negative"),
+		Entry("negative requests", func(b *EgressBatch) { b.Usage[0].Requests = -1 }, "requests must not be negative"),
+	)
+})
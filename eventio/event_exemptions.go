@@ -0,0 +1,84 @@
+package eventio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ExemptComponentName is the name of the £0 price component given to
+	// usage that an exemption covers
+	ExemptComponentName = "exempt"
+	// ExemptionSourceConfig is an exemption set in the store's config
+	ExemptionSourceConfig = "config"
+	// ExemptionSourceAPI is an exemption added with AddExemption
+	ExemptionSourceAPI = "api"
+)
+
+// ErrExemptionInConfig is returned when removing an exemption that is set in
+// the store's config, as it would be restored the next time the store starts
+var ErrExemptionInConfig = errors.New("exemption is set in the config and can only be removed there")
+
+type ExemptionReader interface {
+	// GetExemptions returns every exemption, with how much usage it covered
+	// when events were last refreshed
+	GetExemptions() ([]Exemption, error)
+	GetExemptionsContext(ctx context.Context) ([]Exemption, error)
+}
+
+type ExemptionWriter interface {
+	// AddExemption stores a new exemption. It applies from the next Refresh.
+	AddExemption(exemption Exemption) (*Exemption, error)
+	// RemoveExemption deletes an exemption added with AddExemption. It
+	// returns false if there is no such exemption, and ErrExemptionInConfig
+	// if it is set in the config.
+	RemoveExemption(guid string) (bool, error)
+}
+
+// Exemption stops usage in an org, or in one space of an org if SpaceGUID is
+// set, from being charged for between ValidFrom and ValidTo. Exempt usage is
+// still billed, with a single £0 ExemptComponentName component instead of
+// the components of its pricing plan. An empty ValidTo never ends.
+//
+// GUID, Source, CreatedAt, ExemptEvents and ExemptHours are set by the
+// store.
+type Exemption struct {
+	GUID         string `json:"guid"`
+	OrgGUID      string `json:"org_guid"`
+	SpaceGUID    string `json:"space_guid"`
+	ValidFrom    string `json:"valid_from"`
+	ValidTo      string `json:"valid_to"`
+	Reason       string `json:"reason"`
+	Source       string `json:"source"`
+	CreatedAt    string `json:"created_at"`
+	ExemptEvents int64  `json:"exempt_events"`
+	ExemptHours  string `json:"exempt_hours"`
+}
+
+func (e *Exemption) Validate() error {
+	if !guidPattern.MatchString(e.OrgGUID) {
+		return fmt.Errorf("exemption org guid must be a guid - got %s", e.OrgGUID)
+	}
+	if e.SpaceGUID != "" && !guidPattern.MatchString(e.SpaceGUID) {
+		return fmt.Errorf("exemption space guid must be a guid if given - got %s", e.SpaceGUID)
+	}
+	validFrom, err := time.Parse("2006-01-02", e.ValidFrom)
+	if err != nil {
+		return fmt.Errorf("exemption valid_from must be a date - expected format 2006-01-02 - got %s", e.ValidFrom)
+	}
+	if e.ValidTo != "" {
+		validTo, err := time.Parse("2006-01-02", e.ValidTo)
+		if err != nil {
+			return fmt.Errorf("exemption valid_to must be a date if given - expected format 2006-01-02 - got %s", e.ValidTo)
+		}
+		if !validTo.After(validFrom) {
+			return fmt.Errorf("exemption valid_to must be after valid_from - got %s to %s", e.ValidFrom, e.ValidTo)
+		}
+	}
+	if e.Reason == "" {
+		return fmt.Errorf("a reason is required for an exemption")
+	}
+	return nil
+}
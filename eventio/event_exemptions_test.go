@@ -0,0 +1,30 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exemption", func() {
+	const orgGUID = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+	const spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+
+	DescribeTable("Validate",
+		func(exemption Exemption, expectedErr string) {
+			if expectedErr == "" {
+				Expect(exemption.Validate()).To(Succeed())
+			} else {
+				Expect(exemption.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("org", Exemption{OrgGUID: orgGUID, ValidFrom: "2018-01-01", Reason: "smoke tests"}, ""),
+		Entry("space with end", Exemption{OrgGUID: orgGUID, SpaceGUID: spaceGUID, ValidFrom: "2018-01-01", ValidTo: "2018-02-01", Reason: "smoke tests"}, ""),
+		Entry("org not a guid", Exemption{OrgGUID: "admin", ValidFrom: "2018-01-01", Reason: "smoke tests"}, "exemption org guid must be a guid"),
+		Entry("space not a guid", Exemption{OrgGUID: orgGUID, SpaceGUID: "SMOKE-1", ValidFrom: "2018-01-01", Reason: "smoke tests"}, "exemption space guid must be a guid"),
+		Entry("missing valid_from", Exemption{OrgGUID: orgGUID, Reason: "smoke tests"}, "exemption valid_from must be a date"),
+		Entry("bad valid_to", Exemption{OrgGUID: orgGUID, ValidFrom: "2018-01-01", ValidTo: "soon", Reason: "smoke tests"}, "exemption valid_to must be a date"),
+		Entry("valid_to not after valid_from", Exemption{OrgGUID: orgGUID, ValidFrom: "2018-01-01", ValidTo: "2018-01-01", Reason: "smoke tests"}, "exemption valid_to must be after valid_from"),
+		Entry("missing reason", Exemption{OrgGUID: orgGUID, ValidFrom: "2018-01-01"}, "a reason is required"),
+	)
+})
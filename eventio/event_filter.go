@@ -6,10 +6,20 @@ import (
 	"time"
 )
 
+const (
+	// AggregateDefault folds the events of each resource type configured for
+	// aggregation into one event per space
+	AggregateDefault = ""
+	// AggregateNone returns every event individually
+	AggregateNone = "none"
+)
+
 type EventFilter struct {
-	RangeStart string
-	RangeStop  string
-	OrgGUIDs   []string
+	RangeStart    string
+	RangeStop     string
+	OrgGUIDs      []string
+	Aggregate     string
+	LabelSelector string // see ParseLabelSelector
 }
 
 func (filter *EventFilter) SplitByMonth() ([]EventFilter, error) {
@@ -45,9 +55,11 @@ func (filter *EventFilter) recursiveSplitByMonth(t1, t2 time.Time) []EventFilter
 		return append(
 			[]EventFilter{
 				{
-					RangeStart: t1.Format(dateFormat),
-					RangeStop:  minDate(t2, next).Format(dateFormat),
-					OrgGUIDs:   filter.OrgGUIDs,
+					RangeStart:    t1.Format(dateFormat),
+					RangeStop:     minDate(t2, next).Format(dateFormat),
+					OrgGUIDs:      filter.OrgGUIDs,
+					Aggregate:     filter.Aggregate,
+					LabelSelector: filter.LabelSelector,
 				},
 			},
 			filter.recursiveSplitByMonth(next, t2)...,
@@ -78,9 +90,11 @@ func (filter *EventFilter) TruncateMonth() (EventFilter, error) {
 	}
 
 	return EventFilter{
-		RangeStart: truncateMonth(start).Format("2006-01-02"),
-		RangeStop:  truncateMonth(stop).Format("2006-01-02"),
-		OrgGUIDs:   filter.OrgGUIDs,
+		RangeStart:    truncateMonth(start).Format("2006-01-02"),
+		RangeStop:     truncateMonth(stop).Format("2006-01-02"),
+		OrgGUIDs:      filter.OrgGUIDs,
+		Aggregate:     filter.Aggregate,
+		LabelSelector: filter.LabelSelector,
 	}, nil
 }
 
@@ -91,6 +105,12 @@ func (filter *EventFilter) Validate() error {
 	if err := validateDateString("end", filter.RangeStop); err != nil {
 		return err
 	}
+	if filter.Aggregate != AggregateDefault && filter.Aggregate != AggregateNone {
+		return fmt.Errorf(`aggregate must be "%s" if given - got %s`, AggregateNone, filter.Aggregate)
+	}
+	if _, err := ParseLabelSelector(filter.LabelSelector); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -113,6 +113,14 @@ var _ = Describe("EventFilter", func() {
 				{RangeStart: "2018-01-01", RangeStop: "2018-01-05"},
 			},
 		),
+		Entry(
+			"Should maintain aggregation",
+			EventFilter{RangeStart: "2018-01-15", RangeStop: "2018-02-15", Aggregate: AggregateNone},
+			[]EventFilter{
+				{RangeStart: "2018-01-15", RangeStop: "2018-02-01", Aggregate: AggregateNone},
+				{RangeStart: "2018-02-01", RangeStop: "2018-02-15", Aggregate: AggregateNone},
+			},
+		),
 	)
 
 	DescribeTable(
@@ -149,4 +157,34 @@ var _ = Describe("EventFilter", func() {
 			EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01", OrgGUIDs: []string{"org-guid"}},
 		),
 	)
+
+	DescribeTable(
+		"Validate should only accept known aggregations",
+		func(aggregate string, valid bool) {
+			filter := EventFilter{RangeStart: "2018-01-01", RangeStop: "2018-02-01", Aggregate: aggregate}
+			if valid {
+				Expect(filter.Validate()).To(Succeed())
+			} else {
+				Expect(filter.Validate()).To(MatchError(ContainSubstring(`aggregate must be "none"`)))
+			}
+		},
+		Entry("default", AggregateDefault, true),
+		Entry("none", AggregateNone, true),
+		Entry("unknown", "monthly", false),
+	)
+})
+
+var _ = Describe("ResourceHistoryFilter", func() {
+	DescribeTable("Validate",
+		func(filter ResourceHistoryFilter, expectedErr string) {
+			if expectedErr == "" {
+				Expect(filter.Validate()).To(Succeed())
+			} else {
+				Expect(filter.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("valid", ResourceHistoryFilter{ResourceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0", RangeStart: "2018-01-01", RangeStop: "2018-02-01"}, ""),
+		Entry("not a guid", ResourceHistoryFilter{ResourceGUID: "app1", RangeStart: "2018-01-01", RangeStop: "2018-02-01"}, "resource guid must be a guid"),
+		Entry("missing range", ResourceHistoryFilter{ResourceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0"}, "a valid range start filter value is required"),
+	)
 })
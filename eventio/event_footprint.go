@@ -0,0 +1,137 @@
+package eventio
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type FootprintReader interface {
+	GetFootprintEventRows(ctx context.Context, filter EventFilter) (FootprintEventRows, error)
+	GetFootprintPlans(ctx context.Context, filter TimeRangeFilter) ([]FootprintPlan, error)
+}
+
+// FootprintPlan estimates the environmental footprint of the resources with
+// PlanGUID from ValidFrom until the plan's next version. EnergyFormula is
+// the energy used in kWh and CarbonFormula is the carbon emitted other than
+// by using that energy, such as embodied carbon, in gCO2e. Both are written
+// like the formulas of a PricingPlanComponent. An empty CarbonFormula is 0.
+type FootprintPlan struct {
+	Name          string `json:"name"`
+	PlanGUID      string `json:"plan_guid"`
+	ValidFrom     string `json:"valid_from"`
+	EnergyFormula string `json:"energy_formula"`
+	CarbonFormula string `json:"carbon_formula,omitempty"`
+}
+
+func (p *FootprintPlan) Validate() error {
+	if !guidPattern.MatchString(p.PlanGUID) {
+		return fmt.Errorf("footprint plan plan_guid must be a guid - got %s", p.PlanGUID)
+	}
+	if p.Name == "" {
+		return fmt.Errorf("footprint plan %s must have a name", p.PlanGUID)
+	}
+	if _, err := time.Parse("2006-01-02", p.ValidFrom); err != nil {
+		return fmt.Errorf("footprint plan %s valid_from must be a date - expected format 2006-01-02 - got %s", p.PlanGUID, p.ValidFrom)
+	}
+	if p.EnergyFormula == "" {
+		return fmt.Errorf("footprint plan %s must have an energy_formula", p.PlanGUID)
+	}
+	return nil
+}
+
+// GridIntensity is the carbon emitted in generating each kWh of the energy
+// used from ValidFrom until the next GridIntensity
+type GridIntensity struct {
+	ValidFrom   string `json:"valid_from"`
+	GCO2ePerKWh string `json:"gco2e_per_kwh"`
+}
+
+func (g *GridIntensity) Validate() error {
+	if _, err := time.Parse("2006-01-02", g.ValidFrom); err != nil {
+		if _, err := time.Parse(time.RFC3339, g.ValidFrom); err != nil {
+			return fmt.Errorf("grid intensity valid_from must be a date or RFC3339 time - got %s", g.ValidFrom)
+		}
+	}
+	if _, err := ParseMoney(g.GCO2ePerKWh); err != nil {
+		return fmt.Errorf("grid intensity from %s gco2e_per_kwh: %s", g.ValidFrom, err)
+	}
+	if strings.HasPrefix(g.GCO2ePerKWh, "-") {
+		return fmt.Errorf("grid intensity from %s gco2e_per_kwh must not be negative - got %s", g.ValidFrom, g.GCO2ePerKWh)
+	}
+	return nil
+}
+
+// ReadGridIntensities reads grid intensities from a CSV file with a
+// valid_from,gco2e_per_kwh header, such as one exported from a grid
+// operator's carbon intensity service
+func ReadGridIntensities(r io.Reader) ([]GridIntensity, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("grid intensity file is empty")
+	}
+	header := records[0]
+	if len(header) != 2 || strings.TrimSpace(header[0]) != "valid_from" || strings.TrimSpace(header[1]) != "gco2e_per_kwh" {
+		return nil, fmt.Errorf("grid intensity file header must be valid_from,gco2e_per_kwh - got %s", strings.Join(header, ","))
+	}
+	intensities := []GridIntensity{}
+	for i, record := range records[1:] {
+		intensity := GridIntensity{
+			ValidFrom:   strings.TrimSpace(record[0]),
+			GCO2ePerKWh: strings.TrimSpace(record[1]),
+		}
+		if err := intensity.Validate(); err != nil {
+			return nil, fmt.Errorf("grid intensity file line %d: %s", i+2, err)
+		}
+		intensities = append(intensities, intensity)
+	}
+	return intensities, nil
+}
+
+// FootprintEvent is the estimated energy use and carbon emissions of a
+// billable event. Details has the estimate for each period priced by a
+// different footprint plan version or grid intensity.
+type FootprintEvent struct {
+	EventGUID    string               `json:"event_guid"`
+	EventStart   string               `json:"event_start"`
+	EventStop    string               `json:"event_stop"`
+	ResourceGUID string               `json:"resource_guid"`
+	ResourceName string               `json:"resource_name"`
+	ResourceType string               `json:"resource_type"`
+	OrgGUID      string               `json:"org_guid"`
+	OrgName      string               `json:"org_name"`
+	SpaceGUID    string               `json:"space_guid"`
+	SpaceName    string               `json:"space_name"`
+	PlanGUID     string               `json:"plan_guid"`
+	EnergyKWh    string               `json:"energy_kwh"`
+	CarbonGCO2e  string               `json:"carbon_gco2e"`
+	Details      []FootprintComponent `json:"details"`
+}
+
+// FootprintComponent is the estimate for one period of a FootprintEvent.
+// CarbonGCO2e is EnergyKWh multiplied by GridIntensity plus the plan's
+// CarbonFormula. GridIntensity is empty, and counted as 0, before the first
+// grid intensity.
+type FootprintComponent struct {
+	Start         string `json:"start"`
+	Stop          string `json:"stop"`
+	PlanName      string `json:"plan_name"`
+	GridIntensity string `json:"grid_intensity"`
+	EnergyKWh     string `json:"energy_kwh"`
+	CarbonGCO2e   string `json:"carbon_gco2e"`
+}
+
+//counterfeiter:generate . FootprintEventRows
+type FootprintEventRows interface {
+	Next() bool
+	Close() error
+	Err() error
+	EventJSON() ([]byte, error)
+	Event() (*FootprintEvent, error)
+}
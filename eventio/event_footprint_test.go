@@ -0,0 +1,37 @@
+package eventio_test
+
+import (
+	"strings"
+
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReadGridIntensities", func() {
+
+	It("should read dates and times with their intensities", func() {
+		intensities, err := ReadGridIntensities(strings.NewReader("" +
+			"valid_from,gco2e_per_kwh\n" +
+			"2001-01-01,210.5\n" +
+			"2001-01-01T12:30:00Z, 180\n",
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(intensities).To(Equal([]GridIntensity{
+			{ValidFrom: "2001-01-01", GCO2ePerKWh: "210.5"},
+			{ValidFrom: "2001-01-01T12:30:00Z", GCO2ePerKWh: "180"},
+		}))
+	})
+
+	DescribeTable("should reject invalid files",
+		func(file string, expectedErr string) {
+			_, err := ReadGridIntensities(strings.NewReader(file))
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("empty", "", "grid intensity file is empty"),
+		Entry("wrong header", "from,intensity\n2001-01-01,100\n", "header must be valid_from,gco2e_per_kwh"),
+		Entry("bad valid_from", "valid_from,gco2e_per_kwh\nsoon,100\n", "line 2: grid intensity valid_from must be a date"),
+		Entry("negative intensity", "valid_from,gco2e_per_kwh\n2001-01-01,-1\n", "must not be negative"),
+		Entry("missing intensity", "valid_from,gco2e_per_kwh\n2001-01-01\n", "wrong number of fields"),
+	)
+})
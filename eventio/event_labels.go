@@ -0,0 +1,84 @@
+package eventio
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// LabelEquals matches events with the label set to the value
+	LabelEquals = "="
+	// LabelNotEquals matches events without the label set to the value,
+	// including those without the label
+	LabelNotEquals = "!="
+	// LabelExists matches events with the label set to any value
+	LabelExists = "exists"
+	// LabelNotExists matches events without the label
+	LabelNotExists = "!exists"
+
+	// LabelGroupByPrefix is the prefix of a group_by that groups costs by
+	// the value of a label, such as label:cost-centre
+	LabelGroupByPrefix = "label:"
+)
+
+var (
+	// labelKeyPattern is the format of cf metadata label keys: a name,
+	// optionally after a dns subdomain prefix and a slash
+	labelKeyPattern = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	// labelValuePattern is the format of cf metadata label values
+	labelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+)
+
+// LabelRequirement is one of the comma separated requirements of a label
+// selector, all of which an event's labels must meet
+type LabelRequirement struct {
+	Key      string
+	Operator string
+	Value    string
+}
+
+// ParseLabelSelector parses a label selector in the subset of the cf api
+// syntax that matches single values: key=value, key==value, key!=value,
+// key and !key, separated by commas. An empty selector has no requirements.
+func ParseLabelSelector(selector string) ([]LabelRequirement, error) {
+	requirements := []LabelRequirement{}
+	if strings.TrimSpace(selector) == "" {
+		return requirements, nil
+	}
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+		var r LabelRequirement
+		switch {
+		case strings.HasPrefix(term, "!"):
+			r = LabelRequirement{Key: strings.TrimSpace(term[1:]), Operator: LabelNotExists}
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			r = LabelRequirement{Key: strings.TrimSpace(parts[0]), Operator: LabelNotEquals, Value: strings.TrimSpace(parts[1])}
+		case strings.Contains(term, "=="):
+			parts := strings.SplitN(term, "==", 2)
+			r = LabelRequirement{Key: strings.TrimSpace(parts[0]), Operator: LabelEquals, Value: strings.TrimSpace(parts[1])}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			r = LabelRequirement{Key: strings.TrimSpace(parts[0]), Operator: LabelEquals, Value: strings.TrimSpace(parts[1])}
+		default:
+			r = LabelRequirement{Key: term, Operator: LabelExists}
+		}
+		if err := ValidateLabelKey(r.Key); err != nil {
+			return nil, fmt.Errorf("label_selector: %s", err)
+		}
+		if !labelValuePattern.MatchString(r.Value) {
+			return nil, fmt.Errorf("label_selector: invalid label value %q in %q", r.Value, term)
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements, nil
+}
+
+// ValidateLabelKey checks key is a valid cf metadata label key
+func ValidateLabelKey(key string) error {
+	if !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package eventio_test
+
+import (
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseLabelSelector", func() {
+	DescribeTable("valid selectors",
+		func(selector string, expected []LabelRequirement) {
+			requirements, err := ParseLabelSelector(selector)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requirements).To(Equal(expected))
+		},
+		Entry("empty", "", []LabelRequirement{}),
+		Entry("equals", "team=billing", []LabelRequirement{
+			{Key: "team", Operator: LabelEquals, Value: "billing"},
+		}),
+		Entry("double equals", "team==billing", []LabelRequirement{
+			{Key: "team", Operator: LabelEquals, Value: "billing"},
+		}),
+		Entry("not equals", "team!=billing", []LabelRequirement{
+			{Key: "team", Operator: LabelNotEquals, Value: "billing"},
+		}),
+		Entry("exists and not exists", "team, !example.com/temporary", []LabelRequirement{
+			{Key: "team", Operator: LabelExists},
+			{Key: "example.com/temporary", Operator: LabelNotExists},
+		}),
+		Entry("empty value", "team=", []LabelRequirement{
+			{Key: "team", Operator: LabelEquals, Value: ""},
+		}),
+	)
+
+	DescribeTable("invalid selectors",
+		func(selector string) {
+			_, err := ParseLabelSelector(selector)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty key", "=billing"),
+		Entry("empty term", "team=billing,"),
+		Entry("space in value", "team=not billing"),
+		Entry("bad prefix", "Example.com/team=billing"),
+		Entry("set based", "team in (billing)"),
+	)
+})
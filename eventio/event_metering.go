@@ -0,0 +1,98 @@
+package eventio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// MeteringKind is the RawEvent Kind of metering records
+const MeteringKind = "metering"
+
+// ErrUnknownServiceInstance is returned when storing a metering record for
+// a service instance there are no service usage events for, or that is not
+// an instance of one of the services of the broker that sent the record
+var ErrUnknownServiceInstance = errors.New("unknown service instance")
+
+var metricPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// MeteringRecord is a quantity of a metric, such as storage used or
+// requests served, that a service broker measured for a service instance
+// from Start until Stop. Records are identified by the broker and its
+// IdempotencyKey, so a broker can safely send a record more than once.
+// ServiceGUIDs are the services the broker offers, which the record's
+// service instance must be an instance of.
+type MeteringRecord struct {
+	Broker              string      `json:"broker"`
+	ServiceGUIDs        []string    `json:"service_guids"`
+	IdempotencyKey      string      `json:"idempotency_key"`
+	ServiceInstanceGUID string      `json:"service_instance_guid"`
+	Metric              string      `json:"metric"`
+	Quantity            json.Number `json:"quantity"`
+	Start               string      `json:"start"`
+	Stop                string      `json:"stop"`
+}
+
+func (r *MeteringRecord) Validate() error {
+	if r.Broker == "" {
+		return fmt.Errorf("metering record must have a broker")
+	}
+	if len(r.ServiceGUIDs) == 0 {
+		return fmt.Errorf("metering record must have the service_guids of its broker")
+	}
+	for _, serviceGUID := range r.ServiceGUIDs {
+		if !guidPattern.MatchString(serviceGUID) {
+			return fmt.Errorf("metering record service_guids must be guids - got %s", serviceGUID)
+		}
+	}
+	if strings.TrimSpace(r.IdempotencyKey) == "" {
+		return fmt.Errorf("metering record must have an idempotency_key")
+	}
+	if !guidPattern.MatchString(r.ServiceInstanceGUID) {
+		return fmt.Errorf("metering record service_instance_guid must be a guid - got %s", r.ServiceInstanceGUID)
+	}
+	if !metricPattern.MatchString(r.Metric) {
+		return fmt.Errorf("metering record metric must be lowercase letters, digits and underscores - got %s", r.Metric)
+	}
+	if _, err := ParseMoney(string(r.Quantity)); err != nil || strings.HasPrefix(string(r.Quantity), "-") {
+		return fmt.Errorf("metering record quantity must be a decimal number that is not negative - got %s", r.Quantity)
+	}
+	start, err := time.Parse(time.RFC3339, r.Start)
+	if err != nil {
+		return fmt.Errorf("metering record start must be an RFC3339 time - got %s", r.Start)
+	}
+	stop, err := time.Parse(time.RFC3339, r.Stop)
+	if err != nil {
+		return fmt.Errorf("metering record stop must be an RFC3339 time - got %s", r.Stop)
+	}
+	if !stop.After(start) {
+		return fmt.Errorf("metering record stop must be after start - got %s to %s", r.Start, r.Stop)
+	}
+	return nil
+}
+
+// RawEvent returns the record as a RawEvent of MeteringKind. Its GUID is
+// derived from the broker and idempotency key, so storing a record again
+// does nothing.
+func (r *MeteringRecord) RawEvent() (RawEvent, error) {
+	if err := r.Validate(); err != nil {
+		return RawEvent{}, err
+	}
+	createdAt, _ := time.Parse(time.RFC3339, r.Start)
+	b, err := json.Marshal(r)
+	if err != nil {
+		return RawEvent{}, err
+	}
+	identity := strings.Join([]string{MeteringKind, r.Broker, r.IdempotencyKey}, "/")
+	return RawEvent{
+		GUID:       uuid.NewV5(uuid.NamespaceURL, identity).String(),
+		Kind:       MeteringKind,
+		RawMessage: b,
+		CreatedAt:  createdAt,
+	}, nil
+}
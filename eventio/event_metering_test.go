@@ -0,0 +1,85 @@
+package eventio_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MeteringRecord", func() {
+	var record MeteringRecord
+
+	BeforeEach(func() {
+		record = MeteringRecord{
+			Broker:              "s3-broker",
+			ServiceGUIDs:        []string{"efadb775-58c4-4e17-8087-6d0f4febc489"},
+			IdempotencyKey:      "bucket-1/2001-01-01T00",
+			ServiceInstanceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+			Metric:              "storage_gb_hours",
+			Quantity:            "12.5",
+			Start:               "2001-01-01T00:00:00Z",
+			Stop:                "2001-01-01T01:00:00Z",
+		}
+	})
+
+	DescribeTable("Validate",
+		func(update func(*MeteringRecord), expectedErr string) {
+			update(&record)
+			if expectedErr == "" {
+				Expect(record.Validate()).To(Succeed())
+			} else {
+				Expect(record.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("valid", func(r *MeteringRecord) {}, ""),
+		Entry("zero quantity", func(r *MeteringRecord) { r.Quantity = "0" }, ""),
+		Entry("missing broker", func(r *MeteringRecord) { r.Broker = "" }, "must have a broker"),
+		Entry("missing service guids", func(r *MeteringRecord) { r.ServiceGUIDs = nil }, "must have the service_guids of its broker"),
+		Entry("service guid not a guid", func(r *MeteringRecord) { r.ServiceGUIDs = []string{"s3"} }, "service_guids must be guids"),
+		Entry("missing idempotency key", func(r *MeteringRecord) { r.IdempotencyKey = " " }, "must have an idempotency_key"),
+		Entry("service instance not a guid", func(r *MeteringRecord) { r.ServiceInstanceGUID = "bucket" }, "service_instance_guid must be a guid"),
+		Entry("metric with capitals", func(r *MeteringRecord) { r.Metric = "StorageGB" }, "metric must be lowercase"),
+		Entry("metric with a dollar", func(r *MeteringRecord) { r.Metric = "$bytes" }, "metric must be lowercase"),
+		Entry("negative quantity", func(r *MeteringRecord) { r.Quantity = "-1" }, "quantity must be a decimal number"),
+		Entry("exponent quantity", func(r *MeteringRecord) { r.Quantity = "1e3" }, "quantity must be a decimal number"),
+		Entry("bad start", func(r *MeteringRecord) { r.Start = "2001-01-01" }, "start must be an RFC3339 time"),
+		Entry("stop before start", func(r *MeteringRecord) { r.Stop = r.Start }, "stop must be after start"),
+	)
+
+	It("should read quantities sent as numbers or strings", func() {
+		var fromNumber, fromString MeteringRecord
+		Expect(json.Unmarshal([]byte(`{"quantity": 12.5}`), &fromNumber)).To(Succeed())
+		Expect(json.Unmarshal([]byte(`{"quantity": "12.5"}`), &fromString)).To(Succeed())
+		Expect(fromNumber.Quantity).To(Equal(json.Number("12.5")))
+		Expect(fromString.Quantity).To(Equal(json.Number("12.5")))
+	})
+
+	It("should convert to a raw event starting with its interval", func() {
+		event, err := record.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(event.Kind).To(Equal(MeteringKind))
+		Expect(event.CreatedAt).To(Equal(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(event.Validate()).To(Succeed())
+
+		var stored MeteringRecord
+		Expect(json.Unmarshal(event.RawMessage, &stored)).To(Succeed())
+		Expect(stored).To(Equal(record))
+	})
+
+	It("should give the same guid to records with the same broker and idempotency key", func() {
+		first, err := record.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		record.Quantity = "13"
+		second, err := record.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second.GUID).To(Equal(first.GUID))
+
+		record.Broker = "other-broker"
+		third, err := record.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(third.GUID).ToNot(Equal(first.GUID))
+	})
+})
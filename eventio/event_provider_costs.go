@@ -0,0 +1,97 @@
+package eventio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// ProviderCostKind is the RawEvent Kind of provider costs
+const ProviderCostKind = "provider_cost"
+
+// ProviderCost is a line item of a cloud provider's cost and usage export
+// for the backing resources of a service instance. Cost is in CurrencyCode
+// for the usage from UsageStart until UsageStop. UsageAmount and Unit
+// measure the usage, if the provider reports it.
+type ProviderCost struct {
+	Provider            string `json:"provider"`
+	LineItemID          string `json:"line_item_id,omitempty"`
+	LineItemType        string `json:"line_item_type,omitempty"`
+	ServiceInstanceGUID string `json:"service_instance_guid"`
+	ResourceID          string `json:"resource_id,omitempty"`
+	ProductCode         string `json:"product_code,omitempty"`
+	UsageType           string `json:"usage_type,omitempty"`
+	Description         string `json:"description,omitempty"`
+	UsageStart          string `json:"usage_start"`
+	UsageStop           string `json:"usage_stop"`
+	UsageAmount         Money  `json:"usage_amount,omitempty"`
+	Unit                string `json:"unit,omitempty"`
+	Cost                Money  `json:"cost"`
+	CurrencyCode        string `json:"currency_code"`
+}
+
+func (c *ProviderCost) Validate() error {
+	if c.Provider == "" {
+		return fmt.Errorf("provider cost must have a provider")
+	}
+	if !guidPattern.MatchString(c.ServiceInstanceGUID) {
+		return fmt.Errorf("provider cost service_instance_guid must be a guid - got %s", c.ServiceInstanceGUID)
+	}
+	start, err := time.Parse(time.RFC3339, c.UsageStart)
+	if err != nil {
+		return fmt.Errorf("provider cost usage_start must be an RFC3339 time - got %s", c.UsageStart)
+	}
+	stop, err := time.Parse(time.RFC3339, c.UsageStop)
+	if err != nil {
+		return fmt.Errorf("provider cost usage_stop must be an RFC3339 time - got %s", c.UsageStop)
+	}
+	if !stop.After(start) {
+		return fmt.Errorf("provider cost usage_stop must be after usage_start - got %s to %s", c.UsageStart, c.UsageStop)
+	}
+	if _, err := ParseMoney(string(c.Cost)); err != nil {
+		return fmt.Errorf("provider cost cost: %s", err)
+	}
+	if c.UsageAmount != "" {
+		if _, err := ParseMoney(string(c.UsageAmount)); err != nil {
+			return fmt.Errorf("provider cost usage_amount: %s", err)
+		}
+	}
+	if c.CurrencyCode == "" {
+		return fmt.Errorf("provider cost must have a currency_code")
+	}
+	return nil
+}
+
+// RawEvent returns the cost as a RawEvent of ProviderCostKind. Its GUID is
+// derived from the line item and the usage period, so importing a newer
+// export of the same line item replaces it.
+func (c *ProviderCost) RawEvent() (RawEvent, error) {
+	if err := c.Validate(); err != nil {
+		return RawEvent{}, err
+	}
+	createdAt, _ := time.Parse(time.RFC3339, c.UsageStart)
+	b, err := json.Marshal(c)
+	if err != nil {
+		return RawEvent{}, err
+	}
+	identity := strings.Join([]string{
+		ProviderCostKind,
+		c.Provider,
+		c.LineItemID,
+		c.LineItemType,
+		c.ResourceID,
+		c.ProductCode,
+		c.UsageType,
+		c.UsageStart,
+		c.UsageStop,
+	}, "/")
+	return RawEvent{
+		GUID:       uuid.NewV5(uuid.NamespaceURL, identity).String(),
+		Kind:       ProviderCostKind,
+		RawMessage: b,
+		CreatedAt:  createdAt,
+	}, nil
+}
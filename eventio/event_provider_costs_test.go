@@ -0,0 +1,81 @@
+package eventio_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ProviderCost", func() {
+	var cost ProviderCost
+
+	BeforeEach(func() {
+		cost = ProviderCost{
+			Provider:            "aws",
+			LineItemID:          "li-1",
+			LineItemType:        "Usage",
+			ServiceInstanceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+			ResourceID:          "arn:aws:rds:eu-west-1:1:db:rdsbroker-1",
+			UsageStart:          "2001-01-01T00:00:00Z",
+			UsageStop:           "2001-01-01T01:00:00Z",
+			Cost:                "0.036",
+			CurrencyCode:        "USD",
+		}
+	})
+
+	DescribeTable("Validate",
+		func(update func(*ProviderCost), expectedErr string) {
+			update(&cost)
+			if expectedErr == "" {
+				Expect(cost.Validate()).To(Succeed())
+			} else {
+				Expect(cost.Validate()).To(MatchError(ContainSubstring(expectedErr)))
+			}
+		},
+		Entry("valid", func(c *ProviderCost) {}, ""),
+		Entry("credit", func(c *ProviderCost) { c.Cost = "-0.5" }, ""),
+		Entry("missing provider", func(c *ProviderCost) { c.Provider = "" }, "must have a provider"),
+		Entry("service instance not a guid", func(c *ProviderCost) { c.ServiceInstanceGUID = "db" }, "service_instance_guid must be a guid"),
+		Entry("bad usage_start", func(c *ProviderCost) { c.UsageStart = "2001-01-01" }, "usage_start must be an RFC3339 time"),
+		Entry("usage_stop before usage_start", func(c *ProviderCost) { c.UsageStop = c.UsageStart }, "usage_stop must be after usage_start"),
+		Entry("bad cost", func(c *ProviderCost) { c.Cost = "$1" }, "provider cost cost"),
+		Entry("bad usage_amount", func(c *ProviderCost) { c.UsageAmount = "lots" }, "usage_amount"),
+		Entry("missing currency", func(c *ProviderCost) { c.CurrencyCode = "" }, "must have a currency_code"),
+	)
+
+	It("should convert to a raw event starting with its usage", func() {
+		event, err := cost.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(event.Kind).To(Equal(ProviderCostKind))
+		Expect(event.CreatedAt).To(Equal(time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(event.Validate()).To(Succeed())
+
+		var stored ProviderCost
+		Expect(json.Unmarshal(event.RawMessage, &stored)).To(Succeed())
+		Expect(stored).To(Equal(cost))
+	})
+
+	It("should give the same guid to the same line item with a different cost", func() {
+		first, err := cost.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		cost.Cost = "0.04"
+		second, err := cost.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(second.GUID).To(Equal(first.GUID))
+
+		cost.UsageStart = "2001-01-01T01:00:00Z"
+		cost.UsageStop = "2001-01-01T02:00:00Z"
+		third, err := cost.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(third.GUID).ToNot(Equal(first.GUID))
+	})
+
+	It("should not convert an invalid cost", func() {
+		cost.Provider = ""
+		_, err := cost.RawEvent()
+		Expect(err).To(MatchError(ContainSubstring("must have a provider")))
+	})
+})
@@ -0,0 +1,97 @@
+package eventio
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+const (
+	// ResourceChangeStart is an interval that starts after the resource was
+	// stopped or before it had ever been started
+	ResourceChangeStart = "start"
+	// ResourceChangePlan is an interval that starts with a change of plan
+	ResourceChangePlan = "plan"
+	// ResourceChangeScale is an interval that starts with a change in the
+	// number of nodes, memory or storage
+	ResourceChangeScale = "scale"
+	// ResourceChangeUpdate is an interval that starts with an event that
+	// changed nothing that is billed, such as a restage
+	ResourceChangeUpdate = "update"
+)
+
+type ResourceHistoryReader interface {
+	// GetResourceHistory returns everything used to price a single resource
+	// between RangeStart and RangeStop, or nil if nothing is known about it
+	GetResourceHistory(filter ResourceHistoryFilter) (*ResourceHistory, error)
+	GetResourceHistoryContext(ctx context.Context, filter ResourceHistoryFilter) (*ResourceHistory, error)
+}
+
+var guidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type ResourceHistoryFilter struct {
+	ResourceGUID string
+	RangeStart   string
+	RangeStop    string
+}
+
+func (filter *ResourceHistoryFilter) Validate() error {
+	if !guidPattern.MatchString(filter.ResourceGUID) {
+		return fmt.Errorf("resource guid must be a guid - got %s", filter.ResourceGUID)
+	}
+	timeRange := TimeRangeFilter{
+		RangeStart: filter.RangeStart,
+		RangeStop:  filter.RangeStop,
+	}
+	return timeRange.Validate()
+}
+
+// ResourceHistory explains the charges for a resource. UsageEvents are the
+// raw usage events received from Cloud Foundry, Events are the intervals
+// derived from them and Components are the prices calculated for each
+// interval.
+type ResourceHistory struct {
+	ResourceGUID string                     `json:"resource_guid"`
+	OrgGUIDs     []string                   `json:"org_guids"`
+	UsageEvents  []RawEvent                 `json:"usage_events"`
+	Events       []ResourceHistoryEvent     `json:"events"`
+	Components   []ResourceHistoryComponent `json:"components"`
+}
+
+// ResourceHistoryEvent is one interval of a resource in a single state.
+// Change says what was different from the previous interval.
+type ResourceHistoryEvent struct {
+	UsageEvent
+	Change string `json:"change"`
+}
+
+// ResourceHistoryComponent is one priced component of an interval, with the
+// inputs, plan version and rates that were used to price it. Price is
+// Formula evaluated with the inputs and multiplied by CurrencyRate.
+type ResourceHistoryComponent struct {
+	EventGUID     string `json:"event_guid"`
+	Name          string `json:"name"`
+	Start         string `json:"start"`
+	Stop          string `json:"stop"`
+	PlanGUID      string `json:"plan_guid"`
+	PlanName      string `json:"plan_name"`
+	PlanValidFrom string `json:"plan_valid_from"`
+	Formula       string `json:"formula"`
+	MemoryInMB    string `json:"memory_in_mb"`
+	StorageInMB   string `json:"storage_in_mb"`
+	DiskInMB      string `json:"disk_in_mb"`
+	LogRateLimit  string `json:"log_rate_limit"`
+	NumberOfNodes int64  `json:"number_of_nodes"`
+	TimeInSeconds string `json:"time_in_seconds"`
+	CurrencyCode  string `json:"currency_code"`
+	CurrencyRate  string `json:"currency_rate"`
+	VATCode       string `json:"vat_code"`
+	VATRate       string `json:"vat_rate"`
+	ExVAT         Money  `json:"ex_vat"`
+	IncVAT        Money  `json:"inc_vat"`
+	// Unit, QuantityFormula and Quantity are the usage the component
+	// measures, if it has a unit
+	Unit            string `json:"unit,omitempty"`
+	QuantityFormula string `json:"quantity_formula,omitempty"`
+	Quantity        string `json:"quantity,omitempty"`
+}
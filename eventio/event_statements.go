@@ -0,0 +1,114 @@
+package eventio
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BillingAccount groups several orgs so they receive a single statement.
+// Orgs that do not belong to a billing account are billed individually.
+// The statement is in CurrencyCode, or GBP if it is empty, converted with
+// the currency rate valid at the start of the month.
+type BillingAccount struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	OrgGUIDs     []string `json:"org_guids"`
+	CurrencyCode string   `json:"currency_code,omitempty"`
+}
+
+type StatementReader interface {
+	GetStatements(filter StatementFilter) ([]Statement, error)
+	GetStatementsContext(ctx context.Context, filter StatementFilter) ([]Statement, error)
+	GetStatement(statementNumber int64) (*Statement, error)
+	GetStatementContext(ctx context.Context, statementNumber int64) (*Statement, error)
+}
+
+type StatementGenerator interface {
+	// GenerateStatements issues a statement for every org or billing account
+	// that has consolidated events in the given month and does not already
+	// have an outstanding statement for it
+	GenerateStatements(month string) ([]Statement, error)
+	// GenerateAllStatements runs GenerateStatements for every consolidated
+	// month that has no statements yet
+	GenerateAllStatements() error
+	// CreditStatement issues a credit note cancelling the given statement
+	// and issues a replacement statement from the current consolidated data.
+	// The replacement is nil if there is nothing left to bill.
+	CreditStatement(statementNumber int64, reason string) (*CreditNote, *Statement, error)
+}
+
+// StatementFilter selects statements for the periods overlapping
+// [RangeStart, RangeStop). If OrgGUIDs is set only statements that cover
+// nothing but those orgs are returned, so a statement for a billing account
+// is only visible to someone who can see all of its orgs.
+type StatementFilter struct {
+	RangeStart string
+	RangeStop  string
+	OrgGUIDs   []string
+}
+
+func (filter *StatementFilter) Validate() error {
+	eventFilter := EventFilter{
+		RangeStart: filter.RangeStart,
+		RangeStop:  filter.RangeStop,
+		OrgGUIDs:   filter.OrgGUIDs,
+	}
+	return eventFilter.Validate()
+}
+
+type StatementLineItem struct {
+	ResourceType string `json:"resource_type"`
+	PlanName     string `json:"plan_name"`
+	VatCode      string `json:"vat_code"`
+	VatRate      string `json:"vat_rate"`
+	ExVAT        Money  `json:"ex_vat"`
+}
+
+type StatementVAT struct {
+	VatCode string `json:"vat_code"`
+	VatRate string `json:"vat_rate"`
+	ExVAT   Money  `json:"ex_vat"`
+	VAT     Money  `json:"vat"`
+}
+
+type CreditNote struct {
+	CreditNoteNumber int64  `json:"credit_note_number"`
+	StatementNumber  int64  `json:"statement_number"`
+	Reason           string `json:"reason"`
+	IssuedAt         string `json:"issued_at"`
+	Subtotal         Money  `json:"subtotal"`
+	VATTotal         Money  `json:"vat_total"`
+	Total            Money  `json:"total"`
+}
+
+type Statement struct {
+	StatementNumber int64               `json:"statement_number"`
+	AccountID       string              `json:"account_id"`
+	AccountName     string              `json:"account_name"`
+	OrgGUIDs        []string            `json:"org_guids"`
+	PeriodStart     string              `json:"period_start"`
+	PeriodStop      string              `json:"period_stop"`
+	IssuedAt        string              `json:"issued_at"`
+	CurrencyCode    string              `json:"currency_code"`
+	LineItems       []StatementLineItem `json:"line_items"`
+	VAT             []StatementVAT      `json:"vat"`
+	Subtotal        Money               `json:"subtotal"`
+	VATTotal        Money               `json:"vat_total"`
+	Total           Money               `json:"total"`
+	Replaces        *int64              `json:"replaces"`
+	CreditNote      *CreditNote         `json:"credit_note"`
+}
+
+// ParseStatementMonth turns a month in YYYY-MM form into the month long
+// EventFilter statements are generated for
+func ParseStatementMonth(month string) (EventFilter, error) {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return EventFilter{}, fmt.Errorf("month must be in the form YYYY-MM - got %s", month)
+	}
+	return EventFilter{
+		RangeStart: start.Format("2006-01-02"),
+		RangeStop:  start.AddDate(0, 1, 0).Format("2006-01-02"),
+	}, nil
+}
@@ -1,25 +1,35 @@
 package eventio
 
+import "context"
+
 type RawEventWriter interface {
 	StoreEvents(events []RawEvent) error
 }
 
 type RawEventReader interface {
 	GetEvents(filter RawEventFilter) ([]RawEvent, error)
+	GetEventsContext(ctx context.Context, filter RawEventFilter) ([]RawEvent, error)
 }
 
 type PricingPlanReader interface {
 	GetPricingPlans(filter TimeRangeFilter) ([]PricingPlan, error)
+	GetPricingPlansContext(ctx context.Context, filter TimeRangeFilter) ([]PricingPlan, error)
 }
 
 type CurrencyRateReader interface {
 	GetCurrencyRates(filter TimeRangeFilter) ([]CurrencyRate, error)
+	GetCurrencyRatesContext(ctx context.Context, filter TimeRangeFilter) ([]CurrencyRate, error)
 }
 
 type VATRateReader interface {
 	GetVATRates(filter TimeRangeFilter) ([]VATRate, error)
+	GetVATRatesContext(ctx context.Context, filter TimeRangeFilter) ([]VATRate, error)
 }
 
+// EventStore is implemented by eventstore.EventStore. Reader methods with a
+// Context suffix stop their queries when ctx is done; the versions without
+// it use the store's own context.
+//
 //counterfeiter:generate . EventStore
 type EventStore interface {
 	Init() error
@@ -37,4 +47,15 @@ type EventStore interface {
 	BillableEventForecaster
 	ConsolidatedBillableEventReader
 	BillableEventConsolidator
+	CostTimeSeriesReader
+	StatementReader
+	StatementGenerator
+	ResourceHistoryReader
+	DataQualityReader
+	UnpricedPlanReader
+	ExemptionReader
+	ExemptionWriter
+	FootprintReader
+	EgressUsageWriter
+	AllowanceReader
 }
@@ -0,0 +1,34 @@
+package eventio
+
+import "context"
+
+type UnpricedPlanReader interface {
+	GetUnpricedPlans() ([]UnpricedPlan, error)
+	GetUnpricedPlansContext(ctx context.Context) ([]UnpricedPlan, error)
+}
+
+// UnpricedPlan is a plan seen in usage events with usage that no configured
+// pricing plan covers. The usage is unbilled, or billed at £0 if the store
+// is configured to ignore missing plans, until a pricing plan covering it
+// is configured.
+//
+// ServicePlanGUID, ServicePlanName and ServiceLabel come from Cloud Foundry
+// for service plans and are empty for apps and tasks. The Unbilled fields
+// describe the usage that is still not covered and are zero once PricedAt
+// is set.
+type UnpricedPlan struct {
+	PlanGUID          string   `json:"plan_guid"`
+	PlanName          string   `json:"plan_name"`
+	ResourceType      string   `json:"resource_type"`
+	ServicePlanGUID   string   `json:"service_plan_guid"`
+	ServicePlanName   string   `json:"service_plan_name"`
+	ServiceLabel      string   `json:"service_label"`
+	FirstSeenAt       string   `json:"first_seen_at"`
+	LastSeenAt        string   `json:"last_seen_at"`
+	PricedAt          *string  `json:"priced_at"`
+	OrgGUIDs          []string `json:"org_guids"`
+	UnbilledEvents    int64    `json:"unbilled_events"`
+	UnbilledFrom      *string  `json:"unbilled_from"`
+	UnbilledHours     string   `json:"unbilled_hours"`
+	UnbilledNodeHours string   `json:"unbilled_node_hours"`
+}
@@ -1,8 +1,12 @@
 package eventio
 
+import "context"
+
 type UsageEventReader interface {
 	GetUsageEventRows(filter EventFilter) (UsageEventRows, error)
+	GetUsageEventRowsContext(ctx context.Context, filter EventFilter) (UsageEventRows, error)
 	GetUsageEvents(filter EventFilter) ([]UsageEvent, error)
+	GetUsageEventsContext(ctx context.Context, filter EventFilter) ([]UsageEvent, error)
 }
 
 type UsageEvent struct {
@@ -23,6 +27,14 @@ type UsageEvent struct {
 	NumberOfNodes int64  `json:"number_of_nodes"`
 	MemoryInMB    int64  `json:"memory_in_mb"`
 	StorageInMB   int64  `json:"storage_in_mb"`
+	// DiskInMB and LogRateLimit are the disk quota and log rate limit, in
+	// bytes per second, of each app instance or task. They are 0 for other
+	// resources and for an unlimited log rate.
+	DiskInMB     int64 `json:"disk_in_mb,omitempty"`
+	LogRateLimit int64 `json:"log_rate_limit,omitempty"`
+	// Labels are the cf metadata labels of the resource, its space and its
+	// org during the event, with the resource's taking precedence
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 //counterfeiter:generate . UsageEventRows
@@ -0,0 +1,302 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package eventiofakes
+
+import (
+	"sync"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+type FakeCostTimeSeriesRows struct {
+	CloseStub        func() error
+	closeMutex       sync.RWMutex
+	closeArgsForCall []struct {
+	}
+	closeReturns struct {
+		result1 error
+	}
+	closeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ErrStub        func() error
+	errMutex       sync.RWMutex
+	errArgsForCall []struct {
+	}
+	errReturns struct {
+		result1 error
+	}
+	errReturnsOnCall map[int]struct {
+		result1 error
+	}
+	NextStub        func() bool
+	nextMutex       sync.RWMutex
+	nextArgsForCall []struct {
+	}
+	nextReturns struct {
+		result1 bool
+	}
+	nextReturnsOnCall map[int]struct {
+		result1 bool
+	}
+	PointStub        func() (*eventio.CostTimeSeriesPoint, error)
+	pointMutex       sync.RWMutex
+	pointArgsForCall []struct {
+	}
+	pointReturns struct {
+		result1 *eventio.CostTimeSeriesPoint
+		result2 error
+	}
+	pointReturnsOnCall map[int]struct {
+		result1 *eventio.CostTimeSeriesPoint
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeCostTimeSeriesRows) Close() error {
+	fake.closeMutex.Lock()
+	ret, specificReturn := fake.closeReturnsOnCall[len(fake.closeArgsForCall)]
+	fake.closeArgsForCall = append(fake.closeArgsForCall, struct {
+	}{})
+	stub := fake.CloseStub
+	fakeReturns := fake.closeReturns
+	fake.recordInvocation("Close", []interface{}{})
+	fake.closeMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCostTimeSeriesRows) CloseCallCount() int {
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return len(fake.closeArgsForCall)
+}
+
+func (fake *FakeCostTimeSeriesRows) CloseCalls(stub func() error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = stub
+}
+
+func (fake *FakeCostTimeSeriesRows) CloseReturns(result1 error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = nil
+	fake.closeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCostTimeSeriesRows) CloseReturnsOnCall(i int, result1 error) {
+	fake.closeMutex.Lock()
+	defer fake.closeMutex.Unlock()
+	fake.CloseStub = nil
+	if fake.closeReturnsOnCall == nil {
+		fake.closeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.closeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCostTimeSeriesRows) Err() error {
+	fake.errMutex.Lock()
+	ret, specificReturn := fake.errReturnsOnCall[len(fake.errArgsForCall)]
+	fake.errArgsForCall = append(fake.errArgsForCall, struct {
+	}{})
+	stub := fake.ErrStub
+	fakeReturns := fake.errReturns
+	fake.recordInvocation("Err", []interface{}{})
+	fake.errMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCostTimeSeriesRows) ErrCallCount() int {
+	fake.errMutex.RLock()
+	defer fake.errMutex.RUnlock()
+	return len(fake.errArgsForCall)
+}
+
+func (fake *FakeCostTimeSeriesRows) ErrCalls(stub func() error) {
+	fake.errMutex.Lock()
+	defer fake.errMutex.Unlock()
+	fake.ErrStub = stub
+}
+
+func (fake *FakeCostTimeSeriesRows) ErrReturns(result1 error) {
+	fake.errMutex.Lock()
+	defer fake.errMutex.Unlock()
+	fake.ErrStub = nil
+	fake.errReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCostTimeSeriesRows) ErrReturnsOnCall(i int, result1 error) {
+	fake.errMutex.Lock()
+	defer fake.errMutex.Unlock()
+	fake.ErrStub = nil
+	if fake.errReturnsOnCall == nil {
+		fake.errReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.errReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCostTimeSeriesRows) Next() bool {
+	fake.nextMutex.Lock()
+	ret, specificReturn := fake.nextReturnsOnCall[len(fake.nextArgsForCall)]
+	fake.nextArgsForCall = append(fake.nextArgsForCall, struct {
+	}{})
+	stub := fake.NextStub
+	fakeReturns := fake.nextReturns
+	fake.recordInvocation("Next", []interface{}{})
+	fake.nextMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeCostTimeSeriesRows) NextCallCount() int {
+	fake.nextMutex.RLock()
+	defer fake.nextMutex.RUnlock()
+	return len(fake.nextArgsForCall)
+}
+
+func (fake *FakeCostTimeSeriesRows) NextCalls(stub func() bool) {
+	fake.nextMutex.Lock()
+	defer fake.nextMutex.Unlock()
+	fake.NextStub = stub
+}
+
+func (fake *FakeCostTimeSeriesRows) NextReturns(result1 bool) {
+	fake.nextMutex.Lock()
+	defer fake.nextMutex.Unlock()
+	fake.NextStub = nil
+	fake.nextReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeCostTimeSeriesRows) NextReturnsOnCall(i int, result1 bool) {
+	fake.nextMutex.Lock()
+	defer fake.nextMutex.Unlock()
+	fake.NextStub = nil
+	if fake.nextReturnsOnCall == nil {
+		fake.nextReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.nextReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *FakeCostTimeSeriesRows) Point() (*eventio.CostTimeSeriesPoint, error) {
+	fake.pointMutex.Lock()
+	ret, specificReturn := fake.pointReturnsOnCall[len(fake.pointArgsForCall)]
+	fake.pointArgsForCall = append(fake.pointArgsForCall, struct {
+	}{})
+	stub := fake.PointStub
+	fakeReturns := fake.pointReturns
+	fake.recordInvocation("Point", []interface{}{})
+	fake.pointMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeCostTimeSeriesRows) PointCallCount() int {
+	fake.pointMutex.RLock()
+	defer fake.pointMutex.RUnlock()
+	return len(fake.pointArgsForCall)
+}
+
+func (fake *FakeCostTimeSeriesRows) PointCalls(stub func() (*eventio.CostTimeSeriesPoint, error)) {
+	fake.pointMutex.Lock()
+	defer fake.pointMutex.Unlock()
+	fake.PointStub = stub
+}
+
+func (fake *FakeCostTimeSeriesRows) PointReturns(result1 *eventio.CostTimeSeriesPoint, result2 error) {
+	fake.pointMutex.Lock()
+	defer fake.pointMutex.Unlock()
+	fake.PointStub = nil
+	fake.pointReturns = struct {
+		result1 *eventio.CostTimeSeriesPoint
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCostTimeSeriesRows) PointReturnsOnCall(i int, result1 *eventio.CostTimeSeriesPoint, result2 error) {
+	fake.pointMutex.Lock()
+	defer fake.pointMutex.Unlock()
+	fake.PointStub = nil
+	if fake.pointReturnsOnCall == nil {
+		fake.pointReturnsOnCall = make(map[int]struct {
+			result1 *eventio.CostTimeSeriesPoint
+			result2 error
+		})
+	}
+	fake.pointReturnsOnCall[i] = struct {
+		result1 *eventio.CostTimeSeriesPoint
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeCostTimeSeriesRows) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	fake.errMutex.RLock()
+	defer fake.errMutex.RUnlock()
+	fake.nextMutex.RLock()
+	defer fake.nextMutex.RUnlock()
+	fake.pointMutex.RLock()
+	defer fake.pointMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeCostTimeSeriesRows) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ eventio.CostTimeSeriesRows = new(FakeCostTimeSeriesRows)
@@ -9,6 +9,19 @@ import (
 )
 
 type FakeEventStore struct {
+	AddExemptionStub        func(eventio.Exemption) (*eventio.Exemption, error)
+	addExemptionMutex       sync.RWMutex
+	addExemptionArgsForCall []struct {
+		arg1 eventio.Exemption
+	}
+	addExemptionReturns struct {
+		result1 *eventio.Exemption
+		result2 error
+	}
+	addExemptionReturnsOnCall map[int]struct {
+		result1 *eventio.Exemption
+		result2 error
+	}
 	ConsolidateStub        func(eventio.EventFilter) error
 	consolidateMutex       sync.RWMutex
 	consolidateArgsForCall []struct {
@@ -42,6 +55,22 @@ type FakeEventStore struct {
 	consolidateFullMonthsReturnsOnCall map[int]struct {
 		result1 error
 	}
+	CreditStatementStub        func(int64, string) (*eventio.CreditNote, *eventio.Statement, error)
+	creditStatementMutex       sync.RWMutex
+	creditStatementArgsForCall []struct {
+		arg1 int64
+		arg2 string
+	}
+	creditStatementReturns struct {
+		result1 *eventio.CreditNote
+		result2 *eventio.Statement
+		result3 error
+	}
+	creditStatementReturnsOnCall map[int]struct {
+		result1 *eventio.CreditNote
+		result2 *eventio.Statement
+		result3 error
+	}
 	ForecastBillableEventRowsStub        func(context.Context, []eventio.UsageEvent, eventio.EventFilter) (eventio.BillableEventRows, error)
 	forecastBillableEventRowsMutex       sync.RWMutex
 	forecastBillableEventRowsArgsForCall []struct {
@@ -71,6 +100,71 @@ type FakeEventStore struct {
 		result1 []eventio.BillableEvent
 		result2 error
 	}
+	ForecastBillableEventsContextStub        func(context.Context, []eventio.UsageEvent, eventio.EventFilter) ([]eventio.BillableEvent, error)
+	forecastBillableEventsContextMutex       sync.RWMutex
+	forecastBillableEventsContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 []eventio.UsageEvent
+		arg3 eventio.EventFilter
+	}
+	forecastBillableEventsContextReturns struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
+	forecastBillableEventsContextReturnsOnCall map[int]struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
+	GenerateAllStatementsStub        func() error
+	generateAllStatementsMutex       sync.RWMutex
+	generateAllStatementsArgsForCall []struct {
+	}
+	generateAllStatementsReturns struct {
+		result1 error
+	}
+	generateAllStatementsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	GenerateStatementsStub        func(string) ([]eventio.Statement, error)
+	generateStatementsMutex       sync.RWMutex
+	generateStatementsArgsForCall []struct {
+		arg1 string
+	}
+	generateStatementsReturns struct {
+		result1 []eventio.Statement
+		result2 error
+	}
+	generateStatementsReturnsOnCall map[int]struct {
+		result1 []eventio.Statement
+		result2 error
+	}
+	GetAllowanceUsageStub        func(eventio.EventFilter) ([]eventio.AllowanceUsage, error)
+	getAllowanceUsageMutex       sync.RWMutex
+	getAllowanceUsageArgsForCall []struct {
+		arg1 eventio.EventFilter
+	}
+	getAllowanceUsageReturns struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}
+	getAllowanceUsageReturnsOnCall map[int]struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}
+	GetAllowanceUsageContextStub        func(context.Context, eventio.EventFilter) ([]eventio.AllowanceUsage, error)
+	getAllowanceUsageContextMutex       sync.RWMutex
+	getAllowanceUsageContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	getAllowanceUsageContextReturns struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}
+	getAllowanceUsageContextReturnsOnCall map[int]struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}
 	GetBillableEventRowsStub        func(context.Context, eventio.EventFilter) (eventio.BillableEventRows, error)
 	getBillableEventRowsMutex       sync.RWMutex
 	getBillableEventRowsArgsForCall []struct {
@@ -98,6 +192,20 @@ type FakeEventStore struct {
 		result1 []eventio.BillableEvent
 		result2 error
 	}
+	GetBillableEventsContextStub        func(context.Context, eventio.EventFilter) ([]eventio.BillableEvent, error)
+	getBillableEventsContextMutex       sync.RWMutex
+	getBillableEventsContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	getBillableEventsContextReturns struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
+	getBillableEventsContextReturnsOnCall map[int]struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
 	GetConsolidatedBillableEventRowsStub        func(context.Context, eventio.EventFilter) (eventio.BillableEventRows, error)
 	getConsolidatedBillableEventRowsMutex       sync.RWMutex
 	getConsolidatedBillableEventRowsArgsForCall []struct {
@@ -125,6 +233,61 @@ type FakeEventStore struct {
 		result1 []eventio.BillableEvent
 		result2 error
 	}
+	GetConsolidatedBillableEventsContextStub        func(context.Context, eventio.EventFilter) ([]eventio.BillableEvent, error)
+	getConsolidatedBillableEventsContextMutex       sync.RWMutex
+	getConsolidatedBillableEventsContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	getConsolidatedBillableEventsContextReturns struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
+	getConsolidatedBillableEventsContextReturnsOnCall map[int]struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}
+	GetConsolidationHistoryStub        func(eventio.EventFilter) ([]eventio.ConsolidationRecord, error)
+	getConsolidationHistoryMutex       sync.RWMutex
+	getConsolidationHistoryArgsForCall []struct {
+		arg1 eventio.EventFilter
+	}
+	getConsolidationHistoryReturns struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}
+	getConsolidationHistoryReturnsOnCall map[int]struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}
+	GetConsolidationHistoryContextStub        func(context.Context, eventio.EventFilter) ([]eventio.ConsolidationRecord, error)
+	getConsolidationHistoryContextMutex       sync.RWMutex
+	getConsolidationHistoryContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	getConsolidationHistoryContextReturns struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}
+	getConsolidationHistoryContextReturnsOnCall map[int]struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}
+	GetCostTimeSeriesRowsStub        func(context.Context, eventio.CostTimeSeriesFilter) (eventio.CostTimeSeriesRows, error)
+	getCostTimeSeriesRowsMutex       sync.RWMutex
+	getCostTimeSeriesRowsArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.CostTimeSeriesFilter
+	}
+	getCostTimeSeriesRowsReturns struct {
+		result1 eventio.CostTimeSeriesRows
+		result2 error
+	}
+	getCostTimeSeriesRowsReturnsOnCall map[int]struct {
+		result1 eventio.CostTimeSeriesRows
+		result2 error
+	}
 	GetCurrencyRatesStub        func(eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error)
 	getCurrencyRatesMutex       sync.RWMutex
 	getCurrencyRatesArgsForCall []struct {
@@ -138,6 +301,60 @@ type FakeEventStore struct {
 		result1 []eventio.CurrencyRate
 		result2 error
 	}
+	GetCurrencyRatesContextStub        func(context.Context, eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error)
+	getCurrencyRatesContextMutex       sync.RWMutex
+	getCurrencyRatesContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}
+	getCurrencyRatesContextReturns struct {
+		result1 []eventio.CurrencyRate
+		result2 error
+	}
+	getCurrencyRatesContextReturnsOnCall map[int]struct {
+		result1 []eventio.CurrencyRate
+		result2 error
+	}
+	GetDataQualityIssuesStub        func(eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error)
+	getDataQualityIssuesMutex       sync.RWMutex
+	getDataQualityIssuesArgsForCall []struct {
+		arg1 eventio.DataQualityFilter
+	}
+	getDataQualityIssuesReturns struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}
+	getDataQualityIssuesReturnsOnCall map[int]struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}
+	GetDataQualityIssuesContextStub        func(context.Context, eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error)
+	getDataQualityIssuesContextMutex       sync.RWMutex
+	getDataQualityIssuesContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.DataQualityFilter
+	}
+	getDataQualityIssuesContextReturns struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}
+	getDataQualityIssuesContextReturnsOnCall map[int]struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}
+	GetEgressOffsetStub        func(string) (int64, error)
+	getEgressOffsetMutex       sync.RWMutex
+	getEgressOffsetArgsForCall []struct {
+		arg1 string
+	}
+	getEgressOffsetReturns struct {
+		result1 int64
+		result2 error
+	}
+	getEgressOffsetReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
 	GetEventsStub        func(eventio.RawEventFilter) ([]eventio.RawEvent, error)
 	getEventsMutex       sync.RWMutex
 	getEventsArgsForCall []struct {
@@ -151,6 +368,73 @@ type FakeEventStore struct {
 		result1 []eventio.RawEvent
 		result2 error
 	}
+	GetEventsContextStub        func(context.Context, eventio.RawEventFilter) ([]eventio.RawEvent, error)
+	getEventsContextMutex       sync.RWMutex
+	getEventsContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.RawEventFilter
+	}
+	getEventsContextReturns struct {
+		result1 []eventio.RawEvent
+		result2 error
+	}
+	getEventsContextReturnsOnCall map[int]struct {
+		result1 []eventio.RawEvent
+		result2 error
+	}
+	GetExemptionsStub        func() ([]eventio.Exemption, error)
+	getExemptionsMutex       sync.RWMutex
+	getExemptionsArgsForCall []struct {
+	}
+	getExemptionsReturns struct {
+		result1 []eventio.Exemption
+		result2 error
+	}
+	getExemptionsReturnsOnCall map[int]struct {
+		result1 []eventio.Exemption
+		result2 error
+	}
+	GetExemptionsContextStub        func(context.Context) ([]eventio.Exemption, error)
+	getExemptionsContextMutex       sync.RWMutex
+	getExemptionsContextArgsForCall []struct {
+		arg1 context.Context
+	}
+	getExemptionsContextReturns struct {
+		result1 []eventio.Exemption
+		result2 error
+	}
+	getExemptionsContextReturnsOnCall map[int]struct {
+		result1 []eventio.Exemption
+		result2 error
+	}
+	GetFootprintEventRowsStub        func(context.Context, eventio.EventFilter) (eventio.FootprintEventRows, error)
+	getFootprintEventRowsMutex       sync.RWMutex
+	getFootprintEventRowsArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	getFootprintEventRowsReturns struct {
+		result1 eventio.FootprintEventRows
+		result2 error
+	}
+	getFootprintEventRowsReturnsOnCall map[int]struct {
+		result1 eventio.FootprintEventRows
+		result2 error
+	}
+	GetFootprintPlansStub        func(context.Context, eventio.TimeRangeFilter) ([]eventio.FootprintPlan, error)
+	getFootprintPlansMutex       sync.RWMutex
+	getFootprintPlansArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}
+	getFootprintPlansReturns struct {
+		result1 []eventio.FootprintPlan
+		result2 error
+	}
+	getFootprintPlansReturnsOnCall map[int]struct {
+		result1 []eventio.FootprintPlan
+		result2 error
+	}
 	GetPricingPlansStub        func(eventio.TimeRangeFilter) ([]eventio.PricingPlan, error)
 	getPricingPlansMutex       sync.RWMutex
 	getPricingPlansArgsForCall []struct {
@@ -164,6 +448,101 @@ type FakeEventStore struct {
 		result1 []eventio.PricingPlan
 		result2 error
 	}
+	GetPricingPlansContextStub        func(context.Context, eventio.TimeRangeFilter) ([]eventio.PricingPlan, error)
+	getPricingPlansContextMutex       sync.RWMutex
+	getPricingPlansContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}
+	getPricingPlansContextReturns struct {
+		result1 []eventio.PricingPlan
+		result2 error
+	}
+	getPricingPlansContextReturnsOnCall map[int]struct {
+		result1 []eventio.PricingPlan
+		result2 error
+	}
+	GetResourceHistoryStub        func(eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error)
+	getResourceHistoryMutex       sync.RWMutex
+	getResourceHistoryArgsForCall []struct {
+		arg1 eventio.ResourceHistoryFilter
+	}
+	getResourceHistoryReturns struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}
+	getResourceHistoryReturnsOnCall map[int]struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}
+	GetResourceHistoryContextStub        func(context.Context, eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error)
+	getResourceHistoryContextMutex       sync.RWMutex
+	getResourceHistoryContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.ResourceHistoryFilter
+	}
+	getResourceHistoryContextReturns struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}
+	getResourceHistoryContextReturnsOnCall map[int]struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}
+	GetStatementStub        func(int64) (*eventio.Statement, error)
+	getStatementMutex       sync.RWMutex
+	getStatementArgsForCall []struct {
+		arg1 int64
+	}
+	getStatementReturns struct {
+		result1 *eventio.Statement
+		result2 error
+	}
+	getStatementReturnsOnCall map[int]struct {
+		result1 *eventio.Statement
+		result2 error
+	}
+	GetStatementContextStub        func(context.Context, int64) (*eventio.Statement, error)
+	getStatementContextMutex       sync.RWMutex
+	getStatementContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 int64
+	}
+	getStatementContextReturns struct {
+		result1 *eventio.Statement
+		result2 error
+	}
+	getStatementContextReturnsOnCall map[int]struct {
+		result1 *eventio.Statement
+		result2 error
+	}
+	GetStatementsStub        func(eventio.StatementFilter) ([]eventio.Statement, error)
+	getStatementsMutex       sync.RWMutex
+	getStatementsArgsForCall []struct {
+		arg1 eventio.StatementFilter
+	}
+	getStatementsReturns struct {
+		result1 []eventio.Statement
+		result2 error
+	}
+	getStatementsReturnsOnCall map[int]struct {
+		result1 []eventio.Statement
+		result2 error
+	}
+	GetStatementsContextStub        func(context.Context, eventio.StatementFilter) ([]eventio.Statement, error)
+	getStatementsContextMutex       sync.RWMutex
+	getStatementsContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.StatementFilter
+	}
+	getStatementsContextReturns struct {
+		result1 []eventio.Statement
+		result2 error
+	}
+	getStatementsContextReturnsOnCall map[int]struct {
+		result1 []eventio.Statement
+		result2 error
+	}
 	GetTotalCostStub        func() ([]eventio.TotalCost, error)
 	getTotalCostMutex       sync.RWMutex
 	getTotalCostArgsForCall []struct {
@@ -176,6 +555,44 @@ type FakeEventStore struct {
 		result1 []eventio.TotalCost
 		result2 error
 	}
+	GetTotalCostContextStub        func(context.Context) ([]eventio.TotalCost, error)
+	getTotalCostContextMutex       sync.RWMutex
+	getTotalCostContextArgsForCall []struct {
+		arg1 context.Context
+	}
+	getTotalCostContextReturns struct {
+		result1 []eventio.TotalCost
+		result2 error
+	}
+	getTotalCostContextReturnsOnCall map[int]struct {
+		result1 []eventio.TotalCost
+		result2 error
+	}
+	GetUnpricedPlansStub        func() ([]eventio.UnpricedPlan, error)
+	getUnpricedPlansMutex       sync.RWMutex
+	getUnpricedPlansArgsForCall []struct {
+	}
+	getUnpricedPlansReturns struct {
+		result1 []eventio.UnpricedPlan
+		result2 error
+	}
+	getUnpricedPlansReturnsOnCall map[int]struct {
+		result1 []eventio.UnpricedPlan
+		result2 error
+	}
+	GetUnpricedPlansContextStub        func(context.Context) ([]eventio.UnpricedPlan, error)
+	getUnpricedPlansContextMutex       sync.RWMutex
+	getUnpricedPlansContextArgsForCall []struct {
+		arg1 context.Context
+	}
+	getUnpricedPlansContextReturns struct {
+		result1 []eventio.UnpricedPlan
+		result2 error
+	}
+	getUnpricedPlansContextReturnsOnCall map[int]struct {
+		result1 []eventio.UnpricedPlan
+		result2 error
+	}
 	GetUsageEventRowsStub        func(eventio.EventFilter) (eventio.UsageEventRows, error)
 	getUsageEventRowsMutex       sync.RWMutex
 	getUsageEventRowsArgsForCall []struct {
@@ -189,6 +606,20 @@ type FakeEventStore struct {
 		result1 eventio.UsageEventRows
 		result2 error
 	}
+	GetUsageEventRowsContextStub        func(context.Context, eventio.EventFilter) (eventio.UsageEventRows, error)
+	getUsageEventRowsContextMutex       sync.RWMutex
+	getUsageEventRowsContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	getUsageEventRowsContextReturns struct {
+		result1 eventio.UsageEventRows
+		result2 error
+	}
+	getUsageEventRowsContextReturnsOnCall map[int]struct {
+		result1 eventio.UsageEventRows
+		result2 error
+	}
 	GetUsageEventsStub        func(eventio.EventFilter) ([]eventio.UsageEvent, error)
 	getUsageEventsMutex       sync.RWMutex
 	getUsageEventsArgsForCall []struct {
@@ -202,6 +633,20 @@ type FakeEventStore struct {
 		result1 []eventio.UsageEvent
 		result2 error
 	}
+	GetUsageEventsContextStub        func(context.Context, eventio.EventFilter) ([]eventio.UsageEvent, error)
+	getUsageEventsContextMutex       sync.RWMutex
+	getUsageEventsContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	getUsageEventsContextReturns struct {
+		result1 []eventio.UsageEvent
+		result2 error
+	}
+	getUsageEventsContextReturnsOnCall map[int]struct {
+		result1 []eventio.UsageEvent
+		result2 error
+	}
 	GetVATRatesStub        func(eventio.TimeRangeFilter) ([]eventio.VATRate, error)
 	getVATRatesMutex       sync.RWMutex
 	getVATRatesArgsForCall []struct {
@@ -215,6 +660,20 @@ type FakeEventStore struct {
 		result1 []eventio.VATRate
 		result2 error
 	}
+	GetVATRatesContextStub        func(context.Context, eventio.TimeRangeFilter) ([]eventio.VATRate, error)
+	getVATRatesContextMutex       sync.RWMutex
+	getVATRatesContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}
+	getVATRatesContextReturns struct {
+		result1 []eventio.VATRate
+		result2 error
+	}
+	getVATRatesContextReturnsOnCall map[int]struct {
+		result1 []eventio.VATRate
+		result2 error
+	}
 	InitStub        func() error
 	initMutex       sync.RWMutex
 	initArgsForCall []struct {
@@ -238,6 +697,20 @@ type FakeEventStore struct {
 		result1 bool
 		result2 error
 	}
+	IsRangeConsolidatedContextStub        func(context.Context, eventio.EventFilter) (bool, error)
+	isRangeConsolidatedContextMutex       sync.RWMutex
+	isRangeConsolidatedContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}
+	isRangeConsolidatedContextReturns struct {
+		result1 bool
+		result2 error
+	}
+	isRangeConsolidatedContextReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	PingStub        func() error
 	pingMutex       sync.RWMutex
 	pingArgsForCall []struct {
@@ -268,6 +741,30 @@ type FakeEventStore struct {
 	refreshReturnsOnCall map[int]struct {
 		result1 error
 	}
+	RemoveExemptionStub        func(string) (bool, error)
+	removeExemptionMutex       sync.RWMutex
+	removeExemptionArgsForCall []struct {
+		arg1 string
+	}
+	removeExemptionReturns struct {
+		result1 bool
+		result2 error
+	}
+	removeExemptionReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
+	StoreEgressBatchStub        func(eventio.EgressBatch) error
+	storeEgressBatchMutex       sync.RWMutex
+	storeEgressBatchArgsForCall []struct {
+		arg1 eventio.EgressBatch
+	}
+	storeEgressBatchReturns struct {
+		result1 error
+	}
+	storeEgressBatchReturnsOnCall map[int]struct {
+		result1 error
+	}
 	StoreEventsStub        func([]eventio.RawEvent) error
 	storeEventsMutex       sync.RWMutex
 	storeEventsArgsForCall []struct {
@@ -283,21 +780,85 @@ type FakeEventStore struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeEventStore) Consolidate(arg1 eventio.EventFilter) error {
-	fake.consolidateMutex.Lock()
-	ret, specificReturn := fake.consolidateReturnsOnCall[len(fake.consolidateArgsForCall)]
-	fake.consolidateArgsForCall = append(fake.consolidateArgsForCall, struct {
-		arg1 eventio.EventFilter
+func (fake *FakeEventStore) AddExemption(arg1 eventio.Exemption) (*eventio.Exemption, error) {
+	fake.addExemptionMutex.Lock()
+	ret, specificReturn := fake.addExemptionReturnsOnCall[len(fake.addExemptionArgsForCall)]
+	fake.addExemptionArgsForCall = append(fake.addExemptionArgsForCall, struct {
+		arg1 eventio.Exemption
 	}{arg1})
-	stub := fake.ConsolidateStub
-	fakeReturns := fake.consolidateReturns
-	fake.recordInvocation("Consolidate", []interface{}{arg1})
-	fake.consolidateMutex.Unlock()
+	stub := fake.AddExemptionStub
+	fakeReturns := fake.addExemptionReturns
+	fake.recordInvocation("AddExemption", []interface{}{arg1})
+	fake.addExemptionMutex.Unlock()
 	if stub != nil {
 		return stub(arg1)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) AddExemptionCallCount() int {
+	fake.addExemptionMutex.RLock()
+	defer fake.addExemptionMutex.RUnlock()
+	return len(fake.addExemptionArgsForCall)
+}
+
+func (fake *FakeEventStore) AddExemptionCalls(stub func(eventio.Exemption) (*eventio.Exemption, error)) {
+	fake.addExemptionMutex.Lock()
+	defer fake.addExemptionMutex.Unlock()
+	fake.AddExemptionStub = stub
+}
+
+func (fake *FakeEventStore) AddExemptionArgsForCall(i int) eventio.Exemption {
+	fake.addExemptionMutex.RLock()
+	defer fake.addExemptionMutex.RUnlock()
+	argsForCall := fake.addExemptionArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) AddExemptionReturns(result1 *eventio.Exemption, result2 error) {
+	fake.addExemptionMutex.Lock()
+	defer fake.addExemptionMutex.Unlock()
+	fake.AddExemptionStub = nil
+	fake.addExemptionReturns = struct {
+		result1 *eventio.Exemption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) AddExemptionReturnsOnCall(i int, result1 *eventio.Exemption, result2 error) {
+	fake.addExemptionMutex.Lock()
+	defer fake.addExemptionMutex.Unlock()
+	fake.AddExemptionStub = nil
+	if fake.addExemptionReturnsOnCall == nil {
+		fake.addExemptionReturnsOnCall = make(map[int]struct {
+			result1 *eventio.Exemption
+			result2 error
+		})
+	}
+	fake.addExemptionReturnsOnCall[i] = struct {
+		result1 *eventio.Exemption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) Consolidate(arg1 eventio.EventFilter) error {
+	fake.consolidateMutex.Lock()
+	ret, specificReturn := fake.consolidateReturnsOnCall[len(fake.consolidateArgsForCall)]
+	fake.consolidateArgsForCall = append(fake.consolidateArgsForCall, struct {
+		arg1 eventio.EventFilter
+	}{arg1})
+	stub := fake.ConsolidateStub
+	fakeReturns := fake.consolidateReturns
+	fake.recordInvocation("Consolidate", []interface{}{arg1})
+	fake.consolidateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
 	}
 	return fakeReturns.result1
 }
@@ -459,6 +1020,74 @@ func (fake *FakeEventStore) ConsolidateFullMonthsReturnsOnCall(i int, result1 er
 	}{result1}
 }
 
+func (fake *FakeEventStore) CreditStatement(arg1 int64, arg2 string) (*eventio.CreditNote, *eventio.Statement, error) {
+	fake.creditStatementMutex.Lock()
+	ret, specificReturn := fake.creditStatementReturnsOnCall[len(fake.creditStatementArgsForCall)]
+	fake.creditStatementArgsForCall = append(fake.creditStatementArgsForCall, struct {
+		arg1 int64
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.CreditStatementStub
+	fakeReturns := fake.creditStatementReturns
+	fake.recordInvocation("CreditStatement", []interface{}{arg1, arg2})
+	fake.creditStatementMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2, ret.result3
+	}
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
+}
+
+func (fake *FakeEventStore) CreditStatementCallCount() int {
+	fake.creditStatementMutex.RLock()
+	defer fake.creditStatementMutex.RUnlock()
+	return len(fake.creditStatementArgsForCall)
+}
+
+func (fake *FakeEventStore) CreditStatementCalls(stub func(int64, string) (*eventio.CreditNote, *eventio.Statement, error)) {
+	fake.creditStatementMutex.Lock()
+	defer fake.creditStatementMutex.Unlock()
+	fake.CreditStatementStub = stub
+}
+
+func (fake *FakeEventStore) CreditStatementArgsForCall(i int) (int64, string) {
+	fake.creditStatementMutex.RLock()
+	defer fake.creditStatementMutex.RUnlock()
+	argsForCall := fake.creditStatementArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) CreditStatementReturns(result1 *eventio.CreditNote, result2 *eventio.Statement, result3 error) {
+	fake.creditStatementMutex.Lock()
+	defer fake.creditStatementMutex.Unlock()
+	fake.CreditStatementStub = nil
+	fake.creditStatementReturns = struct {
+		result1 *eventio.CreditNote
+		result2 *eventio.Statement
+		result3 error
+	}{result1, result2, result3}
+}
+
+func (fake *FakeEventStore) CreditStatementReturnsOnCall(i int, result1 *eventio.CreditNote, result2 *eventio.Statement, result3 error) {
+	fake.creditStatementMutex.Lock()
+	defer fake.creditStatementMutex.Unlock()
+	fake.CreditStatementStub = nil
+	if fake.creditStatementReturnsOnCall == nil {
+		fake.creditStatementReturnsOnCall = make(map[int]struct {
+			result1 *eventio.CreditNote
+			result2 *eventio.Statement
+			result3 error
+		})
+	}
+	fake.creditStatementReturnsOnCall[i] = struct {
+		result1 *eventio.CreditNote
+		result2 *eventio.Statement
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *FakeEventStore) ForecastBillableEventRows(arg1 context.Context, arg2 []eventio.UsageEvent, arg3 eventio.EventFilter) (eventio.BillableEventRows, error) {
 	var arg2Copy []eventio.UsageEvent
 	if arg2 != nil {
@@ -600,6 +1229,323 @@ func (fake *FakeEventStore) ForecastBillableEventsReturnsOnCall(i int, result1 [
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) ForecastBillableEventsContext(arg1 context.Context, arg2 []eventio.UsageEvent, arg3 eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	var arg2Copy []eventio.UsageEvent
+	if arg2 != nil {
+		arg2Copy = make([]eventio.UsageEvent, len(arg2))
+		copy(arg2Copy, arg2)
+	}
+	fake.forecastBillableEventsContextMutex.Lock()
+	ret, specificReturn := fake.forecastBillableEventsContextReturnsOnCall[len(fake.forecastBillableEventsContextArgsForCall)]
+	fake.forecastBillableEventsContextArgsForCall = append(fake.forecastBillableEventsContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 []eventio.UsageEvent
+		arg3 eventio.EventFilter
+	}{arg1, arg2Copy, arg3})
+	stub := fake.ForecastBillableEventsContextStub
+	fakeReturns := fake.forecastBillableEventsContextReturns
+	fake.recordInvocation("ForecastBillableEventsContext", []interface{}{arg1, arg2Copy, arg3})
+	fake.forecastBillableEventsContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) ForecastBillableEventsContextCallCount() int {
+	fake.forecastBillableEventsContextMutex.RLock()
+	defer fake.forecastBillableEventsContextMutex.RUnlock()
+	return len(fake.forecastBillableEventsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) ForecastBillableEventsContextCalls(stub func(context.Context, []eventio.UsageEvent, eventio.EventFilter) ([]eventio.BillableEvent, error)) {
+	fake.forecastBillableEventsContextMutex.Lock()
+	defer fake.forecastBillableEventsContextMutex.Unlock()
+	fake.ForecastBillableEventsContextStub = stub
+}
+
+func (fake *FakeEventStore) ForecastBillableEventsContextArgsForCall(i int) (context.Context, []eventio.UsageEvent, eventio.EventFilter) {
+	fake.forecastBillableEventsContextMutex.RLock()
+	defer fake.forecastBillableEventsContextMutex.RUnlock()
+	argsForCall := fake.forecastBillableEventsContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeEventStore) ForecastBillableEventsContextReturns(result1 []eventio.BillableEvent, result2 error) {
+	fake.forecastBillableEventsContextMutex.Lock()
+	defer fake.forecastBillableEventsContextMutex.Unlock()
+	fake.ForecastBillableEventsContextStub = nil
+	fake.forecastBillableEventsContextReturns = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) ForecastBillableEventsContextReturnsOnCall(i int, result1 []eventio.BillableEvent, result2 error) {
+	fake.forecastBillableEventsContextMutex.Lock()
+	defer fake.forecastBillableEventsContextMutex.Unlock()
+	fake.ForecastBillableEventsContextStub = nil
+	if fake.forecastBillableEventsContextReturnsOnCall == nil {
+		fake.forecastBillableEventsContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.BillableEvent
+			result2 error
+		})
+	}
+	fake.forecastBillableEventsContextReturnsOnCall[i] = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GenerateAllStatements() error {
+	fake.generateAllStatementsMutex.Lock()
+	ret, specificReturn := fake.generateAllStatementsReturnsOnCall[len(fake.generateAllStatementsArgsForCall)]
+	fake.generateAllStatementsArgsForCall = append(fake.generateAllStatementsArgsForCall, struct {
+	}{})
+	stub := fake.GenerateAllStatementsStub
+	fakeReturns := fake.generateAllStatementsReturns
+	fake.recordInvocation("GenerateAllStatements", []interface{}{})
+	fake.generateAllStatementsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) GenerateAllStatementsCallCount() int {
+	fake.generateAllStatementsMutex.RLock()
+	defer fake.generateAllStatementsMutex.RUnlock()
+	return len(fake.generateAllStatementsArgsForCall)
+}
+
+func (fake *FakeEventStore) GenerateAllStatementsCalls(stub func() error) {
+	fake.generateAllStatementsMutex.Lock()
+	defer fake.generateAllStatementsMutex.Unlock()
+	fake.GenerateAllStatementsStub = stub
+}
+
+func (fake *FakeEventStore) GenerateAllStatementsReturns(result1 error) {
+	fake.generateAllStatementsMutex.Lock()
+	defer fake.generateAllStatementsMutex.Unlock()
+	fake.GenerateAllStatementsStub = nil
+	fake.generateAllStatementsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) GenerateAllStatementsReturnsOnCall(i int, result1 error) {
+	fake.generateAllStatementsMutex.Lock()
+	defer fake.generateAllStatementsMutex.Unlock()
+	fake.GenerateAllStatementsStub = nil
+	if fake.generateAllStatementsReturnsOnCall == nil {
+		fake.generateAllStatementsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.generateAllStatementsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) GenerateStatements(arg1 string) ([]eventio.Statement, error) {
+	fake.generateStatementsMutex.Lock()
+	ret, specificReturn := fake.generateStatementsReturnsOnCall[len(fake.generateStatementsArgsForCall)]
+	fake.generateStatementsArgsForCall = append(fake.generateStatementsArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GenerateStatementsStub
+	fakeReturns := fake.generateStatementsReturns
+	fake.recordInvocation("GenerateStatements", []interface{}{arg1})
+	fake.generateStatementsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GenerateStatementsCallCount() int {
+	fake.generateStatementsMutex.RLock()
+	defer fake.generateStatementsMutex.RUnlock()
+	return len(fake.generateStatementsArgsForCall)
+}
+
+func (fake *FakeEventStore) GenerateStatementsCalls(stub func(string) ([]eventio.Statement, error)) {
+	fake.generateStatementsMutex.Lock()
+	defer fake.generateStatementsMutex.Unlock()
+	fake.GenerateStatementsStub = stub
+}
+
+func (fake *FakeEventStore) GenerateStatementsArgsForCall(i int) string {
+	fake.generateStatementsMutex.RLock()
+	defer fake.generateStatementsMutex.RUnlock()
+	argsForCall := fake.generateStatementsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GenerateStatementsReturns(result1 []eventio.Statement, result2 error) {
+	fake.generateStatementsMutex.Lock()
+	defer fake.generateStatementsMutex.Unlock()
+	fake.GenerateStatementsStub = nil
+	fake.generateStatementsReturns = struct {
+		result1 []eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GenerateStatementsReturnsOnCall(i int, result1 []eventio.Statement, result2 error) {
+	fake.generateStatementsMutex.Lock()
+	defer fake.generateStatementsMutex.Unlock()
+	fake.GenerateStatementsStub = nil
+	if fake.generateStatementsReturnsOnCall == nil {
+		fake.generateStatementsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Statement
+			result2 error
+		})
+	}
+	fake.generateStatementsReturnsOnCall[i] = struct {
+		result1 []eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetAllowanceUsage(arg1 eventio.EventFilter) ([]eventio.AllowanceUsage, error) {
+	fake.getAllowanceUsageMutex.Lock()
+	ret, specificReturn := fake.getAllowanceUsageReturnsOnCall[len(fake.getAllowanceUsageArgsForCall)]
+	fake.getAllowanceUsageArgsForCall = append(fake.getAllowanceUsageArgsForCall, struct {
+		arg1 eventio.EventFilter
+	}{arg1})
+	stub := fake.GetAllowanceUsageStub
+	fakeReturns := fake.getAllowanceUsageReturns
+	fake.recordInvocation("GetAllowanceUsage", []interface{}{arg1})
+	fake.getAllowanceUsageMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageCallCount() int {
+	fake.getAllowanceUsageMutex.RLock()
+	defer fake.getAllowanceUsageMutex.RUnlock()
+	return len(fake.getAllowanceUsageArgsForCall)
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageCalls(stub func(eventio.EventFilter) ([]eventio.AllowanceUsage, error)) {
+	fake.getAllowanceUsageMutex.Lock()
+	defer fake.getAllowanceUsageMutex.Unlock()
+	fake.GetAllowanceUsageStub = stub
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageArgsForCall(i int) eventio.EventFilter {
+	fake.getAllowanceUsageMutex.RLock()
+	defer fake.getAllowanceUsageMutex.RUnlock()
+	argsForCall := fake.getAllowanceUsageArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageReturns(result1 []eventio.AllowanceUsage, result2 error) {
+	fake.getAllowanceUsageMutex.Lock()
+	defer fake.getAllowanceUsageMutex.Unlock()
+	fake.GetAllowanceUsageStub = nil
+	fake.getAllowanceUsageReturns = struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageReturnsOnCall(i int, result1 []eventio.AllowanceUsage, result2 error) {
+	fake.getAllowanceUsageMutex.Lock()
+	defer fake.getAllowanceUsageMutex.Unlock()
+	fake.GetAllowanceUsageStub = nil
+	if fake.getAllowanceUsageReturnsOnCall == nil {
+		fake.getAllowanceUsageReturnsOnCall = make(map[int]struct {
+			result1 []eventio.AllowanceUsage
+			result2 error
+		})
+	}
+	fake.getAllowanceUsageReturnsOnCall[i] = struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageContext(arg1 context.Context, arg2 eventio.EventFilter) ([]eventio.AllowanceUsage, error) {
+	fake.getAllowanceUsageContextMutex.Lock()
+	ret, specificReturn := fake.getAllowanceUsageContextReturnsOnCall[len(fake.getAllowanceUsageContextArgsForCall)]
+	fake.getAllowanceUsageContextArgsForCall = append(fake.getAllowanceUsageContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.GetAllowanceUsageContextStub
+	fakeReturns := fake.getAllowanceUsageContextReturns
+	fake.recordInvocation("GetAllowanceUsageContext", []interface{}{arg1, arg2})
+	fake.getAllowanceUsageContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageContextCallCount() int {
+	fake.getAllowanceUsageContextMutex.RLock()
+	defer fake.getAllowanceUsageContextMutex.RUnlock()
+	return len(fake.getAllowanceUsageContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageContextCalls(stub func(context.Context, eventio.EventFilter) ([]eventio.AllowanceUsage, error)) {
+	fake.getAllowanceUsageContextMutex.Lock()
+	defer fake.getAllowanceUsageContextMutex.Unlock()
+	fake.GetAllowanceUsageContextStub = stub
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageContextArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.getAllowanceUsageContextMutex.RLock()
+	defer fake.getAllowanceUsageContextMutex.RUnlock()
+	argsForCall := fake.getAllowanceUsageContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageContextReturns(result1 []eventio.AllowanceUsage, result2 error) {
+	fake.getAllowanceUsageContextMutex.Lock()
+	defer fake.getAllowanceUsageContextMutex.Unlock()
+	fake.GetAllowanceUsageContextStub = nil
+	fake.getAllowanceUsageContextReturns = struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetAllowanceUsageContextReturnsOnCall(i int, result1 []eventio.AllowanceUsage, result2 error) {
+	fake.getAllowanceUsageContextMutex.Lock()
+	defer fake.getAllowanceUsageContextMutex.Unlock()
+	fake.GetAllowanceUsageContextStub = nil
+	if fake.getAllowanceUsageContextReturnsOnCall == nil {
+		fake.getAllowanceUsageContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.AllowanceUsage
+			result2 error
+		})
+	}
+	fake.getAllowanceUsageContextReturnsOnCall[i] = struct {
+		result1 []eventio.AllowanceUsage
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetBillableEventRows(arg1 context.Context, arg2 eventio.EventFilter) (eventio.BillableEventRows, error) {
 	fake.getBillableEventRowsMutex.Lock()
 	ret, specificReturn := fake.getBillableEventRowsReturnsOnCall[len(fake.getBillableEventRowsArgsForCall)]
@@ -729,6 +1675,71 @@ func (fake *FakeEventStore) GetBillableEventsReturnsOnCall(i int, result1 []even
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetBillableEventsContext(arg1 context.Context, arg2 eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	fake.getBillableEventsContextMutex.Lock()
+	ret, specificReturn := fake.getBillableEventsContextReturnsOnCall[len(fake.getBillableEventsContextArgsForCall)]
+	fake.getBillableEventsContextArgsForCall = append(fake.getBillableEventsContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.GetBillableEventsContextStub
+	fakeReturns := fake.getBillableEventsContextReturns
+	fake.recordInvocation("GetBillableEventsContext", []interface{}{arg1, arg2})
+	fake.getBillableEventsContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetBillableEventsContextCallCount() int {
+	fake.getBillableEventsContextMutex.RLock()
+	defer fake.getBillableEventsContextMutex.RUnlock()
+	return len(fake.getBillableEventsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetBillableEventsContextCalls(stub func(context.Context, eventio.EventFilter) ([]eventio.BillableEvent, error)) {
+	fake.getBillableEventsContextMutex.Lock()
+	defer fake.getBillableEventsContextMutex.Unlock()
+	fake.GetBillableEventsContextStub = stub
+}
+
+func (fake *FakeEventStore) GetBillableEventsContextArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.getBillableEventsContextMutex.RLock()
+	defer fake.getBillableEventsContextMutex.RUnlock()
+	argsForCall := fake.getBillableEventsContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetBillableEventsContextReturns(result1 []eventio.BillableEvent, result2 error) {
+	fake.getBillableEventsContextMutex.Lock()
+	defer fake.getBillableEventsContextMutex.Unlock()
+	fake.GetBillableEventsContextStub = nil
+	fake.getBillableEventsContextReturns = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetBillableEventsContextReturnsOnCall(i int, result1 []eventio.BillableEvent, result2 error) {
+	fake.getBillableEventsContextMutex.Lock()
+	defer fake.getBillableEventsContextMutex.Unlock()
+	fake.GetBillableEventsContextStub = nil
+	if fake.getBillableEventsContextReturnsOnCall == nil {
+		fake.getBillableEventsContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.BillableEvent
+			result2 error
+		})
+	}
+	fake.getBillableEventsContextReturnsOnCall[i] = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetConsolidatedBillableEventRows(arg1 context.Context, arg2 eventio.EventFilter) (eventio.BillableEventRows, error) {
 	fake.getConsolidatedBillableEventRowsMutex.Lock()
 	ret, specificReturn := fake.getConsolidatedBillableEventRowsReturnsOnCall[len(fake.getConsolidatedBillableEventRowsArgsForCall)]
@@ -858,18 +1869,277 @@ func (fake *FakeEventStore) GetConsolidatedBillableEventsReturnsOnCall(i int, re
 	}{result1, result2}
 }
 
-func (fake *FakeEventStore) GetCurrencyRates(arg1 eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error) {
-	fake.getCurrencyRatesMutex.Lock()
-	ret, specificReturn := fake.getCurrencyRatesReturnsOnCall[len(fake.getCurrencyRatesArgsForCall)]
-	fake.getCurrencyRatesArgsForCall = append(fake.getCurrencyRatesArgsForCall, struct {
-		arg1 eventio.TimeRangeFilter
-	}{arg1})
-	stub := fake.GetCurrencyRatesStub
-	fakeReturns := fake.getCurrencyRatesReturns
-	fake.recordInvocation("GetCurrencyRates", []interface{}{arg1})
-	fake.getCurrencyRatesMutex.Unlock()
+func (fake *FakeEventStore) GetConsolidatedBillableEventsContext(arg1 context.Context, arg2 eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	fake.getConsolidatedBillableEventsContextMutex.Lock()
+	ret, specificReturn := fake.getConsolidatedBillableEventsContextReturnsOnCall[len(fake.getConsolidatedBillableEventsContextArgsForCall)]
+	fake.getConsolidatedBillableEventsContextArgsForCall = append(fake.getConsolidatedBillableEventsContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.GetConsolidatedBillableEventsContextStub
+	fakeReturns := fake.getConsolidatedBillableEventsContextReturns
+	fake.recordInvocation("GetConsolidatedBillableEventsContext", []interface{}{arg1, arg2})
+	fake.getConsolidatedBillableEventsContextMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetConsolidatedBillableEventsContextCallCount() int {
+	fake.getConsolidatedBillableEventsContextMutex.RLock()
+	defer fake.getConsolidatedBillableEventsContextMutex.RUnlock()
+	return len(fake.getConsolidatedBillableEventsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetConsolidatedBillableEventsContextCalls(stub func(context.Context, eventio.EventFilter) ([]eventio.BillableEvent, error)) {
+	fake.getConsolidatedBillableEventsContextMutex.Lock()
+	defer fake.getConsolidatedBillableEventsContextMutex.Unlock()
+	fake.GetConsolidatedBillableEventsContextStub = stub
+}
+
+func (fake *FakeEventStore) GetConsolidatedBillableEventsContextArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.getConsolidatedBillableEventsContextMutex.RLock()
+	defer fake.getConsolidatedBillableEventsContextMutex.RUnlock()
+	argsForCall := fake.getConsolidatedBillableEventsContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetConsolidatedBillableEventsContextReturns(result1 []eventio.BillableEvent, result2 error) {
+	fake.getConsolidatedBillableEventsContextMutex.Lock()
+	defer fake.getConsolidatedBillableEventsContextMutex.Unlock()
+	fake.GetConsolidatedBillableEventsContextStub = nil
+	fake.getConsolidatedBillableEventsContextReturns = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetConsolidatedBillableEventsContextReturnsOnCall(i int, result1 []eventio.BillableEvent, result2 error) {
+	fake.getConsolidatedBillableEventsContextMutex.Lock()
+	defer fake.getConsolidatedBillableEventsContextMutex.Unlock()
+	fake.GetConsolidatedBillableEventsContextStub = nil
+	if fake.getConsolidatedBillableEventsContextReturnsOnCall == nil {
+		fake.getConsolidatedBillableEventsContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.BillableEvent
+			result2 error
+		})
+	}
+	fake.getConsolidatedBillableEventsContextReturnsOnCall[i] = struct {
+		result1 []eventio.BillableEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetConsolidationHistory(arg1 eventio.EventFilter) ([]eventio.ConsolidationRecord, error) {
+	fake.getConsolidationHistoryMutex.Lock()
+	ret, specificReturn := fake.getConsolidationHistoryReturnsOnCall[len(fake.getConsolidationHistoryArgsForCall)]
+	fake.getConsolidationHistoryArgsForCall = append(fake.getConsolidationHistoryArgsForCall, struct {
+		arg1 eventio.EventFilter
+	}{arg1})
+	stub := fake.GetConsolidationHistoryStub
+	fakeReturns := fake.getConsolidationHistoryReturns
+	fake.recordInvocation("GetConsolidationHistory", []interface{}{arg1})
+	fake.getConsolidationHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryCallCount() int {
+	fake.getConsolidationHistoryMutex.RLock()
+	defer fake.getConsolidationHistoryMutex.RUnlock()
+	return len(fake.getConsolidationHistoryArgsForCall)
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryCalls(stub func(eventio.EventFilter) ([]eventio.ConsolidationRecord, error)) {
+	fake.getConsolidationHistoryMutex.Lock()
+	defer fake.getConsolidationHistoryMutex.Unlock()
+	fake.GetConsolidationHistoryStub = stub
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryArgsForCall(i int) eventio.EventFilter {
+	fake.getConsolidationHistoryMutex.RLock()
+	defer fake.getConsolidationHistoryMutex.RUnlock()
+	argsForCall := fake.getConsolidationHistoryArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryReturns(result1 []eventio.ConsolidationRecord, result2 error) {
+	fake.getConsolidationHistoryMutex.Lock()
+	defer fake.getConsolidationHistoryMutex.Unlock()
+	fake.GetConsolidationHistoryStub = nil
+	fake.getConsolidationHistoryReturns = struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryReturnsOnCall(i int, result1 []eventio.ConsolidationRecord, result2 error) {
+	fake.getConsolidationHistoryMutex.Lock()
+	defer fake.getConsolidationHistoryMutex.Unlock()
+	fake.GetConsolidationHistoryStub = nil
+	if fake.getConsolidationHistoryReturnsOnCall == nil {
+		fake.getConsolidationHistoryReturnsOnCall = make(map[int]struct {
+			result1 []eventio.ConsolidationRecord
+			result2 error
+		})
+	}
+	fake.getConsolidationHistoryReturnsOnCall[i] = struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryContext(arg1 context.Context, arg2 eventio.EventFilter) ([]eventio.ConsolidationRecord, error) {
+	fake.getConsolidationHistoryContextMutex.Lock()
+	ret, specificReturn := fake.getConsolidationHistoryContextReturnsOnCall[len(fake.getConsolidationHistoryContextArgsForCall)]
+	fake.getConsolidationHistoryContextArgsForCall = append(fake.getConsolidationHistoryContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.GetConsolidationHistoryContextStub
+	fakeReturns := fake.getConsolidationHistoryContextReturns
+	fake.recordInvocation("GetConsolidationHistoryContext", []interface{}{arg1, arg2})
+	fake.getConsolidationHistoryContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryContextCallCount() int {
+	fake.getConsolidationHistoryContextMutex.RLock()
+	defer fake.getConsolidationHistoryContextMutex.RUnlock()
+	return len(fake.getConsolidationHistoryContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryContextCalls(stub func(context.Context, eventio.EventFilter) ([]eventio.ConsolidationRecord, error)) {
+	fake.getConsolidationHistoryContextMutex.Lock()
+	defer fake.getConsolidationHistoryContextMutex.Unlock()
+	fake.GetConsolidationHistoryContextStub = stub
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryContextArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.getConsolidationHistoryContextMutex.RLock()
+	defer fake.getConsolidationHistoryContextMutex.RUnlock()
+	argsForCall := fake.getConsolidationHistoryContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryContextReturns(result1 []eventio.ConsolidationRecord, result2 error) {
+	fake.getConsolidationHistoryContextMutex.Lock()
+	defer fake.getConsolidationHistoryContextMutex.Unlock()
+	fake.GetConsolidationHistoryContextStub = nil
+	fake.getConsolidationHistoryContextReturns = struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetConsolidationHistoryContextReturnsOnCall(i int, result1 []eventio.ConsolidationRecord, result2 error) {
+	fake.getConsolidationHistoryContextMutex.Lock()
+	defer fake.getConsolidationHistoryContextMutex.Unlock()
+	fake.GetConsolidationHistoryContextStub = nil
+	if fake.getConsolidationHistoryContextReturnsOnCall == nil {
+		fake.getConsolidationHistoryContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.ConsolidationRecord
+			result2 error
+		})
+	}
+	fake.getConsolidationHistoryContextReturnsOnCall[i] = struct {
+		result1 []eventio.ConsolidationRecord
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetCostTimeSeriesRows(arg1 context.Context, arg2 eventio.CostTimeSeriesFilter) (eventio.CostTimeSeriesRows, error) {
+	fake.getCostTimeSeriesRowsMutex.Lock()
+	ret, specificReturn := fake.getCostTimeSeriesRowsReturnsOnCall[len(fake.getCostTimeSeriesRowsArgsForCall)]
+	fake.getCostTimeSeriesRowsArgsForCall = append(fake.getCostTimeSeriesRowsArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.CostTimeSeriesFilter
+	}{arg1, arg2})
+	stub := fake.GetCostTimeSeriesRowsStub
+	fakeReturns := fake.getCostTimeSeriesRowsReturns
+	fake.recordInvocation("GetCostTimeSeriesRows", []interface{}{arg1, arg2})
+	fake.getCostTimeSeriesRowsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetCostTimeSeriesRowsCallCount() int {
+	fake.getCostTimeSeriesRowsMutex.RLock()
+	defer fake.getCostTimeSeriesRowsMutex.RUnlock()
+	return len(fake.getCostTimeSeriesRowsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetCostTimeSeriesRowsCalls(stub func(context.Context, eventio.CostTimeSeriesFilter) (eventio.CostTimeSeriesRows, error)) {
+	fake.getCostTimeSeriesRowsMutex.Lock()
+	defer fake.getCostTimeSeriesRowsMutex.Unlock()
+	fake.GetCostTimeSeriesRowsStub = stub
+}
+
+func (fake *FakeEventStore) GetCostTimeSeriesRowsArgsForCall(i int) (context.Context, eventio.CostTimeSeriesFilter) {
+	fake.getCostTimeSeriesRowsMutex.RLock()
+	defer fake.getCostTimeSeriesRowsMutex.RUnlock()
+	argsForCall := fake.getCostTimeSeriesRowsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetCostTimeSeriesRowsReturns(result1 eventio.CostTimeSeriesRows, result2 error) {
+	fake.getCostTimeSeriesRowsMutex.Lock()
+	defer fake.getCostTimeSeriesRowsMutex.Unlock()
+	fake.GetCostTimeSeriesRowsStub = nil
+	fake.getCostTimeSeriesRowsReturns = struct {
+		result1 eventio.CostTimeSeriesRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetCostTimeSeriesRowsReturnsOnCall(i int, result1 eventio.CostTimeSeriesRows, result2 error) {
+	fake.getCostTimeSeriesRowsMutex.Lock()
+	defer fake.getCostTimeSeriesRowsMutex.Unlock()
+	fake.GetCostTimeSeriesRowsStub = nil
+	if fake.getCostTimeSeriesRowsReturnsOnCall == nil {
+		fake.getCostTimeSeriesRowsReturnsOnCall = make(map[int]struct {
+			result1 eventio.CostTimeSeriesRows
+			result2 error
+		})
+	}
+	fake.getCostTimeSeriesRowsReturnsOnCall[i] = struct {
+		result1 eventio.CostTimeSeriesRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetCurrencyRates(arg1 eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error) {
+	fake.getCurrencyRatesMutex.Lock()
+	ret, specificReturn := fake.getCurrencyRatesReturnsOnCall[len(fake.getCurrencyRatesArgsForCall)]
+	fake.getCurrencyRatesArgsForCall = append(fake.getCurrencyRatesArgsForCall, struct {
+		arg1 eventio.TimeRangeFilter
+	}{arg1})
+	stub := fake.GetCurrencyRatesStub
+	fakeReturns := fake.getCurrencyRatesReturns
+	fake.recordInvocation("GetCurrencyRates", []interface{}{arg1})
+	fake.getCurrencyRatesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -922,6 +2192,264 @@ func (fake *FakeEventStore) GetCurrencyRatesReturnsOnCall(i int, result1 []event
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetCurrencyRatesContext(arg1 context.Context, arg2 eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error) {
+	fake.getCurrencyRatesContextMutex.Lock()
+	ret, specificReturn := fake.getCurrencyRatesContextReturnsOnCall[len(fake.getCurrencyRatesContextArgsForCall)]
+	fake.getCurrencyRatesContextArgsForCall = append(fake.getCurrencyRatesContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}{arg1, arg2})
+	stub := fake.GetCurrencyRatesContextStub
+	fakeReturns := fake.getCurrencyRatesContextReturns
+	fake.recordInvocation("GetCurrencyRatesContext", []interface{}{arg1, arg2})
+	fake.getCurrencyRatesContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetCurrencyRatesContextCallCount() int {
+	fake.getCurrencyRatesContextMutex.RLock()
+	defer fake.getCurrencyRatesContextMutex.RUnlock()
+	return len(fake.getCurrencyRatesContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetCurrencyRatesContextCalls(stub func(context.Context, eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error)) {
+	fake.getCurrencyRatesContextMutex.Lock()
+	defer fake.getCurrencyRatesContextMutex.Unlock()
+	fake.GetCurrencyRatesContextStub = stub
+}
+
+func (fake *FakeEventStore) GetCurrencyRatesContextArgsForCall(i int) (context.Context, eventio.TimeRangeFilter) {
+	fake.getCurrencyRatesContextMutex.RLock()
+	defer fake.getCurrencyRatesContextMutex.RUnlock()
+	argsForCall := fake.getCurrencyRatesContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetCurrencyRatesContextReturns(result1 []eventio.CurrencyRate, result2 error) {
+	fake.getCurrencyRatesContextMutex.Lock()
+	defer fake.getCurrencyRatesContextMutex.Unlock()
+	fake.GetCurrencyRatesContextStub = nil
+	fake.getCurrencyRatesContextReturns = struct {
+		result1 []eventio.CurrencyRate
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetCurrencyRatesContextReturnsOnCall(i int, result1 []eventio.CurrencyRate, result2 error) {
+	fake.getCurrencyRatesContextMutex.Lock()
+	defer fake.getCurrencyRatesContextMutex.Unlock()
+	fake.GetCurrencyRatesContextStub = nil
+	if fake.getCurrencyRatesContextReturnsOnCall == nil {
+		fake.getCurrencyRatesContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.CurrencyRate
+			result2 error
+		})
+	}
+	fake.getCurrencyRatesContextReturnsOnCall[i] = struct {
+		result1 []eventio.CurrencyRate
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetDataQualityIssues(arg1 eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error) {
+	fake.getDataQualityIssuesMutex.Lock()
+	ret, specificReturn := fake.getDataQualityIssuesReturnsOnCall[len(fake.getDataQualityIssuesArgsForCall)]
+	fake.getDataQualityIssuesArgsForCall = append(fake.getDataQualityIssuesArgsForCall, struct {
+		arg1 eventio.DataQualityFilter
+	}{arg1})
+	stub := fake.GetDataQualityIssuesStub
+	fakeReturns := fake.getDataQualityIssuesReturns
+	fake.recordInvocation("GetDataQualityIssues", []interface{}{arg1})
+	fake.getDataQualityIssuesMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesCallCount() int {
+	fake.getDataQualityIssuesMutex.RLock()
+	defer fake.getDataQualityIssuesMutex.RUnlock()
+	return len(fake.getDataQualityIssuesArgsForCall)
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesCalls(stub func(eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error)) {
+	fake.getDataQualityIssuesMutex.Lock()
+	defer fake.getDataQualityIssuesMutex.Unlock()
+	fake.GetDataQualityIssuesStub = stub
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesArgsForCall(i int) eventio.DataQualityFilter {
+	fake.getDataQualityIssuesMutex.RLock()
+	defer fake.getDataQualityIssuesMutex.RUnlock()
+	argsForCall := fake.getDataQualityIssuesArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesReturns(result1 []eventio.DataQualityIssue, result2 error) {
+	fake.getDataQualityIssuesMutex.Lock()
+	defer fake.getDataQualityIssuesMutex.Unlock()
+	fake.GetDataQualityIssuesStub = nil
+	fake.getDataQualityIssuesReturns = struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesReturnsOnCall(i int, result1 []eventio.DataQualityIssue, result2 error) {
+	fake.getDataQualityIssuesMutex.Lock()
+	defer fake.getDataQualityIssuesMutex.Unlock()
+	fake.GetDataQualityIssuesStub = nil
+	if fake.getDataQualityIssuesReturnsOnCall == nil {
+		fake.getDataQualityIssuesReturnsOnCall = make(map[int]struct {
+			result1 []eventio.DataQualityIssue
+			result2 error
+		})
+	}
+	fake.getDataQualityIssuesReturnsOnCall[i] = struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesContext(arg1 context.Context, arg2 eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error) {
+	fake.getDataQualityIssuesContextMutex.Lock()
+	ret, specificReturn := fake.getDataQualityIssuesContextReturnsOnCall[len(fake.getDataQualityIssuesContextArgsForCall)]
+	fake.getDataQualityIssuesContextArgsForCall = append(fake.getDataQualityIssuesContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.DataQualityFilter
+	}{arg1, arg2})
+	stub := fake.GetDataQualityIssuesContextStub
+	fakeReturns := fake.getDataQualityIssuesContextReturns
+	fake.recordInvocation("GetDataQualityIssuesContext", []interface{}{arg1, arg2})
+	fake.getDataQualityIssuesContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesContextCallCount() int {
+	fake.getDataQualityIssuesContextMutex.RLock()
+	defer fake.getDataQualityIssuesContextMutex.RUnlock()
+	return len(fake.getDataQualityIssuesContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesContextCalls(stub func(context.Context, eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error)) {
+	fake.getDataQualityIssuesContextMutex.Lock()
+	defer fake.getDataQualityIssuesContextMutex.Unlock()
+	fake.GetDataQualityIssuesContextStub = stub
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesContextArgsForCall(i int) (context.Context, eventio.DataQualityFilter) {
+	fake.getDataQualityIssuesContextMutex.RLock()
+	defer fake.getDataQualityIssuesContextMutex.RUnlock()
+	argsForCall := fake.getDataQualityIssuesContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesContextReturns(result1 []eventio.DataQualityIssue, result2 error) {
+	fake.getDataQualityIssuesContextMutex.Lock()
+	defer fake.getDataQualityIssuesContextMutex.Unlock()
+	fake.GetDataQualityIssuesContextStub = nil
+	fake.getDataQualityIssuesContextReturns = struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetDataQualityIssuesContextReturnsOnCall(i int, result1 []eventio.DataQualityIssue, result2 error) {
+	fake.getDataQualityIssuesContextMutex.Lock()
+	defer fake.getDataQualityIssuesContextMutex.Unlock()
+	fake.GetDataQualityIssuesContextStub = nil
+	if fake.getDataQualityIssuesContextReturnsOnCall == nil {
+		fake.getDataQualityIssuesContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.DataQualityIssue
+			result2 error
+		})
+	}
+	fake.getDataQualityIssuesContextReturnsOnCall[i] = struct {
+		result1 []eventio.DataQualityIssue
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetEgressOffset(arg1 string) (int64, error) {
+	fake.getEgressOffsetMutex.Lock()
+	ret, specificReturn := fake.getEgressOffsetReturnsOnCall[len(fake.getEgressOffsetArgsForCall)]
+	fake.getEgressOffsetArgsForCall = append(fake.getEgressOffsetArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.GetEgressOffsetStub
+	fakeReturns := fake.getEgressOffsetReturns
+	fake.recordInvocation("GetEgressOffset", []interface{}{arg1})
+	fake.getEgressOffsetMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetEgressOffsetCallCount() int {
+	fake.getEgressOffsetMutex.RLock()
+	defer fake.getEgressOffsetMutex.RUnlock()
+	return len(fake.getEgressOffsetArgsForCall)
+}
+
+func (fake *FakeEventStore) GetEgressOffsetCalls(stub func(string) (int64, error)) {
+	fake.getEgressOffsetMutex.Lock()
+	defer fake.getEgressOffsetMutex.Unlock()
+	fake.GetEgressOffsetStub = stub
+}
+
+func (fake *FakeEventStore) GetEgressOffsetArgsForCall(i int) string {
+	fake.getEgressOffsetMutex.RLock()
+	defer fake.getEgressOffsetMutex.RUnlock()
+	argsForCall := fake.getEgressOffsetArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetEgressOffsetReturns(result1 int64, result2 error) {
+	fake.getEgressOffsetMutex.Lock()
+	defer fake.getEgressOffsetMutex.Unlock()
+	fake.GetEgressOffsetStub = nil
+	fake.getEgressOffsetReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetEgressOffsetReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.getEgressOffsetMutex.Lock()
+	defer fake.getEgressOffsetMutex.Unlock()
+	fake.GetEgressOffsetStub = nil
+	if fake.getEgressOffsetReturnsOnCall == nil {
+		fake.getEgressOffsetReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.getEgressOffsetReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetEvents(arg1 eventio.RawEventFilter) ([]eventio.RawEvent, error) {
 	fake.getEventsMutex.Lock()
 	ret, specificReturn := fake.getEventsReturnsOnCall[len(fake.getEventsArgsForCall)]
@@ -986,15 +2514,330 @@ func (fake *FakeEventStore) GetEventsReturnsOnCall(i int, result1 []eventio.RawE
 	}{result1, result2}
 }
 
-func (fake *FakeEventStore) GetPricingPlans(arg1 eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
-	fake.getPricingPlansMutex.Lock()
-	ret, specificReturn := fake.getPricingPlansReturnsOnCall[len(fake.getPricingPlansArgsForCall)]
-	fake.getPricingPlansArgsForCall = append(fake.getPricingPlansArgsForCall, struct {
-		arg1 eventio.TimeRangeFilter
-	}{arg1})
-	stub := fake.GetPricingPlansStub
-	fakeReturns := fake.getPricingPlansReturns
-	fake.recordInvocation("GetPricingPlans", []interface{}{arg1})
+func (fake *FakeEventStore) GetEventsContext(arg1 context.Context, arg2 eventio.RawEventFilter) ([]eventio.RawEvent, error) {
+	fake.getEventsContextMutex.Lock()
+	ret, specificReturn := fake.getEventsContextReturnsOnCall[len(fake.getEventsContextArgsForCall)]
+	fake.getEventsContextArgsForCall = append(fake.getEventsContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.RawEventFilter
+	}{arg1, arg2})
+	stub := fake.GetEventsContextStub
+	fakeReturns := fake.getEventsContextReturns
+	fake.recordInvocation("GetEventsContext", []interface{}{arg1, arg2})
+	fake.getEventsContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetEventsContextCallCount() int {
+	fake.getEventsContextMutex.RLock()
+	defer fake.getEventsContextMutex.RUnlock()
+	return len(fake.getEventsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetEventsContextCalls(stub func(context.Context, eventio.RawEventFilter) ([]eventio.RawEvent, error)) {
+	fake.getEventsContextMutex.Lock()
+	defer fake.getEventsContextMutex.Unlock()
+	fake.GetEventsContextStub = stub
+}
+
+func (fake *FakeEventStore) GetEventsContextArgsForCall(i int) (context.Context, eventio.RawEventFilter) {
+	fake.getEventsContextMutex.RLock()
+	defer fake.getEventsContextMutex.RUnlock()
+	argsForCall := fake.getEventsContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetEventsContextReturns(result1 []eventio.RawEvent, result2 error) {
+	fake.getEventsContextMutex.Lock()
+	defer fake.getEventsContextMutex.Unlock()
+	fake.GetEventsContextStub = nil
+	fake.getEventsContextReturns = struct {
+		result1 []eventio.RawEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetEventsContextReturnsOnCall(i int, result1 []eventio.RawEvent, result2 error) {
+	fake.getEventsContextMutex.Lock()
+	defer fake.getEventsContextMutex.Unlock()
+	fake.GetEventsContextStub = nil
+	if fake.getEventsContextReturnsOnCall == nil {
+		fake.getEventsContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.RawEvent
+			result2 error
+		})
+	}
+	fake.getEventsContextReturnsOnCall[i] = struct {
+		result1 []eventio.RawEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetExemptions() ([]eventio.Exemption, error) {
+	fake.getExemptionsMutex.Lock()
+	ret, specificReturn := fake.getExemptionsReturnsOnCall[len(fake.getExemptionsArgsForCall)]
+	fake.getExemptionsArgsForCall = append(fake.getExemptionsArgsForCall, struct {
+	}{})
+	stub := fake.GetExemptionsStub
+	fakeReturns := fake.getExemptionsReturns
+	fake.recordInvocation("GetExemptions", []interface{}{})
+	fake.getExemptionsMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetExemptionsCallCount() int {
+	fake.getExemptionsMutex.RLock()
+	defer fake.getExemptionsMutex.RUnlock()
+	return len(fake.getExemptionsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetExemptionsCalls(stub func() ([]eventio.Exemption, error)) {
+	fake.getExemptionsMutex.Lock()
+	defer fake.getExemptionsMutex.Unlock()
+	fake.GetExemptionsStub = stub
+}
+
+func (fake *FakeEventStore) GetExemptionsReturns(result1 []eventio.Exemption, result2 error) {
+	fake.getExemptionsMutex.Lock()
+	defer fake.getExemptionsMutex.Unlock()
+	fake.GetExemptionsStub = nil
+	fake.getExemptionsReturns = struct {
+		result1 []eventio.Exemption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetExemptionsReturnsOnCall(i int, result1 []eventio.Exemption, result2 error) {
+	fake.getExemptionsMutex.Lock()
+	defer fake.getExemptionsMutex.Unlock()
+	fake.GetExemptionsStub = nil
+	if fake.getExemptionsReturnsOnCall == nil {
+		fake.getExemptionsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Exemption
+			result2 error
+		})
+	}
+	fake.getExemptionsReturnsOnCall[i] = struct {
+		result1 []eventio.Exemption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetExemptionsContext(arg1 context.Context) ([]eventio.Exemption, error) {
+	fake.getExemptionsContextMutex.Lock()
+	ret, specificReturn := fake.getExemptionsContextReturnsOnCall[len(fake.getExemptionsContextArgsForCall)]
+	fake.getExemptionsContextArgsForCall = append(fake.getExemptionsContextArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetExemptionsContextStub
+	fakeReturns := fake.getExemptionsContextReturns
+	fake.recordInvocation("GetExemptionsContext", []interface{}{arg1})
+	fake.getExemptionsContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetExemptionsContextCallCount() int {
+	fake.getExemptionsContextMutex.RLock()
+	defer fake.getExemptionsContextMutex.RUnlock()
+	return len(fake.getExemptionsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetExemptionsContextCalls(stub func(context.Context) ([]eventio.Exemption, error)) {
+	fake.getExemptionsContextMutex.Lock()
+	defer fake.getExemptionsContextMutex.Unlock()
+	fake.GetExemptionsContextStub = stub
+}
+
+func (fake *FakeEventStore) GetExemptionsContextArgsForCall(i int) context.Context {
+	fake.getExemptionsContextMutex.RLock()
+	defer fake.getExemptionsContextMutex.RUnlock()
+	argsForCall := fake.getExemptionsContextArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetExemptionsContextReturns(result1 []eventio.Exemption, result2 error) {
+	fake.getExemptionsContextMutex.Lock()
+	defer fake.getExemptionsContextMutex.Unlock()
+	fake.GetExemptionsContextStub = nil
+	fake.getExemptionsContextReturns = struct {
+		result1 []eventio.Exemption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetExemptionsContextReturnsOnCall(i int, result1 []eventio.Exemption, result2 error) {
+	fake.getExemptionsContextMutex.Lock()
+	defer fake.getExemptionsContextMutex.Unlock()
+	fake.GetExemptionsContextStub = nil
+	if fake.getExemptionsContextReturnsOnCall == nil {
+		fake.getExemptionsContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Exemption
+			result2 error
+		})
+	}
+	fake.getExemptionsContextReturnsOnCall[i] = struct {
+		result1 []eventio.Exemption
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetFootprintEventRows(arg1 context.Context, arg2 eventio.EventFilter) (eventio.FootprintEventRows, error) {
+	fake.getFootprintEventRowsMutex.Lock()
+	ret, specificReturn := fake.getFootprintEventRowsReturnsOnCall[len(fake.getFootprintEventRowsArgsForCall)]
+	fake.getFootprintEventRowsArgsForCall = append(fake.getFootprintEventRowsArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.GetFootprintEventRowsStub
+	fakeReturns := fake.getFootprintEventRowsReturns
+	fake.recordInvocation("GetFootprintEventRows", []interface{}{arg1, arg2})
+	fake.getFootprintEventRowsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetFootprintEventRowsCallCount() int {
+	fake.getFootprintEventRowsMutex.RLock()
+	defer fake.getFootprintEventRowsMutex.RUnlock()
+	return len(fake.getFootprintEventRowsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetFootprintEventRowsCalls(stub func(context.Context, eventio.EventFilter) (eventio.FootprintEventRows, error)) {
+	fake.getFootprintEventRowsMutex.Lock()
+	defer fake.getFootprintEventRowsMutex.Unlock()
+	fake.GetFootprintEventRowsStub = stub
+}
+
+func (fake *FakeEventStore) GetFootprintEventRowsArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.getFootprintEventRowsMutex.RLock()
+	defer fake.getFootprintEventRowsMutex.RUnlock()
+	argsForCall := fake.getFootprintEventRowsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetFootprintEventRowsReturns(result1 eventio.FootprintEventRows, result2 error) {
+	fake.getFootprintEventRowsMutex.Lock()
+	defer fake.getFootprintEventRowsMutex.Unlock()
+	fake.GetFootprintEventRowsStub = nil
+	fake.getFootprintEventRowsReturns = struct {
+		result1 eventio.FootprintEventRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetFootprintEventRowsReturnsOnCall(i int, result1 eventio.FootprintEventRows, result2 error) {
+	fake.getFootprintEventRowsMutex.Lock()
+	defer fake.getFootprintEventRowsMutex.Unlock()
+	fake.GetFootprintEventRowsStub = nil
+	if fake.getFootprintEventRowsReturnsOnCall == nil {
+		fake.getFootprintEventRowsReturnsOnCall = make(map[int]struct {
+			result1 eventio.FootprintEventRows
+			result2 error
+		})
+	}
+	fake.getFootprintEventRowsReturnsOnCall[i] = struct {
+		result1 eventio.FootprintEventRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetFootprintPlans(arg1 context.Context, arg2 eventio.TimeRangeFilter) ([]eventio.FootprintPlan, error) {
+	fake.getFootprintPlansMutex.Lock()
+	ret, specificReturn := fake.getFootprintPlansReturnsOnCall[len(fake.getFootprintPlansArgsForCall)]
+	fake.getFootprintPlansArgsForCall = append(fake.getFootprintPlansArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}{arg1, arg2})
+	stub := fake.GetFootprintPlansStub
+	fakeReturns := fake.getFootprintPlansReturns
+	fake.recordInvocation("GetFootprintPlans", []interface{}{arg1, arg2})
+	fake.getFootprintPlansMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetFootprintPlansCallCount() int {
+	fake.getFootprintPlansMutex.RLock()
+	defer fake.getFootprintPlansMutex.RUnlock()
+	return len(fake.getFootprintPlansArgsForCall)
+}
+
+func (fake *FakeEventStore) GetFootprintPlansCalls(stub func(context.Context, eventio.TimeRangeFilter) ([]eventio.FootprintPlan, error)) {
+	fake.getFootprintPlansMutex.Lock()
+	defer fake.getFootprintPlansMutex.Unlock()
+	fake.GetFootprintPlansStub = stub
+}
+
+func (fake *FakeEventStore) GetFootprintPlansArgsForCall(i int) (context.Context, eventio.TimeRangeFilter) {
+	fake.getFootprintPlansMutex.RLock()
+	defer fake.getFootprintPlansMutex.RUnlock()
+	argsForCall := fake.getFootprintPlansArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetFootprintPlansReturns(result1 []eventio.FootprintPlan, result2 error) {
+	fake.getFootprintPlansMutex.Lock()
+	defer fake.getFootprintPlansMutex.Unlock()
+	fake.GetFootprintPlansStub = nil
+	fake.getFootprintPlansReturns = struct {
+		result1 []eventio.FootprintPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetFootprintPlansReturnsOnCall(i int, result1 []eventio.FootprintPlan, result2 error) {
+	fake.getFootprintPlansMutex.Lock()
+	defer fake.getFootprintPlansMutex.Unlock()
+	fake.GetFootprintPlansStub = nil
+	if fake.getFootprintPlansReturnsOnCall == nil {
+		fake.getFootprintPlansReturnsOnCall = make(map[int]struct {
+			result1 []eventio.FootprintPlan
+			result2 error
+		})
+	}
+	fake.getFootprintPlansReturnsOnCall[i] = struct {
+		result1 []eventio.FootprintPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetPricingPlans(arg1 eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
+	fake.getPricingPlansMutex.Lock()
+	ret, specificReturn := fake.getPricingPlansReturnsOnCall[len(fake.getPricingPlansArgsForCall)]
+	fake.getPricingPlansArgsForCall = append(fake.getPricingPlansArgsForCall, struct {
+		arg1 eventio.TimeRangeFilter
+	}{arg1})
+	stub := fake.GetPricingPlansStub
+	fakeReturns := fake.getPricingPlansReturns
+	fake.recordInvocation("GetPricingPlans", []interface{}{arg1})
 	fake.getPricingPlansMutex.Unlock()
 	if stub != nil {
 		return stub(arg1)
@@ -1011,56 +2854,685 @@ func (fake *FakeEventStore) GetPricingPlansCallCount() int {
 	return len(fake.getPricingPlansArgsForCall)
 }
 
-func (fake *FakeEventStore) GetPricingPlansCalls(stub func(eventio.TimeRangeFilter) ([]eventio.PricingPlan, error)) {
-	fake.getPricingPlansMutex.Lock()
-	defer fake.getPricingPlansMutex.Unlock()
-	fake.GetPricingPlansStub = stub
+func (fake *FakeEventStore) GetPricingPlansCalls(stub func(eventio.TimeRangeFilter) ([]eventio.PricingPlan, error)) {
+	fake.getPricingPlansMutex.Lock()
+	defer fake.getPricingPlansMutex.Unlock()
+	fake.GetPricingPlansStub = stub
+}
+
+func (fake *FakeEventStore) GetPricingPlansArgsForCall(i int) eventio.TimeRangeFilter {
+	fake.getPricingPlansMutex.RLock()
+	defer fake.getPricingPlansMutex.RUnlock()
+	argsForCall := fake.getPricingPlansArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetPricingPlansReturns(result1 []eventio.PricingPlan, result2 error) {
+	fake.getPricingPlansMutex.Lock()
+	defer fake.getPricingPlansMutex.Unlock()
+	fake.GetPricingPlansStub = nil
+	fake.getPricingPlansReturns = struct {
+		result1 []eventio.PricingPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetPricingPlansReturnsOnCall(i int, result1 []eventio.PricingPlan, result2 error) {
+	fake.getPricingPlansMutex.Lock()
+	defer fake.getPricingPlansMutex.Unlock()
+	fake.GetPricingPlansStub = nil
+	if fake.getPricingPlansReturnsOnCall == nil {
+		fake.getPricingPlansReturnsOnCall = make(map[int]struct {
+			result1 []eventio.PricingPlan
+			result2 error
+		})
+	}
+	fake.getPricingPlansReturnsOnCall[i] = struct {
+		result1 []eventio.PricingPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetPricingPlansContext(arg1 context.Context, arg2 eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
+	fake.getPricingPlansContextMutex.Lock()
+	ret, specificReturn := fake.getPricingPlansContextReturnsOnCall[len(fake.getPricingPlansContextArgsForCall)]
+	fake.getPricingPlansContextArgsForCall = append(fake.getPricingPlansContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}{arg1, arg2})
+	stub := fake.GetPricingPlansContextStub
+	fakeReturns := fake.getPricingPlansContextReturns
+	fake.recordInvocation("GetPricingPlansContext", []interface{}{arg1, arg2})
+	fake.getPricingPlansContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetPricingPlansContextCallCount() int {
+	fake.getPricingPlansContextMutex.RLock()
+	defer fake.getPricingPlansContextMutex.RUnlock()
+	return len(fake.getPricingPlansContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetPricingPlansContextCalls(stub func(context.Context, eventio.TimeRangeFilter) ([]eventio.PricingPlan, error)) {
+	fake.getPricingPlansContextMutex.Lock()
+	defer fake.getPricingPlansContextMutex.Unlock()
+	fake.GetPricingPlansContextStub = stub
+}
+
+func (fake *FakeEventStore) GetPricingPlansContextArgsForCall(i int) (context.Context, eventio.TimeRangeFilter) {
+	fake.getPricingPlansContextMutex.RLock()
+	defer fake.getPricingPlansContextMutex.RUnlock()
+	argsForCall := fake.getPricingPlansContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetPricingPlansContextReturns(result1 []eventio.PricingPlan, result2 error) {
+	fake.getPricingPlansContextMutex.Lock()
+	defer fake.getPricingPlansContextMutex.Unlock()
+	fake.GetPricingPlansContextStub = nil
+	fake.getPricingPlansContextReturns = struct {
+		result1 []eventio.PricingPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetPricingPlansContextReturnsOnCall(i int, result1 []eventio.PricingPlan, result2 error) {
+	fake.getPricingPlansContextMutex.Lock()
+	defer fake.getPricingPlansContextMutex.Unlock()
+	fake.GetPricingPlansContextStub = nil
+	if fake.getPricingPlansContextReturnsOnCall == nil {
+		fake.getPricingPlansContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.PricingPlan
+			result2 error
+		})
+	}
+	fake.getPricingPlansContextReturnsOnCall[i] = struct {
+		result1 []eventio.PricingPlan
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetResourceHistory(arg1 eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error) {
+	fake.getResourceHistoryMutex.Lock()
+	ret, specificReturn := fake.getResourceHistoryReturnsOnCall[len(fake.getResourceHistoryArgsForCall)]
+	fake.getResourceHistoryArgsForCall = append(fake.getResourceHistoryArgsForCall, struct {
+		arg1 eventio.ResourceHistoryFilter
+	}{arg1})
+	stub := fake.GetResourceHistoryStub
+	fakeReturns := fake.getResourceHistoryReturns
+	fake.recordInvocation("GetResourceHistory", []interface{}{arg1})
+	fake.getResourceHistoryMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetResourceHistoryCallCount() int {
+	fake.getResourceHistoryMutex.RLock()
+	defer fake.getResourceHistoryMutex.RUnlock()
+	return len(fake.getResourceHistoryArgsForCall)
+}
+
+func (fake *FakeEventStore) GetResourceHistoryCalls(stub func(eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error)) {
+	fake.getResourceHistoryMutex.Lock()
+	defer fake.getResourceHistoryMutex.Unlock()
+	fake.GetResourceHistoryStub = stub
+}
+
+func (fake *FakeEventStore) GetResourceHistoryArgsForCall(i int) eventio.ResourceHistoryFilter {
+	fake.getResourceHistoryMutex.RLock()
+	defer fake.getResourceHistoryMutex.RUnlock()
+	argsForCall := fake.getResourceHistoryArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetResourceHistoryReturns(result1 *eventio.ResourceHistory, result2 error) {
+	fake.getResourceHistoryMutex.Lock()
+	defer fake.getResourceHistoryMutex.Unlock()
+	fake.GetResourceHistoryStub = nil
+	fake.getResourceHistoryReturns = struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetResourceHistoryReturnsOnCall(i int, result1 *eventio.ResourceHistory, result2 error) {
+	fake.getResourceHistoryMutex.Lock()
+	defer fake.getResourceHistoryMutex.Unlock()
+	fake.GetResourceHistoryStub = nil
+	if fake.getResourceHistoryReturnsOnCall == nil {
+		fake.getResourceHistoryReturnsOnCall = make(map[int]struct {
+			result1 *eventio.ResourceHistory
+			result2 error
+		})
+	}
+	fake.getResourceHistoryReturnsOnCall[i] = struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetResourceHistoryContext(arg1 context.Context, arg2 eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error) {
+	fake.getResourceHistoryContextMutex.Lock()
+	ret, specificReturn := fake.getResourceHistoryContextReturnsOnCall[len(fake.getResourceHistoryContextArgsForCall)]
+	fake.getResourceHistoryContextArgsForCall = append(fake.getResourceHistoryContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.ResourceHistoryFilter
+	}{arg1, arg2})
+	stub := fake.GetResourceHistoryContextStub
+	fakeReturns := fake.getResourceHistoryContextReturns
+	fake.recordInvocation("GetResourceHistoryContext", []interface{}{arg1, arg2})
+	fake.getResourceHistoryContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetResourceHistoryContextCallCount() int {
+	fake.getResourceHistoryContextMutex.RLock()
+	defer fake.getResourceHistoryContextMutex.RUnlock()
+	return len(fake.getResourceHistoryContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetResourceHistoryContextCalls(stub func(context.Context, eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error)) {
+	fake.getResourceHistoryContextMutex.Lock()
+	defer fake.getResourceHistoryContextMutex.Unlock()
+	fake.GetResourceHistoryContextStub = stub
+}
+
+func (fake *FakeEventStore) GetResourceHistoryContextArgsForCall(i int) (context.Context, eventio.ResourceHistoryFilter) {
+	fake.getResourceHistoryContextMutex.RLock()
+	defer fake.getResourceHistoryContextMutex.RUnlock()
+	argsForCall := fake.getResourceHistoryContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetResourceHistoryContextReturns(result1 *eventio.ResourceHistory, result2 error) {
+	fake.getResourceHistoryContextMutex.Lock()
+	defer fake.getResourceHistoryContextMutex.Unlock()
+	fake.GetResourceHistoryContextStub = nil
+	fake.getResourceHistoryContextReturns = struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetResourceHistoryContextReturnsOnCall(i int, result1 *eventio.ResourceHistory, result2 error) {
+	fake.getResourceHistoryContextMutex.Lock()
+	defer fake.getResourceHistoryContextMutex.Unlock()
+	fake.GetResourceHistoryContextStub = nil
+	if fake.getResourceHistoryContextReturnsOnCall == nil {
+		fake.getResourceHistoryContextReturnsOnCall = make(map[int]struct {
+			result1 *eventio.ResourceHistory
+			result2 error
+		})
+	}
+	fake.getResourceHistoryContextReturnsOnCall[i] = struct {
+		result1 *eventio.ResourceHistory
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatement(arg1 int64) (*eventio.Statement, error) {
+	fake.getStatementMutex.Lock()
+	ret, specificReturn := fake.getStatementReturnsOnCall[len(fake.getStatementArgsForCall)]
+	fake.getStatementArgsForCall = append(fake.getStatementArgsForCall, struct {
+		arg1 int64
+	}{arg1})
+	stub := fake.GetStatementStub
+	fakeReturns := fake.getStatementReturns
+	fake.recordInvocation("GetStatement", []interface{}{arg1})
+	fake.getStatementMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetStatementCallCount() int {
+	fake.getStatementMutex.RLock()
+	defer fake.getStatementMutex.RUnlock()
+	return len(fake.getStatementArgsForCall)
+}
+
+func (fake *FakeEventStore) GetStatementCalls(stub func(int64) (*eventio.Statement, error)) {
+	fake.getStatementMutex.Lock()
+	defer fake.getStatementMutex.Unlock()
+	fake.GetStatementStub = stub
+}
+
+func (fake *FakeEventStore) GetStatementArgsForCall(i int) int64 {
+	fake.getStatementMutex.RLock()
+	defer fake.getStatementMutex.RUnlock()
+	argsForCall := fake.getStatementArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetStatementReturns(result1 *eventio.Statement, result2 error) {
+	fake.getStatementMutex.Lock()
+	defer fake.getStatementMutex.Unlock()
+	fake.GetStatementStub = nil
+	fake.getStatementReturns = struct {
+		result1 *eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatementReturnsOnCall(i int, result1 *eventio.Statement, result2 error) {
+	fake.getStatementMutex.Lock()
+	defer fake.getStatementMutex.Unlock()
+	fake.GetStatementStub = nil
+	if fake.getStatementReturnsOnCall == nil {
+		fake.getStatementReturnsOnCall = make(map[int]struct {
+			result1 *eventio.Statement
+			result2 error
+		})
+	}
+	fake.getStatementReturnsOnCall[i] = struct {
+		result1 *eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatementContext(arg1 context.Context, arg2 int64) (*eventio.Statement, error) {
+	fake.getStatementContextMutex.Lock()
+	ret, specificReturn := fake.getStatementContextReturnsOnCall[len(fake.getStatementContextArgsForCall)]
+	fake.getStatementContextArgsForCall = append(fake.getStatementContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 int64
+	}{arg1, arg2})
+	stub := fake.GetStatementContextStub
+	fakeReturns := fake.getStatementContextReturns
+	fake.recordInvocation("GetStatementContext", []interface{}{arg1, arg2})
+	fake.getStatementContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetStatementContextCallCount() int {
+	fake.getStatementContextMutex.RLock()
+	defer fake.getStatementContextMutex.RUnlock()
+	return len(fake.getStatementContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetStatementContextCalls(stub func(context.Context, int64) (*eventio.Statement, error)) {
+	fake.getStatementContextMutex.Lock()
+	defer fake.getStatementContextMutex.Unlock()
+	fake.GetStatementContextStub = stub
+}
+
+func (fake *FakeEventStore) GetStatementContextArgsForCall(i int) (context.Context, int64) {
+	fake.getStatementContextMutex.RLock()
+	defer fake.getStatementContextMutex.RUnlock()
+	argsForCall := fake.getStatementContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetStatementContextReturns(result1 *eventio.Statement, result2 error) {
+	fake.getStatementContextMutex.Lock()
+	defer fake.getStatementContextMutex.Unlock()
+	fake.GetStatementContextStub = nil
+	fake.getStatementContextReturns = struct {
+		result1 *eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatementContextReturnsOnCall(i int, result1 *eventio.Statement, result2 error) {
+	fake.getStatementContextMutex.Lock()
+	defer fake.getStatementContextMutex.Unlock()
+	fake.GetStatementContextStub = nil
+	if fake.getStatementContextReturnsOnCall == nil {
+		fake.getStatementContextReturnsOnCall = make(map[int]struct {
+			result1 *eventio.Statement
+			result2 error
+		})
+	}
+	fake.getStatementContextReturnsOnCall[i] = struct {
+		result1 *eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatements(arg1 eventio.StatementFilter) ([]eventio.Statement, error) {
+	fake.getStatementsMutex.Lock()
+	ret, specificReturn := fake.getStatementsReturnsOnCall[len(fake.getStatementsArgsForCall)]
+	fake.getStatementsArgsForCall = append(fake.getStatementsArgsForCall, struct {
+		arg1 eventio.StatementFilter
+	}{arg1})
+	stub := fake.GetStatementsStub
+	fakeReturns := fake.getStatementsReturns
+	fake.recordInvocation("GetStatements", []interface{}{arg1})
+	fake.getStatementsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetStatementsCallCount() int {
+	fake.getStatementsMutex.RLock()
+	defer fake.getStatementsMutex.RUnlock()
+	return len(fake.getStatementsArgsForCall)
+}
+
+func (fake *FakeEventStore) GetStatementsCalls(stub func(eventio.StatementFilter) ([]eventio.Statement, error)) {
+	fake.getStatementsMutex.Lock()
+	defer fake.getStatementsMutex.Unlock()
+	fake.GetStatementsStub = stub
+}
+
+func (fake *FakeEventStore) GetStatementsArgsForCall(i int) eventio.StatementFilter {
+	fake.getStatementsMutex.RLock()
+	defer fake.getStatementsMutex.RUnlock()
+	argsForCall := fake.getStatementsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetStatementsReturns(result1 []eventio.Statement, result2 error) {
+	fake.getStatementsMutex.Lock()
+	defer fake.getStatementsMutex.Unlock()
+	fake.GetStatementsStub = nil
+	fake.getStatementsReturns = struct {
+		result1 []eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatementsReturnsOnCall(i int, result1 []eventio.Statement, result2 error) {
+	fake.getStatementsMutex.Lock()
+	defer fake.getStatementsMutex.Unlock()
+	fake.GetStatementsStub = nil
+	if fake.getStatementsReturnsOnCall == nil {
+		fake.getStatementsReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Statement
+			result2 error
+		})
+	}
+	fake.getStatementsReturnsOnCall[i] = struct {
+		result1 []eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatementsContext(arg1 context.Context, arg2 eventio.StatementFilter) ([]eventio.Statement, error) {
+	fake.getStatementsContextMutex.Lock()
+	ret, specificReturn := fake.getStatementsContextReturnsOnCall[len(fake.getStatementsContextArgsForCall)]
+	fake.getStatementsContextArgsForCall = append(fake.getStatementsContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.StatementFilter
+	}{arg1, arg2})
+	stub := fake.GetStatementsContextStub
+	fakeReturns := fake.getStatementsContextReturns
+	fake.recordInvocation("GetStatementsContext", []interface{}{arg1, arg2})
+	fake.getStatementsContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetStatementsContextCallCount() int {
+	fake.getStatementsContextMutex.RLock()
+	defer fake.getStatementsContextMutex.RUnlock()
+	return len(fake.getStatementsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetStatementsContextCalls(stub func(context.Context, eventio.StatementFilter) ([]eventio.Statement, error)) {
+	fake.getStatementsContextMutex.Lock()
+	defer fake.getStatementsContextMutex.Unlock()
+	fake.GetStatementsContextStub = stub
+}
+
+func (fake *FakeEventStore) GetStatementsContextArgsForCall(i int) (context.Context, eventio.StatementFilter) {
+	fake.getStatementsContextMutex.RLock()
+	defer fake.getStatementsContextMutex.RUnlock()
+	argsForCall := fake.getStatementsContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetStatementsContextReturns(result1 []eventio.Statement, result2 error) {
+	fake.getStatementsContextMutex.Lock()
+	defer fake.getStatementsContextMutex.Unlock()
+	fake.GetStatementsContextStub = nil
+	fake.getStatementsContextReturns = struct {
+		result1 []eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetStatementsContextReturnsOnCall(i int, result1 []eventio.Statement, result2 error) {
+	fake.getStatementsContextMutex.Lock()
+	defer fake.getStatementsContextMutex.Unlock()
+	fake.GetStatementsContextStub = nil
+	if fake.getStatementsContextReturnsOnCall == nil {
+		fake.getStatementsContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.Statement
+			result2 error
+		})
+	}
+	fake.getStatementsContextReturnsOnCall[i] = struct {
+		result1 []eventio.Statement
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetTotalCost() ([]eventio.TotalCost, error) {
+	fake.getTotalCostMutex.Lock()
+	ret, specificReturn := fake.getTotalCostReturnsOnCall[len(fake.getTotalCostArgsForCall)]
+	fake.getTotalCostArgsForCall = append(fake.getTotalCostArgsForCall, struct {
+	}{})
+	stub := fake.GetTotalCostStub
+	fakeReturns := fake.getTotalCostReturns
+	fake.recordInvocation("GetTotalCost", []interface{}{})
+	fake.getTotalCostMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetTotalCostCallCount() int {
+	fake.getTotalCostMutex.RLock()
+	defer fake.getTotalCostMutex.RUnlock()
+	return len(fake.getTotalCostArgsForCall)
+}
+
+func (fake *FakeEventStore) GetTotalCostCalls(stub func() ([]eventio.TotalCost, error)) {
+	fake.getTotalCostMutex.Lock()
+	defer fake.getTotalCostMutex.Unlock()
+	fake.GetTotalCostStub = stub
+}
+
+func (fake *FakeEventStore) GetTotalCostReturns(result1 []eventio.TotalCost, result2 error) {
+	fake.getTotalCostMutex.Lock()
+	defer fake.getTotalCostMutex.Unlock()
+	fake.GetTotalCostStub = nil
+	fake.getTotalCostReturns = struct {
+		result1 []eventio.TotalCost
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetTotalCostReturnsOnCall(i int, result1 []eventio.TotalCost, result2 error) {
+	fake.getTotalCostMutex.Lock()
+	defer fake.getTotalCostMutex.Unlock()
+	fake.GetTotalCostStub = nil
+	if fake.getTotalCostReturnsOnCall == nil {
+		fake.getTotalCostReturnsOnCall = make(map[int]struct {
+			result1 []eventio.TotalCost
+			result2 error
+		})
+	}
+	fake.getTotalCostReturnsOnCall[i] = struct {
+		result1 []eventio.TotalCost
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetTotalCostContext(arg1 context.Context) ([]eventio.TotalCost, error) {
+	fake.getTotalCostContextMutex.Lock()
+	ret, specificReturn := fake.getTotalCostContextReturnsOnCall[len(fake.getTotalCostContextArgsForCall)]
+	fake.getTotalCostContextArgsForCall = append(fake.getTotalCostContextArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetTotalCostContextStub
+	fakeReturns := fake.getTotalCostContextReturns
+	fake.recordInvocation("GetTotalCostContext", []interface{}{arg1})
+	fake.getTotalCostContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetTotalCostContextCallCount() int {
+	fake.getTotalCostContextMutex.RLock()
+	defer fake.getTotalCostContextMutex.RUnlock()
+	return len(fake.getTotalCostContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetTotalCostContextCalls(stub func(context.Context) ([]eventio.TotalCost, error)) {
+	fake.getTotalCostContextMutex.Lock()
+	defer fake.getTotalCostContextMutex.Unlock()
+	fake.GetTotalCostContextStub = stub
+}
+
+func (fake *FakeEventStore) GetTotalCostContextArgsForCall(i int) context.Context {
+	fake.getTotalCostContextMutex.RLock()
+	defer fake.getTotalCostContextMutex.RUnlock()
+	argsForCall := fake.getTotalCostContextArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetTotalCostContextReturns(result1 []eventio.TotalCost, result2 error) {
+	fake.getTotalCostContextMutex.Lock()
+	defer fake.getTotalCostContextMutex.Unlock()
+	fake.GetTotalCostContextStub = nil
+	fake.getTotalCostContextReturns = struct {
+		result1 []eventio.TotalCost
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetTotalCostContextReturnsOnCall(i int, result1 []eventio.TotalCost, result2 error) {
+	fake.getTotalCostContextMutex.Lock()
+	defer fake.getTotalCostContextMutex.Unlock()
+	fake.GetTotalCostContextStub = nil
+	if fake.getTotalCostContextReturnsOnCall == nil {
+		fake.getTotalCostContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.TotalCost
+			result2 error
+		})
+	}
+	fake.getTotalCostContextReturnsOnCall[i] = struct {
+		result1 []eventio.TotalCost
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetUnpricedPlans() ([]eventio.UnpricedPlan, error) {
+	fake.getUnpricedPlansMutex.Lock()
+	ret, specificReturn := fake.getUnpricedPlansReturnsOnCall[len(fake.getUnpricedPlansArgsForCall)]
+	fake.getUnpricedPlansArgsForCall = append(fake.getUnpricedPlansArgsForCall, struct {
+	}{})
+	stub := fake.GetUnpricedPlansStub
+	fakeReturns := fake.getUnpricedPlansReturns
+	fake.recordInvocation("GetUnpricedPlans", []interface{}{})
+	fake.getUnpricedPlansMutex.Unlock()
+	if stub != nil {
+		return stub()
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetUnpricedPlansCallCount() int {
+	fake.getUnpricedPlansMutex.RLock()
+	defer fake.getUnpricedPlansMutex.RUnlock()
+	return len(fake.getUnpricedPlansArgsForCall)
 }
 
-func (fake *FakeEventStore) GetPricingPlansArgsForCall(i int) eventio.TimeRangeFilter {
-	fake.getPricingPlansMutex.RLock()
-	defer fake.getPricingPlansMutex.RUnlock()
-	argsForCall := fake.getPricingPlansArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeEventStore) GetUnpricedPlansCalls(stub func() ([]eventio.UnpricedPlan, error)) {
+	fake.getUnpricedPlansMutex.Lock()
+	defer fake.getUnpricedPlansMutex.Unlock()
+	fake.GetUnpricedPlansStub = stub
 }
 
-func (fake *FakeEventStore) GetPricingPlansReturns(result1 []eventio.PricingPlan, result2 error) {
-	fake.getPricingPlansMutex.Lock()
-	defer fake.getPricingPlansMutex.Unlock()
-	fake.GetPricingPlansStub = nil
-	fake.getPricingPlansReturns = struct {
-		result1 []eventio.PricingPlan
+func (fake *FakeEventStore) GetUnpricedPlansReturns(result1 []eventio.UnpricedPlan, result2 error) {
+	fake.getUnpricedPlansMutex.Lock()
+	defer fake.getUnpricedPlansMutex.Unlock()
+	fake.GetUnpricedPlansStub = nil
+	fake.getUnpricedPlansReturns = struct {
+		result1 []eventio.UnpricedPlan
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeEventStore) GetPricingPlansReturnsOnCall(i int, result1 []eventio.PricingPlan, result2 error) {
-	fake.getPricingPlansMutex.Lock()
-	defer fake.getPricingPlansMutex.Unlock()
-	fake.GetPricingPlansStub = nil
-	if fake.getPricingPlansReturnsOnCall == nil {
-		fake.getPricingPlansReturnsOnCall = make(map[int]struct {
-			result1 []eventio.PricingPlan
+func (fake *FakeEventStore) GetUnpricedPlansReturnsOnCall(i int, result1 []eventio.UnpricedPlan, result2 error) {
+	fake.getUnpricedPlansMutex.Lock()
+	defer fake.getUnpricedPlansMutex.Unlock()
+	fake.GetUnpricedPlansStub = nil
+	if fake.getUnpricedPlansReturnsOnCall == nil {
+		fake.getUnpricedPlansReturnsOnCall = make(map[int]struct {
+			result1 []eventio.UnpricedPlan
 			result2 error
 		})
 	}
-	fake.getPricingPlansReturnsOnCall[i] = struct {
-		result1 []eventio.PricingPlan
+	fake.getUnpricedPlansReturnsOnCall[i] = struct {
+		result1 []eventio.UnpricedPlan
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeEventStore) GetTotalCost() ([]eventio.TotalCost, error) {
-	fake.getTotalCostMutex.Lock()
-	ret, specificReturn := fake.getTotalCostReturnsOnCall[len(fake.getTotalCostArgsForCall)]
-	fake.getTotalCostArgsForCall = append(fake.getTotalCostArgsForCall, struct {
-	}{})
-	stub := fake.GetTotalCostStub
-	fakeReturns := fake.getTotalCostReturns
-	fake.recordInvocation("GetTotalCost", []interface{}{})
-	fake.getTotalCostMutex.Unlock()
+func (fake *FakeEventStore) GetUnpricedPlansContext(arg1 context.Context) ([]eventio.UnpricedPlan, error) {
+	fake.getUnpricedPlansContextMutex.Lock()
+	ret, specificReturn := fake.getUnpricedPlansContextReturnsOnCall[len(fake.getUnpricedPlansContextArgsForCall)]
+	fake.getUnpricedPlansContextArgsForCall = append(fake.getUnpricedPlansContextArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.GetUnpricedPlansContextStub
+	fakeReturns := fake.getUnpricedPlansContextReturns
+	fake.recordInvocation("GetUnpricedPlansContext", []interface{}{arg1})
+	fake.getUnpricedPlansContextMutex.Unlock()
 	if stub != nil {
-		return stub()
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -1068,40 +3540,47 @@ func (fake *FakeEventStore) GetTotalCost() ([]eventio.TotalCost, error) {
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeEventStore) GetTotalCostCallCount() int {
-	fake.getTotalCostMutex.RLock()
-	defer fake.getTotalCostMutex.RUnlock()
-	return len(fake.getTotalCostArgsForCall)
+func (fake *FakeEventStore) GetUnpricedPlansContextCallCount() int {
+	fake.getUnpricedPlansContextMutex.RLock()
+	defer fake.getUnpricedPlansContextMutex.RUnlock()
+	return len(fake.getUnpricedPlansContextArgsForCall)
 }
 
-func (fake *FakeEventStore) GetTotalCostCalls(stub func() ([]eventio.TotalCost, error)) {
-	fake.getTotalCostMutex.Lock()
-	defer fake.getTotalCostMutex.Unlock()
-	fake.GetTotalCostStub = stub
+func (fake *FakeEventStore) GetUnpricedPlansContextCalls(stub func(context.Context) ([]eventio.UnpricedPlan, error)) {
+	fake.getUnpricedPlansContextMutex.Lock()
+	defer fake.getUnpricedPlansContextMutex.Unlock()
+	fake.GetUnpricedPlansContextStub = stub
 }
 
-func (fake *FakeEventStore) GetTotalCostReturns(result1 []eventio.TotalCost, result2 error) {
-	fake.getTotalCostMutex.Lock()
-	defer fake.getTotalCostMutex.Unlock()
-	fake.GetTotalCostStub = nil
-	fake.getTotalCostReturns = struct {
-		result1 []eventio.TotalCost
+func (fake *FakeEventStore) GetUnpricedPlansContextArgsForCall(i int) context.Context {
+	fake.getUnpricedPlansContextMutex.RLock()
+	defer fake.getUnpricedPlansContextMutex.RUnlock()
+	argsForCall := fake.getUnpricedPlansContextArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) GetUnpricedPlansContextReturns(result1 []eventio.UnpricedPlan, result2 error) {
+	fake.getUnpricedPlansContextMutex.Lock()
+	defer fake.getUnpricedPlansContextMutex.Unlock()
+	fake.GetUnpricedPlansContextStub = nil
+	fake.getUnpricedPlansContextReturns = struct {
+		result1 []eventio.UnpricedPlan
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeEventStore) GetTotalCostReturnsOnCall(i int, result1 []eventio.TotalCost, result2 error) {
-	fake.getTotalCostMutex.Lock()
-	defer fake.getTotalCostMutex.Unlock()
-	fake.GetTotalCostStub = nil
-	if fake.getTotalCostReturnsOnCall == nil {
-		fake.getTotalCostReturnsOnCall = make(map[int]struct {
-			result1 []eventio.TotalCost
+func (fake *FakeEventStore) GetUnpricedPlansContextReturnsOnCall(i int, result1 []eventio.UnpricedPlan, result2 error) {
+	fake.getUnpricedPlansContextMutex.Lock()
+	defer fake.getUnpricedPlansContextMutex.Unlock()
+	fake.GetUnpricedPlansContextStub = nil
+	if fake.getUnpricedPlansContextReturnsOnCall == nil {
+		fake.getUnpricedPlansContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.UnpricedPlan
 			result2 error
 		})
 	}
-	fake.getTotalCostReturnsOnCall[i] = struct {
-		result1 []eventio.TotalCost
+	fake.getUnpricedPlansContextReturnsOnCall[i] = struct {
+		result1 []eventio.UnpricedPlan
 		result2 error
 	}{result1, result2}
 }
@@ -1170,6 +3649,71 @@ func (fake *FakeEventStore) GetUsageEventRowsReturnsOnCall(i int, result1 eventi
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetUsageEventRowsContext(arg1 context.Context, arg2 eventio.EventFilter) (eventio.UsageEventRows, error) {
+	fake.getUsageEventRowsContextMutex.Lock()
+	ret, specificReturn := fake.getUsageEventRowsContextReturnsOnCall[len(fake.getUsageEventRowsContextArgsForCall)]
+	fake.getUsageEventRowsContextArgsForCall = append(fake.getUsageEventRowsContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.GetUsageEventRowsContextStub
+	fakeReturns := fake.getUsageEventRowsContextReturns
+	fake.recordInvocation("GetUsageEventRowsContext", []interface{}{arg1, arg2})
+	fake.getUsageEventRowsContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetUsageEventRowsContextCallCount() int {
+	fake.getUsageEventRowsContextMutex.RLock()
+	defer fake.getUsageEventRowsContextMutex.RUnlock()
+	return len(fake.getUsageEventRowsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetUsageEventRowsContextCalls(stub func(context.Context, eventio.EventFilter) (eventio.UsageEventRows, error)) {
+	fake.getUsageEventRowsContextMutex.Lock()
+	defer fake.getUsageEventRowsContextMutex.Unlock()
+	fake.GetUsageEventRowsContextStub = stub
+}
+
+func (fake *FakeEventStore) GetUsageEventRowsContextArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.getUsageEventRowsContextMutex.RLock()
+	defer fake.getUsageEventRowsContextMutex.RUnlock()
+	argsForCall := fake.getUsageEventRowsContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetUsageEventRowsContextReturns(result1 eventio.UsageEventRows, result2 error) {
+	fake.getUsageEventRowsContextMutex.Lock()
+	defer fake.getUsageEventRowsContextMutex.Unlock()
+	fake.GetUsageEventRowsContextStub = nil
+	fake.getUsageEventRowsContextReturns = struct {
+		result1 eventio.UsageEventRows
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetUsageEventRowsContextReturnsOnCall(i int, result1 eventio.UsageEventRows, result2 error) {
+	fake.getUsageEventRowsContextMutex.Lock()
+	defer fake.getUsageEventRowsContextMutex.Unlock()
+	fake.GetUsageEventRowsContextStub = nil
+	if fake.getUsageEventRowsContextReturnsOnCall == nil {
+		fake.getUsageEventRowsContextReturnsOnCall = make(map[int]struct {
+			result1 eventio.UsageEventRows
+			result2 error
+		})
+	}
+	fake.getUsageEventRowsContextReturnsOnCall[i] = struct {
+		result1 eventio.UsageEventRows
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetUsageEvents(arg1 eventio.EventFilter) ([]eventio.UsageEvent, error) {
 	fake.getUsageEventsMutex.Lock()
 	ret, specificReturn := fake.getUsageEventsReturnsOnCall[len(fake.getUsageEventsArgsForCall)]
@@ -1234,6 +3778,71 @@ func (fake *FakeEventStore) GetUsageEventsReturnsOnCall(i int, result1 []eventio
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetUsageEventsContext(arg1 context.Context, arg2 eventio.EventFilter) ([]eventio.UsageEvent, error) {
+	fake.getUsageEventsContextMutex.Lock()
+	ret, specificReturn := fake.getUsageEventsContextReturnsOnCall[len(fake.getUsageEventsContextArgsForCall)]
+	fake.getUsageEventsContextArgsForCall = append(fake.getUsageEventsContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.GetUsageEventsContextStub
+	fakeReturns := fake.getUsageEventsContextReturns
+	fake.recordInvocation("GetUsageEventsContext", []interface{}{arg1, arg2})
+	fake.getUsageEventsContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetUsageEventsContextCallCount() int {
+	fake.getUsageEventsContextMutex.RLock()
+	defer fake.getUsageEventsContextMutex.RUnlock()
+	return len(fake.getUsageEventsContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetUsageEventsContextCalls(stub func(context.Context, eventio.EventFilter) ([]eventio.UsageEvent, error)) {
+	fake.getUsageEventsContextMutex.Lock()
+	defer fake.getUsageEventsContextMutex.Unlock()
+	fake.GetUsageEventsContextStub = stub
+}
+
+func (fake *FakeEventStore) GetUsageEventsContextArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.getUsageEventsContextMutex.RLock()
+	defer fake.getUsageEventsContextMutex.RUnlock()
+	argsForCall := fake.getUsageEventsContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetUsageEventsContextReturns(result1 []eventio.UsageEvent, result2 error) {
+	fake.getUsageEventsContextMutex.Lock()
+	defer fake.getUsageEventsContextMutex.Unlock()
+	fake.GetUsageEventsContextStub = nil
+	fake.getUsageEventsContextReturns = struct {
+		result1 []eventio.UsageEvent
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetUsageEventsContextReturnsOnCall(i int, result1 []eventio.UsageEvent, result2 error) {
+	fake.getUsageEventsContextMutex.Lock()
+	defer fake.getUsageEventsContextMutex.Unlock()
+	fake.GetUsageEventsContextStub = nil
+	if fake.getUsageEventsContextReturnsOnCall == nil {
+		fake.getUsageEventsContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.UsageEvent
+			result2 error
+		})
+	}
+	fake.getUsageEventsContextReturnsOnCall[i] = struct {
+		result1 []eventio.UsageEvent
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) GetVATRates(arg1 eventio.TimeRangeFilter) ([]eventio.VATRate, error) {
 	fake.getVATRatesMutex.Lock()
 	ret, specificReturn := fake.getVATRatesReturnsOnCall[len(fake.getVATRatesArgsForCall)]
@@ -1298,6 +3907,71 @@ func (fake *FakeEventStore) GetVATRatesReturnsOnCall(i int, result1 []eventio.VA
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) GetVATRatesContext(arg1 context.Context, arg2 eventio.TimeRangeFilter) ([]eventio.VATRate, error) {
+	fake.getVATRatesContextMutex.Lock()
+	ret, specificReturn := fake.getVATRatesContextReturnsOnCall[len(fake.getVATRatesContextArgsForCall)]
+	fake.getVATRatesContextArgsForCall = append(fake.getVATRatesContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.TimeRangeFilter
+	}{arg1, arg2})
+	stub := fake.GetVATRatesContextStub
+	fakeReturns := fake.getVATRatesContextReturns
+	fake.recordInvocation("GetVATRatesContext", []interface{}{arg1, arg2})
+	fake.getVATRatesContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) GetVATRatesContextCallCount() int {
+	fake.getVATRatesContextMutex.RLock()
+	defer fake.getVATRatesContextMutex.RUnlock()
+	return len(fake.getVATRatesContextArgsForCall)
+}
+
+func (fake *FakeEventStore) GetVATRatesContextCalls(stub func(context.Context, eventio.TimeRangeFilter) ([]eventio.VATRate, error)) {
+	fake.getVATRatesContextMutex.Lock()
+	defer fake.getVATRatesContextMutex.Unlock()
+	fake.GetVATRatesContextStub = stub
+}
+
+func (fake *FakeEventStore) GetVATRatesContextArgsForCall(i int) (context.Context, eventio.TimeRangeFilter) {
+	fake.getVATRatesContextMutex.RLock()
+	defer fake.getVATRatesContextMutex.RUnlock()
+	argsForCall := fake.getVATRatesContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) GetVATRatesContextReturns(result1 []eventio.VATRate, result2 error) {
+	fake.getVATRatesContextMutex.Lock()
+	defer fake.getVATRatesContextMutex.Unlock()
+	fake.GetVATRatesContextStub = nil
+	fake.getVATRatesContextReturns = struct {
+		result1 []eventio.VATRate
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) GetVATRatesContextReturnsOnCall(i int, result1 []eventio.VATRate, result2 error) {
+	fake.getVATRatesContextMutex.Lock()
+	defer fake.getVATRatesContextMutex.Unlock()
+	fake.GetVATRatesContextStub = nil
+	if fake.getVATRatesContextReturnsOnCall == nil {
+		fake.getVATRatesContextReturnsOnCall = make(map[int]struct {
+			result1 []eventio.VATRate
+			result2 error
+		})
+	}
+	fake.getVATRatesContextReturnsOnCall[i] = struct {
+		result1 []eventio.VATRate
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) Init() error {
 	fake.initMutex.Lock()
 	ret, specificReturn := fake.initReturnsOnCall[len(fake.initArgsForCall)]
@@ -1415,6 +4089,71 @@ func (fake *FakeEventStore) IsRangeConsolidatedReturnsOnCall(i int, result1 bool
 	}{result1, result2}
 }
 
+func (fake *FakeEventStore) IsRangeConsolidatedContext(arg1 context.Context, arg2 eventio.EventFilter) (bool, error) {
+	fake.isRangeConsolidatedContextMutex.Lock()
+	ret, specificReturn := fake.isRangeConsolidatedContextReturnsOnCall[len(fake.isRangeConsolidatedContextArgsForCall)]
+	fake.isRangeConsolidatedContextArgsForCall = append(fake.isRangeConsolidatedContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 eventio.EventFilter
+	}{arg1, arg2})
+	stub := fake.IsRangeConsolidatedContextStub
+	fakeReturns := fake.isRangeConsolidatedContextReturns
+	fake.recordInvocation("IsRangeConsolidatedContext", []interface{}{arg1, arg2})
+	fake.isRangeConsolidatedContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) IsRangeConsolidatedContextCallCount() int {
+	fake.isRangeConsolidatedContextMutex.RLock()
+	defer fake.isRangeConsolidatedContextMutex.RUnlock()
+	return len(fake.isRangeConsolidatedContextArgsForCall)
+}
+
+func (fake *FakeEventStore) IsRangeConsolidatedContextCalls(stub func(context.Context, eventio.EventFilter) (bool, error)) {
+	fake.isRangeConsolidatedContextMutex.Lock()
+	defer fake.isRangeConsolidatedContextMutex.Unlock()
+	fake.IsRangeConsolidatedContextStub = stub
+}
+
+func (fake *FakeEventStore) IsRangeConsolidatedContextArgsForCall(i int) (context.Context, eventio.EventFilter) {
+	fake.isRangeConsolidatedContextMutex.RLock()
+	defer fake.isRangeConsolidatedContextMutex.RUnlock()
+	argsForCall := fake.isRangeConsolidatedContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeEventStore) IsRangeConsolidatedContextReturns(result1 bool, result2 error) {
+	fake.isRangeConsolidatedContextMutex.Lock()
+	defer fake.isRangeConsolidatedContextMutex.Unlock()
+	fake.IsRangeConsolidatedContextStub = nil
+	fake.isRangeConsolidatedContextReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) IsRangeConsolidatedContextReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.isRangeConsolidatedContextMutex.Lock()
+	defer fake.isRangeConsolidatedContextMutex.Unlock()
+	fake.IsRangeConsolidatedContextStub = nil
+	if fake.isRangeConsolidatedContextReturnsOnCall == nil {
+		fake.isRangeConsolidatedContextReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.isRangeConsolidatedContextReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeEventStore) Ping() error {
 	fake.pingMutex.Lock()
 	ret, specificReturn := fake.pingReturnsOnCall[len(fake.pingArgsForCall)]
@@ -1574,6 +4313,131 @@ func (fake *FakeEventStore) RefreshReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeEventStore) RemoveExemption(arg1 string) (bool, error) {
+	fake.removeExemptionMutex.Lock()
+	ret, specificReturn := fake.removeExemptionReturnsOnCall[len(fake.removeExemptionArgsForCall)]
+	fake.removeExemptionArgsForCall = append(fake.removeExemptionArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.RemoveExemptionStub
+	fakeReturns := fake.removeExemptionReturns
+	fake.recordInvocation("RemoveExemption", []interface{}{arg1})
+	fake.removeExemptionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeEventStore) RemoveExemptionCallCount() int {
+	fake.removeExemptionMutex.RLock()
+	defer fake.removeExemptionMutex.RUnlock()
+	return len(fake.removeExemptionArgsForCall)
+}
+
+func (fake *FakeEventStore) RemoveExemptionCalls(stub func(string) (bool, error)) {
+	fake.removeExemptionMutex.Lock()
+	defer fake.removeExemptionMutex.Unlock()
+	fake.RemoveExemptionStub = stub
+}
+
+func (fake *FakeEventStore) RemoveExemptionArgsForCall(i int) string {
+	fake.removeExemptionMutex.RLock()
+	defer fake.removeExemptionMutex.RUnlock()
+	argsForCall := fake.removeExemptionArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) RemoveExemptionReturns(result1 bool, result2 error) {
+	fake.removeExemptionMutex.Lock()
+	defer fake.removeExemptionMutex.Unlock()
+	fake.RemoveExemptionStub = nil
+	fake.removeExemptionReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) RemoveExemptionReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.removeExemptionMutex.Lock()
+	defer fake.removeExemptionMutex.Unlock()
+	fake.RemoveExemptionStub = nil
+	if fake.removeExemptionReturnsOnCall == nil {
+		fake.removeExemptionReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.removeExemptionReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEventStore) StoreEgressBatch(arg1 eventio.EgressBatch) error {
+	fake.storeEgressBatchMutex.Lock()
+	ret, specificReturn := fake.storeEgressBatchReturnsOnCall[len(fake.storeEgressBatchArgsForCall)]
+	fake.storeEgressBatchArgsForCall = append(fake.storeEgressBatchArgsForCall, struct {
+		arg1 eventio.EgressBatch
+	}{arg1})
+	stub := fake.StoreEgressBatchStub
+	fakeReturns := fake.storeEgressBatchReturns
+	fake.recordInvocation("StoreEgressBatch", []interface{}{arg1})
+	fake.storeEgressBatchMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeEventStore) StoreEgressBatchCallCount() int {
+	fake.storeEgressBatchMutex.RLock()
+	defer fake.storeEgressBatchMutex.RUnlock()
+	return len(fake.storeEgressBatchArgsForCall)
+}
+
+func (fake *FakeEventStore) StoreEgressBatchCalls(stub func(eventio.EgressBatch) error) {
+	fake.storeEgressBatchMutex.Lock()
+	defer fake.storeEgressBatchMutex.Unlock()
+	fake.StoreEgressBatchStub = stub
+}
+
+func (fake *FakeEventStore) StoreEgressBatchArgsForCall(i int) eventio.EgressBatch {
+	fake.storeEgressBatchMutex.RLock()
+	defer fake.storeEgressBatchMutex.RUnlock()
+	argsForCall := fake.storeEgressBatchArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeEventStore) StoreEgressBatchReturns(result1 error) {
+	fake.storeEgressBatchMutex.Lock()
+	defer fake.storeEgressBatchMutex.Unlock()
+	fake.StoreEgressBatchStub = nil
+	fake.storeEgressBatchReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEventStore) StoreEgressBatchReturnsOnCall(i int, result1 error) {
+	fake.storeEgressBatchMutex.Lock()
+	defer fake.storeEgressBatchMutex.Unlock()
+	fake.StoreEgressBatchStub = nil
+	if fake.storeEgressBatchReturnsOnCall == nil {
+		fake.storeEgressBatchReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.storeEgressBatchReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeEventStore) StoreEvents(arg1 []eventio.RawEvent) error {
 	var arg1Copy []eventio.RawEvent
 	if arg1 != nil {
@@ -1643,48 +4507,122 @@ func (fake *FakeEventStore) StoreEventsReturnsOnCall(i int, result1 error) {
 func (fake *FakeEventStore) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.addExemptionMutex.RLock()
+	defer fake.addExemptionMutex.RUnlock()
 	fake.consolidateMutex.RLock()
 	defer fake.consolidateMutex.RUnlock()
 	fake.consolidateAllMutex.RLock()
 	defer fake.consolidateAllMutex.RUnlock()
 	fake.consolidateFullMonthsMutex.RLock()
 	defer fake.consolidateFullMonthsMutex.RUnlock()
+	fake.creditStatementMutex.RLock()
+	defer fake.creditStatementMutex.RUnlock()
 	fake.forecastBillableEventRowsMutex.RLock()
 	defer fake.forecastBillableEventRowsMutex.RUnlock()
 	fake.forecastBillableEventsMutex.RLock()
 	defer fake.forecastBillableEventsMutex.RUnlock()
+	fake.forecastBillableEventsContextMutex.RLock()
+	defer fake.forecastBillableEventsContextMutex.RUnlock()
+	fake.generateAllStatementsMutex.RLock()
+	defer fake.generateAllStatementsMutex.RUnlock()
+	fake.generateStatementsMutex.RLock()
+	defer fake.generateStatementsMutex.RUnlock()
+	fake.getAllowanceUsageMutex.RLock()
+	defer fake.getAllowanceUsageMutex.RUnlock()
+	fake.getAllowanceUsageContextMutex.RLock()
+	defer fake.getAllowanceUsageContextMutex.RUnlock()
 	fake.getBillableEventRowsMutex.RLock()
 	defer fake.getBillableEventRowsMutex.RUnlock()
 	fake.getBillableEventsMutex.RLock()
 	defer fake.getBillableEventsMutex.RUnlock()
+	fake.getBillableEventsContextMutex.RLock()
+	defer fake.getBillableEventsContextMutex.RUnlock()
 	fake.getConsolidatedBillableEventRowsMutex.RLock()
 	defer fake.getConsolidatedBillableEventRowsMutex.RUnlock()
 	fake.getConsolidatedBillableEventsMutex.RLock()
 	defer fake.getConsolidatedBillableEventsMutex.RUnlock()
+	fake.getConsolidatedBillableEventsContextMutex.RLock()
+	defer fake.getConsolidatedBillableEventsContextMutex.RUnlock()
+	fake.getConsolidationHistoryMutex.RLock()
+	defer fake.getConsolidationHistoryMutex.RUnlock()
+	fake.getConsolidationHistoryContextMutex.RLock()
+	defer fake.getConsolidationHistoryContextMutex.RUnlock()
+	fake.getCostTimeSeriesRowsMutex.RLock()
+	defer fake.getCostTimeSeriesRowsMutex.RUnlock()
 	fake.getCurrencyRatesMutex.RLock()
 	defer fake.getCurrencyRatesMutex.RUnlock()
+	fake.getCurrencyRatesContextMutex.RLock()
+	defer fake.getCurrencyRatesContextMutex.RUnlock()
+	fake.getDataQualityIssuesMutex.RLock()
+	defer fake.getDataQualityIssuesMutex.RUnlock()
+	fake.getDataQualityIssuesContextMutex.RLock()
+	defer fake.getDataQualityIssuesContextMutex.RUnlock()
+	fake.getEgressOffsetMutex.RLock()
+	defer fake.getEgressOffsetMutex.RUnlock()
 	fake.getEventsMutex.RLock()
 	defer fake.getEventsMutex.RUnlock()
+	fake.getEventsContextMutex.RLock()
+	defer fake.getEventsContextMutex.RUnlock()
+	fake.getExemptionsMutex.RLock()
+	defer fake.getExemptionsMutex.RUnlock()
+	fake.getExemptionsContextMutex.RLock()
+	defer fake.getExemptionsContextMutex.RUnlock()
+	fake.getFootprintEventRowsMutex.RLock()
+	defer fake.getFootprintEventRowsMutex.RUnlock()
+	fake.getFootprintPlansMutex.RLock()
+	defer fake.getFootprintPlansMutex.RUnlock()
 	fake.getPricingPlansMutex.RLock()
 	defer fake.getPricingPlansMutex.RUnlock()
+	fake.getPricingPlansContextMutex.RLock()
+	defer fake.getPricingPlansContextMutex.RUnlock()
+	fake.getResourceHistoryMutex.RLock()
+	defer fake.getResourceHistoryMutex.RUnlock()
+	fake.getResourceHistoryContextMutex.RLock()
+	defer fake.getResourceHistoryContextMutex.RUnlock()
+	fake.getStatementMutex.RLock()
+	defer fake.getStatementMutex.RUnlock()
+	fake.getStatementContextMutex.RLock()
+	defer fake.getStatementContextMutex.RUnlock()
+	fake.getStatementsMutex.RLock()
+	defer fake.getStatementsMutex.RUnlock()
+	fake.getStatementsContextMutex.RLock()
+	defer fake.getStatementsContextMutex.RUnlock()
 	fake.getTotalCostMutex.RLock()
 	defer fake.getTotalCostMutex.RUnlock()
+	fake.getTotalCostContextMutex.RLock()
+	defer fake.getTotalCostContextMutex.RUnlock()
+	fake.getUnpricedPlansMutex.RLock()
+	defer fake.getUnpricedPlansMutex.RUnlock()
+	fake.getUnpricedPlansContextMutex.RLock()
+	defer fake.getUnpricedPlansContextMutex.RUnlock()
 	fake.getUsageEventRowsMutex.RLock()
 	defer fake.getUsageEventRowsMutex.RUnlock()
+	fake.getUsageEventRowsContextMutex.RLock()
+	defer fake.getUsageEventRowsContextMutex.RUnlock()
 	fake.getUsageEventsMutex.RLock()
 	defer fake.getUsageEventsMutex.RUnlock()
+	fake.getUsageEventsContextMutex.RLock()
+	defer fake.getUsageEventsContextMutex.RUnlock()
 	fake.getVATRatesMutex.RLock()
 	defer fake.getVATRatesMutex.RUnlock()
+	fake.getVATRatesContextMutex.RLock()
+	defer fake.getVATRatesContextMutex.RUnlock()
 	fake.initMutex.RLock()
 	defer fake.initMutex.RUnlock()
 	fake.isRangeConsolidatedMutex.RLock()
 	defer fake.isRangeConsolidatedMutex.RUnlock()
+	fake.isRangeConsolidatedContextMutex.RLock()
+	defer fake.isRangeConsolidatedContextMutex.RUnlock()
 	fake.pingMutex.RLock()
 	defer fake.pingMutex.RUnlock()
 	fake.recordPeriodicMetricsMutex.RLock()
 	defer fake.recordPeriodicMetricsMutex.RUnlock()
 	fake.refreshMutex.RLock()
 	defer fake.refreshMutex.RUnlock()
+	fake.removeExemptionMutex.RLock()
+	defer fake.removeExemptionMutex.RUnlock()
+	fake.storeEgressBatchMutex.RLock()
+	defer fake.storeEgressBatchMutex.RUnlock()
 	fake.storeEventsMutex.RLock()
 	defer fake.storeEventsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
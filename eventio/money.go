@@ -0,0 +1,183 @@
+package eventio
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+)
+
+// Money is an exact decimal amount, such as "12.3456", in the form Postgres
+// formats numeric values. It is a string so it reads and marshals exactly
+// as the prices the store has always returned. Do arithmetic on it with its
+// methods, which never lose precision, rather than converting to float.
+//
+// Money is never rounded on events, price components, totals or time
+// series. It is rounded once, when it is billed: each statement line item
+// is rounded to the penny, then the VAT for each VAT code is calculated on
+// the rounded line items and rounded to the penny. Rounding is always half
+// away from zero, as Postgres round does.
+type Money string
+
+// ZeroMoney is nothing
+const ZeroMoney Money = "0"
+
+// maxMoneyPlaces limits the decimal places kept for results that cannot be
+// written exactly as decimals, which only division can produce
+const maxMoneyPlaces = 16
+
+var moneyPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// ParseMoney checks that s is a plain decimal amount
+func ParseMoney(s string) (Money, error) {
+	if !moneyPattern.MatchString(s) {
+		return "", fmt.Errorf("invalid amount '%s'", s)
+	}
+	return Money(s), nil
+}
+
+// MoneyFromRat writes r as Money. It is exact unless r has no finite
+// decimal form, when it keeps 16 decimal places.
+func MoneyFromRat(r *big.Rat) Money {
+	return Money(r.FloatString(decimalPlaces(r)))
+}
+
+// MoneyFromPence converts a whole number of pence, eg 1234 as 12.34
+func MoneyFromPence(pence int64) Money {
+	return Money(big.NewRat(pence, 100).FloatString(2))
+}
+
+// decimalPlaces returns how many decimal places are needed to write r
+// exactly, or maxMoneyPlaces if it cannot be
+func decimalPlaces(r *big.Rat) int {
+	denom := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	two, five := big.NewInt(2), big.NewInt(5)
+	mod := new(big.Int)
+	for denom.Cmp(big.NewInt(1)) != 0 {
+		switch {
+		case mod.Mod(denom, two).Sign() == 0:
+			denom.Quo(denom, two)
+			twos++
+		case mod.Mod(denom, five).Sign() == 0:
+			denom.Quo(denom, five)
+			fives++
+		default:
+			return maxMoneyPlaces
+		}
+	}
+	if twos > fives {
+		return twos
+	}
+	return fives
+}
+
+// Rat returns the exact value of m
+func (m Money) Rat() (*big.Rat, error) {
+	if _, err := ParseMoney(string(m)); err != nil {
+		return nil, err
+	}
+	r, ok := new(big.Rat).SetString(string(m))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount '%s'", m)
+	}
+	return r, nil
+}
+
+// Add returns m + other
+func (m Money) Add(other Money) (Money, error) {
+	return SumMoney(m, other)
+}
+
+// MulRate returns m multiplied by a decimal rate such as a VAT rate of "0.2"
+func (m Money) MulRate(rate string) (Money, error) {
+	r, err := m.Rat()
+	if err != nil {
+		return "", err
+	}
+	multiplier, err := Money(rate).Rat()
+	if err != nil {
+		return "", fmt.Errorf("invalid rate '%s'", rate)
+	}
+	return MoneyFromRat(r.Mul(r, multiplier)), nil
+}
+
+// SumMoney adds up amounts exactly
+func SumMoney(amounts ...Money) (Money, error) {
+	total := new(big.Rat)
+	for _, amount := range amounts {
+		r, err := amount.Rat()
+		if err != nil {
+			return "", err
+		}
+		total.Add(total, r)
+	}
+	return MoneyFromRat(total), nil
+}
+
+// Round rounds m to the given number of decimal places, half away from
+// zero. Round(2) rounds to the penny.
+func (m Money) Round(places int) (Money, error) {
+	r, err := m.Rat()
+	if err != nil {
+		return "", err
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	rounded := roundHalfAwayFromZero(new(big.Rat).Mul(r, new(big.Rat).SetInt(scale)))
+	return Money(new(big.Rat).SetFrac(rounded, scale).FloatString(places)), nil
+}
+
+func roundHalfAwayFromZero(r *big.Rat) *big.Int {
+	abs := new(big.Rat).Abs(r)
+	abs.Add(abs, big.NewRat(1, 2))
+	n := new(big.Int).Quo(abs.Num(), abs.Denom())
+	if r.Sign() < 0 {
+		n.Neg(n)
+	}
+	return n
+}
+
+// Pence converts m to a whole number of pence. It fails rather than round
+// if m has fractions of a penny.
+func (m Money) Pence() (int64, error) {
+	r, err := m.Rat()
+	if err != nil {
+		return 0, err
+	}
+	r.Mul(r, big.NewRat(100, 1))
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, fmt.Errorf("amount '%s' is not a whole number of pence", m)
+	}
+	return r.Num().Int64(), nil
+}
+
+// Float64 approximates m for charts and metrics. Never sum the result.
+func (m Money) Float64() (float64, error) {
+	if _, err := ParseMoney(string(m)); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(m), 64)
+}
+
+// Scan reads a numeric column
+func (m *Money) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Errorf("cannot Scan into Money with: %T", src)
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,102 @@
+package eventio_test
+
+import (
+	"encoding/json"
+	"math/big"
+
+	. "github.com/alphagov/paas-billing/eventio"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Money", func() {
+	DescribeTable("ParseMoney should only accept plain decimals",
+		func(s string, valid bool) {
+			m, err := ParseMoney(s)
+			if valid {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(m).To(Equal(Money(s)))
+			} else {
+				Expect(err).To(MatchError(ContainSubstring("invalid amount")))
+			}
+		},
+		Entry("integer", "12", true),
+		Entry("decimal", "12.3456", true),
+		Entry("negative", "-0.01", true),
+		Entry("empty", "", false),
+		Entry("exponent", "1e5", false),
+		Entry("missing leading digit", ".5", false),
+		Entry("NaN", "NaN", false),
+	)
+
+	It("should add up amounts without losing precision", func() {
+		total, err := SumMoney("0.1", "0.2", "0.0000000000000001")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(total).To(Equal(Money("0.3000000000000001")))
+
+		total, err = Money("0.012").Add("0.012")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(total).To(Equal(Money("0.024")))
+
+		total, err = SumMoney()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(total).To(Equal(ZeroMoney))
+	})
+
+	It("should refuse to add invalid amounts", func() {
+		_, err := SumMoney("1", "one")
+		Expect(err).To(MatchError("invalid amount 'one'"))
+	})
+
+	DescribeTable("Round should round half away from zero",
+		func(m Money, places int, expected Money) {
+			Expect(m.Round(places)).To(Equal(expected))
+		},
+		Entry("down", Money("2.344"), 2, Money("2.34")),
+		Entry("half up", Money("2.345"), 2, Money("2.35")),
+		Entry("half down when negative", Money("-2.345"), 2, Money("-2.35")),
+		Entry("pads to places", Money("3"), 2, Money("3.00")),
+		Entry("whole numbers", Money("2.5"), 0, Money("3")),
+	)
+
+	It("should multiply by a rate exactly", func() {
+		Expect(Money("12.34").MulRate("0.2")).To(Equal(Money("2.468")))
+		Expect(Money("0.175").MulRate("100")).To(Equal(Money("17.5")))
+		_, err := Money("1").MulRate("twenty percent")
+		Expect(err).To(MatchError("invalid rate 'twenty percent'"))
+	})
+
+	It("should keep 16 places of results without a finite decimal form", func() {
+		Expect(MoneyFromRat(big.NewRat(1, 3))).To(Equal(Money("0.3333333333333333")))
+	})
+
+	It("should convert to and from pence", func() {
+		Expect(Money("12.3").Pence()).To(Equal(int64(1230)))
+		Expect(MoneyFromPence(-5)).To(Equal(Money("-0.05")))
+		_, err := Money("0.001").Pence()
+		Expect(err).To(MatchError("amount '0.001' is not a whole number of pence"))
+	})
+
+	It("should scan numeric columns", func() {
+		var m Money
+		Expect(m.Scan([]byte("1417.00"))).To(Succeed())
+		Expect(m).To(Equal(Money("1417.00")))
+		Expect(m.Scan(int64(3))).To(Succeed())
+		Expect(m).To(Equal(Money("3")))
+		Expect(m.Scan(nil)).To(MatchError("cannot Scan into Money with: <nil>"))
+	})
+
+	It("should marshal as a JSON string", func() {
+		b, err := json.Marshal(Price{IncVAT: "0.012", ExVAT: "0.01", Details: []PriceComponent{}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b).To(MatchJSON(`{"inc_vat": "0.012", "ex_vat": "0.01", "details": []}`))
+	})
+})
+
+var _ = Describe("TotalCost", func() {
+	It("should marshal the cost as a JSON number", func() {
+		b, err := json.Marshal(TotalCost{PlanGUID: "plan-guid", PlanName: "plan", Kind: "kind", Cost: "1417.00"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(Equal(`{"plan_guid":"plan-guid","cost":1417.00}`))
+	})
+})
@@ -0,0 +1,68 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- statements are issued per org (or billing account) per consolidated month
+-- and must never change once issued. a statement is cancelled by issuing a
+-- credit note against it, after which a replacement statement can be issued.
+--
+
+CREATE TABLE statements (
+  statement_number bigint NOT NULL,
+
+  account_id text NOT NULL,
+  account_name text NOT NULL,
+  org_guids uuid[] NOT NULL,
+
+  period tstzrange REFERENCES consolidation_history(consolidated_range) NOT NULL,
+  issued_at timestamptz NOT NULL,
+  currency_code text NOT NULL,
+
+  line_items jsonb NOT NULL,
+  vat jsonb NOT NULL,
+  subtotal numeric NOT NULL,
+  vat_total numeric NOT NULL,
+  total numeric NOT NULL,
+
+  replaces bigint REFERENCES statements(statement_number),
+
+  PRIMARY KEY (statement_number),
+  CONSTRAINT statement_number_positive CHECK (statement_number > 0),
+  CONSTRAINT replaces_once UNIQUE (replaces)
+);
+
+CREATE INDEX statements_account_period_idx ON statements (account_id, period);
+
+CREATE TABLE credit_notes (
+  credit_note_number bigint NOT NULL,
+  statement_number bigint REFERENCES statements(statement_number) NOT NULL,
+
+  reason text NOT NULL,
+  issued_at timestamptz NOT NULL,
+
+  subtotal numeric NOT NULL,
+  vat_total numeric NOT NULL,
+  total numeric NOT NULL,
+
+  PRIMARY KEY (credit_note_number),
+  CONSTRAINT credit_note_number_positive CHECK (credit_note_number > 0),
+  CONSTRAINT credit_once UNIQUE (statement_number),
+  CONSTRAINT reason_required CHECK (reason <> '')
+);
+
+CREATE FUNCTION reject_statement_changes() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION '% are immutable once issued', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER statements_immutable
+	BEFORE UPDATE OR DELETE ON statements
+	FOR EACH ROW EXECUTE PROCEDURE reject_statement_changes();
+
+CREATE TRIGGER credit_notes_immutable
+	BEFORE UPDATE OR DELETE ON credit_notes
+	FOR EACH ROW EXECUTE PROCEDURE reject_statement_changes();
+
+COMMIT;
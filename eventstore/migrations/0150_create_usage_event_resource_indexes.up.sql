@@ -0,0 +1,15 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- index the resource guids in raw usage events so the usage events of a
+-- single resource can be found for /resources/:resource_guid/history
+--
+
+CREATE INDEX IF NOT EXISTS app_usage_app_guid_idx ON app_usage_events ( (raw_message->>'app_guid') );
+CREATE INDEX IF NOT EXISTS app_usage_parent_app_guid_idx ON app_usage_events ( (raw_message->>'parent_app_guid') );
+CREATE INDEX IF NOT EXISTS app_usage_task_guid_idx ON app_usage_events ( (raw_message->>'task_guid') );
+CREATE INDEX IF NOT EXISTS service_usage_service_instance_guid_idx ON service_usage_events ( (raw_message->>'service_instance_guid') );
+
+COMMIT;
@@ -0,0 +1,27 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- usage events that were dropped, mis-paired or priced with a generated
+-- plan when events were last refreshed. the table is replaced after every
+-- refresh.
+--
+
+CREATE TABLE data_quality_issues (
+	category text NOT NULL,
+	event_guid uuid NOT NULL,
+	observed_at timestamptz NOT NULL,
+	org_guid uuid,
+	space_guid uuid,
+	resource_guid uuid,
+	resource_type text NOT NULL,
+	plan_guid uuid,
+	detail text NOT NULL,
+
+	PRIMARY KEY (category, event_guid)
+);
+
+CREATE INDEX data_quality_issues_org_idx ON data_quality_issues (org_guid);
+
+COMMIT;
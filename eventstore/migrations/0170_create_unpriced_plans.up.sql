@@ -0,0 +1,27 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- mark the £0 pricing plans made up by generateMissingPlans so they can be
+-- told apart from configured ones
+--
+
+ALTER TABLE pricing_plans ADD COLUMN generated boolean NOT NULL DEFAULT false;
+
+--
+-- plans seen in usage events with usage that no configured pricing plan
+-- covers. rows are kept once the plan is priced, with priced_at set, so
+-- there is a record of when each plan was onboarded.
+--
+
+CREATE TABLE unpriced_plans (
+	plan_guid uuid PRIMARY KEY NOT NULL,
+	plan_name text NOT NULL,
+	resource_type text NOT NULL,
+	first_seen_at timestamptz NOT NULL,
+	last_seen_at timestamptz NOT NULL,
+	priced_at timestamptz
+);
+
+COMMIT;
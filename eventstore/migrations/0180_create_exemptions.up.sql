@@ -0,0 +1,41 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- orgs, or spaces within them, whose usage is priced at £0 while the
+-- exemption is valid. replaces excluding usage in spaces named like test
+-- spaces. rows from the config are replaced whenever the store starts.
+--
+
+CREATE TABLE exemptions (
+	guid uuid PRIMARY KEY NOT NULL,
+	org_guid uuid NOT NULL,
+	space_guid uuid,
+	valid_from timestamptz NOT NULL,
+	valid_to timestamptz,
+	reason text NOT NULL CHECK (length(reason) > 0),
+	source text NOT NULL CHECK (source in ('config', 'api')),
+	created_at timestamptz NOT NULL DEFAULT now(),
+
+	CONSTRAINT valid_to_after_valid_from CHECK (valid_to is null or valid_to > valid_from)
+);
+
+CREATE INDEX exemptions_org_idx ON exemptions (org_guid);
+
+--
+-- replaced on every refresh by create_event_exemption_periods.sql, created
+-- here so exemptions can be listed before the first refresh
+--
+
+CREATE TABLE event_exemption_periods (
+	event_guid uuid NOT NULL,
+	duration tstzrange NOT NULL,
+	exemption_guid uuid,
+
+	PRIMARY KEY (event_guid, duration)
+);
+
+CREATE INDEX event_exemption_periods_exemption_idx ON event_exemption_periods (exemption_guid);
+
+COMMIT;
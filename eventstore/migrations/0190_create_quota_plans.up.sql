@@ -0,0 +1,52 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- prices for orgs with a quota definition, replaced from the config whenever
+-- the store starts. monthly_fee is prorated within each calendar month.
+--
+
+CREATE TABLE quota_plans (
+	quota_definition_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	name text NOT NULL CHECK (length(name) > 0),
+	monthly_fee numeric NOT NULL CHECK (monthly_fee >= 0),
+	currency_code currency_code NOT NULL,
+	vat_code vat_code NOT NULL,
+
+	PRIMARY KEY (quota_definition_guid, valid_from),
+	CONSTRAINT valid_from_start_of_month CHECK (
+	  (extract (day from valid_from)) = 1 AND
+	  (extract (hour from valid_from)) = 0 AND
+	  (extract (minute from valid_from)) = 0 AND
+	  (extract (second from valid_from)) = 0
+	)
+);
+
+--
+-- multipliers for the price of the resources of orgs with the quota. an
+-- empty component_name applies to every component without its own.
+--
+
+CREATE TABLE quota_plan_multipliers (
+	quota_definition_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	component_name text NOT NULL,
+	multiplier numeric NOT NULL CHECK (multiplier >= 0),
+
+	PRIMARY KEY (quota_definition_guid, valid_from, component_name),
+	FOREIGN KEY (quota_definition_guid, valid_from) REFERENCES quota_plans (quota_definition_guid, valid_from) ON DELETE CASCADE
+);
+
+--
+-- orgs that are no longer listed by Cloud Foundry, so their quota stops
+-- being charged for
+--
+
+CREATE TABLE org_deletions (
+	guid uuid PRIMARY KEY NOT NULL,
+	deleted_at timestamptz NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- the isolation segment each space is assigned to, collected with the
+-- space. null is the org's default segment, usually the shared one.
+--
+
+ALTER TABLE spaces ADD COLUMN isolation_segment_guid uuid;
+
+--
+-- plans for the compute resources of spaces in an isolation segment. their
+-- plan_guid is derived from the segment and the plan they replace.
+--
+
+ALTER TABLE pricing_plans ADD COLUMN isolation_segment_guid uuid;
+
+COMMIT;
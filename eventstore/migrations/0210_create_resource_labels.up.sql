@@ -0,0 +1,26 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- the cf metadata labels of orgs, spaces, apps and service instances, with
+-- a row each time they change
+--
+
+CREATE TABLE resource_labels (
+	guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	resource_type text NOT NULL CHECK (resource_type in ('org', 'space', 'app', 'service_instance')),
+	labels jsonb NOT NULL,
+
+	PRIMARY KEY (guid, valid_from)
+);
+
+--
+-- keep the labels of consolidated events so they can still be filtered
+-- and grouped by
+--
+
+ALTER TABLE consolidated_billable_events ADD COLUMN labels jsonb;
+
+COMMIT;
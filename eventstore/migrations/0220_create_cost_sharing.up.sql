@@ -0,0 +1,38 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- how the cost of a shared service instance is split between the spaces it
+-- is used in. weights maps space guids to their weight for the 'weights'
+-- method.
+--
+
+CREATE TABLE cost_sharing_rules (
+	service_instance_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	valid_to timestamptz,
+	method text NOT NULL CHECK (method in ('equal', 'bindings', 'weights')),
+	weights jsonb NOT NULL DEFAULT '{}',
+
+	PRIMARY KEY (service_instance_guid, valid_from),
+	CONSTRAINT valid_to_after_valid_from CHECK (valid_to is null or valid_to > valid_from)
+);
+
+--
+-- the spaces each service instance with a cost sharing rule is shared into
+-- and the number of apps bound to it in each space, with a row each time
+-- they change. cf does not record when a service instance was shared, so
+-- valid_from is when the change was collected.
+--
+
+CREATE TABLE service_instance_shares (
+	service_instance_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	shared_spaces jsonb NOT NULL,
+	bindings jsonb NOT NULL,
+
+	PRIMARY KEY (service_instance_guid, valid_from)
+);
+
+COMMIT;
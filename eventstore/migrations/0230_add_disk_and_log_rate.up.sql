@@ -0,0 +1,134 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- the disk quota and log rate limit of app processes, with a row each time
+-- a process is updated, and of tasks, which cannot change. log_rate_limit
+-- is in bytes per second and -1 is unlimited.
+--
+
+CREATE TABLE app_processes (
+	guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	app_guid uuid NOT NULL,
+	process_type text NOT NULL,
+	disk_in_mb integer,
+	log_rate_limit bigint,
+	created_at timestamptz NOT NULL,
+	updated_at timestamptz NOT NULL,
+
+	PRIMARY KEY (guid, valid_from)
+);
+
+CREATE INDEX app_processes_app_idx ON app_processes (app_guid, process_type);
+
+CREATE TABLE app_tasks (
+	guid uuid PRIMARY KEY NOT NULL,
+	app_guid uuid NOT NULL,
+	disk_in_mb integer,
+	log_rate_limit bigint,
+	created_at timestamptz NOT NULL
+);
+
+--
+-- let formulas use $disk_in_mb and $log_rate_limit. the five argument
+-- eval_formula is kept for formulas priced without them.
+--
+
+CREATE OR REPLACE FUNCTION compile_formula( formula text ) RETURNS text AS $$
+DECLARE
+	out text;
+BEGIN
+	out := coalesce(lower(formula), '0');
+	out := regexp_replace(out, '\$memory_in_mb', '($1::numeric)', 'g');
+	out := regexp_replace(out, '\$storage_in_mb', '($2::numeric)', 'g');
+	out := regexp_replace(out, '\$number_of_nodes', '($3::numeric)', 'g');
+	out := regexp_replace(out, '\$time_in_seconds', '($4::numeric)', 'g');
+	out := regexp_replace(out, '\$disk_in_mb', '($5::numeric)', 'g');
+	out := regexp_replace(out, '\$log_rate_limit', '($6::numeric)', 'g');
+	out := (select 'select (' || out || ')::numeric;');
+	return out;
+END; $$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION eval_formula(
+	memory_in_mb numeric,
+	storage_in_mb numeric,
+	number_of_nodes integer,
+	duration tstzrange,
+	formula text,
+	disk_in_mb numeric,
+	log_rate_limit numeric
+) returns numeric AS $$
+DECLARE
+	out numeric;
+BEGIN
+	execute compile_formula(formula) into out using
+		coalesce(memory_in_mb, 0),
+		coalesce(storage_in_mb, 0),
+		coalesce(number_of_nodes, 0),
+		extract(epoch from (upper(duration) - lower(duration))),
+		coalesce(disk_in_mb, 0),
+		coalesce(log_rate_limit, 0);
+	return out;
+END; $$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION eval_formula(
+	memory_in_mb numeric,
+	storage_in_mb numeric,
+	number_of_nodes integer,
+	duration tstzrange,
+	formula text
+) returns numeric AS $$
+BEGIN
+	return eval_formula(memory_in_mb, storage_in_mb, number_of_nodes, duration, formula, 0, 0);
+END; $$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION validate_formula() RETURNS trigger AS $$
+DECLARE
+	invalid_formula text;
+	illegal_token text;
+	dummy_price numeric;
+BEGIN
+	IF (NEW.formula = '') THEN
+		RAISE EXCEPTION 'formula can not be empty';
+	END IF;
+	invalid_formula := lower(NEW.formula);
+	invalid_formula := (select regexp_replace(invalid_formula, '::(integer|bigint|numeric)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)?\.([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$memory_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$storage_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$time_in_seconds', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$number_of_nodes', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$disk_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$log_rate_limit', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, 'ceil', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\(|\)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\*', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\-', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\/', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\^', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\s+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '#+', '', 'g'));
+	IF (invalid_formula != '') THEN
+		illegal_token := (select * from regexp_split_to_table(invalid_formula, '\s+') limit 1);
+		RAISE EXCEPTION 'illegal token in formula: %', illegal_token;
+	END IF;
+	-- attempt to use the formula to ensure it works with common edge case inputs
+	dummy_price := (select eval_formula(0, 0, 0, tstzrange(now(), now()), NEW.formula, 0, 0));
+	dummy_price := (select eval_formula(1, 1, 1, tstzrange(now(), now() + '1 second'), NEW.formula, 1, 1));
+	dummy_price := (select eval_formula(null, null, null, null, NEW.formula, null, null));
+	RETURN NEW;
+END;
+$$ language plpgsql;
+
+--
+-- keep the disk quota and log rate limit of consolidated events
+--
+
+ALTER TABLE consolidated_billable_events ADD COLUMN disk_in_mb integer;
+ALTER TABLE consolidated_billable_events ADD COLUMN log_rate_limit bigint;
+
+COMMIT;
@@ -0,0 +1,70 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- a pricing component can measure the usage it charges for in a unit, such
+-- as GB-hours, with a formula evaluated like its price formula
+--
+
+ALTER TABLE pricing_plan_components ADD COLUMN unit text NOT NULL DEFAULT '';
+ALTER TABLE pricing_plan_components ADD COLUMN quantity_formula text;
+ALTER TABLE pricing_plan_components ADD CONSTRAINT unit_requires_quantity_formula CHECK (
+	(unit = '') = (quantity_formula is null)
+);
+
+--
+-- check_formula is the body of validate_formula, so it can check both
+-- formulas of a component
+--
+
+CREATE OR REPLACE FUNCTION check_formula( formula text ) RETURNS void AS $$
+DECLARE
+	invalid_formula text;
+	illegal_token text;
+	dummy_price numeric;
+BEGIN
+	IF (formula = '') THEN
+		RAISE EXCEPTION 'formula can not be empty';
+	END IF;
+	invalid_formula := lower(formula);
+	invalid_formula := (select regexp_replace(invalid_formula, '::(integer|bigint|numeric)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)?\.([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$memory_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$storage_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$time_in_seconds', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$number_of_nodes', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$disk_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$log_rate_limit', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, 'ceil', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\(|\)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\*', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\-', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\/', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\^', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\s+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '#+', '', 'g'));
+	IF (invalid_formula != '') THEN
+		illegal_token := (select * from regexp_split_to_table(invalid_formula, '\s+') limit 1);
+		RAISE EXCEPTION 'illegal token in formula: %', illegal_token;
+	END IF;
+	-- attempt to use the formula to ensure it works with common edge case inputs
+	dummy_price := (select eval_formula(0, 0, 0, tstzrange(now(), now()), formula, 0, 0));
+	dummy_price := (select eval_formula(1, 1, 1, tstzrange(now(), now() + '1 second'), formula, 1, 1));
+	dummy_price := (select eval_formula(null, null, null, null, formula, null, null));
+END;
+$$ language plpgsql;
+
+CREATE OR REPLACE FUNCTION validate_formula() RETURNS trigger AS $$
+BEGIN
+	PERFORM check_formula(NEW.formula);
+	IF (NEW.quantity_formula IS NOT NULL) THEN
+		PERFORM check_formula(NEW.quantity_formula);
+	END IF;
+	RETURN NEW;
+END;
+$$ language plpgsql;
+
+COMMIT;
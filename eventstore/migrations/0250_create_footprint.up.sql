@@ -0,0 +1,44 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- footprint plans estimate the energy used by resources, in kWh, and the
+-- carbon they emit other than by using it, in gCO2e, with formulas like
+-- those of pricing plan components
+--
+
+CREATE TABLE footprint_plans (
+	plan_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	name text NOT NULL,
+	energy_formula text NOT NULL,
+	carbon_formula text NOT NULL DEFAULT '0',
+
+	PRIMARY KEY (plan_guid, valid_from),
+	CONSTRAINT name_must_not_be_blank CHECK (length(trim(name)) > 0)
+);
+
+CREATE OR REPLACE FUNCTION validate_footprint_formulas() RETURNS trigger AS $$
+BEGIN
+	PERFORM check_formula(NEW.energy_formula);
+	PERFORM check_formula(NEW.carbon_formula);
+	RETURN NEW;
+END;
+$$ language plpgsql;
+
+CREATE TRIGGER tgr_fp_validate_formulas BEFORE INSERT OR UPDATE ON footprint_plans FOR EACH ROW EXECUTE PROCEDURE validate_footprint_formulas();
+
+--
+-- the carbon emitted generating each kWh of grid energy from valid_from
+-- until the next grid intensity
+--
+
+CREATE TABLE grid_intensities (
+	valid_from timestamptz PRIMARY KEY NOT NULL,
+	gco2e_per_kwh numeric NOT NULL,
+
+	CONSTRAINT gco2e_per_kwh_must_not_be_negative CHECK (gco2e_per_kwh >= 0)
+);
+
+COMMIT;
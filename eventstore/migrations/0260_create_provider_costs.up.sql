@@ -0,0 +1,36 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- line items of cloud provider cost exports for the backing resources of
+-- service instances, imported with the import-costs command. importing a
+-- newer export of a line item replaces it.
+--
+
+CREATE TABLE provider_cost_events (
+	id SERIAL,
+	guid uuid UNIQUE NOT NULL,
+	created_at timestamptz NOT NULL,
+	raw_message JSONB NOT NULL
+);
+
+CREATE INDEX provider_cost_events_id_idx ON provider_cost_events (id);
+CREATE INDEX provider_cost_events_service_instance_idx ON provider_cost_events ( ((raw_message->>'service_instance_guid')::uuid) );
+
+--
+-- plans whose service instances are billed the provider costs imported for
+-- them, plus a markup, replaced from the config whenever the store starts.
+-- markup is a fraction of the cost, so 0.1 adds 10%.
+--
+
+CREATE TABLE provider_cost_rules (
+	plan_guid uuid NOT NULL,
+	valid_from timestamptz NOT NULL,
+	markup numeric NOT NULL DEFAULT 0 CHECK (markup >= 0),
+	vat_code vat_code NOT NULL,
+
+	PRIMARY KEY (plan_guid, valid_from)
+);
+
+COMMIT;
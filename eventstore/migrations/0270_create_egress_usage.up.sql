@@ -0,0 +1,97 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- the bytes each app sent in responses through the router during each hour,
+-- from the router access logs ingested with the ingest-egress command.
+-- source is a digest of the log the usage was read from, so ingesting a log
+-- again replaces its usage.
+--
+
+CREATE TABLE egress_usage (
+	source text NOT NULL,
+	app_guid uuid NOT NULL,
+	hour timestamptz NOT NULL,
+	bytes bigint NOT NULL CHECK (bytes >= 0),
+	requests bigint NOT NULL CHECK (requests >= 0),
+
+	PRIMARY KEY (source, app_guid, hour)
+);
+
+CREATE INDEX egress_usage_app_hour_idx ON egress_usage (app_guid, hour);
+
+--
+-- let formulas use $bytes. it is 0 unless bind_bytes has replaced it with
+-- the bytes of an egress event.
+--
+
+CREATE OR REPLACE FUNCTION compile_formula( formula text ) RETURNS text AS $$
+DECLARE
+	out text;
+BEGIN
+	out := coalesce(lower(formula), '0');
+	out := regexp_replace(out, '\$memory_in_mb', '($1::numeric)', 'g');
+	out := regexp_replace(out, '\$storage_in_mb', '($2::numeric)', 'g');
+	out := regexp_replace(out, '\$number_of_nodes', '($3::numeric)', 'g');
+	out := regexp_replace(out, '\$time_in_seconds', '($4::numeric)', 'g');
+	out := regexp_replace(out, '\$disk_in_mb', '($5::numeric)', 'g');
+	out := regexp_replace(out, '\$log_rate_limit', '($6::numeric)', 'g');
+	out := regexp_replace(out, '\$bytes', '(0::numeric)', 'g');
+	out := (select 'select (' || out || ')::numeric;');
+	return out;
+END; $$ LANGUAGE plpgsql;
+
+-- bind_bytes replaces $bytes in formula with the bytes sent during duration,
+-- spread evenly over it so parts of the duration are priced in proportion
+CREATE OR REPLACE FUNCTION bind_bytes( formula text, bytes bigint, duration tstzrange ) RETURNS text AS $$
+	select (case
+		when bytes is null or formula is null then formula
+		else regexp_replace(formula, '\$bytes', format('($time_in_seconds * %s / %s)',
+			bytes, extract(epoch from upper(duration) - lower(duration))
+		), 'gi')
+	end)
+$$ LANGUAGE SQL IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION check_formula( formula text ) RETURNS void AS $$
+DECLARE
+	invalid_formula text;
+	illegal_token text;
+	dummy_price numeric;
+BEGIN
+	IF (formula = '') THEN
+		RAISE EXCEPTION 'formula can not be empty';
+	END IF;
+	invalid_formula := lower(formula);
+	invalid_formula := (select regexp_replace(invalid_formula, '::(integer|bigint|numeric)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)?\.([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$memory_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$storage_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$time_in_seconds', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$number_of_nodes', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$disk_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$log_rate_limit', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$bytes', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, 'ceil', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\(|\)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\*', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\-', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\/', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\^', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\s+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '#+', '', 'g'));
+	IF (invalid_formula != '') THEN
+		illegal_token := (select * from regexp_split_to_table(invalid_formula, '\s+') limit 1);
+		RAISE EXCEPTION 'illegal token in formula: %', illegal_token;
+	END IF;
+	-- attempt to use the formula to ensure it works with common edge case inputs
+	dummy_price := (select eval_formula(0, 0, 0, tstzrange(now(), now()), formula, 0, 0));
+	dummy_price := (select eval_formula(1, 1, 1, tstzrange(now(), now() + '1 second'), formula, 1, 1));
+	dummy_price := (select eval_formula(null, null, null, null, formula, null, null));
+	dummy_price := (select eval_formula(1, 1, 1, tstzrange(now(), now() + '1 second'), bind_bytes(formula, 1, tstzrange(now(), now() + '1 second')), 1, 1));
+END;
+$$ language plpgsql;
+
+COMMIT;
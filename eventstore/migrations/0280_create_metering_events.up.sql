@@ -0,0 +1,104 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- quantities of metrics measured by service brokers for their service
+-- instances, posted to /metering. guid is derived from the broker and the
+-- record's idempotency key, so a record posted again is ignored.
+--
+
+CREATE TABLE metering_events (
+	id SERIAL,
+	guid uuid UNIQUE NOT NULL,
+	created_at timestamptz NOT NULL,
+	raw_message JSONB NOT NULL
+);
+
+CREATE INDEX metering_events_id_idx ON metering_events (id);
+CREATE INDEX metering_events_service_instance_idx ON metering_events ( ((raw_message->>'service_instance_guid')::uuid) );
+
+--
+-- let formulas use $metric_<name> for the quantity of a metered metric. it
+-- is 0 unless bind_metrics has replaced it with the quantity an event had.
+--
+
+CREATE OR REPLACE FUNCTION compile_formula( formula text ) RETURNS text AS $$
+DECLARE
+	out text;
+BEGIN
+	out := coalesce(lower(formula), '0');
+	out := regexp_replace(out, '\$memory_in_mb', '($1::numeric)', 'g');
+	out := regexp_replace(out, '\$storage_in_mb', '($2::numeric)', 'g');
+	out := regexp_replace(out, '\$number_of_nodes', '($3::numeric)', 'g');
+	out := regexp_replace(out, '\$time_in_seconds', '($4::numeric)', 'g');
+	out := regexp_replace(out, '\$disk_in_mb', '($5::numeric)', 'g');
+	out := regexp_replace(out, '\$log_rate_limit', '($6::numeric)', 'g');
+	out := regexp_replace(out, '\$bytes', '(0::numeric)', 'g');
+	out := regexp_replace(out, '\$metric_[a-z0-9_]+', '(0::numeric)', 'g');
+	out := (select 'select (' || out || ')::numeric;');
+	return out;
+END; $$ LANGUAGE plpgsql;
+
+-- bind_metrics replaces $metric_<name> in formula with the quantity of each
+-- metric in metrics measured during duration, spread evenly over it like
+-- bind_bytes
+CREATE OR REPLACE FUNCTION bind_metrics( formula text, metrics jsonb, duration tstzrange ) RETURNS text AS $$
+DECLARE
+	out text := formula;
+	metric record;
+BEGIN
+	IF formula IS NULL OR metrics IS NULL THEN
+		RETURN formula;
+	END IF;
+	FOR metric IN SELECT key, value FROM jsonb_each_text(metrics) LOOP
+		out := regexp_replace(out, '\$metric_' || metric.key || '\M', format('($time_in_seconds * %s / %s)',
+			metric.value, extract(epoch from upper(duration) - lower(duration))
+		), 'gi');
+	END LOOP;
+	RETURN out;
+END; $$ LANGUAGE plpgsql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION check_formula( formula text ) RETURNS void AS $$
+DECLARE
+	invalid_formula text;
+	illegal_token text;
+	dummy_price numeric;
+BEGIN
+	IF (formula = '') THEN
+		RAISE EXCEPTION 'formula can not be empty';
+	END IF;
+	invalid_formula := lower(formula);
+	invalid_formula := (select regexp_replace(invalid_formula, '\$metric_[a-z0-9_]+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '::(integer|bigint|numeric)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)?\.([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '([0-9]+)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$memory_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$storage_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$time_in_seconds', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$number_of_nodes', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$disk_in_mb', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$log_rate_limit', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\$bytes', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, 'ceil', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\(|\)', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\*', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\-', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\/', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\^', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '\s+', '#', 'g'));
+	invalid_formula := (select regexp_replace(invalid_formula, '#+', '', 'g'));
+	IF (invalid_formula != '') THEN
+		illegal_token := (select * from regexp_split_to_table(invalid_formula, '\s+') limit 1);
+		RAISE EXCEPTION 'illegal token in formula: %', illegal_token;
+	END IF;
+	-- attempt to use the formula to ensure it works with common edge case inputs
+	dummy_price := (select eval_formula(0, 0, 0, tstzrange(now(), now()), formula, 0, 0));
+	dummy_price := (select eval_formula(1, 1, 1, tstzrange(now(), now() + '1 second'), formula, 1, 1));
+	dummy_price := (select eval_formula(null, null, null, null, formula, null, null));
+	dummy_price := (select eval_formula(1, 1, 1, tstzrange(now(), now() + '1 second'), bind_bytes(formula, 1, tstzrange(now(), now() + '1 second')), 1, 1));
+END;
+$$ language plpgsql;
+
+COMMIT;
@@ -0,0 +1,27 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- usage of the plans in plan_guids, measured in unit, that each org is given
+-- free every calendar month from valid_from until valid_to. allowances with
+-- a quota_definition_guid only apply to orgs with that quota.
+--
+
+CREATE TABLE allowances (
+	name text NOT NULL,
+	valid_from timestamptz NOT NULL,
+	valid_to timestamptz,
+	plan_guids uuid[] NOT NULL,
+	unit text NOT NULL,
+	amount numeric NOT NULL,
+	quota_definition_guid uuid,
+
+	PRIMARY KEY (name, valid_from),
+	CONSTRAINT name_must_not_be_blank CHECK (length(trim(name)) > 0),
+	CONSTRAINT plan_guids_must_not_be_empty CHECK (cardinality(plan_guids) > 0),
+	CONSTRAINT amount_must_not_be_negative CHECK (amount >= 0),
+	CONSTRAINT valid_to_after_valid_from CHECK (valid_to is null or valid_to > valid_from)
+);
+
+COMMIT;
@@ -0,0 +1,37 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- the bytes sent by each request to an app's routes, from the router access
+-- logs ingested with the ingest-egress command. id is a digest of the
+-- request's access log line, so a line is only counted once however many
+-- logs it is read from.
+--
+
+CREATE TABLE egress_requests (
+	id bytea PRIMARY KEY,
+	app_guid uuid NOT NULL,
+	hour timestamptz NOT NULL,
+	bytes bigint NOT NULL CHECK (bytes >= 0)
+);
+
+CREATE INDEX egress_requests_app_hour_idx ON egress_requests (app_guid, hour);
+
+--
+-- usage already ingested is kept as a single request for each log, app and
+-- hour, as the lines it was read from are not known
+--
+
+INSERT INTO egress_requests (id, app_guid, hour, bytes)
+	SELECT
+		decode(md5(source || '/' || app_guid || '/' || hour), 'hex'),
+		app_guid,
+		hour,
+		bytes
+	FROM
+		egress_usage;
+
+DROP TABLE egress_usage;
+
+COMMIT;
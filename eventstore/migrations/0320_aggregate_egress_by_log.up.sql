@@ -0,0 +1,60 @@
+-- **do not alter - add new migrations instead**
+
+BEGIN;
+
+--
+-- the access logs ingested with the ingest-egress command. source is a
+-- digest of the log's first line, so it is the same however the log is
+-- named or compressed, and ingested_bytes is how much of the log has been
+-- ingested, so that ingesting it again only reads the lines appended since.
+--
+
+CREATE TABLE egress_sources (
+	source text PRIMARY KEY,
+	ingested_bytes bigint NOT NULL CHECK (ingested_bytes >= 0),
+	updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+--
+-- the bytes each app sent in responses through the router during each hour,
+-- totalled for each log they were read from
+--
+
+CREATE TABLE egress_usage (
+	source text NOT NULL REFERENCES egress_sources (source),
+	app_guid uuid NOT NULL,
+	hour timestamptz NOT NULL,
+	bytes bigint NOT NULL CHECK (bytes >= 0),
+	requests bigint NOT NULL CHECK (requests >= 0),
+
+	PRIMARY KEY (source, app_guid, hour)
+);
+
+CREATE INDEX egress_usage_app_hour_idx ON egress_usage (app_guid, hour);
+
+--
+-- the requests already ingested are kept as the usage of a source of their
+-- own, as the logs and offsets they were read from are not known
+--
+
+INSERT INTO egress_sources (source, ingested_bytes)
+	SELECT
+		'egress_requests', 0
+	WHERE
+		EXISTS (SELECT 1 FROM egress_requests);
+
+INSERT INTO egress_usage (source, app_guid, hour, bytes, requests)
+	SELECT
+		'egress_requests',
+		app_guid,
+		hour,
+		sum(bytes),
+		count(*)
+	FROM
+		egress_requests
+	GROUP BY
+		app_guid, hour;
+
+DROP TABLE egress_requests;
+
+COMMIT;
@@ -16,13 +16,20 @@ CREATE TABLE billable_event_components_temp (
 	number_of_nodes integer NOT NULL,
 	memory_in_mb numeric NOT NULL,
 	storage_in_mb numeric NOT NULL,
+	disk_in_mb numeric NOT NULL,
+	log_rate_limit numeric NOT NULL,
 	component_name text NOT NULL,
 	component_formula text NOT NULL,
+	unit text NOT NULL,
+	quantity_formula text,
 	currency_code currency_code NOT NULL,
 	currency_rate numeric NOT NULL,
 	vat_code vat_code NOT NULL,
 	vat_rate numeric NOT NULL,
 	cost_for_duration numeric NOT NULL,
+	quantity_for_duration numeric,
+	quota_definition_guid uuid,
+	labels jsonb NOT NULL,
 
 	PRIMARY KEY (event_guid, plan_guid, duration, component_name),
 	CONSTRAINT no_empty_duration CHECK (not isempty(duration))
@@ -56,6 +63,155 @@ CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF bi
 			)) as valid_for
 		from
 			vat_rates
+	),
+	valid_quota_plans as (
+		select
+			*,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				partition by quota_definition_guid order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			quota_plans
+	),
+	org_quota_periods as (
+		-- the quota each org had, from the org history, until it was deleted
+		select
+			guid as org_guid,
+			valid_from,
+			name as org_name,
+			quota_definition_guid,
+			tstzrange(valid_from, greatest(valid_from, lead(valid_from, 1, coalesce(deleted_at, 'infinity')) over (
+				partition by guid order by valid_from rows between current row and 1 following
+			))) as valid_for
+		from (
+			select
+				o.*,
+				od.deleted_at,
+				anydistinct(o.quota_definition_guid) over prev_neighb
+				or row_number() over prev_neighb = 1
+				as not_redundant
+			from
+				orgs o
+			left join
+				org_deletions od on od.guid = o.guid
+			window
+				prev_neighb as (
+					partition by o.guid
+					order by o.valid_from
+					rows between 1 preceding and current row
+				)
+		) as sq
+		where
+			not_redundant
+			and quota_definition_guid is not null
+	),
+	quota_fee_periods as (
+		-- quota fees are charged per calendar month, so split them by month
+		select
+			oqp.org_guid,
+			oqp.org_name,
+			oqp.valid_from as org_valid_from,
+			vqp.quota_definition_guid,
+			vqp.valid_from as plan_valid_from,
+			vqp.name as plan_name,
+			vqp.monthly_fee,
+			vqp.currency_code,
+			vqp.vat_code,
+			extract(epoch from (month + interval '1 month') - month) as seconds_in_month,
+			oqp.valid_for * vqp.valid_for * tstzrange(month, month + interval '1 month') * tstzrange('-infinity', now()) as duration
+		from
+			org_quota_periods oqp
+		join
+			valid_quota_plans vqp on vqp.quota_definition_guid = oqp.quota_definition_guid
+			and vqp.valid_for && oqp.valid_for
+		cross join lateral generate_series(
+			date_trunc('month', lower(oqp.valid_for * vqp.valid_for)),
+			least(upper(oqp.valid_for * vqp.valid_for), now()),
+			interval '1 month'
+		) as month
+		where
+			vqp.monthly_fee > 0
+	),
+	event_periods as (
+		select
+			ev.*,
+			coalesce(eep.duration, ev.duration) as period,
+			eep.exemption_guid
+		from
+			events ev
+		left join
+			event_exemption_periods eep on eep.event_guid = ev.event_guid
+	),
+	valid_provider_cost_rules as (
+		select
+			*,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				partition by plan_guid order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			provider_cost_rules
+	),
+	provider_costs as (
+		select
+			guid,
+			(raw_message->>'service_instance_guid')::uuid as service_instance_guid,
+			tstzrange(
+				(raw_message->>'usage_start')::timestamptz,
+				(raw_message->>'usage_stop')::timestamptz
+			) as usage_period,
+			raw_message->>'currency_code' as currency_code,
+			(raw_message->>'cost')::numeric as cost,
+			nullif(raw_message->>'usage_amount', '')::numeric as usage_amount,
+			coalesce(raw_message->>'unit', '') as unit
+		from
+			provider_cost_events
+	),
+	provider_cost_periods as (
+		-- each imported line item is billed to the service instance's event
+		-- it overlaps most, over the part of its usage period they overlap,
+		-- unless that part of the event is exempt
+		select
+			pc.guid as event_guid,
+			pc.currency_code,
+			pc.cost,
+			pc.usage_amount,
+			pc.unit,
+			ev.resource_guid,
+			ev.resource_name,
+			ev.resource_type,
+			ev.org_guid,
+			ev.org_name,
+			ev.space_guid,
+			ev.space_name,
+			ev.plan_guid,
+			ev.plan_name,
+			ev.number_of_nodes,
+			ev.memory_in_mb,
+			ev.storage_in_mb,
+			ev.disk_in_mb,
+			ev.log_rate_limit,
+			ev.quota_definition_guid,
+			ev.labels,
+			ev.cost_period
+		from
+			provider_costs pc
+		cross join lateral (
+			select
+				ev.*,
+				ev.period * pc.usage_period as cost_period
+			from
+				event_periods ev
+			where
+				ev.resource_type = 'service'
+				and ev.resource_guid = pc.service_instance_guid
+				and ev.period && pc.usage_period
+			order by
+				upper(ev.period * pc.usage_period) - lower(ev.period * pc.usage_period) desc,
+				lower(ev.period) desc
+			limit 1
+		) ev
+		where
+			ev.exemption_guid is null
 	)
 	select
 		ev.event_guid,
@@ -66,15 +222,19 @@ CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF bi
 		ev.org_name,
 		ev.space_guid,
 		ev.space_name,
-		ev.duration * vpp.valid_for * vcr.valid_for * vvr.valid_for as duration,
+		ev.period * vpp.valid_for * vcr.valid_for * vvr.valid_for as duration,
 		vpp.plan_guid as plan_guid,
 		vpp.valid_from as plan_valid_from,
 		vpp.name as plan_name,
 		coalesce(ev.number_of_nodes, vpp.number_of_nodes)::integer as number_of_nodes,
 		coalesce(ev.memory_in_mb, vpp.memory_in_mb)::numeric as memory_in_mb,
 		coalesce(ev.storage_in_mb, vpp.storage_in_mb)::numeric as storage_in_mb,
+		coalesce(ev.disk_in_mb, 0)::numeric as disk_in_mb,
+		coalesce(ev.log_rate_limit, 0)::numeric as log_rate_limit,
 		ppc.name AS component_name,
-		ppc.formula as component_formula,
+		coalesce('(' || bound.formula || ') * ' || qm.multiplier, bound.formula) as component_formula,
+		ppc.unit,
+		bound.quantity_formula,
 		vcr.code as currency_code,
 		vcr.rate as currency_rate,
 		vvr.code as vat_code,
@@ -83,27 +243,654 @@ CREATE OR REPLACE FUNCTION generate_billable_event_components() RETURNS SETOF bi
 			coalesce(ev.memory_in_mb, vpp.memory_in_mb)::numeric,
 			coalesce(ev.storage_in_mb, vpp.storage_in_mb)::numeric,
 			coalesce(ev.number_of_nodes, vpp.number_of_nodes)::integer,
-			ev.duration * vpp.valid_for * vcr.valid_for * vvr.valid_for,
-			ppc.formula
-		) * vcr.rate) as cost_for_duration
+			ev.period * vpp.valid_for * vcr.valid_for * vvr.valid_for,
+			coalesce('(' || bound.formula || ') * ' || qm.multiplier, bound.formula),
+			coalesce(ev.disk_in_mb, 0)::numeric,
+			coalesce(ev.log_rate_limit, 0)::numeric
+		) * vcr.rate) as cost_for_duration,
+		-- quantities measure usage, so are not scaled by quota multipliers
+		(case when ppc.quantity_formula is not null then eval_formula(
+			coalesce(ev.memory_in_mb, vpp.memory_in_mb)::numeric,
+			coalesce(ev.storage_in_mb, vpp.storage_in_mb)::numeric,
+			coalesce(ev.number_of_nodes, vpp.number_of_nodes)::integer,
+			ev.period * vpp.valid_for * vcr.valid_for * vvr.valid_for,
+			bound.quantity_formula,
+			coalesce(ev.disk_in_mb, 0)::numeric,
+			coalesce(ev.log_rate_limit, 0)::numeric
+		) end) as quantity_for_duration,
+		ev.quota_definition_guid,
+		ev.labels
 	from
-		events ev
+		event_periods ev
 	left join
 		valid_pricing_plans vpp on ev.plan_guid = vpp.plan_guid
-		and vpp.valid_for && ev.duration
+		and vpp.valid_for && ev.period
 	left join
 		pricing_plan_components ppc on ppc.plan_guid = vpp.plan_guid
 		and ppc.valid_from = vpp.valid_from
 	left join
 		valid_currency_rates vcr on vcr.code = ppc.currency_code
-		and vcr.valid_for && (ev.duration * vpp.valid_for)
+		and vcr.valid_for && (ev.period * vpp.valid_for)
 	left join
 		valid_vat_rates vvr on vvr.code = ppc.vat_code
-		and vvr.valid_for && (ev.duration * vpp.valid_for * vcr.valid_for)
+		and vvr.valid_for && (ev.period * vpp.valid_for * vcr.valid_for)
+	left join lateral (
+		-- the multiplier for the component in the org's quota plan, falling
+		-- back to the multiplier for every component
+		select
+			qpm.multiplier
+		from
+			valid_quota_plans vqp
+		join
+			quota_plan_multipliers qpm on qpm.quota_definition_guid = vqp.quota_definition_guid
+			and qpm.valid_from = vqp.valid_from
+		where
+			vqp.quota_definition_guid = ev.quota_definition_guid
+			and vqp.valid_for @> lower(ev.period * vpp.valid_for)
+			and qpm.component_name in (ppc.name, '')
+		order by
+			qpm.component_name = ''
+		limit 1
+	) qm on true
+	cross join lateral (
+		-- $bytes and $metric_<name> are the bytes sent during egress events
+		-- and the quantities metered during service events, and 0 otherwise
+		select
+			bind_metrics(bind_bytes(ppc.formula, ev.egress_bytes, ev.duration), ev.metrics, ev.duration) as formula,
+			bind_metrics(bind_bytes(ppc.quantity_formula, ev.egress_bytes, ev.duration), ev.metrics, ev.duration) as quantity_formula
+	) bound
+	where
+		ev.exemption_guid is null
+	union all
+	-- exempt periods are priced at zero with a single component, so they
+	-- need no pricing plan
+	select
+		ev.event_guid,
+		ev.resource_guid,
+		ev.resource_name,
+		ev.resource_type,
+		ev.org_guid,
+		ev.org_name,
+		ev.space_guid,
+		ev.space_name,
+		ev.period as duration,
+		ev.plan_guid,
+		x.valid_from as plan_valid_from,
+		ev.plan_name,
+		coalesce(ev.number_of_nodes, vpp.number_of_nodes, 0)::integer as number_of_nodes,
+		coalesce(ev.memory_in_mb, vpp.memory_in_mb, 0)::numeric as memory_in_mb,
+		coalesce(ev.storage_in_mb, vpp.storage_in_mb, 0)::numeric as storage_in_mb,
+		coalesce(ev.disk_in_mb, 0)::numeric as disk_in_mb,
+		coalesce(ev.log_rate_limit, 0)::numeric as log_rate_limit,
+		'exempt' as component_name,
+		'0' as component_formula,
+		'' as unit,
+		null::text as quantity_formula,
+		'GBP'::currency_code as currency_code,
+		1 as currency_rate,
+		'Zero'::vat_code as vat_code,
+		0 as vat_rate,
+		0 as cost_for_duration,
+		null::numeric as quantity_for_duration,
+		ev.quota_definition_guid,
+		ev.labels
+	from
+		event_periods ev
+	join
+		exemptions x on x.guid = ev.exemption_guid
+	left join
+		valid_pricing_plans vpp on ev.plan_guid = vpp.plan_guid
+		and vpp.valid_for @> lower(ev.period)
+	union all
+	-- quota fees are billed as a "quota" resource for each org, outside of
+	-- any space
+	select
+		uuid_generate_v5(uuid_ns_url(), 'quota/' || qfp.org_guid || '/' || qfp.org_valid_from) as event_guid,
+		qfp.org_guid as resource_guid,
+		qfp.plan_name as resource_name,
+		'quota' as resource_type,
+		qfp.org_guid,
+		qfp.org_name,
+		'00000000-0000-0000-0000-000000000000'::uuid as space_guid,
+		'' as space_name,
+		qfp.duration * vcr.valid_for * vvr.valid_for as duration,
+		qfp.quota_definition_guid as plan_guid,
+		qfp.plan_valid_from,
+		qfp.plan_name,
+		1 as number_of_nodes,
+		0 as memory_in_mb,
+		0 as storage_in_mb,
+		0 as disk_in_mb,
+		0 as log_rate_limit,
+		'quota' as component_name,
+		format('$time_in_seconds * %s / %s', qfp.monthly_fee, qfp.seconds_in_month) as component_formula,
+		'' as unit,
+		null::text as quantity_formula,
+		vcr.code as currency_code,
+		vcr.rate as currency_rate,
+		vvr.code as vat_code,
+		vvr.rate as vat_rate,
+		(eval_formula(
+			0,
+			0,
+			1,
+			qfp.duration * vcr.valid_for * vvr.valid_for,
+			format('$time_in_seconds * %s / %s', qfp.monthly_fee, qfp.seconds_in_month)
+		) * vcr.rate) as cost_for_duration,
+		null::numeric as quantity_for_duration,
+		qfp.quota_definition_guid,
+		'{}'::jsonb as labels
+	from
+		quota_fee_periods qfp
+	join
+		valid_currency_rates vcr on vcr.code = qfp.currency_code
+		and vcr.valid_for && qfp.duration
+	join
+		valid_vat_rates vvr on vvr.code = qfp.vat_code
+		and vvr.valid_for && (qfp.duration * vcr.valid_for)
+	where
+		not isempty(qfp.duration)
+	union all
+	-- imported provider costs are passed through with the markup of the
+	-- plan's provider cost rule, spread evenly over the period billed
+	select
+		pcp.event_guid,
+		pcp.resource_guid,
+		pcp.resource_name,
+		pcp.resource_type,
+		pcp.org_guid,
+		pcp.org_name,
+		pcp.space_guid,
+		pcp.space_name,
+		pcp.cost_period as duration,
+		pcp.plan_guid,
+		vpcr.valid_from as plan_valid_from,
+		pcp.plan_name,
+		coalesce(pcp.number_of_nodes, 0)::integer as number_of_nodes,
+		coalesce(pcp.memory_in_mb, 0)::numeric as memory_in_mb,
+		coalesce(pcp.storage_in_mb, 0)::numeric as storage_in_mb,
+		coalesce(pcp.disk_in_mb, 0)::numeric as disk_in_mb,
+		coalesce(pcp.log_rate_limit, 0)::numeric as log_rate_limit,
+		'provider_cost' as component_name,
+		format('$time_in_seconds * (%s) * %s / %s',
+			pcp.cost, 1 + vpcr.markup, extract(epoch from upper(pcp.cost_period) - lower(pcp.cost_period))
+		) as component_formula,
+		pcp.unit,
+		(case when pcp.usage_amount is not null then format('$time_in_seconds * (%s) / %s',
+			pcp.usage_amount, extract(epoch from upper(pcp.cost_period) - lower(pcp.cost_period))
+		) end) as quantity_formula,
+		vcr.code as currency_code,
+		vcr.rate as currency_rate,
+		vvr.code as vat_code,
+		vvr.rate as vat_rate,
+		pcp.cost * (1 + vpcr.markup) * vcr.rate as cost_for_duration,
+		pcp.usage_amount as quantity_for_duration,
+		pcp.quota_definition_guid,
+		pcp.labels
+	from
+		provider_cost_periods pcp
+	join
+		valid_provider_cost_rules vpcr on vpcr.plan_guid = pcp.plan_guid
+		and vpcr.valid_for @> lower(pcp.cost_period)
+	join
+		valid_currency_rates vcr on vcr.code::text = pcp.currency_code
+		and vcr.valid_for @> lower(pcp.cost_period)
+	join
+		valid_vat_rates vvr on vvr.code = vpcr.vat_code
+		and vvr.valid_for @> lower(pcp.cost_period)
 ; $$ LANGUAGE SQL;
 
 INSERT INTO billable_event_components_temp (select * from generate_billable_event_components());
 
+-- split the priced components of shared service instances with a cost
+-- sharing rule between the owning space and the spaces they are shared
+-- into, using the sharing recorded by the collector. each consuming space
+-- is billed its share as a separate event in its own org and the owning
+-- space is given a credit event for the same amount, so the original event
+-- keeps its full price.
+INSERT INTO billable_event_components_temp with
+	valid_cost_sharing_rules as (
+		select
+			*,
+			tstzrange(valid_from, least(coalesce(valid_to, 'infinity'), lead(valid_from, 1, 'infinity') over (
+				partition by service_instance_guid order by valid_from rows between current row and 1 following
+			))) as valid_for
+		from
+			cost_sharing_rules
+	),
+	valid_service_instance_shares as (
+		select
+			*,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				partition by service_instance_guid order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			service_instance_shares
+	),
+	sharing_periods as (
+		select
+			c.*,
+			c.duration * r.valid_for * s.valid_for as share_period,
+			r.method,
+			r.weights,
+			s.shared_spaces,
+			s.bindings
+		from
+			billable_event_components_temp c
+		join
+			valid_cost_sharing_rules r on r.service_instance_guid = c.resource_guid
+			and r.valid_for && c.duration
+		join
+			valid_service_instance_shares s on s.service_instance_guid = c.resource_guid
+			and s.valid_for && (c.duration * r.valid_for)
+		where
+			c.component_name <> 'exempt'
+	),
+	space_weights as (
+		-- the owning space and every space the instance is shared into
+		select
+			sp.*,
+			sh.space_guid as share_space_guid,
+			sh.space_name as share_space_name,
+			sh.org_guid as share_org_guid,
+			sh.org_name as share_org_name,
+			case sp.method
+				when 'equal' then 1
+				when 'bindings' then coalesce((sp.bindings->>sh.space_guid::text)::numeric, 0)
+				else coalesce((sp.weights->>sh.space_guid::text)::numeric, 0)
+			end as weight
+		from
+			sharing_periods sp
+		cross join lateral (
+			select
+				sp.space_guid,
+				sp.space_name,
+				sp.org_guid,
+				sp.org_name
+			union all
+			select
+				(x->>'space_guid')::uuid,
+				x->>'space_name',
+				(x->>'org_guid')::uuid,
+				x->>'org_name'
+			from
+				jsonb_array_elements(sp.shared_spaces) x
+			where
+				(x->>'space_guid')::uuid <> sp.space_guid
+		) as sh (space_guid, space_name, org_guid, org_name)
+		where
+			not isempty(sp.share_period)
+	),
+	space_shares as (
+		select
+			*,
+			weight / nullif(sum(weight) over (
+				partition by event_guid, plan_guid, duration, component_name, share_period
+			), 0) as share
+		from
+			space_weights
+	),
+	shared_components as (
+		-- the share of each consuming space
+		select
+			uuid_generate_v5(uuid_ns_url(), 'shared/' || event_guid || '/' || share_space_guid) as event_guid,
+			resource_guid,
+			resource_name,
+			resource_type,
+			share_org_guid as org_guid,
+			share_org_name as org_name,
+			share_space_guid as space_guid,
+			share_space_name as space_name,
+			share_period as duration,
+			plan_guid,
+			plan_valid_from,
+			plan_name,
+			number_of_nodes,
+			memory_in_mb,
+			storage_in_mb,
+			disk_in_mb,
+			log_rate_limit,
+			component_name,
+			'(' || component_formula || ') * ' || share as component_formula,
+			unit,
+			'(' || quantity_formula || ') * ' || share as quantity_formula,
+			currency_code,
+			currency_rate,
+			vat_code,
+			vat_rate,
+			null::uuid as quota_definition_guid,
+			'{}'::jsonb as labels
+		from
+			space_shares
+		where
+			share_space_guid <> space_guid
+			and share > 0
+		union all
+		-- the credit to the owning space for the shares of the others
+		select
+			uuid_generate_v5(uuid_ns_url(), 'shared/' || event_guid || '/credit') as event_guid,
+			resource_guid,
+			resource_name,
+			resource_type,
+			org_guid,
+			org_name,
+			space_guid,
+			space_name,
+			share_period as duration,
+			plan_guid,
+			plan_valid_from,
+			plan_name,
+			number_of_nodes,
+			memory_in_mb,
+			storage_in_mb,
+			disk_in_mb,
+			log_rate_limit,
+			component_name,
+			'(' || component_formula || ') * ' || (share - 1) as component_formula,
+			unit,
+			'(' || quantity_formula || ') * ' || (share - 1) as quantity_formula,
+			currency_code,
+			currency_rate,
+			vat_code,
+			vat_rate,
+			quota_definition_guid,
+			labels
+		from
+			space_shares
+		where
+			share_space_guid = space_guid
+			and share < 1
+	)
+	select
+		event_guid,
+		resource_guid,
+		resource_name,
+		resource_type,
+		org_guid,
+		org_name,
+		space_guid,
+		space_name,
+		duration,
+		plan_guid,
+		plan_valid_from,
+		plan_name,
+		number_of_nodes,
+		memory_in_mb,
+		storage_in_mb,
+		disk_in_mb,
+		log_rate_limit,
+		component_name,
+		component_formula,
+		unit,
+		quantity_formula,
+		currency_code,
+		currency_rate,
+		vat_code,
+		vat_rate,
+		(eval_formula(
+			memory_in_mb,
+			storage_in_mb,
+			number_of_nodes,
+			duration,
+			component_formula,
+			disk_in_mb,
+			log_rate_limit
+		) * currency_rate) as cost_for_duration,
+		(case when quantity_formula is not null then eval_formula(
+			memory_in_mb,
+			storage_in_mb,
+			number_of_nodes,
+			duration,
+			quantity_formula,
+			disk_in_mb,
+			log_rate_limit
+		) end) as quantity_for_duration,
+		quota_definition_guid,
+		labels
+	from
+		shared_components
+;
+
+-- set the usage of each org against its allowances for each calendar month
+-- in the order it happened, until they are used up. allowances for the
+-- org's quota take the place of allowances for every org of the same plan
+-- and unit. the usage of a shared service instance is set against the
+-- allowances of each space's org by its share, so the owning space's usage
+-- is less the shares credited back to it. the part of each component an
+-- allowance covers is recorded in allowance_periods, and credited as a
+-- component named after the allowance with the same event guid, so it is
+-- taken off the event's price.
+CREATE TABLE allowance_periods_temp (
+	org_guid uuid NOT NULL,
+	allowance_name text NOT NULL,
+	allowance_valid_from timestamptz NOT NULL,
+	month timestamptz NOT NULL,
+	event_guid uuid NOT NULL,
+	plan_guid uuid NOT NULL,
+	duration tstzrange NOT NULL,
+	component_name text NOT NULL,
+	quantity numeric NOT NULL,
+	covered numeric NOT NULL,
+
+	PRIMARY KEY (allowance_name, allowance_valid_from, event_guid, plan_guid, duration, component_name),
+	CONSTRAINT no_empty_duration CHECK (not isempty(duration))
+);
+
+INSERT INTO allowance_periods_temp with
+	valid_allowances as (
+		select
+			*,
+			tstzrange(valid_from, least(coalesce(valid_to, 'infinity'), lead(valid_from, 1, 'infinity') over (
+				partition by name order by valid_from rows between current row and 1 following
+			))) as valid_for
+		from
+			allowances
+	),
+	allowance_months as (
+		-- the part of each component in each calendar month of an allowance
+		select
+			c.*,
+			a.name as allowance_name,
+			a.valid_from as allowance_valid_from,
+			a.quota_definition_guid as allowance_quota_definition_guid,
+			a.amount,
+			month,
+			c.duration * a.valid_for * tstzrange(month, month + interval '1 month') as period
+		from
+			billable_event_components_temp c
+		join
+			valid_allowances a on c.plan_guid = any(a.plan_guids)
+			and a.unit = c.unit
+			and a.valid_for && c.duration
+			and (a.quota_definition_guid is null or a.quota_definition_guid = c.quota_definition_guid)
+		cross join lateral generate_series(
+			date_trunc('month', lower(c.duration * a.valid_for)),
+			upper(c.duration * a.valid_for),
+			interval '1 month'
+		) as month
+		where
+			c.quantity_formula is not null
+			and c.component_name <> 'exempt'
+	),
+	allowance_quantities as (
+		select
+			*,
+			eval_formula(
+				memory_in_mb,
+				storage_in_mb,
+				number_of_nodes,
+				period,
+				quantity_formula,
+				disk_in_mb,
+				log_rate_limit
+			) + coalesce((
+				-- the shares of other spaces credited back to the owning space
+				select
+					sum(eval_formula(
+						sc.memory_in_mb,
+						sc.storage_in_mb,
+						sc.number_of_nodes,
+						sc.duration * am.period,
+						sc.quantity_formula,
+						sc.disk_in_mb,
+						sc.log_rate_limit
+					))
+				from
+					billable_event_components_temp sc
+				where
+					sc.event_guid = uuid_generate_v5(uuid_ns_url(), 'shared/' || am.event_guid || '/credit')
+					and sc.plan_guid = am.plan_guid
+					and sc.component_name = am.component_name
+					and sc.duration && am.period
+			), 0) as quantity
+		from
+			allowance_months am
+		where
+			not isempty(period)
+			and (allowance_quota_definition_guid is not null or not exists (
+				select
+					1
+				from
+					valid_allowances qa
+				where
+					qa.quota_definition_guid = am.quota_definition_guid
+					and am.plan_guid = any(qa.plan_guids)
+					and qa.unit = am.unit
+					and qa.valid_for && am.period
+			))
+	),
+	allowance_use as (
+		select
+			*,
+			coalesce(sum(quantity) over (
+				partition by org_guid, allowance_name, allowance_valid_from, month
+				order by lower(period), event_guid, plan_guid, component_name
+				rows between unbounded preceding and 1 preceding
+			), 0) as used_before
+		from
+			allowance_quantities
+		where
+			quantity > 0
+	)
+	select
+		org_guid,
+		allowance_name,
+		allowance_valid_from,
+		month,
+		event_guid,
+		plan_guid,
+		period as duration,
+		component_name,
+		quantity,
+		greatest(0, least(quantity, amount - used_before)) as covered
+	from
+		allowance_use
+;
+
+INSERT INTO billable_event_components_temp with
+	allowance_credits as (
+		select
+			c.event_guid,
+			c.resource_guid,
+			c.resource_name,
+			c.resource_type,
+			c.org_guid,
+			c.org_name,
+			c.space_guid,
+			c.space_name,
+			ap.duration,
+			c.plan_guid,
+			c.plan_valid_from,
+			c.plan_name,
+			c.number_of_nodes,
+			c.memory_in_mb,
+			c.storage_in_mb,
+			c.disk_in_mb,
+			c.log_rate_limit,
+			ap.allowance_name || ' (' || c.component_name || ')' as component_name,
+			'(' || c.component_formula || ') * ' || -(ap.covered / cq.quantity) as component_formula,
+			c.unit,
+			'(' || c.quantity_formula || ') * ' || -(ap.covered / cq.quantity) as quantity_formula,
+			c.currency_code,
+			c.currency_rate,
+			c.vat_code,
+			c.vat_rate,
+			c.quota_definition_guid,
+			c.labels
+		from
+			allowance_periods_temp ap
+		join
+			billable_event_components_temp c on c.event_guid = ap.event_guid
+			and c.plan_guid = ap.plan_guid
+			and c.component_name = ap.component_name
+			and c.duration @> ap.duration
+		cross join lateral (
+			-- the whole quantity of the component, before any of it was
+			-- credited to other spaces
+			select
+				eval_formula(
+					c.memory_in_mb,
+					c.storage_in_mb,
+					c.number_of_nodes,
+					ap.duration,
+					c.quantity_formula,
+					c.disk_in_mb,
+					c.log_rate_limit
+				) as quantity
+		) as cq
+		where
+			ap.covered > 0
+	)
+	select
+		event_guid,
+		resource_guid,
+		resource_name,
+		resource_type,
+		org_guid,
+		org_name,
+		space_guid,
+		space_name,
+		duration,
+		plan_guid,
+		plan_valid_from,
+		plan_name,
+		number_of_nodes,
+		memory_in_mb,
+		storage_in_mb,
+		disk_in_mb,
+		log_rate_limit,
+		component_name,
+		component_formula,
+		unit,
+		quantity_formula,
+		currency_code,
+		currency_rate,
+		vat_code,
+		vat_rate,
+		(eval_formula(
+			memory_in_mb,
+			storage_in_mb,
+			number_of_nodes,
+			duration,
+			component_formula,
+			disk_in_mb,
+			log_rate_limit
+		) * currency_rate) as cost_for_duration,
+		eval_formula(
+			memory_in_mb,
+			storage_in_mb,
+			number_of_nodes,
+			duration,
+			quantity_formula,
+			disk_in_mb,
+			log_rate_limit
+		) as quantity_for_duration,
+		quota_definition_guid,
+		labels
+	from
+		allowance_credits
+;
+
 CREATE INDEX billable_event_components_temp_org_idx on billable_event_components_temp (org_guid);
 CREATE INDEX billable_event_components_temp_space_idx on billable_event_components_temp (space_guid);
 CREATE INDEX billable_event_components_temp_duration_idx on billable_event_components_temp using gist (duration);
@@ -116,3 +903,9 @@ ALTER INDEX billable_event_components_temp_space_idx RENAME TO billable_event_co
 ALTER INDEX billable_event_components_temp_duration_idx RENAME TO billable_event_components_duration_idx;
 
 ANALYZE billable_event_components;
+
+DROP TABLE IF EXISTS allowance_periods;
+ALTER TABLE allowance_periods_temp RENAME TO allowance_periods;
+ALTER INDEX allowance_periods_temp_pkey RENAME TO allowance_periods_pkey;
+
+ANALYZE allowance_periods;
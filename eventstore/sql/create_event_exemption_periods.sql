@@ -0,0 +1,85 @@
+-- split every event that an exemption overlaps at the exemption boundaries
+-- so each period is either fully exempt or not exempt at all.
+-- exemption_guid is the earliest exemption covering the period, or null if
+-- no exemption covers it. events that no exemption overlaps are not listed.
+CREATE TABLE event_exemption_periods_temp (
+	event_guid uuid NOT NULL,
+	duration tstzrange NOT NULL,
+	exemption_guid uuid,
+
+	PRIMARY KEY (event_guid, duration),
+	CONSTRAINT duration_must_not_be_empty CHECK (not isempty(duration))
+);
+
+INSERT INTO event_exemption_periods_temp with
+	valid_exemptions as (
+		select
+			guid,
+			org_guid,
+			space_guid,
+			tstzrange(valid_from, coalesce(valid_to, 'infinity')) as valid_for
+		from
+			exemptions
+	),
+	exempt_events as (
+		select
+			ev.event_guid,
+			ev.duration,
+			x.guid as exemption_guid,
+			x.valid_for
+		from
+			events ev
+		join
+			valid_exemptions x on x.org_guid = ev.org_guid
+			and (x.space_guid is null or x.space_guid = ev.space_guid)
+			and x.valid_for && ev.duration
+	),
+	boundaries as (
+		select event_guid, lower(duration) as boundary from exempt_events
+		union
+		select event_guid, upper(duration) as boundary from exempt_events
+		union
+		select event_guid, lower(valid_for) as boundary from exempt_events
+		where duration @> lower(valid_for)
+		union
+		select event_guid, upper(valid_for) as boundary from exempt_events
+		where duration @> upper(valid_for)
+	),
+	periods as (
+		select
+			event_guid,
+			tstzrange(boundary, next_boundary) as duration
+		from (
+			select
+				event_guid,
+				boundary,
+				lead(boundary) over (
+					partition by event_guid order by boundary
+				) as next_boundary
+			from
+				boundaries
+		) b
+		where
+			next_boundary is not null
+	)
+	select distinct on (p.event_guid, p.duration)
+		p.event_guid,
+		p.duration,
+		ee.exemption_guid
+	from
+		periods p
+	left join
+		exempt_events ee on ee.event_guid = p.event_guid
+		and ee.valid_for @> p.duration
+	order by
+		p.event_guid, p.duration, lower(ee.valid_for), ee.exemption_guid
+;
+
+CREATE INDEX event_exemption_periods_exemption_temp_idx ON event_exemption_periods_temp (exemption_guid);
+
+DROP TABLE IF EXISTS event_exemption_periods;
+ALTER TABLE event_exemption_periods_temp RENAME TO event_exemption_periods;
+ALTER INDEX event_exemption_periods_temp_pkey RENAME TO event_exemption_periods_pkey;
+ALTER INDEX event_exemption_periods_exemption_temp_idx RENAME TO event_exemption_periods_exemption_idx;
+
+ANALYZE event_exemption_periods;
@@ -0,0 +1,70 @@
+-- split every event with a footprint plan at the boundaries of the plan's
+-- versions and of the grid intensities, so each period has a single energy
+-- formula, carbon formula and grid intensity. gco2e_per_kwh is null before
+-- the first grid intensity. events without a footprint plan are not listed.
+CREATE TABLE event_footprint_periods_temp (
+	event_guid uuid NOT NULL,
+	duration tstzrange NOT NULL,
+	plan_guid uuid NOT NULL,
+	plan_valid_from timestamptz NOT NULL,
+	plan_name text NOT NULL,
+	energy_formula text NOT NULL,
+	carbon_formula text NOT NULL,
+	gco2e_per_kwh numeric,
+
+	PRIMARY KEY (event_guid, duration),
+	CONSTRAINT no_empty_duration CHECK (not isempty(duration))
+);
+
+INSERT INTO event_footprint_periods_temp with
+	valid_footprint_plans as (
+		select
+			*,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				partition by plan_guid order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			footprint_plans
+	),
+	valid_grid_intensities as (
+		select
+			gco2e_per_kwh,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			grid_intensities
+		union all
+		-- there is always a period before the first grid intensity, which
+		-- covers everything if there are none
+		select
+			null as gco2e_per_kwh,
+			tstzrange('-infinity', coalesce(min(valid_from), 'infinity')) as valid_for
+		from
+			grid_intensities
+	)
+	select
+		ev.event_guid,
+		ev.duration * vfp.valid_for * vgi.valid_for as duration,
+		vfp.plan_guid,
+		vfp.valid_from as plan_valid_from,
+		vfp.name as plan_name,
+		bind_metrics(bind_bytes(vfp.energy_formula, ev.egress_bytes, ev.duration), ev.metrics, ev.duration) as energy_formula,
+		bind_metrics(bind_bytes(vfp.carbon_formula, ev.egress_bytes, ev.duration), ev.metrics, ev.duration) as carbon_formula,
+		vgi.gco2e_per_kwh
+	from
+		events ev
+	join
+		valid_footprint_plans vfp on vfp.plan_guid = ev.plan_guid
+		and vfp.valid_for && ev.duration
+	join
+		valid_grid_intensities vgi on vgi.valid_for && (ev.duration * vfp.valid_for)
+	where
+		not isempty(ev.duration * vfp.valid_for * vgi.valid_for)
+;
+
+DROP TABLE IF EXISTS event_footprint_periods;
+ALTER TABLE event_footprint_periods_temp RENAME TO event_footprint_periods;
+ALTER INDEX event_footprint_periods_temp_pkey RENAME TO event_footprint_periods_pkey;
+
+ANALYZE event_footprint_periods;
@@ -15,6 +15,13 @@ CREATE TABLE events_temp (
 	number_of_nodes integer,
 	memory_in_mb integer,
 	storage_in_mb integer,
+	disk_in_mb integer,
+	log_rate_limit bigint,
+	quota_definition_guid uuid,
+	isolation_segment_guid uuid,
+	labels jsonb NOT NULL DEFAULT '{}',
+	egress_bytes bigint,
+	metrics jsonb,
 
 	CONSTRAINT duration_must_not_be_empty CHECK (not isempty(duration))
 );
@@ -42,12 +49,12 @@ INSERT INTO events_temp with
 				coalesce(raw_message->>'instance_count', '1')::numeric as number_of_nodes,
 				coalesce(raw_message->>'memory_in_mb_per_instance', '0')::numeric as memory_in_mb,
 				'0'::numeric as storage_in_mb,
+				coalesce(raw_message->>'process_type', 'web') as process_type,
 				(raw_message->>'state')::resource_state as state
 			from
 				app_usage_events
 			where
 				(raw_message->>'state' = 'STARTED' or raw_message->>'state' = 'STOPPED')
-				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
 		) union all (
 			select
 				id as event_sequence,
@@ -66,6 +73,7 @@ INSERT INTO events_temp with
 				NULL::numeric as number_of_nodes,
 				NULL::numeric as memory_in_mb,
 				NULL::numeric as storage_in_mb,
+				NULL::text as process_type,
 				(case
 					when (raw_message->>'state') = 'CREATED' then 'STARTED'
 					when (raw_message->>'state') = 'DELETED' then 'STOPPED'
@@ -75,7 +83,6 @@ INSERT INTO events_temp with
 				service_usage_events
 			where
 				raw_message->>'service_instance_type' = 'managed_service_instance'
-				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
 		) union all (
 			select
 				id as event_sequence,
@@ -94,6 +101,7 @@ INSERT INTO events_temp with
 				coalesce(raw_message->>'instance_count', '1')::numeric as number_of_nodes,
 				coalesce(raw_message->>'memory_in_mb_per_instance', '0')::numeric as memory_in_mb,
 				'0'::numeric as storage_in_mb,
+				NULL::text as process_type,
 				(case
 					when (raw_message->>'state') = 'TASK_STARTED' then 'STARTED'
 					when (raw_message->>'state') = 'TASK_STOPPED' then 'STOPPED'
@@ -102,7 +110,6 @@ INSERT INTO events_temp with
 				app_usage_events
 			where
 				(raw_message->>'state' = 'TASK_STARTED' or raw_message->>'state' = 'TASK_STOPPED')
-				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
 		) union all (
 			select
 				id as event_sequence,
@@ -121,6 +128,7 @@ INSERT INTO events_temp with
 				'1'::numeric as number_of_nodes,
 				coalesce(raw_message->>'memory_in_mb_per_instance', '0')::numeric as memory_in_mb,
 				'0'::numeric as storage_in_mb,
+				NULL::text as process_type,
 				(case
 					when (raw_message->>'state') = 'STAGING_STARTED' then 'STARTED'
 					when (raw_message->>'state') = 'STAGING_STOPPED' then 'STOPPED'
@@ -129,7 +137,6 @@ INSERT INTO events_temp with
 				app_usage_events
 			where
 				(raw_message->>'state' = 'STAGING_STARTED' or raw_message->>'state' = 'STAGING_STOPPED')
-				and raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
 		) union all (
 			select
 				s.id as event_sequence,
@@ -154,6 +161,7 @@ INSERT INTO events_temp with
 				NULL::numeric as number_of_nodes,
 				(pg_size_bytes(c.raw_message->'data'->>'memory') / 1024 / 1024)::numeric as memory_in_mb,
 				(pg_size_bytes(c.raw_message->'data'->>'storage') / 1024 / 1024)::numeric as storage_in_mb,
+				NULL::text as process_type,
 				'STARTED'::resource_state as state
 			from
 				compose_audit_events c
@@ -165,7 +173,7 @@ INSERT INTO events_temp with
 					from '[a-zA-Z0-9]{8}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{4}-[a-zA-Z0-9]{12}$'
 				) AND s.raw_message->>'state' = 'CREATED'
 			where
-				s.raw_message->>'space_name' !~ '^(SMOKE|ACC|CATS|PERF)-' -- FIXME: this is open to abuse
+				s.id is not null -- compose events are only billed for known service instances
 		)
 	),
 	raw_events_with_injected_values as (
@@ -186,6 +194,7 @@ INSERT INTO events_temp with
 			number_of_nodes,
 			last_agg(memory_in_mb) FILTER (WHERE memory_in_mb IS NOT NULL) over prev_events as memory_in_mb,
 			last_agg(storage_in_mb) FILTER (WHERE storage_in_mb IS NOT NULL) over prev_events as storage_in_mb,
+			process_type,
 			state
 		from
 			raw_events
@@ -281,10 +290,12 @@ INSERT INTO events_temp with
 				guid,
 				valid_from,
 				anydistinct(name) OVER prev_neighb
+				OR anydistinct(quota_definition_guid) OVER prev_neighb
 				OR row_number() OVER prev_neighb = 1
 				AS not_redundant,
 				-- only expose fields we've considered in not_redundant
-				name
+				name,
+				quota_definition_guid
 			FROM orgs
 			WINDOW
 				prev_neighb AS (
@@ -307,10 +318,12 @@ INSERT INTO events_temp with
 				guid,
 				valid_from,
 				anydistinct(name) OVER prev_neighb
+				OR anydistinct(isolation_segment_guid) OVER prev_neighb
 				OR row_number() OVER prev_neighb = 1
 				AS not_redundant,
 				-- only expose fields we've considered in not_redundant
-				name
+				name,
+				isolation_segment_guid
 			FROM spaces
 			WINDOW
 				prev_neighb AS (
@@ -321,10 +334,132 @@ INSERT INTO events_temp with
 		) AS sq
 		where
 			not_redundant
+	),
+	valid_labels as (
+		select
+			guid,
+			labels,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				partition by guid order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			resource_labels
+	),
+	valid_processes as (
+		select
+			app_guid,
+			process_type,
+			disk_in_mb,
+			log_rate_limit,
+			tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+				partition by guid order by valid_from rows between current row and 1 following
+			)) as valid_for
+		from
+			app_processes
+	),
+	started_events as (
+		select
+			*
+		from
+			event_ranges
+		where
+			state = 'STARTED'
+			and not isempty(duration)
+	),
+	attribute_changes as (
+		-- when the quota of an org, the isolation segment of a space or the
+		-- labels of an org, space or resource changed. compute_only changes
+		-- don't affect services.
+		select
+			guid,
+			valid_from as changed_at,
+			compute_only
+		from (
+			select
+				guid,
+				valid_from,
+				false as compute_only,
+				quota_definition_guid is distinct from lag(quota_definition_guid) over prev_record as changed,
+				row_number() over prev_record as n
+			from
+				valid_orgs
+			window
+				prev_record as (partition by guid order by valid_from)
+			union all
+			select
+				guid,
+				valid_from,
+				true as compute_only,
+				isolation_segment_guid is distinct from lag(isolation_segment_guid) over prev_record as changed,
+				row_number() over prev_record as n
+			from
+				valid_spaces
+			window
+				prev_record as (partition by guid order by valid_from)
+			union all
+			select
+				guid,
+				lower(valid_for) as valid_from,
+				false as compute_only,
+				labels is distinct from lag(labels) over prev_record as changed,
+				row_number() over prev_record as n
+			from
+				valid_labels
+			window
+				prev_record as (partition by guid order by lower(valid_for))
+		) c
+		where
+			changed
+			and n > 1
+	),
+	event_boundaries as (
+		select event_guid, lower(duration) as boundary from started_events
+		union
+		select event_guid, upper(duration) as boundary from started_events
+		union
+		select
+			ev.event_guid,
+			ac.changed_at as boundary
+		from
+			started_events ev
+		join
+			attribute_changes ac on ac.guid in (ev.org_guid, ev.space_guid, ev.resource_guid)
+			and (not ac.compute_only or ev.resource_type <> 'service')
+			and ev.duration @> ac.changed_at
+	),
+	event_parts as (
+		-- events are split where an attribute they are billed by changed, so
+		-- each part is billed by the attributes valid during it. the org,
+		-- space, plan and labels of the last part are looked up at the end of
+		-- the event as before, and of the others just before the change that
+		-- ends them.
+		select
+			event_guid,
+			tstzrange(boundary, next_boundary) as duration,
+			(case
+				when next_boundary < max(next_boundary) over (partition by event_guid)
+				then next_boundary - interval '1 microsecond'
+				else next_boundary
+			end) as attributes_at
+		from (
+			select
+				event_guid,
+				boundary,
+				lead(boundary) over (
+					partition by event_guid order by boundary
+				) as next_boundary
+			from
+				event_boundaries
+		) b
+		where
+			next_boundary is not null
 	)
 
 	select
-		event_guid,
+		(case
+			when lower(p.duration) = lower(ev.duration) then ev.event_guid
+			else uuid_generate_v5(uuid_ns_url(), 'split/' || ev.event_guid || '/' || lower(p.duration))
+		end) as event_guid,
 		resource_guid,
 		resource_name,
 		resource_type,
@@ -332,35 +467,200 @@ INSERT INTO events_temp with
 		coalesce(vo.name, org_guid::text) as org_name,
 		space_guid,
 		coalesce(vspace.name, space_guid::text) as space_name,
-		duration,
+		p.duration,
 		(case
 			when resource_type = 'service'
 			then coalesce(uuid_or_placeholder(vsp.unique_id), 'd5091c33-2f9d-4b15-82dc-4ad69717fc03')::uuid
-			else plan_guid
+			else coalesce(spp.plan_guid, ev.plan_guid)
 		end) as plan_guid,
 		coalesce(vsp.name, plan_name) as plan_name,
 		coalesce(vs.guid, ev.service_guid) as service_guid,
 		coalesce(vs.label, ev.service_name) as service_name,
 		number_of_nodes,
 		memory_in_mb,
-		storage_in_mb
+		storage_in_mb,
+		-- a log rate limit of -1 is unlimited, which is priced as 0
+		coalesce(vproc.disk_in_mb, task.disk_in_mb) as disk_in_mb,
+		nullif(coalesce(vproc.log_rate_limit, task.log_rate_limit), -1) as log_rate_limit,
+		vo.quota_definition_guid,
+		(case
+			when resource_type <> 'service'
+			then vspace.isolation_segment_guid
+		end) as isolation_segment_guid,
+		-- labels of the resource override those of its space, which override
+		-- those of its org
+		coalesce(olabels.labels, '{}') || coalesce(slabels.labels, '{}') || coalesce(rlabels.labels, '{}') as labels
 	from
-		event_ranges ev
+		started_events ev
+	join
+		event_parts p on p.event_guid = ev.event_guid
 	left join
 		valid_service_plans vsp on ev.plan_guid = vsp.guid
-		and upper(ev.duration) <@ vsp.valid_for
+		and p.attributes_at <@ vsp.valid_for
 	left join
 		valid_services vs on vsp.service_guid = vs.guid
-		and upper(ev.duration) <@ vs.valid_for
+		and p.attributes_at <@ vs.valid_for
 	left join
 		valid_orgs vo on ev.org_guid = vo.guid
-		and upper(ev.duration) <@ vo.valid_for
+		and p.attributes_at <@ vo.valid_for
 	left join
 		valid_spaces vspace on ev.space_guid = vspace.guid
-		and upper(ev.duration) <@ vspace.valid_for
+		and p.attributes_at <@ vspace.valid_for
+	left join (
+		-- compute resources in an isolation segment with its own plan use that
+		-- plan, see IsolationSegmentPlanGUID
+		select distinct
+			plan_guid
+		from
+			pricing_plans
+		where
+			isolation_segment_guid is not null
+	) spp on ev.resource_type <> 'service'
+		and spp.plan_guid = uuid_generate_v5(uuid_ns_url(), 'isolation_segment/' || vspace.isolation_segment_guid || '/' || ev.plan_guid)
+	left join lateral (
+		-- an app has one process of each type, but take the latest in case
+		-- one was replaced so events are not duplicated
+		select
+			disk_in_mb,
+			log_rate_limit
+		from
+			valid_processes
+		where
+			ev.event_type = 'app'
+			and ev.resource_guid = app_guid
+			and ev.process_type = process_type
+			and p.attributes_at <@ valid_for
+		order by
+			lower(valid_for) desc
+		limit 1
+	) vproc on true
+	left join
+		app_tasks task on ev.event_type = 'task'
+		and ev.resource_guid = task.guid
+	left join
+		valid_labels olabels on ev.org_guid = olabels.guid
+		and p.attributes_at <@ olabels.valid_for
+	left join
+		valid_labels slabels on ev.space_guid = slabels.guid
+		and p.attributes_at <@ slabels.valid_for
+	left join
+		valid_labels rlabels on ev.resource_guid = rlabels.guid
+		and p.attributes_at <@ rlabels.valid_for
+;
+
+-- egress bandwidth is billed as an "egress" resource for each app and hour
+-- with a bandwidth pricing plan, in the space the app was last in by the end
+-- of the hour. apps that were never started are left out.
+INSERT INTO events_temp with
+	bandwidth_plan as (
+		-- plan guid for all egress, see BandwidthPlanGUID
+		select '0d5c91dc-c91d-49f1-8921-f95344be3e31'::uuid as plan_guid
+	),
+	egress_hours as (
+		select
+			app_guid,
+			tstzrange(hour, hour + interval '1 hour') as duration,
+			sum(bytes) as bytes
+		from
+			egress_usage
+		group by
+			app_guid, hour
+	)
+	select
+		uuid_generate_v5(uuid_ns_url(), 'egress/' || eh.app_guid || '/' || lower(eh.duration)) as event_guid,
+		eh.app_guid as resource_guid,
+		app.resource_name,
+		'egress' as resource_type,
+		app.org_guid,
+		app.org_name,
+		app.space_guid,
+		app.space_name,
+		eh.duration,
+		bp.plan_guid,
+		'bandwidth' as plan_name,
+		null as service_guid,
+		'bandwidth' as service_name,
+		1 as number_of_nodes,
+		0 as memory_in_mb,
+		0 as storage_in_mb,
+		null as disk_in_mb,
+		null as log_rate_limit,
+		app.quota_definition_guid,
+		null as isolation_segment_guid,
+		app.labels,
+		eh.bytes as egress_bytes
+	from
+		egress_hours eh
+	cross join
+		bandwidth_plan bp
+	cross join lateral (
+		select
+			*
+		from
+			events_temp ev
+		where
+			ev.resource_type = 'app'
+			and ev.resource_guid = eh.app_guid
+			and lower(ev.duration) < upper(eh.duration)
+		order by
+			lower(ev.duration) desc
+		limit 1
+	) app
 	where
-		state = 'STARTED'
-		and not isempty(duration)
+		exists (
+			select 1
+			from pricing_plans pp
+			where pp.plan_guid = bp.plan_guid
+			and pp.valid_from <= lower(eh.duration)
+		)
+;
+
+-- the quantity of each metric service brokers metered for a service
+-- instance during each of its events, counting the part of each metering
+-- record's interval that the event covers. records only count for instances
+-- of the services of the broker that sent them.
+UPDATE events_temp ev SET
+	metrics = em.metrics
+FROM (
+	select
+		event_guid,
+		jsonb_object_agg(metric, quantity) as metrics
+	from (
+		select
+			ev.event_guid,
+			mr.metric,
+			sum(
+				mr.quantity
+				* extract(epoch from upper(ev.duration * mr.duration) - lower(ev.duration * mr.duration))
+				/ extract(epoch from upper(mr.duration) - lower(mr.duration))
+			) as quantity
+		from
+			events_temp ev
+		join (
+			select
+				(raw_message->>'service_instance_guid')::uuid as service_instance_guid,
+				raw_message->>'metric' as metric,
+				(raw_message->>'quantity')::numeric as quantity,
+				array(select jsonb_array_elements_text(raw_message->'service_guids'))::uuid[] as service_guids,
+				tstzrange(
+					(raw_message->>'start')::timestamptz,
+					(raw_message->>'stop')::timestamptz
+				) as duration
+			from
+				metering_events
+		) mr on mr.service_instance_guid = ev.resource_guid
+			and ev.service_guid = any(mr.service_guids)
+			and mr.duration && ev.duration
+		where
+			ev.resource_type = 'service'
+		group by
+			ev.event_guid, mr.metric
+	) event_metrics
+	group by
+		event_guid
+) em
+WHERE
+	ev.event_guid = em.event_guid
 ;
 
 CREATE INDEX events_org_temp_idx ON events_temp (org_guid);
@@ -22,13 +22,15 @@ WITH
 
       number_of_nodes, memory_in_mb, storage_in_mb,
 
-      component_name, component_formula,
+      component_name, component_formula, unit,
 
       currency_code, currency_rate,
 
       vat_code, vat_rate,
 
-      cost_for_duration, duration,
+      labels,
+
+      cost_for_duration, quantity_for_duration, duration,
 
       -- unroll event duration (start -> end) into rows where each row is a day
       -- so we can group and query by day and by month using a regular index
@@ -55,13 +57,15 @@ WITH
 
       number_of_nodes, memory_in_mb, storage_in_mb,
 
-      component_name, component_formula,
+      component_name, component_formula, unit,
 
       currency_code, currency_rate,
 
       vat_code, vat_rate,
 
-      cost_for_duration, duration,
+      labels,
+
+      cost_for_duration, quantity_for_duration, duration,
 
       day::date as day,
 
@@ -92,13 +96,15 @@ WITH
 
       number_of_nodes, memory_in_mb, storage_in_mb,
 
-      component_name, component_formula,
+      component_name, component_formula, unit,
 
       currency_code, currency_rate,
 
       vat_code, vat_rate,
 
-      cost_for_duration, duration, day_duration,
+      labels,
+
+      cost_for_duration, quantity_for_duration, duration, day_duration,
 
       -- compute cost for this event for this day
       -- $duration_seconds_of_day_event / $duration_seconds_of_event
@@ -107,7 +113,14 @@ WITH
         EXTRACT(EPOCH FROM (UPPER(day_duration) - LOWER(day_duration)))
       ) / (
         EXTRACT(EPOCH FROM (UPPER(duration) - LOWER(duration)))
-      ) * cost_for_duration AS cost
+      ) * cost_for_duration AS cost,
+
+      -- the usage measured in unit for this day, split the same way
+      (
+        EXTRACT(EPOCH FROM (UPPER(day_duration) - LOWER(day_duration)))
+      ) / (
+        EXTRACT(EPOCH FROM (UPPER(duration) - LOWER(duration)))
+      ) * quantity_for_duration AS quantity
 
     FROM costed_billable_event_component_series
   )
@@ -18,6 +18,7 @@ import (
 
 	"github.com/alphagov/paas-billing/eventio"
 	"github.com/lib/pq"
+	uuid "github.com/satori/go.uuid"
 
 	"github.com/golang-migrate/migrate/v4"
 	migrate_postgres "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -31,12 +32,28 @@ const (
 	ComputeServiceGUID    = "4f6f0a18-cdd4-4e51-8b6b-dc39b696e61b"
 	TaskPlanGUID          = "ebfa9453-ef66-450c-8c37-d53dfd931038"
 	StagingPlanGUID       = "9d071c77-7a68-4346-9981-e8dafac95b6f"
+	BandwidthPlanGUID     = "0d5c91dc-c91d-49f1-8921-f95344be3e31"
 	DefaultInitTimeout    = 25 * time.Minute
 	DefaultRefreshTimeout = 700 * time.Minute
 	DefaultStoreTimeout   = 45 * time.Second
 	DefaultQueryTimeout   = 45 * time.Second
 )
 
+// IsolationSegmentPlanGUID returns the plan_guid of the plan that replaces
+// the app, task or staging plan planGUID for spaces in the isolation segment.
+// It matches the guid create_events.sql gives their events.
+func IsolationSegmentPlanGUID(planGUID string, isolationSegmentGUID string) (string, error) {
+	plan, err := uuid.FromString(planGUID)
+	if err != nil {
+		return "", fmt.Errorf("pricing plan plan_guid must be a guid - got %s", planGUID)
+	}
+	segment, err := uuid.FromString(isolationSegmentGUID)
+	if err != nil {
+		return "", fmt.Errorf("pricing plan isolation_segment_guid must be a guid - got %s", isolationSegmentGUID)
+	}
+	return uuid.NewV5(uuid.NamespaceURL, "isolation_segment/"+segment.String()+"/"+plan.String()).String(), nil
+}
+
 var (
 	eventStorePerformanceGauge = promauto.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -183,6 +200,27 @@ func (s *EventStore) Init() error {
 	if err := s.initPlans(tx); err != nil {
 		return fmt.Errorf("failed to init plans: %s", err)
 	}
+	if err := s.initExemptions(tx); err != nil {
+		return fmt.Errorf("failed to init exemptions: %s", err)
+	}
+	if err := s.initQuotaPlans(tx); err != nil {
+		return fmt.Errorf("failed to init quota plans: %s", err)
+	}
+	if err := s.initCostSharingRules(tx); err != nil {
+		return fmt.Errorf("failed to init cost sharing rules: %s", err)
+	}
+	if err := s.initProviderCostRules(tx); err != nil {
+		return fmt.Errorf("failed to init provider cost rules: %s", err)
+	}
+	if err := s.initAllowances(tx); err != nil {
+		return fmt.Errorf("failed to init allowances: %s", err)
+	}
+	if err := s.initFootprintPlans(tx); err != nil {
+		return fmt.Errorf("failed to init footprint plans: %s", err)
+	}
+	if err := s.initGridIntensities(tx); err != nil {
+		return fmt.Errorf("failed to init grid intensities: %s", err)
+	}
 	if err := tx.Commit(); err != nil {
 		return err
 	}
@@ -215,10 +253,15 @@ func (s *EventStore) regenerateEvents() error {
 	if err := s.runSQLFilesInTransaction(
 		ctx,
 		"create_events.sql",
+		"create_event_exemption_periods.sql",
 	); err != nil {
 		return err
 	}
 
+	if err := s.recordUnpricedPlans(ctx); err != nil {
+		return err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -253,6 +296,17 @@ func (s *EventStore) regenerateEvents() error {
 		return err
 	}
 
+	if err := s.runSQLFilesInTransaction(
+		ctx,
+		"create_event_footprint_periods.sql",
+	); err != nil {
+		return err
+	}
+
+	if err := s.checkDataQuality(ctx); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -323,36 +377,50 @@ func (s *EventStore) initPlans(tx *sql.Tx) (err error) {
 	}
 
 	for _, pp := range s.cfg.PricingPlans {
+		planGUID := pp.PlanGUID
+		if pp.IsolationSegmentGUID != "" {
+			segmentPlanGUID, err := IsolationSegmentPlanGUID(pp.PlanGUID, pp.IsolationSegmentGUID)
+			if err != nil {
+				return err
+			}
+			planGUID = segmentPlanGUID
+		}
 		s.logger.Info("configuring-pricing-plan", lager.Data{
-			"plan_guid":  pp.PlanGUID,
-			"name":       pp.Name,
-			"valid_from": pp.ValidFrom,
+			"plan_guid":              planGUID,
+			"name":                   pp.Name,
+			"valid_from":             pp.ValidFrom,
+			"isolation_segment_guid": pp.IsolationSegmentGUID,
 		})
 		_, err := tx.Exec(`insert into pricing_plans (
 			plan_guid, valid_from, name,
-			memory_in_mb, storage_in_mb, number_of_nodes
+			memory_in_mb, storage_in_mb, number_of_nodes,
+			isolation_segment_guid
 		) values (
 			$1, $2, $3,
-			$4, $5, $6
-		)`, pp.PlanGUID, pp.ValidFrom, pp.Name,
+			$4, $5, $6,
+			nullif($7, '')::uuid
+		)`, planGUID, pp.ValidFrom, pp.Name,
 			pp.MemoryInMB, pp.StorageInMB, pp.NumberOfNodes,
+			pp.IsolationSegmentGUID,
 		)
 		if err != nil {
 			return wrapPqError(err, "invalid pricing plan")
 		}
 		for _, ppc := range pp.Components {
 			s.logger.Info("configuring-pricing-plan-component", lager.Data{
-				"plan_guid":  pp.PlanGUID,
+				"plan_guid":  planGUID,
 				"name":       ppc.Name,
 				"valid_from": pp.ValidFrom,
 			})
 			_, err := tx.Exec(`insert into pricing_plan_components (
 				plan_guid, valid_from, name,
-				formula, currency_code, vat_code
+				formula, currency_code, vat_code,
+				unit, quantity_formula
 			) values (
 				$1, $2, $3,
-				$4, $5, $6
-			)`, pp.PlanGUID, pp.ValidFrom, ppc.Name, ppc.Formula, ppc.CurrencyCode, ppc.VATCode)
+				$4, $5, $6,
+				$7, nullif($8, '')
+			)`, planGUID, pp.ValidFrom, ppc.Name, ppc.Formula, ppc.CurrencyCode, ppc.VATCode, ppc.Unit, ppc.QuantityFormula)
 			if err != nil {
 				return wrapPqError(err, "invalid pricing plan component")
 			}
@@ -395,6 +463,14 @@ func (s *EventStore) StoreEvents(events []eventio.RawEvent) error {
 			if err := s.storeComposeEvent(tx, event); err != nil {
 				return err
 			}
+		case eventio.ProviderCostKind:
+			if err := s.storeProviderCostEvent(tx, event); err != nil {
+				return err
+			}
+		case eventio.MeteringKind:
+			if err := s.storeMeteringEvent(tx, event); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("cannot store event without a Kind: %v", event)
 		}
@@ -440,19 +516,26 @@ func (s *EventStore) storeComposeEvent(tx *sql.Tx, event eventio.RawEvent) error
 
 // GetEvents returns the eventio.RawEvents filtered using eventio.RawEventFilter if present
 func (s *EventStore) GetEvents(filter eventio.RawEventFilter) ([]eventio.RawEvent, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetEventsContext(ctx, filter)
+}
+
+// GetEventsContext is GetEvents stopping early if ctx is done
+func (s *EventStore) GetEventsContext(ctx context.Context, filter eventio.RawEventFilter) ([]eventio.RawEvent, error) {
 	if filter.Kind == "" {
 		return nil, fmt.Errorf("you must supply a kind to filter events by")
 	}
 	switch filter.Kind {
-	case "app", "service":
-		return s.getUsageEvents(filter)
+	case "app", "service", eventio.ProviderCostKind, eventio.MeteringKind:
+		return s.getUsageEvents(ctx, filter)
 	case "compose":
-		return s.getComposeEvents(filter)
+		return s.getComposeEvents(ctx, filter)
 	}
 	return nil, fmt.Errorf("cannot query events of kind '%s'", filter.Kind)
 }
 
-func (s *EventStore) getComposeEvents(filter eventio.RawEventFilter) ([]eventio.RawEvent, error) {
+func (s *EventStore) getComposeEvents(ctx context.Context, filter eventio.RawEventFilter) ([]eventio.RawEvent, error) {
 	events := []eventio.RawEvent{}
 	sortDirection := "desc"
 	if filter.Reverse {
@@ -465,14 +548,12 @@ func (s *EventStore) getComposeEvents(filter eventio.RawEventFilter) ([]eventio.
 	if filter.Kind != "compose" {
 		return nil, fmt.Errorf("getComposeEvents can not filter events of kind: %s", filter.Kind)
 	}
-	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
-	defer cancel()
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
-	rows, err := tx.Query(`
+	rows, err := tx.QueryContext(ctx, `
 		select
 			event_id,
 			created_at,
@@ -480,8 +561,8 @@ func (s *EventStore) getComposeEvents(filter eventio.RawEventFilter) ([]eventio.
 		from
 			compose_audit_events
 		order by
-			id ` + sortDirection + `
-		` + limit + `
+			id `+sortDirection+`
+		`+limit+`
 	`)
 	if err != nil {
 		return nil, err
@@ -503,7 +584,7 @@ func (s *EventStore) getComposeEvents(filter eventio.RawEventFilter) ([]eventio.
 
 }
 
-func (s *EventStore) getUsageEvents(filter eventio.RawEventFilter) ([]eventio.RawEvent, error) {
+func (s *EventStore) getUsageEvents(ctx context.Context, filter eventio.RawEventFilter) ([]eventio.RawEvent, error) {
 	events := []eventio.RawEvent{}
 	sortDirection := "desc"
 	if filter.Reverse {
@@ -519,26 +600,28 @@ func (s *EventStore) getUsageEvents(filter eventio.RawEventFilter) ([]eventio.Ra
 		tableName = "service_usage_events"
 	case "app":
 		tableName = "app_usage_events"
+	case eventio.ProviderCostKind:
+		tableName = "provider_cost_events"
+	case eventio.MeteringKind:
+		tableName = "metering_events"
 	default:
 		return nil, fmt.Errorf("getUsageEvents unknown kind: %s", filter.Kind)
 	}
-	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
-	defer cancel()
-	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
 	if err != nil {
 		return nil, err
 	}
 	defer tx.Rollback()
-	rows, err := tx.Query(`
+	rows, err := tx.QueryContext(ctx, `
 		select
 			guid,
 			created_at,
 			raw_message
 		from
-			` + tableName + `
+			`+tableName+`
 		order by
-			id ` + sortDirection + `
-		` + limit + `
+			id `+sortDirection+`
+		`+limit+`
 	`)
 	if err != nil {
 		return nil, err
@@ -630,7 +713,7 @@ func checkCurrencyRates(tx *sql.Tx) error {
 func (s *EventStore) generateMissingPlans(tx *sql.Tx) error {
 	rows, err := tx.Query(`
 		insert into pricing_plans (
-			plan_guid, valid_from, name
+			plan_guid, valid_from, name, generated
 		) (
 			select
 				distinct plan_guid,
@@ -639,7 +722,8 @@ func (s *EventStore) generateMissingPlans(tx *sql.Tx) error {
 				over (
 					partition by plan_guid
 					order by lower(duration) desc
-				)
+				),
+				true
 			from events
 			where plan_guid not in (
 				select distinct plan_guid
@@ -711,11 +795,15 @@ func (s *EventStore) recordTotalCostMetrics() error {
 		return err
 	}
 	for _, c := range costs {
+		cost, err := c.Cost.Float64()
+		if err != nil {
+			return err
+		}
 		totalCostGauge.With(prometheus.Labels{
 			"plan_guid": c.PlanGUID,
 			"kind":      c.Kind,
 			"plan":      c.PlanName,
-		}).Set(float64(c.Cost))
+		}).Set(cost)
 	}
 	return nil
 }
@@ -758,7 +846,8 @@ func checkPricingComponents(tx *sql.Tx) error {
 
 // checkPlanConsistency reports an error if there are any plans in use in the
 // the existing service_usage_events data that do not have corresponding
-// pricing_plans configured
+// pricing_plans configured. Usage that exemptions cover for its
+// whole duration is priced at zero and needs no pricing plan.
 func checkPlanConsistency(tx *sql.Tx) error {
 	rows, err := tx.Query(`
 		with valid_pricing_plans as (
@@ -783,6 +872,7 @@ func checkPlanConsistency(tx *sql.Tx) error {
 				where pp.plan_guid = events.plan_guid
 				and events.duration && pp.valid_for
 			)
+			and events.event_guid not in (` + fullyExemptEventsQuery + `)
 	`)
 	if err != nil {
 		return err
@@ -857,8 +947,8 @@ func (s *EventStore) runSQLFile(tx *sql.Tx, filename string) error {
 }
 
 // queryJSON returns rows as a json blobs, which makes it easier to decode into structs.
-func queryJSON(tx *sql.Tx, q string, args ...interface{}) (*sql.Rows, error) {
-	return tx.Query(fmt.Sprintf(`
+func queryJSON(ctx context.Context, tx *sql.Tx, q string, args ...interface{}) (*sql.Rows, error) {
+	return tx.QueryContext(ctx, fmt.Sprintf(`
 		with q as ( %s )
 		select row_to_json(q.*) from q;
 	`, q), args...)
@@ -913,5 +1003,14 @@ func LoadConfig(filename string) (Config, error) {
 	if err := json.Unmarshal(b, &cfg); err != nil {
 		return Config{}, err
 	}
+	if cfg.Aggregations == nil {
+		cfg.Aggregations = DefaultAggregations
+	}
+	if err := validateAggregations(cfg.Aggregations); err != nil {
+		return Config{}, err
+	}
+	if err := loadGridIntensityFiles(&cfg, filepath.Dir(filename)); err != nil {
+		return Config{}, err
+	}
 	return cfg, nil
 }
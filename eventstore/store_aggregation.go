@@ -0,0 +1,277 @@
+package eventstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// DefaultAggregations are used when config.json does not list any
+// aggregations. Tasks are short lived and numerous so each space's tasks
+// are reported as a single event. Configure `"aggregations": []` to report
+// every task individually.
+var DefaultAggregations = []eventio.AggregationRule{
+	{ResourceType: "task", ResourceName: "Total Task Events"},
+}
+
+func validateAggregations(rules []eventio.AggregationRule) error {
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if rule.ResourceType == "" {
+			return errors.New("aggregations: resource_type is required")
+		}
+		if rule.ResourceName == "" {
+			return fmt.Errorf("aggregations: resource_name is required for %s", rule.ResourceType)
+		}
+		if seen[rule.ResourceType] {
+			return fmt.Errorf("aggregations: %s is aggregated more than once", rule.ResourceType)
+		}
+		seen[rule.ResourceType] = true
+	}
+	return nil
+}
+
+// aggregationRules returns the aggregations to apply to events read for
+// filter
+func (s *EventStore) aggregationRules(filter eventio.EventFilter) []eventio.AggregationRule {
+	if filter.Aggregate == eventio.AggregateNone {
+		return nil
+	}
+	return s.cfg.Aggregations
+}
+
+// aggregationRuleValues appends the rules to args and returns a values list
+// of (resource_type, resource_name) rows referring to them
+func aggregationRuleValues(rules []eventio.AggregationRule, args []interface{}) (string, []interface{}) {
+	values := []string{}
+	for _, rule := range rules {
+		args = append(args, rule.ResourceType, rule.ResourceName)
+		values = append(values, fmt.Sprintf("($%d::text, $%d::text)", len(args)-1, len(args)))
+	}
+	return strings.Join(values, ", "), args
+}
+
+// withAggregatedBillableEvents wraps a query returning billable events so
+// that the events of each aggregated resource type are replaced by one
+// event per org, space and plan covering the filter range. Prices and
+// quantities are summed with numeric arithmetic for each distinct price
+// component name, plan name, currency, VAT rate and unit, so the aggregated
+// event keeps the currency and VAT of the events it replaces. The query is returned
+// unchanged if there are no rules.
+func withAggregatedBillableEvents(query string, args []interface{}, filter eventio.EventFilter, rules []eventio.AggregationRule) (string, []interface{}) {
+	if len(rules) == 0 {
+		return query, args
+	}
+	args = append(args, fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop))
+	rangeArgPosition := len(args)
+	values, args := aggregationRuleValues(rules, args)
+
+	return fmt.Sprintf(`
+		with
+		unaggregated_events as (
+			%[1]s
+		),
+		aggregation_rules (resource_type, resource_name) as (
+			values %[3]s
+		),
+		aggregated_components as (
+			select
+				e.org_guid,
+				e.org_name,
+				e.space_guid,
+				e.space_name,
+				e.resource_type,
+				e.plan_guid,
+				d->>'name' as name,
+				d->>'plan_name' as plan_name,
+				d->>'vat_code' as vat_code,
+				d->>'vat_rate' as vat_rate,
+				d->>'currency_code' as currency_code,
+				d->>'unit' as unit,
+				sum((d->>'ex_vat')::numeric) as ex_vat,
+				sum((d->>'inc_vat')::numeric) as inc_vat,
+				sum((d->>'quantity')::numeric) as quantity
+			from
+				unaggregated_events e
+			cross join lateral
+				json_array_elements(e.price::json->'details') d
+			where
+				e.resource_type in (select resource_type from aggregation_rules)
+			group by
+				e.org_guid,
+				e.org_name,
+				e.space_guid,
+				e.space_name,
+				e.resource_type,
+				e.plan_guid,
+				d->>'name',
+				d->>'plan_name',
+				d->>'vat_code',
+				d->>'vat_rate',
+				d->>'currency_code',
+				d->>'unit'
+		),
+		aggregated_events as (
+			select
+				md5(concat_ws('|', c.org_guid, c.space_guid, c.resource_type, c.plan_guid, $%[2]d::tstzrange::text))::uuid as event_guid,
+				lower($%[2]d::tstzrange) as event_start,
+				upper($%[2]d::tstzrange) as event_stop,
+				c.space_guid as resource_guid,
+				r.resource_name,
+				c.resource_type,
+				c.org_guid,
+				c.org_name,
+				c.space_guid,
+				c.space_name,
+				c.plan_guid,
+				null::uuid as quota_definition_guid,
+				null::integer as number_of_nodes,
+				null::integer as memory_in_mb,
+				null::integer as storage_in_mb,
+				null::integer as disk_in_mb,
+				null::bigint as log_rate_limit,
+				json_build_object(
+					'ex_vat', (sum(c.ex_vat))::text,
+					'inc_vat', (sum(c.inc_vat))::text,
+					'details', json_agg(json_build_object(
+						'name', c.name,
+						'start', lower($%[2]d::tstzrange),
+						'stop', upper($%[2]d::tstzrange),
+						'plan_name', c.plan_name,
+						'ex_vat', (c.ex_vat)::text,
+						'inc_vat', (c.inc_vat)::text,
+						'vat_rate', c.vat_rate,
+						'vat_code', c.vat_code,
+						'currency_code', c.currency_code,
+						'unit', c.unit,
+						'quantity', (c.quantity)::text
+					) order by c.name, c.plan_name, c.currency_code, c.vat_code, c.unit)
+				) as price,
+				null::jsonb as labels
+			from
+				aggregated_components c
+			join
+				aggregation_rules r on r.resource_type = c.resource_type
+			group by
+				c.org_guid,
+				c.org_name,
+				c.space_guid,
+				c.space_name,
+				c.resource_type,
+				c.plan_guid,
+				r.resource_name
+		)
+		select
+			event_guid,
+			event_start,
+			event_stop,
+			resource_guid,
+			resource_name,
+			resource_type,
+			org_guid,
+			org_name,
+			space_guid,
+			space_name,
+			plan_guid,
+			quota_definition_guid,
+			number_of_nodes,
+			memory_in_mb,
+			storage_in_mb,
+			disk_in_mb,
+			log_rate_limit,
+			price::json as price,
+			labels
+		from
+			unaggregated_events e
+		where
+			not exists (select 1 from aggregation_rules r where r.resource_type = e.resource_type)
+		union all
+		select
+			*
+		from
+			aggregated_events
+		order by
+			event_guid
+	`, query, rangeArgPosition, values), args
+}
+
+// withAggregatedUsageEvents wraps a query returning usage events so that
+// the events of each aggregated resource type are replaced by one event per
+// org, space and plan covering the filter range. The aggregated event has
+// the largest size of the events it replaces. The query is returned
+// unchanged if there are no rules.
+func withAggregatedUsageEvents(query string, args []interface{}, filter eventio.EventFilter, rules []eventio.AggregationRule) (string, []interface{}) {
+	if len(rules) == 0 {
+		return query, args
+	}
+	args = append(args, fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop))
+	rangeArgPosition := len(args)
+	values, args := aggregationRuleValues(rules, args)
+
+	return fmt.Sprintf(`
+		with
+		unaggregated_events as (
+			%[1]s
+		),
+		aggregation_rules (resource_type, resource_name) as (
+			values %[3]s
+		),
+		aggregated_events as (
+			select
+				md5(concat_ws('|', e.org_guid, e.space_guid, e.resource_type, e.plan_guid, $%[2]d::tstzrange::text))::uuid as event_guid,
+				to_json(lower($%[2]d::tstzrange)) as event_start,
+				to_json(upper($%[2]d::tstzrange)) as event_stop,
+				e.space_guid as resource_guid,
+				r.resource_name,
+				e.resource_type,
+				e.org_guid,
+				e.org_name,
+				e.space_guid,
+				e.space_name,
+				e.plan_guid,
+				e.plan_name,
+				e.service_guid,
+				e.service_name,
+				max(e.number_of_nodes) as number_of_nodes,
+				max(e.memory_in_mb) as memory_in_mb,
+				max(e.storage_in_mb) as storage_in_mb,
+				max(e.disk_in_mb) as disk_in_mb,
+				max(e.log_rate_limit) as log_rate_limit,
+				null::jsonb as labels
+			from
+				unaggregated_events e
+			join
+				aggregation_rules r on r.resource_type = e.resource_type
+			group by
+				e.org_guid,
+				e.org_name,
+				e.space_guid,
+				e.space_name,
+				e.resource_type,
+				e.plan_guid,
+				e.plan_name,
+				e.service_guid,
+				e.service_name,
+				r.resource_name
+		)
+		select
+			*
+		from (
+			select
+				*
+			from
+				unaggregated_events e
+			where
+				not exists (select 1 from aggregation_rules r where r.resource_type = e.resource_type)
+			union all
+			select
+				*
+			from
+				aggregated_events
+		) events
+		order by
+			(event_start #>> '{}')::timestamptz, event_guid
+	`, query, rangeArgPosition, values), args
+}
@@ -0,0 +1,204 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+var _ eventio.AllowanceReader = &EventStore{}
+
+// initAllowances replaces the allowances with those from the config
+func (s *EventStore) initAllowances(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM allowances"); err != nil {
+		return wrapPqError(err, "error deleting existing allowances")
+	}
+	for _, a := range s.cfg.Allowances {
+		if err := a.Validate(); err != nil {
+			return err
+		}
+		s.logger.Info("configuring-allowance", lager.Data{
+			"name":                  a.Name,
+			"plan_guids":            a.PlanGUIDs,
+			"quota_definition_guid": a.QuotaDefinitionGUID,
+			"valid_from":            a.ValidFrom,
+			"valid_to":              a.ValidTo,
+		})
+		_, err := tx.Exec(`insert into allowances (
+			name, valid_from, valid_to,
+			plan_guids, unit, amount,
+			quota_definition_guid
+		) values (
+			$1, $2, nullif($3, '')::timestamptz,
+			$4::uuid[], $5, $6,
+			nullif($7, '')::uuid
+		)`, a.Name, a.ValidFrom, a.ValidTo,
+			pq.Array(a.PlanGUIDs), a.Unit, a.Amount,
+			a.QuotaDefinitionGUID,
+		)
+		if err != nil {
+			return wrapPqError(err, "invalid allowance")
+		}
+	}
+	return nil
+}
+
+// GetAllowanceUsage returns how much of each allowance the orgs in the
+// filter, or every org if it has none, used in each calendar month that
+// starts during the filter range. It only includes the allowances that
+// applied to the org that month.
+func (s *EventStore) GetAllowanceUsage(filter eventio.EventFilter) ([]eventio.AllowanceUsage, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetAllowanceUsageContext(ctx, filter)
+}
+
+// GetAllowanceUsageContext is GetAllowanceUsage stopping early if ctx is done
+func (s *EventStore) GetAllowanceUsageContext(ctx context.Context, filter eventio.EventFilter) ([]eventio.AllowanceUsage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetAllowanceUsage", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{filter.RangeStart, filter.RangeStop}
+	orgQuery := ""
+	orgPlaceholders := []string{}
+	for _, orgGUID := range filter.OrgGUIDs {
+		args = append(args, orgGUID)
+		orgPlaceholders = append(orgPlaceholders, fmt.Sprintf("($%d::uuid)", len(args))) // $N
+	}
+	if len(orgPlaceholders) > 0 {
+		orgQuery = fmt.Sprintf("where o.guid = any (values %s)", strings.Join(orgPlaceholders, ","))
+	}
+
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, fmt.Sprintf(`
+		with
+		months as (
+			select
+				month,
+				tstzrange(month, month + interval '1 month') as month_range
+			from
+				generate_series(
+					date_trunc('month', $1::timestamptz),
+					$2::timestamptz - interval '1 microsecond',
+					interval '1 month'
+				) as month
+		),
+		valid_allowances as (
+			select
+				*,
+				tstzrange(valid_from, least(coalesce(valid_to, 'infinity'), lead(valid_from, 1, 'infinity') over (
+					partition by name order by valid_from rows between current row and 1 following
+				))) as valid_for
+			from
+				allowances
+		),
+		org_quota_periods as (
+			select
+				o.guid as org_guid,
+				o.quota_definition_guid,
+				tstzrange(o.valid_from, greatest(o.valid_from, lead(o.valid_from, 1, coalesce(od.deleted_at, 'infinity')) over (
+					partition by o.guid order by o.valid_from rows between current row and 1 following
+				))) as valid_for
+			from
+				orgs o
+			left join
+				org_deletions od on od.guid = o.guid
+			%s
+		),
+		org_allowances as (
+			select distinct
+				oqp.org_guid,
+				m.month,
+				a.name,
+				a.valid_from,
+				a.unit,
+				a.amount
+			from
+				org_quota_periods oqp
+			join
+				months m on m.month_range && oqp.valid_for
+			join
+				valid_allowances a on a.valid_for && (m.month_range * oqp.valid_for)
+				and (a.quota_definition_guid = oqp.quota_definition_guid or (
+					a.quota_definition_guid is null and not exists (
+						select 1 from valid_allowances qa
+						where qa.quota_definition_guid = oqp.quota_definition_guid
+						and qa.plan_guids && a.plan_guids
+						and qa.unit = a.unit
+						and qa.valid_for && (m.month_range * oqp.valid_for)
+					)
+				))
+		)
+		select
+			oa.org_guid,
+			oa.name,
+			oa.unit,
+			oa.month,
+			(oa.amount)::text as amount,
+			(coalesce(sum(ap.covered), 0))::text as used,
+			(oa.amount - coalesce(sum(ap.covered), 0))::text as remaining
+		from
+			org_allowances oa
+		left join
+			allowance_periods ap on ap.org_guid = oa.org_guid
+			and ap.allowance_name = oa.name
+			and ap.allowance_valid_from = oa.valid_from
+			and ap.month = oa.month
+		group by
+			oa.org_guid,
+			oa.name,
+			oa.valid_from,
+			oa.unit,
+			oa.month,
+			oa.amount
+		order by
+			oa.month, oa.org_guid, oa.name, oa.valid_from
+	`, orgQuery), args...)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("GetAllowanceUsage", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "GetAllowanceUsage", err)
+		s.logger.Error("get-allowance-usage-query", err, lager.Data{
+			"filter":  filter,
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("GetAllowanceUsage", "").Set(elapsed.Seconds())
+	s.logger.Info("get-allowance-usage-query", lager.Data{
+		"filter":  filter,
+		"elapsed": int64(elapsed),
+	})
+	defer rows.Close()
+	usage := []eventio.AllowanceUsage{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var u eventio.AllowanceUsage
+		if err := json.Unmarshal(b, &u); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "GetAllowanceUsage", err)
+		return nil, err
+	}
+	return usage, nil
+}
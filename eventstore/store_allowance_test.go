@@ -0,0 +1,229 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Allowances", func() {
+
+	const (
+		orgGUID        = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID      = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		appGUID        = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+		quotaGUID      = "f9909cea-81fe-4934-ba17-2a10278d2646"
+		otherQuotaGUID = "8d6a0a5c-3f0e-4d8e-9a51-6f1c2b3d4e5f"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:            "compute",
+					Formula:         "$number_of_nodes * ($time_in_seconds / 3600) * ($memory_in_mb / 1024) * 0.01",
+					CurrencyCode:    "GBP",
+					VATCode:         "Standard",
+					Unit:            "GB-hours",
+					QuantityFormula: "$number_of_nodes * ($time_in_seconds / 3600) * ($memory_in_mb / 1024)",
+				},
+				{
+					Name:         "platform",
+					Formula:      "($time_in_seconds / 3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+	})
+
+	// an app using 12 GB-hours, 4 on the first day and 8 on the second
+	openWithApp := func(ctx SpecContext, cfg eventstore.Config) {
+		var err error
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("orgs", testenv.Row{
+			"guid":                  orgGUID,
+			"valid_from":            "2000-01-01T00:00:00Z",
+			"name":                  "my-org",
+			"owner":                 "Testing Body",
+			"created_at":            "2000-01-01T00:00:00Z",
+			"updated_at":            "2000-01-01T00:00:00Z",
+			"quota_definition_guid": quotaGUID,
+		})).To(Succeed())
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+			},
+			testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+			},
+			testenv.Row{
+				"guid":        "1a2b3c4d-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-02T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+			},
+			testenv.Row{
+				"guid":        "2b3c4d5e-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-02T02:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	}
+
+	billableEvents := func() []eventio.BillableEvent {
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		return events
+	}
+
+	components := func(ev eventio.BillableEvent) map[string]eventio.PriceComponent {
+		byName := map[string]eventio.PriceComponent{}
+		for _, component := range ev.Price.Details {
+			byName[component.Name] = component
+		}
+		return byName
+	}
+
+	allowanceUsage := func(ctx SpecContext) []eventio.AllowanceUsage {
+		usage, err := db.Schema.GetAllowanceUsageContext(ctx, eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-03-01",
+			OrgGUIDs:   []string{orgGUID},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return usage
+	}
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should credit the usage an allowance covers in the order it happened", func(ctx SpecContext) {
+		cfg.Allowances = []eventio.Allowance{{
+			Name:      "free compute",
+			PlanGUIDs: []string{eventstore.ComputePlanGUID},
+			Unit:      "GB-hours",
+			Amount:    "6",
+			ValidFrom: "2001-01-01",
+		}}
+		openWithApp(ctx, cfg)
+
+		events := billableEvents()
+
+		first := components(events[0])
+		Expect(first).To(HaveKey("free compute (compute)"))
+		Expect(first["free compute (compute)"].ExVAT.Round(2)).To(Equal(eventio.Money("-0.04")))
+		Expect(first["free compute (compute)"].Unit).To(Equal("GB-hours"))
+		Expect(eventio.Money(first["free compute (compute)"].Quantity).Round(2)).To(Equal(eventio.Money("-4.00")))
+		Expect(events[0].Price.ExVAT.Round(2)).To(Equal(eventio.Money("0.01")))
+
+		second := components(events[1])
+		Expect(second).To(HaveKey("free compute (compute)"))
+		Expect(second["free compute (compute)"].ExVAT.Round(2)).To(Equal(eventio.Money("-0.02")))
+		Expect(events[1].Price.ExVAT.Round(2)).To(Equal(eventio.Money("0.08")))
+
+		usage := allowanceUsage(ctx)
+		Expect(usage).To(HaveLen(2))
+		Expect(usage[0].Name).To(Equal("free compute"))
+		Expect(usage[0].Unit).To(Equal("GB-hours"))
+		Expect(usage[0].Month).To(HavePrefix("2001-01-01"))
+		Expect(eventio.Money(usage[0].Amount).Round(2)).To(Equal(eventio.Money("6.00")))
+		Expect(eventio.Money(usage[0].Used).Round(2)).To(Equal(eventio.Money("6.00")))
+		Expect(eventio.Money(usage[0].Remaining).Round(2)).To(Equal(eventio.Money("0.00")))
+		Expect(usage[1].Month).To(HavePrefix("2001-02-01"))
+		Expect(eventio.Money(usage[1].Used).Round(2)).To(Equal(eventio.Money("0.00")))
+		Expect(eventio.Money(usage[1].Remaining).Round(2)).To(Equal(eventio.Money("6.00")))
+	})
+
+	It("should not credit more than the usage", func(ctx SpecContext) {
+		cfg.Allowances = []eventio.Allowance{{
+			Name:      "free compute",
+			PlanGUIDs: []string{eventstore.ComputePlanGUID},
+			Unit:      "GB-hours",
+			Amount:    "100",
+			ValidFrom: "2001-01-01",
+		}}
+		openWithApp(ctx, cfg)
+
+		events := billableEvents()
+		Expect(events[0].Price.ExVAT.Round(2)).To(Equal(eventio.Money("0.01")))
+		Expect(events[1].Price.ExVAT.Round(2)).To(Equal(eventio.Money("0.02")))
+
+		usage := allowanceUsage(ctx)
+		Expect(usage).ToNot(BeEmpty())
+		Expect(eventio.Money(usage[0].Used).Round(2)).To(Equal(eventio.Money("12.00")))
+		Expect(eventio.Money(usage[0].Remaining).Round(2)).To(Equal(eventio.Money("88.00")))
+	})
+
+	It("should use the allowance for the org's quota instead of the one for every org", func(ctx SpecContext) {
+		cfg.Allowances = []eventio.Allowance{
+			{
+				Name:      "free compute",
+				PlanGUIDs: []string{eventstore.ComputePlanGUID},
+				Unit:      "GB-hours",
+				Amount:    "100",
+				ValidFrom: "2001-01-01",
+			},
+			{
+				Name:                "trial compute",
+				PlanGUIDs:           []string{eventstore.ComputePlanGUID},
+				Unit:                "GB-hours",
+				Amount:              "2",
+				QuotaDefinitionGUID: quotaGUID,
+				ValidFrom:           "2001-01-01",
+			},
+		}
+		openWithApp(ctx, cfg)
+
+		events := billableEvents()
+		first := components(events[0])
+		Expect(first).ToNot(HaveKey("free compute (compute)"))
+		Expect(first["trial compute (compute)"].ExVAT.Round(2)).To(Equal(eventio.Money("-0.02")))
+		Expect(components(events[1])).To(HaveLen(2))
+
+		usage := allowanceUsage(ctx)
+		Expect(usage).To(HaveLen(2))
+		Expect(usage[0].Name).To(Equal("trial compute"))
+		Expect(eventio.Money(usage[0].Used).Round(2)).To(Equal(eventio.Money("2.00")))
+	})
+
+	It("should not apply an allowance for another quota", func(ctx SpecContext) {
+		cfg.Allowances = []eventio.Allowance{{
+			Name:                "trial compute",
+			PlanGUIDs:           []string{eventstore.ComputePlanGUID},
+			Unit:                "GB-hours",
+			Amount:              "2",
+			QuotaDefinitionGUID: otherQuotaGUID,
+			ValidFrom:           "2001-01-01",
+		}}
+		openWithApp(ctx, cfg)
+
+		events := billableEvents()
+		Expect(events[0].Price.ExVAT.Round(2)).To(Equal(eventio.Money("0.05")))
+		Expect(allowanceUsage(ctx)).To(BeEmpty())
+	})
+})
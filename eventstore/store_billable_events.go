@@ -19,11 +19,12 @@ var _ eventio.BillableEventReader = &EventStore{}
 // rows.Close when you are done to release the connection. Use GetBillableEvents
 // if you intend on buffering everything into memory.
 func (s *EventStore) GetBillableEventRows(ctx context.Context, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "GetBillableEventRows", err)
 		return nil, err
 	}
-	rows, err := s.getBillableEventRows(tx, filter)
+	rows, err := s.getBillableEventRows(ctx, tx, filter)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -31,7 +32,7 @@ func (s *EventStore) GetBillableEventRows(ctx context.Context, filter eventio.Ev
 	return rows, nil
 }
 
-func (s *EventStore) getBillableEventRows(tx *sql.Tx, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+func (s *EventStore) getBillableEventRows(ctx context.Context, tx *sql.Tx, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
@@ -43,12 +44,14 @@ func (s *EventStore) getBillableEventRows(tx *sql.Tx, filter eventio.EventFilter
 	if err != nil {
 		return nil, err
 	}
+	query, args = withAggregatedBillableEvents(query, args, filter, s.aggregationRules(filter))
 
 	startTime := time.Now()
-	rows, queryErr := queryJSON(tx, query, args...)
+	rows, err := queryJSON(ctx, tx, query, args...)
 	elapsed := time.Since(startTime)
-	if queryErr != nil {
+	if err != nil {
 		eventStorePerformanceGauge.WithLabelValues("getBillableEventRows", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "getBillableEventRows", err)
 		s.logger.Error("get-billable-event-rows-query", err, lager.Data{
 			"filter":  filter,
 			"elapsed": int64(elapsed),
@@ -61,7 +64,7 @@ func (s *EventStore) getBillableEventRows(tx *sql.Tx, filter eventio.EventFilter
 		"elapsed": int64(elapsed),
 	})
 
-	return &BillableEventRows{rows}, nil
+	return &BillableEventRows{rows: rows, ctx: ctx, fn: "getBillableEventRows"}, nil
 }
 
 // GetBillableEvents returns a slice of billable events for the given filter.
@@ -69,7 +72,12 @@ func (s *EventStore) getBillableEventRows(tx *sql.Tx, filter eventio.EventFilter
 // you use the GetBillableEventRows version to avoid buffering everything into
 // memory
 func (s *EventStore) GetBillableEvents(filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
-	ctx, cancel := context.WithCancel(s.ctx)
+	return s.GetBillableEventsContext(s.ctx, filter)
+}
+
+// GetBillableEventsContext is GetBillableEvents stopping early if ctx is done
+func (s *EventStore) GetBillableEventsContext(ctx context.Context, filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	rows, err := s.GetBillableEventRows(ctx, filter)
@@ -93,6 +101,8 @@ func (s *EventStore) GetBillableEvents(filter eventio.EventFilter) ([]eventio.Bi
 
 type BillableEventRows struct {
 	rows *sql.Rows
+	ctx  context.Context // the context the query was started with
+	fn   string          // the query's name in the cancelled queries metric
 }
 
 // Next moves the row cursor to the next iteration. Returns false if no more
@@ -104,7 +114,11 @@ func (ber *BillableEventRows) Next() bool {
 // Err returns any errors that occurred behind the scenes during processing.
 // Call this at the end of your iteration.
 func (ber *BillableEventRows) Err() error {
-	return ber.rows.Err()
+	err := ber.rows.Err()
+	if ber.ctx != nil {
+		observeCancellation(ber.ctx, ber.fn, err)
+	}
+	return err
 }
 
 // Close ends the query connection. You must call this. So stick it in a defer.
@@ -160,6 +174,11 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 	if len(orgPlaceholders) > 0 {
 		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
 	}
+	labelConditions, args, err := labelSelectorConditions(filter.LabelSelector, args)
+	if err != nil {
+		return query, args, err
+	}
+	filterConditions = append(filterConditions, labelConditions...)
 	filterQuery := ""
 	if len(filterConditions) > 0 {
 		filterQuery = " and " + strings.Join(filterConditions, " and ")
@@ -178,6 +197,7 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 				b.resource_type,
 				b.org_guid,
 				b.org_name,
+				b.quota_definition_guid,
 				b.space_guid,
 				b.space_name,
 				b.plan_guid,
@@ -186,8 +206,12 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 				b.number_of_nodes,
 				b.memory_in_mb,
 				b.storage_in_mb,
+				b.disk_in_mb,
+				b.log_rate_limit,
+				b.labels,
 				b.component_name,
 				b.component_formula,
+				b.unit,
 				b.vat_code,
 				b.vat_rate,
 				'GBP' as currency_code,
@@ -196,8 +220,19 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 					b.storage_in_mb,
 					b.number_of_nodes,
 					b.duration * filtered_range,
-					b.component_formula
-				) * b.currency_rate) as price_ex_vat
+					b.component_formula,
+					b.disk_in_mb,
+					b.log_rate_limit
+				) * b.currency_rate) as price_ex_vat,
+				(case when b.quantity_formula is not null then eval_formula(
+					b.memory_in_mb,
+					b.storage_in_mb,
+					b.number_of_nodes,
+					b.duration * filtered_range,
+					b.quantity_formula,
+					b.disk_in_mb,
+					b.log_rate_limit
+				) end) as quantity
 			from
 			    filtered_range,
 				billable_event_components b
@@ -217,13 +252,16 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 				resource_type,
 				org_guid,
 				org_name,
-				null::uuid as quota_definition_guid,
+				quota_definition_guid,
 				space_guid,
 				space_name,
 				plan_guid,
 				number_of_nodes,
 				memory_in_mb,
 				storage_in_mb,
+				disk_in_mb,
+				log_rate_limit,
+				nullif(labels, '{}'::jsonb) as labels,
 				json_build_object(
 					'ex_vat', (sum(price_ex_vat))::text,
 					'inc_vat', (sum(price_ex_vat * (1 + vat_rate)))::text,
@@ -236,7 +274,9 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 						'inc_vat', (price_ex_vat * (1 + vat_rate))::text,
 						'vat_rate', (vat_rate)::text,
 						'vat_code', vat_code,
-						'currency_code', currency_code
+						'currency_code', currency_code,
+						'unit', nullif(unit, ''),
+						'quantity', (quantity)::text
 					))
 				) as price
 			from
@@ -254,7 +294,10 @@ func WithBillableEvents(query string, filter eventio.EventFilter, args ...interf
 				plan_guid,
 				number_of_nodes,
 				memory_in_mb,
-				storage_in_mb
+				storage_in_mb,
+				disk_in_mb,
+				log_rate_limit,
+				labels
 			order by
 				event_guid
 	  )
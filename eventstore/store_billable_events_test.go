@@ -304,6 +304,91 @@ var _ = Describe("GetBillableEvents", func() {
 		Expect(rows.Next()).To(BeFalse(), "did not expect any more rows")
 	})
 
+	It("Should aggregate tasks into one BillingEvent per space unless aggregation is turned off", func(ctx SpecContext) {
+		cfg.Aggregations = eventstore.DefaultAggregations
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.TaskPlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "task",
+					Formula:      "ceil($time_in_seconds/3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		taskEvent := func(guid string, createdAt string, taskGUID string, state string) testenv.Row {
+			return testenv.Row{
+				"guid":        guid,
+				"created_at":  createdAt,
+				"raw_message": json.RawMessage(fmt.Sprintf(`{"state": "%s", "task_guid": "%s", "task_name": "TSK", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": null, "instance_count": 1, "previous_state": "TASK_STARTED", "memory_in_mb_per_instance": 1024}`, state, taskGUID)),
+			}
+		}
+		Expect(db.Insert("app_usage_events",
+			taskEvent("8c7dc213-6b64-45af-8635-027ca94687c6", "2001-01-01T00:00Z", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "TASK_STARTED"),
+			taskEvent("ad1aaa9e-f015-4b33-8fa6-e7bfa74acdc5", "2001-01-01T01:00Z", "c85e98f0-6d1b-4f45-9368-ea58263165a0", "TASK_STOPPED"),
+			taskEvent("2a5cf5a8-4b1a-4a4e-9c8e-59e6a1a7b1e1", "2001-01-01T02:00Z", "a2b7a4f6-4e0c-4d5c-9f3e-6f1b2d3c4e5f", "TASK_STARTED"),
+			taskEvent("5f0c7b1e-8d2a-4c3b-a1e4-7d6c5b4a3f2e", "2001-01-01T03:00Z", "a2b7a4f6-4e0c-4d5c-9f3e-6f1b2d3c4e5f", "TASK_STOPPED"),
+		)).To(Succeed())
+
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events, err := db.Schema.GetBillableEventsContext(ctx, eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].EventGUID).ToNot(BeEmpty())
+		events[0].EventGUID = ""
+		Expect(events[0]).To(Equal(eventio.BillableEvent{
+			EventStart:   "2001-01-01T00:00:00+00:00",
+			EventStop:    "2001-02-01T00:00:00+00:00",
+			ResourceGUID: "276f4886-ac40-492d-a8cd-b2646637ba76",
+			ResourceName: "Total Task Events",
+			ResourceType: "task",
+			OrgGUID:      "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			OrgName:      "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			SpaceGUID:    "276f4886-ac40-492d-a8cd-b2646637ba76",
+			SpaceName:    "276f4886-ac40-492d-a8cd-b2646637ba76",
+			PlanGUID:     eventstore.TaskPlanGUID,
+			Price: eventio.Price{
+				IncVAT: "0.024",
+				ExVAT:  "0.02",
+				Details: []eventio.PriceComponent{
+					{
+						Name:         "task",
+						PlanName:     "PLAN1",
+						Start:        "2001-01-01T00:00:00+00:00",
+						Stop:         "2001-02-01T00:00:00+00:00",
+						VatRate:      "0.2",
+						VatCode:      "Standard",
+						CurrencyCode: "GBP",
+						IncVAT:       "0.024",
+						ExVAT:        "0.02",
+					},
+				},
+			},
+		}))
+
+		events, err = db.Schema.GetBillableEventsContext(ctx, eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			Aggregate:  eventio.AggregateNone,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].ResourceName).To(Equal("TSK"))
+		Expect(events[1].ResourceName).To(Equal("TSK"))
+	})
+
 	/*-----------------------------------------------------------------------------------*
 	       00:00       01:00       02:00                                                 .
 	         |           |           |                                                   .
@@ -1210,8 +1295,8 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    1024,
 			StorageInMB:   2048,
 			Price: eventio.Price{
-				IncVAT: fmt.Sprintf("%d", expectedEvent1PriceIncVat),
-				ExVAT:  fmt.Sprintf("%d", expectedEvent1PriceExVat),
+				IncVAT: eventio.Money(fmt.Sprintf("%d", expectedEvent1PriceIncVat)),
+				ExVAT:  eventio.Money(fmt.Sprintf("%d", expectedEvent1PriceExVat)),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compose",
@@ -1221,8 +1306,8 @@ var _ = Describe("GetBillableEvents", func() {
 						VatRate:      "0",
 						VatCode:      "Zero",
 						CurrencyCode: "GBP",
-						IncVAT:       fmt.Sprintf("%d", expectedEvent1PriceIncVat),
-						ExVAT:        fmt.Sprintf("%d", expectedEvent1PriceExVat),
+						IncVAT:       eventio.Money(fmt.Sprintf("%d", expectedEvent1PriceIncVat)),
+						ExVAT:        eventio.Money(fmt.Sprintf("%d", expectedEvent1PriceExVat)),
 					},
 				},
 			},
@@ -1244,8 +1329,8 @@ var _ = Describe("GetBillableEvents", func() {
 			MemoryInMB:    2048,
 			StorageInMB:   4096,
 			Price: eventio.Price{
-				IncVAT: fmt.Sprintf("%d", expectedEvent2PriceIncVat),
-				ExVAT:  fmt.Sprintf("%d", expectedEvent2PriceExVat),
+				IncVAT: eventio.Money(fmt.Sprintf("%d", expectedEvent2PriceIncVat)),
+				ExVAT:  eventio.Money(fmt.Sprintf("%d", expectedEvent2PriceExVat)),
 				Details: []eventio.PriceComponent{
 					{
 						Name:         "compose",
@@ -1255,8 +1340,8 @@ var _ = Describe("GetBillableEvents", func() {
 						VatRate:      "0",
 						VatCode:      "Zero",
 						CurrencyCode: "GBP",
-						IncVAT:       fmt.Sprintf("%d", expectedEvent2PriceIncVat),
-						ExVAT:        fmt.Sprintf("%d", expectedEvent2PriceExVat),
+						IncVAT:       eventio.Money(fmt.Sprintf("%d", expectedEvent2PriceIncVat)),
+						ExVAT:        eventio.Money(fmt.Sprintf("%d", expectedEvent2PriceExVat)),
 					},
 				},
 			},
@@ -1,16 +1,27 @@
 package eventstore
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/alphagov/paas-billing/eventio"
 )
 
 type Config struct {
-	VATRates           []eventio.VATRate      `json:"vat_rates"`            // vat rate
-	CurrencyRates      []eventio.CurrencyRate `json:"currency_rates"`       // exchange rates
-	PricingPlans       []eventio.PricingPlan  `json:"pricing_plans"`        // dataset to generate prices from
-	IgnoreMissingPlans bool                   `json:"ignore_missing_plans"` // if true, will generate missing plans that emit "£0", useful for testing
+	VATRates           []eventio.VATRate          `json:"vat_rates"`            // vat rate
+	CurrencyRates      []eventio.CurrencyRate     `json:"currency_rates"`       // exchange rates
+	PricingPlans       []eventio.PricingPlan      `json:"pricing_plans"`        // dataset to generate prices from
+	IgnoreMissingPlans bool                       `json:"ignore_missing_plans"` // if true, will generate missing plans that emit "£0", useful for testing
+	BillingAccounts    []eventio.BillingAccount   `json:"billing_accounts"`     // orgs that share a single statement
+	Aggregations       []eventio.AggregationRule  `json:"aggregations"`         // resource types reported as one event per space, see DefaultAggregations
+	Exemptions         []eventio.Exemption        `json:"exemptions"`           // orgs and spaces whose usage is priced at £0, such as test spaces
+	QuotaPlans         []eventio.QuotaPlan        `json:"quota_plans"`          // fees and multipliers for orgs by quota definition
+	CostSharingRules   []eventio.CostSharingRule  `json:"cost_sharing_rules"`   // shared service instances whose cost is split between spaces
+	ProviderCostRules  []eventio.ProviderCostRule `json:"provider_cost_rules"`  // plans billed the provider costs imported for their service instances
+	Allowances         []eventio.Allowance        `json:"allowances"`           // usage given free to each org every month
+	FootprintPlans     []eventio.FootprintPlan    `json:"footprint_plans"`      // energy and carbon formulas to estimate footprints with
+	GridIntensities    []eventio.GridIntensity    `json:"grid_intensities"`     // carbon emitted per kWh of grid energy
+	GridIntensityFiles []string                   `json:"grid_intensity_files"` // CSV files of grid intensities, relative to the config file
 }
 
 func (cfg *Config) AddPlan(p eventio.PricingPlan) {
@@ -28,15 +39,21 @@ func (cfg *Config) AddCurrencyRate(c eventio.CurrencyRate) {
 var _ eventio.PricingPlanReader = &EventStore{}
 
 func (s *EventStore) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
+	return s.GetPricingPlansContext(s.ctx, filter)
+}
+
+// GetPricingPlansContext is GetPricingPlans stopping early if ctx is done
+func (s *EventStore) GetPricingPlansContext(ctx context.Context, filter eventio.TimeRangeFilter) ([]eventio.PricingPlan, error) {
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
-	tx, err := s.db.Begin()
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "GetPricingPlans", err)
 		return nil, err
 	}
 	defer tx.Rollback()
-	rows, err := queryJSON(tx, `
+	rows, err := queryJSON(ctx, tx, `
 		with
 		valid_pricing_plans as (
 			select
@@ -54,12 +71,15 @@ func (s *EventStore) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.
 			vpp.memory_in_mb,
 			vpp.number_of_nodes,
 			vpp.storage_in_mb,
+			coalesce(vpp.isolation_segment_guid::text, '') as isolation_segment_guid,
 			json_agg(json_build_object(
 				'plan_guid', ppc.plan_guid::text,
 				'name', ppc.name,
 				'formula', ppc.formula,
 				'vat_code', ppc.vat_code,
-				'currency_code', ppc.currency_code
+				'currency_code', ppc.currency_code,
+				'unit', ppc.unit,
+				'quantity_formula', ppc.quantity_formula
 			)) as components
 		from
 			valid_pricing_plans vpp
@@ -74,11 +94,13 @@ func (s *EventStore) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.
 			vpp.name,
 			vpp.memory_in_mb,
 			vpp.number_of_nodes,
-			vpp.storage_in_mb
+			vpp.storage_in_mb,
+			vpp.isolation_segment_guid
 		order by
 			valid_from
 	`, filter.RangeStart, filter.RangeStop)
 	if err != nil {
+		observeCancellation(ctx, "GetPricingPlans", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -95,5 +117,9 @@ func (s *EventStore) GetPricingPlans(filter eventio.TimeRangeFilter) ([]eventio.
 		plans = append(plans, plan)
 
 	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "GetPricingPlans", err)
+		return nil, err
+	}
 	return plans, nil
 }
@@ -17,11 +17,12 @@ const (
 )
 
 func (s *EventStore) GetConsolidatedBillableEventRows(ctx context.Context, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "GetConsolidatedBillableEventRows", err)
 		return nil, err
 	}
-	rows, err := s.getConsolidatedBillableEventRows(tx, filter)
+	rows, err := s.getConsolidatedBillableEventRows(ctx, tx, filter)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -29,7 +30,7 @@ func (s *EventStore) GetConsolidatedBillableEventRows(ctx context.Context, filte
 	return rows, nil
 }
 
-func (s *EventStore) getConsolidatedBillableEventRows(tx *sql.Tx, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+func (s *EventStore) getConsolidatedBillableEventRows(ctx context.Context, tx *sql.Tx, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
@@ -53,13 +54,17 @@ func (s *EventStore) getConsolidatedBillableEventRows(tx *sql.Tx, filter eventio
 	if len(orgPlaceholders) > 0 {
 		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
 	}
+	labelConditions, args, err := labelSelectorConditions(filter.LabelSelector, args)
+	if err != nil {
+		return nil, err
+	}
+	filterConditions = append(filterConditions, labelConditions...)
 	filterQuery := ""
 	if len(filterConditions) > 0 {
 		filterQuery = " and " + strings.Join(filterConditions, " and ")
 	}
 
-	startTime := time.Now()
-	rows, err := queryJSON(tx, fmt.Sprintf(`
+	query, args := withAggregatedBillableEvents(fmt.Sprintf(`
 		select
 			event_guid,
 			lower(duration) as event_start,
@@ -76,17 +81,24 @@ func (s *EventStore) getConsolidatedBillableEventRows(tx *sql.Tx, filter eventio
 			number_of_nodes,
 			memory_in_mb,
 			storage_in_mb,
-			price
+			disk_in_mb,
+			log_rate_limit,
+			price,
+			labels
 		from
 			consolidated_billable_events
  		where
 			consolidated_range && $1::tstzrange
 			%s
 		order by event_guid
-	`, filterQuery), args...)
+	`, filterQuery), args, filter, s.aggregationRules(filter))
+
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, query, args...)
 	elapsed := time.Since(startTime)
 	eventStorePerformanceGauge.WithLabelValues("getConsolidatedBillableEventRows", "").Set(elapsed.Seconds())
 	if err != nil {
+		observeCancellation(ctx, "getConsolidatedBillableEventRows", err)
 		s.logger.Error("get-consolidated-billable-event-rows-query", err, lager.Data{
 			"filter":  filter,
 			"elapsed": int64(elapsed),
@@ -97,11 +109,17 @@ func (s *EventStore) getConsolidatedBillableEventRows(tx *sql.Tx, filter eventio
 		"filter":  filter,
 		"elapsed": int64(elapsed),
 	})
-	return &BillableEventRows{rows}, nil
+	return &BillableEventRows{rows: rows, ctx: ctx, fn: "getConsolidatedBillableEventRows"}, nil
 }
 
 func (s *EventStore) GetConsolidatedBillableEvents(filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+	return s.GetConsolidatedBillableEventsContext(s.ctx, filter)
+}
+
+// GetConsolidatedBillableEventsContext is GetConsolidatedBillableEvents
+// stopping early if ctx is done
+func (s *EventStore) GetConsolidatedBillableEventsContext(ctx context.Context, filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	rows, err := s.GetConsolidatedBillableEventRows(ctx, filter)
@@ -125,11 +143,18 @@ func (s *EventStore) GetConsolidatedBillableEvents(filter eventio.EventFilter) (
 }
 
 func (s *EventStore) IsRangeConsolidated(filter eventio.EventFilter) (bool, error) {
-	tx, err := s.db.Begin()
+	return s.IsRangeConsolidatedContext(s.ctx, filter)
+}
+
+// IsRangeConsolidatedContext is IsRangeConsolidated stopping early if ctx is
+// done
+func (s *EventStore) IsRangeConsolidatedContext(ctx context.Context, filter eventio.EventFilter) (bool, error) {
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "IsRangeConsolidated", err)
 		return false, err
 	}
-	result, err := s.isRangeConsolidated(tx, filter)
+	result, err := s.isRangeConsolidated(ctx, tx, filter)
 	if err != nil {
 		tx.Rollback()
 		return false, err
@@ -137,18 +162,19 @@ func (s *EventStore) IsRangeConsolidated(filter eventio.EventFilter) (bool, erro
 	return result, tx.Commit()
 }
 
-func (s *EventStore) isRangeConsolidated(tx *sql.Tx, filter eventio.EventFilter) (bool, error) {
+func (s *EventStore) isRangeConsolidated(ctx context.Context, tx *sql.Tx, filter eventio.EventFilter) (bool, error) {
 	if err := filter.Validate(); err != nil {
 		return false, err
 	}
 	startTime := time.Now()
-	rows, err := tx.Query(
+	rows, err := tx.QueryContext(ctx,
 		"SELECT 1 FROM consolidation_history where consolidated_range=$1::tstzrange",
 		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop),
 	)
 	elapsed := time.Since(startTime)
 	if err != nil {
 		eventStorePerformanceGauge.WithLabelValues("isRangeConsolidated", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "isRangeConsolidated", err)
 		s.logger.Error("is-range-consolidated-query", err, lager.Data{
 			"filter":  filter,
 			"elapsed": int64(elapsed),
@@ -164,6 +190,59 @@ func (s *EventStore) isRangeConsolidated(tx *sql.Tx, filter eventio.EventFilter)
 	return rows.Next(), nil
 }
 
+// GetConsolidationHistory returns the consolidated months that fall
+// entirely within the filter range, in order.
+func (s *EventStore) GetConsolidationHistory(filter eventio.EventFilter) ([]eventio.ConsolidationRecord, error) {
+	return s.GetConsolidationHistoryContext(s.ctx, filter)
+}
+
+// GetConsolidationHistoryContext is GetConsolidationHistory stopping early
+// if ctx is done
+func (s *EventStore) GetConsolidationHistoryContext(ctx context.Context, filter eventio.EventFilter) ([]eventio.ConsolidationRecord, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	startTime := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		select
+			to_char(lower(consolidated_range), 'YYYY-MM-DD'),
+			to_char(upper(consolidated_range), 'YYYY-MM-DD'),
+			created_at
+		from
+			consolidation_history
+		where
+			consolidated_range <@ $1::tstzrange
+		order by
+			lower(consolidated_range)
+	`, fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop))
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("GetConsolidationHistory", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "GetConsolidationHistory", err)
+		s.logger.Error("get-consolidation-history-query", err, lager.Data{
+			"filter":  filter,
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("GetConsolidationHistory", "").Set(elapsed.Seconds())
+	s.logger.Info("get-consolidation-history-query", lager.Data{
+		"filter":  filter,
+		"elapsed": int64(elapsed),
+	})
+	defer rows.Close()
+
+	records := []eventio.ConsolidationRecord{}
+	for rows.Next() {
+		var record eventio.ConsolidationRecord
+		if err := rows.Scan(&record.RangeStart, &record.RangeStop, &record.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
 func (s *EventStore) ConsolidateAll() error {
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -222,7 +301,7 @@ func (s *EventStore) consolidateFullMonths(tx *sql.Tx, startAt string, endAt str
 		return err
 	}
 	for _, filter := range monthFilters {
-		isConsolidated, err := s.isRangeConsolidated(tx, filter)
+		isConsolidated, err := s.isRangeConsolidated(s.ctx, tx, filter)
 		if err != nil {
 			return err
 		}
@@ -307,7 +386,10 @@ func (s *EventStore) consolidate(tx *sql.Tx, filter eventio.EventFilter) error {
 				number_of_nodes,
 				memory_in_mb,
 				storage_in_mb,
-				price
+				disk_in_mb,
+				log_rate_limit,
+				price,
+				labels
 			)
 			select
 				filtered_range,
@@ -326,7 +408,10 @@ func (s *EventStore) consolidate(tx *sql.Tx, filter eventio.EventFilter) error {
 				billable_events.number_of_nodes,
 				billable_events.memory_in_mb,
 				billable_events.storage_in_mb,
-				billable_events.price
+				billable_events.disk_in_mb,
+				billable_events.log_rate_limit,
+				billable_events.price,
+				billable_events.labels
 			from
 				billable_events,
 				filtered_range
@@ -232,6 +232,28 @@ var _ = Describe("IsRangeConsolidated", func() {
 		Expect(result).To(BeTrue())
 	})
 
+	It("Should list the consolidated months within a range", func(ctx SpecContext) {
+		db, err = scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Schema.Consolidate(eventio.EventFilter{RangeStart: "2001-02-01", RangeStop: "2001-03-01"})).To(Succeed())
+		Expect(db.Schema.Consolidate(eventio.EventFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"})).To(Succeed())
+		Expect(db.Schema.Consolidate(eventio.EventFilter{RangeStart: "2001-03-01", RangeStop: "2001-04-01"})).To(Succeed())
+
+		records, err := db.Schema.GetConsolidationHistory(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-03-15",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].RangeStart).To(Equal("2001-01-01"))
+		Expect(records[0].RangeStop).To(Equal("2001-02-01"))
+		Expect(records[0].CreatedAt).ToNot(BeZero())
+		Expect(records[1].RangeStart).To(Equal("2001-02-01"))
+		Expect(records[1].RangeStop).To(Equal("2001-03-01"))
+	})
+
 })
 
 var _ = Describe("ConsolidateFullMonths", func() {
@@ -0,0 +1,69 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// pqQueryCanceled is the SQLSTATE Postgres returns when a statement is
+// cancelled, either by request or because statement_timeout expired
+const pqQueryCanceled = "57014"
+
+var (
+	cancelledQueriesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "paas_billing",
+			Subsystem: "eventstore",
+			Name:      "cancelled_queries_total",
+			Help:      "Count of queries stopped before completing because the caller went away or a timeout expired",
+		}, []string{"function", "reason"})
+)
+
+// beginQueryTx starts a transaction bound to ctx. If ctx has a deadline the
+// transaction's statement_timeout is set to match, so Postgres stops work on
+// its own even if the cancel request sent when ctx expires is lost.
+func (s *EventStore) beginQueryTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout := time.Until(deadline).Milliseconds()
+		if timeout < 1 {
+			timeout = 1
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("set local statement_timeout = %d", timeout)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// observeCancellation counts err against fn if it was caused by ctx being
+// cancelled or by a statement timeout
+func observeCancellation(ctx context.Context, fn string, err error) {
+	if err == nil {
+		return
+	}
+	reason := ""
+	var pqErr *pq.Error
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded):
+		reason = "deadline_exceeded"
+	case errors.Is(ctx.Err(), context.Canceled) || errors.Is(err, context.Canceled):
+		reason = "canceled"
+	case errors.As(err, &pqErr) && pqErr.Code == pqQueryCanceled:
+		reason = "statement_timeout"
+	default:
+		return
+	}
+	cancelledQueriesCounter.WithLabelValues(fn, reason).Inc()
+}
@@ -0,0 +1,44 @@
+package eventstore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// initCostSharingRules replaces the cost sharing rules with those from the
+// config
+func (s *EventStore) initCostSharingRules(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM cost_sharing_rules"); err != nil {
+		return wrapPqError(err, "error deleting existing cost_sharing_rules")
+	}
+	for _, rule := range s.cfg.CostSharingRules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		s.logger.Info("configuring-cost-sharing-rule", lager.Data{
+			"service_instance_guid": rule.ServiceInstanceGUID,
+			"method":                rule.Method,
+			"valid_from":            rule.ValidFrom,
+			"valid_to":              rule.ValidTo,
+		})
+		weights := map[string]string{}
+		for _, w := range rule.Weights {
+			weights[w.SpaceGUID] = w.Weight
+		}
+		weightsJSON, err := json.Marshal(weights)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`insert into cost_sharing_rules (
+			service_instance_guid, valid_from, valid_to, method, weights
+		) values (
+			$1, $2, nullif($3, '')::timestamptz, $4, $5
+		)`, rule.ServiceInstanceGUID, rule.ValidFrom, rule.ValidTo, rule.Method, string(weightsJSON))
+		if err != nil {
+			return wrapPqError(err, "invalid cost sharing rule")
+		}
+	}
+	return nil
+}
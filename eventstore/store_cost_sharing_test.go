@@ -0,0 +1,222 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cost sharing", func() {
+
+	const (
+		ownerOrgGUID        = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		ownerSpaceGUID      = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		consumerOrgGUID     = "b1e5a0d4-2c7f-4e36-9a6e-0f4c1d2e3b4a"
+		consumerSpaceGUID   = "c2f6b1e5-3d8a-4f47-8b7f-1a5d2e3f4c5b"
+		serviceInstanceGUID = "aaaaaaaa-0000-0000-0000-000000000001"
+		servicePlanGUID     = "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5"
+		serviceGUID         = "efadb775-58c4-4e17-8087-6d0f4febc489"
+		planUniqueID        = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  planUniqueID,
+			ValidFrom: "2001-01-01",
+			Name:      "DB",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:            "db",
+					Formula:         "$time_in_seconds / 3600",
+					CurrencyCode:    "GBP",
+					VATCode:         "Standard",
+					Unit:            "hours",
+					QuantityFormula: "$time_in_seconds / 3600",
+				},
+			},
+		})
+	})
+
+	openWithServiceInstance := func(ctx SpecContext, cfg eventstore.Config, bindings string) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("services",
+			testenv.Row{
+				"label":               "postgres",
+				"guid":                serviceGUID,
+				"valid_from":          "2000-01-01T00:00Z",
+				"created_at":          "2000-01-01T00:00Z",
+				"updated_at":          "2000-01-01T00:00Z",
+				"description":         "",
+				"service_broker_guid": "efadb775-58c4-4e17-8087-6d0f4febc481",
+				"active":              true,
+				"bindable":            true,
+			})).To(Succeed())
+		Expect(db.Insert("service_plans",
+			testenv.Row{
+				"unique_id":          planUniqueID,
+				"name":               "DB",
+				"guid":               servicePlanGUID,
+				"valid_from":         "2000-01-01T00:00Z",
+				"created_at":         "2000-01-01T00:00Z",
+				"updated_at":         "2000-01-01T00:00Z",
+				"description":        "",
+				"service_guid":       serviceGUID,
+				"service_valid_from": "2000-01-01T00:00Z",
+				"active":             true,
+				"public":             true,
+				"free":               false,
+				"extra":              "",
+			})).To(Succeed())
+		Expect(db.Insert("service_usage_events",
+			testenv.Row{
+				"guid":        "00000000-0000-0000-0000-000000000001",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "CREATED", "org_guid": "` + ownerOrgGUID + `", "space_guid": "` + ownerSpaceGUID + `", "space_name": "platform", "service_guid": "` + serviceGUID + `", "service_label": "postgres", "service_plan_guid": "` + servicePlanGUID + `", "service_plan_name": "DB", "service_instance_guid": "` + serviceInstanceGUID + `", "service_instance_name": "db1", "service_instance_type": "managed_service_instance"}`),
+			},
+			testenv.Row{
+				"guid":        "00000000-0000-0000-0000-000000000002",
+				"created_at":  "2001-01-01T06:00Z",
+				"raw_message": json.RawMessage(`{"state": "DELETED", "org_guid": "` + ownerOrgGUID + `", "space_guid": "` + ownerSpaceGUID + `", "space_name": "platform", "service_guid": "` + serviceGUID + `", "service_label": "postgres", "service_plan_guid": "` + servicePlanGUID + `", "service_plan_name": "DB", "service_instance_guid": "` + serviceInstanceGUID + `", "service_instance_name": "db1", "service_instance_type": "managed_service_instance"}`),
+			},
+		)).To(Succeed())
+		Expect(db.Insert("service_instance_shares",
+			testenv.Row{
+				"service_instance_guid": serviceInstanceGUID,
+				"valid_from":            "2000-01-01T00:00Z",
+				"shared_spaces":         json.RawMessage(`[{"space_guid": "` + consumerSpaceGUID + `", "space_name": "product", "org_guid": "` + consumerOrgGUID + `", "org_name": "product-org"}]`),
+				"bindings":              json.RawMessage(bindings),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	}
+
+	billableEventsByOrg := func() map[string][]eventio.BillableEvent {
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		byOrg := map[string][]eventio.BillableEvent{}
+		for _, ev := range events {
+			byOrg[ev.OrgGUID] = append(byOrg[ev.OrgGUID], ev)
+		}
+		return byOrg
+	}
+
+	roundedPrice := func(ev eventio.BillableEvent) eventio.Money {
+		price, err := ev.Price.ExVAT.Round(2)
+		Expect(err).ToNot(HaveOccurred())
+		return price
+	}
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should bill nothing extra for a shared service instance without a rule", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg, `{}`)
+
+		byOrg := billableEventsByOrg()
+		Expect(byOrg).To(HaveLen(1))
+		Expect(byOrg[ownerOrgGUID]).To(HaveLen(1))
+		Expect(roundedPrice(byOrg[ownerOrgGUID][0])).To(Equal(eventio.Money("6.00")))
+	})
+
+	It("should split the cost equally and credit the owning space", func(ctx SpecContext) {
+		cfg.CostSharingRules = []eventio.CostSharingRule{{
+			ServiceInstanceGUID: serviceInstanceGUID,
+			Method:              eventio.CostSharingEqual,
+			ValidFrom:           "2001-01-01",
+		}}
+		openWithServiceInstance(ctx, cfg, `{}`)
+
+		byOrg := billableEventsByOrg()
+		Expect(byOrg[consumerOrgGUID]).To(HaveLen(1))
+		consumerEvent := byOrg[consumerOrgGUID][0]
+		Expect(consumerEvent.SpaceGUID).To(Equal(consumerSpaceGUID))
+		Expect(consumerEvent.SpaceName).To(Equal("product"))
+		Expect(consumerEvent.OrgName).To(Equal("product-org"))
+		Expect(consumerEvent.ResourceGUID).To(Equal(serviceInstanceGUID))
+		Expect(roundedPrice(consumerEvent)).To(Equal(eventio.Money("3.00")))
+
+		Expect(byOrg[ownerOrgGUID]).To(HaveLen(2))
+		prices := []eventio.Money{}
+		for _, ev := range byOrg[ownerOrgGUID] {
+			Expect(ev.SpaceGUID).To(Equal(ownerSpaceGUID))
+			prices = append(prices, roundedPrice(ev))
+		}
+		Expect(prices).To(ConsistOf(eventio.Money("6.00"), eventio.Money("-3.00")))
+	})
+
+	It("should split the cost by the apps bound in each space", func(ctx SpecContext) {
+		cfg.CostSharingRules = []eventio.CostSharingRule{{
+			ServiceInstanceGUID: serviceInstanceGUID,
+			Method:              eventio.CostSharingBindings,
+			ValidFrom:           "2001-01-01",
+		}}
+		openWithServiceInstance(ctx, cfg, `{"`+ownerSpaceGUID+`": 1, "`+consumerSpaceGUID+`": 2}`)
+
+		byOrg := billableEventsByOrg()
+		Expect(byOrg[consumerOrgGUID]).To(HaveLen(1))
+		Expect(roundedPrice(byOrg[consumerOrgGUID][0])).To(Equal(eventio.Money("4.00")))
+	})
+
+	It("should give spaces without a weight no share", func(ctx SpecContext) {
+		cfg.CostSharingRules = []eventio.CostSharingRule{{
+			ServiceInstanceGUID: serviceInstanceGUID,
+			Method:              eventio.CostSharingWeights,
+			ValidFrom:           "2001-01-01",
+			Weights: []eventio.CostSharingWeight{
+				{SpaceGUID: consumerSpaceGUID, Weight: "1"},
+			},
+		}}
+		openWithServiceInstance(ctx, cfg, `{}`)
+
+		byOrg := billableEventsByOrg()
+		Expect(byOrg[consumerOrgGUID]).To(HaveLen(1))
+		Expect(roundedPrice(byOrg[consumerOrgGUID][0])).To(Equal(eventio.Money("6.00")))
+		prices := []eventio.Money{}
+		for _, ev := range byOrg[ownerOrgGUID] {
+			prices = append(prices, roundedPrice(ev))
+		}
+		Expect(prices).To(ConsistOf(eventio.Money("6.00"), eventio.Money("-6.00")))
+	})
+
+	It("should only set each space's share against its org's allowances", func(ctx SpecContext) {
+		cfg.CostSharingRules = []eventio.CostSharingRule{{
+			ServiceInstanceGUID: serviceInstanceGUID,
+			Method:              eventio.CostSharingEqual,
+			ValidFrom:           "2001-01-01",
+		}}
+		cfg.Allowances = []eventio.Allowance{{
+			Name:      "free db",
+			PlanGUIDs: []string{planUniqueID},
+			Unit:      "hours",
+			Amount:    "100",
+			ValidFrom: "2001-01-01",
+		}}
+		openWithServiceInstance(ctx, cfg, `{}`)
+
+		byOrg := billableEventsByOrg()
+		Expect(byOrg[consumerOrgGUID]).To(HaveLen(1))
+		Expect(roundedPrice(byOrg[consumerOrgGUID][0])).To(Equal(eventio.Money("0.00")))
+		prices := []eventio.Money{}
+		for _, ev := range byOrg[ownerOrgGUID] {
+			prices = append(prices, roundedPrice(ev))
+		}
+		Expect(prices).To(ConsistOf(eventio.Money("3.00"), eventio.Money("-3.00")))
+	})
+})
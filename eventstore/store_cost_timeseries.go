@@ -0,0 +1,258 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+var _ eventio.CostTimeSeriesReader = &EventStore{}
+
+// costTimeSeriesGroupColumns maps a group_by value to the key and display
+// name columns of billable_event_components_by_day. Grouping by a label uses
+// its value as both, with costs without the label in a group with an empty
+// name.
+var costTimeSeriesGroupColumns = map[string][2]string{
+	"":              {"''", "'total'"},
+	"org":           {"org_guid::text", "org_name"},
+	"space":         {"space_guid::text", "space_name"},
+	"plan":          {"plan_guid::text", "plan_name"},
+	"resource_type": {"resource_type", "resource_type"},
+}
+
+// GetCostTimeSeriesRows returns a handle to a dense, zero-filled resultset of
+// costs, instance hours and quantities by unit per step per group read from
+// billable_event_components_by_day. You must call rows.Close when you are done
+// to release the connection.
+func (s *EventStore) GetCostTimeSeriesRows(ctx context.Context, filter eventio.CostTimeSeriesFilter) (eventio.CostTimeSeriesRows, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetCostTimeSeriesRows", err)
+		return nil, err
+	}
+	rows, err := s.getCostTimeSeriesRows(ctx, tx, filter)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *EventStore) getCostTimeSeriesRows(ctx context.Context, tx *sql.Tx, filter eventio.CostTimeSeriesFilter) (eventio.CostTimeSeriesRows, error) {
+	args := []interface{}{
+		filter.RangeStart, // $1
+		filter.RangeStop,  // $2
+		filter.Step,       // $3
+	}
+	filterConditions := []string{}
+	orgPlaceholders := []string{}
+	for _, orgGUID := range filter.OrgGUIDs {
+		args = append(args, orgGUID)
+		orgPlaceholders = append(orgPlaceholders, fmt.Sprintf("($%d::uuid)", len(args))) // $N
+	}
+	if len(orgPlaceholders) > 0 {
+		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
+	}
+	labelConditions, args, err := labelSelectorConditions(filter.LabelSelector, args)
+	if err != nil {
+		return nil, err
+	}
+	filterConditions = append(filterConditions, labelConditions...)
+	filterQuery := ""
+	if len(filterConditions) > 0 {
+		filterQuery = " and " + strings.Join(filterConditions, " and ")
+	}
+
+	groupColumns := costTimeSeriesGroupColumns[filter.GroupBy]
+	if strings.HasPrefix(filter.GroupBy, eventio.LabelGroupByPrefix) {
+		args = append(args, strings.TrimPrefix(filter.GroupBy, eventio.LabelGroupByPrefix))
+		labelColumn := fmt.Sprintf("coalesce(labels->>$%d::text, '')", len(args))
+		groupColumns = [2]string{labelColumn, labelColumn}
+	}
+	groupsQuery := `select ''::text as group_key, 'total'::text as group_name`
+	if filter.GroupBy != "" {
+		// every group with costs up to the end of the range, so groups
+		// without costs in the range are zero-filled rather than left out
+		groupsQuery = fmt.Sprintf(`
+			select
+				(%s)::text as group_key,
+				max((%s)::text) as group_name
+			from
+				billable_event_components_by_day
+			where
+				day < $2::date
+				%s
+			group by
+				group_key
+		`, groupColumns[0], groupColumns[1], filterQuery)
+	}
+
+	startTime := time.Now()
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		with
+		periods as (
+			select generate_series(
+				date_trunc($3, $1::date),
+				$2::date - interval '1 day',
+				('1 ' || $3)::interval
+			)::date as period_start
+		),
+		filtered_costs as (
+			select
+				date_trunc($3, day)::date as period_start,
+				(%s)::text as group_key,
+				(%s)::text as group_name,
+				cost,
+				vat_rate,
+				unit,
+				quantity,
+				-- every component of an event shares its duration, so split the
+				-- instance hours between them to avoid counting them twice
+				coalesce(
+					number_of_nodes * extract(epoch from (upper(day_duration) - lower(day_duration))) / 3600,
+					0
+				) / count(*) over (
+					partition by event_guid, plan_guid, day_duration
+				) as instance_hours
+			from
+				billable_event_components_by_day
+			where
+				day >= $1::date and day < $2::date
+				%s
+		),
+		groups as (
+			%s
+		),
+		costs as (
+			select
+				period_start,
+				group_key,
+				sum(cost) as ex_vat,
+				sum(cost * (1 + vat_rate)) as inc_vat,
+				sum(instance_hours) as instance_hours
+			from
+				filtered_costs
+			group by
+				period_start, group_key
+		),
+		quantities as (
+			select
+				period_start,
+				group_key,
+				json_object_agg(unit, (quantity)::text order by unit) as quantities
+			from (
+				select
+					period_start,
+					group_key,
+					unit,
+					sum(quantity) as quantity
+				from
+					filtered_costs
+				where
+					unit <> ''
+				group by
+					period_start, group_key, unit
+			) q
+			group by
+				period_start, group_key
+		)
+		select
+			to_char(p.period_start, 'YYYY-MM-DD'),
+			g.group_key,
+			g.group_name,
+			coalesce(c.ex_vat, 0)::text,
+			coalesce(c.inc_vat, 0)::text,
+			coalesce(c.instance_hours, 0)::text,
+			q.quantities
+		from
+			periods p
+		cross join
+			groups g
+		left join
+			costs c on c.period_start = p.period_start
+			and c.group_key = g.group_key
+		left join
+			quantities q on q.period_start = p.period_start
+			and q.group_key = g.group_key
+		order by
+			g.group_key, p.period_start
+	`, groupColumns[0], groupColumns[1], filterQuery, groupsQuery), args...)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("getCostTimeSeriesRows", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "getCostTimeSeriesRows", err)
+		s.logger.Error("get-cost-timeseries-rows-query", err, lager.Data{
+			"filter":  filter,
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("getCostTimeSeriesRows", "").Set(elapsed.Seconds())
+	s.logger.Info("get-cost-timeseries-rows-query", lager.Data{
+		"filter":  filter,
+		"elapsed": int64(elapsed),
+	})
+	return &CostTimeSeriesRows{rows: rows, tx: tx, ctx: ctx, fn: "getCostTimeSeriesRows"}, nil
+}
+
+type CostTimeSeriesRows struct {
+	rows *sql.Rows
+	tx   *sql.Tx
+	ctx  context.Context // the context the query was started with
+	fn   string          // the query's name in the cancelled queries metric
+}
+
+// Next moves the row cursor to the next iteration. Returns false if no more
+// rows.
+func (r *CostTimeSeriesRows) Next() bool {
+	return r.rows.Next()
+}
+
+// Err returns any errors that occurred behind the scenes during processing.
+// Call this at the end of your iteration.
+func (r *CostTimeSeriesRows) Err() error {
+	err := r.rows.Err()
+	if r.ctx != nil {
+		observeCancellation(r.ctx, r.fn, err)
+	}
+	return err
+}
+
+// Close ends the query connection. You must call this. So stick it in a defer.
+func (r *CostTimeSeriesRows) Close() error {
+	defer r.tx.Rollback()
+	return r.rows.Close()
+}
+
+// Point returns the current row's CostTimeSeriesPoint. You must call Next
+// _before_ calling this method
+func (r *CostTimeSeriesRows) Point() (*eventio.CostTimeSeriesPoint, error) {
+	var point eventio.CostTimeSeriesPoint
+	var quantities []byte
+	if err := r.rows.Scan(
+		&point.PeriodStart,
+		&point.Group,
+		&point.GroupName,
+		&point.ExVAT,
+		&point.IncVAT,
+		&point.InstanceHours,
+		&quantities,
+	); err != nil {
+		return nil, err
+	}
+	if quantities != nil {
+		if err := json.Unmarshal(quantities, &point.Quantities); err != nil {
+			return nil, err
+		}
+	}
+	return &point, nil
+}
@@ -0,0 +1,182 @@
+package eventstore_test
+
+import (
+	"context"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetCostTimeSeriesRows", func() {
+	var (
+		cfg      eventstore.Config
+		scenario *testenv.TestScenario
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		scenario = testenv.NewTestScenario("2001-01-01T00:00")
+		scenario.AddComputePlan()
+	})
+
+	getPoints := func(ctx context.Context, db *testenv.TempDB, filter eventio.CostTimeSeriesFilter) []eventio.CostTimeSeriesPoint {
+		rows, err := db.Schema.GetCostTimeSeriesRows(ctx, filter)
+		Expect(err).ToNot(HaveOccurred())
+		defer rows.Close()
+		points := []eventio.CostTimeSeriesPoint{}
+		for rows.Next() {
+			point, err := rows.Point()
+			Expect(err).ToNot(HaveOccurred())
+			points = append(points, *point)
+		}
+		Expect(rows.Err()).ToNot(HaveOccurred())
+		return points
+	}
+
+	It("should return a zero-filled daily total when not grouped", func(ctx SpecContext) {
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+24h", State: "STOPPED"},
+		)
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		points := getPoints(ctx, db, eventio.CostTimeSeriesFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-01-05",
+			},
+			Step: "day",
+		})
+
+		Expect(points).To(HaveLen(4))
+		Expect(points[0].PeriodStart).To(Equal("2001-01-01"))
+		Expect(points[0].Group).To(Equal(""))
+		Expect(points[0].GroupName).To(Equal("total"))
+		Expect(points[0].ExVAT).ToNot(Equal(eventio.Money("0")))
+		Expect(points[0].InstanceHours).ToNot(Equal("0"))
+		Expect(points[2]).To(Equal(eventio.CostTimeSeriesPoint{
+			PeriodStart:   "2001-01-03",
+			Group:         "",
+			GroupName:     "total",
+			ExVAT:         "0",
+			IncVAT:        "0",
+			InstanceHours: "0",
+		}))
+		Expect(points[3].PeriodStart).To(Equal("2001-01-04"))
+		Expect(points[3].ExVAT).To(Equal(eventio.Money("0")))
+	})
+
+	It("should return one series per org when grouped by org", func(ctx SpecContext) {
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+24h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org2", "space2", "app2",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+1000h", State: "STOPPED"},
+		)
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		points := getPoints(ctx, db, eventio.CostTimeSeriesFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-03-01",
+			},
+			Step:    "month",
+			GroupBy: "org",
+		})
+
+		Expect(points).To(HaveLen(4))
+		groups := map[string]int{}
+		for _, point := range points {
+			groups[point.Group]++
+		}
+		Expect(groups).To(Equal(map[string]int{
+			scenario.GetOrgGUID("org1"): 2,
+			scenario.GetOrgGUID("org2"): 2,
+		}))
+	})
+
+	It("should only include the requested orgs", func(ctx SpecContext) {
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+24h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org2", "space2", "app2",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+24h", State: "STOPPED"},
+		)
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		points := getPoints(ctx, db, eventio.CostTimeSeriesFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-01-02",
+				OrgGUIDs:   []string{scenario.GetOrgGUID("org2")},
+			},
+			Step:    "day",
+			GroupBy: "org",
+		})
+
+		Expect(points).To(HaveLen(1))
+		Expect(points[0].Group).To(Equal(scenario.GetOrgGUID("org2")))
+	})
+
+	It("should zero-fill groups without costs in the range", func(ctx SpecContext) {
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+24h", State: "STOPPED"},
+		)
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		points := getPoints(ctx, db, eventio.CostTimeSeriesFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-10",
+				RangeStop:  "2001-01-12",
+			},
+			Step:    "day",
+			GroupBy: "org",
+		})
+
+		Expect(points).To(HaveLen(2))
+		for _, point := range points {
+			Expect(point.Group).To(Equal(scenario.GetOrgGUID("org1")))
+			Expect(point.ExVAT).To(Equal(eventio.Money("0")))
+		}
+		Expect(points[0].PeriodStart).To(Equal("2001-01-10"))
+		Expect(points[1].PeriodStart).To(Equal("2001-01-11"))
+	})
+
+	It("should return no points when grouped and there are no groups", func(ctx SpecContext) {
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		points := getPoints(ctx, db, eventio.CostTimeSeriesFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-01-03",
+			},
+			Step:    "day",
+			GroupBy: "org",
+		})
+
+		Expect(points).To(BeEmpty())
+	})
+})
@@ -1,6 +1,7 @@
 package eventstore
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -11,17 +12,23 @@ import (
 var _ eventio.CurrencyRateReader = &EventStore{}
 
 func (s *EventStore) GetCurrencyRates(filter eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error) {
+	return s.GetCurrencyRatesContext(s.ctx, filter)
+}
+
+// GetCurrencyRatesContext is GetCurrencyRates stopping early if ctx is done
+func (s *EventStore) GetCurrencyRatesContext(ctx context.Context, filter eventio.TimeRangeFilter) ([]eventio.CurrencyRate, error) {
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
-	tx, err := s.db.Begin()
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "GetCurrencyRates", err)
 		return nil, err
 	}
 	defer tx.Rollback()
 
 	startTime := time.Now()
-	rows, err := queryJSON(tx, `
+	rows, err := queryJSON(ctx, tx, `
         with
         valid_currency_rates as (
             select
@@ -49,6 +56,7 @@ func (s *EventStore) GetCurrencyRates(filter eventio.TimeRangeFilter) ([]eventio
     `, filter.RangeStart, filter.RangeStop)
 	elapsed := time.Since(startTime)
 	if err != nil {
+		observeCancellation(ctx, "GetCurrencyRates", err)
 		eventStorePerformanceGauge.WithLabelValues("GetCurrencyRates", err.Error()).Set(elapsed.Seconds())
 		s.logger.Error("get-currency-rates-query", err, lager.Data{
 			"filter":  filter,
@@ -76,5 +84,9 @@ func (s *EventStore) GetCurrencyRates(filter eventio.TimeRangeFilter) ([]eventio
 		currencyRates = append(currencyRates, currencyRate)
 
 	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "GetCurrencyRates", err)
+		return nil, err
+	}
 	return currencyRates, nil
 }
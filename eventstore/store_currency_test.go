@@ -92,8 +92,8 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
-		Expect(events[0].Price.ExVAT).To(Equal("1"))
-		Expect(events[0].Price.IncVAT).To(Equal("1.2"))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.Money("1")))
+		Expect(events[0].Price.IncVAT).To(Equal(eventio.Money("1.2")))
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 1), "expected a single event component to be returned")
 	})
 
@@ -160,8 +160,8 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
-		Expect(events[0].Price.ExVAT).To(Equal("80.0"))
-		Expect(events[0].Price.IncVAT).To(Equal("96.00"))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.Money("80.0")))
+		Expect(events[0].Price.IncVAT).To(Equal(eventio.Money("96.00")))
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 1), "expected a single event component to be returned")
 	})
 
@@ -233,14 +233,14 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
-		Expect(events[0].Price.ExVAT).To(Equal("6"))
-		Expect(events[0].Price.IncVAT).To(Equal("7.2"))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.Money("6")))
+		Expect(events[0].Price.IncVAT).To(Equal(eventio.Money("7.2")))
 
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 2), "expected two event components to be returned")
-		Expect(events[0].Price.Details[0].ExVAT).To(Equal("2"))
-		Expect(events[0].Price.Details[0].IncVAT).To(Equal("2.4"))
-		Expect(events[0].Price.Details[1].ExVAT).To(Equal("4"))
-		Expect(events[0].Price.Details[1].IncVAT).To(Equal("4.8"))
+		Expect(events[0].Price.Details[0].ExVAT).To(Equal(eventio.Money("2")))
+		Expect(events[0].Price.Details[0].IncVAT).To(Equal(eventio.Money("2.4")))
+		Expect(events[0].Price.Details[1].ExVAT).To(Equal(eventio.Money("4")))
+		Expect(events[0].Price.Details[1].IncVAT).To(Equal(eventio.Money("4.8")))
 	})
 
 	/*---------------------------------------------------------------------------------------*
@@ -320,11 +320,11 @@ var _ = Describe("Currency Conversion", func() {
 		Expect(len(events)).To(BeNumerically("==", 1), "expected a single event to be returned")
 		Expect(len(events[0].Price.Details)).To(BeNumerically("==", 2), "expected two event components to be returned")
 
-		Expect(events[0].Price.Details[0].ExVAT).To(Equal("1"))
-		Expect(events[0].Price.Details[0].IncVAT).To(Equal("1.2"))
+		Expect(events[0].Price.Details[0].ExVAT).To(Equal(eventio.Money("1")))
+		Expect(events[0].Price.Details[0].IncVAT).To(Equal(eventio.Money("1.2")))
 		Expect(events[0].Price.Details[0].CurrencyCode).To(Equal("GBP"))
-		Expect(events[0].Price.Details[1].ExVAT).To(Equal("200"))
-		Expect(events[0].Price.Details[1].IncVAT).To(Equal("240.0"))
+		Expect(events[0].Price.Details[1].ExVAT).To(Equal(eventio.Money("200")))
+		Expect(events[0].Price.Details[1].IncVAT).To(Equal(eventio.Money("240.0")))
 		Expect(events[0].Price.Details[1].CurrencyCode).To(Equal("GBP"))
 	})
 
@@ -0,0 +1,392 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var _ eventio.DataQualityReader = &EventStore{}
+
+var (
+	dataQualityIssuesFoundCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "paas_billing",
+			Subsystem: "eventstore",
+			Name:      "data_quality_issues_found_total",
+			Help:      "Count of data quality issues found that were not present after the previous refresh",
+		}, []string{"category"})
+
+	dataQualityIssuesGauge = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "paas_billing",
+			Subsystem: "eventstore",
+			Name:      "data_quality_issues",
+			Help:      "Number of data quality issues found by the last refresh",
+		}, []string{"category"})
+)
+
+// dataQualityIssuesQuery finds usage events that create_events.sql drops or
+// mis-pairs, by reading the raw usage events the same way, billable events
+// priced with a plan generated by generateMissingPlans and provider costs
+// that match no service instance event
+var dataQualityIssuesQuery = fmt.Sprintf(`
+	with
+	raw_events as (
+		(
+			select
+				id as event_sequence,
+				guid as event_guid,
+				created_at,
+				(raw_message->>'app_guid')::uuid as resource_guid,
+				'app'::text as resource_type,
+				(raw_message->>'org_guid')::uuid as org_guid,
+				(raw_message->>'space_guid')::uuid as space_guid,
+				null::uuid as plan_guid,
+				raw_message->>'state' as state
+			from
+				app_usage_events
+			where
+				raw_message->>'state' in ('STARTED', 'STOPPED')
+		) union all (
+			select
+				id as event_sequence,
+				guid as event_guid,
+				created_at,
+				(raw_message->>'task_guid')::uuid as resource_guid,
+				'task'::text as resource_type,
+				(raw_message->>'org_guid')::uuid as org_guid,
+				(raw_message->>'space_guid')::uuid as space_guid,
+				null::uuid as plan_guid,
+				raw_message->>'state' as state
+			from
+				app_usage_events
+			where
+				raw_message->>'state' in ('TASK_STARTED', 'TASK_STOPPED')
+		) union all (
+			select
+				id as event_sequence,
+				guid as event_guid,
+				created_at,
+				(raw_message->>'service_instance_guid')::uuid as resource_guid,
+				'service'::text as resource_type,
+				(raw_message->>'org_guid')::uuid as org_guid,
+				(raw_message->>'space_guid')::uuid as space_guid,
+				(raw_message->>'service_plan_guid')::uuid as plan_guid,
+				raw_message->>'state' as state
+			from
+				service_usage_events
+			where
+				raw_message->>'service_instance_type' = 'managed_service_instance'
+		)
+	),
+	raw_event_sequences as (
+		select
+			*,
+			coalesce(bool_or(state in ('STARTED', 'TASK_STARTED', 'CREATED')) over (
+				partition by resource_type, resource_guid
+				order by created_at, event_sequence
+				rows between unbounded preceding and 1 preceding
+			), false) as started_before,
+			lead(created_at) over (
+				partition by resource_type, resource_guid
+				order by created_at, event_sequence
+			) as next_created_at
+		from
+			raw_events
+	)
+	select
+		(case
+			when resource_type = 'service' then '%[1]s'
+			else '%[2]s'
+		end) as category,
+		event_guid,
+		created_at as observed_at,
+		org_guid,
+		space_guid,
+		resource_guid,
+		resource_type,
+		plan_guid,
+		state as detail
+	from
+		raw_event_sequences
+	where
+		state in ('STOPPED', 'TASK_STOPPED', 'DELETED')
+		and not started_before
+	union all
+	select
+		'%[3]s' as category,
+		event_guid,
+		created_at as observed_at,
+		org_guid,
+		space_guid,
+		resource_guid,
+		resource_type,
+		plan_guid,
+		state as detail
+	from
+		raw_event_sequences
+	where
+		state in ('STARTED', 'TASK_STARTED', 'CREATED', 'UPDATED')
+		and next_created_at <= created_at
+	union all
+	select distinct on (b.event_guid)
+		'%[4]s' as category,
+		b.event_guid,
+		lower(b.duration) as observed_at,
+		b.org_guid,
+		b.space_guid,
+		b.resource_guid,
+		b.resource_type,
+		b.plan_guid,
+		b.plan_name as detail
+	from
+		billable_event_components b
+	join
+		pricing_plans pp on pp.plan_guid = b.plan_guid
+		and pp.valid_from = b.plan_valid_from
+	where
+		pp.generated
+	union all
+	select
+		'%[5]s' as category,
+		pc.guid as event_guid,
+		pc.created_at as observed_at,
+		last_ev.org_guid,
+		last_ev.space_guid,
+		(pc.raw_message->>'service_instance_guid')::uuid as resource_guid,
+		'service'::text as resource_type,
+		last_ev.plan_guid,
+		concat_ws(' ', pc.raw_message->>'cost', pc.raw_message->>'currency_code') as detail
+	from
+		provider_cost_events pc
+	left join lateral (
+		-- the service instance's last event, if it ever existed, so the
+		-- issue is reported to its org
+		select
+			ev.org_guid,
+			ev.space_guid,
+			ev.plan_guid
+		from
+			events ev
+		where
+			ev.resource_type = 'service'
+			and ev.resource_guid = (pc.raw_message->>'service_instance_guid')::uuid
+		order by
+			lower(ev.duration) desc
+		limit 1
+	) last_ev on true
+	where
+		not exists (
+			select 1
+			from events ev
+			where ev.resource_type = 'service'
+			and ev.resource_guid = (pc.raw_message->>'service_instance_guid')::uuid
+			and ev.duration && tstzrange(
+				(pc.raw_message->>'usage_start')::timestamptz,
+				(pc.raw_message->>'usage_stop')::timestamptz
+			)
+		)
+`,
+	eventio.DataQualityDeletedWithoutCreate,
+	eventio.DataQualityStoppedWithoutStart,
+	eventio.DataQualityEmptyDuration,
+	eventio.DataQualityUnknownPlan,
+	eventio.DataQualityUnmatchedProviderCost,
+)
+
+// checkDataQuality replaces the data quality issues with those found in the
+// current events and updates the data quality metrics. It is run at the end
+// of every Refresh.
+func (s *EventStore) checkDataQuality(ctx context.Context) error {
+	startTime := time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		create temporary table new_data_quality_issues
+		on commit drop
+		as ` + dataQualityIssuesQuery,
+	); err != nil {
+		return wrapPqError(err, "check-data-quality")
+	}
+
+	newIssues, err := countDataQualityIssues(tx, `
+		select
+			category,
+			count(*)
+		from
+			new_data_quality_issues n
+		where
+			not exists (
+				select 1
+				from data_quality_issues d
+				where d.category = n.category
+				and d.event_guid = n.event_guid
+			)
+		group by
+			category
+	`)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		delete from data_quality_issues;
+		insert into data_quality_issues (
+			category, event_guid, observed_at, org_guid, space_guid,
+			resource_guid, resource_type, plan_guid, detail
+		) (
+			select
+				category, event_guid, observed_at, org_guid, space_guid,
+				resource_guid, resource_type, plan_guid, detail
+			from
+				new_data_quality_issues
+		);
+	`); err != nil {
+		return wrapPqError(err, "check-data-quality")
+	}
+	issues, err := countDataQualityIssues(tx, `
+		select
+			category,
+			count(*)
+		from
+			data_quality_issues
+		group by
+			category
+	`)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, category := range eventio.DataQualityCategories {
+		dataQualityIssuesFoundCounter.WithLabelValues(category).Add(float64(newIssues[category]))
+		dataQualityIssuesGauge.WithLabelValues(category).Set(float64(issues[category]))
+	}
+	elapsed := time.Since(startTime)
+	eventStorePerformanceGauge.WithLabelValues("checkDataQuality", "").Set(elapsed.Seconds())
+	s.logger.Info("check-data-quality", lager.Data{
+		"issues":     issues,
+		"new_issues": newIssues,
+		"elapsed":    int64(elapsed),
+	})
+	return nil
+}
+
+func countDataQualityIssues(tx *sql.Tx, query string) (map[string]int64, error) {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, wrapPqError(err, "count-data-quality-issues")
+	}
+	defer rows.Close()
+	counts := map[string]int64{}
+	for rows.Next() {
+		var category string
+		var count int64
+		if err := rows.Scan(&category, &count); err != nil {
+			return nil, err
+		}
+		counts[category] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetDataQualityIssues returns the data quality issues found by the last
+// Refresh, most recent first
+func (s *EventStore) GetDataQualityIssues(filter eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetDataQualityIssuesContext(ctx, filter)
+}
+
+// GetDataQualityIssuesContext is GetDataQualityIssues stopping early if ctx
+// is done
+func (s *EventStore) GetDataQualityIssuesContext(ctx context.Context, filter eventio.DataQualityFilter) ([]eventio.DataQualityIssue, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetDataQualityIssues", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{}
+	conditions := []string{}
+	if len(filter.OrgGUIDs) > 0 {
+		args = append(args, pq.Array(filter.OrgGUIDs))
+		conditions = append(conditions, fmt.Sprintf("org_guid = any($%d::uuid[])", len(args)))
+	}
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		conditions = append(conditions, fmt.Sprintf("category = $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, fmt.Sprintf(`
+		select
+			category,
+			event_guid,
+			observed_at,
+			coalesce(org_guid::text, '') as org_guid,
+			coalesce(space_guid::text, '') as space_guid,
+			coalesce(resource_guid::text, '') as resource_guid,
+			resource_type,
+			coalesce(plan_guid::text, '') as plan_guid,
+			detail
+		from
+			data_quality_issues
+		%s
+		order by
+			observed_at desc, category, event_guid
+	`, where), args...)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("getDataQualityIssues", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "getDataQualityIssues", err)
+		s.logger.Error("get-data-quality-issues-query", err, lager.Data{
+			"filter":  filter,
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("getDataQualityIssues", "").Set(elapsed.Seconds())
+	defer rows.Close()
+
+	issues := []eventio.DataQualityIssue{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var issue eventio.DataQualityIssue
+		if err := json.Unmarshal(b, &issue); err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "getDataQualityIssues", err)
+		return nil, err
+	}
+	return issues, nil
+}
@@ -0,0 +1,95 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetDataQualityIssues", func() {
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.IgnoreMissingPlans = true
+	})
+
+	appEvent := func(guid string, appGUID string, createdAt time.Time, state string) eventio.RawEvent {
+		return eventio.RawEvent{
+			GUID:       guid,
+			Kind:       "app",
+			CreatedAt:  createdAt,
+			RawMessage: json.RawMessage(`{"state": "` + state + `", "app_guid": "` + appGUID + `", "app_name": "APP", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": 1, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024}`),
+		}
+	}
+
+	It("should record events that were dropped, mis-paired or priced with a generated plan", func(ctx SpecContext) {
+		stoppedOnly := appEvent("a0000000-f485-48e1-87d0-98b7b8b66dfa", "a85e98f0-6d1b-4f45-9368-ea58263165a0", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "STOPPED")
+		emptyStart := appEvent("b0000000-f485-48e1-87d0-98b7b8b66dfa", "b85e98f0-6d1b-4f45-9368-ea58263165a0", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "STARTED")
+		emptyStop := appEvent("b1000000-f485-48e1-87d0-98b7b8b66dfa", "b85e98f0-6d1b-4f45-9368-ea58263165a0", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "STOPPED")
+		unpricedStart := appEvent("c0000000-f485-48e1-87d0-98b7b8b66dfa", "c85e98f0-6d1b-4f45-9368-ea58263165a0", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "STARTED")
+		unpricedStop := appEvent("c1000000-f485-48e1-87d0-98b7b8b66dfa", "c85e98f0-6d1b-4f45-9368-ea58263165a0", time.Date(2001, 1, 1, 1, 0, 0, 0, time.UTC), "STOPPED")
+		deletedOnly := eventio.RawEvent{
+			GUID:       "d0000000-f485-48e1-87d0-98b7b8b66dfa",
+			Kind:       "service",
+			CreatedAt:  time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+			RawMessage: json.RawMessage(`{"state": "DELETED", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d", "space_name": "sandbox", "service_guid": "efadb775-58c4-4e17-8087-6d0f4febc489", "service_label": "postgres", "service_plan_guid": "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5", "service_plan_name": "Free", "service_instance_guid": "f3f98365-6a95-4bbd-ab8f-527a7957a41f", "service_instance_name": "DB1", "service_instance_type": "managed_service_instance"}`),
+		}
+
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			stoppedOnly, emptyStart, emptyStop, unpricedStart, unpricedStop, deletedOnly,
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+		Expect(store.Refresh()).To(Succeed(), "refreshing again should replace the issues")
+
+		issues, err := store.GetDataQualityIssues(eventio.DataQualityFilter{})
+		Expect(err).ToNot(HaveOccurred())
+		found := map[string]string{}
+		for _, issue := range issues {
+			found[issue.EventGUID] = issue.Category
+		}
+		Expect(found).To(Equal(map[string]string{
+			stoppedOnly.GUID:   eventio.DataQualityStoppedWithoutStart,
+			emptyStart.GUID:    eventio.DataQualityEmptyDuration,
+			unpricedStart.GUID: eventio.DataQualityUnknownPlan,
+			deletedOnly.GUID:   eventio.DataQualityDeletedWithoutCreate,
+		}))
+
+		issues, err = store.GetDataQualityIssues(eventio.DataQualityFilter{
+			OrgGUIDs: []string{"51ba75ef-edc0-47ad-a633-a8f6e8770944"},
+			Category: eventio.DataQualityStoppedWithoutStart,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issues).To(Equal([]eventio.DataQualityIssue{{
+			Category:     eventio.DataQualityStoppedWithoutStart,
+			EventGUID:    stoppedOnly.GUID,
+			ObservedAt:   "2001-01-01T00:00:00+00:00",
+			OrgGUID:      "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+			SpaceGUID:    "276f4886-ac40-492d-a8cd-b2646637ba76",
+			ResourceGUID: "a85e98f0-6d1b-4f45-9368-ea58263165a0",
+			ResourceType: "app",
+			Detail:       "STOPPED",
+		}}))
+
+		issues, err = store.GetDataQualityIssues(eventio.DataQualityFilter{
+			OrgGUIDs: []string{"00000000-0000-0000-0000-000000000000"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issues).To(BeEmpty())
+	})
+})
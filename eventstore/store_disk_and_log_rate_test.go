@@ -0,0 +1,147 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Disk quota and log rate limit", func() {
+
+	const (
+		orgGUID   = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		appGUID   = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func(ctx SpecContext) {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "disk",
+					Formula:      "$number_of_nodes * ($time_in_seconds / 3600) * ($disk_in_mb / 1024) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+				{
+					Name:         "logs",
+					Formula:      "$number_of_nodes * ($time_in_seconds / 3600) * ($log_rate_limit / 1024) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("app_processes",
+			testenv.Row{
+				"guid":           appGUID,
+				"valid_from":     "2001-01-01T00:00:00Z",
+				"app_guid":       appGUID,
+				"process_type":   "web",
+				"disk_in_mb":     2048,
+				"log_rate_limit": 1024,
+				"created_at":     "2001-01-01T00:00:00Z",
+				"updated_at":     "2001-01-01T00:00:00Z",
+			},
+			testenv.Row{
+				"guid":           appGUID,
+				"valid_from":     "2001-01-01T02:00:00Z",
+				"app_guid":       appGUID,
+				"process_type":   "web",
+				"disk_in_mb":     1024,
+				"log_rate_limit": -1,
+				"created_at":     "2001-01-01T00:00:00Z",
+				"updated_at":     "2001-01-01T02:00:00Z",
+			},
+		)).To(Succeed())
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "process_type": "web", "instance_count": 2, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "process_type": "web", "instance_count": 2, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "0c9e5fd1-0f35-4c8c-9f0a-4a59c1e3c6b1",
+				"created_at":  "2001-01-01T02:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "process_type": "web", "instance_count": 2, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "6a1f0d2e-3b4c-4d5e-8f6a-7b8c9d0e1f2a",
+				"created_at":  "2001-01-01T03:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "process_type": "web", "instance_count": 2, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	})
+
+	roundedPrice := func(components []eventio.PriceComponent, name string) eventio.Money {
+		for _, component := range components {
+			if component.Name == name {
+				price, err := component.ExVAT.Round(2)
+				Expect(err).ToNot(HaveOccurred())
+				return price
+			}
+		}
+		Fail("no component named " + name)
+		return ""
+	}
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should give app events the disk quota and log rate limit of their process", func() {
+		events, err := db.Schema.GetUsageEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].DiskInMB).To(Equal(int64(2048)))
+		Expect(events[0].LogRateLimit).To(Equal(int64(1024)))
+		Expect(events[1].DiskInMB).To(Equal(int64(1024)))
+		Expect(events[1].LogRateLimit).To(Equal(int64(0)), "an unlimited log rate should be 0")
+	})
+
+	It("should price components using the disk quota and log rate limit", func() {
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		sort.Slice(events, func(i, j int) bool {
+			return events[i].EventStart < events[j].EventStart
+		})
+
+		Expect(events[0].DiskInMB).To(Equal(int64(2048)))
+		Expect(roundedPrice(events[0].Price.Details, "disk")).To(Equal(eventio.Money("0.04")))
+		Expect(roundedPrice(events[0].Price.Details, "logs")).To(Equal(eventio.Money("0.02")))
+
+		Expect(events[1].DiskInMB).To(Equal(int64(1024)))
+		Expect(roundedPrice(events[1].Price.Details, "disk")).To(Equal(eventio.Money("0.02")))
+		Expect(roundedPrice(events[1].Price.Details, "logs")).To(Equal(eventio.Money("0.00")))
+	})
+})
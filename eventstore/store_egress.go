@@ -0,0 +1,135 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+var _ eventio.EgressUsageWriter = &EventStore{}
+
+// GetEgressOffset returns how many bytes of the access log identified by
+// source have been ingested, or 0 for a log that has not been seen before
+func (s *EventStore) GetEgressOffset(source string) (int64, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	var offset int64
+	err := s.db.QueryRowContext(ctx, `
+		select ingested_bytes from egress_sources where source = $1
+	`, source).Scan(&offset)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		observeCancellation(ctx, "GetEgressOffset", err)
+		return 0, err
+	}
+	return offset, nil
+}
+
+// StoreEgressBatch adds the usage of a batch to the usage of its log and
+// records that the log has been ingested up to the end of the batch. Nothing
+// is stored if the batch does not start where the log was last ingested up
+// to, eg when the same log is being ingested twice at once, so no bytes are
+// counted twice.
+func (s *EventStore) StoreEgressBatch(batch eventio.EgressBatch) error {
+	if err := batch.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultStoreTimeout)
+	defer cancel()
+	startTime := time.Now()
+	err := s.storeEgressBatch(ctx, batch)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("StoreEgressBatch", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "StoreEgressBatch", err)
+		s.logger.Error("store-egress-batch", err, lager.Data{
+			"source":  batch.Source,
+			"from":    batch.From,
+			"to":      batch.To,
+			"count":   len(batch.Usage),
+			"elapsed": int64(elapsed),
+		})
+		return err
+	}
+	eventStorePerformanceGauge.WithLabelValues("StoreEgressBatch", "").Set(elapsed.Seconds())
+	s.logger.Info("store-egress-batch", lager.Data{
+		"source":  batch.Source,
+		"from":    batch.From,
+		"to":      batch.To,
+		"count":   len(batch.Usage),
+		"elapsed": int64(elapsed),
+	})
+	return nil
+}
+
+func (s *EventStore) storeEgressBatch(ctx context.Context, batch eventio.EgressBatch) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		insert into egress_sources (
+			source, ingested_bytes
+		) values (
+			$1, 0
+		) on conflict (source) do nothing
+	`, batch.Source); err != nil {
+		return wrapPqError(err, "invalid egress source")
+	}
+	var offset int64
+	if err := tx.QueryRow(`
+		select ingested_bytes from egress_sources where source = $1 for update
+	`, batch.Source).Scan(&offset); err != nil {
+		return err
+	}
+	if offset != batch.From {
+		return fmt.Errorf("egress log %s has been ingested up to byte %d, not %d, since it was read", batch.Source, offset, batch.From)
+	}
+
+	appGUIDs := make([]string, 0, len(batch.Usage))
+	hours := make([]string, 0, len(batch.Usage))
+	bytes := make([]int64, 0, len(batch.Usage))
+	requests := make([]int64, 0, len(batch.Usage))
+	for _, u := range batch.Usage {
+		appGUIDs = append(appGUIDs, u.AppGUID)
+		hours = append(hours, u.Hour)
+		bytes = append(bytes, u.Bytes)
+		requests = append(requests, u.Requests)
+	}
+	if _, err := tx.Exec(`
+		insert into egress_usage (
+			source, app_guid, hour, bytes, requests
+		) select
+			$1, app_guid, hour, sum(bytes), sum(requests)
+		from
+			unnest($2::uuid[], $3::timestamptz[], $4::bigint[], $5::bigint[]) as u(app_guid, hour, bytes, requests)
+		group by
+			app_guid, hour
+		on conflict (source, app_guid, hour) do update set
+			bytes = egress_usage.bytes + excluded.bytes,
+			requests = egress_usage.requests + excluded.requests
+	`, batch.Source, pq.Array(appGUIDs), pq.Array(hours), pq.Array(bytes), pq.Array(requests)); err != nil {
+		return wrapPqError(err, "invalid egress usage")
+	}
+
+	if _, err := tx.Exec(`
+		update egress_sources set
+			ingested_bytes = $2,
+			updated_at = now()
+		where
+			source = $1
+	`, batch.Source, batch.To); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,201 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Egress", func() {
+
+	const (
+		orgGUID   = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		appGUID   = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+		gib       = 1024 * 1024 * 1024
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.BandwidthPlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "bandwidth",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:            "egress",
+					Formula:         "$bytes / 1073741824 * 0.05",
+					CurrencyCode:    "GBP",
+					VATCode:         "Standard",
+					Unit:            "GiB",
+					QuantityFormula: "$bytes / 1073741824",
+				},
+			},
+		})
+	})
+
+	openWithApp := func(ctx SpecContext, cfg eventstore.Config) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+	}
+
+	egressEvents := func() []eventio.BillableEvent {
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		egress := []eventio.BillableEvent{}
+		for _, ev := range events {
+			if ev.ResourceType == "egress" {
+				egress = append(egress, ev)
+			}
+		}
+		return egress
+	}
+
+	const source = "0123456789abcdef0123456789abcdef"
+
+	usage := func(hour string, bytes int64) eventio.EgressUsage {
+		return eventio.EgressUsage{
+			AppGUID:  appGUID,
+			Hour:     hour,
+			Bytes:    bytes,
+			Requests: 1,
+		}
+	}
+
+	roundedPrice := func(ev eventio.BillableEvent) eventio.Money {
+		price, err := ev.Price.ExVAT.Round(2)
+		Expect(err).ToNot(HaveOccurred())
+		return price
+	}
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should bill the bytes an app sent each hour with the bandwidth plan", func(ctx SpecContext) {
+		openWithApp(ctx, cfg)
+		Expect(db.Schema.StoreEgressBatch(eventio.EgressBatch{
+			Source: source,
+			From:   0,
+			To:     300,
+			Usage: []eventio.EgressUsage{
+				usage("2001-01-01T10:00:00Z", gib),
+				usage("2001-01-01T10:00:00Z", gib),
+				usage("2001-01-01T11:00:00Z", gib),
+			},
+		})).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events := egressEvents()
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].EventStart).To(Equal("2001-01-01T10:00:00+00:00"))
+		Expect(events[0].EventStop).To(Equal("2001-01-01T11:00:00+00:00"))
+		Expect(events[0].ResourceGUID).To(Equal(appGUID))
+		Expect(events[0].ResourceName).To(Equal("APP1"))
+		Expect(events[0].OrgGUID).To(Equal(orgGUID))
+		Expect(events[0].SpaceGUID).To(Equal(spaceGUID))
+		Expect(events[0].PlanGUID).To(Equal(eventstore.BandwidthPlanGUID))
+		Expect(roundedPrice(events[0])).To(Equal(eventio.Money("0.10")))
+		Expect(events[0].Price.Details[0].Unit).To(Equal("GiB"))
+		Expect(eventio.Money(events[0].Price.Details[0].Quantity).Round(2)).To(Equal(eventio.Money("2.00")))
+		Expect(roundedPrice(events[1])).To(Equal(eventio.Money("0.05")))
+	})
+
+	It("should add the usage of each batch of a log once", func(ctx SpecContext) {
+		openWithApp(ctx, cfg)
+		offset, err := db.Schema.GetEgressOffset(source)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offset).To(Equal(int64(0)))
+
+		first := eventio.EgressBatch{
+			Source: source,
+			From:   0,
+			To:     100,
+			Usage:  []eventio.EgressUsage{usage("2001-01-01T10:00:00Z", 2*gib)},
+		}
+		Expect(db.Schema.StoreEgressBatch(first)).To(Succeed())
+		offset, err = db.Schema.GetEgressOffset(source)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offset).To(Equal(int64(100)))
+
+		Expect(db.Schema.StoreEgressBatch(first)).To(MatchError(ContainSubstring("has been ingested up to byte 100, not 0")))
+
+		Expect(db.Schema.StoreEgressBatch(eventio.EgressBatch{
+			Source: source,
+			From:   100,
+			To:     150,
+			Usage:  []eventio.EgressUsage{usage("2001-01-01T10:00:00Z", gib)},
+		})).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events := egressEvents()
+		Expect(events).To(HaveLen(1))
+		Expect(roundedPrice(events[0])).To(Equal(eventio.Money("0.15")))
+	})
+
+	It("should add up the usage of different logs", func(ctx SpecContext) {
+		openWithApp(ctx, cfg)
+		for _, s := range []string{source, "fedcba9876543210fedcba9876543210"} {
+			Expect(db.Schema.StoreEgressBatch(eventio.EgressBatch{
+				Source: s,
+				From:   0,
+				To:     100,
+				Usage:  []eventio.EgressUsage{usage("2001-01-01T10:00:00Z", gib)},
+			})).To(Succeed())
+		}
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events := egressEvents()
+		Expect(events).To(HaveLen(1))
+		Expect(roundedPrice(events[0])).To(Equal(eventio.Money("0.10")))
+	})
+
+	It("should not bill egress without a bandwidth plan", func(ctx SpecContext) {
+		cfg = testenv.BasicConfig
+		openWithApp(ctx, cfg)
+		Expect(db.Schema.StoreEgressBatch(eventio.EgressBatch{
+			Source: source,
+			From:   0,
+			To:     100,
+			Usage:  []eventio.EgressUsage{usage("2001-01-01T10:00:00Z", gib)},
+		})).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		Expect(egressEvents()).To(BeEmpty())
+	})
+
+	It("should reject invalid usage", func(ctx SpecContext) {
+		openWithApp(ctx, cfg)
+		err := db.Schema.StoreEgressBatch(eventio.EgressBatch{
+			Source: source,
+			From:   0,
+			To:     100,
+			Usage:  []eventio.EgressUsage{usage("2001-01-01T10:30:00Z", gib)},
+		})
+		Expect(err).To(MatchError(ContainSubstring("start of an hour")))
+		offset, err := db.Schema.GetEgressOffset(source)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(offset).To(Equal(int64(0)))
+	})
+})
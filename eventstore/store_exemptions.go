@@ -0,0 +1,239 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+var _ eventio.ExemptionReader = &EventStore{}
+var _ eventio.ExemptionWriter = &EventStore{}
+
+// fullyExemptEventsQuery selects the events that exemptions cover for their
+// whole duration, which are priced at zero and need no pricing plan
+const fullyExemptEventsQuery = `
+	select
+		event_guid
+	from
+		event_exemption_periods
+	group by
+		event_guid
+	having
+		bool_and(exemption_guid is not null)
+`
+
+// exemptionsQuery lists the exemptions with the usage they covered when
+// events were last refreshed
+const exemptionsQuery = `
+	with
+	exempt_usage as (
+		select
+			exemption_guid,
+			count(distinct event_guid) as exempt_events,
+			sum(to_seconds(duration)) / 3600 as exempt_hours
+		from
+			event_exemption_periods
+		where
+			exemption_guid is not null
+		group by
+			exemption_guid
+	)
+	select
+		x.guid,
+		x.org_guid,
+		coalesce(x.space_guid::text, '') as space_guid,
+		x.valid_from,
+		x.valid_to,
+		x.reason,
+		x.source,
+		x.created_at,
+		coalesce(eu.exempt_events, 0) as exempt_events,
+		coalesce(eu.exempt_hours, 0)::text as exempt_hours
+	from
+		exemptions x
+	left join
+		exempt_usage eu on eu.exemption_guid = x.guid
+`
+
+// initExemptions replaces the exemptions from the config. Exemptions added
+// with AddExemption are kept. An exemption in the config without a guid is
+// given one derived from its fields, so it keeps it while it is unchanged.
+func (s *EventStore) initExemptions(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM exemptions WHERE source = $1", eventio.ExemptionSourceConfig); err != nil {
+		return wrapPqError(err, "error deleting existing exemptions")
+	}
+	for _, exemption := range s.cfg.Exemptions {
+		if err := exemption.Validate(); err != nil {
+			return err
+		}
+		s.logger.Info("configuring-exemption", lager.Data{
+			"org_guid":   exemption.OrgGUID,
+			"space_guid": exemption.SpaceGUID,
+			"valid_from": exemption.ValidFrom,
+			"valid_to":   exemption.ValidTo,
+		})
+		_, err := tx.Exec(`insert into exemptions (
+			guid, org_guid, space_guid,
+			valid_from, valid_to, reason, source
+		) values (
+			coalesce(
+				nullif($1::text, '')::uuid,
+				uuid_generate_v5(uuid_ns_url(), concat_ws('/', $2::text, $3::text, $4::text, $5::text, $6::text))
+			),
+			$2::text::uuid, nullif($3::text, '')::uuid,
+			$4::text::timestamptz, nullif($5::text, '')::timestamptz, $6::text, $7
+		)`, exemption.GUID, exemption.OrgGUID, exemption.SpaceGUID,
+			exemption.ValidFrom, exemption.ValidTo, exemption.Reason, eventio.ExemptionSourceConfig,
+		)
+		if err != nil {
+			return wrapPqError(err, "invalid exemption")
+		}
+	}
+	return nil
+}
+
+// GetExemptions returns every exemption, most recently valid first
+func (s *EventStore) GetExemptions() ([]eventio.Exemption, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetExemptionsContext(ctx)
+}
+
+// GetExemptionsContext is GetExemptions stopping early if ctx is done
+func (s *EventStore) GetExemptionsContext(ctx context.Context) ([]eventio.Exemption, error) {
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetExemptions", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	startTime := time.Now()
+	exemptions, err := queryExemptions(ctx, tx, exemptionsQuery+`
+		order by
+			x.valid_from desc, x.org_guid, x.guid
+	`)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("getExemptions", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "getExemptions", err)
+		s.logger.Error("get-exemptions-query", err, lager.Data{
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("getExemptions", "").Set(elapsed.Seconds())
+	return exemptions, nil
+}
+
+// AddExemption stores a new exemption, which applies from the next Refresh
+func (s *EventStore) AddExemption(exemption eventio.Exemption) (*eventio.Exemption, error) {
+	if err := exemption.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var guid string
+	if err := tx.QueryRow(`
+		insert into exemptions (
+			guid, org_guid, space_guid,
+			valid_from, valid_to, reason, source
+		) values (
+			uuid_generate_v4(), $1, nullif($2, '')::uuid,
+			$3, nullif($4, '')::timestamptz, $5, $6
+		)
+		returning guid
+	`, exemption.OrgGUID, exemption.SpaceGUID,
+		exemption.ValidFrom, exemption.ValidTo, exemption.Reason, eventio.ExemptionSourceAPI,
+	).Scan(&guid); err != nil {
+		return nil, wrapPqError(err, "invalid exemption")
+	}
+	exemptions, err := queryExemptions(ctx, tx, exemptionsQuery+`
+		where
+			x.guid = $1
+	`, guid)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	s.logger.Info("add-exemption", lager.Data{
+		"guid":       guid,
+		"org_guid":   exemption.OrgGUID,
+		"space_guid": exemption.SpaceGUID,
+		"valid_from": exemption.ValidFrom,
+		"valid_to":   exemption.ValidTo,
+		"reason":     exemption.Reason,
+	})
+	return &exemptions[0], nil
+}
+
+// RemoveExemption deletes an exemption added with AddExemption. The usage it
+// covered is charged for from the next Refresh.
+func (s *EventStore) RemoveExemption(guid string) (bool, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var source string
+	err = tx.QueryRow(`
+		delete from
+			exemptions
+		where
+			guid::text = lower($1)
+		returning
+			source
+	`, guid).Scan(&source)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, wrapPqError(err, "remove-exemption")
+	}
+	if source == eventio.ExemptionSourceConfig {
+		return true, eventio.ErrExemptionInConfig
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	s.logger.Info("remove-exemption", lager.Data{
+		"guid": guid,
+	})
+	return true, nil
+}
+
+func queryExemptions(ctx context.Context, tx *sql.Tx, q string, args ...interface{}) ([]eventio.Exemption, error) {
+	rows, err := queryJSON(ctx, tx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	exemptions := []eventio.Exemption{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var exemption eventio.Exemption
+		if err := json.Unmarshal(b, &exemption); err != nil {
+			return nil, err
+		}
+		exemptions = append(exemptions, exemption)
+	}
+	return exemptions, rows.Err()
+}
@@ -0,0 +1,179 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Exemptions", func() {
+
+	const (
+		orgGUID   = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "ceil($time_in_seconds/3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+	})
+
+	/*-----------------------------------------------------------------------------------*
+	       23:00       00:00       01:00                                                  .
+	         |           |           |                                                    .
+	 .   .   [==========app1=========]   .   .   .   .   .   .   .   .   .   .   .   .   .
+	 .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .   .
+	                     <=====================EXEMPTION=====================================>
+	*-----------------------------------------------------------------------------------*/
+	It("should price the exempt part of an event at zero with an exempt component", func(ctx SpecContext) {
+		cfg.Exemptions = []eventio.Exemption{{
+			OrgGUID:   orgGUID,
+			SpaceGUID: spaceGUID,
+			ValidFrom: "2001-01-02",
+			Reason:    "smoke tests",
+		}}
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T23:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "SMOKE-1", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-02T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "SMOKE-1", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.Money("0.01")))
+		Expect(events[0].Price.Details).To(HaveLen(2))
+		Expect(events[0].Price.Details[0].Name).To(Equal("compute"))
+		Expect(events[0].Price.Details[0].Stop).To(Equal("2001-01-02T00:00:00+00:00"))
+		Expect(events[0].Price.Details[1]).To(Equal(eventio.PriceComponent{
+			Name:         eventio.ExemptComponentName,
+			PlanName:     "app",
+			Start:        "2001-01-02T00:00:00+00:00",
+			Stop:         "2001-01-02T01:00:00+00:00",
+			VatRate:      "0",
+			VatCode:      "Zero",
+			CurrencyCode: "GBP",
+			IncVAT:       "0",
+			ExVAT:        "0",
+		}))
+
+		exemptions, err := db.Schema.GetExemptions()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exemptions).To(HaveLen(1))
+		Expect(exemptions[0].Source).To(Equal(eventio.ExemptionSourceConfig))
+		Expect(exemptions[0].SpaceGUID).To(Equal(spaceGUID))
+		Expect(exemptions[0].ExemptEvents).To(Equal(int64(1)))
+		Expect(eventio.Money(exemptions[0].ExemptHours).Float64()).To(Equal(1.0))
+
+		found, err := db.Schema.RemoveExemption(exemptions[0].GUID)
+		Expect(err).To(MatchError(eventio.ErrExemptionInConfig))
+		Expect(found).To(BeTrue())
+	})
+
+	It("should not need a pricing plan for usage that is exempt for its whole duration", func(ctx SpecContext) {
+		cfg.Exemptions = []eventio.Exemption{{
+			OrgGUID:   orgGUID,
+			ValidFrom: "2001-01-01",
+			Reason:    "acceptance tests",
+		}}
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("service_usage_events",
+			testenv.Row{
+				"guid":        "c497eb13-f48a-4859-be53-5569f302b516",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "CREATED", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "ACC-1", "service_guid": "efadb775-58c4-4e17-8087-6d0f4febc489", "service_label": "postgres", "service_plan_guid": "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5", "service_plan_name": "UNPRICED", "service_instance_guid": "f3f98365-6a95-4bbd-ab8f-527a7957a41f", "service_instance_name": "DB1", "service_instance_type": "managed_service_instance"}`),
+			},
+			testenv.Row{
+				"guid":        "dd52b4f4-9e33-4504-8fca-fd9e33af11a6",
+				"created_at":  "2001-01-01T02:00Z",
+				"raw_message": json.RawMessage(`{"state": "DELETED", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "ACC-1", "service_guid": "efadb775-58c4-4e17-8087-6d0f4febc489", "service_label": "postgres", "service_plan_guid": "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5", "service_plan_name": "UNPRICED", "service_instance_guid": "f3f98365-6a95-4bbd-ab8f-527a7957a41f", "service_instance_name": "DB1", "service_instance_type": "managed_service_instance"}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].ResourceName).To(Equal("DB1"))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.Money("0")))
+		Expect(events[0].Price.Details).To(HaveLen(1))
+		Expect(events[0].Price.Details[0].Name).To(Equal(eventio.ExemptComponentName))
+
+		plans, err := db.Schema.GetUnpricedPlans()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(BeEmpty())
+	})
+
+	It("should add and remove exemptions", func(ctx SpecContext) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		added, err := db.Schema.AddExemption(eventio.Exemption{
+			OrgGUID:   orgGUID,
+			ValidFrom: "2001-01-01",
+			ValidTo:   "2001-02-01",
+			Reason:    "performance tests",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(added.GUID).ToNot(BeEmpty())
+		Expect(added.SpaceGUID).To(BeEmpty())
+		Expect(added.ValidFrom).To(Equal("2001-01-01T00:00:00+00:00"))
+		Expect(added.ValidTo).To(Equal("2001-02-01T00:00:00+00:00"))
+		Expect(added.Source).To(Equal(eventio.ExemptionSourceAPI))
+
+		exemptions, err := db.Schema.GetExemptions()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exemptions).To(Equal([]eventio.Exemption{*added}))
+
+		Expect(db.Schema.RemoveExemption(added.GUID)).To(BeTrue())
+		Expect(db.Schema.RemoveExemption(added.GUID)).To(BeFalse())
+
+		exemptions, err = db.Schema.GetExemptions()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exemptions).To(BeEmpty())
+	})
+})
@@ -0,0 +1,350 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+var _ eventio.FootprintReader = &EventStore{}
+
+// initFootprintPlans replaces the footprint plans with those from the config
+func (s *EventStore) initFootprintPlans(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM footprint_plans"); err != nil {
+		return wrapPqError(err, "error deleting existing footprint_plans")
+	}
+	for _, fp := range s.cfg.FootprintPlans {
+		if err := fp.Validate(); err != nil {
+			return err
+		}
+		s.logger.Info("configuring-footprint-plan", lager.Data{
+			"plan_guid":  fp.PlanGUID,
+			"name":       fp.Name,
+			"valid_from": fp.ValidFrom,
+		})
+		_, err := tx.Exec(`insert into footprint_plans (
+			plan_guid, valid_from, name,
+			energy_formula, carbon_formula
+		) values (
+			$1, $2, $3,
+			$4, coalesce(nullif($5, ''), '0')
+		)`, fp.PlanGUID, fp.ValidFrom, fp.Name,
+			fp.EnergyFormula, fp.CarbonFormula,
+		)
+		if err != nil {
+			return wrapPqError(err, "invalid footprint plan")
+		}
+	}
+	return nil
+}
+
+// initGridIntensities replaces the grid intensities with those from the
+// config, including those read from its grid intensity files
+func (s *EventStore) initGridIntensities(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM grid_intensities"); err != nil {
+		return wrapPqError(err, "error deleting existing grid_intensities")
+	}
+	for _, gi := range s.cfg.GridIntensities {
+		if err := gi.Validate(); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`insert into grid_intensities (
+			valid_from, gco2e_per_kwh
+		) values (
+			$1, $2
+		)`, gi.ValidFrom, gi.GCO2ePerKWh)
+		if err != nil {
+			return wrapPqError(err, "invalid grid intensity")
+		}
+	}
+	s.logger.Info("configuring-grid-intensities", lager.Data{
+		"count": len(s.cfg.GridIntensities),
+	})
+	return nil
+}
+
+// loadGridIntensityFiles appends the grid intensities in cfg's grid
+// intensity files to its grid intensities. Relative paths are relative to
+// dir.
+func loadGridIntensityFiles(cfg *Config, dir string) error {
+	for _, filename := range cfg.GridIntensityFiles {
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		intensities, err := eventio.ReadGridIntensities(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+		cfg.GridIntensities = append(cfg.GridIntensities, intensities...)
+	}
+	return nil
+}
+
+// GetFootprintPlans returns the footprint plans valid during the filter range
+func (s *EventStore) GetFootprintPlans(ctx context.Context, filter eventio.TimeRangeFilter) ([]eventio.FootprintPlan, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetFootprintPlans", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	rows, err := queryJSON(ctx, tx, `
+		with
+		valid_footprint_plans as (
+			select
+				*,
+				tstzrange(valid_from, lead(valid_from, 1, 'infinity') over (
+					partition by plan_guid order by valid_from rows between current row and 1 following
+				)) as valid_for
+			from
+				footprint_plans
+		)
+		select
+			plan_guid,
+			valid_from,
+			name,
+			energy_formula,
+			carbon_formula
+		from
+			valid_footprint_plans
+		where
+			valid_for && tstzrange($1, $2)
+		order by
+			valid_from, plan_guid
+	`, filter.RangeStart, filter.RangeStop)
+	if err != nil {
+		observeCancellation(ctx, "GetFootprintPlans", err)
+		return nil, err
+	}
+	defer rows.Close()
+	plans := []eventio.FootprintPlan{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var plan eventio.FootprintPlan
+		if err := json.Unmarshal(b, &plan); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "GetFootprintPlans", err)
+		return nil, err
+	}
+	return plans, nil
+}
+
+// GetFootprintEventRows returns a handle to a resultset of the estimated
+// footprint of each event with a footprint plan during the filter range.
+// You must call rows.Close when you are done to release the connection.
+func (s *EventStore) GetFootprintEventRows(ctx context.Context, filter eventio.EventFilter) (eventio.FootprintEventRows, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetFootprintEventRows", err)
+		return nil, err
+	}
+	rows, err := s.getFootprintEventRows(ctx, tx, filter)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (s *EventStore) getFootprintEventRows(ctx context.Context, tx *sql.Tx, filter eventio.EventFilter) (eventio.FootprintEventRows, error) {
+	args := []interface{}{
+		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop), // $1
+	}
+	filterConditions := []string{}
+	orgPlaceholders := []string{}
+	for _, orgGUID := range filter.OrgGUIDs {
+		args = append(args, orgGUID)
+		orgPlaceholders = append(orgPlaceholders, fmt.Sprintf("($%d::uuid)", len(args))) // $N
+	}
+	if len(orgPlaceholders) > 0 {
+		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
+	}
+	labelConditions, args, err := labelSelectorConditions(filter.LabelSelector, args)
+	if err != nil {
+		return nil, err
+	}
+	filterConditions = append(filterConditions, labelConditions...)
+	filterQuery := ""
+	if len(filterConditions) > 0 {
+		filterQuery = " and " + strings.Join(filterConditions, " and ")
+	}
+
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, fmt.Sprintf(`
+		with
+		filtered_range as (
+			select $1::tstzrange as filtered_range
+		),
+		footprint_periods as (
+			select
+				ev.event_guid,
+				ev.resource_guid,
+				ev.resource_name,
+				ev.resource_type,
+				ev.org_guid,
+				ev.org_name,
+				ev.space_guid,
+				ev.space_name,
+				ev.plan_guid,
+				p.duration * filtered_range as duration,
+				p.plan_name,
+				p.gco2e_per_kwh,
+				eval_formula(
+					ev.memory_in_mb,
+					ev.storage_in_mb,
+					ev.number_of_nodes,
+					p.duration * filtered_range,
+					p.energy_formula,
+					ev.disk_in_mb,
+					ev.log_rate_limit
+				) as energy_kwh,
+				eval_formula(
+					ev.memory_in_mb,
+					ev.storage_in_mb,
+					ev.number_of_nodes,
+					p.duration * filtered_range,
+					p.carbon_formula,
+					ev.disk_in_mb,
+					ev.log_rate_limit
+				) as other_carbon_gco2e
+			from
+				filtered_range,
+				event_footprint_periods p
+			join
+				events ev on ev.event_guid = p.event_guid
+			where
+				p.duration && filtered_range
+				%s
+		)
+		select
+			event_guid,
+			min(lower(duration)) as event_start,
+			max(upper(duration)) as event_stop,
+			resource_guid,
+			resource_name,
+			resource_type,
+			org_guid,
+			org_name,
+			space_guid,
+			space_name,
+			plan_guid,
+			(sum(energy_kwh))::text as energy_kwh,
+			(sum(energy_kwh * coalesce(gco2e_per_kwh, 0) + other_carbon_gco2e))::text as carbon_gco2e,
+			json_agg(json_build_object(
+				'start', lower(duration),
+				'stop', upper(duration),
+				'plan_name', plan_name,
+				'grid_intensity', coalesce((gco2e_per_kwh)::text, ''),
+				'energy_kwh', (energy_kwh)::text,
+				'carbon_gco2e', (energy_kwh * coalesce(gco2e_per_kwh, 0) + other_carbon_gco2e)::text
+			) order by lower(duration)) as details
+		from
+			footprint_periods
+		group by
+			event_guid,
+			resource_guid,
+			resource_name,
+			resource_type,
+			org_guid,
+			org_name,
+			space_guid,
+			space_name,
+			plan_guid
+		order by
+			min(lower(duration)), event_guid
+	`, filterQuery), args...)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("getFootprintEventRows", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "getFootprintEventRows", err)
+		s.logger.Error("get-footprint-event-rows-query", err, lager.Data{
+			"filter":  filter,
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("getFootprintEventRows", "").Set(elapsed.Seconds())
+	s.logger.Info("get-footprint-event-rows-query", lager.Data{
+		"filter":  filter,
+		"elapsed": int64(elapsed),
+	})
+	return &FootprintEventRows{rows: rows, tx: tx, ctx: ctx, fn: "getFootprintEventRows"}, nil
+}
+
+type FootprintEventRows struct {
+	rows *sql.Rows
+	tx   *sql.Tx
+	ctx  context.Context // the context the query was started with
+	fn   string          // the query's name in the cancelled queries metric
+}
+
+// Next moves the row cursor to the next iteration. Returns false if no more
+// rows.
+func (r *FootprintEventRows) Next() bool {
+	return r.rows.Next()
+}
+
+// Err returns any errors that occurred behind the scenes during processing.
+// Call this at the end of your iteration.
+func (r *FootprintEventRows) Err() error {
+	err := r.rows.Err()
+	if r.ctx != nil {
+		observeCancellation(r.ctx, r.fn, err)
+	}
+	return err
+}
+
+// Close ends the query connection. You must call this. So stick it in a defer.
+func (r *FootprintEventRows) Close() error {
+	r.tx.Rollback()
+	return r.rows.Close()
+}
+
+// EventJSON returns the JSON representation of the event directly from the db.
+func (r *FootprintEventRows) EventJSON() ([]byte, error) {
+	var b []byte
+	if err := r.rows.Scan(&b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Event returns the current row's FootprintEvent. You must call Next
+// _before_ calling this method
+func (r *FootprintEventRows) Event() (*eventio.FootprintEvent, error) {
+	b, err := r.EventJSON()
+	if err != nil {
+		return nil, err
+	}
+	var event eventio.FootprintEvent
+	if err := json.Unmarshal(b, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
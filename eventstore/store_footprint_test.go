@@ -0,0 +1,149 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Footprint", func() {
+
+	const (
+		orgGUID   = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		appGUID   = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+	)
+
+	var (
+		cfg eventstore.Config
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.FootprintPlans = []eventio.FootprintPlan{
+			{
+				PlanGUID:      eventstore.ComputePlanGUID,
+				ValidFrom:     "2001-01-01",
+				Name:          "app",
+				EnergyFormula: "$number_of_nodes * ($memory_in_mb / 1024) * ($time_in_seconds / 3600) * 0.5",
+				CarbonFormula: "$number_of_nodes * ($time_in_seconds / 3600)",
+			},
+		}
+		cfg.GridIntensities = []eventio.GridIntensity{
+			{ValidFrom: "2001-01-01", GCO2ePerKWh: "100"},
+			{ValidFrom: "2001-01-01T01:00:00Z", GCO2ePerKWh: "400"},
+		}
+	})
+
+	It("should reject an energy formula with an illegal token", func(ctx SpecContext) {
+		cfg.FootprintPlans[0].EnergyFormula = "$memory_in_mb * bad"
+		_, err := testenv.OpenWithContext(cfg, ctx)
+		Expect(err).To(MatchError(ContainSubstring("illegal token in formula: bad")))
+	})
+
+	It("should return the configured footprint plans", func(ctx SpecContext) {
+		db, err := testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		plans, err := db.Schema.GetFootprintPlans(ctx, eventio.TimeRangeFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].Name).To(Equal("app"))
+		Expect(plans[0].PlanGUID).To(Equal(eventstore.ComputePlanGUID))
+	})
+
+	Context("with an app that ran across a change in grid intensity", func() {
+		var db *testenv.TempDB
+
+		BeforeEach(func(ctx SpecContext) {
+			var err error
+			db, err = testenv.OpenWithContext(cfg, ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(db.Insert("app_usage_events",
+				testenv.Row{
+					"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+					"created_at":  "2001-01-01T00:00Z",
+					"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+				},
+				testenv.Row{
+					"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+					"created_at":  "2001-01-01T03:00Z",
+					"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+				},
+			)).To(Succeed())
+			Expect(db.Schema.Refresh()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			db.Close()
+		})
+
+		getFootprintEvents := func(ctx SpecContext, filter eventio.EventFilter) []eventio.FootprintEvent {
+			rows, err := db.Schema.GetFootprintEventRows(ctx, filter)
+			Expect(err).ToNot(HaveOccurred())
+			defer rows.Close()
+			events := []eventio.FootprintEvent{}
+			for rows.Next() {
+				event, err := rows.Event()
+				Expect(err).ToNot(HaveOccurred())
+				events = append(events, *event)
+			}
+			Expect(rows.Err()).ToNot(HaveOccurred())
+			return events
+		}
+
+		It("should estimate the energy and carbon of each grid intensity period", func(ctx SpecContext) {
+			events := getFootprintEvents(ctx, eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-02-01",
+			})
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].ResourceName).To(Equal("APP1"))
+			Expect(eventio.Money(events[0].EnergyKWh).Round(2)).To(Equal(eventio.Money("6.00")))
+			// 2kWh at 100g + 4kWh at 400g + 2 nodes for 3 hours
+			Expect(eventio.Money(events[0].CarbonGCO2e).Round(2)).To(Equal(eventio.Money("1806.00")))
+
+			Expect(events[0].Details).To(HaveLen(2))
+			Expect(events[0].Details[0].PlanName).To(Equal("app"))
+			Expect(eventio.Money(events[0].Details[0].GridIntensity).Round(0)).To(Equal(eventio.Money("100")))
+			Expect(eventio.Money(events[0].Details[0].EnergyKWh).Round(2)).To(Equal(eventio.Money("2.00")))
+			Expect(eventio.Money(events[0].Details[0].CarbonGCO2e).Round(2)).To(Equal(eventio.Money("202.00")))
+			Expect(eventio.Money(events[0].Details[1].GridIntensity).Round(0)).To(Equal(eventio.Money("400")))
+			Expect(eventio.Money(events[0].Details[1].EnergyKWh).Round(2)).To(Equal(eventio.Money("4.00")))
+			Expect(eventio.Money(events[0].Details[1].CarbonGCO2e).Round(2)).To(Equal(eventio.Money("1604.00")))
+		})
+
+		It("should only estimate the part of the event within the range", func(ctx SpecContext) {
+			events := getFootprintEvents(ctx, eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-01-02",
+				OrgGUIDs:   []string{orgGUID},
+			})
+			Expect(events).To(HaveLen(1))
+
+			events = getFootprintEvents(ctx, eventio.EventFilter{
+				RangeStart: "2001-01-02",
+				RangeStop:  "2001-01-03",
+			})
+			Expect(events).To(BeEmpty())
+		})
+
+		It("should filter by org", func(ctx SpecContext) {
+			events := getFootprintEvents(ctx, eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-02-01",
+				OrgGUIDs:   []string{"00000000-0000-0000-0000-000000000001"},
+			})
+			Expect(events).To(BeEmpty())
+		})
+	})
+})
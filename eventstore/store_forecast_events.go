@@ -18,11 +18,12 @@ const (
 )
 
 func (s *EventStore) ForecastBillableEventRows(ctx context.Context, events []eventio.UsageEvent, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "ForecastBillableEventRows", err)
 		return nil, err
 	}
-	rows, err := s.forecastBillableEventRows(tx, events, filter)
+	rows, err := s.forecastBillableEventRows(ctx, tx, events, filter)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -30,24 +31,26 @@ func (s *EventStore) ForecastBillableEventRows(ctx context.Context, events []eve
 	return rows, nil
 }
 
-func (s *EventStore) forecastBillableEventRows(tx *sql.Tx, events []eventio.UsageEvent, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
+func (s *EventStore) forecastBillableEventRows(ctx context.Context, tx *sql.Tx, events []eventio.UsageEvent, filter eventio.EventFilter) (eventio.BillableEventRows, error) {
 	eventGUIDs := []string{}
 	for _, ev := range events {
-		_, err := tx.Exec(`
+		_, err := tx.ExecContext(ctx, `
 			insert into events (
 				event_guid,
 				resource_guid, resource_name, resource_type,
 				org_guid, org_name, space_guid, space_name,
 				duration,
 				plan_guid, plan_name,
-				number_of_nodes, memory_in_mb, storage_in_mb
+				number_of_nodes, memory_in_mb, storage_in_mb,
+				disk_in_mb, log_rate_limit
 			) values (
 				$1::uuid,
 				$2::uuid, $3::text, $4::text,
 				$5::uuid, $6::text, $7::uuid, $8::text,
 				tstzrange($9::timestamptz, $10::timestamptz),
 				$11::uuid, 'simulated',
-				$12::numeric, $13::numeric, $14::numeric
+				$12::numeric, $13::numeric, $14::numeric,
+				nullif($15::numeric, 0), nullif($16::numeric, 0)
 			)
 		`,
 			ev.EventGUID,
@@ -56,27 +59,36 @@ func (s *EventStore) forecastBillableEventRows(tx *sql.Tx, events []eventio.Usag
 			ev.EventStart, ev.EventStop,
 			ev.PlanGUID,
 			ev.NumberOfNodes, ev.MemoryInMB, ev.StorageInMB,
+			ev.DiskInMB, ev.LogRateLimit,
 		)
 		if err != nil {
+			observeCancellation(ctx, "forecastBillableEventRows", err)
 			return nil, err
 		}
 		eventGUIDs = append(eventGUIDs, ev.EventGUID)
 	}
-	_, err := tx.Exec(`
+	_, err := tx.ExecContext(ctx, `
 		insert into billable_event_components (
 			select * from generate_billable_event_components()
 			where event_guid = any($1)
 		)
 	`, pq.Array(eventGUIDs))
 	if err != nil {
+		observeCancellation(ctx, "forecastBillableEventRows", err)
 		return nil, err
 	}
 
-	return s.getBillableEventRows(tx, filter)
+	return s.getBillableEventRows(ctx, tx, filter)
 }
 
 func (s *EventStore) ForecastBillableEvents(input []eventio.UsageEvent, filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
-	ctx, cancel := context.WithCancel(s.ctx)
+	return s.ForecastBillableEventsContext(s.ctx, input, filter)
+}
+
+// ForecastBillableEventsContext is ForecastBillableEvents stopping early if
+// ctx is done
+func (s *EventStore) ForecastBillableEventsContext(ctx context.Context, input []eventio.UsageEvent, filter eventio.EventFilter) ([]eventio.BillableEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	rows, err := s.ForecastBillableEventRows(ctx, input, filter)
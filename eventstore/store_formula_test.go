@@ -37,7 +37,7 @@ var _ = Describe("Pricing Formulae", func() {
 
 		return db.Conn.QueryRow(`
 			select
-				eval_formula(64, 128, 2, tstzrange(now(), now() + '60 seconds'), formula) as result
+				eval_formula(64, 128, 2, tstzrange(now(), now() + '60 seconds'), formula, 256, 1024) as result
 			from
 				pricing_plan_components
 			where
@@ -108,6 +108,20 @@ var _ = Describe("Pricing Formulae", func() {
 		Expect(out).To(Equal(128 / 1024.0 * 2))
 	})
 
+	It("Should allow $disk_in_mb variable", func(ctx SpecContext) {
+		var out int
+		err := insert("$disk_in_mb * 2", &out, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(256 * 2))
+	})
+
+	It("Should allow $log_rate_limit variable", func(ctx SpecContext) {
+		var out int
+		err := insert("$log_rate_limit * 2", &out, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out).To(Equal(1024 * 2))
+	})
+
 	It("Should allow $number_of_nodes variable", func(ctx SpecContext) {
 		var out int
 		err := insert("$number_of_nodes * 2", &out, ctx)
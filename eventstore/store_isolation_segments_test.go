@@ -0,0 +1,202 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Isolation segments", func() {
+
+	const (
+		orgGUID              = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		sharedSpaceGUID      = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		isolatedSpaceGUID    = "5f6f3a3c-4b4a-4d6c-9c5e-0c2b0a3d1e7f"
+		isolationSegmentGUID = "3c5b3e7a-9a4b-4f3e-8d2c-1b0a9f8e7d6c"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "ceil($time_in_seconds/3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:             eventstore.ComputePlanGUID,
+			IsolationSegmentGUID: isolationSegmentGUID,
+			ValidFrom:            "2001-01-01",
+			Name:                 "PLAN1 isolated",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "ceil($time_in_seconds/3600) * 0.05",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+	})
+
+	It("should price apps in an isolation segment with its plan", func(ctx SpecContext) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("spaces",
+			testenv.Row{
+				"guid":       sharedSpaceGUID,
+				"valid_from": "2001-01-01T00:00:00Z",
+				"name":       "shared",
+				"created_at": "2001-01-01T00:00:00Z",
+				"updated_at": "2001-01-01T00:00:00Z",
+			},
+			testenv.Row{
+				"guid":                   isolatedSpaceGUID,
+				"valid_from":             "2001-01-01T00:00:00Z",
+				"name":                   "isolated",
+				"created_at":             "2001-01-01T00:00:00Z",
+				"updated_at":             "2001-01-01T00:00:00Z",
+				"isolation_segment_guid": isolationSegmentGUID,
+			},
+		)).To(Succeed())
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + sharedSpaceGUID + `", "space_name": "shared", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + sharedSpaceGUID + `", "space_name": "shared", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "0c9e5fd1-0f35-4c8c-9f0a-4a59c1e3c6b1",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "f1f5bde4-1b7c-4a5b-9d8e-2f3c4d5e6a7b", "app_name": "APP2", "org_guid": "` + orgGUID + `", "space_guid": "` + isolatedSpaceGUID + `", "space_name": "isolated", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "6a1f0d2e-3b4c-4d5e-8f6a-7b8c9d0e1f2a",
+				"created_at":  "2001-01-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "f1f5bde4-1b7c-4a5b-9d8e-2f3c4d5e6a7b", "app_name": "APP2", "org_guid": "` + orgGUID + `", "space_guid": "` + isolatedSpaceGUID + `", "space_name": "isolated", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		segmentPlanGUID, err := eventstore.IsolationSegmentPlanGUID(eventstore.ComputePlanGUID, isolationSegmentGUID)
+		Expect(err).ToNot(HaveOccurred())
+
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+
+		byName := map[string]eventio.BillableEvent{}
+		for _, ev := range events {
+			byName[ev.ResourceName] = ev
+		}
+		Expect(byName["APP1"].PlanGUID).To(Equal(eventstore.ComputePlanGUID))
+		Expect(byName["APP1"].Price.ExVAT).To(Equal(eventio.Money("0.01")))
+		Expect(byName["APP2"].PlanGUID).To(Equal(segmentPlanGUID))
+		Expect(byName["APP2"].Price.ExVAT).To(Equal(eventio.Money("0.05")))
+		Expect(byName["APP2"].Price.Details[0].PlanName).To(Equal("PLAN1 isolated"))
+
+		Expect(db.Get(`select isolation_segment_guid from events where space_guid = $1`, isolatedSpaceGUID)).To(Equal(isolationSegmentGUID))
+
+		plans, err := db.Schema.GetPricingPlans(eventio.TimeRangeFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(HaveLen(2))
+		segmentPlans := 0
+		for _, plan := range plans {
+			if plan.PlanGUID == segmentPlanGUID {
+				Expect(plan.IsolationSegmentGUID).To(Equal(isolationSegmentGUID))
+				segmentPlans++
+			}
+		}
+		Expect(segmentPlans).To(Equal(1))
+	})
+
+	It("should price the part of an app's usage after its space moved into an isolation segment with its plan", func(ctx SpecContext) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("spaces",
+			testenv.Row{
+				"guid":       isolatedSpaceGUID,
+				"valid_from": "2001-01-01T00:00:00Z",
+				"name":       "isolated",
+				"created_at": "2001-01-01T00:00:00Z",
+				"updated_at": "2001-01-01T00:00:00Z",
+			},
+			testenv.Row{
+				"guid":                   isolatedSpaceGUID,
+				"valid_from":             "2001-01-01T01:00:00Z",
+				"name":                   "isolated",
+				"created_at":             "2001-01-01T00:00:00Z",
+				"updated_at":             "2001-01-01T01:00:00Z",
+				"isolation_segment_guid": isolationSegmentGUID,
+			},
+		)).To(Succeed())
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "0c9e5fd1-0f35-4c8c-9f0a-4a59c1e3c6b1",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "f1f5bde4-1b7c-4a5b-9d8e-2f3c4d5e6a7b", "app_name": "APP2", "org_guid": "` + orgGUID + `", "space_guid": "` + isolatedSpaceGUID + `", "space_name": "isolated", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "6a1f0d2e-3b4c-4d5e-8f6a-7b8c9d0e1f2a",
+				"created_at":  "2001-01-01T02:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "f1f5bde4-1b7c-4a5b-9d8e-2f3c4d5e6a7b", "app_name": "APP2", "org_guid": "` + orgGUID + `", "space_guid": "` + isolatedSpaceGUID + `", "space_name": "isolated", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		segmentPlanGUID, err := eventstore.IsolationSegmentPlanGUID(eventstore.ComputePlanGUID, isolationSegmentGUID)
+		Expect(err).ToNot(HaveOccurred())
+
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+
+		byStart := map[string]eventio.BillableEvent{}
+		for _, ev := range events {
+			byStart[ev.EventStart] = ev
+		}
+		shared := byStart["2001-01-01T00:00:00+00:00"]
+		Expect(shared.EventStop).To(Equal("2001-01-01T01:00:00+00:00"))
+		Expect(shared.PlanGUID).To(Equal(eventstore.ComputePlanGUID))
+		Expect(shared.Price.ExVAT).To(Equal(eventio.Money("0.01")))
+		isolated := byStart["2001-01-01T01:00:00+00:00"]
+		Expect(isolated.EventStop).To(Equal("2001-01-01T02:00:00+00:00"))
+		Expect(isolated.PlanGUID).To(Equal(segmentPlanGUID))
+		Expect(isolated.Price.ExVAT).To(Equal(eventio.Money("0.05")))
+	})
+})
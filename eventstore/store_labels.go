@@ -0,0 +1,34 @@
+package eventstore
+
+import (
+	"fmt"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// labelSelectorConditions appends the keys and values of a label selector
+// to args and returns a condition on the labels column for each requirement
+func labelSelectorConditions(selector string, args []interface{}) ([]string, []interface{}, error) {
+	requirements, err := eventio.ParseLabelSelector(selector)
+	if err != nil {
+		return nil, args, err
+	}
+	conditions := []string{}
+	for _, r := range requirements {
+		args = append(args, r.Key)
+		keyPosition := len(args)
+		switch r.Operator {
+		case eventio.LabelEquals:
+			args = append(args, r.Value)
+			conditions = append(conditions, fmt.Sprintf("labels->>$%d::text = $%d::text", keyPosition, len(args)))
+		case eventio.LabelNotEquals:
+			args = append(args, r.Value)
+			conditions = append(conditions, fmt.Sprintf("labels->>$%d::text is distinct from $%d::text", keyPosition, len(args)))
+		case eventio.LabelExists:
+			conditions = append(conditions, fmt.Sprintf("labels ? $%d::text", keyPosition))
+		case eventio.LabelNotExists:
+			conditions = append(conditions, fmt.Sprintf("not coalesce(labels ? $%d::text, false)", keyPosition))
+		}
+	}
+	return conditions, args, nil
+}
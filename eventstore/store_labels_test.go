@@ -0,0 +1,177 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Labels", func() {
+
+	const (
+		orgGUID   = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		app1GUID  = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+		app2GUID  = "f1f5bde4-1b7c-4a5b-9d8e-2f3c4d5e6a7b"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func(ctx SpecContext) {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "ceil($time_in_seconds/3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("resource_labels",
+			testenv.Row{
+				"guid":          orgGUID,
+				"valid_from":    "2001-01-01T00:00:00Z",
+				"resource_type": "org",
+				"labels":        json.RawMessage(`{"team": "platform", "env": "prod"}`),
+			},
+			testenv.Row{
+				"guid":          app2GUID,
+				"valid_from":    "2001-01-01T00:00:00Z",
+				"resource_type": "app",
+				"labels":        json.RawMessage(`{"team": "billing"}`),
+			},
+		)).To(Succeed())
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + app1GUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + app1GUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "0c9e5fd1-0f35-4c8c-9f0a-4a59c1e3c6b1",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + app2GUID + `", "app_name": "APP2", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "6a1f0d2e-3b4c-4d5e-8f6a-7b8c9d0e1f2a",
+				"created_at":  "2001-01-01T02:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + app2GUID + `", "app_name": "APP2", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should give events the labels of their org, overridden by those of their resource", func() {
+		events, err := db.Schema.GetUsageEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Labels).To(Equal(map[string]string{"team": "platform", "env": "prod"}))
+		Expect(events[1].Labels).To(Equal(map[string]string{"team": "billing", "env": "prod"}))
+	})
+
+	It("should split events where their labels change", func() {
+		Expect(db.Insert("resource_labels", testenv.Row{
+			"guid":          app2GUID,
+			"valid_from":    "2001-01-01T01:00:00Z",
+			"resource_type": "app",
+			"labels":        json.RawMessage(`{"team": "payments"}`),
+		})).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart:    "2001-01-01",
+			RangeStop:     "2001-02-01",
+			LabelSelector: "team!=platform",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+
+		byTeam := map[string]eventio.BillableEvent{}
+		for _, ev := range events {
+			Expect(ev.ResourceName).To(Equal("APP2"))
+			byTeam[ev.Labels["team"]] = ev
+		}
+		Expect(byTeam["billing"].EventStart).To(Equal("2001-01-01T00:00:00+00:00"))
+		Expect(byTeam["billing"].EventStop).To(Equal("2001-01-01T01:00:00+00:00"))
+		Expect(byTeam["billing"].Price.ExVAT).To(Equal(eventio.Money("0.01")))
+		Expect(byTeam["payments"].EventStart).To(Equal("2001-01-01T01:00:00+00:00"))
+		Expect(byTeam["payments"].EventStop).To(Equal("2001-01-01T02:00:00+00:00"))
+		Expect(byTeam["payments"].Price.ExVAT).To(Equal(eventio.Money("0.01")))
+	})
+
+	It("should filter billable events with a label selector", func() {
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart:    "2001-01-01",
+			RangeStop:     "2001-02-01",
+			LabelSelector: "team=billing,env",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].ResourceName).To(Equal("APP2"))
+		Expect(events[0].Labels).To(HaveKeyWithValue("team", "billing"))
+
+		events, err = db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart:    "2001-01-01",
+			RangeStop:     "2001-02-01",
+			LabelSelector: "team!=billing,!cost-centre",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].ResourceName).To(Equal("APP1"))
+	})
+
+	It("should group costs by the value of a label", func(ctx SpecContext) {
+		rows, err := db.Schema.GetCostTimeSeriesRows(ctx, eventio.CostTimeSeriesFilter{
+			EventFilter: eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-01-02",
+			},
+			Step:    "day",
+			GroupBy: "label:team",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		defer rows.Close()
+
+		points := []eventio.CostTimeSeriesPoint{}
+		for rows.Next() {
+			point, err := rows.Point()
+			Expect(err).ToNot(HaveOccurred())
+			points = append(points, *point)
+		}
+		Expect(rows.Err()).ToNot(HaveOccurred())
+		Expect(points).To(HaveLen(2))
+		Expect(points[0].Group).To(Equal("billing"))
+		Expect(points[0].GroupName).To(Equal("billing"))
+		Expect(points[0].ExVAT).ToNot(Equal(eventio.Money("0")))
+		Expect(points[1].Group).To(Equal("platform"))
+		Expect(points[1].ExVAT).ToNot(Equal(eventio.Money("0")))
+	})
+})
@@ -0,0 +1,51 @@
+package eventstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+// storeMeteringEvent stores a metering record posted by a service broker.
+// Records for service instances there are no usage events for, or that are
+// not instances of the broker's services, are rejected, and a record that is
+// already stored is left as it is.
+func (s *EventStore) storeMeteringEvent(tx *sql.Tx, event eventio.RawEvent) error {
+	if event.Kind != eventio.MeteringKind {
+		return fmt.Errorf("storeMeteringEvent cannot store event of type %s", event.Kind)
+	}
+	var record eventio.MeteringRecord
+	if err := json.Unmarshal(event.RawMessage, &record); err != nil {
+		return err
+	}
+	serviceGUIDs := make([]string, len(record.ServiceGUIDs))
+	for i, serviceGUID := range record.ServiceGUIDs {
+		serviceGUIDs[i] = strings.ToLower(serviceGUID)
+	}
+	var known bool
+	err := tx.QueryRow(`
+		select exists (
+			select 1 from service_usage_events
+			where raw_message->>'service_instance_guid' = $1
+			and lower(raw_message->>'service_guid') = any($2)
+		)
+	`, strings.ToLower(record.ServiceInstanceGUID), pq.Array(serviceGUIDs)).Scan(&known)
+	if err != nil {
+		return err
+	}
+	if !known {
+		return fmt.Errorf("%w: %s", eventio.ErrUnknownServiceInstance, record.ServiceInstanceGUID)
+	}
+	_, err = tx.Exec(`
+		insert into metering_events (
+			guid, created_at, raw_message
+		) values (
+			$1, $2, $3
+		) on conflict (guid) do nothing
+	`, event.GUID, event.CreatedAt, event.RawMessage)
+	return err
+}
@@ -0,0 +1,266 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metering", func() {
+
+	const (
+		orgGUID             = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID           = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		serviceInstanceGUID = "aaaaaaaa-0000-0000-0000-000000000001"
+		servicePlanGUID     = "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5"
+		serviceGUID         = "efadb775-58c4-4e17-8087-6d0f4febc489"
+		planUniqueID        = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  planUniqueID,
+			ValidFrom: "2001-01-01",
+			Name:      "bucket",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "instance",
+					Formula:      "$time_in_seconds / 3600",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+				{
+					Name:            "storage",
+					Formula:         "$metric_storage_gb_hours * 0.1",
+					CurrencyCode:    "GBP",
+					VATCode:         "Standard",
+					Unit:            "GB-hours",
+					QuantityFormula: "$metric_storage_gb_hours",
+				},
+			},
+		})
+	})
+
+	// the service instance is updated at 02:00 so has two events
+	openWithServiceInstance := func(ctx SpecContext, cfg eventstore.Config) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("services",
+			testenv.Row{
+				"label":               "s3",
+				"guid":                serviceGUID,
+				"valid_from":          "2000-01-01T00:00Z",
+				"created_at":          "2000-01-01T00:00Z",
+				"updated_at":          "2000-01-01T00:00Z",
+				"description":         "",
+				"service_broker_guid": "efadb775-58c4-4e17-8087-6d0f4febc481",
+				"active":              true,
+				"bindable":            true,
+			})).To(Succeed())
+		Expect(db.Insert("service_plans",
+			testenv.Row{
+				"unique_id":          planUniqueID,
+				"name":               "bucket",
+				"guid":               servicePlanGUID,
+				"valid_from":         "2000-01-01T00:00Z",
+				"created_at":         "2000-01-01T00:00Z",
+				"updated_at":         "2000-01-01T00:00Z",
+				"description":        "",
+				"service_guid":       serviceGUID,
+				"service_valid_from": "2000-01-01T00:00Z",
+				"active":             true,
+				"public":             true,
+				"free":               false,
+				"extra":              "",
+			})).To(Succeed())
+		rawMessage := func(state string) json.RawMessage {
+			return json.RawMessage(`{"state": "` + state + `", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "platform", "service_guid": "` + serviceGUID + `", "service_label": "s3", "service_plan_guid": "` + servicePlanGUID + `", "service_plan_name": "bucket", "service_instance_guid": "` + serviceInstanceGUID + `", "service_instance_name": "bucket1", "service_instance_type": "managed_service_instance"}`)
+		}
+		Expect(db.Insert("service_usage_events",
+			testenv.Row{
+				"guid":        "00000000-0000-0000-0000-000000000001",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": rawMessage("CREATED"),
+			},
+			testenv.Row{
+				"guid":        "00000000-0000-0000-0000-000000000002",
+				"created_at":  "2001-01-01T02:00Z",
+				"raw_message": rawMessage("UPDATED"),
+			},
+			testenv.Row{
+				"guid":        "00000000-0000-0000-0000-000000000003",
+				"created_at":  "2001-01-01T04:00Z",
+				"raw_message": rawMessage("DELETED"),
+			},
+		)).To(Succeed())
+	}
+
+	storeRecords := func(records ...eventio.MeteringRecord) error {
+		events := []eventio.RawEvent{}
+		for _, record := range records {
+			event, err := record.RawEvent()
+			Expect(err).ToNot(HaveOccurred())
+			events = append(events, event)
+		}
+		return db.Schema.StoreEvents(events)
+	}
+
+	record := func(key string, quantity string, start string, stop string) eventio.MeteringRecord {
+		return eventio.MeteringRecord{
+			Broker:              "s3-broker",
+			ServiceGUIDs:        []string{serviceGUID},
+			IdempotencyKey:      key,
+			ServiceInstanceGUID: serviceInstanceGUID,
+			Metric:              "storage_gb_hours",
+			Quantity:            json.Number(quantity),
+			Start:               start,
+			Stop:                stop,
+		}
+	}
+
+	storageComponents := func() map[string]eventio.PriceComponent {
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		byStart := map[string]eventio.PriceComponent{}
+		for _, ev := range events {
+			for _, component := range ev.Price.Details {
+				if component.Name == "storage" {
+					byStart[ev.EventStart] = component
+				}
+			}
+		}
+		return byStart
+	}
+
+	roundedPrice := func(component eventio.PriceComponent) eventio.Money {
+		price, err := component.ExVAT.Round(2)
+		Expect(err).ToNot(HaveOccurred())
+		return price
+	}
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should store each record once", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+
+		first := record("k1", "10", "2001-01-01T00:00:00Z", "2001-01-01T01:00:00Z")
+		Expect(storeRecords(first)).To(Succeed())
+		first.Quantity = "20"
+		Expect(storeRecords(first)).To(Succeed())
+
+		stored, err := db.Schema.GetEvents(eventio.RawEventFilter{Kind: eventio.MeteringKind})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored).To(HaveLen(1))
+		Expect(string(stored[0].RawMessage)).To(ContainSubstring(`"quantity": 10`))
+	})
+
+	It("should reject records for unknown service instances", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+
+		unknown := record("k1", "10", "2001-01-01T00:00:00Z", "2001-01-01T01:00:00Z")
+		unknown.ServiceInstanceGUID = "aaaaaaaa-0000-0000-0000-000000000002"
+		err := storeRecords(record("k0", "10", "2001-01-01T00:00:00Z", "2001-01-01T01:00:00Z"), unknown)
+		Expect(errors.Is(err, eventio.ErrUnknownServiceInstance)).To(BeTrue())
+
+		stored, err := db.Schema.GetEvents(eventio.RawEventFilter{Kind: eventio.MeteringKind})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored).To(BeEmpty())
+	})
+
+	It("should reject records for instances of services of other brokers", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+
+		other := record("k1", "10", "2001-01-01T00:00:00Z", "2001-01-01T01:00:00Z")
+		other.Broker = "rds-broker"
+		other.ServiceGUIDs = []string{"bbbbbbbb-0000-0000-0000-000000000001"}
+		err := storeRecords(other)
+		Expect(errors.Is(err, eventio.ErrUnknownServiceInstance)).To(BeTrue())
+
+		stored, err := db.Schema.GetEvents(eventio.RawEventFilter{Kind: eventio.MeteringKind})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored).To(BeEmpty())
+	})
+
+	It("should not price records stored for instances of services of other brokers", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+		other := record("k1", "10", "2001-01-01T00:00:00Z", "2001-01-01T01:00:00Z")
+		other.ServiceGUIDs = []string{"bbbbbbbb-0000-0000-0000-000000000001"}
+		event, err := other.RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(db.Insert("metering_events", testenv.Row{
+			"guid":        event.GUID,
+			"created_at":  "2001-01-01T00:00Z",
+			"raw_message": json.RawMessage(event.RawMessage),
+		})).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		components := storageComponents()
+		Expect(roundedPrice(components["2001-01-01T00:00:00+00:00"])).To(Equal(eventio.Money("0.00")))
+	})
+
+	It("should price metered quantities in the events they were measured during", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+		Expect(storeRecords(
+			record("k1", "10", "2001-01-01T00:00:00Z", "2001-01-01T01:00:00Z"),
+			// half before and half after the update
+			record("k2", "20", "2001-01-01T01:00:00Z", "2001-01-01T03:00:00Z"),
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		components := storageComponents()
+		Expect(components).To(HaveLen(2))
+
+		first := components["2001-01-01T00:00:00+00:00"]
+		Expect(roundedPrice(first)).To(Equal(eventio.Money("2.00")))
+		Expect(first.Unit).To(Equal("GB-hours"))
+		Expect(eventio.Money(first.Quantity).Round(2)).To(Equal(eventio.Money("20.00")))
+
+		second := components["2001-01-01T02:00:00+00:00"]
+		Expect(roundedPrice(second)).To(Equal(eventio.Money("1.00")))
+	})
+
+	It("should price metrics without records at zero", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		components := storageComponents()
+		Expect(components).To(HaveLen(2))
+		Expect(roundedPrice(components["2001-01-01T00:00:00+00:00"])).To(Equal(eventio.Money("0.00")))
+	})
+
+	It("should reject formulas with malformed metrics", func(ctx SpecContext) {
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  "bbbbbbbb-0000-0000-0000-000000000001",
+			ValidFrom: "2001-01-01",
+			Name:      "bad",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "bad",
+					Formula:      "$metric-storage * 0.1",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).To(MatchError(ContainSubstring("illegal token in formula")))
+	})
+})
@@ -0,0 +1,85 @@
+package eventstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// initProviderCostRules replaces the provider cost rules with those from the
+// config
+func (s *EventStore) initProviderCostRules(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM provider_cost_rules"); err != nil {
+		return wrapPqError(err, "error deleting existing provider_cost_rules")
+	}
+	for _, rule := range s.cfg.ProviderCostRules {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		s.logger.Info("configuring-provider-cost-rule", lager.Data{
+			"plan_guid":  rule.PlanGUID,
+			"valid_from": rule.ValidFrom,
+			"markup":     rule.Markup,
+		})
+		_, err := tx.Exec(`insert into provider_cost_rules (
+			plan_guid, valid_from, markup, vat_code
+		) values (
+			$1, $2, coalesce(nullif($3, ''), '0')::numeric, $4
+		)`, rule.PlanGUID, rule.ValidFrom, rule.Markup, rule.VATCode)
+		if err != nil {
+			return wrapPqError(err, "invalid provider cost rule")
+		}
+	}
+	return checkProviderCostRuleRates(tx)
+}
+
+// checkProviderCostRuleRates ensures there are VAT rates for every provider
+// cost rule from when it is valid. The currency rates needed depend on the
+// imported costs, so are not checked.
+func checkProviderCostRuleRates(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		select
+			plan_guid,
+			valid_from
+		from
+			provider_cost_rules pcr
+		where
+			not exists (
+				select 1 from vat_rates vr
+				where vr.code = pcr.vat_code and vr.valid_from <= pcr.valid_from
+			)
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var guid string
+		var valid string
+		if err := rows.Scan(&guid, &valid); err != nil {
+			return err
+		}
+		return fmt.Errorf("missing vat_rate for period '%s' required by provider cost rule '%s'", valid, guid)
+	}
+	return rows.Err()
+}
+
+// storeProviderCostEvent stores an imported provider cost, replacing any
+// earlier import of the same line item
+func (s *EventStore) storeProviderCostEvent(tx *sql.Tx, event eventio.RawEvent) error {
+	if event.Kind != eventio.ProviderCostKind {
+		return fmt.Errorf("storeProviderCostEvent cannot store event of type %s", event.Kind)
+	}
+	_, err := tx.Exec(`
+		insert into provider_cost_events (
+			guid, created_at, raw_message
+		) values (
+			$1, $2, $3
+		) on conflict (guid) do update set
+			created_at = excluded.created_at,
+			raw_message = excluded.raw_message
+	`, event.GUID, event.CreatedAt, event.RawMessage)
+	return err
+}
@@ -0,0 +1,236 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provider costs", func() {
+
+	const (
+		orgGUID             = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID           = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		serviceInstanceGUID = "aaaaaaaa-0000-0000-0000-000000000001"
+		servicePlanGUID     = "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5"
+		serviceGUID         = "efadb775-58c4-4e17-8087-6d0f4febc489"
+		planUniqueID        = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+	)
+
+	var (
+		cfg   eventstore.Config
+		db    *testenv.TempDB
+		err   error
+		costs []eventio.ProviderCost
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddCurrencyRate(eventio.CurrencyRate{
+			Code:      "USD",
+			Rate:      0.8,
+			ValidFrom: "epoch",
+		})
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  planUniqueID,
+			ValidFrom: "2001-01-01",
+			Name:      "DB",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "db",
+					Formula:      "$time_in_seconds / 3600",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		costs = []eventio.ProviderCost{
+			{
+				Provider:            "aws",
+				LineItemID:          "li-1",
+				ServiceInstanceGUID: serviceInstanceGUID,
+				UsageStart:          "2001-01-01T01:00:00Z",
+				UsageStop:           "2001-01-01T02:00:00Z",
+				UsageAmount:         "1",
+				Unit:                "Hrs",
+				Cost:                "0.5",
+				CurrencyCode:        "USD",
+			},
+			{
+				// runs past the deletion of the instance
+				Provider:            "aws",
+				LineItemID:          "li-2",
+				ServiceInstanceGUID: serviceInstanceGUID,
+				UsageStart:          "2001-01-01T05:00:00Z",
+				UsageStop:           "2001-01-01T07:00:00Z",
+				Cost:                "2",
+				CurrencyCode:        "USD",
+			},
+			{
+				// after the instance was deleted
+				Provider:            "aws",
+				LineItemID:          "li-3",
+				ServiceInstanceGUID: serviceInstanceGUID,
+				UsageStart:          "2001-01-02T00:00:00Z",
+				UsageStop:           "2001-01-02T01:00:00Z",
+				Cost:                "3",
+				CurrencyCode:        "USD",
+			},
+		}
+	})
+
+	openWithServiceInstance := func(ctx SpecContext, cfg eventstore.Config) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(db.Insert("services",
+			testenv.Row{
+				"label":               "postgres",
+				"guid":                serviceGUID,
+				"valid_from":          "2000-01-01T00:00Z",
+				"created_at":          "2000-01-01T00:00Z",
+				"updated_at":          "2000-01-01T00:00Z",
+				"description":         "",
+				"service_broker_guid": "efadb775-58c4-4e17-8087-6d0f4febc481",
+				"active":              true,
+				"bindable":            true,
+			})).To(Succeed())
+		Expect(db.Insert("service_plans",
+			testenv.Row{
+				"unique_id":          planUniqueID,
+				"name":               "DB",
+				"guid":               servicePlanGUID,
+				"valid_from":         "2000-01-01T00:00Z",
+				"created_at":         "2000-01-01T00:00Z",
+				"updated_at":         "2000-01-01T00:00Z",
+				"description":        "",
+				"service_guid":       serviceGUID,
+				"service_valid_from": "2000-01-01T00:00Z",
+				"active":             true,
+				"public":             true,
+				"free":               false,
+				"extra":              "",
+			})).To(Succeed())
+		Expect(db.Insert("service_usage_events",
+			testenv.Row{
+				"guid":        "00000000-0000-0000-0000-000000000001",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "CREATED", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "platform", "service_guid": "` + serviceGUID + `", "service_label": "postgres", "service_plan_guid": "` + servicePlanGUID + `", "service_plan_name": "DB", "service_instance_guid": "` + serviceInstanceGUID + `", "service_instance_name": "db1", "service_instance_type": "managed_service_instance"}`),
+			},
+			testenv.Row{
+				"guid":        "00000000-0000-0000-0000-000000000002",
+				"created_at":  "2001-01-01T06:00Z",
+				"raw_message": json.RawMessage(`{"state": "DELETED", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "platform", "service_guid": "` + serviceGUID + `", "service_label": "postgres", "service_plan_guid": "` + servicePlanGUID + `", "service_plan_name": "DB", "service_instance_guid": "` + serviceInstanceGUID + `", "service_instance_name": "db1", "service_instance_type": "managed_service_instance"}`),
+			},
+		)).To(Succeed())
+
+		events := []eventio.RawEvent{}
+		for _, cost := range costs {
+			event, err := cost.RawEvent()
+			Expect(err).ToNot(HaveOccurred())
+			events = append(events, event)
+		}
+		Expect(db.Schema.StoreEvents(events)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+	}
+
+	providerCostEvents := func() map[string]eventio.BillableEvent {
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		byStart := map[string]eventio.BillableEvent{}
+		for _, ev := range events {
+			if ev.Price.Details[0].Name == "provider_cost" {
+				byStart[ev.EventStart] = ev
+			}
+		}
+		return byStart
+	}
+
+	roundedPrice := func(ev eventio.BillableEvent) eventio.Money {
+		price, err := ev.Price.ExVAT.Round(2)
+		Expect(err).ToNot(HaveOccurred())
+		return price
+	}
+
+	AfterEach(func() {
+		db.Close()
+	})
+
+	It("should store and replace imported line items", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+
+		costs[0].Cost = "0.6"
+		event, err := costs[0].RawEvent()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(db.Schema.StoreEvents([]eventio.RawEvent{event})).To(Succeed())
+
+		stored, err := db.Schema.GetEvents(eventio.RawEventFilter{Kind: eventio.ProviderCostKind, Reverse: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stored).To(HaveLen(3))
+		Expect(stored[0].GUID).To(Equal(event.GUID))
+		Expect(string(stored[0].RawMessage)).To(ContainSubstring(`"cost": "0.6"`))
+	})
+
+	It("should not bill provider costs for plans without a rule", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+
+		Expect(providerCostEvents()).To(BeEmpty())
+	})
+
+	It("should bill provider costs with the markup to the service instance's org", func(ctx SpecContext) {
+		cfg.ProviderCostRules = []eventio.ProviderCostRule{{
+			PlanGUID:  servicePlanGUID,
+			ValidFrom: "2001-01-01",
+			Markup:    "0.1",
+			VATCode:   "Standard",
+		}}
+		openWithServiceInstance(ctx, cfg)
+
+		events := providerCostEvents()
+		Expect(events).To(HaveLen(2))
+
+		first := events["2001-01-01T01:00:00+00:00"]
+		Expect(first.OrgGUID).To(Equal(orgGUID))
+		Expect(first.SpaceGUID).To(Equal(spaceGUID))
+		Expect(first.ResourceGUID).To(Equal(serviceInstanceGUID))
+		Expect(first.PlanGUID).To(Equal(servicePlanGUID))
+		Expect(roundedPrice(first)).To(Equal(eventio.Money("0.44")))
+		Expect(first.Price.Details[0].CurrencyCode).To(Equal("USD"))
+		Expect(first.Price.Details[0].Unit).To(Equal("Hrs"))
+
+		// billed in full over the part of its usage the instance existed for
+		second := events["2001-01-01T05:00:00+00:00"]
+		Expect(second.EventStop).To(Equal("2001-01-01T06:00:00+00:00"))
+		Expect(roundedPrice(second)).To(Equal(eventio.Money("1.76")))
+	})
+
+	It("should report provider costs that match no service instance", func(ctx SpecContext) {
+		openWithServiceInstance(ctx, cfg)
+
+		issues, err := db.Schema.GetDataQualityIssues(eventio.DataQualityFilter{
+			Category: eventio.DataQualityUnmatchedProviderCost,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(issues).To(HaveLen(1))
+		Expect(issues[0].ResourceGUID).To(Equal(serviceInstanceGUID))
+		Expect(issues[0].OrgGUID).To(Equal(orgGUID))
+		Expect(issues[0].Detail).To(Equal("3 USD"))
+	})
+
+	It("should need a VAT rate for each rule", func(ctx SpecContext) {
+		cfg.ProviderCostRules = []eventio.ProviderCostRule{{
+			PlanGUID:  servicePlanGUID,
+			ValidFrom: "2001-01-01",
+			VATCode:   "Reduced",
+		}}
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).To(MatchError(ContainSubstring("missing vat_rate for period")))
+	})
+})
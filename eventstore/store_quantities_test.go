@@ -0,0 +1,146 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Usage quantities", func() {
+
+	const (
+		orgGUID   = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		appGUID   = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+	)
+
+	var (
+		cfg eventstore.Config
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+	})
+
+	openWithPlan := func(ctx SpecContext, components ...eventio.PricingPlanComponent) (*testenv.TempDB, error) {
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:   eventstore.ComputePlanGUID,
+			ValidFrom:  "2001-01-01",
+			Name:       "PLAN1",
+			Components: components,
+		})
+		return testenv.OpenWithContext(cfg, ctx)
+	}
+
+	It("should reject a quantity formula with an illegal token", func(ctx SpecContext) {
+		_, err := openWithPlan(ctx, eventio.PricingPlanComponent{
+			Name:            "compute",
+			Formula:         "1",
+			CurrencyCode:    "GBP",
+			VATCode:         "Standard",
+			Unit:            "GB-hours",
+			QuantityFormula: "$memory_in_mb * bad",
+		})
+		Expect(err).To(MatchError(ContainSubstring("illegal token in formula: bad")))
+	})
+
+	It("should reject a unit without a quantity formula", func(ctx SpecContext) {
+		_, err := openWithPlan(ctx, eventio.PricingPlanComponent{
+			Name:         "compute",
+			Formula:      "1",
+			CurrencyCode: "GBP",
+			VATCode:      "Standard",
+			Unit:         "GB-hours",
+		})
+		Expect(err).To(MatchError(ContainSubstring("unit_requires_quantity_formula")))
+	})
+
+	Context("with a component that has a unit", func() {
+		var db *testenv.TempDB
+
+		BeforeEach(func(ctx SpecContext) {
+			var err error
+			db, err = openWithPlan(ctx,
+				eventio.PricingPlanComponent{
+					Name:            "compute",
+					Formula:         "$number_of_nodes * ($time_in_seconds / 3600) * ($memory_in_mb / 1024) * 0.01",
+					CurrencyCode:    "GBP",
+					VATCode:         "Standard",
+					Unit:            "GB-hours",
+					QuantityFormula: "$number_of_nodes * ($time_in_seconds / 3600) * ($memory_in_mb / 1024)",
+				},
+				eventio.PricingPlanComponent{
+					Name:         "platform",
+					Formula:      "($time_in_seconds / 3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(db.Insert("app_usage_events",
+				testenv.Row{
+					"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+					"created_at":  "2001-01-01T00:00Z",
+					"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+				},
+				testenv.Row{
+					"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+					"created_at":  "2001-01-01T03:00Z",
+					"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space", "instance_count": 2, "memory_in_mb_per_instance": 2048}`),
+				},
+			)).To(Succeed())
+			Expect(db.Schema.Refresh()).To(Succeed())
+		})
+
+		AfterEach(func() {
+			db.Close()
+		})
+
+		It("should report the quantity of the component alongside its price", func() {
+			events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+				RangeStart: "2001-01-01",
+				RangeStop:  "2001-02-01",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(HaveLen(1))
+
+			components := map[string]eventio.PriceComponent{}
+			for _, component := range events[0].Price.Details {
+				components[component.Name] = component
+			}
+			Expect(components["compute"].Unit).To(Equal("GB-hours"))
+			Expect(eventio.Money(components["compute"].Quantity).Round(2)).To(Equal(eventio.Money("12.00")))
+			Expect(components["platform"].Unit).To(BeEmpty())
+			Expect(components["platform"].Quantity).To(BeEmpty())
+		})
+
+		It("should total the quantities of each unit in the cost time series", func(ctx SpecContext) {
+			rows, err := db.Schema.GetCostTimeSeriesRows(ctx, eventio.CostTimeSeriesFilter{
+				EventFilter: eventio.EventFilter{
+					RangeStart: "2001-01-01",
+					RangeStop:  "2001-01-03",
+				},
+				Step: "day",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			defer rows.Close()
+
+			points := []eventio.CostTimeSeriesPoint{}
+			for rows.Next() {
+				point, err := rows.Point()
+				Expect(err).ToNot(HaveOccurred())
+				points = append(points, *point)
+			}
+			Expect(rows.Err()).ToNot(HaveOccurred())
+			Expect(points).To(HaveLen(2))
+			Expect(points[0].Quantities).To(HaveKey("GB-hours"))
+			Expect(eventio.Money(points[0].Quantities["GB-hours"]).Round(2)).To(Equal(eventio.Money("12.00")))
+			Expect(points[1].Quantities).To(BeEmpty())
+		})
+	})
+})
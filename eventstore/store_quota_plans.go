@@ -0,0 +1,92 @@
+package eventstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// initQuotaPlans replaces the quota plans and their multipliers with those
+// from the config
+func (s *EventStore) initQuotaPlans(tx *sql.Tx) error {
+	if _, err := tx.Exec("DELETE FROM quota_plans"); err != nil {
+		return wrapPqError(err, "error deleting existing quota_plans")
+	}
+	for _, qp := range s.cfg.QuotaPlans {
+		if err := qp.Validate(); err != nil {
+			return err
+		}
+		s.logger.Info("configuring-quota-plan", lager.Data{
+			"quota_definition_guid": qp.QuotaDefinitionGUID,
+			"name":                  qp.Name,
+			"valid_from":            qp.ValidFrom,
+			"monthly_fee":           qp.MonthlyFee,
+		})
+		_, err := tx.Exec(`insert into quota_plans (
+			quota_definition_guid, valid_from, name,
+			monthly_fee, currency_code, vat_code
+		) values (
+			$1, $2, $3,
+			$4, $5, $6
+		)`, qp.QuotaDefinitionGUID, qp.ValidFrom, qp.Name,
+			string(qp.MonthlyFee), qp.CurrencyCode, qp.VATCode,
+		)
+		if err != nil {
+			return wrapPqError(err, "invalid quota plan")
+		}
+		for _, m := range qp.Multipliers {
+			_, err := tx.Exec(`insert into quota_plan_multipliers (
+				quota_definition_guid, valid_from, component_name, multiplier
+			) values (
+				$1, $2, $3, $4
+			)`, qp.QuotaDefinitionGUID, qp.ValidFrom, m.ComponentName, m.Multiplier)
+			if err != nil {
+				return wrapPqError(err, "invalid quota plan multiplier")
+			}
+		}
+	}
+	return checkQuotaPlanRates(tx)
+}
+
+// checkQuotaPlanRates ensures there are VAT and currency rates for every
+// quota plan from when it is valid
+func checkQuotaPlanRates(tx *sql.Tx) error {
+	rows, err := tx.Query(`
+		select
+			quota_definition_guid,
+			valid_from,
+			not exists (
+				select 1 from vat_rates vr
+				where vr.code = qp.vat_code and vr.valid_from <= qp.valid_from
+			) as missing_vat_rate
+		from
+			quota_plans qp
+		where
+			not exists (
+				select 1 from vat_rates vr
+				where vr.code = qp.vat_code and vr.valid_from <= qp.valid_from
+			)
+			or not exists (
+				select 1 from currency_rates cr
+				where cr.code = qp.currency_code and cr.valid_from <= qp.valid_from
+			)
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var guid string
+		var valid string
+		var missingVATRate bool
+		if err := rows.Scan(&guid, &valid, &missingVATRate); err != nil {
+			return err
+		}
+		if missingVATRate {
+			return fmt.Errorf("missing vat_rate for period '%s' required by quota plan '%s'", valid, guid)
+		}
+		return fmt.Errorf("missing currency_rate for period '%s' required by quota plan '%s'", valid, guid)
+	}
+	return rows.Err()
+}
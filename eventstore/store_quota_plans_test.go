@@ -0,0 +1,199 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Quota plans", func() {
+
+	const (
+		orgGUID   = "51ba75ef-edc0-47ad-a633-a8f6e8770944"
+		spaceGUID = "276f4886-ac40-492d-a8cd-b2646637ba76"
+		quotaGUID = "f9909cea-81fe-4934-ba17-2a10278d2646"
+	)
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "PLAN1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "ceil($time_in_seconds/3600) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		cfg.QuotaPlans = []eventio.QuotaPlan{{
+			Name:                "medium",
+			QuotaDefinitionGUID: quotaGUID,
+			ValidFrom:           "2001-01-01",
+			MonthlyFee:          "31",
+			CurrencyCode:        "GBP",
+			VATCode:             "Standard",
+			Multipliers: []eventio.QuotaMultiplier{
+				{ComponentName: "compute", Multiplier: "2"},
+			},
+		}}
+	})
+
+	/*-----------------------------------------------------------------------------------*
+	 2001-01-01                  2001-01-11                                               .
+	     |                           |                                                    .
+	     [app1]                      |                                                    .
+	     <==========medium===========> org deleted                                        .
+	*-----------------------------------------------------------------------------------*/
+	It("should charge the prorated monthly fee and multiply the components of the org's resources", func(ctx SpecContext) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("orgs", testenv.Row{
+			"guid":                  orgGUID,
+			"valid_from":            "2001-01-01T00:00:00Z",
+			"name":                  "my-org",
+			"owner":                 "Testing Body",
+			"created_at":            "2001-01-01T00:00:00Z",
+			"updated_at":            "2001-01-01T00:00:00Z",
+			"quota_definition_guid": quotaGUID,
+		})).To(Succeed())
+		Expect(db.Insert("org_deletions", testenv.Row{
+			"guid":       orgGUID,
+			"deleted_at": "2001-01-11T00:00:00Z",
+		})).To(Succeed())
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space1", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-01T01:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space1", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+
+		byType := map[string]eventio.BillableEvent{}
+		for _, ev := range events {
+			Expect(ev.QuotaDefinitionGUID).To(Equal(quotaGUID))
+			byType[ev.ResourceType] = ev
+		}
+
+		app := byType["app"]
+		Expect(app.Price.Details).To(HaveLen(1))
+		Expect(app.Price.Details[0].Name).To(Equal("compute"))
+		Expect(app.Price.ExVAT.Float64()).To(BeNumerically("~", 0.02))
+
+		quota := byType["quota"]
+		Expect(quota.ResourceGUID).To(Equal(orgGUID))
+		Expect(quota.ResourceName).To(Equal("medium"))
+		Expect(quota.PlanGUID).To(Equal(quotaGUID))
+		Expect(quota.EventStart).To(Equal("2001-01-01T00:00:00+00:00"))
+		Expect(quota.EventStop).To(Equal("2001-01-11T00:00:00+00:00"))
+		Expect(quota.Price.Details).To(HaveLen(1))
+		Expect(quota.Price.Details[0].Name).To(Equal("quota"))
+		Expect(quota.Price.ExVAT.Float64()).To(BeNumerically("~", 10))
+	})
+
+	/*-----------------------------------------------------------------------------------*
+	 2001-01-01T00:00     01:00     02:00                                                 .
+	     |                  |         |                                                   .
+	     [=======app1================]                                                    .
+	     <=no quota========><=medium==>                                                   .
+	*-----------------------------------------------------------------------------------*/
+	It("should split the events of the org's resources where its quota changes", func(ctx SpecContext) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		Expect(db.Insert("orgs",
+			testenv.Row{
+				"guid":       orgGUID,
+				"valid_from": "2001-01-01T00:00:00Z",
+				"name":       "my-org",
+				"owner":      "Testing Body",
+				"created_at": "2001-01-01T00:00:00Z",
+				"updated_at": "2001-01-01T00:00:00Z",
+			},
+			testenv.Row{
+				"guid":                  orgGUID,
+				"valid_from":            "2001-01-01T01:00:00Z",
+				"name":                  "my-org",
+				"owner":                 "Testing Body",
+				"created_at":            "2001-01-01T00:00:00Z",
+				"updated_at":            "2001-01-01T01:00:00Z",
+				"quota_definition_guid": quotaGUID,
+			},
+		)).To(Succeed())
+		Expect(db.Insert("app_usage_events",
+			testenv.Row{
+				"guid":        "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"created_at":  "2001-01-01T00:00Z",
+				"raw_message": json.RawMessage(`{"state": "STARTED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space1", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+			testenv.Row{
+				"guid":        "8d9036c5-8367-497d-bb56-94bfcac6621a",
+				"created_at":  "2001-01-01T02:00Z",
+				"raw_message": json.RawMessage(`{"state": "STOPPED", "app_guid": "c85e98f0-6d1b-4f45-9368-ea58263165a0", "app_name": "APP1", "org_guid": "` + orgGUID + `", "space_guid": "` + spaceGUID + `", "space_name": "space1", "instance_count": 1, "memory_in_mb_per_instance": 1024}`),
+			},
+		)).To(Succeed())
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		events, err := db.Schema.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		apps := []eventio.BillableEvent{}
+		for _, ev := range events {
+			if ev.ResourceType == "app" {
+				apps = append(apps, ev)
+			}
+		}
+		Expect(apps).To(HaveLen(2))
+		sort.Slice(apps, func(i, j int) bool { return apps[i].EventStart < apps[j].EventStart })
+
+		Expect(apps[0].EventGUID).To(Equal("ee28a570-f485-48e1-87d0-98b7b8b66dfa"))
+		Expect(apps[0].EventStop).To(Equal("2001-01-01T01:00:00+00:00"))
+		Expect(apps[0].QuotaDefinitionGUID).To(BeEmpty())
+		Expect(apps[0].Price.ExVAT.Float64()).To(BeNumerically("~", 0.01))
+
+		Expect(apps[1].EventGUID).ToNot(Equal(apps[0].EventGUID))
+		Expect(apps[1].EventStart).To(Equal("2001-01-01T01:00:00+00:00"))
+		Expect(apps[1].EventStop).To(Equal("2001-01-01T02:00:00+00:00"))
+		Expect(apps[1].QuotaDefinitionGUID).To(Equal(quotaGUID))
+		Expect(apps[1].Price.ExVAT.Float64()).To(BeNumerically("~", 0.02))
+	})
+
+	It("should fail to initialise if a quota plan has no VAT rate", func(ctx SpecContext) {
+		cfg.QuotaPlans[0].VATCode = "Reduced"
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).To(MatchError(ContainSubstring("missing vat_rate")))
+	})
+})
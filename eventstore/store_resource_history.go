@@ -0,0 +1,325 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+var _ eventio.ResourceHistoryReader = &EventStore{}
+
+// resourceUsageEventsQuery selects the raw usage events for resource $1 that
+// explain the intervals overlapping $2. It starts from the event that began
+// the earliest of those intervals, which may be before the range.
+const resourceUsageEventsQuery = `
+	with
+	first_event as (
+		select
+			least(min(lower(duration)), lower($2::tstzrange)) as created_at
+		from
+			events
+		where
+			resource_guid = $1::uuid
+			and duration && $2::tstzrange
+	)
+	select
+		guid,
+		kind,
+		raw_message,
+		created_at
+	from (
+		select
+			id,
+			guid,
+			'app' as kind,
+			raw_message,
+			created_at
+		from
+			app_usage_events
+		where
+			raw_message->>'app_guid' = $1::uuid::text
+			or raw_message->>'parent_app_guid' = $1::uuid::text
+			or raw_message->>'task_guid' = $1::uuid::text
+		union all
+		select
+			id,
+			guid,
+			'service' as kind,
+			raw_message,
+			created_at
+		from
+			service_usage_events
+		where
+			raw_message->>'service_instance_guid' = $1::uuid::text
+	) usage_events
+	where
+		created_at >= (select created_at from first_event)
+		and created_at < upper($2::tstzrange)
+	order by
+		created_at, kind, id
+`
+
+// resourceEventsQuery selects the intervals of resource $1 overlapping $2,
+// clipped to $2. Each is compared with the interval of the same resource
+// type that ended as it started, preferring one on the same plan, to say
+// what changed.
+const resourceEventsQuery = `
+	select
+		e.event_guid,
+		to_json(lower(e.duration * $2::tstzrange)) as event_start,
+		to_json(upper(e.duration * $2::tstzrange)) as event_stop,
+		e.resource_guid,
+		e.resource_name,
+		e.resource_type,
+		e.org_guid,
+		e.org_name,
+		e.space_guid,
+		e.space_name,
+		e.plan_guid,
+		e.plan_name,
+		e.service_guid,
+		e.service_name,
+		e.number_of_nodes,
+		e.memory_in_mb,
+		e.storage_in_mb,
+		e.disk_in_mb,
+		e.log_rate_limit,
+		(case
+			when p.event_guid is null then 'start'
+			when p.plan_guid <> e.plan_guid then 'plan'
+			when (p.number_of_nodes, p.memory_in_mb, p.storage_in_mb, p.disk_in_mb, p.log_rate_limit)
+				is distinct from (e.number_of_nodes, e.memory_in_mb, e.storage_in_mb, e.disk_in_mb, e.log_rate_limit) then 'scale'
+			else 'update'
+		end) as change
+	from
+		events e
+	left join lateral (
+		select
+			*
+		from
+			events p
+		where
+			p.resource_guid = e.resource_guid
+			and p.resource_type = e.resource_type
+			and p.event_guid <> e.event_guid
+			and upper(p.duration) = lower(e.duration)
+		order by
+			(p.plan_guid = e.plan_guid) desc, p.event_guid
+		limit 1
+	) p on true
+	where
+		e.resource_guid = $1::uuid
+		and e.duration && $2::tstzrange
+	order by
+		lower(e.duration), e.event_guid
+`
+
+// resourceComponentsQuery prices each component of the intervals of
+// resource $1 overlapping $2 the same way WithBillableEvents does
+const resourceComponentsQuery = `
+	with
+	components as (
+		select
+			*,
+			duration * $2::tstzrange as billed_duration
+		from
+			billable_event_components
+		where
+			resource_guid = $1::uuid
+			and duration && $2::tstzrange
+	),
+	components_with_price as (
+		select
+			*,
+			(eval_formula(
+				memory_in_mb,
+				storage_in_mb,
+				number_of_nodes,
+				billed_duration,
+				component_formula,
+				disk_in_mb,
+				log_rate_limit
+			) * currency_rate) as price_ex_vat,
+			(case when quantity_formula is not null then eval_formula(
+				memory_in_mb,
+				storage_in_mb,
+				number_of_nodes,
+				billed_duration,
+				quantity_formula,
+				disk_in_mb,
+				log_rate_limit
+			) end) as quantity
+		from
+			components
+	)
+	select
+		event_guid,
+		component_name as name,
+		lower(billed_duration) as start,
+		upper(billed_duration) as stop,
+		plan_guid,
+		plan_name,
+		plan_valid_from,
+		component_formula as formula,
+		(memory_in_mb)::text as memory_in_mb,
+		(storage_in_mb)::text as storage_in_mb,
+		(disk_in_mb)::text as disk_in_mb,
+		(log_rate_limit)::text as log_rate_limit,
+		number_of_nodes,
+		(extract(epoch from (upper(billed_duration) - lower(billed_duration))))::text as time_in_seconds,
+		currency_code,
+		(currency_rate)::text as currency_rate,
+		vat_code,
+		(vat_rate)::text as vat_rate,
+		(price_ex_vat)::text as ex_vat,
+		(price_ex_vat * (1 + vat_rate))::text as inc_vat,
+		unit,
+		coalesce(quantity_formula, '') as quantity_formula,
+		coalesce((quantity)::text, '') as quantity
+	from
+		components_with_price
+	order by
+		lower(billed_duration), event_guid, component_name
+`
+
+// GetResourceHistory returns the raw usage events, derived intervals and
+// priced components for a single resource. It returns nil if the resource
+// has no usage events or intervals in the range.
+func (s *EventStore) GetResourceHistory(filter eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetResourceHistoryContext(ctx, filter)
+}
+
+// GetResourceHistoryContext is GetResourceHistory stopping early if ctx is
+// done
+func (s *EventStore) GetResourceHistoryContext(ctx context.Context, filter eventio.ResourceHistoryFilter) (*eventio.ResourceHistory, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetResourceHistory", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{
+		filter.ResourceGUID, // $1
+		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop), // $2
+	}
+	history := &eventio.ResourceHistory{
+		ResourceGUID: filter.ResourceGUID,
+		OrgGUIDs:     []string{},
+		UsageEvents:  []eventio.RawEvent{},
+		Events:       []eventio.ResourceHistoryEvent{},
+		Components:   []eventio.ResourceHistoryComponent{},
+	}
+	err = s.queryResourceHistory(ctx, tx, "getResourceUsageEvents", resourceUsageEventsQuery, args, func(b []byte) error {
+		var event eventio.RawEvent
+		if err := json.Unmarshal(b, &event); err != nil {
+			return err
+		}
+		history.UsageEvents = append(history.UsageEvents, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.queryResourceHistory(ctx, tx, "getResourceEvents", resourceEventsQuery, args, func(b []byte) error {
+		var event eventio.ResourceHistoryEvent
+		if err := json.Unmarshal(b, &event); err != nil {
+			return err
+		}
+		history.Events = append(history.Events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.queryResourceHistory(ctx, tx, "getResourceComponents", resourceComponentsQuery, args, func(b []byte) error {
+		var component eventio.ResourceHistoryComponent
+		if err := json.Unmarshal(b, &component); err != nil {
+			return err
+		}
+		history.Components = append(history.Components, component)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(history.UsageEvents) == 0 && len(history.Events) == 0 {
+		return nil, nil
+	}
+	history.OrgGUIDs, err = resourceOrgGUIDs(history)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (s *EventStore) queryResourceHistory(ctx context.Context, tx *sql.Tx, fn string, query string, args []interface{}, scan func(b []byte) error) error {
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, query, args...)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues(fn, err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, fn, err)
+		s.logger.Error("get-resource-history-query", err, lager.Data{
+			"fn":      fn,
+			"args":    args,
+			"elapsed": int64(elapsed),
+		})
+		return err
+	}
+	eventStorePerformanceGauge.WithLabelValues(fn, "").Set(elapsed.Seconds())
+	defer rows.Close()
+
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return err
+		}
+		if err := scan(b); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, fn, err)
+		return err
+	}
+	return nil
+}
+
+// resourceOrgGUIDs returns the orgs the resource belonged to according to
+// its intervals and usage events. Resources can't move between orgs so
+// there should only ever be one.
+func resourceOrgGUIDs(history *eventio.ResourceHistory) ([]string, error) {
+	orgGUIDs := []string{}
+	seen := map[string]bool{}
+	add := func(orgGUID string) {
+		if orgGUID != "" && !seen[orgGUID] {
+			seen[orgGUID] = true
+			orgGUIDs = append(orgGUIDs, orgGUID)
+		}
+	}
+	for _, event := range history.Events {
+		add(event.OrgGUID)
+	}
+	for _, event := range history.UsageEvents {
+		var msg struct {
+			OrgGUID string `json:"org_guid"`
+		}
+		if err := json.Unmarshal(event.RawMessage, &msg); err != nil {
+			return nil, err
+		}
+		add(msg.OrgGUID)
+	}
+	return orgGUIDs, nil
+}
@@ -0,0 +1,133 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetResourceHistory", func() {
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  eventstore.ComputePlanGUID,
+			ValidFrom: "2001-01-01",
+			Name:      "APP_PLAN_1",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "$number_of_nodes * ceil($time_in_seconds/3600) * ($memory_in_mb/1024.0) * 0.01",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+	})
+
+	appEvent := func(guid string, appGUID string, createdAt time.Time, state string, instances int) eventio.RawEvent {
+		return eventio.RawEvent{
+			GUID:      guid,
+			Kind:      "app",
+			CreatedAt: createdAt,
+			RawMessage: json.RawMessage(`{"state": "` + state + `", "app_guid": "` + appGUID + `", "app_name": "APP1", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "276f4886-ac40-492d-a8cd-b2646637ba76", "space_name": "ORG1-SPACE1", "process_type": "web", "instance_count": ` +
+				strconv.Itoa(instances) + `, "previous_state": "STARTED", "memory_in_mb_per_instance": 1024}`),
+		}
+	}
+
+	/*-----------------------------------------------------------------------------------*
+	     2001-01-01                                              2001-01-02              .
+	       00:00           01:00           02:00                   00:00                 .
+	         |               |               |                       |                   .
+	 .   .   [===APP1 x1=====][===APP1 x2=====]  .   .   .   .   .   |   .   .   .   .   .
+	 .   .   [===APP2=========================]  .   .   .   .   .   |   .   .   .   .   .
+	 .   .   |_________________ request range _______________________|   .   .   .   .   .
+	*-----------------------------------------------------------------------------------*/
+	It("should explain the charges for a resource that was scaled", func(ctx SpecContext) {
+		app1GUID := "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+		app2GUID := "d85e98f0-6d1b-4f45-9368-ea58263165a0"
+		start := appEvent("ae28a572-f485-48e1-87d0-98b7b8b66dfa", app1GUID, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "STARTED", 1)
+		scale := appEvent("be28a572-f485-48e1-87d0-98b7b8b66dfa", app1GUID, time.Date(2001, 1, 1, 1, 0, 0, 0, time.UTC), "STARTED", 2)
+		stop := appEvent("ce28a572-f485-48e1-87d0-98b7b8b66dfa", app1GUID, time.Date(2001, 1, 1, 2, 0, 0, 0, time.UTC), "STOPPED", 2)
+		otherStart := appEvent("de28a572-f485-48e1-87d0-98b7b8b66dfa", app2GUID, time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "STARTED", 1)
+		otherStop := appEvent("ee28a572-f485-48e1-87d0-98b7b8b66dfa", app2GUID, time.Date(2001, 1, 1, 2, 0, 0, 0, time.UTC), "STOPPED", 1)
+
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		Expect(store.StoreEvents([]eventio.RawEvent{start, scale, stop, otherStart, otherStop})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+
+		history, err := store.GetResourceHistory(eventio.ResourceHistoryFilter{
+			ResourceGUID: app1GUID,
+			RangeStart:   "2001-01-01",
+			RangeStop:    "2001-01-02",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(history).ToNot(BeNil())
+		Expect(history.OrgGUIDs).To(Equal([]string{"51ba75ef-edc0-47ad-a633-a8f6e8770944"}))
+
+		Expect(history.UsageEvents).To(HaveLen(3))
+		for i, ev := range []eventio.RawEvent{start, scale, stop} {
+			Expect(history.UsageEvents[i].GUID).To(Equal(ev.GUID))
+			Expect(history.UsageEvents[i].Kind).To(Equal("app"))
+			Expect(history.UsageEvents[i].RawMessage).To(MatchJSON(ev.RawMessage))
+		}
+
+		Expect(history.Events).To(HaveLen(2))
+		Expect(history.Events[0].EventGUID).To(Equal(start.GUID))
+		Expect(history.Events[0].NumberOfNodes).To(Equal(int64(1)))
+		Expect(history.Events[0].Change).To(Equal(eventio.ResourceChangeStart))
+		Expect(history.Events[1].EventGUID).To(Equal(scale.GUID))
+		Expect(history.Events[1].NumberOfNodes).To(Equal(int64(2)))
+		Expect(history.Events[1].Change).To(Equal(eventio.ResourceChangeScale))
+
+		Expect(history.Components).To(HaveLen(2))
+		for i, nodes := range []int64{1, 2} {
+			component := history.Components[i]
+			Expect(component.EventGUID).To(Equal(history.Events[i].EventGUID))
+			Expect(component.Name).To(Equal("compute"))
+			Expect(component.PlanGUID).To(Equal(eventstore.ComputePlanGUID))
+			Expect(component.PlanName).To(Equal("APP_PLAN_1"))
+			Expect(component.PlanValidFrom).To(Equal("2001-01-01T00:00:00+00:00"))
+			Expect(component.Formula).To(Equal("$number_of_nodes * ceil($time_in_seconds/3600) * ($memory_in_mb/1024.0) * 0.01"))
+			Expect(component.NumberOfNodes).To(Equal(nodes))
+			Expect(strconv.ParseFloat(component.MemoryInMB, 64)).To(Equal(1024.0))
+			Expect(strconv.ParseFloat(component.TimeInSeconds, 64)).To(Equal(3600.0))
+			Expect(component.CurrencyCode).To(Equal("GBP"))
+			Expect(strconv.ParseFloat(component.CurrencyRate, 64)).To(Equal(1.0))
+			Expect(component.VATCode).To(Equal("Standard"))
+			Expect(strconv.ParseFloat(component.VATRate, 64)).To(Equal(0.2))
+			Expect(component.ExVAT.Float64()).To(BeNumerically("~", float64(nodes)*0.01, 0.000001))
+			Expect(component.IncVAT.Float64()).To(BeNumerically("~", float64(nodes)*0.012, 0.000001))
+		}
+	})
+
+	It("should return nil for a resource it knows nothing about", func(ctx SpecContext) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+
+		history, err := db.Schema.GetResourceHistory(eventio.ResourceHistoryFilter{
+			ResourceGUID: "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+			RangeStart:   "2001-01-01",
+			RangeStop:    "2001-01-02",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(history).To(BeNil())
+	})
+})
@@ -0,0 +1,594 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/lib/pq"
+)
+
+const (
+	// DefaultStatementCurrencyCode is the currency of statements for orgs
+	// that are not in a billing account with a currency_code. Prices are
+	// worked out in it, so it needs no currency rate.
+	DefaultStatementCurrencyCode = "GBP"
+)
+
+var _ eventio.StatementReader = &EventStore{}
+var _ eventio.StatementGenerator = &EventStore{}
+
+// statementQuery selects statements in the shape of eventio.Statement. Any
+// conditions are expected to refer to the statements table as s.
+const statementQuery = `
+	select
+		s.statement_number,
+		s.account_id,
+		s.account_name,
+		s.org_guids,
+		lower(s.period)::date as period_start,
+		upper(s.period)::date as period_stop,
+		s.issued_at,
+		s.currency_code,
+		s.line_items,
+		s.vat,
+		s.subtotal::text as subtotal,
+		s.vat_total::text as vat_total,
+		s.total::text as total,
+		s.replaces,
+		(
+			select row_to_json(cn) from (
+				select
+					credit_note_number,
+					statement_number,
+					reason,
+					issued_at,
+					subtotal::text as subtotal,
+					vat_total::text as vat_total,
+					total::text as total
+				from
+					credit_notes
+				where
+					statement_number = s.statement_number
+			) cn
+		) as credit_note
+	from
+		statements s
+`
+
+func (s *EventStore) GetStatements(filter eventio.StatementFilter) ([]eventio.Statement, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetStatementsContext(ctx, filter)
+}
+
+// GetStatementsContext is GetStatements stopping early if ctx is done
+func (s *EventStore) GetStatementsContext(ctx context.Context, filter eventio.StatementFilter) ([]eventio.Statement, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetStatements", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	args := []interface{}{
+		filter.RangeStart, // $1
+		filter.RangeStop,  // $2
+	}
+	orgCondition := ""
+	if len(filter.OrgGUIDs) > 0 {
+		args = append(args, pq.Array(filter.OrgGUIDs))
+		orgCondition = fmt.Sprintf("and s.org_guids && $%d::uuid[]", len(args))
+	}
+	return s.getStatements(ctx, tx, "getStatements", fmt.Sprintf(`
+		where
+			s.period && tstzrange($1, $2)
+			%s
+		order by
+			lower(s.period), s.statement_number
+	`, orgCondition), args...)
+}
+
+func (s *EventStore) GetStatement(statementNumber int64) (*eventio.Statement, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetStatementContext(ctx, statementNumber)
+}
+
+// GetStatementContext is GetStatement stopping early if ctx is done
+func (s *EventStore) GetStatementContext(ctx context.Context, statementNumber int64) (*eventio.Statement, error) {
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetStatement", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+	return s.getStatement(ctx, tx, statementNumber)
+}
+
+func (s *EventStore) getStatement(ctx context.Context, tx *sql.Tx, statementNumber int64) (*eventio.Statement, error) {
+	statements, err := s.getStatements(ctx, tx, "getStatement", `
+		where
+			s.statement_number = $1
+	`, statementNumber)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return nil, nil
+	}
+	return &statements[0], nil
+}
+
+func (s *EventStore) getStatements(ctx context.Context, tx *sql.Tx, fn string, conditions string, args ...interface{}) ([]eventio.Statement, error) {
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, statementQuery+conditions, args...)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues(fn, err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, fn, err)
+		s.logger.Error("get-statements-query", err, lager.Data{
+			"args":    args,
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues(fn, "").Set(elapsed.Seconds())
+	defer rows.Close()
+
+	statements := []eventio.Statement{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var statement eventio.Statement
+		if err := json.Unmarshal(b, &statement); err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, fn, err)
+		return nil, err
+	}
+	return statements, nil
+}
+
+func (s *EventStore) GenerateStatements(month string) ([]eventio.Statement, error) {
+	filter, err := eventio.ParseStatementMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultRefreshTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockStatements(tx); err != nil {
+		return nil, err
+	}
+	isConsolidated, err := s.isRangeConsolidated(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if !isConsolidated {
+		return nil, fmt.Errorf("statements can only be generated for consolidated months - %s has not been consolidated", month)
+	}
+	statementNumbers, err := s.generateStatements(tx, filter, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	statements := []eventio.Statement{}
+	for _, statementNumber := range statementNumbers {
+		statement, err := s.getStatement(ctx, tx, statementNumber)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, *statement)
+	}
+	return statements, tx.Commit()
+}
+
+func (s *EventStore) GenerateAllStatements() error {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `
+		select
+			to_char(lower(consolidated_range), 'YYYY-MM')
+		from
+			consolidation_history ch
+		where
+			not exists (
+				select 1 from statements s where s.period = ch.consolidated_range
+			)
+		order by
+			consolidated_range
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	months := []string{}
+	for rows.Next() {
+		var month string
+		if err := rows.Scan(&month); err != nil {
+			return err
+		}
+		months = append(months, month)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, month := range months {
+		if _, err := s.GenerateStatements(month); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *EventStore) CreditStatement(statementNumber int64, reason string) (*eventio.CreditNote, *eventio.Statement, error) {
+	if reason == "" {
+		return nil, nil, fmt.Errorf("a reason is required to credit a statement")
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultRefreshTimeout)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockStatements(tx); err != nil {
+		return nil, nil, err
+	}
+	credited, err := s.getStatement(ctx, tx, statementNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if credited == nil {
+		return nil, nil, fmt.Errorf("statement %d does not exist", statementNumber)
+	}
+	if credited.CreditNote != nil {
+		return nil, nil, fmt.Errorf("statement %d has already been credited by credit note %d", statementNumber, credited.CreditNote.CreditNoteNumber)
+	}
+
+	startTime := time.Now()
+	_, err = tx.Exec(`
+		insert into credit_notes (
+			credit_note_number,
+			statement_number,
+			reason,
+			issued_at,
+			subtotal,
+			vat_total,
+			total
+		)
+		select
+			(select coalesce(max(credit_note_number), 0) + 1 from credit_notes),
+			statement_number,
+			$2,
+			$3::timestamptz,
+			subtotal,
+			vat_total,
+			total
+		from
+			statements
+		where
+			statement_number = $1
+	`, statementNumber, reason, time.Now())
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("creditStatement", err.Error()).Set(elapsed.Seconds())
+		s.logger.Error("credit-statement-query", err, lager.Data{
+			"statement_number": statementNumber,
+			"elapsed":          int64(elapsed),
+		})
+		return nil, nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("creditStatement", "").Set(elapsed.Seconds())
+	s.logger.Info("credit-statement-query", lager.Data{
+		"statement_number": statementNumber,
+		"reason":           reason,
+		"elapsed":          int64(elapsed),
+	})
+
+	credited, err = s.getStatement(ctx, tx, statementNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	period := eventio.EventFilter{
+		RangeStart: credited.PeriodStart,
+		RangeStop:  credited.PeriodStop,
+	}
+	replacementNumbers, err := s.generateStatements(tx, period, credited.AccountID, &statementNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	var replacement *eventio.Statement
+	if len(replacementNumbers) > 0 {
+		replacement, err = s.getStatement(ctx, tx, replacementNumbers[0])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return credited.CreditNote, replacement, tx.Commit()
+}
+
+// lockStatements prevents concurrent statement generation so that statement
+// and credit note numbers remain sequential without gaps
+func lockStatements(tx *sql.Tx) error {
+	_, err := tx.Exec(`lock table statements, credit_notes in exclusive mode`)
+	return err
+}
+
+// generateStatements issues statements for the consolidated events in the
+// given month. Events for orgs already covered by an outstanding (not
+// credited) statement for the month are skipped, so this is safe to call
+// repeatedly. If accountID is given only that account is considered. The
+// new statement numbers are returned in order.
+func (s *EventStore) generateStatements(tx *sql.Tx, filter eventio.EventFilter, accountID string, replaces *int64) ([]int64, error) {
+	accounts, err := s.billingAccountsByOrg()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkStatementCurrencyRates(tx, filter); err != nil {
+		return nil, err
+	}
+	replacesArg := sql.NullInt64{}
+	if replaces != nil {
+		replacesArg = sql.NullInt64{Int64: *replaces, Valid: true}
+	}
+
+	startTime := time.Now()
+	rows, err := tx.Query(`
+		with
+		accounts as (
+			select
+				key::uuid as org_guid,
+				value->>'id' as account_id,
+				value->>'name' as account_name,
+				value->>'currency_code' as currency_code
+			from
+				jsonb_each($2::jsonb)
+		),
+		account_currency_rates as (
+			-- the rate valid at the start of the month, which converts
+			-- the currency to the one prices are in
+			select distinct on (code)
+				code::text as currency_code,
+				rate
+			from
+				currency_rates
+			where
+				valid_from <= lower($1::tstzrange)
+			order by
+				code, valid_from desc
+		),
+		components as (
+			select
+				coalesce(a.account_id, e.org_guid::text) as account_id,
+				coalesce(a.account_name, e.org_name) as account_name,
+				coalesce(a.currency_code, $5) as currency_code,
+				e.org_guid,
+				e.resource_type,
+				d->>'plan_name' as plan_name,
+				d->>'vat_code' as vat_code,
+				(d->>'vat_rate')::numeric as vat_rate,
+				(case
+					when coalesce(a.currency_code, $5) = $5 then (d->>'ex_vat')::numeric
+					else (d->>'ex_vat')::numeric / acr.rate
+				end) as ex_vat
+			from
+				consolidated_billable_events e
+			cross join lateral
+				jsonb_array_elements(e.price->'details') d
+			left join
+				accounts a on a.org_guid = e.org_guid
+			left join
+				account_currency_rates acr on acr.currency_code = a.currency_code
+			where
+				e.consolidated_range = $1::tstzrange
+				and not exists (
+					select 1 from statements s
+					where s.period = $1::tstzrange
+					and e.org_guid = any (s.org_guids)
+					and not exists (
+						select 1 from credit_notes cn where cn.statement_number = s.statement_number
+					)
+				)
+		),
+		statement_accounts as (
+			select
+				account_id,
+				max(account_name) as account_name,
+				max(currency_code) as currency_code,
+				array_agg(distinct org_guid order by org_guid) as org_guids
+			from
+				components
+			where
+				$3 = '' or account_id = $3
+			group by
+				account_id
+		),
+		line_items as (
+			select
+				account_id,
+				resource_type,
+				plan_name,
+				vat_code,
+				vat_rate,
+				round(sum(ex_vat), 2) as ex_vat
+			from
+				components
+			group by
+				account_id, resource_type, plan_name, vat_code, vat_rate
+		),
+		vat as (
+			select
+				account_id,
+				vat_code,
+				vat_rate,
+				sum(ex_vat) as ex_vat,
+				round(sum(ex_vat) * vat_rate, 2) as vat
+			from
+				line_items
+			group by
+				account_id, vat_code, vat_rate
+		)
+		insert into statements (
+			statement_number,
+			account_id,
+			account_name,
+			org_guids,
+			period,
+			issued_at,
+			currency_code,
+			line_items,
+			vat,
+			subtotal,
+			vat_total,
+			total,
+			replaces
+		)
+		select
+			(select coalesce(max(statement_number), 0) from statements)
+				+ row_number() over (order by sa.account_id),
+			sa.account_id,
+			sa.account_name,
+			sa.org_guids,
+			$1::tstzrange,
+			$4::timestamptz,
+			sa.currency_code,
+			(
+				select jsonb_agg(jsonb_build_object(
+					'resource_type', li.resource_type,
+					'plan_name', li.plan_name,
+					'vat_code', li.vat_code,
+					'vat_rate', li.vat_rate::text,
+					'ex_vat', li.ex_vat::text
+				) order by li.resource_type, li.plan_name, li.vat_code)
+				from line_items li
+				where li.account_id = sa.account_id
+			),
+			(
+				select jsonb_agg(jsonb_build_object(
+					'vat_code', v.vat_code,
+					'vat_rate', v.vat_rate::text,
+					'ex_vat', v.ex_vat::text,
+					'vat', v.vat::text
+				) order by v.vat_code, v.vat_rate)
+				from vat v
+				where v.account_id = sa.account_id
+			),
+			totals.subtotal,
+			totals.vat_total,
+			totals.subtotal + totals.vat_total,
+			$6::bigint
+		from
+			statement_accounts sa
+		cross join lateral (
+			select
+				(select coalesce(sum(ex_vat), 0) from line_items li where li.account_id = sa.account_id) as subtotal,
+				(select coalesce(sum(vat), 0) from vat v where v.account_id = sa.account_id) as vat_total
+		) totals
+		returning
+			statement_number
+	`,
+		fmt.Sprintf("[%s, %s)", filter.RangeStart, filter.RangeStop),
+		accounts,
+		accountID,
+		time.Now(),
+		DefaultStatementCurrencyCode,
+		replacesArg,
+	)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("generateStatements", err.Error()).Set(elapsed.Seconds())
+		s.logger.Error("generate-statements-query", err, lager.Data{
+			"filter":  filter,
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("generateStatements", "").Set(elapsed.Seconds())
+	defer rows.Close()
+
+	statementNumbers := []int64{}
+	for rows.Next() {
+		var statementNumber int64
+		if err := rows.Scan(&statementNumber); err != nil {
+			return nil, err
+		}
+		statementNumbers = append(statementNumbers, statementNumber)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	s.logger.Info("generate-statements-query", lager.Data{
+		"filter":     filter,
+		"statements": statementNumbers,
+		"elapsed":    int64(elapsed),
+	})
+	return statementNumbers, nil
+}
+
+// checkStatementCurrencyRates returns an error if a billing account is in a
+// currency that has no currency rate valid at the start of the month, rather
+// than issuing it a statement with no total
+func (s *EventStore) checkStatementCurrencyRates(tx *sql.Tx, filter eventio.EventFilter) error {
+	for _, ba := range s.cfg.BillingAccounts {
+		if ba.CurrencyCode == "" || ba.CurrencyCode == DefaultStatementCurrencyCode {
+			continue
+		}
+		var hasRate bool
+		err := tx.QueryRow(`
+			select exists (
+				select 1 from currency_rates
+				where code::text = $1 and valid_from <= $2::timestamptz
+			)
+		`, ba.CurrencyCode, filter.RangeStart).Scan(&hasRate)
+		if err != nil {
+			return err
+		}
+		if !hasRate {
+			return fmt.Errorf("billing account %s is billed in %s but there is no %s currency rate valid from %s", ba.ID, ba.CurrencyCode, ba.CurrencyCode, filter.RangeStart)
+		}
+	}
+	return nil
+}
+
+// billingAccountsByOrg returns the configured billing accounts keyed by org
+// guid as a JSON object
+func (s *EventStore) billingAccountsByOrg() (string, error) {
+	type account struct {
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		CurrencyCode string `json:"currency_code,omitempty"`
+	}
+	accounts := map[string]account{}
+	for _, ba := range s.cfg.BillingAccounts {
+		for _, orgGUID := range ba.OrgGUIDs {
+			if existing, ok := accounts[orgGUID]; ok {
+				return "", fmt.Errorf("org %s is in more than one billing account: %s and %s", orgGUID, existing.ID, ba.ID)
+			}
+			accounts[orgGUID] = account{ID: ba.ID, Name: ba.Name, CurrencyCode: ba.CurrencyCode}
+		}
+	}
+	b, err := json.Marshal(accounts)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,228 @@
+package eventstore_test
+
+import (
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Statements", func() {
+	var (
+		cfg      eventstore.Config
+		scenario *testenv.TestScenario
+	)
+
+	januaryFilter := eventio.EventFilter{
+		RangeStart: "2001-01-01",
+		RangeStop:  "2001-02-01",
+	}
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+		scenario = testenv.NewTestScenario("2001-01-01T00:00")
+		scenario.AddComputePlan()
+		scenario.AppLifeCycle("org1", "space1", "app1",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+24h", State: "STOPPED"},
+		)
+		scenario.AppLifeCycle("org2", "space2", "app2",
+			testenv.EventInfo{Delta: "+0h", State: "STARTED"},
+			testenv.EventInfo{Delta: "+48h", State: "STOPPED"},
+		)
+	})
+
+	It("should refuse to generate statements for a month that has not been consolidated", func(ctx SpecContext) {
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+
+		_, err = db.Schema.GenerateStatements("2001-01")
+		Expect(err).To(MatchError(ContainSubstring("has not been consolidated")))
+	})
+
+	It("should generate one numbered statement per org with line items, VAT and totals", func(ctx SpecContext) {
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.Consolidate(januaryFilter)).To(Succeed())
+
+		statements, err := db.Schema.GenerateStatements("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statements).To(HaveLen(2))
+
+		byAccount := map[string]eventio.Statement{}
+		numbers := []int64{}
+		for _, statement := range statements {
+			byAccount[statement.AccountID] = statement
+			numbers = append(numbers, statement.StatementNumber)
+		}
+		Expect(numbers).To(ConsistOf(int64(1), int64(2)))
+
+		org1 := byAccount[scenario.GetOrgGUID("org1")]
+		Expect(org1.OrgGUIDs).To(Equal([]string{scenario.GetOrgGUID("org1")}))
+		Expect(org1.PeriodStart).To(Equal("2001-01-01"))
+		Expect(org1.PeriodStop).To(Equal("2001-02-01"))
+		Expect(org1.CurrencyCode).To(Equal("GBP"))
+		Expect(org1.LineItems).ToNot(BeEmpty())
+		Expect(org1.LineItems[0].ResourceType).To(Equal("app"))
+		Expect(org1.VAT).To(HaveLen(1))
+		Expect(org1.VAT[0].VatCode).To(Equal("Standard"))
+		Expect(org1.VAT[0].VatRate).To(Equal("0.2"))
+		Expect(org1.CreditNote).To(BeNil())
+		Expect(org1.Replaces).To(BeNil())
+
+		stored, err := db.Schema.GetStatement(org1.StatementNumber)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*stored).To(Equal(org1))
+	})
+
+	It("should not issue a second statement when generating again", func(ctx SpecContext) {
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.Consolidate(januaryFilter)).To(Succeed())
+
+		_, err = db.Schema.GenerateStatements("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		statements, err := db.Schema.GenerateStatements("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statements).To(BeEmpty())
+	})
+
+	It("should combine the orgs in a billing account into one statement", func(ctx SpecContext) {
+		cfg.BillingAccounts = []eventio.BillingAccount{{
+			ID:       "account-1",
+			Name:     "Department of Examples",
+			OrgGUIDs: []string{scenario.GetOrgGUID("org1"), scenario.GetOrgGUID("org2")},
+		}}
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.Consolidate(januaryFilter)).To(Succeed())
+
+		statements, err := db.Schema.GenerateStatements("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statements).To(HaveLen(1))
+		Expect(statements[0].AccountID).To(Equal("account-1"))
+		Expect(statements[0].AccountName).To(Equal("Department of Examples"))
+		Expect(statements[0].OrgGUIDs).To(ConsistOf(scenario.GetOrgGUID("org1"), scenario.GetOrgGUID("org2")))
+
+		visible, err := db.Schema.GetStatements(eventio.StatementFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+			OrgGUIDs:   []string{scenario.GetOrgGUID("org1")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(visible).To(HaveLen(1), "a statement must be listed for each of its orgs")
+		Expect(visible[0].StatementNumber).To(Equal(statements[0].StatementNumber))
+	})
+
+	It("should issue the statement of a billing account in its currency", func(ctx SpecContext) {
+		cfg.CurrencyRates = append(cfg.CurrencyRates, eventio.CurrencyRate{
+			Code:      "USD",
+			Rate:      0.5,
+			ValidFrom: "2000-01-01",
+		})
+		cfg.BillingAccounts = []eventio.BillingAccount{{
+			ID:           "account-1",
+			Name:         "Department of Examples",
+			OrgGUIDs:     []string{scenario.GetOrgGUID("org1")},
+			CurrencyCode: "USD",
+		}}
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.Consolidate(januaryFilter)).To(Succeed())
+
+		statements, err := db.Schema.GenerateStatements("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statements).To(HaveLen(2))
+		byAccount := map[string]eventio.Statement{}
+		for _, statement := range statements {
+			byAccount[statement.AccountID] = statement
+		}
+		Expect(byAccount["account-1"].CurrencyCode).To(Equal("USD"))
+		Expect(byAccount[scenario.GetOrgGUID("org2")].CurrencyCode).To(Equal("GBP"))
+
+		gbp, err := eventio.Money(byAccount[scenario.GetOrgGUID("org2")].Subtotal).Float64()
+		Expect(err).ToNot(HaveOccurred())
+		usd, err := eventio.Money(byAccount["account-1"].Subtotal).Float64()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usd).To(BeNumerically("~", gbp, 0.01), "org1 used half as much as org2, at half the USD rate")
+	})
+
+	It("should refuse to issue a statement in a currency without a rate", func(ctx SpecContext) {
+		cfg.BillingAccounts = []eventio.BillingAccount{{
+			ID:           "account-1",
+			Name:         "Department of Examples",
+			OrgGUIDs:     []string{scenario.GetOrgGUID("org1")},
+			CurrencyCode: "EUR",
+		}}
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.Consolidate(januaryFilter)).To(Succeed())
+
+		_, err = db.Schema.GenerateStatements("2001-01")
+		Expect(err).To(MatchError(ContainSubstring("no EUR currency rate")))
+	})
+
+	It("should generate statements for the consolidated months without any", func(ctx SpecContext) {
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.Consolidate(januaryFilter)).To(Succeed())
+
+		Expect(db.Schema.GenerateAllStatements()).To(Succeed())
+		statements, err := db.Schema.GetStatements(eventio.StatementFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(statements).To(HaveLen(2))
+
+		Expect(db.Schema.GenerateAllStatements()).To(Succeed())
+		again, err := db.Schema.GetStatements(eventio.StatementFilter{RangeStart: "2001-01-01", RangeStop: "2001-02-01"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(again).To(Equal(statements))
+	})
+
+	It("should only regenerate a statement through a credit note", func(ctx SpecContext) {
+		db, err := scenario.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		Expect(db.Schema.Refresh()).To(Succeed())
+		Expect(db.Schema.Consolidate(januaryFilter)).To(Succeed())
+
+		statements, err := db.Schema.GenerateStatements("2001-01")
+		Expect(err).ToNot(HaveOccurred())
+		original := statements[0]
+
+		_, err = db.Conn.Exec(`update statements set total = 0`)
+		Expect(err).To(MatchError(ContainSubstring("immutable")))
+
+		creditNote, replacement, err := db.Schema.CreditStatement(original.StatementNumber, "wrong org name")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creditNote.CreditNoteNumber).To(Equal(int64(1)))
+		Expect(creditNote.StatementNumber).To(Equal(original.StatementNumber))
+		Expect(creditNote.Total).To(Equal(original.Total))
+		Expect(replacement).ToNot(BeNil())
+		Expect(replacement.StatementNumber).To(Equal(int64(3)))
+		Expect(*replacement.Replaces).To(Equal(original.StatementNumber))
+		Expect(replacement.AccountID).To(Equal(original.AccountID))
+		Expect(replacement.Total).To(Equal(original.Total))
+
+		credited, err := db.Schema.GetStatement(original.StatementNumber)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credited.CreditNote).To(Equal(creditNote))
+
+		_, _, err = db.Schema.CreditStatement(original.StatementNumber, "again")
+		Expect(err).To(MatchError(ContainSubstring("already been credited")))
+	})
+})
@@ -123,40 +123,54 @@ var _ = Describe("Store", func() {
 			db.Query(`select * from events`),
 		).To(MatchJSON(testenv.Rows{
 			{
-				"duration":        "[\"2001-01-01 00:00:00+00\",\"2001-01-01 01:00:00+00\")",
-				"event_guid":      "c497eb13-f48a-4859-be53-5569f302b516",
-				"memory_in_mb":    nil,
-				"number_of_nodes": nil,
-				"org_guid":        "51ba75ef-edc0-47ad-a633-a8f6e8770944",
-				"org_name":        "51ba75ef-edc0-47ad-a633-a8f6e8770944",
-				"plan_guid":       "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
-				"plan_name":       "Free",
-				"service_guid":    "efadb775-58c4-4e17-8087-6d0f4febc489",
-				"service_name":    "postgres",
-				"resource_guid":   "f3f98365-6a95-4bbd-ab8f-527a7957a41f",
-				"resource_name":   "ja-rails-postgres",
-				"resource_type":   "service",
-				"space_guid":      "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d",
-				"space_name":      "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d",
-				"storage_in_mb":   nil,
+				"duration":               "[\"2001-01-01 00:00:00+00\",\"2001-01-01 01:00:00+00\")",
+				"event_guid":             "c497eb13-f48a-4859-be53-5569f302b516",
+				"memory_in_mb":           nil,
+				"number_of_nodes":        nil,
+				"org_guid":               "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				"org_name":               "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				"plan_guid":              "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+				"plan_name":              "Free",
+				"service_guid":           "efadb775-58c4-4e17-8087-6d0f4febc489",
+				"service_name":           "postgres",
+				"resource_guid":          "f3f98365-6a95-4bbd-ab8f-527a7957a41f",
+				"resource_name":          "ja-rails-postgres",
+				"resource_type":          "service",
+				"space_guid":             "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d",
+				"space_name":             "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d",
+				"storage_in_mb":          nil,
+				"disk_in_mb":             nil,
+				"log_rate_limit":         nil,
+				"quota_definition_guid":  nil,
+				"isolation_segment_guid": nil,
+				"labels":                 map[string]string{},
+				"egress_bytes":           nil,
+				"metrics":                nil,
 			},
 			{
-				"duration":        "[\"2001-01-01 00:00:00+00\",\"2001-01-01 01:00:00+00\")",
-				"event_guid":      "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
-				"memory_in_mb":    1024,
-				"number_of_nodes": 1,
-				"org_guid":        "51ba75ef-edc0-47ad-a633-a8f6e8770944",
-				"org_name":        "51ba75ef-edc0-47ad-a633-a8f6e8770944",
-				"plan_guid":       "f4d4b95a-f55e-4593-8d54-3364c25798c4",
-				"plan_name":       "app",
-				"service_guid":    "4f6f0a18-cdd4-4e51-8b6b-dc39b696e61b",
-				"service_name":    "app",
-				"resource_guid":   "c85e98f0-6d1b-4f45-9368-ea58263165a0",
-				"resource_name":   "APP1",
-				"resource_type":   "app",
-				"space_guid":      "276f4886-ac40-492d-a8cd-b2646637ba76",
-				"space_name":      "276f4886-ac40-492d-a8cd-b2646637ba76",
-				"storage_in_mb":   0,
+				"duration":               "[\"2001-01-01 00:00:00+00\",\"2001-01-01 01:00:00+00\")",
+				"event_guid":             "ee28a570-f485-48e1-87d0-98b7b8b66dfa",
+				"memory_in_mb":           1024,
+				"number_of_nodes":        1,
+				"org_guid":               "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				"org_name":               "51ba75ef-edc0-47ad-a633-a8f6e8770944",
+				"plan_guid":              "f4d4b95a-f55e-4593-8d54-3364c25798c4",
+				"plan_name":              "app",
+				"service_guid":           "4f6f0a18-cdd4-4e51-8b6b-dc39b696e61b",
+				"service_name":           "app",
+				"resource_guid":          "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+				"resource_name":          "APP1",
+				"resource_type":          "app",
+				"space_guid":             "276f4886-ac40-492d-a8cd-b2646637ba76",
+				"space_name":             "276f4886-ac40-492d-a8cd-b2646637ba76",
+				"storage_in_mb":          0,
+				"disk_in_mb":             nil,
+				"log_rate_limit":         nil,
+				"quota_definition_guid":  nil,
+				"isolation_segment_guid": nil,
+				"labels":                 map[string]string{},
+				"egress_bytes":           nil,
+				"metrics":                nil,
 			},
 		}))
 	})
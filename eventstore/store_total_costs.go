@@ -1,6 +1,8 @@
 package eventstore
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
 	"code.cloudfoundry.org/lager"
@@ -10,12 +12,25 @@ import (
 var _ eventio.TotalCostReader = &EventStore{}
 
 func (s *EventStore) GetTotalCost() ([]eventio.TotalCost, error) {
+	return s.GetTotalCostContext(s.ctx)
+}
+
+// GetTotalCostContext is GetTotalCost stopping early if ctx is done
+func (s *EventStore) GetTotalCostContext(ctx context.Context) ([]eventio.TotalCost, error) {
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetTotalCost", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
 	startTime := time.Now()
-	rows, err := s.db.Query(`select plan_guid, split_part(plan_name, ' ', 1) as service, split_part(plan_name, ' ', 2) as plan, round(sum(cost_for_duration),2) as cost from billable_event_components group by plan_guid, plan_name order by plan_guid`)
+	rows, err := tx.QueryContext(ctx, `select plan_guid, split_part(plan_name, ' ', 1) as service, split_part(plan_name, ' ', 2) as plan, round(sum(cost_for_duration),2) as cost from billable_event_components group by plan_guid, plan_name order by plan_guid`)
 
 	if err != nil {
 		elapsed := time.Since(startTime)
 		eventStorePerformanceGauge.WithLabelValues("GetTotalCost", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "GetTotalCost", err)
 		s.logger.Error("get-total-cost", err, lager.Data{
 			"elapsed": int64(elapsed),
 		})
@@ -33,6 +48,10 @@ func (s *EventStore) GetTotalCost() ([]eventio.TotalCost, error) {
 		planGUIDSByCost = append(planGUIDSByCost, planGUIDByCost)
 
 	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "GetTotalCost", err)
+		return nil, err
+	}
 	elapsed := time.Since(startTime)
 	eventStorePerformanceGauge.WithLabelValues("GetTotalCost", "").Set(elapsed.Seconds())
 	s.logger.Info("get-total-cost", lager.Data{
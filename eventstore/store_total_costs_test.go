@@ -106,13 +106,13 @@ var _ = Describe("TotalCostEvents", func() {
 		Expect(len(outputEvents)).To(Equal(2))
 		Expect(outputEvents[0]).To(Equal(eventio.TotalCost{
 			PlanGUID: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
-			Cost:     1417,
+			Cost:     "1417.00",
 			PlanName: "DB_PLAN_1",
 			Kind:     "postgres",
 		}))
 		Expect(outputEvents[1]).To(Equal(eventio.TotalCost{
 			PlanGUID: "f4d4b95a-f55e-4593-8d54-3364c25798c4",
-			Cost:     7.45,
+			Cost:     "7.45",
 			Kind:     "APP_PLAN_1",
 			PlanName: "",
 		}))
@@ -0,0 +1,239 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var _ eventio.UnpricedPlanReader = &EventStore{}
+
+var (
+	unpricedPlansGauge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "paas_billing",
+			Subsystem: "eventstore",
+			Name:      "unpriced_plans",
+			Help:      "Number of plans with usage that no configured pricing plan covers",
+		})
+)
+
+// unbilledEventsQuery selects the part of each event that no configured
+// pricing plan covers. Pricing plan versions run from the first valid_from
+// until the next, and the last runs forever, so a plan covers everything
+// from its first valid_from on. Events that exemptions cover for their whole
+// duration need no pricing plan.
+const unbilledEventsQuery = `
+	with
+	priced_plans as (
+		select
+			plan_guid,
+			min(valid_from) as priced_from
+		from
+			pricing_plans
+		where
+			not generated
+		group by
+			plan_guid
+	)
+	select
+		e.*,
+		e.duration * tstzrange('-infinity', coalesce(pp.priced_from, 'infinity')) as unbilled_duration
+	from
+		events e
+	left join
+		priced_plans pp on pp.plan_guid = e.plan_guid
+	where
+		e.duration && tstzrange('-infinity', coalesce(pp.priced_from, 'infinity'))
+		and e.event_guid not in (` + fullyExemptEventsQuery + `)
+`
+
+// recordUnpricedPlans adds plans with unbilled usage to the registry of
+// unpriced plans, and marks plans that are now fully priced. It runs during
+// Refresh, before missing plans are generated or reported, so the registry
+// is up to date even if the refresh then fails because of them.
+func (s *EventStore) recordUnpricedPlans(ctx context.Context) error {
+	startTime := time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		create temporary table unbilled_events
+		on commit drop
+		as ` + unbilledEventsQuery,
+	); err != nil {
+		return wrapPqError(err, "record-unpriced-plans")
+	}
+	if _, err := tx.Exec(`
+		insert into unpriced_plans (
+			plan_guid, plan_name, resource_type, first_seen_at, last_seen_at
+		) (
+			select distinct on (plan_guid)
+				plan_guid,
+				plan_name,
+				resource_type,
+				min(lower(duration)) over (partition by plan_guid),
+				now()
+			from
+				unbilled_events
+			order by
+				plan_guid, upper(duration) desc
+		)
+		on conflict (plan_guid) do update set
+			plan_name = excluded.plan_name,
+			first_seen_at = least(unpriced_plans.first_seen_at, excluded.first_seen_at),
+			last_seen_at = excluded.last_seen_at,
+			priced_at = null
+	`); err != nil {
+		return wrapPqError(err, "record-unpriced-plans")
+	}
+	if _, err := tx.Exec(`
+		update
+			unpriced_plans
+		set
+			priced_at = now()
+		where
+			priced_at is null
+			and plan_guid not in (select plan_guid from unbilled_events)
+	`); err != nil {
+		return wrapPqError(err, "record-unpriced-plans")
+	}
+	var unpriced int64
+	if err := tx.QueryRow(`
+		select count(*) from unpriced_plans where priced_at is null
+	`).Scan(&unpriced); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	unpricedPlansGauge.Set(float64(unpriced))
+	elapsed := time.Since(startTime)
+	eventStorePerformanceGauge.WithLabelValues("recordUnpricedPlans", "").Set(elapsed.Seconds())
+	s.logger.Info("record-unpriced-plans", lager.Data{
+		"unpriced_plans": unpriced,
+		"elapsed":        int64(elapsed),
+	})
+	return nil
+}
+
+// GetUnpricedPlans returns every plan that has been in the registry of
+// unpriced plans, still unpriced first, with the usage that is unbilled
+func (s *EventStore) GetUnpricedPlans() ([]eventio.UnpricedPlan, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, DefaultQueryTimeout)
+	defer cancel()
+	return s.GetUnpricedPlansContext(ctx)
+}
+
+// GetUnpricedPlansContext is GetUnpricedPlans stopping early if ctx is done
+func (s *EventStore) GetUnpricedPlansContext(ctx context.Context) ([]eventio.UnpricedPlan, error) {
+	tx, err := s.beginQueryTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		observeCancellation(ctx, "GetUnpricedPlans", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, `
+		with
+		unbilled_events as (
+			`+unbilledEventsQuery+`
+		),
+		unbilled_usage as (
+			select
+				plan_guid,
+				array_agg(distinct org_guid::text order by org_guid::text) as org_guids,
+				count(*) as unbilled_events,
+				min(lower(unbilled_duration)) as unbilled_from,
+				sum(to_seconds(unbilled_duration)) / 3600 as unbilled_hours,
+				sum(to_seconds(unbilled_duration) * coalesce(number_of_nodes, 1)) / 3600 as unbilled_node_hours
+			from
+				unbilled_events
+			group by
+				plan_guid
+		)
+		select
+			u.plan_guid,
+			u.plan_name,
+			u.resource_type,
+			coalesce(sp.guid::text, '') as service_plan_guid,
+			coalesce(sp.name, '') as service_plan_name,
+			coalesce(sv.label, '') as service_label,
+			u.first_seen_at,
+			u.last_seen_at,
+			u.priced_at,
+			coalesce(uu.org_guids, '{}') as org_guids,
+			coalesce(uu.unbilled_events, 0) as unbilled_events,
+			uu.unbilled_from,
+			coalesce(uu.unbilled_hours, 0)::text as unbilled_hours,
+			coalesce(uu.unbilled_node_hours, 0)::text as unbilled_node_hours
+		from
+			unpriced_plans u
+		left join
+			unbilled_usage uu on uu.plan_guid = u.plan_guid
+		left join lateral (
+			select
+				*
+			from
+				service_plans
+			where
+				unique_id = u.plan_guid::text
+			order by
+				valid_from desc
+			limit 1
+		) sp on true
+		left join lateral (
+			select
+				*
+			from
+				services
+			where
+				guid = sp.service_guid
+			order by
+				valid_from desc
+			limit 1
+		) sv on true
+		order by
+			u.priced_at desc nulls first, u.first_seen_at, u.plan_guid
+	`)
+	elapsed := time.Since(startTime)
+	if err != nil {
+		eventStorePerformanceGauge.WithLabelValues("getUnpricedPlans", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "getUnpricedPlans", err)
+		s.logger.Error("get-unpriced-plans-query", err, lager.Data{
+			"elapsed": int64(elapsed),
+		})
+		return nil, err
+	}
+	eventStorePerformanceGauge.WithLabelValues("getUnpricedPlans", "").Set(elapsed.Seconds())
+	defer rows.Close()
+
+	plans := []eventio.UnpricedPlan{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var plan eventio.UnpricedPlan
+		if err := json.Unmarshal(b, &plan); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "getUnpricedPlans", err)
+		return nil, err
+	}
+	return plans, nil
+}
@@ -0,0 +1,147 @@
+package eventstore_test
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventstore"
+	"github.com/alphagov/paas-billing/testenv"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetUnpricedPlans", func() {
+
+	var (
+		cfg eventstore.Config
+		db  *testenv.TempDB
+		err error
+	)
+
+	BeforeEach(func() {
+		cfg = testenv.BasicConfig
+	})
+
+	serviceEvent := func(guid string, createdAt time.Time, state string) eventio.RawEvent {
+		return eventio.RawEvent{
+			GUID:       guid,
+			Kind:       "service",
+			CreatedAt:  createdAt,
+			RawMessage: json.RawMessage(`{"state": "` + state + `", "org_guid": "51ba75ef-edc0-47ad-a633-a8f6e8770944", "space_guid": "bd405d91-0b7c-4b8c-96ef-8b4c1e26e75d", "space_name": "sandbox", "service_guid": "efadb775-58c4-4e17-8087-6d0f4febc489", "service_label": "postgres", "service_plan_guid": "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5", "service_plan_name": "NEW_PLAN", "service_instance_guid": "f3f98365-6a95-4bbd-ab8f-527a7957a41f", "service_instance_name": "DB1", "service_instance_type": "managed_service_instance"}`),
+		}
+	}
+
+	It("should record unpriced plans even though the refresh fails, and mark them priced once configured", func(ctx SpecContext) {
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		Expect(db.Insert("services", testenv.Row{
+			"guid":                "efadb775-58c4-4e17-8087-6d0f4febc489",
+			"valid_from":          "2000-01-01T00:00:00Z",
+			"created_at":          "2000-01-01T00:00:00Z",
+			"updated_at":          "2000-01-01T00:00:00Z",
+			"label":               "postgres",
+			"description":         "",
+			"active":              true,
+			"bindable":            true,
+			"service_broker_guid": "879d7b06-642d-4bf6-b5e8-1a52451c849a",
+		})).To(Succeed())
+		Expect(db.Insert("service_plans", testenv.Row{
+			"guid":               "efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5",
+			"valid_from":         "2000-01-01T00:00:00Z",
+			"created_at":         "2000-01-01T00:00:00Z",
+			"updated_at":         "2000-01-01T00:00:00Z",
+			"name":               "NEW_PLAN",
+			"description":        "",
+			"service_guid":       "efadb775-58c4-4e17-8087-6d0f4febc489",
+			"service_valid_from": "2000-01-01T00:00:00Z",
+			"unique_id":          "c6221308-b7bb-46d2-9d79-a357f5a3837b",
+			"active":             true,
+			"public":             true,
+			"free":               false,
+			"extra":              "",
+		})).To(Succeed())
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			serviceEvent("c497eb13-f48a-4859-be53-5569f302b516", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "CREATED"),
+			serviceEvent("dd52b4f4-9e33-4504-8fca-fd9e33af11a6", time.Date(2001, 1, 1, 2, 0, 0, 0, time.UTC), "DELETED"),
+		})).To(Succeed())
+
+		Expect(store.Refresh()).To(MatchError(ContainSubstring("missing 'service' pricing plan configuration for 'NEW_PLAN'")))
+
+		plans, err := store.GetUnpricedPlans()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].PlanGUID).To(Equal("c6221308-b7bb-46d2-9d79-a357f5a3837b"))
+		Expect(plans[0].PlanName).To(Equal("NEW_PLAN"))
+		Expect(plans[0].ResourceType).To(Equal("service"))
+		Expect(plans[0].ServicePlanGUID).To(Equal("efb5f1ce-0a8a-435d-a8b2-6b2b61c6dbe5"))
+		Expect(plans[0].ServiceLabel).To(Equal("postgres"))
+		Expect(plans[0].PricedAt).To(BeNil())
+		Expect(plans[0].OrgGUIDs).To(Equal([]string{"51ba75ef-edc0-47ad-a633-a8f6e8770944"}))
+		Expect(plans[0].UnbilledEvents).To(Equal(int64(1)))
+		Expect(plans[0].UnbilledFrom).ToNot(BeNil())
+		Expect(*plans[0].UnbilledFrom).To(Equal("2001-01-01T00:00:00+00:00"))
+		Expect(eventio.Money(plans[0].UnbilledHours).Float64()).To(Equal(2.0))
+		Expect(plans[0].FirstSeenAt).To(Equal("2001-01-01T00:00:00+00:00"))
+		firstSeenAt := plans[0].FirstSeenAt
+
+		By("configuring a pricing plan covering the usage")
+		cfg.AddPlan(eventio.PricingPlan{
+			PlanGUID:  "c6221308-b7bb-46d2-9d79-a357f5a3837b",
+			ValidFrom: "2001-01-01",
+			Name:      "NEW_PLAN",
+			Components: []eventio.PricingPlanComponent{
+				{
+					Name:         "compute",
+					Formula:      "ceil($time_in_seconds/3600) * 1",
+					CurrencyCode: "GBP",
+					VATCode:      "Standard",
+				},
+			},
+		})
+		configured := eventstore.New(ctx, db.Conn, lager.NewLogger("test"), cfg)
+		Expect(configured.Init()).To(Succeed())
+		Expect(configured.Refresh()).To(Succeed())
+
+		plans, err = configured.GetUnpricedPlans()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].FirstSeenAt).To(Equal(firstSeenAt))
+		Expect(plans[0].PricedAt).ToNot(BeNil())
+		Expect(plans[0].OrgGUIDs).To(BeEmpty())
+		Expect(plans[0].UnbilledEvents).To(Equal(int64(0)))
+
+		events, err := configured.GetBillableEvents(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Price.ExVAT).To(Equal(eventio.Money("2")))
+	})
+
+	It("should record plans priced with generated plans when ignoring missing plans", func(ctx SpecContext) {
+		cfg.IgnoreMissingPlans = true
+		db, err = testenv.OpenWithContext(cfg, ctx)
+		Expect(err).ToNot(HaveOccurred())
+		defer db.Close()
+		store := db.Schema
+
+		Expect(store.StoreEvents([]eventio.RawEvent{
+			serviceEvent("c497eb13-f48a-4859-be53-5569f302b516", time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC), "CREATED"),
+			serviceEvent("dd52b4f4-9e33-4504-8fca-fd9e33af11a6", time.Date(2001, 1, 1, 2, 0, 0, 0, time.UTC), "DELETED"),
+		})).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+		Expect(store.Refresh()).To(Succeed())
+
+		plans, err := store.GetUnpricedPlans()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(plans).To(HaveLen(1))
+		Expect(plans[0].PricedAt).To(BeNil())
+		Expect(plans[0].UnbilledEvents).To(Equal(int64(1)))
+	})
+})
@@ -1,6 +1,7 @@
 package eventstore
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -18,11 +19,18 @@ var _ eventio.UsageEventReader = &EventStore{}
 // rows.Close when you are done to release the connection. Use GetUsageEvents
 // if you intend on buffering everything into memory.
 func (s *EventStore) GetUsageEventRows(filter eventio.EventFilter) (eventio.UsageEventRows, error) {
-	tx, err := s.db.Begin()
+	return s.GetUsageEventRowsContext(s.ctx, filter)
+}
+
+// GetUsageEventRowsContext is GetUsageEventRows stopping the query if ctx
+// is done
+func (s *EventStore) GetUsageEventRowsContext(ctx context.Context, filter eventio.EventFilter) (eventio.UsageEventRows, error) {
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "GetUsageEventRows", err)
 		return nil, err
 	}
-	rows, err := s.getUsageEventRows(tx, filter)
+	rows, err := s.getUsageEventRows(ctx, tx, filter)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
@@ -30,7 +38,7 @@ func (s *EventStore) GetUsageEventRows(filter eventio.EventFilter) (eventio.Usag
 	return rows, nil
 }
 
-func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (eventio.UsageEventRows, error) {
+func (s *EventStore) getUsageEventRows(ctx context.Context, tx *sql.Tx, filter eventio.EventFilter) (eventio.UsageEventRows, error) {
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
@@ -46,13 +54,17 @@ func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (
 	if len(orgPlaceholders) > 0 {
 		filterConditions = append(filterConditions, fmt.Sprintf("org_guid = any (values %s)", strings.Join(orgPlaceholders, ",")))
 	}
+	labelConditions, args, err := labelSelectorConditions(filter.LabelSelector, args)
+	if err != nil {
+		return nil, err
+	}
+	filterConditions = append(filterConditions, labelConditions...)
 	filterQuery := ""
 	if len(filterConditions) > 0 {
 		filterQuery = " and " + strings.Join(filterConditions, " and ")
 	}
 
-	startTime := time.Now()
-	rows, err := queryJSON(tx, fmt.Sprintf(`
+	query, args := withAggregatedUsageEvents(fmt.Sprintf(`
 		select
 			event_guid,
 			to_json(lower(duration * $1::tstzrange)) as event_start,
@@ -70,7 +82,10 @@ func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (
 			service_name,
 			number_of_nodes,
 			memory_in_mb,
-			storage_in_mb
+			storage_in_mb,
+			disk_in_mb,
+			log_rate_limit,
+			nullif(labels, '{}'::jsonb) as labels
 		from
 			events
 		where
@@ -78,10 +93,14 @@ func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (
 			%s
 		order by
 			lower(duration), event_guid
-	`, filterQuery), args...)
+	`, filterQuery), args, filter, s.aggregationRules(filter))
+
+	startTime := time.Now()
+	rows, err := queryJSON(ctx, tx, query, args...)
 	elapsed := time.Since(startTime)
 	if err != nil {
 		eventStorePerformanceGauge.WithLabelValues("getUsageEventRows", err.Error()).Set(elapsed.Seconds())
+		observeCancellation(ctx, "getUsageEventRows", err)
 		s.logger.Error("get-usage-event-rows-query", err, lager.Data{
 			"filter":  filter,
 			"elapsed": int64(elapsed),
@@ -93,7 +112,7 @@ func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (
 		"filter":  filter,
 		"elapsed": int64(elapsed),
 	})
-	return &UsageEventRows{rows, tx}, nil
+	return &UsageEventRows{rows: rows, tx: tx, ctx: ctx, fn: "getUsageEventRows"}, nil
 }
 
 // GetUsageEvents returns a slice of usage events for the given filter.
@@ -101,7 +120,12 @@ func (s *EventStore) getUsageEventRows(tx *sql.Tx, filter eventio.EventFilter) (
 // you use the GetUsageEventRows version to avoid buffering everything into
 // memory
 func (s *EventStore) GetUsageEvents(filter eventio.EventFilter) ([]eventio.UsageEvent, error) {
-	rows, err := s.GetUsageEventRows(filter)
+	return s.GetUsageEventsContext(s.ctx, filter)
+}
+
+// GetUsageEventsContext is GetUsageEvents stopping early if ctx is done
+func (s *EventStore) GetUsageEventsContext(ctx context.Context, filter eventio.EventFilter) ([]eventio.UsageEvent, error) {
+	rows, err := s.GetUsageEventRowsContext(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -123,6 +147,8 @@ func (s *EventStore) GetUsageEvents(filter eventio.EventFilter) ([]eventio.Usage
 type UsageEventRows struct {
 	rows *sql.Rows
 	tx   *sql.Tx
+	ctx  context.Context // the context the query was started with
+	fn   string          // the query's name in the cancelled queries metric
 }
 
 // Next moves the row cursor to the next iteration. Returns false if no more
@@ -134,7 +160,11 @@ func (ber *UsageEventRows) Next() bool {
 // Err returns any errors that occurred behind the scenes during processing.
 // Call this at the end of your iteration.
 func (ber *UsageEventRows) Err() error {
-	return ber.rows.Err()
+	err := ber.rows.Err()
+	if ber.ctx != nil {
+		observeCancellation(ber.ctx, ber.fn, err)
+	}
+	return err
 }
 
 // Close ends the query connection. You must call this. So stick it in a defer.
@@ -1,6 +1,7 @@
 package eventstore
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 
@@ -11,17 +12,23 @@ import (
 var _ eventio.VATRateReader = &EventStore{}
 
 func (s *EventStore) GetVATRates(filter eventio.TimeRangeFilter) ([]eventio.VATRate, error) {
+	return s.GetVATRatesContext(s.ctx, filter)
+}
+
+// GetVATRatesContext is GetVATRates stopping early if ctx is done
+func (s *EventStore) GetVATRatesContext(ctx context.Context, filter eventio.TimeRangeFilter) ([]eventio.VATRate, error) {
 	if err := filter.Validate(); err != nil {
 		return nil, err
 	}
-	tx, err := s.db.Begin()
+	tx, err := s.beginQueryTx(ctx, nil)
 	if err != nil {
+		observeCancellation(ctx, "GetVATRates", err)
 		return nil, err
 	}
 	defer tx.Rollback()
 
 	startTime := time.Now()
-	rows, err := queryJSON(tx, `
+	rows, err := queryJSON(ctx, tx, `
         with
         valid_vat_rates as (
             select
@@ -49,6 +56,7 @@ func (s *EventStore) GetVATRates(filter eventio.TimeRangeFilter) ([]eventio.VATR
     `, filter.RangeStart, filter.RangeStop)
 	elapsed := time.Since(startTime)
 	if err != nil {
+		observeCancellation(ctx, "GetVATRates", err)
 		eventStorePerformanceGauge.WithLabelValues("GetVATRates", err.Error()).Set(elapsed.Seconds())
 		s.logger.Error("get-vat-rates-query", err, lager.Data{
 			"filter":  filter,
@@ -75,5 +83,9 @@ func (s *EventStore) GetVATRates(filter eventio.TimeRangeFilter) ([]eventio.VATR
 		}
 		vatRates = append(vatRates, vatRate)
 	}
+	if err := rows.Err(); err != nil {
+		observeCancellation(ctx, "GetVATRates", err)
+		return nil, err
+	}
 	return vatRates, nil
 }
@@ -313,6 +313,7 @@ func theBillShouldBe(pounds, pence int) error {
 				b.resource_type,
 				b.org_guid,
 				b.org_name,
+				b.quota_definition_guid,
 				b.space_guid,
 				b.space_name,
 				b.plan_guid,
@@ -352,7 +353,7 @@ func theBillShouldBe(pounds, pence int) error {
 				resource_type,
 				org_guid,
 				org_name,
-				null::uuid as quota_definition_guid,
+				quota_definition_guid,
 				space_guid,
 				space_name,
 				plan_guid,
@@ -25,7 +25,7 @@ func Main(ctx context.Context, logger lager.Logger) error {
 	}
 
 	if len(os.Args) < 2 {
-		return errors.New("Please provide a command to run [api | collector | proxymetrics]")
+		return errors.New("Please provide a command to run [api | collector | proxymetrics | export | import-costs | ingest-egress]")
 	}
 	switch command := os.Args[1]; command {
 	case "collector":
@@ -34,6 +34,12 @@ func Main(ctx context.Context, logger lager.Logger) error {
 		return startAPI(app, cfg)
 	case "proxymetrics":
 		return startProxyMetrics(app, cfg)
+	case "export":
+		return runAccountingExport(app.store, cfg.AccountingExport, os.Args[2:], os.Stdout)
+	case "import-costs":
+		return runProviderCostImport(app.store, cfg.ProviderCosts, os.Args[2:], os.Stdout)
+	case "ingest-egress":
+		return runEgressIngest(app.store, os.Args[2:], os.Stdin, os.Stdout)
 	default:
 		return fmt.Errorf("Subcommand %s not recognised", command)
 	}
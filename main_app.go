@@ -7,8 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alphagov/paas-billing/accountingexport"
 	"github.com/alphagov/paas-billing/instancediscoverer"
 	"github.com/alphagov/paas-billing/metricsproxy"
+	"github.com/alphagov/paas-billing/providercosts"
 
 	"github.com/alphagov/paas-billing/cfstore"
 
@@ -88,9 +90,13 @@ func (app *App) StartAPIServer() error {
 		Config: uaaConfig,
 	}
 	apiServer := apiserver.New(apiserver.Config{
-		Store:         app.store,
-		Authenticator: apiAuthenticator,
-		Logger:        logger,
+		Store:                      app.store,
+		Authenticator:              apiAuthenticator,
+		Logger:                     logger,
+		AccountingExport:           app.cfg.AccountingExport,
+		ConsolidatedMonthCacheSize: app.cfg.ConsolidatedMonthCacheSize,
+		QueryTimeouts:              app.cfg.QueryTimeouts,
+		MeteringBrokers:            app.cfg.MeteringBrokers,
 	})
 	return app.start(name, logger, func() error {
 		return apiserver.ListenAndServe(
@@ -182,6 +188,8 @@ func runRefreshAndConsolidateLoop(ctx context.Context, logger lager.Logger, sche
 			logger.Error("refresh-error", err)
 		} else if err := store.ConsolidateAll(); err != nil {
 			logger.Error("consolidate-error", err)
+		} else if err := store.GenerateAllStatements(); err != nil {
+			logger.Error("generate-statements-error", err)
 		} else {
 			logger.Info("processed", lager.Data{
 				"next_processing_in": schedule.String(),
@@ -212,6 +220,18 @@ func (app *App) StartHistoricDataCollector() error {
 			if err := app.historicDataStore.CollectSpaces(); err != nil {
 				logger.Error("collect-spaces", err)
 			}
+			if err := app.historicDataStore.CollectProcesses(); err != nil {
+				logger.Error("collect-processes", err)
+			}
+			if err := app.historicDataStore.CollectTasks(); err != nil {
+				logger.Error("collect-tasks", err)
+			}
+			if err := app.historicDataStore.CollectLabels(); err != nil {
+				logger.Error("collect-labels", err)
+			}
+			if err := app.historicDataStore.CollectServiceInstanceShares(); err != nil {
+				logger.Error("collect-service-instance-shares", err)
+			}
 
 			time.Sleep(app.cfg.HistoricDataCollector.Schedule)
 		}
@@ -273,6 +293,16 @@ func New(ctx context.Context, cfg Config) (*App, error) {
 	}
 	cfg.Store = store
 
+	cfg.AccountingExport, err = accountingexport.LoadMapping(planConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ProviderCosts, err = providercosts.LoadConfig(planConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
 	client, err := cfclient.NewClient(cfg.HistoricDataCollector.ClientConfig)
 	if err != nil {
 		return nil, err
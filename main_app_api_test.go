@@ -227,7 +227,7 @@ var _ = It("Should perform a smoke test against a real environment", func() {
 			Expect(ev.EventGUID).ToNot(BeEmpty())
 			Expect(ev.EventStart).To(Equal("2001-01-01T00:00:00+00:00"))
 			Expect(ev.EventStop).To(Equal("2001-02-01T00:00:00+00:00"))
-			Expect(ev.Price.ExVAT).To(Equal("0.01"))
+			Expect(ev.Price.ExVAT).To(Equal(eventio.Money("0.01")))
 		}
 	})
 
@@ -183,6 +183,29 @@ var _ = Describe("runRefreshAndConsolidateLoop", func() {
 			return fakeStore.ConsolidateAllCallCount()
 		}).Should(BeNumerically("==", 0))
 	})
+
+	It("should generate statements only after a successful Consolidate", func() {
+		fakeStore.ConsolidateAllReturnsOnCall(0, fmt.Errorf("some-error"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		wg := sync.WaitGroup{}
+		defer wg.Wait()
+		defer cancel()
+
+		wg.Add(1)
+		go func() {
+			runRefreshAndConsolidateLoop(ctx, logger, 1*time.Nanosecond, fakeStore)
+			wg.Done()
+		}()
+
+		Eventually(func() int {
+			return fakeStore.GenerateAllStatementsCallCount()
+		}).Should(BeNumerically(">=", 1))
+
+		generated := fakeStore.GenerateAllStatementsCallCount()
+		Expect(fakeStore.ConsolidateAllCallCount()).To(BeNumerically(">", generated))
+	})
 })
 
 var _ = Describe("runPeriodicMetricsLoop", func() {
@@ -10,7 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alphagov/paas-billing/accountingexport"
+	"github.com/alphagov/paas-billing/apiserver"
 	"github.com/alphagov/paas-billing/instancediscoverer"
+	"github.com/alphagov/paas-billing/providercosts"
 
 	"github.com/alphagov/paas-billing/cfstore"
 	"github.com/alphagov/paas-billing/eventcollector"
@@ -39,6 +42,16 @@ type Config struct {
 	HistoricDataCollector cfstore.Config
 	InstanceDiscoverer    instancediscoverer.Config
 	VCAPApplication       *VCAPApplication
+	AccountingExport      accountingexport.Mapping
+	ProviderCosts         providercosts.Config
+	// ConsolidatedMonthCacheSize is the number of consolidated months of
+	// billable events the API keeps in memory
+	ConsolidatedMonthCacheSize int
+	// QueryTimeouts overrides the API's default query timeout per route
+	QueryTimeouts map[string]time.Duration
+	// MeteringBrokers are the service brokers that can post metering
+	// requests, with their keys and services
+	MeteringBrokers map[string]apiserver.MeteringBroker
 }
 
 type VCAPApplication struct {
@@ -81,6 +94,16 @@ func NewConfigFromEnv() (cfg Config, err error) {
 		rootDir = getwd()
 	}
 
+	queryTimeouts, err := apiserver.ParseQueryTimeouts(os.Getenv("API_QUERY_TIMEOUTS"))
+	if err != nil {
+		return cfg, err
+	}
+
+	meteringBrokers, err := apiserver.ParseMeteringBrokers(os.Getenv("METERING_BROKER_KEYS"), os.Getenv("METERING_BROKER_SERVICES"))
+	if err != nil {
+		return cfg, err
+	}
+
 	vcapApplication := VCAPApplication{}
 
 	_ = json.Unmarshal([]byte(os.Getenv("VCAP_APPLICATION")), &vcapApplication)
@@ -158,7 +181,10 @@ func NewConfigFromEnv() (cfg Config, err error) {
 			},
 			ThisAppName: vcapApplication.ApplicationName,
 		},
-		VCAPApplication: &vcapApplication,
+		VCAPApplication:            &vcapApplication,
+		ConsolidatedMonthCacheSize: getEnvWithDefaultInt("CONSOLIDATED_MONTH_CACHE_SIZE", 0),
+		QueryTimeouts:              queryTimeouts,
+		MeteringBrokers:            meteringBrokers,
 	}
 	cfg.ListenAddr = fmt.Sprintf("%s:%d", cfg.ServerHost, cfg.ServerPort)
 	return cfg, nil
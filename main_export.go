@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alphagov/paas-billing/accountingexport"
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// runAccountingExport writes the statements for a consolidated month as a
+// ledger import file, eg:
+//
+//	paas-billing export -month 2018-01 -format sage50 -output jan.csv
+func runAccountingExport(store eventio.EventStore, mapping accountingexport.Mapping, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	month := flags.String("month", "", "consolidated month to export, eg 2018-01 (required)")
+	formatName := flags.String("format", "journal", "one of "+strings.Join(accountingexport.FormatNames(), ", "))
+	output := flags.String("output", "", "file to write to (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter, err := eventio.ParseStatementMonth(*month)
+	if err != nil {
+		return err
+	}
+	format, err := accountingexport.GetFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	isConsolidated, err := store.IsRangeConsolidated(filter)
+	if err != nil {
+		return err
+	}
+	if !isConsolidated {
+		return fmt.Errorf("%s has not been consolidated yet", *month)
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return accountingexport.New(store, mapping).Export(w, *month, format)
+}
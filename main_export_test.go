@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/alphagov/paas-billing/accountingexport"
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("runAccountingExport", func() {
+	var (
+		fakeStore *eventiofakes.FakeEventStore
+		mapping   accountingexport.Mapping
+		stdout    *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		fakeStore.IsRangeConsolidatedReturns(true, nil)
+		fakeStore.GetStatementsContextReturns([]eventio.Statement{{
+			StatementNumber: 42,
+			AccountID:       "account-1",
+			CurrencyCode:    "GBP",
+			PeriodStart:     "2001-01-01",
+			IssuedAt:        "2001-02-06T00:00:00+00:00",
+			LineItems: []eventio.StatementLineItem{
+				{ResourceType: "app", PlanName: "app", VatCode: "Standard", VatRate: "0.2", ExVAT: "10.00"},
+			},
+			VAT: []eventio.StatementVAT{
+				{VatCode: "Standard", VatRate: "0.2", ExVAT: "10.00", VAT: "2.00"},
+			},
+		}}, nil)
+		mapping = accountingexport.Mapping{
+			DefaultNominalCode: "4000",
+			VATTaxCodes:        map[string]string{"Standard": "T1"},
+			CustomerRefs:       map[string]string{"account-1": "DEPT01"},
+		}
+		stdout = &bytes.Buffer{}
+	})
+
+	It("should write the export to stdout", func() {
+		err := runAccountingExport(fakeStore, mapping, []string{"-month", "2001-01", "-format", "sage50"}, stdout)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeStore.IsRangeConsolidatedArgsForCall(0)).To(Equal(eventio.EventFilter{
+			RangeStart: "2001-01-01",
+			RangeStop:  "2001-02-01",
+		}))
+		Expect(stdout.String()).To(ContainSubstring("SI,DEPT01,4000,,06/02/2001,42,app app Jan 2001,10.00,T1,2.00"))
+	})
+
+	It("should write the export to a file", func() {
+		output := filepath.Join(GinkgoT().TempDir(), "export.csv")
+		err := runAccountingExport(fakeStore, mapping, []string{"-month", "2001-01", "-format", "sage50", "-output", output}, stdout)
+		Expect(err).ToNot(HaveOccurred())
+
+		b, err := os.ReadFile(output)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(HavePrefix("Type,Account Reference"))
+		Expect(stdout.String()).To(BeEmpty())
+	})
+
+	It("should refuse to export a month that is not consolidated", func() {
+		fakeStore.IsRangeConsolidatedReturns(false, nil)
+
+		err := runAccountingExport(fakeStore, mapping, []string{"-month", "2001-01"}, stdout)
+		Expect(err).To(MatchError("2001-01 has not been consolidated yet"))
+		Expect(fakeStore.GetStatementsContextCallCount()).To(Equal(0))
+	})
+
+	It("should reject an unknown format", func() {
+		err := runAccountingExport(fakeStore, mapping, []string{"-month", "2001-01", "-format", "abacus"}, stdout)
+		Expect(err).To(MatchError(ContainSubstring("unknown accounting export format 'abacus'")))
+	})
+})
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/providercosts"
+)
+
+// importBatchSize is the number of line items stored per transaction
+const importBatchSize = 1000
+
+// runProviderCostImport stores the line items of provider cost exports that
+// are tagged with a service instance guid, eg:
+//
+//	paas-billing import-costs 2018-01.parquet 2018-02.parquet
+func runProviderCostImport(store eventio.RawEventWriter, cfg providercosts.Config, args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("import-costs", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	flags.StringVar(&cfg.Provider, "provider", cfg.Provider, "name to record against the costs (default "+providercosts.DefaultProvider+")")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return fmt.Errorf("provide one or more .csv, .csv.gz or .parquet cost exports to import")
+	}
+
+	for _, filename := range flags.Args() {
+		costs, skipped, err := cfg.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		events := make([]eventio.RawEvent, 0, len(costs))
+		for _, cost := range costs {
+			event, err := cost.RawEvent()
+			if err != nil {
+				return fmt.Errorf("%s: %s", filename, err)
+			}
+			events = append(events, event)
+		}
+		for start := 0; start < len(events); start += importBatchSize {
+			end := start + importBatchSize
+			if end > len(events) {
+				end = len(events)
+			}
+			if err := store.StoreEvents(events[start:end]); err != nil {
+				return fmt.Errorf("%s: %s", filename, err)
+			}
+		}
+		fmt.Fprintf(stdout, "%s: imported %d line items, skipped %d\n", filename, len(events), skipped)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+	"github.com/alphagov/paas-billing/providercosts"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("runProviderCostImport", func() {
+	var (
+		fakeStore *eventiofakes.FakeEventStore
+		stdout    *bytes.Buffer
+		filename  string
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		stdout = &bytes.Buffer{}
+		filename = filepath.Join(GinkgoT().TempDir(), "costs.csv")
+		Expect(os.WriteFile(filename, []byte(""+
+			"identity/LineItemId,lineItem/UsageStartDate,lineItem/UsageEndDate,lineItem/UnblendedCost,lineItem/CurrencyCode,resourceTags/user:service_instance_guid\n"+
+			"li-1,2001-01-01T00:00:00Z,2001-01-01T01:00:00Z,0.036,USD,c85e98f0-6d1b-4f45-9368-ea58263165a0\n"+
+			"li-2,2001-01-01T00:00:00Z,2001-01-01T01:00:00Z,0.5,USD,\n",
+		), 0644)).To(Succeed())
+	})
+
+	It("should store the tagged line items as provider cost events", func() {
+		err := runProviderCostImport(fakeStore, providercosts.Config{}, []string{filename}, stdout)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeStore.StoreEventsCallCount()).To(Equal(1))
+		events := fakeStore.StoreEventsArgsForCall(0)
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Kind).To(Equal(eventio.ProviderCostKind))
+		Expect(string(events[0].RawMessage)).To(ContainSubstring(`"line_item_id":"li-1"`))
+		Expect(stdout.String()).To(Equal(filename + ": imported 1 line items, skipped 1\n"))
+	})
+
+	It("should record the provider given", func() {
+		err := runProviderCostImport(fakeStore, providercosts.Config{}, []string{"-provider", "aws-paas", filename}, stdout)
+		Expect(err).ToNot(HaveOccurred())
+
+		events := fakeStore.StoreEventsArgsForCall(0)
+		Expect(string(events[0].RawMessage)).To(ContainSubstring(`"provider":"aws-paas"`))
+	})
+
+	It("should need a file to import", func() {
+		err := runProviderCostImport(fakeStore, providercosts.Config{}, []string{}, stdout)
+		Expect(err).To(MatchError(ContainSubstring("provide one or more")))
+	})
+
+	It("should fail if the costs cannot be stored", func() {
+		fakeStore.StoreEventsReturns(errors.New("no database"))
+
+		err := runProviderCostImport(fakeStore, providercosts.Config{}, []string{filename}, stdout)
+		Expect(err).To(MatchError(filename + ": no database"))
+	})
+})
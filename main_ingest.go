@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/routerlogs"
+)
+
+// egressBatchLines is the number of access log lines whose usage is stored
+// per transaction
+const egressBatchLines = 100000
+
+// runEgressIngest stores the bytes each app sent in responses each hour from
+// gorouter access logs. Each log is only read from where it was last
+// ingested, so logs can be ingested again after more lines are appended to
+// them or they are rotated. Arguments may be log files or directories of
+// them, and with no arguments, or "-", the log is read from stdin, eg:
+//
+//	paas-billing ingest-egress /var/log/gorouter
+//	zcat access.log.gz | paas-billing ingest-egress
+func runEgressIngest(store eventio.EgressUsageWriter, args []string, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ingest-egress", flag.ContinueOnError)
+	flags.SetOutput(stdout)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	for _, path := range paths {
+		filenames, err := accessLogFiles(path)
+		if err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			if filename == "-" {
+				err = ingestEgressLog(store, filename, stdin, stdout)
+			} else {
+				err = ingestEgressFile(store, filename, stdout)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %s", filename, err)
+			}
+		}
+	}
+	return nil
+}
+
+func ingestEgressFile(store eventio.EgressUsageWriter, filename string, stdout io.Writer) error {
+	f, err := routerlogs.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return ingestEgressLog(store, filename, f, stdout)
+}
+
+// ingestEgressLog stores the usage of the lines of a log after those already
+// ingested, a batch at a time
+func ingestEgressLog(store eventio.EgressUsageWriter, filename string, r io.Reader, stdout io.Writer) error {
+	lr, err := routerlogs.NewReader(r)
+	if err != nil {
+		return err
+	}
+	if lr.Source() == "" {
+		fmt.Fprintf(stdout, "%s: no complete lines to ingest\n", filename)
+		return nil
+	}
+	offset, err := store.GetEgressOffset(lr.Source())
+	if err != nil {
+		return err
+	}
+	if err := lr.Skip(offset); err != nil {
+		return err
+	}
+	for {
+		batch, err := lr.Next(egressBatchLines)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := store.StoreEgressBatch(batch); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(stdout, "%s: ingested the egress of %d app requests from %d lines, skipped %d, after %d bytes ingested before\n", filename, lr.Requests(), lr.Lines(), lr.Skipped(), offset)
+	return nil
+}
+
+// accessLogFiles returns the files in path if it is a directory, or path
+func accessLogFiles(path string) ([]string, error) {
+	if path == "-" {
+		return []string{path}, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	filenames := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			filenames = append(filenames, filepath.Join(path, entry.Name()))
+		}
+	}
+	return filenames, nil
+}
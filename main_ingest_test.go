@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/eventio/eventiofakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("runEgressIngest", func() {
+	const accessLog = `app.example.com - [2001-01-01T10:00:00.000+0000] "GET / HTTP/1.1" 200 0 1024 "-" "curl/7.54.0" ` +
+		`"10.0.0.1:53212" "10.0.16.5:61012" response_time:0.005 app_id:"c85e98f0-6d1b-4f45-9368-ea58263165a0" app_index:"0"` + "\n"
+
+	var (
+		fakeStore *eventiofakes.FakeEventStore
+		stdout    *bytes.Buffer
+		dir       string
+	)
+
+	BeforeEach(func() {
+		fakeStore = &eventiofakes.FakeEventStore{}
+		stdout = &bytes.Buffer{}
+		dir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "access.log"), []byte(accessLog+"router started\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "access.log.1"), []byte(accessLog+accessLog), 0644)).To(Succeed())
+	})
+
+	It("should store the egress usage of each log in a directory", func() {
+		err := runEgressIngest(fakeStore, []string{dir}, nil, stdout)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeStore.GetEgressOffsetCallCount()).To(Equal(2))
+		Expect(fakeStore.StoreEgressBatchCallCount()).To(Equal(2))
+		batch1 := fakeStore.StoreEgressBatchArgsForCall(0)
+		batch2 := fakeStore.StoreEgressBatchArgsForCall(1)
+		Expect(batch1.Source).To(Equal(fakeStore.GetEgressOffsetArgsForCall(0)))
+		Expect(batch1.From).To(Equal(int64(0)))
+		Expect(batch1.To).To(Equal(int64(len(accessLog + "router started\n"))))
+		Expect(batch1.Usage).To(Equal([]eventio.EgressUsage{{
+			AppGUID:  "c85e98f0-6d1b-4f45-9368-ea58263165a0",
+			Hour:     "2001-01-01T10:00:00Z",
+			Bytes:    1024,
+			Requests: 1,
+		}}))
+		Expect(batch2.Source).To(Equal(batch1.Source))
+		Expect(batch2.Usage[0].Requests).To(Equal(int64(2)))
+		Expect(stdout.String()).To(Equal("" +
+			filepath.Join(dir, "access.log") + ": ingested the egress of 1 app requests from 2 lines, skipped 1, after 0 bytes ingested before\n" +
+			filepath.Join(dir, "access.log.1") + ": ingested the egress of 2 app requests from 2 lines, skipped 0, after 0 bytes ingested before\n",
+		))
+	})
+
+	It("should only store the lines after those already ingested", func() {
+		fakeStore.GetEgressOffsetReturns(int64(len(accessLog)), nil)
+		err := runEgressIngest(fakeStore, []string{filepath.Join(dir, "access.log.1")}, nil, stdout)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeStore.StoreEgressBatchCallCount()).To(Equal(1))
+		batch := fakeStore.StoreEgressBatchArgsForCall(0)
+		Expect(batch.From).To(Equal(int64(len(accessLog))))
+		Expect(batch.To).To(Equal(int64(2 * len(accessLog))))
+		Expect(batch.Usage[0].Requests).To(Equal(int64(1)))
+	})
+
+	It("should store nothing for logs that have all been ingested", func() {
+		fakeStore.GetEgressOffsetReturns(int64(2*len(accessLog)), nil)
+		err := runEgressIngest(fakeStore, []string{filepath.Join(dir, "access.log.1")}, nil, stdout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fakeStore.StoreEgressBatchCallCount()).To(Equal(0))
+	})
+
+	It("should read the log from stdin without arguments", func() {
+		err := runEgressIngest(fakeStore, []string{}, strings.NewReader(accessLog), stdout)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fakeStore.StoreEgressBatchCallCount()).To(Equal(1))
+		Expect(fakeStore.StoreEgressBatchArgsForCall(0).Usage).To(HaveLen(1))
+		Expect(stdout.String()).To(HavePrefix("-: ingested the egress of 1 app requests"))
+	})
+
+	It("should fail for missing logs", func() {
+		err := runEgressIngest(fakeStore, []string{filepath.Join(dir, "missing.log")}, nil, stdout)
+		Expect(err).To(HaveOccurred())
+		Expect(fakeStore.StoreEgressBatchCallCount()).To(Equal(0))
+	})
+
+	It("should fail if the usage cannot be stored", func() {
+		fakeStore.StoreEgressBatchReturns(errors.New("no database"))
+		err := runEgressIngest(fakeStore, []string{dir}, nil, stdout)
+		Expect(err).To(MatchError(ContainSubstring("no database")))
+	})
+})
@@ -0,0 +1,212 @@
+package providercosts
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// parquetBatchSize is how many rows are read from a row group at a time
+const parquetBatchSize = 1000
+
+// julianUnixEpoch is the julian day of 1970-01-01, which INT96 timestamps
+// count from
+const julianUnixEpoch = 2440588
+
+// parquetColumn is a leaf of the schema and the name it is given in a row
+type parquetColumn struct {
+	path   []string
+	name   string
+	typ    parquet.Type
+	maxRep int
+	// mapValue is the index of the value column of a map for the key
+	// column, or -1
+	mapValue int
+}
+
+// readParquet calls fn with each row of the Parquet file in r, keyed by
+// column name. Columns inside groups are named by their path joined with
+// underscores and map entries, such as the resource_tags of a CUR 2.0
+// export, by the map's name and the key. Other repeated columns are skipped.
+func readParquet(r io.ReaderAt, size int64, fn func(row map[string]string) error) error {
+	f, err := parquet.OpenFile(r, size)
+	if err != nil {
+		return err
+	}
+	columns := parquetColumns(f.Schema())
+
+	batch := make([]parquet.Row, parquetBatchSize)
+	for _, rowGroup := range f.RowGroups() {
+		err := func() error {
+			rows := rowGroup.Rows()
+			defer rows.Close()
+			for {
+				n, err := rows.ReadRows(batch)
+				for _, values := range batch[:n] {
+					row, err := parquetRow(columns, values)
+					if err != nil {
+						return err
+					}
+					if err := fn(row); err != nil {
+						return err
+					}
+				}
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parquetColumns lists the leaves of a schema in the order of the values of
+// each row, pairing the key and value columns of maps
+func parquetColumns(schema *parquet.Schema) []parquetColumn {
+	paths := schema.Columns()
+	columns := make([]parquetColumn, len(paths))
+	byPath := map[string]int{}
+	for i, path := range paths {
+		leaf, _ := schema.Lookup(path...)
+		columns[i] = parquetColumn{
+			path:     path,
+			name:     columnName(strings.Join(path, "_")),
+			typ:      leaf.Node.Type(),
+			maxRep:   leaf.MaxRepetitionLevel,
+			mapValue: -1,
+		}
+		byPath[strings.Join(path, ".")] = i
+	}
+	for i, col := range columns {
+		n := len(col.path)
+		if col.maxRep != 1 || n != 3 || col.path[n-1] != "key" {
+			continue
+		}
+		if v, ok := byPath[strings.Join(append(col.path[:n-1:n-1], "value"), ".")]; ok {
+			columns[i].name = columnName(col.path[0])
+			columns[i].mapValue = v
+		}
+	}
+	return columns
+}
+
+// parquetRow converts the values of a row to strings keyed by column name.
+// Null values are left out.
+func parquetRow(columns []parquetColumn, values parquet.Row) (map[string]string, error) {
+	row := map[string]string{}
+	byColumn := make([][]parquet.Value, len(columns))
+	for _, v := range values {
+		byColumn[v.Column()] = append(byColumn[v.Column()], v)
+	}
+	for i, col := range columns {
+		switch {
+		case col.maxRep == 0:
+			if len(byColumn[i]) == 0 || byColumn[i][0].IsNull() {
+				continue
+			}
+			s, err := formatParquetValue(col.typ, byColumn[i][0])
+			if err != nil {
+				return nil, fmt.Errorf("parquet column %s: %s", strings.Join(col.path, "."), err)
+			}
+			row[col.name] = s
+		case col.mapValue >= 0:
+			keys, mapValues := byColumn[i], byColumn[col.mapValue]
+			if len(keys) != len(mapValues) {
+				return nil, fmt.Errorf("parquet map %s has %d keys and %d values", col.path[0], len(keys), len(mapValues))
+			}
+			for j, k := range keys {
+				if k.IsNull() || mapValues[j].IsNull() {
+					continue
+				}
+				row[col.name+"_"+columnName(string(k.ByteArray()))] = string(mapValues[j].ByteArray())
+			}
+		}
+	}
+	return row, nil
+}
+
+// formatParquetValue formats a value as it would appear in a CSV export,
+// using the logical type of its column for decimals, dates and timestamps
+func formatParquetValue(t parquet.Type, v parquet.Value) (string, error) {
+	logicalType := t.LogicalType()
+	if logicalType == nil {
+		logicalType = &format.LogicalType{}
+	}
+	switch v.Kind() {
+	case parquet.Boolean:
+		return strconv.FormatBool(v.Boolean()), nil
+	case parquet.Int32, parquet.Int64:
+		n := v.Int64()
+		if v.Kind() == parquet.Int32 {
+			n = int64(v.Int32())
+		}
+		switch {
+		case logicalType.Decimal != nil:
+			return formatDecimal(big.NewInt(n), int(logicalType.Decimal.Scale)), nil
+		case logicalType.Date != nil:
+			return time.Unix(n*86400, 0).UTC().Format("2006-01-02"), nil
+		case logicalType.Timestamp != nil:
+			unit := timestampUnit(logicalType.Timestamp)
+			return time.Unix(n/unit, (n%unit)*(1e9/unit)).UTC().Format(time.RFC3339Nano), nil
+		}
+		return strconv.FormatInt(n, 10), nil
+	case parquet.Int96:
+		return formatInt96(v.Int96()), nil
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'f', -1, 32), nil
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'f', -1, 64), nil
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		b := v.ByteArray()
+		switch {
+		case logicalType.Decimal != nil:
+			n := new(big.Int).SetBytes(b)
+			if len(b) > 0 && b[0]&0x80 != 0 {
+				n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+			}
+			return formatDecimal(n, int(logicalType.Decimal.Scale)), nil
+		case logicalType.UUID != nil && len(b) == 16:
+			return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+		}
+		return string(b), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+// timestampUnit returns how many of a timestamp's units there are in a
+// second
+func timestampUnit(t *format.TimestampType) int64 {
+	switch {
+	case t.Unit.Millis != nil:
+		return 1e3
+	case t.Unit.Micros != nil:
+		return 1e6
+	}
+	return 1e9
+}
+
+// formatInt96 formats the INT96 timestamps of older writers, which hold the
+// nanoseconds of the day followed by the julian day
+func formatInt96(v deprecated.Int96) string {
+	nanos := int64(v[1])<<32 | int64(v[0])
+	day := int64(v[2])
+	return time.Unix((day-julianUnixEpoch)*86400, nanos).UTC().Format(time.RFC3339Nano)
+}
+
+func formatDecimal(v *big.Int, scale int) string {
+	denom := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	return new(big.Rat).SetFrac(v, denom).FloatString(scale)
+}
@@ -0,0 +1,277 @@
+// Package providercosts reads the cost and usage exports of cloud providers,
+// such as AWS Cost and Usage Reports, so the actual cost of the resources
+// backing service instances can be billed. Line items are matched to
+// service instances by a resource tag holding the service instance guid.
+package providercosts
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+const (
+	DefaultProvider   = "aws"
+	DefaultTagKey     = "user:service_instance_guid"
+	DefaultCostColumn = "line_item_unblended_cost"
+)
+
+// line item columns, named as in a CUR 2.0 or Athena compatible export.
+// Legacy CSV columns such as lineItem/UsageStartDate are renamed to match.
+const (
+	columnLineItemID   = "identity_line_item_id"
+	columnLineItemType = "line_item_line_item_type"
+	columnUsageStart   = "line_item_usage_start_date"
+	columnUsageStop    = "line_item_usage_end_date"
+	columnResourceID   = "line_item_resource_id"
+	columnProductCode  = "line_item_product_code"
+	columnUsageType    = "line_item_usage_type"
+	columnDescription  = "line_item_line_item_description"
+	columnUsageAmount  = "line_item_usage_amount"
+	columnUnit         = "pricing_unit"
+	columnCurrencyCode = "line_item_currency_code"
+	columnResourceTags = "resource_tags"
+)
+
+var guidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// Config is read from the provider_costs section of config.json
+type Config struct {
+	Provider   string `json:"provider"`    // name recorded against imported costs, default aws
+	TagKey     string `json:"tag_key"`     // resource tag holding the service instance guid, default user:service_instance_guid
+	CostColumn string `json:"cost_column"` // column of the cost to bill, default line_item_unblended_cost
+}
+
+func LoadConfig(filename string) (Config, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg struct {
+		ProviderCosts Config `json:"provider_costs"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg.ProviderCosts, nil
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Provider == "" {
+		cfg.Provider = DefaultProvider
+	}
+	if cfg.TagKey == "" {
+		cfg.TagKey = DefaultTagKey
+	}
+	if cfg.CostColumn == "" {
+		cfg.CostColumn = DefaultCostColumn
+	}
+	cfg.CostColumn = columnName(cfg.CostColumn)
+	return cfg
+}
+
+// ReadFile returns the line items of a CSV, gzipped CSV or Parquet export
+// that are tagged with a service instance guid, and how many line items it
+// skipped because they were not or were taxes
+func (cfg Config) ReadFile(filename string) ([]eventio.ProviderCost, int, error) {
+	costs := []eventio.ProviderCost{}
+	skipped := 0
+	line := 0
+	err := readFile(filename, func(row map[string]string) error {
+		line++
+		cost, ok, err := cfg.lineItem(row)
+		if err != nil {
+			return fmt.Errorf("line item %d: %s", line, err)
+		}
+		if !ok {
+			skipped++
+			return nil
+		}
+		costs = append(costs, cost)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %s", filename, err)
+	}
+	return costs, skipped, nil
+}
+
+func readFile(filename string, fn func(row map[string]string) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	switch {
+	case strings.HasSuffix(filename, ".parquet"):
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		return readParquet(f, info.Size(), fn)
+	case strings.HasSuffix(filename, ".csv.gz"):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return readCSV(zr, fn)
+	case strings.HasSuffix(filename, ".csv"):
+		return readCSV(f, fn)
+	}
+	return fmt.Errorf("cost exports must be .csv, .csv.gz or .parquet files")
+}
+
+// readCSV calls fn with each row of a CSV file with a header, keyed by
+// column name
+func readCSV(r io.Reader, fn func(row map[string]string) error) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(header))
+	for i, h := range header {
+		names[i] = columnName(h)
+	}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		row := make(map[string]string, len(record))
+		for i, v := range record {
+			row[names[i]] = v
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
+
+// lineItem converts a row of an export to a ProviderCost. ok is false for
+// line items that are not tagged with a service instance guid and for
+// taxes, which are not passed through as VAT is added when billing.
+func (cfg Config) lineItem(row map[string]string) (cost eventio.ProviderCost, ok bool, err error) {
+	cfg = cfg.withDefaults()
+	expandTags(row)
+	if row[columnLineItemType] == "Tax" {
+		return cost, false, nil
+	}
+	guid := strings.ToLower(strings.TrimSpace(row[columnResourceTags+"_"+columnName(cfg.TagKey)]))
+	if !guidPattern.MatchString(guid) {
+		return cost, false, nil
+	}
+	cost = eventio.ProviderCost{
+		Provider:            cfg.Provider,
+		LineItemID:          row[columnLineItemID],
+		LineItemType:        row[columnLineItemType],
+		ServiceInstanceGUID: guid,
+		ResourceID:          row[columnResourceID],
+		ProductCode:         row[columnProductCode],
+		UsageType:           row[columnUsageType],
+		Description:         row[columnDescription],
+		Unit:                row[columnUnit],
+		CurrencyCode:        row[columnCurrencyCode],
+	}
+	if cost.UsageStart, err = parseTime(row[columnUsageStart]); err != nil {
+		return cost, false, fmt.Errorf("%s: %s", columnUsageStart, err)
+	}
+	if cost.UsageStop, err = parseTime(row[columnUsageStop]); err != nil {
+		return cost, false, fmt.Errorf("%s: %s", columnUsageStop, err)
+	}
+	if cost.Cost, err = parseAmount(row[cfg.CostColumn]); err != nil {
+		return cost, false, fmt.Errorf("%s: %s", cfg.CostColumn, err)
+	}
+	if amount := row[columnUsageAmount]; amount != "" {
+		if cost.UsageAmount, err = parseAmount(amount); err != nil {
+			return cost, false, fmt.Errorf("%s: %s", columnUsageAmount, err)
+		}
+	}
+	if err := cost.Validate(); err != nil {
+		return cost, false, err
+	}
+	return cost, true, nil
+}
+
+// expandTags adds the entries of a resource_tags column holding a JSON
+// object, as in CUR 2.0 CSV exports, as columns like the tag columns of
+// other exports
+func expandTags(row map[string]string) {
+	v := strings.TrimSpace(row[columnResourceTags])
+	if !strings.HasPrefix(v, "{") {
+		return
+	}
+	tags := map[string]string{}
+	if err := json.Unmarshal([]byte(v), &tags); err != nil {
+		return
+	}
+	for k, v := range tags {
+		row[columnResourceTags+"_"+columnName(k)] = v
+	}
+}
+
+// columnName converts the column names and tag keys of the different export
+// formats to the same form, eg lineItem/UsageStartDate and
+// line_item_usage_start_date are both line_item_usage_start_date, and
+// resourceTags/user:cost-centre is resource_tags_user_cost_centre
+func columnName(s string) string {
+	var b strings.Builder
+	var prev rune
+	underscore := false
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				underscore = true
+			}
+			r = unicode.ToLower(r)
+		case unicode.IsLower(r) || unicode.IsDigit(r):
+		default:
+			underscore = true
+			prev = r
+			continue
+		}
+		if underscore && b.Len() > 0 {
+			b.WriteRune('_')
+		}
+		underscore = false
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+func parseTime(s string) (string, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t.UTC().Format(time.RFC3339), nil
+		}
+	}
+	return "", fmt.Errorf("invalid time '%s'", s)
+}
+
+// parseAmount converts amounts such as 1.5E-4 to a plain decimal
+func parseAmount(s string) (eventio.Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return "", fmt.Errorf("invalid amount '%s'", s)
+	}
+	return eventio.MoneyFromRat(r), nil
+}
@@ -0,0 +1,13 @@
+package providercosts_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestProviderCosts(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ProviderCosts")
+}
@@ -0,0 +1,194 @@
+package providercosts_test
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/providercosts"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const serviceInstanceGUID = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+
+var _ = Describe("Config", func() {
+	var (
+		cfg providercosts.Config
+		dir string
+	)
+
+	BeforeEach(func() {
+		cfg = providercosts.Config{}
+		dir = GinkgoT().TempDir()
+	})
+
+	writeFile := func(name string, content string) string {
+		filename := filepath.Join(dir, name)
+		Expect(os.WriteFile(filename, []byte(content), 0644)).To(Succeed())
+		return filename
+	}
+
+	It("should read the tagged line items of a legacy CSV export", func() {
+		filename := writeFile("legacy.csv", ""+
+			"identity/LineItemId,lineItem/LineItemType,lineItem/UsageStartDate,lineItem/UsageEndDate,lineItem/ProductCode,lineItem/UsageType,lineItem/ResourceId,lineItem/UsageAmount,pricing/unit,lineItem/CurrencyCode,lineItem/UnblendedCost,resourceTags/user:service_instance_guid\n"+
+			"li-1,Usage,2001-01-01T00:00:00Z,2001-01-01T01:00:00Z,AmazonRDS,EUW1-InstanceUsage:db.t3.small,arn:aws:rds:eu-west-1:1:db:rdsbroker-1,1,Hrs,USD,0.036,"+serviceInstanceGUID+"\n"+
+			"li-2,Usage,2001-01-01T00:00:00Z,2001-01-01T01:00:00Z,AmazonS3,EUW1-TimedStorage-ByteHrs,bucket,10,GB-Mo,USD,0.5,\n"+
+			"li-3,Tax,2001-01-01T00:00:00Z,2001-01-01T01:00:00Z,AmazonRDS,,,,,USD,0.01,"+serviceInstanceGUID+"\n",
+		)
+
+		costs, skipped, err := cfg.ReadFile(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(skipped).To(Equal(2))
+		Expect(costs).To(Equal([]eventio.ProviderCost{{
+			Provider:            "aws",
+			LineItemID:          "li-1",
+			LineItemType:        "Usage",
+			ServiceInstanceGUID: serviceInstanceGUID,
+			ResourceID:          "arn:aws:rds:eu-west-1:1:db:rdsbroker-1",
+			ProductCode:         "AmazonRDS",
+			UsageType:           "EUW1-InstanceUsage:db.t3.small",
+			UsageStart:          "2001-01-01T00:00:00Z",
+			UsageStop:           "2001-01-01T01:00:00Z",
+			UsageAmount:         "1",
+			Unit:                "Hrs",
+			Cost:                "0.036",
+			CurrencyCode:        "USD",
+		}}))
+	})
+
+	It("should read a gzipped CUR 2.0 CSV export with its tags as JSON", func() {
+		filename := filepath.Join(dir, "cur2.csv.gz")
+		f, err := os.Create(filename)
+		Expect(err).ToNot(HaveOccurred())
+		zw := gzip.NewWriter(f)
+		_, err = zw.Write([]byte("" +
+			"identity_line_item_id,line_item_line_item_type,line_item_usage_start_date,line_item_usage_end_date,line_item_unblended_cost,line_item_currency_code,resource_tags\n" +
+			`li-1,Usage,2001-01-01 00:00:00,2001-01-01 01:00:00,1.5E-4,USD,"{""user:service_instance_guid"":""C85E98F0-6D1B-4F45-9368-EA58263165A0""}"` + "\n" +
+			`li-2,Usage,2001-01-01 00:00:00,2001-01-01 01:00:00,0.2,USD,"{}"` + "\n",
+		))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(zw.Close()).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+
+		costs, skipped, err := cfg.ReadFile(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(skipped).To(Equal(1))
+		Expect(costs).To(HaveLen(1))
+		Expect(costs[0].ServiceInstanceGUID).To(Equal(serviceInstanceGUID))
+		Expect(costs[0].UsageStart).To(Equal("2001-01-01T00:00:00Z"))
+		Expect(costs[0].Cost).To(Equal(eventio.Money("0.00015")))
+		Expect(costs[0].UsageAmount).To(BeEmpty())
+	})
+
+	It("should use the configured provider, tag and cost column", func() {
+		cfg = providercosts.Config{
+			Provider:   "aws-paas",
+			TagKey:     "user:tenant",
+			CostColumn: "lineItem/NetUnblendedCost",
+		}
+		filename := writeFile("net.csv", ""+
+			"lineItem/UsageStartDate,lineItem/UsageEndDate,lineItem/UnblendedCost,lineItem/NetUnblendedCost,lineItem/CurrencyCode,resourceTags/user:tenant,resourceTags/user:service_instance_guid\n"+
+			"2001-01-01T00:00:00Z,2001-01-02T00:00:00Z,10,9,USD,"+serviceInstanceGUID+",\n",
+		)
+
+		costs, _, err := cfg.ReadFile(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(costs).To(HaveLen(1))
+		Expect(costs[0].Provider).To(Equal("aws-paas"))
+		Expect(costs[0].Cost).To(Equal(eventio.Money("9")))
+	})
+
+	It("should read a CUR 2.0 Parquet export with its tags as a map", func() {
+		costs, skipped, err := cfg.ReadFile("testdata/cur2.parquet")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(skipped).To(Equal(2))
+		Expect(costs).To(Equal([]eventio.ProviderCost{{
+			Provider:            "aws",
+			LineItemID:          "li-1",
+			LineItemType:        "Usage",
+			ServiceInstanceGUID: serviceInstanceGUID,
+			ResourceID:          "arn:aws:rds:eu-west-1:1:db:rdsbroker-1",
+			ProductCode:         "AmazonRDS",
+			UsageType:           "EUW1-InstanceUsage:db.t3.small",
+			UsageStart:          "2001-01-01T00:00:00Z",
+			UsageStop:           "2001-01-01T01:00:00Z",
+			UsageAmount:         "1",
+			Unit:                "Hrs",
+			Cost:                "0.036",
+			CurrencyCode:        "USD",
+		}, {
+			Provider:            "aws",
+			LineItemID:          "li-2",
+			LineItemType:        "Usage",
+			ServiceInstanceGUID: serviceInstanceGUID,
+			ResourceID:          "arn:aws:rds:eu-west-1:1:db:rdsbroker-1",
+			ProductCode:         "AmazonRDS",
+			UsageType:           "EUW1-RDS:GP2-Storage",
+			UsageStart:          "2001-01-01T00:00:00Z",
+			UsageStop:           "2001-01-01T01:00:00Z",
+			Unit:                "GB-Mo",
+			Cost:                "0.00015",
+			CurrencyCode:        "USD",
+		}}))
+	})
+
+	It("should read a Parquet export with flat tag columns", func() {
+		costs, skipped, err := cfg.ReadFile("testdata/legacy.parquet")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(skipped).To(Equal(1))
+		Expect(costs).To(HaveLen(2))
+		Expect(costs[0].LineItemID).To(Equal("li-1"))
+		Expect(costs[0].Cost).To(Equal(eventio.Money("1.25")))
+		Expect(costs[1].LineItemType).To(Equal("Credit"))
+		Expect(costs[1].Cost).To(Equal(eventio.Money("-0.5")))
+		Expect(costs[1].UsageStart).To(Equal("2001-01-01T01:00:00Z"))
+		Expect(costs[1].UsageStop).To(Equal("2001-01-01T02:00:00Z"))
+	})
+
+	It("should read credits as negative costs", func() {
+		filename := writeFile("credits.csv", ""+
+			"identity/LineItemId,lineItem/LineItemType,lineItem/UsageStartDate,lineItem/UsageEndDate,lineItem/CurrencyCode,lineItem/UnblendedCost,resourceTags/user:service_instance_guid\n"+
+			"li-1,Credit,2001-01-01T01:00:00Z,2001-01-01T02:00:00Z,USD,-0.5,"+serviceInstanceGUID+"\n",
+		)
+
+		costs, _, err := cfg.ReadFile(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(costs).To(HaveLen(1))
+		Expect(costs[0].LineItemType).To(Equal("Credit"))
+		Expect(costs[0].Cost).To(Equal(eventio.Money("-0.5")))
+	})
+
+	It("should report the line item that is invalid", func() {
+		filename := writeFile("bad.csv", ""+
+			"lineItem/UsageStartDate,lineItem/UsageEndDate,lineItem/UnblendedCost,lineItem/CurrencyCode,resourceTags/user:service_instance_guid\n"+
+			"2001-01-01T00:00:00Z,2001-01-01T01:00:00Z,1,USD,"+serviceInstanceGUID+"\n"+
+			"yesterday,2001-01-01T01:00:00Z,1,USD,"+serviceInstanceGUID+"\n",
+		)
+
+		_, _, err := cfg.ReadFile(filename)
+		Expect(err).To(MatchError(filename + ": line item 2: line_item_usage_start_date: invalid time 'yesterday'"))
+	})
+
+	It("should reject files that are not exports", func() {
+		filename := writeFile("costs.xlsx", "")
+		_, _, err := cfg.ReadFile(filename)
+		Expect(err).To(MatchError(ContainSubstring("cost exports must be .csv, .csv.gz or .parquet files")))
+
+		filename = writeFile("costs.parquet", "PAR1 not really")
+		_, _, err = cfg.ReadFile(filename)
+		Expect(err).To(MatchError(ContainSubstring("costs.parquet")))
+	})
+})
+
+var _ = Describe("LoadConfig", func() {
+	It("should read the provider_costs section of the config", func() {
+		filename := filepath.Join(GinkgoT().TempDir(), "config.json")
+		Expect(os.WriteFile(filename, []byte(`{"provider_costs": {"tag_key": "user:tenant"}}`), 0644)).To(Succeed())
+
+		cfg, err := providercosts.LoadConfig(filename)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cfg).To(Equal(providercosts.Config{TagKey: "user:tenant"}))
+	})
+})
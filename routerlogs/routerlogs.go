@@ -0,0 +1,219 @@
+// Package routerlogs reads Cloud Foundry gorouter access logs and the bytes
+// sent in responses from apps, so egress bandwidth can be billed.
+package routerlogs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alphagov/paas-billing/eventio"
+)
+
+// maxLineSize is the longest access log line read, long enough for requests
+// with large headers
+const maxLineSize = 1024 * 1024
+
+// accessLogPattern matches the start of a gorouter access log line, eg
+//
+//	app.example.com - [2019-03-14T10:32:04.123+0000] "GET / HTTP/1.1" 200 0 1234 "-" ...
+//
+// capturing the timestamp and bytes sent. Lines are not anchored so that
+// syslog or loggregator prefixes are ignored.
+var accessLogPattern = regexp.MustCompile(`\S+ - \[([^\]]+)\] "[^"]*" \d{3} (?:\d+|-) (\d+|-) `)
+
+var appIDPattern = regexp.MustCompile(` app_id:"([0-9a-fA-F-]{36})"`)
+
+var timeLayouts = []string{"2006-01-02T15:04:05.000-0700", time.RFC3339Nano, "02/01/2006:15:04:05.000 -0700"}
+
+// Open opens an access log file, which may be gzipped
+func Open(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(filename, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return &gzipFile{Reader: zr, f: f}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.f.Close()
+}
+
+// Reader reads the egress usage of an access log in batches of lines, so
+// that large logs are neither held in memory nor stored in one transaction.
+// Only complete lines are read, so a line that is still being written is
+// left to be read when the log is next ingested.
+type Reader struct {
+	r        *bufio.Reader
+	source   string
+	offset   int64
+	eof      bool
+	lines    int
+	skipped  int
+	requests int64
+}
+
+// NewReader starts reading an access log, working out its source from its
+// first line
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReaderSize(r, maxLineSize)
+	head, err := br.Peek(maxLineSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	lr := &Reader{r: br}
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		digest := sha256.Sum256(bytes.TrimRight(head[:i], "\r"))
+		lr.source = hex.EncodeToString(digest[:16])
+	} else if len(head) == maxLineSize {
+		return nil, bufio.ErrTooLong
+	} else {
+		lr.eof = true
+	}
+	return lr, nil
+}
+
+// Source identifies the log by a digest of its first line, so a log has the
+// same source however it is named, whether it is gzipped and however much
+// has been appended to it. It is empty for a log without a complete line.
+func (lr *Reader) Source() string {
+	return lr.source
+}
+
+// Skip skips the first offset bytes of the log, which have been ingested
+// before
+func (lr *Reader) Skip(offset int64) error {
+	n, err := io.CopyN(io.Discard, lr.r, offset)
+	lr.offset += n
+	if err == io.EOF {
+		return fmt.Errorf("the log is shorter than the %d bytes already ingested from it", offset)
+	}
+	return err
+}
+
+// Next returns the usage of each app and hour in up to maxLines more lines of
+// the log, or io.EOF if there are no more complete lines
+func (lr *Reader) Next(maxLines int) (eventio.EgressBatch, error) {
+	if lr.eof {
+		return eventio.EgressBatch{}, io.EOF
+	}
+	batch := eventio.EgressBatch{
+		Source: lr.source,
+		From:   lr.offset,
+		To:     lr.offset,
+		Usage:  []eventio.EgressUsage{},
+	}
+	usageIndex := map[string]int{}
+	for n := 0; n < maxLines; n++ {
+		line, err := lr.r.ReadSlice('\n')
+		if err == io.EOF {
+			lr.eof = true
+			break
+		}
+		if err == bufio.ErrBufferFull {
+			return eventio.EgressBatch{}, bufio.ErrTooLong
+		}
+		if err != nil {
+			return eventio.EgressBatch{}, err
+		}
+		lr.offset += int64(len(line))
+		batch.To = lr.offset
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		lr.lines++
+		appGUID, hour, sent, ok := parseLine(string(line))
+		if !ok {
+			lr.skipped++
+			continue
+		}
+		lr.requests++
+		k := appGUID + " " + hour
+		i, ok := usageIndex[k]
+		if !ok {
+			batch.Usage = append(batch.Usage, eventio.EgressUsage{AppGUID: appGUID, Hour: hour})
+			i = len(batch.Usage) - 1
+			usageIndex[k] = i
+		}
+		batch.Usage[i].Bytes += sent
+		batch.Usage[i].Requests++
+	}
+	if lr.eof && batch.To == batch.From {
+		return eventio.EgressBatch{}, io.EOF
+	}
+	return batch, nil
+}
+
+// Lines is the number of access log lines read so far
+func (lr *Reader) Lines() int {
+	return lr.lines
+}
+
+// Skipped is the number of lines read so far that are not access logs for an
+// app
+func (lr *Reader) Skipped() int {
+	return lr.skipped
+}
+
+// Requests is the number of requests to apps read so far
+func (lr *Reader) Requests() int64 {
+	return lr.requests
+}
+
+// parseLine returns the app, the UTC hour and the bytes sent of an access log
+// line. ok is false for lines that are not access logs or are for routes that
+// are not mapped to an app.
+func parseLine(line string) (appGUID string, hour string, sent int64, ok bool) {
+	m := accessLogPattern.FindStringSubmatchIndex(line)
+	if m == nil {
+		return "", "", 0, false
+	}
+	timestamp, sentField := line[m[2]:m[3]], line[m[4]:m[5]]
+	// look for the app_id after the request, which could contain anything
+	id := appIDPattern.FindStringSubmatch(line[m[1]-1:])
+	if id == nil {
+		return "", "", 0, false
+	}
+	t, err := parseTime(timestamp)
+	if err != nil {
+		return "", "", 0, false
+	}
+	if sentField != "-" {
+		if sent, err = strconv.ParseInt(sentField, 10, 64); err != nil {
+			return "", "", 0, false
+		}
+	}
+	return strings.ToLower(id[1]), t.UTC().Truncate(time.Hour).Format(time.RFC3339), sent, true
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s'", s)
+}
@@ -0,0 +1,13 @@
+package routerlogs_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestRouterLogs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RouterLogs")
+}
@@ -0,0 +1,196 @@
+package routerlogs_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alphagov/paas-billing/eventio"
+	"github.com/alphagov/paas-billing/routerlogs"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	app1GUID = "c85e98f0-6d1b-4f45-9368-ea58263165a0"
+	app2GUID = "a9e2b3d3-5ed5-4e6b-9f7c-2a8d7e0b4f11"
+)
+
+func accessLog(timestamp string, bytesSent string, appID string) string {
+	return `app.example.com - [` + timestamp + `] "GET /path?q=app_id:%22x%22 HTTP/1.1" 200 12 ` + bytesSent +
+		` "-" "curl/7.54.0" "10.0.0.1:53212" "10.0.16.5:61012" x_forwarded_for:"1.2.3.4" x_forwarded_proto:"https"` +
+		` vcap_request_id:"5b1c6a55-0ab4-4c6e-5c3e-9b2a7b8f1c2d" response_time:0.005 gorouter_time:0.000104` +
+		` app_id:"` + appID + `" app_index:"0" x_cf_routererror:"-"` + "\n"
+}
+
+// readAll reads every batch of a log, returning the reader and the batches
+func readAll(log string, maxLines int) (*routerlogs.Reader, []eventio.EgressBatch) {
+	lr, err := routerlogs.NewReader(strings.NewReader(log))
+	Expect(err).ToNot(HaveOccurred())
+	batches := []eventio.EgressBatch{}
+	for {
+		batch, err := lr.Next(maxLines)
+		if err == io.EOF {
+			return lr, batches
+		}
+		Expect(err).ToNot(HaveOccurred())
+		Expect(batch.Validate()).To(Succeed())
+		batches = append(batches, batch)
+	}
+}
+
+var _ = Describe("Reader", func() {
+	It("should total the bytes sent to each app in each hour", func() {
+		log := "" +
+			accessLog("2001-01-01T10:59:59.999+0000", "1000", app1GUID) +
+			accessLog("2001-01-01T11:00:00.000+0000", "200", app1GUID) +
+			accessLog("2001-01-01T11:30:00.000+0100", "30", app1GUID) +
+			accessLog("2001-01-01T11:45:00.000+0000", "4", app2GUID) +
+			accessLog("2001-01-01T11:50:00.000+0000", "-", strings.ToUpper(app2GUID))
+
+		lr, batches := readAll(log, 100)
+		Expect(lr.Lines()).To(Equal(5))
+		Expect(lr.Skipped()).To(Equal(0))
+		Expect(lr.Requests()).To(Equal(int64(5)))
+		Expect(lr.Source()).To(MatchRegexp(`^[0-9a-f]{32}$`))
+		Expect(batches).To(Equal([]eventio.EgressBatch{{
+			Source: lr.Source(),
+			From:   0,
+			To:     int64(len(log)),
+			Usage: []eventio.EgressUsage{
+				{AppGUID: app1GUID, Hour: "2001-01-01T10:00:00Z", Bytes: 1030, Requests: 2},
+				{AppGUID: app1GUID, Hour: "2001-01-01T11:00:00Z", Bytes: 200, Requests: 1},
+				{AppGUID: app2GUID, Hour: "2001-01-01T11:00:00Z", Bytes: 4, Requests: 2},
+			},
+		}}))
+	})
+
+	It("should read access logs with syslog prefixes and older timestamps", func() {
+		log := "" +
+			"<14>1 2001-01-01T10:00:01.000000+00:00 router.0 rs2 - - - " + accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID) +
+			"2001-01-01T10:00:02.00Z [RTR/0] [OUT] " + strings.TrimSuffix(accessLog("01/01/2001:10:00:00.000 +0000", "20", app1GUID), "\n") + "\r\n"
+
+		lr, batches := readAll(log, 100)
+		Expect(lr.Skipped()).To(Equal(0))
+		Expect(batches).To(HaveLen(1))
+		Expect(batches[0].Usage).To(Equal([]eventio.EgressUsage{
+			{AppGUID: app1GUID, Hour: "2001-01-01T10:00:00Z", Bytes: 30, Requests: 2},
+		}))
+	})
+
+	It("should skip lines that are not access logs for an app", func() {
+		log := "" +
+			accessLog("2001-01-01T10:00:00.000+0000", "10", "-") +
+			accessLog("yesterday", "10", app1GUID) +
+			"router started\n" +
+			"\n" +
+			accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID)
+
+		lr, batches := readAll(log, 100)
+		Expect(lr.Lines()).To(Equal(4))
+		Expect(lr.Skipped()).To(Equal(3))
+		Expect(lr.Requests()).To(Equal(int64(1)))
+		Expect(batches[0].To).To(Equal(int64(len(log))))
+	})
+
+	It("should read a log in batches of lines", func() {
+		line1 := accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID)
+		line2 := accessLog("2001-01-01T10:00:00.001+0000", "20", app1GUID)
+		line3 := accessLog("2001-01-01T10:00:00.002+0000", "40", app1GUID)
+
+		_, batches := readAll(line1+line2+line3, 2)
+		Expect(batches).To(HaveLen(2))
+		Expect(batches[0].From).To(Equal(int64(0)))
+		Expect(batches[0].To).To(Equal(int64(len(line1 + line2))))
+		Expect(batches[0].Usage[0].Bytes).To(Equal(int64(30)))
+		Expect(batches[1].From).To(Equal(batches[0].To))
+		Expect(batches[1].To).To(Equal(int64(len(line1 + line2 + line3))))
+		Expect(batches[1].Usage[0].Bytes).To(Equal(int64(40)))
+	})
+
+	It("should leave a line that is still being written to be read later", func() {
+		line1 := accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID)
+		line2 := accessLog("2001-01-01T10:00:00.001+0000", "20", app1GUID)
+
+		lr, batches := readAll(line1+strings.TrimSuffix(line2, "\n"), 100)
+		Expect(lr.Lines()).To(Equal(1))
+		Expect(batches).To(HaveLen(1))
+		Expect(batches[0].To).To(Equal(int64(len(line1))))
+	})
+
+	It("should only read what was appended after the bytes that are skipped", func() {
+		line1 := accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID)
+		line2 := accessLog("2001-01-01T10:00:00.001+0000", "20", app1GUID)
+
+		first, _ := readAll(line1, 100)
+
+		lr, err := routerlogs.NewReader(strings.NewReader(line1 + line2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lr.Source()).To(Equal(first.Source()))
+		Expect(lr.Skip(int64(len(line1)))).To(Succeed())
+		batch, err := lr.Next(100)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(batch.From).To(Equal(int64(len(line1))))
+		Expect(batch.Usage).To(Equal([]eventio.EgressUsage{
+			{AppGUID: app1GUID, Hour: "2001-01-01T10:00:00Z", Bytes: 20, Requests: 1},
+		}))
+		_, err = lr.Next(100)
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("should identify logs by their first line", func() {
+		line1 := accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID)
+		line2 := accessLog("2001-01-01T10:00:00.001+0000", "20", app1GUID)
+
+		lr1, _ := readAll(line1, 100)
+		lr2, _ := readAll(line2+line1, 100)
+		Expect(lr2.Source()).ToNot(Equal(lr1.Source()))
+	})
+
+	It("should fail to skip more than the log holds", func() {
+		lr, err := routerlogs.NewReader(strings.NewReader(accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lr.Skip(1000000)).To(MatchError(ContainSubstring("shorter than the 1000000 bytes already ingested")))
+	})
+
+	It("should have no source or usage without a complete line", func() {
+		lr, batches := readAll("router sta", 100)
+		Expect(lr.Source()).To(BeEmpty())
+		Expect(batches).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Open", func() {
+	It("should read gzipped logs the same as uncompressed logs", func() {
+		dir := GinkgoT().TempDir()
+		log := accessLog("2001-01-01T10:00:00.000+0000", "10", app1GUID)
+
+		plain := filepath.Join(dir, "access.log")
+		Expect(os.WriteFile(plain, []byte(log), 0644)).To(Succeed())
+
+		gzipped := filepath.Join(dir, "access.log.gz")
+		f, err := os.Create(gzipped)
+		Expect(err).ToNot(HaveOccurred())
+		zw := gzip.NewWriter(f)
+		_, err = zw.Write([]byte(log))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(zw.Close()).To(Succeed())
+		Expect(f.Close()).To(Succeed())
+
+		for _, filename := range []string{plain, gzipped} {
+			r, err := routerlogs.Open(filename)
+			Expect(err).ToNot(HaveOccurred())
+			b, err := io.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Close()).To(Succeed())
+			Expect(string(b)).To(Equal(log))
+		}
+	})
+
+	It("should fail for missing files", func() {
+		_, err := routerlogs.Open("/does/not/exist.log")
+		Expect(err).To(HaveOccurred())
+	})
+})